@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/checkpoint/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Version indicates the version of the 'State' struct used to hold 'MigState' information.
+const Version = "v2"
+
+// State is a versioned struct used to hold 'MigState' information.
+// In addition to the 'MigState' itself, it records the version of the driver
+// that was loaded and the GPUs that were present at the time the checkpoint
+// was taken.
+type State struct {
+	Version       string         `json:"version"`
+	DriverVersion string         `json:"driver-version,omitempty"`
+	Devices       []DeviceInfo   `json:"devices,omitempty"`
+	MigState      types.MigState `json:"mig-state"`
+}
+
+// DeviceInfo identifies a GPU whose MIG state is held in a checkpoint.
+// 'Memory' is the total memory of the GPU in bytes, which MIG profile names
+// are derived from. It is not recorded by older checkpoints.
+type DeviceInfo struct {
+	UUID     string         `json:"uuid"`
+	Name     string         `json:"name"`
+	DeviceID types.DeviceID `json:"device-id"`
+	Memory   uint64         `json:"memory,omitempty"`
+}
+
+// ParseState parses raw checkpoint bytes of any known version into a 'State'.
+// Checkpoints written with an older version are migrated to the current one.
+func ParseState(b []byte) (*State, error) {
+	header := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	var version string
+	for _, k := range []string{"version", "Version"} {
+		if v, exists := header[k]; exists {
+			err := json.Unmarshal(v, &version)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse '%v' field: %v", k, err)
+			}
+			break
+		}
+	}
+
+	switch version {
+	case "":
+		return nil, fmt.Errorf("unable to parse with missing 'version' field")
+	case v1.Version:
+		var state v1.State
+		err := json.Unmarshal(b, &state)
+		if err != nil {
+			return nil, err
+		}
+		return FromV1(&state), nil
+	case Version:
+		var state State
+		err := json.Unmarshal(b, &state)
+		if err != nil {
+			return nil, err
+		}
+		return &state, nil
+	}
+
+	return nil, fmt.Errorf("unknown version: %v", version)
+}
+
+// FromV1 migrates a 'v1.State' to a 'State'.
+// Version 1 checkpoints did not record a driver version, so it is left empty.
+func FromV1(state *v1.State) *State {
+	return &State{
+		Version:  Version,
+		MigState: state.MigState,
+	}
+}
+
+// UnmarshalJSON unmarshals raw bytes into a versioned 'State'.
+func (s *State) UnmarshalJSON(b []byte) error {
+	state := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &state)
+	if err != nil {
+		return err
+	}
+
+	required := []string{"version", "mig-state"}
+	for _, r := range required {
+		if _, exists := state[r]; !exists {
+			return fmt.Errorf("missing required field: %v", r)
+		}
+	}
+
+	result := State{}
+	for k, v := range state {
+		switch k {
+		case "version":
+			err := json.Unmarshal(v, &result.Version)
+			if err != nil {
+				return err
+			}
+			if result.Version != Version {
+				return fmt.Errorf("unknown version: %v", result.Version)
+			}
+		case "driver-version":
+			err := json.Unmarshal(v, &result.DriverVersion)
+			if err != nil {
+				return err
+			}
+		case "devices":
+			err := json.Unmarshal(v, &result.Devices)
+			if err != nil {
+				return err
+			}
+		case "mig-state":
+			err := json.Unmarshal(v, &result.MigState)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*s = result
+	return nil
+}
@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v2
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/checkpoint/v1"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib/mig"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newTestMigState() types.MigState {
+	return types.MigState{
+		Devices: []types.DeviceState{
+			{
+				UUID:    "GPU-0",
+				MigMode: mig.Enabled,
+				GpuInstances: []types.GpuInstanceState{
+					{
+						ProfileID: nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+						Placement: nvml.GpuInstancePlacement{Start: 4, Size: 4},
+						ComputeInstances: []types.ComputeInstanceState{
+							{
+								ProfileID:    nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE,
+								EngProfileID: nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED,
+							},
+						},
+					},
+				},
+			},
+			{
+				UUID:    "GPU-1",
+				MigMode: mig.Disabled,
+			},
+		},
+	}
+}
+
+func TestMarshallParseState(t *testing.T) {
+	state := State{
+		Version:       Version,
+		DriverVersion: "550.54.15",
+		Devices: []DeviceInfo{
+			{
+				UUID:     "GPU-0",
+				Name:     "NVIDIA A100-SXM4-40GB",
+				DeviceID: 0x20B010DE,
+			},
+			{
+				UUID:     "GPU-1",
+				Name:     "NVIDIA A100-SXM4-40GB",
+				DeviceID: 0x20B010DE,
+			},
+		},
+		MigState: newTestMigState(),
+	}
+
+	j, err := json.Marshal(state)
+	require.Nil(t, err, "Unexpected failure json.Marshal")
+
+	parsed, err := ParseState(j)
+	require.Nil(t, err, "Unexpected failure ParseState")
+	require.Equal(t, &state, parsed)
+}
+
+func TestMigrateFromV1(t *testing.T) {
+	state := v1.State{
+		Version:  v1.Version,
+		MigState: newTestMigState(),
+	}
+
+	j, err := json.Marshal(state)
+	require.Nil(t, err, "Unexpected failure json.Marshal")
+
+	parsed, err := ParseState(j)
+	require.Nil(t, err, "Unexpected failure ParseState")
+	require.Equal(t, Version, parsed.Version)
+	require.Equal(t, "", parsed.DriverVersion)
+	require.Equal(t, state.MigState, parsed.MigState)
+}
+
+func TestParseState(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		State           string
+		expectedFailure bool
+	}{
+		{
+			"Well formed",
+			`{
+				"version": "v2",
+				"driver-version": "550.54.15",
+				"mig-state": {
+					"Devices": []
+				}
+			}`,
+			false,
+		},
+		{
+			"Well formed - with devices",
+			`{
+				"version": "v2",
+				"devices": [{
+					"uuid": "GPU-0",
+					"name": "NVIDIA A100-SXM4-40GB",
+					"device-id": 548409566
+				}],
+				"mig-state": {
+					"Devices": []
+				}
+			}`,
+			false,
+		},
+		{
+			"Well formed - v1",
+			`{
+				"Version": "v1",
+				"MigState": {
+					"Devices": []
+				}
+			}`,
+			false,
+		},
+		{
+			"Missing version field",
+			`{
+				"mig-state": {
+					"Devices": []
+				}
+			}`,
+			true,
+		},
+		{
+			"Unknown version",
+			`{
+				"version": "v3",
+				"mig-state": {
+					"Devices": []
+				}
+			}`,
+			true,
+		},
+		{
+			"Missing 'mig-state'",
+			`{
+				"version": "v2"
+			}`,
+			true,
+		},
+		{
+			"Erroneous field",
+			`{
+				"bogus": "field",
+				"version": "v2",
+				"mig-state": {
+					"Devices": []
+				}
+			}`,
+			true,
+		},
+		{
+			"Malformed",
+			`{`,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, err := ParseState([]byte(tc.State))
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success ParseState")
+			} else {
+				require.Nil(t, err, "Unexpected failure ParseState")
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+
+	spec "github.com/NVIDIA/mig-parted/api/spec/v1"
+)
+
+// Version indicates the version of the 'Spec' struct used to hold fleet information.
+const Version = "v1"
+
+// Spec is a versioned struct used to aggregate the exported MIG configs of a set of nodes.
+type Spec struct {
+	Version       string        `json:"version"                  yaml:"version"`
+	Nodes         []NodeSpec    `json:"nodes"                    yaml:"nodes"`
+	ConfigClasses []ConfigClass `json:"config-classes,omitempty" yaml:"config-classes,omitempty"`
+}
+
+// NodeSpec holds the exported MIG config 'Spec' of a single node.
+type NodeSpec struct {
+	Name string    `json:"name" yaml:"name"`
+	Spec spec.Spec `json:"spec" yaml:"spec"`
+}
+
+// ConfigClass groups together the set of nodes that share an identical MIG configuration.
+type ConfigClass struct {
+	Name       string                  `json:"name"        yaml:"name"`
+	Nodes      []string                `json:"nodes"       yaml:"nodes,flow"`
+	MigConfigs spec.MigConfigSpecSlice `json:"mig-configs" yaml:"mig-configs"`
+}
+
+// UnmarshalJSON unmarshals raw bytes into a versioned 'Spec'.
+func (s *Spec) UnmarshalJSON(b []byte) error {
+	fleet := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &fleet)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := fleet["version"]; !exists {
+		return fmt.Errorf("unable to parse with missing 'version' field")
+	}
+
+	result := Spec{}
+	for k, v := range fleet {
+		switch k {
+		case "version":
+			err := json.Unmarshal(v, &result.Version)
+			if err != nil {
+				return err
+			}
+			if result.Version != Version {
+				return fmt.Errorf("unknown version: %v", result.Version)
+			}
+		case "nodes":
+			err := json.Unmarshal(v, &result.Nodes)
+			if err != nil {
+				return err
+			}
+			for _, n := range result.Nodes {
+				if n.Name == "" {
+					return fmt.Errorf("missing required field 'name' in '%v'", k)
+				}
+			}
+		case "config-classes":
+			err := json.Unmarshal(v, &result.ConfigClasses)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*s = result
+	return nil
+}
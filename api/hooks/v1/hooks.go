@@ -17,7 +17,10 @@
 package v1
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 )
@@ -31,12 +34,17 @@ type Spec struct {
 	Hooks   HooksMap `json:"hooks"`
 }
 
-// HookSpec holds the actual data associated with a runnable Hook.
+// HookSpec holds the actual data associated with a runnable Hook. A hook
+// with a 'Job' is run as a Kubernetes Job by nvidia-mig-manager instead of
+// being executed on the host, with 'Envs' set in its container and the other
+// fields ignored.
 type HookSpec struct {
 	Command string   `json:"command"`
 	Args    []string `json:"args"`
 	Envs    EnvsMap  `json:"envs"`
 	Workdir string   `json:"workdir"`
+	Limits  *Limits  `json:"limits,omitempty"`
+	Job     *JobSpec `json:"job,omitempty"`
 }
 
 // EnvsMap holds the (key, value) pairs associated with a set of environment variables.
@@ -55,6 +63,10 @@ func (h HooksMap) Run(name string, envs EnvsMap, output bool) error {
 	}
 	for _, hook := range hooks {
 		err := hook.Run(envs, output)
+		var veto *VetoError
+		if errors.As(err, &veto) {
+			veto.Hook = name
+		}
 		if err != nil {
 			return err
 		}
@@ -62,18 +74,93 @@ func (h HooksMap) Run(name string, envs EnvsMap, output bool) error {
 	return nil
 }
 
+// Output executes all of the hooks associated with a given name in the
+// HooksMap like Run, but captures what each of them writes to stdout rather
+// than printing it, and returns the output of the last one that wrote
+// anything (or nil if none did).
+func (h HooksMap) Output(name string, envs EnvsMap, output bool) ([]byte, error) {
+	var stdout []byte
+	for _, hook := range h[name] {
+		out, err := hook.Output(envs, output)
+		var veto *VetoError
+		if errors.As(err, &veto) {
+			veto.Hook = name
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(bytes.TrimSpace(out)) != 0 {
+			stdout = out
+		}
+	}
+	return stdout, nil
+}
+
 // Run executes a specific hook from a HookSpec.
 // It injects the environment variables associated with the provided EnvMap,
 // and optionally prints the output for each hook to stdout and stderr.
+// If the hook writes a 'Result' that denies continuation (or asks for it to
+// be retried later) to the file named by ResultFileEnv, a *VetoError is
+// returned, regardless of the hook's exit code. The hook is run under its
+// 'Limits', if any. Hooks with a 'Job' are left to nvidia-mig-manager and
+// not run at all.
 func (h *HookSpec) Run(envs EnvsMap, output bool) error {
+	var stdout io.Writer
+	if output {
+		stdout = os.Stdout
+	}
+	return h.run(envs, output, stdout)
+}
+
+// Output executes a specific hook from a HookSpec like Run, but returns what
+// it writes to stdout rather than printing it.
+func (h *HookSpec) Output(envs EnvsMap, output bool) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := h.run(envs, output, &stdout)
+	if err != nil {
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// run executes the hook with its stdout written to 'stdout', and its stderr
+// printed if 'output' is set.
+func (h *HookSpec) run(envs EnvsMap, output bool, stdout io.Writer) error {
+	if h.Job != nil {
+		return nil
+	}
+
+	resultFile, err := os.CreateTemp("", "mig-parted-hook-result-")
+	if err != nil {
+		return fmt.Errorf("error creating hook result file: %w", err)
+	}
+	resultFile.Close()
+	defer os.Remove(resultFile.Name())
+	err = h.Limits.chown(resultFile.Name())
+	if err != nil {
+		return fmt.Errorf("error changing owner of hook result file: %w", err)
+	}
+
 	cmd := exec.Command(h.Command, h.Args...) //nolint:gosec
-	cmd.Env = h.Envs.Combine(envs).Format()
+	cmd.Env = h.Envs.Combine(envs).Combine(EnvsMap{ResultFileEnv: resultFile.Name()}).Format()
 	cmd.Dir = h.Workdir
+	cmd.Stdout = stdout
 	if output {
-		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
 	}
-	return cmd.Run()
+	runErr := h.Limits.run(cmd)
+
+	result, err := ReadResult(resultFile.Name())
+	if err != nil {
+		if runErr != nil {
+			return runErr
+		}
+		return err
+	}
+	if result != nil && result.Decision != DecisionAllow {
+		return &VetoError{Result: *result}
+	}
+	return runErr
 }
 
 // Combine merges to EnvMaps together
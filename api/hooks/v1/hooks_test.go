@@ -18,6 +18,7 @@ package v1
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"os"
 	"sync"
@@ -85,6 +86,15 @@ func TestMarshallUnmarshall(t *testing.T) {
 				},
 			},
 			"hook1": []HookSpec{
+				{
+					Job: &JobSpec{
+						Image:     "busybox",
+						Command:   []string{"/bin/sh", "-c"},
+						Args:      []string{"echo Hello"},
+						Namespace: "gpu-operator",
+						Timeout:   "5m",
+					},
+				},
 				{
 					Workdir: "/wherever0",
 					Command: "whatever0",
@@ -140,6 +150,15 @@ func TestRunHooks(t *testing.T) {
 			"",
 			true,
 		},
+		{
+			"Job",
+			HookSpec{
+				Command: "/doesnotexist",
+				Job:     &JobSpec{Image: "busybox"},
+			},
+			"",
+			false,
+		},
 	}
 	for _, tc := range testCases {
 		t.Run(tc.Description, func(t *testing.T) {
@@ -155,3 +174,177 @@ func TestRunHooks(t *testing.T) {
 		})
 	}
 }
+
+func TestRunHooksWithResult(t *testing.T) {
+	testCases := []struct {
+		Description      string
+		Script           string
+		expectedVeto     *VetoError
+		expectedFailure  bool
+		expectedErrorMsg string
+	}{
+		{
+			"No result",
+			"true",
+			nil,
+			false,
+			"",
+		},
+		{
+			"Allow",
+			`echo 'decision: allow' > "$MIG_PARTED_HOOK_RESULT_FILE"`,
+			nil,
+			false,
+			"",
+		},
+		{
+			"Allow with failing exit code",
+			`echo 'decision: allow' > "$MIG_PARTED_HOOK_RESULT_FILE"; exit 1`,
+			nil,
+			true,
+			"exit status 1",
+		},
+		{
+			"Deny",
+			`printf 'decision: deny\nmessage: business hours\n' > "$MIG_PARTED_HOOK_RESULT_FILE"`,
+			&VetoError{Hook: "hook0", Result: Result{Decision: DecisionDeny, Message: "business hours"}},
+			true,
+			"'hook0' hook denied continuation: business hours",
+		},
+		{
+			"Deny with failing exit code",
+			`echo '{"decision": "deny"}' > "$MIG_PARTED_HOOK_RESULT_FILE"; exit 3`,
+			&VetoError{Hook: "hook0", Result: Result{Decision: DecisionDeny}},
+			true,
+			"'hook0' hook denied continuation",
+		},
+		{
+			"Retry",
+			`printf 'decision: retry\nretry-after: 30m\n' > "$MIG_PARTED_HOOK_RESULT_FILE"`,
+			&VetoError{Hook: "hook0", Result: Result{Decision: DecisionRetry, RetryAfter: "30m"}},
+			true,
+			"'hook0' hook asked to retry after 30m",
+		},
+		{
+			"Invalid result",
+			`echo 'decision: maybe' > "$MIG_PARTED_HOOK_RESULT_FILE"`,
+			nil,
+			true,
+			"error parsing hook result file: error unmarshaling JSON: while decoding JSON: unknown decision: 'maybe'",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			hooks := HooksMap{
+				"hook0": []HookSpec{
+					{
+						Command: "/bin/sh",
+						Args:    []string{"-c", tc.Script},
+					},
+				},
+			}
+			err := hooks.Run("hook0", EnvsMap{}, false)
+			if !tc.expectedFailure {
+				require.Nil(t, err, "Unexpected failure HooksMap.Run")
+				return
+			}
+			require.NotNil(t, err, "Unexpected success HooksMap.Run")
+			require.Equal(t, tc.expectedErrorMsg, err.Error())
+
+			var veto *VetoError
+			if tc.expectedVeto == nil {
+				require.False(t, errors.As(err, &veto), "Unexpected VetoError")
+				return
+			}
+			require.True(t, errors.As(err, &veto), "Expected VetoError")
+			require.Equal(t, tc.expectedVeto, veto)
+		})
+	}
+}
+
+func TestOutputHooks(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Scripts         []string
+		expectedOutput  string
+		expectedFailure bool
+	}{
+		{
+			"No output",
+			[]string{"true"},
+			"",
+			false,
+		},
+		{
+			"Single output",
+			[]string{`echo '{"1g.5gb": 7}'`},
+			"{\"1g.5gb\": 7}\n",
+			false,
+		},
+		{
+			"Last output wins",
+			[]string{`echo first`, `echo second`, `true`},
+			"second\n",
+			false,
+		},
+		{
+			"Environment",
+			[]string{`echo "$ENV0"`},
+			"val0\n",
+			false,
+		},
+		{
+			"Deny",
+			[]string{`echo ignored; echo 'decision: deny' > "$MIG_PARTED_HOOK_RESULT_FILE"`},
+			"",
+			true,
+		},
+		{
+			"Failing hook",
+			[]string{`echo ignored; exit 1`},
+			"",
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var specs []HookSpec
+			for _, script := range tc.Scripts {
+				specs = append(specs, HookSpec{Command: "/bin/sh", Args: []string{"-c", script}})
+			}
+			hooks := HooksMap{"hook0": specs}
+
+			output, err := hooks.Output("hook0", EnvsMap{"ENV0": "val0"}, false)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success HooksMap.Output")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure HooksMap.Output")
+			require.Equal(t, tc.expectedOutput, string(output))
+		})
+	}
+}
+
+func TestJobSpecValidate(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Job             JobSpec
+		expectedFailure bool
+	}{
+		{"Image only", JobSpec{Image: "busybox"}, false},
+		{"With timeout", JobSpec{Image: "busybox", Timeout: "90s"}, false},
+		{"Missing image", JobSpec{Command: []string{"true"}}, true},
+		{"Malformed timeout", JobSpec{Image: "busybox", Timeout: "soon"}, true},
+		{"Negative timeout", JobSpec{Image: "busybox", Timeout: "-1m"}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := tc.Job.Validate()
+			if !tc.expectedFailure {
+				require.Nil(t, err, "Unexpected failure JobSpec.Validate")
+			} else {
+				require.NotNil(t, err, "Unexpected success JobSpec.Validate")
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"time"
+)
+
+// JobSpec describes a hook that nvidia-mig-manager runs as a Kubernetes Job
+// pinned to the node being reconfigured, instead of a command executed on
+// the host. This suits nodes with an immutable OS, where no hook scripts can
+// be installed. Only the 'apply-start' and 'apply-exit' hooks can be Jobs,
+// run before and after the reconfiguration respectively.
+//   - Image and Command (or Args) define the single container of the Job.
+//   - Namespace is where the Job is created, defaulting to the namespace
+//     nvidia-mig-manager is configured with.
+//   - ServiceAccountName is the service account the Job runs as.
+//   - Timeout bounds how long the Job may take, e.g. "5m".
+type JobSpec struct {
+	Image              string   `json:"image"`
+	Command            []string `json:"command,omitempty"`
+	Args               []string `json:"args,omitempty"`
+	Namespace          string   `json:"namespace,omitempty"`
+	ServiceAccountName string   `json:"serviceAccountName,omitempty"`
+	Timeout            string   `json:"timeout,omitempty"`
+}
+
+// Validate checks that the Job described by 'j' can be created.
+func (j *JobSpec) Validate() error {
+	if j.Image == "" {
+		return fmt.Errorf("missing 'image'")
+	}
+	_, err := j.GetTimeout()
+	return err
+}
+
+// GetTimeout returns the parsed 'Timeout' of 'j', or 0 if it has none.
+func (j *JobSpec) GetTimeout() (time.Duration, error) {
+	if j.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(j.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid 'timeout': %v", err)
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("invalid 'timeout': must be positive")
+	}
+	return timeout, nil
+}
@@ -0,0 +1,325 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// CgroupRoot is the cgroup v2 hierarchy under which hooks with memory or CPU
+// limits are run, each in its own cgroup.
+var CgroupRoot = "/sys/fs/cgroup/mig-parted-hooks"
+
+// cpuPeriod is the cgroup 'cpu.max' period (in microseconds) that 'CPUs' is
+// converted against.
+const cpuPeriod = 100000
+
+// Limits constrains a hook, so that a misbehaving hook cannot hang or starve
+// the process running it. Every field is optional:
+//   - Timeout kills the hook (and anything it started) once it has run for
+//     this long, e.g. "30s".
+//   - Nice sets the scheduling priority the hook (and anything it starts)
+//     runs at.
+//   - MemoryMax caps the memory of the hook's cgroup in bytes, with an
+//     optional K, M, G or T suffix (powers of 1024), e.g. "512M".
+//   - CPUs caps the CPU time of the hook's cgroup, in CPUs, e.g. 0.5.
+//   - UID and GID run the hook as a dedicated user and group.
+type Limits struct {
+	Timeout   string  `json:"timeout,omitempty"`
+	Nice      *int    `json:"nice,omitempty"`
+	MemoryMax string  `json:"memory-max,omitempty"`
+	CPUs      float64 `json:"cpus,omitempty"`
+	UID       *uint32 `json:"uid,omitempty"`
+	GID       *uint32 `json:"gid,omitempty"`
+}
+
+// TimeoutError is returned when a hook is killed for exceeding its timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+}
+
+var _ error = (*TimeoutError)(nil)
+
+// UnmarshalJSON unmarshals raw bytes into a 'Limits', validating its fields.
+func (l *Limits) UnmarshalJSON(b []byte) error {
+	type limits Limits
+	var parsed limits
+	err := json.Unmarshal(b, &parsed)
+	if err != nil {
+		return err
+	}
+
+	if parsed.Timeout != "" {
+		d, err := time.ParseDuration(parsed.Timeout)
+		if err != nil {
+			return fmt.Errorf("error parsing value in 'timeout' field: %v", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid value for 'timeout': %v", parsed.Timeout)
+		}
+	}
+	if parsed.Nice != nil && (*parsed.Nice < -20 || *parsed.Nice > 19) {
+		return fmt.Errorf("invalid value for 'nice': %v (must be between -20 and 19)", *parsed.Nice)
+	}
+	if parsed.MemoryMax != "" {
+		if _, err := ParseMemorySize(parsed.MemoryMax); err != nil {
+			return fmt.Errorf("error parsing value in 'memory-max' field: %v", err)
+		}
+	}
+	if parsed.CPUs < 0 {
+		return fmt.Errorf("invalid value for 'cpus': %v", parsed.CPUs)
+	}
+
+	*l = Limits(parsed)
+	return nil
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("hook timed out after %v", e.Timeout)
+}
+
+// ParseMemorySize parses a size in bytes, with an optional K, M, G or T
+// suffix (powers of 1024).
+func ParseMemorySize(size string) (uint64, error) {
+	multiplier := uint64(1)
+	number := size
+	if n := len(size); n > 0 {
+		switch size[n-1] {
+		case 'K', 'k':
+			multiplier = 1 << 10
+		case 'M', 'm':
+			multiplier = 1 << 20
+		case 'G', 'g':
+			multiplier = 1 << 30
+		case 'T', 't':
+			multiplier = 1 << 40
+		}
+		if multiplier != 1 {
+			number = size[:n-1]
+		}
+	}
+
+	value, err := strconv.ParseUint(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size '%v'", size)
+	}
+	if value == 0 {
+		return 0, fmt.Errorf("size must be positive")
+	}
+	if value > math.MaxUint64/multiplier {
+		return 0, fmt.Errorf("size '%v' is too large", size)
+	}
+	return value * multiplier, nil
+}
+
+// run runs 'cmd' under the constraints in 'l'. A nil 'l' runs it
+// unconstrained.
+func (l *Limits) run(cmd *exec.Cmd) error {
+	if l == nil {
+		return cmd.Run()
+	}
+
+	// Run the hook in its own process group, so that a timeout kills
+	// everything it started, not just the hook itself.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	if l.UID != nil || l.GID != nil {
+		credential := &syscall.Credential{
+			Uid: uint32(os.Getuid()),
+			Gid: uint32(os.Getgid()),
+		}
+		if l.UID != nil {
+			credential.Uid = *l.UID
+		}
+		if l.GID != nil {
+			credential.Gid = *l.GID
+		}
+		cmd.SysProcAttr.Credential = credential
+	}
+
+	if l.MemoryMax != "" || l.CPUs > 0 {
+		cgroup, err := newCgroup(l)
+		if err != nil {
+			return fmt.Errorf("error creating cgroup for hook: %w", err)
+		}
+		defer cgroup.remove()
+		cmd.SysProcAttr.UseCgroupFD = true
+		cmd.SysProcAttr.CgroupFD = int(cgroup.dir.Fd())
+	}
+
+	if err := l.start(cmd); err != nil {
+		return err
+	}
+	pgid := cmd.Process.Pid
+
+	done := make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+
+	var timeout <-chan time.Time
+	var d time.Duration
+	if l.Timeout != "" {
+		d, _ = time.ParseDuration(l.Timeout)
+		timer := time.NewTimer(d)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case err := <-done:
+		return err
+	case <-timeout:
+		_ = syscall.Kill(-pgid, syscall.SIGKILL)
+		<-done
+		return &TimeoutError{Timeout: d}
+	}
+}
+
+// start starts 'cmd' at the nice level in 'l'. Linux keeps the nice level
+// per thread and a process inherits it from the thread that forks it, so the
+// hook is started from a thread of its own that is reniced first. The hook,
+// and anything it forks, then runs niced from its first instruction. The
+// thread is never unlocked, so it exits with its goroutine instead of going
+// back to the Go scheduler with the nice level.
+func (l *Limits) start(cmd *exec.Cmd) error {
+	if l.Nice == nil {
+		return cmd.Start()
+	}
+
+	errs := make(chan error, 1)
+	go func() {
+		runtime.LockOSThread()
+		err := syscall.Setpriority(syscall.PRIO_PROCESS, syscall.Gettid(), *l.Nice)
+		if err != nil {
+			errs <- fmt.Errorf("error setting nice level of hook: %w", err)
+			return
+		}
+		errs <- cmd.Start()
+	}()
+	return <-errs
+}
+
+// chown gives the user and group the hook runs as ownership of 'path', so
+// that the hook can write to it.
+func (l *Limits) chown(path string) error {
+	if l == nil || (l.UID == nil && l.GID == nil) {
+		return nil
+	}
+	uid, gid := -1, -1
+	if l.UID != nil {
+		uid = int(*l.UID)
+	}
+	if l.GID != nil {
+		gid = int(*l.GID)
+	}
+	return os.Chown(path, uid, gid)
+}
+
+// cgroup is a cgroup v2 created under CgroupRoot to run a single hook in.
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+func newCgroup(l *Limits) (*cgroup, error) {
+	err := os.MkdirAll(CgroupRoot, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var controllers []string
+	if l.CPUs > 0 {
+		controllers = append(controllers, "+cpu")
+	}
+	if l.MemoryMax != "" {
+		controllers = append(controllers, "+memory")
+	}
+	err = writeCgroupFile(CgroupRoot, "cgroup.subtree_control", strings.Join(controllers, " "))
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := os.MkdirTemp(CgroupRoot, "hook-")
+	if err != nil {
+		return nil, err
+	}
+	c := &cgroup{path: path}
+
+	if l.MemoryMax != "" {
+		size, err := ParseMemorySize(l.MemoryMax)
+		if err != nil {
+			c.remove()
+			return nil, err
+		}
+		err = writeCgroupFile(path, "memory.max", strconv.FormatUint(size, 10))
+		if err != nil {
+			c.remove()
+			return nil, err
+		}
+	}
+	if l.CPUs > 0 {
+		quota := int64(math.Ceil(l.CPUs * cpuPeriod))
+		err = writeCgroupFile(path, "cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod))
+		if err != nil {
+			c.remove()
+			return nil, err
+		}
+	}
+
+	c.dir, err = os.Open(path)
+	if err != nil {
+		c.remove()
+		return nil, err
+	}
+	return c, nil
+}
+
+// remove kills anything left in the cgroup and removes it. The kernel only
+// lets an empty cgroup be removed, so this retries briefly while the killed
+// processes exit.
+func (c *cgroup) remove() {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+	_ = writeCgroupFile(c.path, "cgroup.kill", "1")
+	for i := 0; i < 100; i++ {
+		err := os.Remove(c.path)
+		if err == nil || errors.Is(err, os.ErrNotExist) {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func writeCgroupFile(dir, name, value string) error {
+	err := os.WriteFile(filepath.Join(dir, name), []byte(value), 0)
+	if err != nil {
+		return fmt.Errorf("error writing '%v' to %v: %w", value, filepath.Join(dir, name), err)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestUnmarshalLimits(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Limits          string
+		expectedFailure bool
+	}{
+		{"Empty", `{}`, false},
+		{"All fields", `{timeout: 30s, nice: 10, memory-max: 512M, cpus: 0.5, uid: 65534, gid: 65534}`, false},
+		{"Invalid timeout", `{timeout: soon}`, true},
+		{"Negative timeout", `{timeout: -1s}`, true},
+		{"Nice too low", `{nice: -21}`, true},
+		{"Nice too high", `{nice: 20}`, true},
+		{"Invalid memory-max", `{memory-max: lots}`, true},
+		{"Negative cpus", `{cpus: -1}`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var limits Limits
+			err := yaml.Unmarshal([]byte(tc.Limits), &limits)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success yaml.Unmarshal")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure yaml.Unmarshal")
+		})
+	}
+}
+
+func TestParseMemorySize(t *testing.T) {
+	testCases := []struct {
+		size            string
+		expected        uint64
+		expectedFailure bool
+	}{
+		{"4096", 4096, false},
+		{"1K", 1 << 10, false},
+		{"512M", 512 << 20, false},
+		{"2g", 2 << 30, false},
+		{"1T", 1 << 40, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"0", 0, true},
+		{"1.5G", 0, true},
+		{"-1M", 0, true},
+		{"99999999999T", 0, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.size, func(t *testing.T) {
+			size, err := ParseMemorySize(tc.size)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success ParseMemorySize")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure ParseMemorySize")
+			require.Equal(t, tc.expected, size)
+		})
+	}
+}
+
+func TestRunHookWithTimeout(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "marker")
+	hook := HookSpec{
+		Command: "/bin/sh",
+		// The background sleep must be killed along with the hook.
+		Args:   []string{"-c", "(sleep 1; touch " + marker + ") & sleep 10"},
+		Limits: &Limits{Timeout: "100ms"},
+	}
+
+	start := time.Now()
+	err := hook.Run(EnvsMap{}, false)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	var timeout *TimeoutError
+	require.True(t, errors.As(err, &timeout), "Expected TimeoutError, got: %v", err)
+	require.Equal(t, "hook timed out after 100ms", err.Error())
+
+	time.Sleep(1500 * time.Millisecond)
+	_, err = os.Stat(marker)
+	require.True(t, os.IsNotExist(err), "Background process outlived the hook")
+}
+
+func TestRunHookWithinTimeout(t *testing.T) {
+	hook := HookSpec{
+		Command: "/bin/sh",
+		Args:    []string{"-c", "exit 0"},
+		Limits:  &Limits{Timeout: "10s"},
+	}
+	require.Nil(t, hook.Run(EnvsMap{}, false))
+}
+
+func TestRunHookWithNice(t *testing.T) {
+	nice := 5
+
+	// Without a delay, a race between starting the hook and renicing it
+	// would leave the hook or the children it forks right away un-niced.
+	testCases := []struct {
+		Description string
+		Script      string
+	}{
+		{"Hook", "nice > %v"},
+		{"Child forked right away", "(nice) > %v; exit 0"},
+		{"Background child forked right away", "nice > %v & wait"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "nice")
+			hook := HookSpec{
+				Command: "/bin/sh",
+				Args:    []string{"-c", fmt.Sprintf(tc.Script, output)},
+				Limits:  &Limits{Nice: &nice},
+			}
+			require.Nil(t, hook.Run(EnvsMap{}, false))
+
+			contents, err := os.ReadFile(output)
+			require.Nil(t, err)
+			require.Equal(t, "5", strings.TrimSpace(string(contents)))
+		})
+	}
+}
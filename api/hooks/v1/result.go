@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// ResultFileEnv is the environment variable holding the path of the file a
+// hook may write its 'Result' to.
+const ResultFileEnv = "MIG_PARTED_HOOK_RESULT_FILE"
+
+// Decision is a hook's verdict on whether mig-parted may continue.
+type Decision string
+
+// Decisions a hook can make.
+const (
+	DecisionAllow Decision = "allow"
+	DecisionDeny  Decision = "deny"
+	DecisionRetry Decision = "retry"
+)
+
+// Result is the structured outcome a hook can write to the file named by
+// ResultFileEnv, as JSON or YAML. A hook that writes no result is allowed to
+// continue if it exits successfully.
+type Result struct {
+	Decision   Decision `json:"decision"`
+	Message    string   `json:"message,omitempty"`
+	RetryAfter string   `json:"retry-after,omitempty"`
+}
+
+// VetoError is returned when a hook denies continuation, or asks for it to be
+// retried later.
+type VetoError struct {
+	Hook   string
+	Result Result
+}
+
+var _ error = (*VetoError)(nil)
+
+// UnmarshalJSON unmarshals raw bytes into a 'Result', validating its fields.
+func (r *Result) UnmarshalJSON(b []byte) error {
+	type result Result
+	var parsed result
+	err := json.Unmarshal(b, &parsed)
+	if err != nil {
+		return err
+	}
+
+	switch Decision(parsed.Decision) {
+	case DecisionAllow, DecisionDeny:
+		if parsed.RetryAfter != "" {
+			return fmt.Errorf("'retry-after' only valid with decision '%v'", DecisionRetry)
+		}
+	case DecisionRetry:
+		if parsed.RetryAfter == "" {
+			return fmt.Errorf("missing required field 'retry-after' with decision '%v'", DecisionRetry)
+		}
+		d, err := time.ParseDuration(parsed.RetryAfter)
+		if err != nil {
+			return fmt.Errorf("error parsing value in 'retry-after' field: %v", err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("invalid value for 'retry-after': %v", parsed.RetryAfter)
+		}
+	default:
+		return fmt.Errorf("unknown decision: '%v'", parsed.Decision)
+	}
+
+	*r = Result(parsed)
+	return nil
+}
+
+// RetryAfterDuration returns the parsed 'RetryAfter', or 0 if it is unset.
+func (r *Result) RetryAfterDuration() time.Duration {
+	if r.RetryAfter == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(r.RetryAfter)
+	return d
+}
+
+// ReadResult reads the 'Result' a hook wrote to 'path'. It returns nil if the
+// hook wrote nothing.
+func ReadResult(path string) (*Result, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading hook result file: %w", err)
+	}
+	if strings.TrimSpace(string(contents)) == "" {
+		return nil, nil
+	}
+
+	var result Result
+	err = yaml.Unmarshal(contents, &result)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing hook result file: %w", err)
+	}
+	return &result, nil
+}
+
+func (e *VetoError) Error() string {
+	hook := "hook"
+	if e.Hook != "" {
+		hook = fmt.Sprintf("'%v' hook", e.Hook)
+	}
+
+	var msg string
+	switch e.Result.Decision {
+	case DecisionRetry:
+		msg = fmt.Sprintf("%v asked to retry after %v", hook, e.Result.RetryAfter)
+	default:
+		msg = fmt.Sprintf("%v denied continuation", hook)
+	}
+	if e.Result.Message != "" {
+		msg = fmt.Sprintf("%v: %v", msg, e.Result.Message)
+	}
+	return msg
+}
+
+// RetryAfter returns how long to wait before retrying, or 0 if the hook
+// denied continuation outright.
+func (e *VetoError) RetryAfter() time.Duration {
+	if e.Result.Decision != DecisionRetry {
+		return 0
+	}
+	return e.Result.RetryAfterDuration()
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadResult(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Contents        *string
+		expectedResult  *Result
+		expectedFailure bool
+	}{
+		{
+			"Missing file",
+			nil,
+			nil,
+			false,
+		},
+		{
+			"Empty file",
+			ptr("\n"),
+			nil,
+			false,
+		},
+		{
+			"Allow",
+			ptr("decision: allow"),
+			&Result{Decision: DecisionAllow},
+			false,
+		},
+		{
+			"Deny with message as JSON",
+			ptr(`{"decision": "deny", "message": "business hours"}`),
+			&Result{Decision: DecisionDeny, Message: "business hours"},
+			false,
+		},
+		{
+			"Retry",
+			ptr("decision: retry\nretry-after: 1h\nmessage: drain in progress"),
+			&Result{Decision: DecisionRetry, Message: "drain in progress", RetryAfter: "1h"},
+			false,
+		},
+		{
+			"Retry missing 'retry-after'",
+			ptr("decision: retry"),
+			nil,
+			true,
+		},
+		{
+			"Retry bogus 'retry-after'",
+			ptr("decision: retry\nretry-after: soon"),
+			nil,
+			true,
+		},
+		{
+			"Retry non-positive 'retry-after'",
+			ptr("decision: retry\nretry-after: 0s"),
+			nil,
+			true,
+		},
+		{
+			"Deny with 'retry-after'",
+			ptr("decision: deny\nretry-after: 1h"),
+			nil,
+			true,
+		},
+		{
+			"Missing decision",
+			ptr("message: hello"),
+			nil,
+			true,
+		},
+		{
+			"Malformed",
+			ptr("decision: [allow"),
+			nil,
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "result")
+			if tc.Contents != nil {
+				err := os.WriteFile(path, []byte(*tc.Contents), 0600)
+				require.Nil(t, err)
+			}
+
+			result, err := ReadResult(path)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success ReadResult")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure ReadResult")
+			require.Equal(t, tc.expectedResult, result)
+		})
+	}
+}
+
+func TestVetoErrorRetryAfter(t *testing.T) {
+	deny := &VetoError{Result: Result{Decision: DecisionDeny}}
+	require.Equal(t, time.Duration(0), deny.RetryAfter())
+	require.Equal(t, "hook denied continuation", deny.Error())
+
+	retry := &VetoError{Hook: "pre-apply-config", Result: Result{Decision: DecisionRetry, RetryAfter: "90s", Message: "busy"}}
+	require.Equal(t, 90*time.Second, retry.RetryAfter())
+	require.Equal(t, "'pre-apply-config' hook asked to retry after 90s: busy", retry.Error())
+}
+
+func ptr(s string) *string {
+	return &s
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Version indicates the version of the 'Plan' struct used to hold planned MIG changes.
+const Version = "v1"
+
+// Step types
+const (
+	SetMigMode             = "set-mig-mode"
+	CreateGpuInstance      = "create-gpu-instance"
+	CreateComputeInstance  = "create-compute-instance"
+	DestroyComputeInstance = "destroy-compute-instance"
+	DestroyGpuInstance     = "destroy-gpu-instance"
+)
+
+// Plan is a versioned struct holding the exact steps that applying a MIG
+// config will perform on each GPU, along with the state of each GPU when the
+// plan was made. A plan is only valid for as long as that state is unchanged.
+type Plan struct {
+	Version        string    `json:"version"`
+	SelectedConfig string    `json:"selected-config,omitempty"`
+	GPUs           []GPUPlan `json:"gpus"`
+}
+
+// GPUPlan holds the steps planned for a single GPU.
+type GPUPlan struct {
+	Index    int            `json:"index"`
+	DeviceID types.DeviceID `json:"device-id"`
+	State    GPUState       `json:"state"`
+	Steps    []Step         `json:"steps"`
+}
+
+// GPUState holds the MIG state of a GPU that a plan was made against.
+type GPUState struct {
+	MigEnabled       bool                `json:"mig-enabled"`
+	GpuInstances     []types.GpuInstance `json:"gpu-instances,omitempty"`
+	ComputeInstances []types.MigDevice   `json:"compute-instances,omitempty"`
+}
+
+// Step is a single planned change to a GPU. Which fields are set depends on
+// its 'Type'. Steps that act on the same GPU instance share its 'Ref', so
+// that compute instances can be placed in GPU instances created by earlier
+// steps.
+type Step struct {
+	Type            string           `json:"type"`
+	Description     string           `json:"description"`
+	MigEnabled      *bool            `json:"mig-enabled,omitempty"`
+	GpuInstance     *GpuInstanceRef  `json:"gpu-instance,omitempty"`
+	ComputeInstance *types.MigDevice `json:"compute-instance,omitempty"`
+	Profile         string           `json:"profile,omitempty"`
+	Start           *uint32          `json:"start,omitempty"`
+}
+
+// GpuInstanceRef identifies the GPU instance a step acts on.
+type GpuInstanceRef struct {
+	Ref int `json:"ref"`
+	types.GpuInstance
+}
+
+// Parse parses raw plan bytes into a 'Plan', ensuring they hold a known version.
+func Parse(b []byte) (*Plan, error) {
+	header := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &header)
+	if err != nil {
+		return nil, err
+	}
+
+	v, exists := header["version"]
+	if !exists {
+		return nil, fmt.Errorf("unable to parse with missing 'version' field")
+	}
+	var version string
+	err = json.Unmarshal(v, &version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse 'version' field: %v", err)
+	}
+	if version != Version {
+		return nil, fmt.Errorf("unknown version: %v", version)
+	}
+
+	var plan Plan
+	err = json.Unmarshal(b, &plan)
+	if err != nil {
+		return nil, err
+	}
+
+	return &plan, nil
+}
+
+// Equals checks if two GPU states hold the same MIG mode and GPU / compute
+// instances, regardless of the order they are listed in.
+func (s GPUState) Equals(other GPUState) bool {
+	return reflect.DeepEqual(s.normalize(), other.normalize())
+}
+
+func (s GPUState) normalize() GPUState {
+	gis := append([]types.GpuInstance{}, s.GpuInstances...)
+	sort.Slice(gis, func(i, j int) bool {
+		return gis[i].ID < gis[j].ID
+	})
+	cis := append([]types.MigDevice{}, s.ComputeInstances...)
+	sort.Slice(cis, func(i, j int) bool {
+		if cis[i].GpuInstanceID != cis[j].GpuInstanceID {
+			return cis[i].GpuInstanceID < cis[j].GpuInstanceID
+		}
+		return cis[i].ComputeInstanceID < cis[j].ComputeInstanceID
+	})
+	return GPUState{MigEnabled: s.MigEnabled, GpuInstances: gis, ComputeInstances: cis}
+}
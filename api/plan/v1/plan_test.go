@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestParse(t *testing.T) {
+	enabled := true
+	plan := Plan{
+		Version:        Version,
+		SelectedConfig: "all-1g.5gb",
+		GPUs: []GPUPlan{
+			{
+				Index:    0,
+				DeviceID: types.NewDeviceID(0x20B0, 0x10DE),
+				State:    GPUState{MigEnabled: false},
+				Steps: []Step{
+					{Type: SetMigMode, Description: "GPU 0: set MIG mode to Enabled", MigEnabled: &enabled},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(plan)
+	require.Nil(t, err, "Unexpected failure from Marshal")
+
+	parsed, err := Parse(b)
+	require.Nil(t, err, "Unexpected failure from Parse")
+	require.Equal(t, &plan, parsed)
+
+	_, err = Parse([]byte(`{"gpus": []}`))
+	require.NotNil(t, err, "Unexpected success parsing plan with missing version")
+
+	_, err = Parse([]byte(`{"version": "v0", "gpus": []}`))
+	require.NotNil(t, err, "Unexpected success parsing plan with unknown version")
+}
+
+func TestGPUStateEquals(t *testing.T) {
+	state := GPUState{
+		MigEnabled: true,
+		GpuInstances: []types.GpuInstance{
+			{Profile: "1g.5gb", ID: 9},
+			{Profile: "3g.20gb", ID: 2},
+		},
+		ComputeInstances: []types.MigDevice{
+			{Profile: "1g.5gb", GpuInstanceID: 9},
+			{Profile: "3g.20gb", GpuInstanceID: 2},
+		},
+	}
+
+	reordered := GPUState{
+		MigEnabled: true,
+		GpuInstances: []types.GpuInstance{
+			{Profile: "3g.20gb", ID: 2},
+			{Profile: "1g.5gb", ID: 9},
+		},
+		ComputeInstances: []types.MigDevice{
+			{Profile: "3g.20gb", GpuInstanceID: 2},
+			{Profile: "1g.5gb", GpuInstanceID: 9},
+		},
+	}
+	require.True(t, state.Equals(reordered))
+
+	changed := reordered
+	changed.GpuInstances = changed.GpuInstances[:1]
+	require.False(t, state.Equals(changed))
+
+	require.False(t, state.Equals(GPUState{MigEnabled: false}))
+}
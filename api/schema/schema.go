@@ -0,0 +1,49 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package schema publishes the JSON Schemas of the machine-readable outputs
+// of nvidia-mig-parted, so that tools written in other languages (e.g.
+// Python) can validate what they consume against the binary that produced it.
+package schema
+
+import (
+	"embed"
+	"fmt"
+)
+
+// Types of output a schema is published for.
+const (
+	Export = "export"
+	Plan   = "plan"
+	Status = "status"
+)
+
+//go:embed *.schema.json
+var schemas embed.FS
+
+// Types returns the types of output a schema is published for.
+func Types() []string {
+	return []string{Export, Plan, Status}
+}
+
+// Get returns the JSON Schema of the output of type 't'.
+func Get(t string) ([]byte, error) {
+	b, err := schemas.ReadFile(t + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("unknown schema type '%v': must be one of %v", t, Types())
+	}
+	return b, nil
+}
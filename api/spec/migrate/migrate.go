@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package migrate rewrites MIG config spec files from one version of the
+// spec to a newer one. Specs are rewritten as YAML documents rather than
+// through the structs of each version, so that their comments and key order
+// are kept where possible.
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+)
+
+// AnnotationPrefix starts the comment added above every part of a migrated
+// spec that needs manual attention.
+const AnnotationPrefix = "migrate-spec:"
+
+// Versions lists the known versions of the spec, oldest first.
+var Versions = []string{v1.Version}
+
+// Note describes a part of a migrated spec that needs manual attention.
+type Note struct {
+	Line    int
+	Message string
+}
+
+func (n Note) String() string {
+	return fmt.Sprintf("line %d: %v", n.Line, n.Message)
+}
+
+// step migrates the root mapping of a spec from version 'from' to version
+// 'to', the one following it in 'Versions'. Everything it cannot migrate
+// on its own is annotated in place and returned as a 'Note'.
+type step struct {
+	from    string
+	to      string
+	migrate func(root *yaml.Node) ([]Note, error)
+}
+
+// steps holds the migration from each version of the spec to the next. It is
+// empty for as long as 'v1' remains the only version.
+var steps []step
+
+// Migrate rewrites the spec in 'content' from version 'from' to version 'to'.
+// If 'from' is empty, the version the spec declares is migrated from. The
+// notes returned list everything annotated in the output for manual
+// attention. 'content' is returned as is if there is nothing to migrate.
+func Migrate(content []byte, from, to string) ([]byte, []Note, error) {
+	var doc yaml.Node
+	err := yaml.Unmarshal(content, &doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing spec: %v", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("spec is not a mapping")
+	}
+	root := doc.Content[0]
+
+	version := lookup(root, "version")
+	if version == nil {
+		return nil, nil, fmt.Errorf("spec has no 'version' field")
+	}
+	if from == "" {
+		from = version.Value
+	}
+	if version.Value != from {
+		return nil, nil, fmt.Errorf("spec is version %v, not %v", version.Value, from)
+	}
+
+	path, err := findSteps(from, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(path) == 0 {
+		return content, nil, nil
+	}
+
+	var notes []Note
+	for _, s := range path {
+		n, err := s.migrate(root)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error migrating spec from %v to %v: %v", s.from, s.to, err)
+		}
+		notes = append(notes, n...)
+		version.Value = s.to
+	}
+
+	var output bytes.Buffer
+	encoder := yaml.NewEncoder(&output)
+	encoder.SetIndent(2)
+	err = encoder.Encode(&doc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding migrated spec: %v", err)
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error encoding migrated spec: %v", err)
+	}
+
+	return output.Bytes(), notes, nil
+}
+
+// findSteps returns the steps migrating a spec from version 'from' to
+// version 'to', in order.
+func findSteps(from, to string) ([]step, error) {
+	i, err := versionIndex(from)
+	if err != nil {
+		return nil, err
+	}
+	j, err := versionIndex(to)
+	if err != nil {
+		return nil, err
+	}
+	if j < i {
+		return nil, fmt.Errorf("cannot migrate spec from %v back to %v", from, to)
+	}
+
+	var path []step
+	for ; i < j; i++ {
+		s := findStep(Versions[i], Versions[i+1])
+		if s == nil {
+			return nil, fmt.Errorf("no migration from %v to %v", Versions[i], Versions[i+1])
+		}
+		path = append(path, *s)
+	}
+	return path, nil
+}
+
+func findStep(from, to string) *step {
+	for i := range steps {
+		if steps[i].from == from && steps[i].to == to {
+			return &steps[i]
+		}
+	}
+	return nil
+}
+
+func versionIndex(version string) (int, error) {
+	for i, v := range Versions {
+		if v == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown spec version '%v' (known versions: %v)", version, strings.Join(Versions, ", "))
+}
+
+// lookup returns the value of 'key' in the mapping 'node', or nil if it is
+// not set.
+func lookup(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// annotate adds a comment above 'node' saying it needs manual attention for
+// the reason in 'message', and returns the matching 'Note'.
+func annotate(node *yaml.Node, message string) Note {
+	comment := fmt.Sprintf("# %v %v", AnnotationPrefix, message)
+	if node.HeadComment != "" {
+		comment = node.HeadComment + "\n" + comment
+	}
+	node.HeadComment = comment
+	return Note{Line: node.Line, Message: message}
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testSpec = `# Configs for the cluster
+version: v1
+# Never touch GPU 0
+unmanaged-devices:
+  - devices: [0]
+mig-configs:
+  all-disabled:
+    - devices: all
+      mig-enabled: false
+`
+
+// withTestVersion registers a 'v2' of the spec whose migration from 'v1'
+// renames 'unmanaged-devices' to 'excluded-devices' and annotates it.
+func withTestVersion(t *testing.T) {
+	versions, registered := Versions, steps
+	t.Cleanup(func() {
+		Versions, steps = versions, registered
+	})
+
+	Versions = append([]string{}, versions...)
+	Versions = append(Versions, "v2")
+	steps = append([]step{}, registered...)
+	steps = append(steps, step{
+		from: "v1",
+		to:   "v2",
+		migrate: func(root *yaml.Node) ([]Note, error) {
+			var notes []Note
+			for i := 0; i+1 < len(root.Content); i += 2 {
+				key := root.Content[i]
+				if key.Value == "unmanaged-devices" {
+					key.Value = "excluded-devices"
+					notes = append(notes, annotate(key, "check that the excluded devices are still correct"))
+				}
+			}
+			return notes, nil
+		},
+	})
+}
+
+func TestMigrate(t *testing.T) {
+	withTestVersion(t)
+
+	output, notes, err := Migrate([]byte(testSpec), "", "v2")
+	require.Nil(t, err, "Unexpected failure from Migrate")
+
+	expected := `# Configs for the cluster
+version: v2
+# Never touch GPU 0
+# migrate-spec: check that the excluded devices are still correct
+excluded-devices:
+  - devices: [0]
+mig-configs:
+  all-disabled:
+    - devices: all
+      mig-enabled: false
+`
+	require.Equal(t, expected, string(output))
+	require.Equal(t, []Note{{Line: 4, Message: "check that the excluded devices are still correct"}}, notes)
+}
+
+func TestMigrateSameVersion(t *testing.T) {
+	output, notes, err := Migrate([]byte(testSpec), "v1", "v1")
+	require.Nil(t, err, "Unexpected failure from Migrate")
+	require.Equal(t, testSpec, string(output))
+	require.Empty(t, notes)
+}
+
+func TestMigrateErrors(t *testing.T) {
+	withTestVersion(t)
+
+	testCases := []struct {
+		Description string
+		Spec        string
+		From        string
+		To          string
+	}{
+		{"Unknown target version", testSpec, "", "v3"},
+		{"Unknown source version", "version: v0\n", "", "v2"},
+		{"Mismatched source version", testSpec, "v2", "v2"},
+		{"Backwards migration", "version: v2\n", "", "v1"},
+		{"Missing version", "mig-configs: {}\n", "", "v2"},
+		{"Not a mapping", "- v1\n", "", "v2"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			_, _, err := Migrate([]byte(tc.Spec), tc.From, tc.To)
+			require.NotNil(t, err, "Unexpected success from Migrate")
+		})
+	}
+}
+
+func TestMigrateNoV2Yet(t *testing.T) {
+	_, _, err := Migrate([]byte(testSpec), "v1", "v2")
+	require.EqualError(t, err, "unknown spec version 'v2' (known versions: v1)")
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+)
+
+// NewSpec returns an empty 'Spec' of the current 'Version', to be populated
+// with 'AddNamedConfig' and 'AddDeviceEntry'.
+func NewSpec() *Spec {
+	return &Spec{
+		Version:    Version,
+		MigConfigs: make(map[string]MigConfigSpecSlice),
+	}
+}
+
+// AddNamedConfig adds an empty MIG config called 'name' to the spec. Entries
+// are added to it with 'AddDeviceEntry'.
+func (s *Spec) AddNamedConfig(name string) error {
+	if name == "" {
+		return fmt.Errorf("MIG config name must not be empty")
+	}
+	if _, exists := s.MigConfigs[name]; exists {
+		return fmt.Errorf("MIG config '%v' already exists", name)
+	}
+	if s.MigConfigs == nil {
+		s.MigConfigs = make(map[string]MigConfigSpecSlice)
+	}
+	s.MigConfigs[name] = MigConfigSpecSlice{}
+	return nil
+}
+
+// AddDeviceEntry validates 'entry' and appends it to the MIG config called
+// 'name', which must already have been added with 'AddNamedConfig'.
+func (s *Spec) AddDeviceEntry(name string, entry MigConfigSpec) error {
+	if _, exists := s.MigConfigs[name]; !exists {
+		return fmt.Errorf("unknown MIG config '%v'", name)
+	}
+
+	var validated MigConfigSpec
+	err := roundTrip(&entry, &validated)
+	if err != nil {
+		return fmt.Errorf("invalid entry for MIG config '%v': %w", name, err)
+	}
+
+	s.MigConfigs[name] = append(s.MigConfigs[name], validated)
+	return nil
+}
+
+// Validate checks that the spec would be accepted when read back from a
+// config file. It runs exactly the same checks as loading a config file does.
+func (s *Spec) Validate() error {
+	var validated Spec
+	err := roundTrip(s, &validated)
+	if err != nil {
+		return fmt.Errorf("invalid spec: %w", err)
+	}
+	return nil
+}
+
+// roundTrip marshals 'in' to YAML and unmarshals it into 'out', so that 'out'
+// is validated the same way as if it was read from a config file.
+func roundTrip(in interface{}, out interface{}) error {
+	b, err := yaml.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("error marshaling: %w", err)
+	}
+	return yaml.Unmarshal(b, out)
+}
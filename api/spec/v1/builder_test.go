@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestBuildSpec(t *testing.T) {
+	spec := NewSpec()
+
+	require.Nil(t, spec.AddNamedConfig("all-disabled"))
+	require.Nil(t, spec.AddDeviceEntry("all-disabled", MigConfigSpec{
+		Devices:    "all",
+		MigEnabled: false,
+	}))
+
+	require.Nil(t, spec.AddNamedConfig("custom"))
+	require.Nil(t, spec.AddDeviceEntry("custom", MigConfigSpec{
+		DeviceFilter: []string{"0x20B010DE"},
+		Devices:      []int{0, 1},
+		MigEnabled:   true,
+		MigDevices:   types.MigConfig{"1g.5gb": 7},
+	}))
+	require.Nil(t, spec.AddDeviceEntry("custom", MigConfigSpec{
+		Devices:    []int{2, 3},
+		MigEnabled: false,
+	}))
+
+	require.Nil(t, spec.Validate(), "Unexpected failure from Validate")
+
+	b, err := yaml.Marshal(spec)
+	require.Nil(t, err)
+
+	var parsed Spec
+	err = yaml.Unmarshal(b, &parsed)
+	require.Nil(t, err, "Unexpected failure loading built spec")
+	require.Equal(t, *spec, parsed)
+}
+
+func TestAddNamedConfig(t *testing.T) {
+	spec := &Spec{Version: Version}
+	require.Nil(t, spec.AddNamedConfig("custom"), "Unexpected failure on spec without configs")
+	require.NotNil(t, spec.AddNamedConfig("custom"), "Unexpected success adding duplicate config")
+	require.NotNil(t, spec.AddNamedConfig(""), "Unexpected success adding unnamed config")
+}
+
+func TestAddDeviceEntry(t *testing.T) {
+	testCases := []struct {
+		description string
+		config      string
+		entry       MigConfigSpec
+		valid       bool
+	}{
+		{
+			"unknown config",
+			"missing",
+			MigConfigSpec{Devices: "all"},
+			false,
+		},
+		{
+			"invalid devices keyword",
+			"custom",
+			MigConfigSpec{Devices: "some"},
+			false,
+		},
+		{
+			"missing devices",
+			"custom",
+			MigConfigSpec{MigEnabled: false},
+			false,
+		},
+		{
+			"MIG devices without MIG enabled",
+			"custom",
+			MigConfigSpec{Devices: "all", MigDevices: types.MigConfig{"1g.5gb": 1}},
+			false,
+		},
+		{
+			"invalid MIG device profile",
+			"custom",
+			MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"bogus": 1}},
+			false,
+		},
+		{
+			"MIG enabled without MIG devices",
+			"custom",
+			MigConfigSpec{Devices: "all", MigEnabled: true},
+			false,
+		},
+		{
+			"valid fill entry",
+			"custom",
+			MigConfigSpec{Devices: "all", MigEnabled: true, Fill: "1g.5gb"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			spec := NewSpec()
+			require.Nil(t, spec.AddNamedConfig("custom"))
+
+			err := spec.AddDeviceEntry(tc.config, tc.entry)
+			if !tc.valid {
+				require.NotNil(t, err, "Unexpected success from AddDeviceEntry")
+				require.Len(t, spec.MigConfigs["custom"], 0)
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from AddDeviceEntry")
+			require.Len(t, spec.MigConfigs["custom"], 1)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	spec := NewSpec()
+	require.Nil(t, spec.AddNamedConfig("empty"))
+	require.NotNil(t, spec.Validate(), "Unexpected success validating config without entries")
+
+	spec = NewSpec()
+	spec.Version = "v2"
+	require.NotNil(t, spec.Validate(), "Unexpected success validating unknown version")
+
+	spec = NewSpec()
+	spec.MigConfigs["hand-built"] = MigConfigSpecSlice{{Devices: []int{0}, MigEnabled: false, MigDevices: types.MigConfig{"1g.5gb": 1}}}
+	require.NotNil(t, spec.Validate(), "Unexpected success validating invalid hand-built entry")
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// checksumLength is the number of bytes of the SHA-256 hash of a MIG config
+// kept in its checksum. Its hex encoding fits in a Kubernetes label value.
+const checksumLength = 16
+
+// Checksum returns a hash of the contents of 'ms'. It changes whenever the
+// meaning of the MIG config does, but not with the formatting of (or the
+// comments in) the file it was read from, nor with the order of the MIG
+// profiles in its entries.
+func (ms MigConfigSpecSlice) Checksum() (string, error) {
+	contents, err := json.Marshal(ms)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:checksumLength]), nil
+}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestChecksum(t *testing.T) {
+	base := `version: v1
+mig-configs:
+  mixed:
+    - devices: all
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 2
+        "3g.20gb": 1
+`
+
+	testCases := []struct {
+		description string
+		spec        string
+		same        bool
+	}{
+		{
+			"Same config",
+			base,
+			true,
+		},
+		{
+			"Formatting, comments and profile order changed",
+			`version: v1
+# Comments do not change the meaning of a config.
+mig-configs:
+  mixed:
+  - devices: all
+    mig-enabled: true
+    mig-devices: {"3g.20gb": 1, "1g.5gb": 2}
+`,
+			true,
+		},
+		{
+			"Number of MIG devices changed",
+			`version: v1
+mig-configs:
+  mixed:
+    - devices: all
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 1
+        "3g.20gb": 1
+`,
+			false,
+		},
+		{
+			"Devices changed",
+			`version: v1
+mig-configs:
+  mixed:
+    - devices: [0]
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 2
+        "3g.20gb": 1
+`,
+			false,
+		},
+	}
+
+	checksum := func(s string) string {
+		var spec Spec
+		require.Nil(t, yaml.Unmarshal([]byte(s), &spec))
+		sum, err := spec.MigConfigs["mixed"].Checksum()
+		require.Nil(t, err)
+		return sum
+	}
+
+	expected := checksum(base)
+	require.Len(t, expected, 2*checksumLength)
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			if tc.same {
+				require.Equal(t, expected, checksum(tc.spec))
+			} else {
+				require.NotEqual(t, expected, checksum(tc.spec))
+			}
+		})
+	}
+}
@@ -17,13 +17,157 @@
 package v1
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 // MatchesDeviceFilter checks a 'MigConfigSpec' to see if its device filter matches the provided 'deviceID'.
 func (ms *MigConfigSpec) MatchesDeviceFilter(deviceID types.DeviceID) bool {
+	return matchesDeviceFilter(ms.DeviceFilter, deviceID)
+}
+
+// MatchesAllDevices checks a 'MigConfigSpec' to see if it matches on 'all' devices.
+func (ms *MigConfigSpec) MatchesAllDevices() bool {
+	return matchesAllDevices(ms.Devices)
+}
+
+// MatchesDevices checks a 'MigConfigSpec' to see if it matches on a device at the specified 'index'.
+func (ms *MigConfigSpec) MatchesDevices(index int) bool {
+	return matchesDevices(ms.Devices, index)
+}
+
+// ExplicitlyMatchesDevice checks a 'MigConfigSpec' to see if it lists the device at the specified 'index' by name
+// (rather than matching it through the special keyword 'all').
+func (ms *MigConfigSpec) ExplicitlyMatchesDevice(index int) bool {
+	return !ms.MatchesAllDevices() && ms.MatchesDevices(index)
+}
+
+// EnablesMigOnly checks a 'MigConfigSpec' to see if it enables MIG mode without creating any MIG devices (i.e. it has
+// 'mig-enabled: true' with 'mig-devices: {}' and no fill profile), e.g. to leave them to be created dynamically later.
+func (ms *MigConfigSpec) EnablesMigOnly() bool {
+	return ms.MigEnabled && len(ms.MigDevices) == 0 && ms.Fill == ""
+}
+
+// HasUUIDOverrides checks a 'MigConfigSpec' to see if any of its overrides are keyed by GPU UUID.
+func (ms *MigConfigSpec) HasUUIDOverrides() bool {
+	for key := range ms.Overrides {
+		if _, isIndex, _ := parseOverrideKey(key); !isIndex {
+			return true
+		}
+	}
+	return false
+}
+
+// ForDevice returns a copy of a 'MigConfigSpec' with the override for the device at the specified 'index' (or with
+// the specified 'uuid') applied. An override keyed by UUID takes precedence over one keyed by index. The returned
+// copy has no overrides of its own.
+func (ms *MigConfigSpec) ForDevice(index int, uuid string) MigConfigSpec {
+	result := *ms
+	result.Overrides = nil
+
+	override, exists := ms.Overrides[uuid]
+	if !exists || uuid == "" {
+		override, exists = ms.Overrides[fmt.Sprintf("%d", index)]
+	}
+	if !exists {
+		return result
+	}
+
+	result.MigEnabled = override.MigEnabled
+	result.MigDevices = override.MigDevices
+	result.Fill = override.Fill
+	return result
+}
+
+// HasProfileIDs checks if any MIG profile in a 'MigConfigSpec' (or its fill profile) is referenced by its numeric
+// profile ID rather than by its name.
+func (ms *MigConfigSpec) HasProfileIDs() bool {
+	return ms.MigDevices.HasProfileIDs() || types.IsMigProfileID(ms.Fill)
+}
+
+// ResolveProfileIDs replaces every MIG profile in a 'MigConfigSpec' (and its fill profile) that is referenced by its
+// numeric profile ID with its name, as translated by 'ids'. It is meant to be called on the result of 'ForDevice' with
+// the 'MigProfileIDs' of that device.
+func (ms *MigConfigSpec) ResolveProfileIDs(ids types.MigProfileIDs) error {
+	devices, err := ms.MigDevices.ResolveProfileIDs(ids)
+	if err != nil {
+		return err
+	}
+	fill, err := ids.Name(ms.Fill)
+	if err != nil {
+		return err
+	}
+	if ms.MigDevices != nil {
+		ms.MigDevices = devices
+	}
+	ms.Fill = fill
+	return nil
+}
+
+// HasFractionalProfiles checks if any MIG profile in a 'MigConfigSpec' (or its fill profile) asks for a fraction of a
+// GPU rather than for a MIG profile by name.
+func (ms *MigConfigSpec) HasFractionalProfiles() bool {
+	return ms.MigDevices.HasFractionalProfiles() || types.IsFractionalMigProfile(ms.Fill)
+}
+
+// ResolveFractionalProfiles replaces every MIG profile in a 'MigConfigSpec' (and its fill profile) that asks for a
+// fraction of a GPU with the name of the profile among 'mps' it resolves to. It is meant to be called on the result of
+// 'ForDevice' with the MIG profiles supported by that device.
+func (ms *MigConfigSpec) ResolveFractionalProfiles(mps []*types.MigProfile) error {
+	devices, err := ms.MigDevices.ResolveFractionalProfiles(mps)
+	if err != nil {
+		return err
+	}
+	if types.IsFractionalMigProfile(ms.Fill) {
+		mp, err := types.ResolveFractionalMigProfile(ms.Fill, mps)
+		if err != nil {
+			return err
+		}
+		ms.Fill = mp.String()
+	}
+	if ms.MigDevices != nil {
+		ms.MigDevices = devices
+	}
+	return nil
+}
+
+// Matches checks an 'UnmanagedDeviceSpec' to see if it selects the device at the specified 'index' with 'deviceID'.
+func (us *UnmanagedDeviceSpec) Matches(index int, deviceID types.DeviceID) bool {
+	return matchesDeviceFilter(us.DeviceFilter, deviceID) && matchesDevices(us.Devices, index)
+}
+
+// Matches checks an 'UnmanagedDeviceSpecSlice' to see if any of its entries select the device at the specified
+// 'index' with 'deviceID'.
+func (us UnmanagedDeviceSpecSlice) Matches(index int, deviceID types.DeviceID) bool {
+	for _, u := range us {
+		if u.Matches(index, deviceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches checks a 'ComputeInstanceConfigSpec' to see if it selects the device at the specified 'index' with 'deviceID'.
+func (cs *ComputeInstanceConfigSpec) Matches(index int, deviceID types.DeviceID) bool {
+	return matchesDeviceFilter(cs.DeviceFilter, deviceID) && matchesDevices(cs.Devices, index)
+}
+
+// Select returns the 'ComputeInstanceLayer' of the first entry in a 'ComputeInstanceConfigSpecSlice' that selects
+// the device at the specified 'index' with 'deviceID', or nil if there is none.
+func (cs ComputeInstanceConfigSpecSlice) Select(index int, deviceID types.DeviceID) types.ComputeInstanceLayer {
+	for i := range cs {
+		if cs[i].Matches(index, deviceID) {
+			return cs[i].ComputeInstances
+		}
+	}
+	return nil
+}
+
+func matchesDeviceFilter(filter interface{}, deviceID types.DeviceID) bool {
 	var deviceFilter []string
-	switch df := ms.DeviceFilter.(type) {
+	switch df := filter.(type) {
 	case string:
 		if df != "" {
 			deviceFilter = append(deviceFilter, df)
@@ -46,22 +190,77 @@ func (ms *MigConfigSpec) MatchesDeviceFilter(deviceID types.DeviceID) bool {
 	return false
 }
 
-// MatchesAllDevices checks a 'MigConfigSpec' to see if it matches on 'all' devices.
-func (ms *MigConfigSpec) MatchesAllDevices() bool {
-	if devices, ok := ms.Devices.(string); ok {
+func matchesAllDevices(devices interface{}) bool {
+	if devices, ok := devices.(string); ok {
 		return devices == "all"
 	}
 	return false
 }
 
-// MatchesDevices checks a 'MigConfigSpec' to see if it matches on a device at the specified 'index'.
-func (ms *MigConfigSpec) MatchesDevices(index int) bool {
-	if devices, ok := ms.Devices.([]int); ok {
+func matchesDevices(devices interface{}, index int) bool {
+	if devices, ok := devices.([]int); ok {
 		for _, d := range devices {
 			if index == d {
 				return true
 			}
 		}
 	}
-	return ms.MatchesAllDevices()
+	return matchesAllDevices(devices)
+}
+
+// AssertStrictProfiles checks that every MIG profile in the spec is written in
+// its canonical form (see types.AssertStrictMigProfileFormat). Configs are
+// checked in order of name so that the first error reported is stable.
+func (s *Spec) AssertStrictProfiles() error {
+	var names []string
+	for name := range s.MigConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for i, mc := range s.MigConfigs[name] {
+			err := mc.MigDevices.AssertStrictFormat()
+			if err != nil {
+				return fmt.Errorf("mig-configs '%v' entry %d: mig-devices: %w", name, i, err)
+			}
+			if mc.Fill != "" {
+				err := types.AssertStrictMigProfileFormat(mc.Fill)
+				if err != nil {
+					return fmt.Errorf("mig-configs '%v' entry %d: fill: %w", name, i, err)
+				}
+			}
+			var keys []string
+			for key := range mc.Overrides {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				o := mc.Overrides[key]
+				err := o.MigDevices.AssertStrictFormat()
+				if err != nil {
+					return fmt.Errorf("mig-configs '%v' entry %d: overrides '%v': mig-devices: %w", name, i, key, err)
+				}
+				if o.Fill != "" {
+					err := types.AssertStrictMigProfileFormat(o.Fill)
+					if err != nil {
+						return fmt.Errorf("mig-configs '%v' entry %d: overrides '%v': fill: %w", name, i, key, err)
+					}
+				}
+			}
+		}
+	}
+	names = nil
+	for name := range s.ComputeInstanceConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for i, cs := range s.ComputeInstanceConfigs[name] {
+			err := cs.ComputeInstances.AssertStrictFormat()
+			if err != nil {
+				return fmt.Errorf("compute-instance-configs '%v' entry %d: compute-instances: %w", name, i, err)
+			}
+		}
+	}
+	return nil
 }
@@ -0,0 +1,46 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+// Merge merges the 'fragments' of a spec into a single 'Spec', in order. A
+// 'mig-configs' or 'compute-instance-configs' entry replaces the entry of the
+// same name from any earlier fragment as a whole, while the
+// 'unmanaged-devices' of all fragments accumulate. The 'mig-strategy' of the
+// last fragment declaring one wins. The fragments themselves are left
+// untouched.
+func Merge(fragments ...*Spec) *Spec {
+	merged := &Spec{Version: Version}
+	for _, fragment := range fragments {
+		merged.UnmanagedDevices = append(merged.UnmanagedDevices, fragment.UnmanagedDevices...)
+		if fragment.MigStrategy != "" {
+			merged.MigStrategy = fragment.MigStrategy
+		}
+		for name, config := range fragment.MigConfigs {
+			if merged.MigConfigs == nil {
+				merged.MigConfigs = make(map[string]MigConfigSpecSlice)
+			}
+			merged.MigConfigs[name] = config
+		}
+		for name, config := range fragment.ComputeInstanceConfigs {
+			if merged.ComputeInstanceConfigs == nil {
+				merged.ComputeInstanceConfigs = make(map[string]ComputeInstanceConfigSpecSlice)
+			}
+			merged.ComputeInstanceConfigs[name] = config
+		}
+	}
+	return merged
+}
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestMerge(t *testing.T) {
+	fragments := []string{
+		`version: v1
+unmanaged-devices:
+  - devices: [0]
+mig-configs:
+  all-disabled:
+    - devices: all
+      mig-enabled: false
+  all-1g.5gb:
+    - devices: all
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 7
+`,
+		`version: v1
+mig-strategy: mixed
+unmanaged-devices:
+  - devices: [1]
+mig-configs:
+  all-1g.5gb:
+    - devices: all
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 4
+compute-instance-configs:
+  split:
+    - devices: all
+      compute-instances:
+        "3g.20gb":
+          "1c.3g.20gb": 1
+          "2c.3g.20gb": 1
+`,
+	}
+
+	var specs []*Spec
+	for _, f := range fragments {
+		var spec Spec
+		require.Nil(t, yaml.Unmarshal([]byte(f), &spec))
+		specs = append(specs, &spec)
+	}
+
+	merged := Merge(specs...)
+
+	require.Equal(t, Version, merged.Version)
+	require.Len(t, merged.UnmanagedDevices, 2)
+	require.Len(t, merged.MigConfigs, 2)
+	require.Equal(t, specs[0].MigConfigs["all-disabled"], merged.MigConfigs["all-disabled"])
+	require.Equal(t, specs[1].MigConfigs["all-1g.5gb"], merged.MigConfigs["all-1g.5gb"], "Later fragment should replace config")
+	require.Equal(t, specs[1].ComputeInstanceConfigs, merged.ComputeInstanceConfigs)
+	require.Equal(t, MigStrategyMixed, merged.MigStrategy)
+
+	require.Len(t, specs[0].UnmanagedDevices, 1, "Fragment modified by Merge")
+	require.Len(t, specs[0].MigConfigs, 2, "Fragment modified by Merge")
+}
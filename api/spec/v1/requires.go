@@ -0,0 +1,181 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RequiresSpec holds the minimum versions of the driver, CUDA and VBIOS that
+// the GPUs selected by a 'MigConfigSpec' must have. Each version is written
+// as e.g. '>=535.104.05', where the leading '>=' is optional. Empty versions
+// are not checked.
+type RequiresSpec struct {
+	Driver string `json:"driver,omitempty" yaml:"driver,omitempty"`
+	CUDA   string `json:"cuda,omitempty"   yaml:"cuda,omitempty"`
+	VBIOS  string `json:"vbios,omitempty"  yaml:"vbios,omitempty"`
+}
+
+// InstalledVersions holds the versions found on a GPU that a 'RequiresSpec'
+// is checked against.
+type InstalledVersions struct {
+	Driver string
+	CUDA   string
+	VBIOS  string
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'RequiresSpec'.
+func (s *RequiresSpec) UnmarshalJSON(b []byte) error {
+	spec := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return err
+	}
+
+	if len(spec) == 0 {
+		return fmt.Errorf("at least one entry in 'requires' is required")
+	}
+
+	result := RequiresSpec{}
+	for k, v := range spec {
+		var version string
+		err := json.Unmarshal(v, &version)
+		if err != nil {
+			return err
+		}
+		_, err = parseMinVersion(version)
+		if err != nil {
+			return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+		}
+		switch k {
+		case "driver":
+			result.Driver = version
+		case "cuda":
+			result.CUDA = version
+		case "vbios":
+			result.VBIOS = version
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*s = result
+	return nil
+}
+
+// Check checks the versions in 'installed' against those required by 's'.
+// Every version that is too old (or could not be found) is reported in the
+// error returned.
+func (s *RequiresSpec) Check(installed InstalledVersions) error {
+	var failures []string
+	for _, r := range []struct {
+		name      string
+		required  string
+		installed string
+	}{
+		{"driver", s.Driver, installed.Driver},
+		{"CUDA", s.CUDA, installed.CUDA},
+		{"VBIOS", s.VBIOS, installed.VBIOS},
+	} {
+		if r.required == "" {
+			continue
+		}
+		err := checkMinVersion(r.name, r.required, r.installed)
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// checkMinVersion checks that the 'installed' version of 'name' is at least
+// the 'required' one.
+func checkMinVersion(name, required, installed string) error {
+	required = trimMinVersion(required)
+	min, err := parseVersion(required)
+	if err != nil {
+		return fmt.Errorf("invalid required %v version: %v", name, err)
+	}
+	if installed == "" {
+		return fmt.Errorf("%v >= %v required, but the installed %v version is unknown", name, required, name)
+	}
+	found, err := parseVersion(installed)
+	if err != nil {
+		return fmt.Errorf("%v >= %v required, but the installed %v version is unknown: %v", name, required, name, err)
+	}
+	if compareVersions(found, min) < 0 {
+		return fmt.Errorf("%v >= %v required, but %v is installed", name, required, installed)
+	}
+	return nil
+}
+
+// parseMinVersion parses a minimum version, optionally written with a
+// leading '>='.
+func parseMinVersion(s string) ([]uint64, error) {
+	return parseVersion(trimMinVersion(s))
+}
+
+func trimMinVersion(s string) string {
+	return strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(s), ">="))
+}
+
+// parseVersion parses a version made of '.' separated components. The
+// components are parsed as hexadecimal numbers, which orders the decimal
+// components of driver and CUDA versions exactly as parsing them as decimal
+// numbers would, and also handles the hexadecimal components of VBIOS
+// versions (e.g. '96.00.5E.00.01').
+func parseVersion(s string) ([]uint64, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty version")
+	}
+	var version []uint64
+	for _, c := range strings.Split(s, ".") {
+		v, err := strconv.ParseUint(c, 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed version '%v'", s)
+		}
+		version = append(version, v)
+	}
+	return version, nil
+}
+
+// compareVersions returns -1, 0 or 1 if 'a' is older than, the same as, or
+// newer than 'b'. Missing trailing components count as zero.
+func compareVersions(a, b []uint64) int {
+	for i := 0; i < len(a) || i < len(b); i++ {
+		var x, y uint64
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x < y {
+			return -1
+		}
+		if x > y {
+			return 1
+		}
+	}
+	return 0
+}
@@ -19,6 +19,11 @@ package v1
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
@@ -26,18 +31,107 @@ import (
 // Version indicates the version of the 'Spec' struct used to hold information on 'MigConfigs'.
 const Version = "v1"
 
+// ProfilesPerGpuModel is the name of the shorthand section mapping GPU models
+// to the names of 'MigConfigs', as well as the name of the 'MigConfigs' entry
+// it expands into. Each GPU model is given the entries of the config it maps
+// to, restricted to that model with a device filter.
+const ProfilesPerGpuModel = "profiles-per-gpu-model"
+
 // Spec is a versioned struct used to hold information on 'MigConfigs'.
 type Spec struct {
-	Version    string                        `json:"version"               yaml:"version"`
-	MigConfigs map[string]MigConfigSpecSlice `json:"mig-configs,omitempty" yaml:"mig-configs,omitempty"`
+	Version                string                                    `json:"version"                            yaml:"version"`
+	UnmanagedDevices       UnmanagedDeviceSpecSlice                  `json:"unmanaged-devices,omitempty"        yaml:"unmanaged-devices,omitempty"`
+	MigConfigs             map[string]MigConfigSpecSlice             `json:"mig-configs,omitempty"              yaml:"mig-configs,omitempty"`
+	ComputeInstanceConfigs map[string]ComputeInstanceConfigSpecSlice `json:"compute-instance-configs,omitempty" yaml:"compute-instance-configs,omitempty"`
+
+	// MigStrategy declares the MIG strategy of the Kubernetes device plugin
+	// the 'MigConfigs' are meant for (one of 'none', 'single' or 'mixed'),
+	// which 'lint' checks them against.
+	MigStrategy string `json:"mig-strategy,omitempty" yaml:"mig-strategy,omitempty"`
 }
 
+// UnmanagedDeviceSpec selects a set of GPUs that must never be modified,
+// regardless of which 'MigConfigs' entry is selected.
+type UnmanagedDeviceSpec struct {
+	DeviceFilter interface{} `json:"device-filter,omitempty" yaml:"device-filter,flow,omitempty"`
+	Devices      interface{} `json:"devices"                 yaml:"devices,flow"`
+}
+
+// UnmanagedDeviceSpecSlice represents a slice of 'UnmanagedDeviceSpec'.
+type UnmanagedDeviceSpecSlice []UnmanagedDeviceSpec
+
 // MigConfigSpec defines the spec to declare the desired MIG configuration for a set of GPUs.
 type MigConfigSpec struct {
 	DeviceFilter interface{}     `json:"device-filter,omitempty" yaml:"device-filter,flow,omitempty"`
 	Devices      interface{}     `json:"devices"                 yaml:"devices,flow"`
 	MigEnabled   bool            `json:"mig-enabled"             yaml:"mig-enabled"`
 	MigDevices   types.MigConfig `json:"mig-devices"             yaml:"mig-devices"`
+	Fill         string          `json:"fill,omitempty"          yaml:"fill,omitempty"`
+
+	PermutationBudget *PermutationBudgetSpec `json:"permutation-budget,omitempty" yaml:"permutation-budget,omitempty"`
+
+	// Placement chooses where GPU instances are placed when more than one
+	// placement would work (one of 'packed', 'balanced' or 'high-first').
+	Placement types.PlacementPreference `json:"placement,omitempty" yaml:"placement,omitempty"`
+
+	// PlacementExclusions reserve ranges of slices from the GPU instances of
+	// some profiles (e.g. keeping slices 0-1 free of '1g.10gb' instances for
+	// a '3g.40gb' one added later). Placements are never chosen by NVML for a
+	// config with exclusions, as 'apply' otherwise falls back to doing.
+	PlacementExclusions []types.PlacementExclusion `json:"placement-exclusions,omitempty" yaml:"placement-exclusions,omitempty"`
+
+	// PersistenceMode is the persistence mode the selected GPUs are expected
+	// to be in. It is not changed by 'apply' (that is left to e.g.
+	// nvidia-persistenced), only checked by 'assert --full'.
+	PersistenceMode *bool `json:"persistence-mode,omitempty" yaml:"persistence-mode,omitempty"`
+
+	// MPS marks the MIG devices of the selected GPUs as shared through MPS,
+	// so that 'apply' hands them to whatever starts the MPS control daemons.
+	MPS bool `json:"mps,omitempty" yaml:"mps,omitempty"`
+
+	// CCMode is the confidential computing mode of the selected GPUs (one of
+	// 'on', 'off' or 'devtools'), asserted and applied together with their
+	// MIG mode. MIG can only be enabled with CC mode 'off'.
+	CCMode types.CCMode `json:"cc-mode,omitempty" yaml:"cc-mode,omitempty"`
+
+	// Requires holds the minimum driver, CUDA and VBIOS versions the selected
+	// GPUs must have for the entry to be applied (or asserted) at all.
+	Requires *RequiresSpec `json:"requires,omitempty" yaml:"requires,omitempty"`
+
+	Overrides map[string]MigConfigOverrideSpec `json:"overrides,omitempty" yaml:"overrides,omitempty"`
+
+	// Labels are arbitrary metadata (e.g. 'team: vision') recorded for the
+	// selected GPUs when the entry is applied, to track the intent behind
+	// their GPU instances. They are kept on the node, never on the GPUs.
+	Labels map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// MigConfigOverrideSpec replaces the MIG settings of a 'MigConfigSpec' for a
+// single GPU. Overrides are keyed by GPU index (e.g. "0") or by GPU UUID
+// (e.g. "GPU-5f6c...") and only apply to GPUs the enclosing entry selects.
+type MigConfigOverrideSpec struct {
+	MigEnabled bool            `json:"mig-enabled"    yaml:"mig-enabled"`
+	MigDevices types.MigConfig `json:"mig-devices"    yaml:"mig-devices"`
+	Fill       string          `json:"fill,omitempty" yaml:"fill,omitempty"`
+}
+
+// ComputeInstanceConfigSpec defines how the GPU instances on a set of GPUs
+// are split into compute instances. It is applied on top of a 'MigConfigSpec'
+// (possibly from a different file) that defines the GPU instances themselves.
+type ComputeInstanceConfigSpec struct {
+	DeviceFilter     interface{}                `json:"device-filter,omitempty" yaml:"device-filter,flow,omitempty"`
+	Devices          interface{}                `json:"devices"                 yaml:"devices,flow"`
+	ComputeInstances types.ComputeInstanceLayer `json:"compute-instances"       yaml:"compute-instances"`
+}
+
+// ComputeInstanceConfigSpecSlice represents a slice of 'ComputeInstanceConfigSpec'.
+type ComputeInstanceConfigSpecSlice []ComputeInstanceConfigSpec
+
+// PermutationBudgetSpec bounds how many orderings of the MIG devices in a
+// 'MigConfigSpec' are tried (and for how long) before applying it fails.
+type PermutationBudgetSpec struct {
+	MaxAttempts int    `json:"max-attempts,omitempty" yaml:"max-attempts,omitempty"`
+	Timeout     string `json:"timeout,omitempty"      yaml:"timeout,omitempty"`
 }
 
 // MigConfigSpecSlice represents a slice of 'MigConfigSpec'.
@@ -72,6 +166,7 @@ func (s *Spec) UnmarshalJSON(b []byte) error {
 	}
 
 	delete(spec, "version")
+	var perGpuModel map[string]string
 	for k, v := range spec {
 		switch k {
 		case "mig-configs":
@@ -89,15 +184,118 @@ func (s *Spec) UnmarshalJSON(b []byte) error {
 				}
 			}
 			result.MigConfigs = configs
+		case "compute-instance-configs":
+			configs := map[string]ComputeInstanceConfigSpecSlice{}
+			err := json.Unmarshal(v, &configs)
+			if err != nil {
+				return err
+			}
+			if len(configs) == 0 {
+				return fmt.Errorf("at least one entry in '%v' is required", k)
+			}
+			for c, s := range configs {
+				if len(s) == 0 {
+					return fmt.Errorf("at least one entry in '%v' is required", c)
+				}
+			}
+			result.ComputeInstanceConfigs = configs
+		case "unmanaged-devices":
+			var unmanaged UnmanagedDeviceSpecSlice
+			err := json.Unmarshal(v, &unmanaged)
+			if err != nil {
+				return err
+			}
+			result.UnmanagedDevices = unmanaged
+		case "mig-strategy":
+			var strategy string
+			err := json.Unmarshal(v, &strategy)
+			if err != nil {
+				return err
+			}
+			err = AssertValidMigStrategy(strategy)
+			if err != nil {
+				return err
+			}
+			result.MigStrategy = strategy
+		case ProfilesPerGpuModel:
+			err := json.Unmarshal(v, &perGpuModel)
+			if err != nil {
+				return err
+			}
+			if len(perGpuModel) == 0 {
+				return fmt.Errorf("at least one entry in '%v' is required", k)
+			}
 		default:
 			return fmt.Errorf("unexpected field: %v", k)
 		}
 	}
 
+	if perGpuModel != nil {
+		expanded, err := expandProfilesPerGpuModel(perGpuModel, result.MigConfigs)
+		if err != nil {
+			return fmt.Errorf("error expanding '%v': %v", ProfilesPerGpuModel, err)
+		}
+		if _, exists := result.MigConfigs[ProfilesPerGpuModel]; exists {
+			return fmt.Errorf("'mig-configs' entry '%v' conflicts with the '%v' section", ProfilesPerGpuModel, ProfilesPerGpuModel)
+		}
+		if result.MigConfigs == nil {
+			result.MigConfigs = make(map[string]MigConfigSpecSlice)
+		}
+		result.MigConfigs[ProfilesPerGpuModel] = expanded
+	}
+
 	*s = result
 	return nil
 }
 
+// expandProfilesPerGpuModel expands a mapping of GPU models to the names of
+// 'configs' into a single 'MigConfigSpecSlice'. The entries of each config are
+// copied with their device filter narrowed down to the device IDs of the GPU
+// model; entries whose device filter excludes the model altogether are
+// dropped. Models are expanded in order of name so that the result is stable.
+func expandProfilesPerGpuModel(perGpuModel map[string]string, configs map[string]MigConfigSpecSlice) (MigConfigSpecSlice, error) {
+	var models []string
+	for model := range perGpuModel {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	var expanded MigConfigSpecSlice
+	for _, model := range models {
+		name := perGpuModel[model]
+		config, exists := configs[name]
+		if !exists {
+			return nil, fmt.Errorf("GPU model '%v' maps to unknown config '%v'", model, name)
+		}
+
+		deviceIDs, err := types.GetGpuModelDeviceIDs(model)
+		if err != nil {
+			return nil, err
+		}
+
+		matched := 0
+		for _, mc := range config {
+			var filter []string
+			for _, id := range deviceIDs {
+				if mc.MatchesDeviceFilter(id) {
+					filter = append(filter, id.String())
+				}
+			}
+			if len(filter) == 0 {
+				continue
+			}
+			mc.DeviceFilter = filter
+			expanded = append(expanded, mc)
+			matched++
+		}
+		if matched == 0 {
+			return nil, fmt.Errorf("config '%v' has no entries for GPU model '%v'", name, model)
+		}
+	}
+
+	return expanded, nil
+}
+
 // UnmarshalJSON unmarshals raw bytes into a 'MigConfigSpec'.
 func (s *MigConfigSpec) UnmarshalJSON(b []byte) error {
 	spec := make(map[string]json.RawMessage)
@@ -117,36 +315,187 @@ func (s *MigConfigSpec) UnmarshalJSON(b []byte) error {
 	for k, v := range spec {
 		switch k {
 		case "device-filter":
-			var str string
-			err1 := json.Unmarshal(v, &str)
-			if err1 == nil {
-				result.DeviceFilter = str
-				break
-			}
-			var strslice []string
-			err2 := json.Unmarshal(v, &strslice)
-			if err2 == nil {
-				result.DeviceFilter = strslice
-				break
-			}
-			return fmt.Errorf("(%v, %v)", err1, err2)
+			result.DeviceFilter, err = unmarshalDeviceFilter(v)
+			if err != nil {
+				return err
+			}
 		case "devices":
-			var str string
-			err1 := json.Unmarshal(v, &str)
-			if err1 == nil {
-				if str != "all" {
-					return fmt.Errorf("invalid string input for '%v': %v", k, str)
+			result.Devices, err = unmarshalDevices(k, v)
+			if err != nil {
+				return err
+			}
+		case "mig-enabled":
+			var enabled bool
+			err := json.Unmarshal(v, &enabled)
+			if err != nil {
+				return err
+			}
+			result.MigEnabled = enabled
+		case "mig-devices":
+			devices := make(types.MigConfig)
+			err := json.Unmarshal(v, &devices)
+			if err != nil {
+				return err
+			}
+			err = devices.AssertValidFormat()
+			if err != nil {
+				return fmt.Errorf("error validating values in '%v' field: %v", k, err)
+			}
+			result.MigDevices = devices
+		case "fill":
+			var fill string
+			err := json.Unmarshal(v, &fill)
+			if err != nil {
+				return err
+			}
+			err = types.AssertValidMigProfileFormat(fill)
+			if err != nil {
+				return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+			}
+			result.Fill = fill
+		case "permutation-budget":
+			var budget PermutationBudgetSpec
+			err := json.Unmarshal(v, &budget)
+			if err != nil {
+				return err
+			}
+			result.PermutationBudget = &budget
+		case "placement":
+			var placement types.PlacementPreference
+			err := json.Unmarshal(v, &placement)
+			if err != nil {
+				return err
+			}
+			err = placement.AssertValid()
+			if err != nil {
+				return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+			}
+			result.Placement = placement
+		case "placement-exclusions":
+			var exclusions []types.PlacementExclusion
+			err := json.Unmarshal(v, &exclusions)
+			if err != nil {
+				return err
+			}
+			if len(exclusions) == 0 {
+				return fmt.Errorf("at least one entry in '%v' is required", k)
+			}
+			for _, e := range exclusions {
+				err := e.AssertValid()
+				if err != nil {
+					return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+				}
+			}
+			result.PlacementExclusions = exclusions
+		case "persistence-mode":
+			var enabled bool
+			err := json.Unmarshal(v, &enabled)
+			if err != nil {
+				return err
+			}
+			result.PersistenceMode = &enabled
+		case "mps":
+			var mps bool
+			err := json.Unmarshal(v, &mps)
+			if err != nil {
+				return err
+			}
+			result.MPS = mps
+		case "cc-mode":
+			// An unquoted 'on' or 'off' is a boolean in YAML.
+			var ccMode types.CCMode
+			var enabled bool
+			if json.Unmarshal(v, &enabled) == nil {
+				ccMode = types.CCOff
+				if enabled {
+					ccMode = types.CCOn
+				}
+			} else {
+				err := json.Unmarshal(v, &ccMode)
+				if err != nil {
+					return err
 				}
-				result.Devices = str
-				break
 			}
-			var intslice []int
-			err2 := json.Unmarshal(v, &intslice)
-			if err2 == nil {
-				result.Devices = intslice
-				break
+			err := ccMode.AssertValid()
+			if err != nil {
+				return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+			}
+			result.CCMode = ccMode
+		case "requires":
+			var requires RequiresSpec
+			err := json.Unmarshal(v, &requires)
+			if err != nil {
+				return err
+			}
+			result.Requires = &requires
+		case "overrides":
+			overrides := make(map[string]MigConfigOverrideSpec)
+			err := json.Unmarshal(v, &overrides)
+			if err != nil {
+				return err
+			}
+			if len(overrides) == 0 {
+				return fmt.Errorf("at least one entry in '%v' is required", k)
 			}
-			return fmt.Errorf("(%v, %v)", err1, err2)
+			result.Overrides = overrides
+		case "labels":
+			labels := make(map[string]string)
+			err := json.Unmarshal(v, &labels)
+			if err != nil {
+				return err
+			}
+			err = assertValidLabels(labels)
+			if err != nil {
+				return fmt.Errorf("error validating values in '%v' field: %v", k, err)
+			}
+			result.Labels = labels
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	err = assertValidMigSettings(result.MigEnabled, result.MigDevices, result.Fill)
+	if err != nil {
+		return err
+	}
+
+	if result.MPS && !result.MigEnabled {
+		return fmt.Errorf("'mps' requires 'mig-enabled' to be true")
+	}
+
+	if result.CCMode != "" && !result.CCMode.SupportsMig() && result.MigEnabled {
+		return fmt.Errorf("'mig-enabled' cannot be true with 'cc-mode' %v", result.CCMode)
+	}
+
+	for key := range result.Overrides {
+		index, isIndex, err := parseOverrideKey(key)
+		if err != nil {
+			return fmt.Errorf("invalid key in 'overrides' field: %v", err)
+		}
+		if isIndex && !matchesDevices(result.Devices, index) {
+			return fmt.Errorf("override for GPU %v not selected by 'devices'", index)
+		}
+	}
+
+	*s = result
+	return nil
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'MigConfigOverrideSpec'.
+func (s *MigConfigOverrideSpec) UnmarshalJSON(b []byte) error {
+	spec := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return err
+	}
+
+	if !containsKey(spec, "mig-enabled") {
+		return fmt.Errorf("missing required field: mig-enabled")
+	}
+
+	result := MigConfigOverrideSpec{}
+	for k, v := range spec {
+		switch k {
 		case "mig-enabled":
 			var enabled bool
 			err := json.Unmarshal(v, &enabled)
@@ -165,23 +514,247 @@ func (s *MigConfigSpec) UnmarshalJSON(b []byte) error {
 				return fmt.Errorf("error validating values in '%v' field: %v", k, err)
 			}
 			result.MigDevices = devices
+		case "fill":
+			var fill string
+			err := json.Unmarshal(v, &fill)
+			if err != nil {
+				return err
+			}
+			err = types.AssertValidMigProfileFormat(fill)
+			if err != nil {
+				return fmt.Errorf("error validating value in '%v' field: %v", k, err)
+			}
+			result.Fill = fill
 		default:
 			return fmt.Errorf("unexpected field: %v", k)
 		}
 	}
 
-	if result.MigEnabled && result.MigDevices == nil {
-		return fmt.Errorf("missing required field 'mig-devices' when 'mig-enabled' is true")
+	err = assertValidMigSettings(result.MigEnabled, result.MigDevices, result.Fill)
+	if err != nil {
+		return err
 	}
 
-	if !result.MigEnabled && len(result.MigDevices) != 0 {
-		return fmt.Errorf("MIG devices included when 'mig-enabled' is false")
+	*s = result
+	return nil
+}
+
+// UnmarshalJSON unmarshals raw bytes into an 'UnmanagedDeviceSpec'.
+func (s *UnmanagedDeviceSpec) UnmarshalJSON(b []byte) error {
+	spec := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return err
+	}
+
+	if !containsKey(spec, "devices") {
+		return fmt.Errorf("missing required field: devices")
+	}
+
+	result := UnmanagedDeviceSpec{}
+	for k, v := range spec {
+		switch k {
+		case "device-filter":
+			result.DeviceFilter, err = unmarshalDeviceFilter(v)
+			if err != nil {
+				return err
+			}
+		case "devices":
+			result.Devices, err = unmarshalDevices(k, v)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*s = result
+	return nil
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'ComputeInstanceConfigSpec'.
+func (s *ComputeInstanceConfigSpec) UnmarshalJSON(b []byte) error {
+	spec := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return err
+	}
+
+	required := []string{"devices", "compute-instances"}
+	for _, r := range required {
+		if !containsKey(spec, r) {
+			return fmt.Errorf("missing required field: %v", r)
+		}
+	}
+
+	result := ComputeInstanceConfigSpec{}
+	for k, v := range spec {
+		switch k {
+		case "device-filter":
+			result.DeviceFilter, err = unmarshalDeviceFilter(v)
+			if err != nil {
+				return err
+			}
+		case "devices":
+			result.Devices, err = unmarshalDevices(k, v)
+			if err != nil {
+				return err
+			}
+		case "compute-instances":
+			layer := make(types.ComputeInstanceLayer)
+			err := json.Unmarshal(v, &layer)
+			if err != nil {
+				return err
+			}
+			err = layer.AssertValidFormat()
+			if err != nil {
+				return fmt.Errorf("error validating values in '%v' field: %v", k, err)
+			}
+			result.ComputeInstances = layer
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*s = result
+	return nil
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'PermutationBudgetSpec'.
+func (s *PermutationBudgetSpec) UnmarshalJSON(b []byte) error {
+	spec := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &spec)
+	if err != nil {
+		return err
+	}
+
+	result := PermutationBudgetSpec{}
+	for k, v := range spec {
+		switch k {
+		case "max-attempts":
+			var attempts int
+			err := json.Unmarshal(v, &attempts)
+			if err != nil {
+				return err
+			}
+			if attempts < 0 {
+				return fmt.Errorf("invalid value for '%v': %v", k, attempts)
+			}
+			result.MaxAttempts = attempts
+		case "timeout":
+			var timeout string
+			err := json.Unmarshal(v, &timeout)
+			if err != nil {
+				return err
+			}
+			d, err := time.ParseDuration(timeout)
+			if err != nil {
+				return fmt.Errorf("error parsing value in '%v' field: %v", k, err)
+			}
+			if d < 0 {
+				return fmt.Errorf("invalid value for '%v': %v", k, timeout)
+			}
+			result.Timeout = timeout
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
 	}
 
 	*s = result
 	return nil
 }
 
+// TimeoutDuration returns the parsed 'Timeout', or 0 if it is unset.
+func (s *PermutationBudgetSpec) TimeoutDuration() time.Duration {
+	if s.Timeout == "" {
+		return 0
+	}
+	d, _ := time.ParseDuration(s.Timeout)
+	return d
+}
+
+// unmarshalDeviceFilter unmarshals a 'device-filter' field, which is either a
+// single device ID or a list of them.
+func unmarshalDeviceFilter(v json.RawMessage) (interface{}, error) {
+	var str string
+	err1 := json.Unmarshal(v, &str)
+	if err1 == nil {
+		return str, nil
+	}
+	var strslice []string
+	err2 := json.Unmarshal(v, &strslice)
+	if err2 == nil {
+		return strslice, nil
+	}
+	return nil, fmt.Errorf("(%v, %v)", err1, err2)
+}
+
+// unmarshalDevices unmarshals a 'devices' field, which is either the keyword
+// 'all' or a list of GPU indices.
+func unmarshalDevices(k string, v json.RawMessage) (interface{}, error) {
+	var str string
+	err1 := json.Unmarshal(v, &str)
+	if err1 == nil {
+		if str != "all" {
+			return nil, fmt.Errorf("invalid string input for '%v': %v", k, str)
+		}
+		return str, nil
+	}
+	var intslice []int
+	err2 := json.Unmarshal(v, &intslice)
+	if err2 == nil {
+		return intslice, nil
+	}
+	return nil, fmt.Errorf("(%v, %v)", err1, err2)
+}
+
+// assertValidMigSettings checks that a set of MIG settings is consistent with
+// whether MIG mode is enabled or not.
+func assertValidMigSettings(enabled bool, devices types.MigConfig, fill string) error {
+	if enabled && devices == nil && fill == "" {
+		return fmt.Errorf("missing required field 'mig-devices' when 'mig-enabled' is true (use 'mig-devices: {}' to enable MIG without creating any MIG devices)")
+	}
+
+	if !enabled && len(devices) != 0 {
+		return fmt.Errorf("MIG devices included when 'mig-enabled' is false")
+	}
+
+	if !enabled && fill != "" {
+		return fmt.Errorf("fill profile included when 'mig-enabled' is false")
+	}
+
+	return nil
+}
+
+// labelKeyRegex matches the keys allowed in the 'labels' of a 'MigConfigSpec'.
+var labelKeyRegex = regexp.MustCompile(`^[A-Za-z0-9]([-A-Za-z0-9_./]*[A-Za-z0-9])?$`)
+
+// assertValidLabels checks that every key in 'labels' is made of
+// alphanumerics, '-', '_', '.' and '/', starting and ending with an
+// alphanumeric.
+func assertValidLabels(labels map[string]string) error {
+	for k := range labels {
+		if !labelKeyRegex.MatchString(k) {
+			return fmt.Errorf("invalid label key '%v'", k)
+		}
+	}
+	return nil
+}
+
+// parseOverrideKey parses a key of an 'overrides' field. Keys are either a
+// GPU index or a GPU UUID; the index is only valid if 'isIndex' is true.
+func parseOverrideKey(key string) (index int, isIndex bool, err error) {
+	if strings.HasPrefix(key, "GPU-") && len(key) > len("GPU-") {
+		return 0, false, nil
+	}
+	index, err = strconv.Atoi(key)
+	if err != nil || index < 0 || strconv.Itoa(index) != key {
+		return 0, false, fmt.Errorf("expected a GPU index or UUID, got '%v'", key)
+	}
+	return index, true, nil
+}
+
 func containsKey(m map[string]json.RawMessage, s string) bool {
 	_, exists := m[s]
 	return exists
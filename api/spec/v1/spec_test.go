@@ -165,6 +165,22 @@ func TestSpec(t *testing.T) {
 			}`,
 			false,
 		},
+		{
+			"MIG strategy",
+			`{
+				"version": "v1",
+				"mig-strategy": "single"
+			}`,
+			false,
+		},
+		{
+			"Unknown MIG strategy",
+			`{
+				"version": "v1",
+				"mig-strategy": "shared"
+			}`,
+			true,
+		},
 		{
 			"Well formed - wrong version",
 			`{
@@ -219,6 +235,154 @@ func TestSpec(t *testing.T) {
 			}`,
 			true,
 		},
+		{
+			"Well formed - with 'unmanaged-devices'",
+			`{
+				"version": "v1",
+				"unmanaged-devices": [{
+					"device-filter": "0x20B010DE",
+					"devices": [0]
+				}],
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			false,
+		},
+		{
+			"'unmanaged-devices' missing 'devices'",
+			`{
+				"version": "v1",
+				"unmanaged-devices": [{
+					"device-filter": "0x20B010DE"
+				}]
+			}`,
+			true,
+		},
+		{
+			"'unmanaged-devices' with erroneous field",
+			`{
+				"version": "v1",
+				"unmanaged-devices": [{
+					"devices": [0],
+					"mig-enabled": false
+				}]
+			}`,
+			true,
+		},
+		{
+			"'unmanaged-devices' with invalid 'devices'",
+			`{
+				"version": "v1",
+				"unmanaged-devices": [{
+					"devices": "bogus"
+				}]
+			}`,
+			true,
+		},
+		{
+			"'profiles-per-gpu-model' well formed",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {
+					"A100-SXM4-40GB": "all-disabled",
+					"0x20B710DE": "all-disabled"
+				},
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			false,
+		},
+		{
+			"'profiles-per-gpu-model' empty",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {},
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			true,
+		},
+		{
+			"'profiles-per-gpu-model' unknown model",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {
+					"V100-SXM2-16GB": "all-disabled"
+				},
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			true,
+		},
+		{
+			"'profiles-per-gpu-model' unknown config",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {
+					"A100-SXM4-40GB": "all-1g.5gb"
+				},
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			true,
+		},
+		{
+			"'profiles-per-gpu-model' conflicting config",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {
+					"A100-SXM4-40GB": "all-disabled"
+				},
+				"mig-configs": {
+					"all-disabled": [{
+						"devices": "all",
+						"mig-enabled": false
+					}],
+					"profiles-per-gpu-model": [{
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			true,
+		},
+		{
+			"'profiles-per-gpu-model' config filtered to other models",
+			`{
+				"version": "v1",
+				"profiles-per-gpu-model": {
+					"A100-SXM4-40GB": "a30-only"
+				},
+				"mig-configs": {
+					"a30-only": [{
+						"device-filter": "0x20B710DE",
+						"devices": "all",
+						"mig-enabled": false
+					}]
+				}
+			}`,
+			true,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -234,6 +398,57 @@ func TestSpec(t *testing.T) {
 	}
 }
 
+func TestProfilesPerGpuModel(t *testing.T) {
+	contents := `
+version: v1
+profiles-per-gpu-model:
+  A100-PCIE-40GB: all-1g.5gb
+  a30-24gb: all-1g.6gb
+mig-configs:
+  all-1g.5gb:
+    - device-filter: ["0x20B110DE", "0x20B710DE"]
+      devices: all
+      mig-enabled: true
+      mig-devices:
+        "1g.5gb": 7
+  all-1g.6gb:
+    - devices: [0]
+      mig-enabled: false
+    - devices: [1, 2, 3]
+      mig-enabled: true
+      mig-devices:
+        "1g.6gb": 4
+`
+
+	var s Spec
+	err := yaml.Unmarshal([]byte(contents), &s)
+	require.Nil(t, err, "Unexpected failure yaml.Unmarshal")
+
+	expected := MigConfigSpecSlice{
+		{
+			DeviceFilter: []string{"0x20B110DE"},
+			Devices:      "all",
+			MigEnabled:   true,
+			MigDevices:   types.MigConfig{"1g.5gb": 7},
+		},
+		{
+			DeviceFilter: []string{"0x20B710DE"},
+			Devices:      []int{0},
+			MigEnabled:   false,
+		},
+		{
+			DeviceFilter: []string{"0x20B710DE"},
+			Devices:      []int{1, 2, 3},
+			MigEnabled:   true,
+			MigDevices:   types.MigConfig{"1g.6gb": 4},
+		},
+	}
+	require.Equal(t, expected, s.MigConfigs[ProfilesPerGpuModel])
+
+	// The expanded spec round trips without the shorthand.
+	require.Nil(t, s.Validate())
+}
+
 func TestMigConfigSpec(t *testing.T) {
 	testCases := []struct {
 		Description     string
@@ -280,6 +495,31 @@ func TestMigConfigSpec(t *testing.T) {
 			}`,
 			false,
 		},
+		{
+			"Well formed with profile IDs",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"19": 2,
+					"9/1": 1
+				},
+				"fill": "19"
+			}`,
+			false,
+		},
+		{
+			"Well formed with fractional profiles",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"half": 1
+				},
+				"fill": "quarter"
+			}`,
+			false,
+		},
 		{
 			"Missing 'devices'",
 			`{
@@ -316,6 +556,24 @@ func TestMigConfigSpec(t *testing.T) {
 			}`,
 			true,
 		},
+		{
+			"Null 'mig-devices', enabled: true",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": null,
+			}`,
+			true,
+		},
+		{
+			"Empty 'mig-devices', enabled: true",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {},
+			}`,
+			false,
+		},
 		{
 			"'mig-devices' formatted correctly",
 			`{
@@ -379,17 +637,952 @@ func TestMigConfigSpec(t *testing.T) {
 			}`,
 			true,
 		},
-	}
-
-	for _, tc := range testCases {
-		t.Run(tc.Description, func(t *testing.T) {
-			var s MigConfigSpec
-			err := yaml.Unmarshal([]byte(tc.Spec), &s)
-			if tc.expectedFailure {
-				require.NotNil(t, err, "Unexpected success yaml.Unmarshal")
-			} else {
-				require.Nil(t, err, "Unexpected failure yaml.Unmarshal")
-			}
-		})
-	}
+		{
+			"'fill' with 'mig-devices'",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"3g.20gb": 1
+				},
+				"fill": "1g.5gb"
+			}`,
+			false,
+		},
+		{
+			"'fill' with empty 'mig-devices'",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {},
+				"fill": "1g.5gb"
+			}`,
+			false,
+		},
+		{
+			"'fill' formatted incorrectly",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {},
+				"fill": "bogus"
+			}`,
+			true,
+		},
+		{
+			"'permutation-budget' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"permutation-budget": {
+					"max-attempts": 100,
+					"timeout": "30s"
+				}
+			}`,
+			false,
+		},
+		{
+			"'permutation-budget' negative 'max-attempts'",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"permutation-budget": {
+					"max-attempts": -1
+				}
+			}`,
+			true,
+		},
+		{
+			"'permutation-budget' bogus 'timeout'",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"permutation-budget": {
+					"timeout": "bogus"
+				}
+			}`,
+			true,
+		},
+		{
+			"'permutation-budget' erroneous field",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"permutation-budget": {
+					"bogus": 1
+				}
+			}`,
+			true,
+		},
+		{
+			"'placement' balanced",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"placement": "balanced"
+			}`,
+			false,
+		},
+		{
+			"'placement' high-first",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"placement": "high-first"
+			}`,
+			false,
+		},
+		{
+			"'placement' bogus",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"placement": "bogus"
+			}`,
+			true,
+		},
+		{
+			"'persistence-mode' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"persistence-mode": true
+			}`,
+			false,
+		},
+		{
+			"'persistence-mode' not a bool",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"persistence-mode": "enabled"
+			}`,
+			true,
+		},
+		{
+			"'placement-exclusions' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 4
+				},
+				"placement-exclusions": [
+					{
+						"slices": "0-1",
+						"profiles": ["1g.5gb"]
+					}
+				]
+			}`,
+			false,
+		},
+		{
+			"'placement-exclusions' empty",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 4
+				},
+				"placement-exclusions": []
+			}`,
+			true,
+		},
+		{
+			"'placement-exclusions' invalid slices",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 4
+				},
+				"placement-exclusions": [
+					{
+						"slices": "1-0",
+						"profiles": ["1g.5gb"]
+					}
+				]
+			}`,
+			true,
+		},
+		{
+			"'placement-exclusions' missing profiles",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 4
+				},
+				"placement-exclusions": [
+					{
+						"slices": "0-1"
+					}
+				]
+			}`,
+			true,
+		},
+		{
+			"'mps' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"mps": true
+			}`,
+			false,
+		},
+		{
+			"'mps' with 'mig-enabled' false",
+			`{
+				"devices": "all",
+				"mig-enabled": false,
+				"mps": true
+			}`,
+			true,
+		},
+		{
+			"'cc-mode' on",
+			`{
+				"devices": "all",
+				"mig-enabled": false,
+				"cc-mode": "on"
+			}`,
+			false,
+		},
+		{
+			"'cc-mode' on as a YAML boolean",
+			`{
+				"devices": "all",
+				"mig-enabled": false,
+				"cc-mode": true
+			}`,
+			false,
+		},
+		{
+			"'cc-mode' off with 'mig-enabled' true",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"cc-mode": "off"
+			}`,
+			false,
+		},
+		{
+			"'cc-mode' devtools with 'mig-enabled' true",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"cc-mode": "devtools"
+			}`,
+			true,
+		},
+		{
+			"'cc-mode' unknown",
+			`{
+				"devices": "all",
+				"mig-enabled": false,
+				"cc-mode": "enabled"
+			}`,
+			true,
+		},
+		{
+			"'requires' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"requires": {
+					"driver": ">=535.104.05",
+					"cuda": "12.2",
+					"vbios": ">=96.00.5E.00.01"
+				}
+			}`,
+			false,
+		},
+		{
+			"'requires' empty",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"requires": {}
+			}`,
+			true,
+		},
+		{
+			"'requires' malformed version",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"requires": {
+					"driver": ">=535.x"
+				}
+			}`,
+			true,
+		},
+		{
+			"'requires' unexpected field",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 2
+				},
+				"requires": {
+					"firmware": "1.0"
+				}
+			}`,
+			true,
+		},
+		{
+			"'fill' with 'mig-enabled' false",
+			`{
+				"devices": "all",
+				"mig-enabled": false,
+				"fill": "1g.5gb"
+			}`,
+			true,
+		},
+		{
+			"'overrides' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"0": {
+						"mig-enabled": false
+					},
+					"GPU-5f6c3a2e-1b2c-4d5e-8f90-123456789abc": {
+						"mig-enabled": true,
+						"mig-devices": {
+							"3g.20gb": 2
+						}
+					}
+				}
+			}`,
+			false,
+		},
+		{
+			"'labels' well formed",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"labels": {
+					"team": "vision",
+					"example.com/tier": "burst"
+				}
+			}`,
+			false,
+		},
+		{
+			"'labels' invalid key",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"labels": {
+					"team name": "vision"
+				}
+			}`,
+			true,
+		},
+		{
+			"'labels' non-string value",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"labels": {
+					"tier": 1
+				}
+			}`,
+			true,
+		},
+		{
+			"'overrides' empty",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {}
+			}`,
+			true,
+		},
+		{
+			"'overrides' bogus key",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"first": {
+						"mig-enabled": false
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"'overrides' index not in 'devices'",
+			`{
+				"devices": [0, 1],
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"2": {
+						"mig-enabled": false
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"'overrides' missing 'mig-enabled'",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"0": {
+						"mig-devices": {
+							"3g.20gb": 2
+						}
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"'overrides' MIG devices with 'mig-enabled' false",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"0": {
+						"mig-enabled": false,
+						"mig-devices": {
+							"3g.20gb": 2
+						}
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"'overrides' erroneous field",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"mig-devices": {
+					"1g.5gb": 7
+				},
+				"overrides": {
+					"0": {
+						"mig-enabled": false,
+						"devices": "all"
+					}
+				}
+			}`,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var s MigConfigSpec
+			err := yaml.Unmarshal([]byte(tc.Spec), &s)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success yaml.Unmarshal")
+			} else {
+				require.Nil(t, err, "Unexpected failure yaml.Unmarshal")
+			}
+		})
+	}
+}
+
+func TestRequiresSpecCheck(t *testing.T) {
+	requires := RequiresSpec{Driver: ">=535.104.05", CUDA: "12.2", VBIOS: ">=96.00.5E.00.01"}
+
+	testCases := []struct {
+		Description     string
+		Installed       InstalledVersions
+		expectedFailure bool
+	}{
+		{
+			"Exact versions",
+			InstalledVersions{Driver: "535.104.05", CUDA: "12.2", VBIOS: "96.00.5E.00.01"},
+			false,
+		},
+		{
+			"Newer versions",
+			InstalledVersions{Driver: "550.54.15", CUDA: "12.10", VBIOS: "96.00.A1.00.01"},
+			false,
+		},
+		{
+			"Older driver",
+			InstalledVersions{Driver: "535.86.10", CUDA: "12.2", VBIOS: "96.00.5E.00.01"},
+			true,
+		},
+		{
+			"Older CUDA",
+			InstalledVersions{Driver: "535.104.05", CUDA: "12.0", VBIOS: "96.00.5E.00.01"},
+			true,
+		},
+		{
+			"Older VBIOS",
+			InstalledVersions{Driver: "535.104.05", CUDA: "12.2", VBIOS: "96.00.30.00.01"},
+			true,
+		},
+		{
+			"Unknown VBIOS",
+			InstalledVersions{Driver: "535.104.05", CUDA: "12.2"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := requires.Check(tc.Installed)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Check")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Check")
+			}
+		})
+	}
+
+	require.Nil(t, (&RequiresSpec{Driver: "535"}).Check(InstalledVersions{Driver: "535.0.0"}))
+}
+
+func TestMigConfigSpecForDevice(t *testing.T) {
+	uuid := "GPU-5f6c3a2e-1b2c-4d5e-8f90-123456789abc"
+	mc := MigConfigSpec{
+		Devices:    "all",
+		MigEnabled: true,
+		MigDevices: types.MigConfig{"1g.5gb": 7},
+		Overrides: map[string]MigConfigOverrideSpec{
+			"0":  {MigEnabled: false},
+			"1":  {MigEnabled: true, Fill: "2g.10gb"},
+			uuid: {MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 2}},
+		},
+	}
+
+	testCases := []struct {
+		Description string
+		Index       int
+		UUID        string
+		Expected    MigConfigSpec
+	}{
+		{
+			"No override",
+			2,
+			"",
+			MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+		},
+		{
+			"Override by index",
+			0,
+			"",
+			MigConfigSpec{Devices: "all", MigEnabled: false},
+		},
+		{
+			"Override by index with fill",
+			1,
+			"GPU-00000000-0000-0000-0000-000000000000",
+			MigConfigSpec{Devices: "all", MigEnabled: true, Fill: "2g.10gb"},
+		},
+		{
+			"Override by UUID takes precedence",
+			0,
+			uuid,
+			MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 2}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			require.Equal(t, tc.Expected, mc.ForDevice(tc.Index, tc.UUID))
+		})
+	}
+
+	require.True(t, mc.HasUUIDOverrides())
+	delete(mc.Overrides, uuid)
+	require.False(t, mc.HasUUIDOverrides())
+}
+
+func TestUnmanagedDevicesMatches(t *testing.T) {
+	a100, _ := types.NewDeviceIDFromString("0x20B010DE")
+	a30, _ := types.NewDeviceIDFromString("0x20B710DE")
+
+	unmanaged := UnmanagedDeviceSpecSlice{
+		{Devices: []int{0}},
+		{DeviceFilter: "0x20B710DE", Devices: "all"},
+	}
+
+	testCases := []struct {
+		Description string
+		Index       int
+		DeviceID    types.DeviceID
+		Expected    bool
+	}{
+		{"Listed index", 0, a100, true},
+		{"Unlisted index", 1, a100, false},
+		{"Filtered device", 2, a30, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			require.Equal(t, tc.Expected, unmanaged.Matches(tc.Index, tc.DeviceID))
+		})
+	}
+}
+
+func TestComputeInstanceConfigSpec(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Spec            string
+		expectedFailure bool
+	}{
+		{
+			"Well formed",
+			`{
+				"devices": "all",
+				"compute-instances": {
+					"2g.20gb": {
+						"1c.2g.20gb": 2
+					}
+				}
+			}`,
+			false,
+		},
+		{
+			"Well formed with device-filter",
+			`{
+				"device-filter": ["0x20B010DE"],
+				"devices": [0, 1],
+				"compute-instances": {
+					"3g.40gb": {
+						"2c.3g.40gb": 1,
+						"1c.3g.40gb": 1
+					}
+				}
+			}`,
+			false,
+		},
+		{
+			"Missing 'compute-instances'",
+			`{
+				"devices": "all"
+			}`,
+			true,
+		},
+		{
+			"Missing 'devices'",
+			`{
+				"compute-instances": {
+					"2g.20gb": {
+						"1c.2g.20gb": 2
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"Invalid GPU instance profile",
+			`{
+				"devices": "all",
+				"compute-instances": {
+					"bogus": {
+						"1c.2g.20gb": 2
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"Invalid compute instance profile",
+			`{
+				"devices": "all",
+				"compute-instances": {
+					"2g.20gb": {
+						"bogus": 2
+					}
+				}
+			}`,
+			true,
+		},
+		{
+			"No compute instances",
+			`{
+				"devices": "all",
+				"compute-instances": {
+					"2g.20gb": {}
+				}
+			}`,
+			true,
+		},
+		{
+			"Erroneous field",
+			`{
+				"devices": "all",
+				"mig-enabled": true,
+				"compute-instances": {
+					"2g.20gb": {
+						"1c.2g.20gb": 2
+					}
+				}
+			}`,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var s ComputeInstanceConfigSpec
+			err := yaml.Unmarshal([]byte(tc.Spec), &s)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success yaml.Unmarshal")
+			} else {
+				require.Nil(t, err, "Unexpected failure yaml.Unmarshal")
+			}
+		})
+	}
+}
+
+func TestComputeInstanceConfigSelect(t *testing.T) {
+	a100, _ := types.NewDeviceIDFromString("0x20B010DE")
+	a30, _ := types.NewDeviceIDFromString("0x20B710DE")
+
+	split := types.ComputeInstanceLayer{"2g.20gb": {"1c.2g.20gb": 2}}
+	fallback := types.ComputeInstanceLayer{"3g.40gb": {"1c.3g.40gb": 3}}
+
+	configs := ComputeInstanceConfigSpecSlice{
+		{DeviceFilter: "0x20B710DE", Devices: "all", ComputeInstances: split},
+		{Devices: []int{0}, ComputeInstances: fallback},
+	}
+
+	testCases := []struct {
+		Description string
+		Index       int
+		DeviceID    types.DeviceID
+		Expected    types.ComputeInstanceLayer
+	}{
+		{"Filtered device", 1, a30, split},
+		{"First match wins", 0, a30, split},
+		{"Listed index", 0, a100, fallback},
+		{"No match", 1, a100, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			require.Equal(t, tc.Expected, configs.Select(tc.Index, tc.DeviceID))
+		})
+	}
+}
+
+func TestAssertStrictProfiles(t *testing.T) {
+	testCases := []struct {
+		description string
+		spec        string
+		err         string
+	}{
+		{
+			"Canonical profiles",
+			`
+version: v1
+mig-configs:
+  custom:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.5gb": 2
+    fill: "1c.2g.10gb"
+compute-instance-configs:
+  split:
+  - devices: all
+    compute-instances:
+      "2g.10gb":
+        "1c.2g.10gb": 2
+`,
+			"",
+		},
+		{
+			"Upper case attribute",
+			`
+version: v1
+mig-configs:
+  custom:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.5gb+ME": 2
+`,
+			`mig-configs 'custom' entry 0: mig-devices: invalid MIG profile "1g.5gb+ME" at offset 7: attribute must be written in lower case as 'me'`,
+		},
+		{
+			"Redundant compute instance count in fill",
+			`
+version: v1
+mig-configs:
+  custom:
+  - devices: all
+    mig-enabled: true
+    mig-devices: {}
+    fill: "2c.2g.10gb"
+`,
+			`mig-configs 'custom' entry 0: fill: invalid MIG profile "2c.2g.10gb" at offset 0: redundant compute instance count 2c, write '2g.' instead`,
+		},
+		{
+			"Numeric profile IDs",
+			`
+version: v1
+mig-configs:
+  custom:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "19": 2
+      "9/1": 1
+`,
+			"",
+		},
+		{
+			"Leading zero in compute instance",
+			`
+version: v1
+compute-instance-configs:
+  split:
+  - devices: all
+    compute-instances:
+      "2g.10gb":
+        "01c.2g.10gb": 2
+`,
+			`compute-instance-configs 'split' entry 0: compute-instances: invalid compute instances for '2g.10gb': invalid MIG profile "01c.2g.10gb" at offset 0: leading zero in '01'`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var spec Spec
+			err := yaml.Unmarshal([]byte(tc.spec), &spec)
+			require.Nil(t, err, "Unexpected failure loading spec")
+
+			err = spec.AssertStrictProfiles()
+			if tc.err == "" {
+				require.Nil(t, err, "Unexpected failure from AssertStrictProfiles")
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func FuzzSpec(f *testing.F) {
+	f.Add([]byte(`
+version: v1
+unmanaged-devices:
+- devices: [0]
+mig-configs:
+  all-1g.5gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.5gb": 7
+  custom:
+  - device-filter: ["0x20B010DE"]
+    devices: [0, 1]
+    mig-enabled: true
+    mig-devices: {}
+    fill: "1g.5gb"
+    permutation-budget:
+      max-attempts: 10
+      timeout: 30s
+compute-instance-configs:
+  split:
+  - devices: all
+    compute-instances:
+      "2g.10gb":
+        "1c.2g.10gb": 2
+`))
+	f.Add([]byte(`{"version": "v1", "mig-configs": {"all-disabled": [{"devices": "all", "mig-enabled": false}]}}`))
+	f.Add([]byte(`version: v1`))
+
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var spec Spec
+		err := yaml.Unmarshal(b, &spec)
+		if err != nil {
+			return
+		}
+		// An empty document leaves the spec untouched rather than being
+		// rejected for its missing version.
+		if spec.Version == "" {
+			return
+		}
+		// Any spec that loads must load again unchanged once written out.
+		require.Nil(t, spec.Validate(), "Loaded spec fails validation")
+		_ = spec.AssertStrictProfiles()
+	})
 }
@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// MIG strategies of the Kubernetes device plugin, which determine how the
+// MIG devices of a node are advertised:
+//   - 'none' advertises whole GPUs only, ignoring MIG.
+//   - 'single' advertises the MIG devices as 'nvidia.com/gpu', so they must
+//     all be of the same profile (or MIG be disabled on every GPU).
+//   - 'mixed' advertises each MIG profile as a resource of its own.
+const (
+	MigStrategyNone   = "none"
+	MigStrategySingle = "single"
+	MigStrategyMixed  = "mixed"
+)
+
+// AssertValidMigStrategy checks that 'strategy' is a known MIG strategy.
+func AssertValidMigStrategy(strategy string) error {
+	switch strategy {
+	case MigStrategyNone, MigStrategySingle, MigStrategyMixed:
+		return nil
+	}
+	return fmt.Errorf("unknown MIG strategy '%v' (expected one of '%v', '%v' or '%v')", strategy, MigStrategyNone, MigStrategySingle, MigStrategyMixed)
+}
+
+// migSettings are the MIG settings of a 'MigConfigSpec' or of one of its
+// overrides, as seen by the device plugin.
+type migSettings struct {
+	name         string
+	deviceFilter []string
+	enabled      bool
+	profiles     []string
+}
+
+// CheckMigStrategy returns why the GPUs configured by 'ms' would not be
+// advertised as intended under the MIG strategy 'strategy'. Entries for
+// GPU models that cannot be on the same node (i.e. with disjoint device
+// filters) are not compared with each other.
+func (ms MigConfigSpecSlice) CheckMigStrategy(strategy string) []error {
+	var settings []migSettings
+	for i := range ms {
+		name := fmt.Sprintf("entry %d", i)
+		if devices, ok := ms[i].Devices.([]int); ok {
+			name = fmt.Sprintf("entry %d (devices %v)", i, devices)
+		}
+		settings = append(settings, ms[i].migSettings(name)...)
+	}
+
+	var problems []error
+	switch strategy {
+	case MigStrategyNone:
+		for _, s := range settings {
+			if s.enabled {
+				problems = append(problems, fmt.Errorf("%v enables MIG, but MIG devices are not advertised under the '%v' strategy", s.name, strategy))
+			}
+		}
+	case MigStrategySingle:
+		for i, s := range settings {
+			if s.enabled && len(s.profiles) == 0 {
+				problems = append(problems, fmt.Errorf("%v enables MIG without MIG devices, leaving nothing to advertise under the '%v' strategy", s.name, strategy))
+			}
+			if len(s.profiles) > 1 {
+				problems = append(problems, fmt.Errorf("%v mixes MIG profiles %v, which the '%v' strategy cannot advertise together", s.name, strings.Join(s.profiles, ", "), strategy))
+			}
+			for _, other := range settings[:i] {
+				if !deviceFiltersOverlap(s.deviceFilter, other.deviceFilter) {
+					continue
+				}
+				if s.enabled != other.enabled {
+					problems = append(problems, fmt.Errorf("%v and %v mix GPUs with MIG enabled and disabled, which the '%v' strategy cannot advertise together", other.name, s.name, strategy))
+					continue
+				}
+				if len(s.profiles) == 1 && len(other.profiles) == 1 && s.profiles[0] != other.profiles[0] {
+					problems = append(problems, fmt.Errorf("%v and %v use different MIG profiles (%v and %v), which the '%v' strategy cannot advertise together", other.name, s.name, other.profiles[0], s.profiles[0], strategy))
+				}
+			}
+		}
+	}
+
+	return problems
+}
+
+// migSettings returns the MIG settings of 'ms' and of each of its overrides,
+// named after 'name'.
+func (ms *MigConfigSpec) migSettings(name string) []migSettings {
+	deviceFilter := normalizeDeviceFilter(ms.DeviceFilter)
+	settings := []migSettings{
+		{name, deviceFilter, ms.MigEnabled, migProfiles(ms.MigDevices, ms.Fill)},
+	}
+
+	var keys []string
+	for key := range ms.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		o := ms.Overrides[key]
+		settings = append(settings, migSettings{
+			fmt.Sprintf("%v override '%v'", name, key),
+			deviceFilter,
+			o.MigEnabled,
+			migProfiles(o.MigDevices, o.Fill),
+		})
+	}
+
+	return settings
+}
+
+// migProfiles returns the distinct MIG profiles of 'devices' and 'fill' in
+// their canonical form, in canonical order.
+func migProfiles(devices types.MigConfig, fill string) []string {
+	seen := make(map[string]bool)
+	var profiles []string
+	add := func(profile string) {
+		if p, err := types.ParseMigProfile(profile); err == nil {
+			profile = p.String()
+		}
+		if !seen[profile] {
+			seen[profile] = true
+			profiles = append(profiles, profile)
+		}
+	}
+	for _, profile := range devices.Profiles() {
+		if devices[profile] > 0 {
+			add(profile)
+		}
+	}
+	if fill != "" {
+		add(fill)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return types.CompareMigProfileNames(profiles[i], profiles[j]) < 0
+	})
+	return profiles
+}
+
+// normalizeDeviceFilter returns the device IDs in 'filter', or nil if it
+// matches all GPUs.
+func normalizeDeviceFilter(filter interface{}) []string {
+	switch df := filter.(type) {
+	case string:
+		if df != "" {
+			return []string{df}
+		}
+	case []string:
+		return df
+	}
+	return nil
+}
+
+// deviceFiltersOverlap returns whether a GPU can match both 'a' and 'b'.
+func deviceFiltersOverlap(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return true
+	}
+	for _, x := range a {
+		idX, errX := types.NewDeviceIDFromString(x)
+		for _, y := range b {
+			idY, errY := types.NewDeviceIDFromString(y)
+			if errX == nil && errY == nil && idX == idY {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package v1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"sigs.k8s.io/yaml"
+)
+
+func TestCheckMigStrategy(t *testing.T) {
+	testCases := []struct {
+		description      string
+		config           string
+		strategy         string
+		expectedProblems int
+	}{
+		{
+			"Single profile under single strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+`,
+			MigStrategySingle,
+			0,
+		},
+		{
+			"MIG disabled under single strategy",
+			`
+- devices: all
+  mig-enabled: false
+`,
+			MigStrategySingle,
+			0,
+		},
+		{
+			"Profile per GPU model under single strategy",
+			`
+- device-filter: "0x20B010DE"
+  devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.5gb": 7
+- device-filter: "0x233010DE"
+  devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+`,
+			MigStrategySingle,
+			0,
+		},
+		{
+			"Heterogeneous profiles under single strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 2
+    "2g.20gb": 1
+`,
+			MigStrategySingle,
+			1,
+		},
+		{
+			"Different profiles on different GPUs under single strategy",
+			`
+- devices: [0]
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+- devices: [1]
+  mig-enabled: true
+  mig-devices:
+    "3g.40gb": 2
+`,
+			MigStrategySingle,
+			1,
+		},
+		{
+			"MIG enabled and disabled under single strategy",
+			`
+- devices: [0]
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+- devices: [1]
+  mig-enabled: false
+`,
+			MigStrategySingle,
+			1,
+		},
+		{
+			"Override with another profile under single strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+  overrides:
+    "1":
+      mig-enabled: true
+      mig-devices:
+        "7g.80gb": 1
+`,
+			MigStrategySingle,
+			1,
+		},
+		{
+			"Fill with another profile under single strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "3g.40gb": 1
+  fill: "1g.10gb"
+`,
+			MigStrategySingle,
+			1,
+		},
+		{
+			"Heterogeneous profiles under mixed strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 2
+    "2g.20gb": 1
+`,
+			MigStrategyMixed,
+			0,
+		},
+		{
+			"MIG enabled under none strategy",
+			`
+- devices: all
+  mig-enabled: true
+  mig-devices:
+    "1g.10gb": 7
+`,
+			MigStrategyNone,
+			1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var config MigConfigSpecSlice
+			require.Nil(t, yaml.Unmarshal([]byte(tc.config), &config))
+			require.Len(t, config.CheckMigStrategy(tc.strategy), tc.expectedProblems)
+		})
+	}
+}
+
+func TestAssertValidMigStrategy(t *testing.T) {
+	require.Nil(t, AssertValidMigStrategy(MigStrategyNone))
+	require.Nil(t, AssertValidMigStrategy(MigStrategySingle))
+	require.Nil(t, AssertValidMigStrategy(MigStrategyMixed))
+	require.NotNil(t, AssertValidMigStrategy(""))
+	require.NotNil(t, AssertValidMigStrategy("shared"))
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+)
+
+// MigConfigChecksumLabel is set to the checksum of the MIG config last
+// applied to the node, so that 'nvidia-mig-parted assert --config-checksum'
+// can tell when the config has since been edited under the same name.
+const MigConfigChecksumLabel = "nvidia.com/mig.config.checksum"
+
+// getMigConfigChecksum returns the checksum of the MIG config named
+// 'selectedConfig' in 'configFile', parsed just as 'assert' parses it.
+func getMigConfigChecksum(configFile string, selectedConfig string) (string, error) {
+	f := &assert.Flags{
+		ConfigFile:     configFile,
+		SelectedConfig: selectedConfig,
+	}
+	spec, err := assert.ParseConfigFile(f)
+	if err != nil {
+		return "", fmt.Errorf("error parsing config file: %v", err)
+	}
+	migConfig, err := assert.GetSelectedMigConfig(f, spec)
+	if err != nil {
+		return "", fmt.Errorf("error selecting MIG config: %v", err)
+	}
+	return migConfig.Checksum()
+}
+
+// updateChecksumLabel sets the checksum label on the node to 'checksum', or
+// removes it if 'checksum' is empty, logging any error rather than failing.
+func updateChecksumLabel(clientset kubernetes.Interface, checksum string) {
+	labels := map[string]interface{}{MigConfigChecksumLabel: nil}
+	if checksum != "" {
+		labels[MigConfigChecksumLabel] = checksum
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": labels,
+		},
+	})
+	if err == nil {
+		_, err = clientset.CoreV1().Nodes().Patch(context.TODO(), nodeNameFlag, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		log.Errorf("Error updating '%s' label on node '%s': %v", MigConfigChecksumLabel, nodeNameFlag, err)
+	}
+}
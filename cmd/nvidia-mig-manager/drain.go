@@ -0,0 +1,186 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	DefaultDrainTimeout = 5 * time.Minute
+
+	GPUResourceName = "nvidia.com/gpu"
+	MigResourceName = "nvidia.com/mig-"
+)
+
+// evictionPollInterval is the time to wait between eviction attempts.
+var evictionPollInterval = 5 * time.Second
+
+// cordonNode marks the node as (un)schedulable.
+func cordonNode(clientset kubernetes.Interface, nodeName string, unschedulable bool) error {
+	patch := fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable)
+	_, err := clientset.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.StrategicMergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("error patching node '%s': %v", nodeName, err)
+	}
+	return nil
+}
+
+// consumesMigResources checks if any container in a pod requests a GPU or MIG device.
+func consumesMigResources(pod *v1.Pod) bool {
+	var containers []v1.Container
+	containers = append(containers, pod.Spec.InitContainers...)
+	containers = append(containers, pod.Spec.Containers...)
+	for _, c := range containers {
+		for name := range c.Resources.Limits {
+			if string(name) == GPUResourceName || strings.HasPrefix(string(name), MigResourceName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isDaemonSetPod checks if a pod is managed by a DaemonSet.
+// Such pods are recreated on the node immediately, so evicting them is pointless.
+func isDaemonSetPod(pod *v1.Pod) bool {
+	for _, ref := range pod.OwnerReferences {
+		if ref.Kind == "DaemonSet" {
+			return true
+		}
+	}
+	return false
+}
+
+// getPodsToEvict returns the set of running pods on the node that consume MIG resources.
+func getPodsToEvict(clientset kubernetes.Interface, nodeName string) ([]v1.Pod, error) {
+	pods, err := clientset.CoreV1().Pods(v1.NamespaceAll).List(context.TODO(), metav1.ListOptions{
+		FieldSelector: fields.OneTermEqualSelector("spec.nodeName", nodeName).String(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods on node '%s': %v", nodeName, err)
+	}
+
+	var evict []v1.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == v1.PodSucceeded || pod.Status.Phase == v1.PodFailed {
+			continue
+		}
+		if isDaemonSetPod(&pod) {
+			continue
+		}
+		if !consumesMigResources(&pod) {
+			continue
+		}
+		evict = append(evict, pod)
+	}
+	return evict, nil
+}
+
+// drainNode evicts all pods consuming MIG resources from the node and waits for them to terminate.
+// Evictions blocked by a PodDisruptionBudget are retried until 'timeout' expires.
+func drainNode(clientset kubernetes.Interface, nodeName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		pods, err := getPodsToEvict(clientset, nodeName)
+		if err != nil {
+			return err
+		}
+
+		if len(pods) == 0 {
+			return nil
+		}
+
+		for _, pod := range pods {
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			log.Infof("Evicting pod %s/%s", pod.Namespace, pod.Name)
+			eviction := &policyv1.Eviction{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      pod.Name,
+					Namespace: pod.Namespace,
+				},
+			}
+			err := clientset.PolicyV1().Evictions(pod.Namespace).Evict(context.TODO(), eviction)
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				log.Infof("Eviction of pod %s/%s blocked by disruption budget, retrying", pod.Namespace, pod.Name)
+			default:
+				return fmt.Errorf("error evicting pod %s/%s: %v", pod.Namespace, pod.Name, err)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %v waiting for %d pod(s) to be evicted", timeout, len(pods))
+		}
+		time.Sleep(evictionPollInterval)
+	}
+}
+
+// runWithCordonAndDrain cordons and drains the node, runs 'f', and then uncordons the node again.
+// The node is uncordoned even if draining or 'f' fails. A node that was
+// already cordoned beforehand is left cordoned.
+func runWithCordonAndDrain(clientset kubernetes.Interface, nodeName string, timeout time.Duration, f func() error) (rerr error) {
+	node, err := clientset.CoreV1().Nodes().Get(context.TODO(), nodeName, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("error getting node '%s': %v", nodeName, err)
+	}
+
+	if node.Spec.Unschedulable {
+		log.Infof("Node %s is already cordoned, leaving it cordoned", nodeName)
+	} else {
+		log.Infof("Cordoning node %s", nodeName)
+		err := cordonNode(clientset, nodeName, true)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			log.Infof("Uncordoning node %s", nodeName)
+			err := cordonNode(clientset, nodeName, false)
+			if err != nil {
+				if rerr == nil {
+					rerr = err
+					return
+				}
+				log.Errorf("Error uncordoning node: %v", err)
+			}
+		}()
+	}
+
+	log.Infof("Draining pods consuming MIG resources from node %s", nodeName)
+	err = drainNode(clientset, nodeName, timeout)
+	if err != nil {
+		return fmt.Errorf("error draining node: %v", err)
+	}
+
+	return f()
+}
@@ -0,0 +1,237 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+const testNodeName = "test-node"
+
+func newTestNode(unschedulable bool) *v1.Node {
+	return &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: testNodeName},
+		Spec:       v1.NodeSpec{Unschedulable: unschedulable},
+	}
+}
+
+func newTestPod(name string, resourceName string) *v1.Pod {
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: v1.PodSpec{
+			NodeName:   testNodeName,
+			Containers: []v1.Container{{Name: "c"}},
+		},
+		Status: v1.PodStatus{Phase: v1.PodRunning},
+	}
+	if resourceName != "" {
+		pod.Spec.Containers[0].Resources.Limits = v1.ResourceList{
+			v1.ResourceName(resourceName): resource.MustParse("1"),
+		}
+	}
+	return pod
+}
+
+// evictionReactor deletes evicted pods from the fake clientset's object
+// tracker, since the fake does not do so by itself. The first 'blocked'
+// evictions of each pod are rejected as if by a PodDisruptionBudget.
+func evictionReactor(clientset *fake.Clientset, blocked int, evicted *[]string) k8stesting.ReactionFunc {
+	attempts := make(map[string]int)
+	return func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "eviction" {
+			return false, nil, nil
+		}
+		eviction := action.(k8stesting.CreateAction).GetObject().(*policyv1.Eviction)
+		attempts[eviction.Name]++
+		if attempts[eviction.Name] <= blocked {
+			return true, nil, apierrors.NewTooManyRequests("disruption budget", 1)
+		}
+		*evicted = append(*evicted, eviction.Name)
+		err := clientset.Tracker().Delete(v1.SchemeGroupVersion.WithResource("pods"), eviction.Namespace, eviction.Name)
+		return true, nil, err
+	}
+}
+
+func TestGetPodsToEvict(t *testing.T) {
+	gpuPod := newTestPod("gpu", GPUResourceName)
+	migPod := newTestPod("mig", MigResourceName+"1g.5gb")
+	cpuPod := newTestPod("cpu", "")
+	initPod := newTestPod("init", "")
+	initPod.Spec.InitContainers = []v1.Container{{
+		Name: "init",
+		Resources: v1.ResourceRequirements{
+			Limits: v1.ResourceList{GPUResourceName: resource.MustParse("1")},
+		},
+	}}
+	donePod := newTestPod("done", GPUResourceName)
+	donePod.Status.Phase = v1.PodSucceeded
+	failedPod := newTestPod("failed", GPUResourceName)
+	failedPod.Status.Phase = v1.PodFailed
+	dsPod := newTestPod("daemonset", GPUResourceName)
+	dsPod.OwnerReferences = []metav1.OwnerReference{{Kind: "DaemonSet", Name: "ds"}}
+
+	clientset := fake.NewSimpleClientset(gpuPod, migPod, cpuPod, initPod, donePod, failedPod, dsPod)
+
+	pods, err := getPodsToEvict(clientset, testNodeName)
+	require.Nil(t, err, "Unexpected failure from getPodsToEvict")
+
+	var names []string
+	for _, pod := range pods {
+		names = append(names, pod.Name)
+	}
+	sort.Strings(names)
+	require.Equal(t, []string{"gpu", "init", "mig"}, names)
+}
+
+func TestDrainNode(t *testing.T) {
+	defer func(d time.Duration) { evictionPollInterval = d }(evictionPollInterval)
+	evictionPollInterval = time.Millisecond
+
+	testCases := []struct {
+		Description   string
+		Blocked       int
+		Timeout       time.Duration
+		ExpectedError bool
+	}{
+		{
+			"Pods evicted immediately",
+			0,
+			time.Minute,
+			false,
+		},
+		{
+			"Eviction blocked by disruption budget then retried",
+			2,
+			time.Minute,
+			false,
+		},
+		{
+			"Eviction blocked by disruption budget until timeout",
+			1000000,
+			20 * time.Millisecond,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(
+				newTestPod("gpu", GPUResourceName),
+				newTestPod("cpu", ""),
+			)
+			var evicted []string
+			clientset.PrependReactor("create", "pods", evictionReactor(clientset, tc.Blocked, &evicted))
+
+			err := drainNode(clientset, testNodeName, tc.Timeout)
+			if tc.ExpectedError {
+				require.NotNil(t, err, "Unexpected success from drainNode")
+				require.Empty(t, evicted)
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from drainNode")
+			require.Equal(t, []string{"gpu"}, evicted)
+
+			_, err = clientset.CoreV1().Pods("default").Get(context.TODO(), "cpu", metav1.GetOptions{})
+			require.Nil(t, err, "Pod not consuming GPUs was evicted")
+		})
+	}
+}
+
+func TestRunWithCordonAndDrain(t *testing.T) {
+	testCases := []struct {
+		Description           string
+		AlreadyCordoned       bool
+		RunError              error
+		ExpectedCordoned      bool
+		ExpectedCordonPatches []bool
+	}{
+		{
+			"Node cordoned and uncordoned",
+			false,
+			nil,
+			false,
+			[]bool{true, false},
+		},
+		{
+			"Node uncordoned after failure",
+			false,
+			fmt.Errorf("reconfigure failed"),
+			false,
+			[]bool{true, false},
+		},
+		{
+			"Node already cordoned left cordoned",
+			true,
+			nil,
+			true,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(newTestNode(tc.AlreadyCordoned))
+
+			ran := false
+			err := runWithCordonAndDrain(clientset, testNodeName, time.Minute, func() error {
+				node, err := clientset.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if !node.Spec.Unschedulable {
+					return fmt.Errorf("node not cordoned while running")
+				}
+				ran = true
+				return tc.RunError
+			})
+			require.True(t, ran)
+			require.Equal(t, tc.RunError, err)
+
+			var patches []bool
+			for _, action := range clientset.Actions() {
+				if action.GetVerb() != "patch" || action.GetResource().Resource != "nodes" {
+					continue
+				}
+				patch := string(action.(k8stesting.PatchAction).GetPatch())
+				patches = append(patches, patch == `{"spec":{"unschedulable":true}}`)
+			}
+			require.Equal(t, tc.ExpectedCordonPatches, patches)
+
+			node, err := clientset.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			require.Nil(t, err, "Unexpected failure getting node")
+			require.Equal(t, tc.ExpectedCordoned, node.Spec.Unschedulable)
+		})
+	}
+}
@@ -0,0 +1,286 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"sigs.k8s.io/yaml"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+)
+
+const (
+	ApplyStartHook = "apply-start"
+	ApplyExitHook  = "apply-exit"
+
+	DefaultHooksNamespace = "default"
+	DefaultJobHookTimeout = 10 * time.Minute
+
+	// HookJobLabel labels the Jobs created for hooks with the hook's name.
+	HookJobLabel = "nvidia.com/mig.hook"
+	// HookStatusAnnotationPrefix is followed by a hook's name to form the
+	// node annotation tracking the status of its latest Job.
+	HookStatusAnnotationPrefix = "nvidia.com/mig.hook."
+
+	HookStatusRunning   = "running"
+	HookStatusSucceeded = "succeeded"
+	HookStatusFailed    = "failed"
+
+	jobPollInterval = 5 * time.Second
+	// jobDeadlineGrace is how long past its 'activeDeadlineSeconds' a Job is
+	// waited for before giving up on it.
+	jobDeadlineGrace = time.Minute
+)
+
+// parseJobHooksFile reads the hooks file at 'file' and returns the hooks in
+// it that are run as Jobs. Hooks executed on the host are left to
+// 'nvidia-mig-parted apply'.
+func parseJobHooksFile(file string) (hooks.HooksMap, error) {
+	jobHooks := make(hooks.HooksMap)
+	if file == "" {
+		return jobHooks, nil
+	}
+
+	yamlBytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	var spec hooks.Spec
+	err = yaml.Unmarshal(yamlBytes, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal error: %v", err)
+	}
+
+	for name, hookSpecs := range spec.Hooks {
+		for _, hook := range hookSpecs {
+			if hook.Job == nil {
+				continue
+			}
+			if name != ApplyStartHook && name != ApplyExitHook {
+				return nil, fmt.Errorf("'%s' hook cannot be run as a Job: only '%s' and '%s' hooks can", name, ApplyStartHook, ApplyExitHook)
+			}
+			err := hook.Job.Validate()
+			if err != nil {
+				return nil, fmt.Errorf("invalid Job in '%s' hook: %v", name, err)
+			}
+			jobHooks[name] = append(jobHooks[name], hook)
+		}
+	}
+
+	return jobHooks, nil
+}
+
+// runWithJobHooks runs the 'apply-start' Job hooks in 'hooksFile', then 'f',
+// and then the 'apply-exit' Job hooks. As with the hooks run by 'apply', the
+// 'apply-exit' hooks run even if 'f' fails, but not if an 'apply-start' hook
+// does, in which case 'f' is not run either.
+func runWithJobHooks(clientset kubernetes.Interface, nodeName string, hooksFile string, migConfigValue string, f func() error) (rerr error) {
+	jobHooks, err := parseJobHooksFile(hooksFile)
+	if err != nil {
+		return fmt.Errorf("error parsing hooks file: %v", err)
+	}
+
+	envs := hooks.EnvsMap{
+		"MIG_PARTED_NODE_NAME":       nodeName,
+		"MIG_PARTED_SELECTED_CONFIG": migConfigValue,
+	}
+
+	err = runJobHooks(clientset, nodeName, ApplyStartHook, jobHooks[ApplyStartHook], envs)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := runJobHooks(clientset, nodeName, ApplyExitHook, jobHooks[ApplyExitHook], envs)
+		if err != nil {
+			if rerr == nil {
+				rerr = err
+				return
+			}
+			log.Errorf("Error running %s hook: %v", ApplyExitHook, err)
+		}
+	}()
+
+	return f()
+}
+
+// runJobHooks runs the Job of each of the hooks named 'name' in turn,
+// stopping at the first one that fails.
+func runJobHooks(clientset kubernetes.Interface, nodeName string, name string, hookSpecs []hooks.HookSpec, envs hooks.EnvsMap) error {
+	for _, hook := range hookSpecs {
+		err := runJobHook(clientset, nodeName, name, &hook, envs)
+		if err != nil {
+			return fmt.Errorf("error running %s hook: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// runJobHook runs the Job of 'hook' on the node and waits for it to finish,
+// tracking its status in the node's annotation for 'name'. The Job is
+// deleted once it has finished.
+func runJobHook(clientset kubernetes.Interface, nodeName string, name string, hook *hooks.HookSpec, envs hooks.EnvsMap) error {
+	timeout, err := hook.Job.GetTimeout()
+	if err != nil {
+		return err
+	}
+	if timeout == 0 {
+		timeout = DefaultJobHookTimeout
+	}
+
+	namespace := hook.Job.Namespace
+	if namespace == "" {
+		namespace = hooksNamespaceFlag
+	}
+
+	job, err := clientset.BatchV1().Jobs(namespace).Create(context.TODO(), buildHookJob(nodeName, name, hook, envs, timeout), metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("error creating Job: %v", err)
+	}
+	log.Infof("Running %s hook as Job %s/%s", name, namespace, job.Name)
+	setHookStatus(clientset, nodeName, name, HookStatusRunning)
+
+	defer func() {
+		propagation := metav1.DeletePropagationBackground
+		err := clientset.BatchV1().Jobs(namespace).Delete(context.TODO(), job.Name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+		if err != nil {
+			log.Errorf("Error deleting Job %s/%s: %v", namespace, job.Name, err)
+		}
+	}()
+
+	err = waitForJob(clientset, namespace, job.Name, timeout+jobDeadlineGrace)
+	if err != nil {
+		setHookStatus(clientset, nodeName, name, HookStatusFailed)
+		return fmt.Errorf("job %s/%s: %v", namespace, job.Name, err)
+	}
+	setHookStatus(clientset, nodeName, name, HookStatusSucceeded)
+	return nil
+}
+
+// buildHookJob builds the Job running 'hook' on the node. It is pinned to
+// the node by name, so that it runs even while the node is cordoned, and
+// tolerates every taint for the same reason.
+func buildHookJob(nodeName string, name string, hook *hooks.HookSpec, envs hooks.EnvsMap, timeout time.Duration) *batchv1.Job {
+	backoffLimit := int32(0)
+	activeDeadlineSeconds := int64(timeout.Seconds())
+	labels := map[string]string{HookJobLabel: name}
+
+	combined := hook.Envs.Combine(envs)
+	var keys []string
+	for k := range combined {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var env []v1.EnvVar
+	for _, k := range keys {
+		env = append(env, v1.EnvVar{Name: k, Value: combined[k]})
+	}
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("nvidia-mig-manager-%s-", name),
+			Labels:       labels,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit:          &backoffLimit,
+			ActiveDeadlineSeconds: &activeDeadlineSeconds,
+			Template: v1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: labels,
+				},
+				Spec: v1.PodSpec{
+					NodeName:           nodeName,
+					RestartPolicy:      v1.RestartPolicyNever,
+					ServiceAccountName: hook.Job.ServiceAccountName,
+					Tolerations: []v1.Toleration{
+						{Operator: v1.TolerationOpExists},
+					},
+					Containers: []v1.Container{
+						{
+							Name:    "hook",
+							Image:   hook.Job.Image,
+							Command: hook.Job.Command,
+							Args:    hook.Job.Args,
+							Env:     env,
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// waitForJob waits for the Job to complete, returning an error if it fails
+// or has not finished after 'timeout'.
+func waitForJob(clientset kubernetes.Interface, namespace string, jobName string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		job, err := clientset.BatchV1().Jobs(namespace).Get(context.TODO(), jobName, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("error getting Job: %v", err)
+		}
+
+		for _, condition := range job.Status.Conditions {
+			if condition.Status != v1.ConditionTrue {
+				continue
+			}
+			switch condition.Type {
+			case batchv1.JobComplete:
+				return nil
+			case batchv1.JobFailed:
+				return fmt.Errorf("failed: %s", condition.Message)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timeout after %v waiting for Job to finish", timeout)
+		}
+		time.Sleep(jobPollInterval)
+	}
+}
+
+// setHookStatus records 'status' for the hook 'name' in its node
+// annotation, logging any error rather than failing.
+func setHookStatus(clientset kubernetes.Interface, nodeName string, name string, status string) {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				HookStatusAnnotationPrefix + name: status,
+			},
+		},
+	})
+	if err == nil {
+		_, err = clientset.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	}
+	if err != nil {
+		log.Errorf("Error setting status of %s hook on node '%s': %v", name, nodeName, err)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"strings"
 	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -68,6 +69,15 @@ var (
 	cdiEnabledFlag    bool
 	driverRoot        string
 	driverRootCtrPath string
+
+	cordonAndDrainFlag bool
+	drainTimeoutFlag   time.Duration
+
+	rebootAnnotationFlag string
+	rebootMarkerFileFlag string
+
+	hooksFileFlag      string
+	hooksNamespaceFlag string
 )
 
 type GPUClients struct {
@@ -133,7 +143,7 @@ func main() {
 			Name:        "config-file",
 			Aliases:     []string{"f"},
 			Value:       "",
-			Usage:       "the path to the MIG parted configuration file",
+			Usage:       "the path to the MIG parted configuration file (or a directory of spec fragments)",
 			Destination: &configFileFlag,
 			EnvVars:     []string{"CONFIG_FILE"},
 		},
@@ -231,6 +241,48 @@ func main() {
 			Destination: &cdiEnabledFlag,
 			EnvVars:     []string{"CDI_ENABLED"},
 		},
+		&cli.BoolFlag{
+			Name:        "cordon-and-drain",
+			Value:       false,
+			Usage:       "cordon the node and evict all pods consuming MIG resources before applying a MIG configuration, uncordoning it afterwards",
+			Destination: &cordonAndDrainFlag,
+			EnvVars:     []string{"CORDON_AND_DRAIN"},
+		},
+		&cli.DurationFlag{
+			Name:        "drain-timeout",
+			Value:       DefaultDrainTimeout,
+			Usage:       "maximum time to wait for pods consuming MIG resources to be evicted (e.g. when blocked by a PodDisruptionBudget)",
+			Destination: &drainTimeoutFlag,
+			EnvVars:     []string{"DRAIN_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-annotation",
+			Value:       "",
+			Usage:       "node annotation ('<key>[=<value>]') to set while a reboot is required for a MIG mode change to take effect, for a reboot controller to act on (disabled if empty)",
+			Destination: &rebootAnnotationFlag,
+			EnvVars:     []string{"REBOOT_ANNOTATION"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Value:       "",
+			Usage:       "path to the reboot marker written by 'nvidia-mig-parted apply' (defaults to its well-known location in the container, or under the host root mount with --with-shutdown-host-gpu-clients)",
+			Destination: &rebootMarkerFileFlag,
+			EnvVars:     []string{"REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "hooks-file",
+			Value:       "",
+			Usage:       "the path to a hooks file whose 'apply-start' and 'apply-exit' hooks with a 'job' are run as Kubernetes Jobs on the node before and after each MIG reconfiguration",
+			Destination: &hooksFileFlag,
+			EnvVars:     []string{"HOOKS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "hooks-namespace",
+			Value:       DefaultHooksNamespace,
+			Usage:       "name of the Kubernetes namespace in which the Jobs of hooks that do not set their own are created",
+			Destination: &hooksNamespaceFlag,
+			EnvVars:     []string{"HOOKS_NAMESPACE"},
+		},
 	}
 
 	err := c.Run(os.Args)
@@ -248,6 +300,16 @@ func validateFlags(c *cli.Context) error {
 	if configFileFlag == "" {
 		return fmt.Errorf("invalid -f <config-file> flag: must not be empty string")
 	}
+	if rebootAnnotationFlag != "" {
+		_, _, err := parseRebootAnnotation(rebootAnnotationFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --reboot-annotation flag: %v", err)
+		}
+	}
+	_, err := parseJobHooksFile(hooksFileFlag)
+	if err != nil {
+		return fmt.Errorf("invalid --hooks-file flag: %v", err)
+	}
 	return nil
 }
 
@@ -267,19 +329,53 @@ func start(c *cli.Context) error {
 	stop := ContinuouslySyncMigConfigChanges(clientset, migConfig)
 	defer close(stop)
 
+	// A reboot clears the marker, so the annotation left behind by a
+	// previous run is removed once the node comes back up.
+	updateRebootAnnotation(clientset)
+
 	for {
 		log.Infof("Waiting for change to '%s' label", MigConfigLabel)
 		value := migConfig.Get()
 		log.Infof("Updating to MIG config: %s", value)
-		err := runScript(value)
+		// The checksum is taken before applying, so that an edit made to
+		// the config while it is applied is still detected as drift.
+		checksum, cerr := getMigConfigChecksum(configFileFlag, value)
+		if cerr != nil {
+			log.Errorf("Error computing checksum of MIG config: %s", cerr)
+		}
+		reconfigure := func() error {
+			return runWithJobHooks(clientset, nodeNameFlag, hooksFileFlag, value, func() error {
+				return runScript(value)
+			})
+		}
+		if cordonAndDrainFlag {
+			err = runWithCordonAndDrain(clientset, nodeNameFlag, drainTimeoutFlag, reconfigure)
+		} else {
+			err = reconfigure()
+		}
+		updateRebootAnnotation(clientset)
 		if err != nil {
 			log.Errorf("Error: %s", err)
+			updateChecksumLabel(clientset, "")
 			continue
 		}
+		updateChecksumLabel(clientset, checksum)
 		log.Infof("Successfully updated to MIG config: %s", value)
 	}
 }
 
+// updateRebootAnnotation syncs the reboot annotation on the node (if enabled)
+// with the reboot marker, logging any error rather than failing.
+func updateRebootAnnotation(clientset kubernetes.Interface) {
+	if rebootAnnotationFlag == "" {
+		return
+	}
+	err := syncRebootAnnotation(clientset, nodeNameFlag, getRebootMarkerFile(), rebootAnnotationFlag)
+	if err != nil {
+		log.Errorf("Error updating reboot annotation: %s", err)
+	}
+}
+
 func parseGPUCLientsFile(file string) (*GPUClients, error) {
 	var err error
 	var yamlBytes []byte
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+)
+
+const DefaultRebootAnnotationValue = "true"
+
+// parseRebootAnnotation splits a '<key>[=<value>]' annotation into its key
+// and value, defaulting the value to DefaultRebootAnnotationValue.
+func parseRebootAnnotation(annotation string) (string, string, error) {
+	key, value, found := strings.Cut(annotation, "=")
+	if key == "" {
+		return "", "", fmt.Errorf("missing annotation key in '%s'", annotation)
+	}
+	if !found {
+		value = DefaultRebootAnnotationValue
+	}
+	return key, value, nil
+}
+
+// getRebootMarkerFile returns the path at which the reboot marker written by
+// 'nvidia-mig-parted apply' can be read. When GPU clients are shut down on
+// the host, 'apply' runs chrooted into the host root and writes its marker
+// there.
+func getRebootMarkerFile() string {
+	if rebootMarkerFileFlag != "" {
+		return rebootMarkerFileFlag
+	}
+	if withShutdownHostGPUClientsFlag {
+		return filepath.Join(hostRootMountFlag, reboot.DefaultMarkerFile)
+	}
+	return reboot.DefaultMarkerFile
+}
+
+// syncRebootAnnotation sets the reboot annotation on the node if the reboot
+// marker says a reboot is required, and removes it otherwise, so that a
+// reboot controller watching for it reboots the node exactly when needed.
+func syncRebootAnnotation(clientset kubernetes.Interface, nodeName string, markerFile string, annotation string) error {
+	key, value, err := parseRebootAnnotation(annotation)
+	if err != nil {
+		return err
+	}
+
+	marker, err := reboot.Read(markerFile)
+	if err != nil {
+		return err
+	}
+
+	annotations := map[string]interface{}{key: nil}
+	if marker != nil {
+		log.Infof("A reboot is required (%s), setting the '%s' node annotation", marker.Reason, key)
+		annotations[key] = value
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error building patch for node '%s': %v", nodeName, err)
+	}
+
+	_, err = clientset.CoreV1().Nodes().Patch(context.TODO(), nodeName, types.StrategicMergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("error patching node '%s': %v", nodeName, err)
+	}
+	return nil
+}
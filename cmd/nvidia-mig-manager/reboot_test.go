@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+)
+
+func TestParseRebootAnnotation(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Annotation      string
+		ExpectedKey     string
+		ExpectedValue   string
+		ExpectedFailure bool
+	}{
+		{
+			"Key only",
+			"example.com/reboot-required",
+			"example.com/reboot-required",
+			DefaultRebootAnnotationValue,
+			false,
+		},
+		{
+			"Key and value",
+			"example.com/reboot=now",
+			"example.com/reboot",
+			"now",
+			false,
+		},
+		{
+			"Empty value",
+			"example.com/reboot=",
+			"example.com/reboot",
+			"",
+			false,
+		},
+		{
+			"Value containing '='",
+			"example.com/reboot=a=b",
+			"example.com/reboot",
+			"a=b",
+			false,
+		},
+		{
+			"Empty annotation",
+			"",
+			"",
+			"",
+			true,
+		},
+		{
+			"Missing key",
+			"=true",
+			"",
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			key, value, err := parseRebootAnnotation(tc.Annotation)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from parseRebootAnnotation")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from parseRebootAnnotation")
+			require.Equal(t, tc.ExpectedKey, key)
+			require.Equal(t, tc.ExpectedValue, value)
+		})
+	}
+}
+
+func TestSyncRebootAnnotation(t *testing.T) {
+	const key = "example.com/reboot-required"
+	const other = "example.com/other"
+
+	testCases := []struct {
+		Description         string
+		Annotations         map[string]string
+		Annotation          string
+		MarkerPresent       bool
+		ExpectedAnnotations map[string]string
+		ExpectedFailure     bool
+	}{
+		{
+			"Marker present sets annotation",
+			map[string]string{other: "kept"},
+			key,
+			true,
+			map[string]string{key: DefaultRebootAnnotationValue, other: "kept"},
+			false,
+		},
+		{
+			"Marker present sets annotation value",
+			nil,
+			key + "=now",
+			true,
+			map[string]string{key: "now"},
+			false,
+		},
+		{
+			"Marker absent leaves annotation unset",
+			map[string]string{other: "kept"},
+			key,
+			false,
+			map[string]string{other: "kept"},
+			false,
+		},
+		{
+			"Marker removed removes annotation",
+			map[string]string{key: DefaultRebootAnnotationValue, other: "kept"},
+			key,
+			false,
+			map[string]string{other: "kept"},
+			false,
+		},
+		{
+			"Malformed annotation",
+			map[string]string{other: "kept"},
+			"=true",
+			true,
+			map[string]string{other: "kept"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(&v1.Node{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        testNodeName,
+					Annotations: tc.Annotations,
+				},
+			})
+
+			markerFile := filepath.Join(t.TempDir(), "reboot-required")
+			if tc.MarkerPresent {
+				err := reboot.Write(markerFile, &reboot.Marker{Reason: reboot.ReasonModeChangePending})
+				require.Nil(t, err, "Unexpected failure writing reboot marker")
+			}
+
+			err := syncRebootAnnotation(clientset, testNodeName, markerFile, tc.Annotation)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from syncRebootAnnotation")
+			} else {
+				require.Nil(t, err, "Unexpected failure from syncRebootAnnotation")
+			}
+
+			node, err := clientset.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+			require.Nil(t, err, "Unexpected failure getting node")
+			require.Equal(t, tc.ExpectedAnnotations, node.Annotations)
+		})
+	}
+}
+
+func TestSyncRebootAnnotationAfterReboot(t *testing.T) {
+	const key = "example.com/reboot-required"
+
+	clientset := fake.NewSimpleClientset(&v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: testNodeName},
+	})
+	markerFile := filepath.Join(t.TempDir(), "reboot-required")
+
+	annotations := func() map[string]string {
+		node, err := clientset.CoreV1().Nodes().Get(context.TODO(), testNodeName, metav1.GetOptions{})
+		require.Nil(t, err, "Unexpected failure getting node")
+		return node.Annotations
+	}
+
+	err := reboot.Write(markerFile, &reboot.Marker{Reason: reboot.ReasonResetSkipped})
+	require.Nil(t, err, "Unexpected failure writing reboot marker")
+	err = syncRebootAnnotation(clientset, testNodeName, markerFile, key)
+	require.Nil(t, err, "Unexpected failure from syncRebootAnnotation")
+	require.Equal(t, map[string]string{key: DefaultRebootAnnotationValue}, annotations())
+
+	err = reboot.Clear(markerFile)
+	require.Nil(t, err, "Unexpected failure clearing reboot marker")
+	err = syncRebootAnnotation(clientset, testNodeName, markerFile, key)
+	require.Nil(t, err, "Unexpected failure from syncRebootAnnotation")
+	require.Empty(t, annotations())
+}
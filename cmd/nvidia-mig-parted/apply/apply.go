@@ -17,9 +17,13 @@
 package apply
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -27,13 +31,31 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
 	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/fabric"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/mps"
+	"github.com/NVIDIA/mig-parted/pkg/policy"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
 
 	"sigs.k8s.io/yaml"
 )
 
 var log = logrus.New()
 
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
 // GetLogger returns the 'logrus.Logger' instance used by this package.
 func GetLogger() *logrus.Logger {
 	return log
@@ -42,13 +64,82 @@ func GetLogger() *logrus.Logger {
 // Flags holds variables that represent the set of flags that can be passed to the 'apply' subcommand.
 type Flags struct {
 	assert.Flags
-	HooksFile string
+	HooksFile    string
+	OutputFormat string
+
+	MaxPermutationAttempts int
+	PermutationTimeout     time.Duration
+
+	TelemetrySink    string
+	AssumeYes        bool
+	PolicyFile       string
+	RebootMarkerFile string
+	MpsConfigFile    string
+	MpsPipeDirectory string
+	MpsLogDirectory  string
+	JournalFile      string
+	StoreFile        string
+	KeepGoing        bool
+	DryRun           bool
+	Shadow           bool
+	PlanFile         string
+	DetailedExitCode bool
+
+	FabricPartition    int
+	FabricCoordination string
+	FmpmPath           string
+
+	DeviceOrder     string
+	DeviceOrderFile string
+
+	MaxUtilization    int
+	UtilizationWindow time.Duration
+	UtilizationWait   time.Duration
+
+	EnableGPUReset bool
+
+	NodeLockFile    string
+	NodeLockTimeout time.Duration
+
+	UnhealthyGPUs         string
+	MaxECCErrors          uint64
+	MaxRetiredPages       int
+	IgnoreThermalThrottle bool
+
+	// Trigger records in the journal what made the apply, if not the user
+	// (e.g. a scheduled switch by the daemon). It is not set by any flag.
+	Trigger string
 }
 
 // Context holds the state we want to pass around between functions associated with the 'apply' subcommand.
 type Context struct {
 	assert.Context
-	Flags *Flags
+	Flags   *Flags
+	Hooks   ApplyHooks
+	Results []Result
+
+	lostGPUs map[int]error
+	// changedGPUs records the GPUs whose MIG mode, CC mode or MIG devices
+	// were changed, for the audit entry of the apply.
+	changedGPUs map[int]bool
+
+	fabric          fabric.Interface
+	fabricPartition *fabric.Partition
+	fabricService   fabricService
+
+	deviceOrder []int
+
+	cancelState
+}
+
+// Result holds the set of MIG devices created on a specific GPU while applying a MIG configuration.
+// If the GPU was lost while applying the configuration, 'Error' holds the reason instead. If it was
+// left out of the apply for being unhealthy (see '--unhealthy-gpus'), 'Excluded' holds why.
+type Result struct {
+	GPU        int               `json:"gpu"`
+	MigDevices []types.MigDevice `json:"mig-devices"`
+	Error      string            `json:"error,omitempty"`
+	Excluded   string            `json:"excluded,omitempty"`
 }
 
 // MigConfigApplier is an interface representing the set of functions required to "Apply" a MIG configuration to a node.
@@ -67,6 +158,7 @@ func BuildCommand() *cli.Command {
 	// Create the 'apply' command
 	apply := cli.Command{}
 	apply.Name = "apply"
+	apply.Aliases = []string{"set"}
 	apply.Usage = "Apply changes (if necessary) for a specific MIG configuration from a configuration file"
 	apply.Action = func(c *cli.Context) error {
 		return applyWrapper(c, &applyFlags)
@@ -74,27 +166,40 @@ func BuildCommand() *cli.Command {
 
 	// Setup the flags for this command
 	apply.Flags = []cli.Flag{
+		util.ConfigFileFlag(&applyFlags.ConfigFile, "Path to the configuration file, or a directory of spec fragments to merge ('-' for stdin)"),
+		util.SelectedConfigFlag(&applyFlags.SelectedConfig, "The label of the mig-config from the config file to apply to the node"),
+		util.SelectedConfigFromFlag(&applyFlags.SelectedConfigFrom),
+		&cli.StringFlag{
+			Name:        "ci-config-file",
+			Usage:       "Path to a configuration file whose 'compute-instance-configs' split the GPU instances of the selected config",
+			Destination: &applyFlags.CIConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CI_CONFIG_FILE"},
+		},
 		&cli.StringFlag{
-			Name:        "config-file",
-			Aliases:     []string{"f"},
-			Usage:       "Path to the configuration file",
-			Destination: &applyFlags.ConfigFile,
-			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+			Name:        "ci-selected-config",
+			Usage:       "The label of the compute-instance-config from the ci-config-file to apply to the node",
+			Destination: &applyFlags.CISelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_CI_SELECTED_CONFIG"},
 		},
 		&cli.StringFlag{
-			Name:        "selected-config",
-			Aliases:     []string{"c"},
-			Usage:       "The label of the mig-config from the config file to apply to the node",
-			Destination: &applyFlags.SelectedConfig,
-			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+			Name:        "trusted-keys",
+			Usage:       "Path to a public key (or a directory of them) that config files must be signed with ('cosign' PEM or 'minisign' keys)",
+			Destination: &applyFlags.TrustedKeys,
+			EnvVars:     []string{"MIG_PARTED_TRUSTED_KEYS"},
 		},
 		&cli.StringFlag{
-			Name:        "hooks-file",
-			Aliases:     []string{"k"},
-			Usage:       "Path to the hooks file",
-			Destination: &applyFlags.HooksFile,
-			EnvVars:     []string{"MIG_PARTED_HOOKS_FILE"},
+			Name:        "config-signature",
+			Usage:       "Path to the detached signature of the config file (defaults to the config file path with '.sig' appended)",
+			Destination: &applyFlags.ConfigSignature,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_SIGNATURE"},
 		},
+		&cli.BoolFlag{
+			Name:        "strict-profiles",
+			Usage:       "Reject MIG profiles in config files that are not written in their canonical form (e.g. '1G.5GB' or '01g.5gb')",
+			Destination: &applyFlags.StrictProfiles,
+			EnvVars:     []string{"MIG_PARTED_STRICT_PROFILES"},
+		},
+		util.HooksFileFlag(&applyFlags.HooksFile),
 		&cli.BoolFlag{
 			Name:        "skip-reset",
 			Aliases:     []string{"s"},
@@ -103,12 +208,199 @@ func BuildCommand() *cli.Command {
 			EnvVars:     []string{"MIG_PARTED_SKIP_RESET"},
 		},
 		&cli.BoolFlag{
-			Name:        "mode-only",
-			Aliases:     []string{"m"},
-			Usage:       "Only change the MIG enabled setting from the config, not configure any MIG devices",
-			Destination: &applyFlags.ModeOnly,
-			EnvVars:     []string{"MIG_PARTED_MODE_CHANGE_ONLY"},
+			Name:        "enable-gpu-reset",
+			Usage:       "Reset GPUs pending a row remapping or page retirement before reconfiguring them, rather than refusing to",
+			Destination: &applyFlags.EnableGPUReset,
+			EnvVars:     []string{"MIG_PARTED_ENABLE_GPU_RESET"},
+		},
+		util.ModeOnlyFlag(&applyFlags.ModeOnly, "Only change the MIG enabled setting from the config, not configure any MIG devices"),
+		util.OutputFormatFlag(&applyFlags.OutputFormat, TextFormat, JSONFormat),
+		&cli.IntFlag{
+			Name:        "max-permutation-attempts",
+			Usage:       "Maximum number of MIG device orderings to try per GPU before failing (0 for no limit, overridden by the config's 'permutation-budget')",
+			Destination: &applyFlags.MaxPermutationAttempts,
+			EnvVars:     []string{"MIG_PARTED_MAX_PERMUTATION_ATTEMPTS"},
+		},
+		&cli.DurationFlag{
+			Name:        "permutation-timeout",
+			Usage:       "Maximum time to spend trying MIG device orderings per GPU before failing (0 for no limit, overridden by the config's 'permutation-budget')",
+			Destination: &applyFlags.PermutationTimeout,
+			EnvVars:     []string{"MIG_PARTED_PERMUTATION_TIMEOUT"},
+		},
+		&cli.BoolFlag{
+			Name:        "assume-yes",
+			Aliases:     []string{"y"},
+			Usage:       "Do not ask for confirmation before destroying existing MIG devices",
+			Destination: &applyFlags.AssumeYes,
+			EnvVars:     []string{"MIG_PARTED_ASSUME_YES"},
+		},
+		&cli.BoolFlag{
+			Name:        "keep-going",
+			Usage:       "Continue with the remaining GPUs if a GPU falls off the bus while applying the MIG config",
+			Destination: &applyFlags.KeepGoing,
+			EnvVars:     []string{"MIG_PARTED_KEEP_GOING"},
+		},
+		&cli.BoolFlag{
+			Name:        "dry-run",
+			Usage:       "Print the operations that applying the MIG config would perform without performing them ('-o json' prints a plan for use with '--plan')",
+			Destination: &applyFlags.DryRun,
+			EnvVars:     []string{"MIG_PARTED_DRY_RUN"},
+		},
+		&cli.BoolFlag{
+			Name:        "shadow",
+			Usage:       "Simulate applying the MIG config against an in-memory copy of each GPU, printing every operation and the resulting MIG devices without touching the GPUs",
+			Destination: &applyFlags.Shadow,
+			EnvVars:     []string{"MIG_PARTED_SHADOW"},
+		},
+		&cli.StringFlag{
+			Name:        "plan",
+			Usage:       "Path to a plan written by '--dry-run -o json' to apply exactly, instead of a config file ('-' for stdin)",
+			Destination: &applyFlags.PlanFile,
+			EnvVars:     []string{"MIG_PARTED_PLAN_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "detailed-exitcode",
+			Usage:       fmt.Sprintf("Exit with %v if the MIG config was already applied, %v if changes were applied and %v on error (with '--dry-run', %v if changes would be applied)", ExitCodeNoChanges, ExitCodeChanged, ExitCodeError, ExitCodeChanged),
+			Destination: &applyFlags.DetailedExitCode,
+			EnvVars:     []string{"MIG_PARTED_DETAILED_EXITCODE"},
+		},
+		&cli.StringFlag{
+			Name:        "policy-file",
+			Usage:       "Path to a policy file restricting which MIG configs may be applied",
+			Destination: &applyFlags.PolicyFile,
+			EnvVars:     []string{"MIG_PARTED_POLICY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Usage:       "Path to write a JSON marker to when a reboot is required for a MIG mode change to take effect (disabled if empty)",
+			Destination: &applyFlags.RebootMarkerFile,
+			Value:       reboot.DefaultMarkerFile,
+			EnvVars:     []string{"MIG_PARTED_REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "mps-config-file",
+			Usage:       "Path to write the MIG devices marked 'mps' in the selected config to, for the 'mps-config' hook or an MPS control daemon launcher (disabled if empty)",
+			Destination: &applyFlags.MpsConfigFile,
+			Value:       mps.DefaultConfigFile,
+			EnvVars:     []string{"MIG_PARTED_MPS_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "mps-pipe-directory",
+			Usage:       "Directory to create the pipe directory of the MPS control daemon of each MIG device marked 'mps' under",
+			Destination: &applyFlags.MpsPipeDirectory,
+			Value:       mps.DefaultPipeDirectory,
+			EnvVars:     []string{"MIG_PARTED_MPS_PIPE_DIRECTORY"},
+		},
+		&cli.StringFlag{
+			Name:        "mps-log-directory",
+			Usage:       "Directory to create the log directory of the MPS control daemon of each MIG device marked 'mps' under",
+			Destination: &applyFlags.MpsLogDirectory,
+			Value:       mps.DefaultLogDirectory,
+			EnvVars:     []string{"MIG_PARTED_MPS_LOG_DIRECTORY"},
+		},
+		&cli.StringFlag{
+			Name:        "journal-file",
+			Usage:       "Path to the journal to record the outcome of each apply in, as reported by 'status' (disabled if empty)",
+			Destination: &applyFlags.JournalFile,
+			Value:       journal.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_JOURNAL_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "store-file",
+			Usage:       "Path to the store to record the labels of the entries of the applied MIG config in, and drop the leases of the scratch GPU instances it replaces from (disabled if empty)",
+			Destination: &applyFlags.StoreFile,
+			Value:       store.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
+		},
+		&cli.IntFlag{
+			Name:        "fabric-partition",
+			Usage:       "Only apply the MIG config to the GPUs of this Fabric Manager partition, treating all other GPUs as unmanaged (-1 for all GPUs)",
+			Destination: &applyFlags.FabricPartition,
+			Value:       -1,
+			EnvVars:     []string{"MIG_PARTED_FABRIC_PARTITION"},
+		},
+		&cli.StringFlag{
+			Name:        "fabric-coordination",
+			Usage:       "How to detach GPUs from the NVSwitch fabric while changing MIG mode: [partition | service | none]",
+			Destination: &applyFlags.FabricCoordination,
+			Value:       FabricCoordinationPartition,
+			EnvVars:     []string{"MIG_PARTED_FABRIC_COORDINATION"},
+		},
+		&cli.StringFlag{
+			Name:        "fmpm-path",
+			Usage:       "Path to the Fabric Manager partition manager CLI used with '--fabric-partition'",
+			Destination: &applyFlags.FmpmPath,
+			Value:       fabric.DefaultFmpmPath,
+			EnvVars:     []string{"MIG_PARTED_FMPM_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "device-order",
+			Usage:       "Order in which to walk the GPUs on the node: [index | pci | uuid-file]",
+			Destination: &applyFlags.DeviceOrder,
+			Value:       DeviceOrderIndex,
+			EnvVars:     []string{"MIG_PARTED_DEVICE_ORDER"},
+		},
+		&cli.StringFlag{
+			Name:        "device-order-file",
+			Usage:       "Path to the file listing GPU UUIDs (one per line) in the order to walk them with '--device-order=uuid-file' ('-' to read from stdin)",
+			Destination: &applyFlags.DeviceOrderFile,
+			EnvVars:     []string{"MIG_PARTED_DEVICE_ORDER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "telemetry-sink",
+			Usage:       "Opt-in sink for anonymized apply events ('file:///path' or 'http(s)://...', disabled if empty)",
+			Destination: &applyFlags.TelemetrySink,
+			EnvVars:     []string{"MIG_PARTED_TELEMETRY_SINK"},
+		},
+		&cli.IntFlag{
+			Name:        "max-utilization",
+			Usage:       "Refuse to change GPUs while their utilization, or that of one of their MIG devices, exceeds this percentage (-1 to disable)",
+			Destination: &applyFlags.MaxUtilization,
+			Value:       -1,
+			EnvVars:     []string{"MIG_PARTED_MAX_UTILIZATION"},
+		},
+		&cli.DurationFlag{
+			Name:        "utilization-window",
+			Usage:       "How long to sample the utilization of the GPUs to change for with '--max-utilization'",
+			Destination: &applyFlags.UtilizationWindow,
+			Value:       5 * time.Second,
+			EnvVars:     []string{"MIG_PARTED_UTILIZATION_WINDOW"},
+		},
+		&cli.DurationFlag{
+			Name:        "utilization-wait",
+			Usage:       "How long to keep sampling for the GPUs to change to become idle enough with '--max-utilization' before refusing (0 to refuse right away)",
+			Destination: &applyFlags.UtilizationWait,
+			EnvVars:     []string{"MIG_PARTED_UTILIZATION_WAIT"},
+		},
+		util.TextfileDirFlag(&applyFlags.TextfileDir),
+		&cli.StringFlag{
+			Name:        "unhealthy-gpus",
+			Usage:       "What to do with GPUs whose health signals are past their thresholds: [ignore | exclude | refuse]",
+			Destination: &applyFlags.UnhealthyGPUs,
+			Value:       UnhealthyGPUsIgnore,
+			EnvVars:     []string{"MIG_PARTED_UNHEALTHY_GPUS"},
 		},
+		&cli.Uint64Flag{
+			Name:        "max-ecc-errors",
+			Usage:       "Number of uncorrectable ECC errors since the driver was loaded past which a GPU is unhealthy",
+			Destination: &applyFlags.MaxECCErrors,
+			EnvVars:     []string{"MIG_PARTED_MAX_ECC_ERRORS"},
+		},
+		&cli.IntFlag{
+			Name:        "max-retired-pages",
+			Usage:       "Number of retired memory pages past which a GPU is unhealthy",
+			Destination: &applyFlags.MaxRetiredPages,
+			Value:       DefaultMaxRetiredPages,
+			EnvVars:     []string{"MIG_PARTED_MAX_RETIRED_PAGES"},
+		},
+		&cli.BoolFlag{
+			Name:        "ignore-thermal-throttle",
+			Usage:       "Do not consider GPUs whose clocks are throttled for being too hot unhealthy",
+			Destination: &applyFlags.IgnoreThermalThrottle,
+			EnvVars:     []string{"MIG_PARTED_IGNORE_THERMAL_THROTTLE"},
+		},
+		util.NodeLockFileFlag(&applyFlags.NodeLockFile),
+		util.NodeLockTimeoutFlag(&applyFlags.NodeLockTimeout),
 	}
 
 	return &apply
@@ -116,15 +408,102 @@ func BuildCommand() *cli.Command {
 
 // CheckFlags ensures that any required flags are provided and ensures they are well-formed.
 func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case TextFormat:
+	case JSONFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	stdin := 0
+	for _, file := range []string{f.ConfigFile, f.CIConfigFile, f.HooksFile, f.PolicyFile, f.DeviceOrderFile, f.PlanFile} {
+		if util.IsStdio(file) {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return fmt.Errorf("only one of 'config-file', 'ci-config-file', 'hooks-file', 'policy-file', 'device-order-file' and 'plan' can be read from stdin")
+	}
+	if f.MaxPermutationAttempts < 0 {
+		return fmt.Errorf("invalid 'max-permutation-attempts': %v", f.MaxPermutationAttempts)
+	}
+	if f.PermutationTimeout < 0 {
+		return fmt.Errorf("invalid 'permutation-timeout': %v", f.PermutationTimeout)
+	}
+	if f.FabricPartition < -1 {
+		return fmt.Errorf("invalid 'fabric-partition': %v", f.FabricPartition)
+	}
+	switch f.FabricCoordination {
+	case FabricCoordinationPartition:
+	case FabricCoordinationService:
+	case FabricCoordinationNone:
+	default:
+		return fmt.Errorf("unrecognized 'fabric-coordination': %v", f.FabricCoordination)
+	}
+	switch f.DeviceOrder {
+	case DeviceOrderIndex:
+	case DeviceOrderPCI:
+	case DeviceOrderUUIDFile:
+		if f.DeviceOrderFile == "" {
+			return fmt.Errorf("'device-order=uuid-file' requires 'device-order-file'")
+		}
+	default:
+		return fmt.Errorf("unrecognized 'device-order': %v", f.DeviceOrder)
+	}
+	if f.DeviceOrderFile != "" && f.DeviceOrder != DeviceOrderUUIDFile {
+		return fmt.Errorf("'device-order-file' requires 'device-order=uuid-file'")
+	}
+	if f.MaxUtilization < -1 || f.MaxUtilization > 100 {
+		return fmt.Errorf("invalid 'max-utilization': %v", f.MaxUtilization)
+	}
+	if f.MaxUtilization >= 0 && f.UtilizationWindow <= 0 {
+		return fmt.Errorf("invalid 'utilization-window': %v", f.UtilizationWindow)
+	}
+	if f.UtilizationWait < 0 {
+		return fmt.Errorf("invalid 'utilization-wait': %v", f.UtilizationWait)
+	}
+	switch f.UnhealthyGPUs {
+	case UnhealthyGPUsIgnore:
+	case UnhealthyGPUsExclude:
+	case UnhealthyGPUsRefuse:
+	default:
+		return fmt.Errorf("unrecognized 'unhealthy-gpus': %v", f.UnhealthyGPUs)
+	}
+	if f.MaxRetiredPages < 0 {
+		return fmt.Errorf("invalid 'max-retired-pages': %v", f.MaxRetiredPages)
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
+	if f.EnableGPUReset && f.SkipReset {
+		return fmt.Errorf("'enable-gpu-reset' cannot be combined with 'skip-reset'")
+	}
+	if f.DryRun && f.Shadow {
+		return fmt.Errorf("'dry-run' cannot be combined with 'shadow'")
+	}
+	if f.DetailedExitCode && f.Shadow {
+		return fmt.Errorf("'detailed-exitcode' cannot be combined with 'shadow'")
+	}
+	if f.PlanFile != "" {
+		if f.ConfigFile != "" || f.CIConfigFile != "" || f.PolicyFile != "" {
+			return fmt.Errorf("'plan' cannot be combined with 'config-file', 'ci-config-file' or 'policy-file'")
+		}
+		if f.DryRun || f.Shadow || f.ModeOnly {
+			return fmt.Errorf("'plan' cannot be combined with 'dry-run', 'shadow' or 'mode-only'")
+		}
+		if f.FabricPartition >= 0 {
+			return fmt.Errorf("'plan' cannot be combined with 'fabric-partition'")
+		}
+		if f.DeviceOrder != DeviceOrderIndex {
+			return fmt.Errorf("'plan' cannot be combined with 'device-order'")
+		}
+		return nil
+	}
 	return assert.CheckFlags(&f.Flags)
 }
 
 // ParseHooksFile parses a hoosk file and unmarshals it into a 'hooks.Spec'.
 func ParseHooksFile(hooksFile string) (*hooks.Spec, error) {
-	var err error
-	var hooksYaml []byte
-
-	hooksYaml, err = os.ReadFile(hooksFile)
+	hooksYaml, err := util.ReadFile(hooksFile)
 	if err != nil {
 		return nil, fmt.Errorf("read error: %v", err)
 	}
@@ -167,7 +546,22 @@ func (c *Context) AssertMigMode() error {
 }
 
 // ApplyMigMode applies the MIG mode settings of the config embedded in the 'Context' to the set of GPUs on the node.
-func (c *Context) ApplyMigMode() error {
+// GPUs are detached from the NVSwitch fabric (as selected by '--fabric-coordination') while their mode changes.
+func (c *Context) ApplyMigMode() (rerr error) {
+	reattach, err := c.detachFromFabric()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := reattach()
+		if rerr == nil {
+			rerr = err
+			return
+		}
+		if err != nil {
+			log.Errorf("%v", err)
+		}
+	}()
 	return ApplyMigMode(c)
 }
 
@@ -189,16 +583,214 @@ func applyWrapper(c *cli.Context, f *Flags) error {
 		return err
 	}
 
+	if f.DryRun {
+		return dryRunWrapper(c, f)
+	}
+
+	if f.Shadow {
+		return shadowWrapper(c, f)
+	}
+
+	apply := applyConfig
+	if f.PlanFile != "" {
+		apply = applyPlan
+	}
+
+	ctx, stop := notifyOnSignal(c.Context)
+	defer stop()
+	c.Context = ctx
+
+	results, changed, err := apply(c, f)
+	var veto *hooks.VetoError
+	if errors.As(err, &veto) {
+		return fmt.Errorf("MIG configuration not applied: %v", veto)
+	}
+	var canceled *CanceledError
+	if errors.As(err, &canceled) {
+		return fmt.Errorf("MIG configuration partially applied: %v", canceled)
+	}
+	if err != nil && results == nil {
+		return err
+	}
+
+	if f.OutputFormat == JSONFormat {
+		output, merr := json.MarshalIndent(results, "", "  ")
+		if merr != nil {
+			return fmt.Errorf("error marshaling apply results to JSON: %v", merr)
+		}
+		fmt.Println(string(output))
+		if err != nil {
+			return err
+		}
+		return detailedExitCode(f, changed)
+	}
+
+	if err != nil {
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Printf("GPU %d: failed: %v\n", r.GPU, r.Error)
+			}
+		}
+		return err
+	}
+
+	for _, r := range results {
+		if r.Excluded != "" {
+			fmt.Printf("GPU %d: excluded: %v\n", r.GPU, r.Excluded)
+		}
+	}
+	fmt.Println("MIG configuration applied successfully")
+	return detailedExitCode(f, changed)
+}
+
+func dryRunWrapper(c *cli.Context, f *Flags) error {
+	plan, err := DryRun(c, f)
+	if err != nil {
+		return err
+	}
+
+	if f.OutputFormat == JSONFormat {
+		err := WritePlan(os.Stdout, plan)
+		if err != nil {
+			return err
+		}
+		return detailedExitCode(f, len(plan.GPUs) > 0)
+	}
+
+	if len(plan.GPUs) == 0 {
+		fmt.Println("No changes required")
+		return nil
+	}
+	for _, g := range plan.GPUs {
+		for _, step := range g.Steps {
+			fmt.Println(step.Description)
+		}
+	}
+	return detailedExitCode(f, true)
+}
+
+// WritePlan writes 'plan' to 'w' as the JSON printed by 'apply --dry-run
+// --output-format json' (see the 'plan' schema in api/schema).
+func WritePlan(w io.Writer, plan *planv1.Plan) error {
+	output, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling plan to JSON: %v", err)
+	}
+	_, err = fmt.Fprintln(w, string(output))
+	if err != nil {
+		return fmt.Errorf("error writing plan: %w", err)
+	}
+	return nil
+}
+
+// Apply parses the config and hooks files referenced in 'f' and applies the selected MIG config
+// (running all hooks along the way). It returns the set of MIG devices created on each GPU.
+// If GPUs were lost and 'f.KeepGoing' is set, it returns the results for all GPUs along with an error.
+func Apply(c *cli.Context, f *Flags) ([]Result, error) {
+	results, _, err := applyConfig(c, f)
+	return results, err
+}
+
+// applyConfig is like Apply, but also returns whether any change was applied.
+// Only GPUs whose MIG mode or MIG devices do not already match the selected
+// MIG config are changed, so applying it again changes nothing.
+func applyConfig(c *cli.Context, f *Flags) ([]Result, bool, error) {
+	context, err := newContext(c, f)
+	if err != nil {
+		return nil, false, err
+	}
+
+	events, err := newApplyTelemetry(f.TelemetrySink)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating telemetry sink: %v", err)
+	}
+	defer events.close()
+
+	if !f.AssumeYes && util.IsTerminal(os.Stdin) {
+		err := confirmApply(context)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	// Take the node lock before checking the GPUs, since doing so may reset
+	// them (see '--enable-gpu-reset') and what is checked must not change
+	// before the MIG config is applied.
+	unlock, err := context.lockNode()
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	err = checkChangedGPUs(context)
+	if err != nil {
+		return nil, false, fmt.Errorf("refusing to apply MIG configuration: %w", err)
+	}
+
+	start := time.Now()
+	placement := config.GetMetrics()
+	defer func() { logPlacementMetrics(config.GetMetrics().Sub(placement)) }()
+	events.started()
+	applier := &changeTracker{MigConfigApplier: context}
+	err = ApplyMigConfigWithHooks(log, c, f.ModeOnly, context.Hooks, applier)
+	invalidateScratch(context)
+	if err == nil && !f.ModeOnly {
+		err = updateMpsConfig(context)
+	}
+	if err == nil {
+		err = context.lostGPUsError()
+		events.finished(err)
+		RecordApply(f, f.SelectedConfig, start, err)
+		recordLabels(context)
+		auditApply(context, f.SelectedConfig, err)
+		return context.Results, applier.changed, err
+	}
+	events.finished(err)
+	RecordApply(f, f.SelectedConfig, start, err)
+	auditApply(context, f.SelectedConfig, err)
+	if hint := nvmlErrorHint(err); hint != "" {
+		log.Warn(hint)
+	}
+	return nil, applier.changed, fmt.Errorf("error applying MIG configuration with hooks: %w", err)
+}
+
+// DryRun parses the config files referenced in 'f' and returns a plan of the
+// operations that applying the selected MIG config would perform, without
+// performing them.
+func DryRun(c *cli.Context, f *Flags) (*planv1.Plan, error) {
+	context, err := newContext(c, f)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := NewPlan(context)
+	if err != nil {
+		return nil, fmt.Errorf("error planning operations: %v", err)
+	}
+
+	return plan, nil
+}
+
+// newContext parses the config, hooks and policy files referenced in 'f' and
+// builds the 'Context' for applying the selected MIG config, checking it
+// against the policy if there is one.
+func newContext(c *cli.Context, f *Flags) (*Context, error) {
 	log.Debugf("Parsing config file...")
 	spec, err := assert.ParseConfigFile(&f.Flags)
 	if err != nil {
-		return fmt.Errorf("error parsing config file: %v", err)
+		return nil, fmt.Errorf("error parsing config file: %v", err)
 	}
 
 	log.Debugf("Selecting specific MIG config...")
 	migConfig, err := assert.GetSelectedMigConfig(&f.Flags, spec)
 	if err != nil {
-		return fmt.Errorf("error selecting MIG config: %v", err)
+		return nil, fmt.Errorf("error selecting MIG config: %v", err)
+	}
+
+	log.Debugf("Selecting specific compute instance config...")
+	ciConfig, err := assert.GetSelectedComputeInstanceConfig(&f.Flags)
+	if err != nil {
+		return nil, fmt.Errorf("error selecting compute instance config: %v", err)
 	}
 
 	hooksSpec := &hooks.Spec{}
@@ -206,45 +798,148 @@ func applyWrapper(c *cli.Context, f *Flags) error {
 		log.Debugf("Parsing Hooks file...")
 		hooksSpec, err = ParseHooksFile(f.HooksFile)
 		if err != nil {
-			return fmt.Errorf("error parsing hooks file: %v", err)
+			return nil, fmt.Errorf("error parsing hooks file: %v", err)
 		}
 	}
 
-	hooks := NewApplyHooks(hooksSpec.Hooks)
+	var applyPolicy *policy.Policy
+	if f.PolicyFile != "" {
+		log.Debugf("Parsing policy file...")
+		applyPolicy, err = ParsePolicyFile(f.PolicyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing policy file: %v", err)
+		}
+	}
 
-	context := Context{
-		Flags: f,
+	context := &Context{
+		Flags:       f,
+		Hooks:       NewApplyHooks(hooksSpec.Hooks),
+		Results:     []Result{},
+		cancelState: cancelState{ctx: c.Context},
 		Context: assert.Context{
-			Context:   c,
-			Flags:     &f.Flags,
-			MigConfig: migConfig,
-			Nvml:      nvml.New(),
+			Context:               c,
+			Flags:                 &f.Flags,
+			MigConfig:             migConfig,
+			ComputeInstanceConfig: ciConfig,
+			UnmanagedDevices:      spec.UnmanagedDevices,
+			Nvml:                  nvml.New(),
 		},
 	}
 
-	err = ApplyMigConfigWithHooks(log, c, f.ModeOnly, hooks, &context)
+	log.Debugf("Checking version requirements of selected MIG config...")
+	err = assert.AssertRequirements(&context.Context)
 	if err != nil {
-		return fmt.Errorf("error applying MIG configuration with hooks: %v", err)
+		return nil, fmt.Errorf("node incompatible with selected MIG config: %v", err)
+	}
+
+	if f.FabricPartition >= 0 {
+		log.Debugf("Scoping MIG config to fabric partition %v...", f.FabricPartition)
+		pciBusIDs, err := util.GetGPUPciBusIDs()
+		if err != nil {
+			return nil, fmt.Errorf("error getting GPU PCI bus IDs: %v", err)
+		}
+		err = context.scopeToFabricPartition(fabric.NewFmpm(f.FmpmPath), pciBusIDs)
+		if err != nil {
+			return nil, fmt.Errorf("error scoping MIG config to fabric partition: %v", err)
+		}
+	}
+	err = context.checkGPUHealth()
+	if err != nil {
+		return nil, fmt.Errorf("refusing to apply MIG configuration: %w", err)
+	}
+	if f.FabricCoordination == FabricCoordinationService {
+		context.fabricService = fabric.NewService(fabric.DefaultServiceName)
+	}
+
+	log.Debugf("Ordering GPUs by %v...", f.DeviceOrder)
+	orderer, err := NewDeviceOrderer(f)
+	if err != nil {
+		return nil, fmt.Errorf("error creating device orderer: %v", err)
+	}
+	context.deviceOrder, err = orderer.Order()
+	if err != nil {
+		return nil, fmt.Errorf("error ordering GPUs: %v", err)
+	}
+
+	if len(hooksSpec.Hooks[preGpuConfigHook]) != 0 && !f.ModeOnly {
+		log.Debugf("Running pre-gpu-config hooks...")
+		err := context.applyPreGpuConfigHooks(GetHooksEnvsMap(c), c.Bool("debug"))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if applyPolicy != nil {
+		log.Debugf("Checking selected MIG config against policy...")
+		err := CheckPolicy(context, applyPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("error checking policy: %v", err)
+		}
+	}
+
+	return context, nil
+}
+
+// confirmApply asks for confirmation on the terminal if applying the config in
+// 'c' would destroy any existing MIG devices.
+func confirmApply(c *Context) error {
+	changes, err := GetDestructiveChanges(c)
+	if err != nil {
+		return fmt.Errorf("error checking for destructive changes: %v", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	confirmed, err := ConfirmDestructiveChanges(os.Stdin, os.Stderr, c.Flags.SelectedConfig, changes)
+	if err != nil {
+		return err
+	}
+	if !confirmed {
+		return fmt.Errorf("aborted: existing MIG devices left untouched (use '--assume-yes' to skip this prompt)")
 	}
 
-	fmt.Println("MIG configuration applied successfully")
 	return nil
 }
 
+// nvmlErrorHint returns a suggestion for how to resolve 'err' based on the
+// class of NVML failure that caused it, or an empty string if there is none.
+func nvmlErrorHint(err error) string {
+	switch {
+	case errors.Is(err, nvmlerrors.ErrInUse):
+		return "One or more GPUs are in use; stop all processes using them and try again"
+	case errors.Is(err, nvmlerrors.ErrInsufficientResources):
+		return "Not enough free resources for the selected MIG configuration; check that it fits on the GPU"
+	case errors.Is(err, nvmlerrors.ErrNeedsReset):
+		return "One or more GPUs must be reset before the MIG configuration can be applied"
+	case errors.Is(err, nvmlerrors.ErrGpuLost):
+		return "One or more GPUs fell off the bus; check the kernel log for Xid errors (use '--keep-going' to apply the config to the remaining GPUs)"
+	case errors.Is(err, nvmlerrors.ErrVGPUGuest):
+		return "Running inside a vGPU guest VM, where MIG mode and GPU instances are set by the hypervisor; select a config matching the vGPU's existing GPU instances"
+	case errors.Is(err, nvmlerrors.ErrNoPermission), errors.Is(err, migcaps.ErrInsufficientPrivilege):
+		return "Insufficient privilege for the MIG configuration; run as root (see 'Running without root' in the README)"
+	case errors.Is(err, nvmlerrors.ErrNotSupported):
+		return "The requested operation is not supported by one or more GPUs"
+	case errors.Is(err, operation.ErrConcurrentModification):
+		return "The MIG devices of a GPU were changed by another process (e.g. 'nvidia-smi mig') during the apply; stop anything else reconfiguring MIG devices (see '--node-lock-file') and apply again"
+	}
+	return ""
+}
+
 // ApplyMigConfigWithHooks orchestrates the calls of a 'MigConfigApplier' between a set of 'ApplyHooks' to the set MIG configuration of a node.
 // If 'modeOnly' is 'true', then only the MIG mode settings embedded in the 'Context' are applied.
 func ApplyMigConfigWithHooks(logger *logrus.Logger, context *cli.Context, modeOnly bool, hooks ApplyHooks, applier MigConfigApplier) (rerr error) {
 	logger.Debugf("Running apply-start hook")
 	err := hooks.ApplyStart(GetHooksEnvsMap(context), context.Bool("debug"))
 	if err != nil {
-		return fmt.Errorf("error running apply-start hook: %v", err)
+		return fmt.Errorf("error running apply-start hook: %w", err)
 	}
 
 	defer func() {
 		logger.Debugf("Running apply-exit hook")
 		err := hooks.ApplyExit(GetHooksEnvsMap(context), context.Bool("debug"))
 		if rerr == nil && err != nil {
-			rerr = fmt.Errorf("error running apply-exit hook: %v", err)
+			rerr = fmt.Errorf("error running apply-exit hook: %w", err)
 			return
 		}
 		if err != nil {
@@ -258,7 +953,7 @@ func ApplyMigConfigWithHooks(logger *logrus.Logger, context *cli.Context, modeOn
 		logger.Debugf("Running pre-apply-mode hook")
 		err := hooks.PreApplyMode(GetHooksEnvsMap(context), context.Bool("debug"))
 		if err != nil {
-			return fmt.Errorf("error running pre-apply-mode hook: %v", err)
+			return fmt.Errorf("error running pre-apply-mode hook: %w", err)
 		}
 
 		logger.Debugf("Applying MIG mode change...")
@@ -278,7 +973,7 @@ func ApplyMigConfigWithHooks(logger *logrus.Logger, context *cli.Context, modeOn
 		logger.Debugf("Running pre-apply-config hook")
 		err := hooks.PreApplyConfig(GetHooksEnvsMap(context), context.Bool("debug"))
 		if err != nil {
-			return fmt.Errorf("error running pre-apply-config hook: %v", err)
+			return fmt.Errorf("error running pre-apply-config hook: %w", err)
 		}
 
 		logger.Debugf("Applying MIG device configuration...")
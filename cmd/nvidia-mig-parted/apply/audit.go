@@ -0,0 +1,48 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"sort"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+)
+
+// markChanged records that the MIG mode, CC mode or MIG devices of GPU 'i'
+// were changed.
+func (c *Context) markChanged(i int) {
+	if c.changedGPUs == nil {
+		c.changedGPUs = make(map[int]bool)
+	}
+	c.changedGPUs[i] = true
+}
+
+// getChangedGPUs returns the indices of the GPUs changed so far, in order.
+func (c *Context) getChangedGPUs() []int {
+	var gpus []int
+	for i := range c.changedGPUs {
+		gpus = append(gpus, i)
+	}
+	sort.Ints(gpus)
+	return gpus
+}
+
+// auditApply records an apply of 'selectedConfig' that returned 'err' in the
+// system journal, along with the GPUs it changed.
+func auditApply(c *Context, selectedConfig string, err error) {
+	util.Audit(log, "apply", selectedConfig, c.getChangedGPUs(), applyOutcome(err), err)
+}
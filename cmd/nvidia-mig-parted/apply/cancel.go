@@ -0,0 +1,145 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// CanceledError is returned when an apply is canceled. The apply only stops
+// at a safe point (between GPUs, or between destroying and creating MIG
+// devices on a GPU, in which case that GPU is rolled back), so every GPU is
+// either fully configured or left as it was.
+type CanceledError struct {
+	Completed []int
+	Pending   []int
+	Err       error
+}
+
+var _ error = (*CanceledError)(nil)
+
+func (e *CanceledError) Error() string {
+	return fmt.Sprintf("apply canceled (%v): completed GPU(s) %v, pending GPU(s) %v", e.Err, e.Completed, e.Pending)
+}
+
+// Unwrap returns the error the apply was canceled with, so that errors.Is
+// can match it against context.Canceled or context.DeadlineExceeded.
+func (e *CanceledError) Unwrap() error {
+	return e.Err
+}
+
+// cancelState holds the context an apply can be canceled through, along
+// with the progress it has made so far.
+type cancelState struct {
+	ctx      context.Context
+	progress progress
+}
+
+// progress records which GPUs have been configured (and which have not) by
+// the current phase of an apply, for reporting when it is canceled.
+type progress struct {
+	completed []int
+	pending   []int
+}
+
+// cancelContext returns the context the apply can be canceled through.
+func (c *cancelState) cancelContext() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// canceled returns the error the apply was canceled with, or nil if it has
+// not been canceled.
+func (c *cancelState) canceled() error {
+	return c.cancelContext().Err()
+}
+
+// stopAtSafePoint wraps a per-GPU walk function so that, once the apply is
+// canceled, no further GPUs are changed. A GPU whose change is stopped part
+// way through (and rolled back) is recorded as pending rather than failing
+// the walk, so that all remaining GPUs are recorded as pending too.
+func (c *Context) stopAtSafePoint(f func(*v1.MigConfigSpec, int, types.DeviceID) error) func(*v1.MigConfigSpec, int, types.DeviceID) error {
+	c.progress = progress{}
+	return func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if c.canceled() != nil {
+			log.Debugf("    Skipping -- apply canceled")
+			c.progress.pending = append(c.progress.pending, i)
+			return nil
+		}
+		err := f(mc, i, d)
+		if err != nil && c.canceled() != nil && errors.Is(err, c.canceled()) {
+			log.Warnf("Stopped changing GPU %d: %v", i, err)
+			c.progress.pending = append(c.progress.pending, i)
+			return nil
+		}
+		if err == nil {
+			c.progress.completed = append(c.progress.completed, i)
+		}
+		return err
+	}
+}
+
+// canceledError returns a *CanceledError summarizing the progress of the
+// current phase if the apply was canceled, or nil otherwise.
+func (c *cancelState) canceledError() error {
+	err := c.canceled()
+	if err == nil {
+		return nil
+	}
+	return &CanceledError{
+		Completed: c.progress.completed,
+		Pending:   c.progress.pending,
+		Err:       err,
+	}
+}
+
+// notifyOnSignal returns a copy of 'parent' that is canceled on SIGINT or
+// SIGTERM. After the first signal, the default behavior of both signals is
+// restored, so that sending either again terminates the process immediately.
+func notifyOnSignal(parent context.Context) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		select {
+		case sig := <-signals:
+			signal.Stop(signals)
+			log.Warnf("Received %v, stopping at the next safe point (send it again to stop immediately)", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		cancel()
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestStopAtSafePoint(t *testing.T) {
+	failure := errors.New("failure")
+
+	testCases := []struct {
+		description string
+		// cancelAt is the GPU whose change cancels the apply (-1 for none).
+		cancelAt int
+		// stoppedMidway makes the canceling GPU's change stop part way
+		// through rather than finish.
+		stoppedMidway     bool
+		failAt            int
+		expectedCompleted []int
+		expectedPending   []int
+		expectedErr       error
+		expectCanceled    bool
+	}{
+		{
+			description:       "not canceled",
+			cancelAt:          -1,
+			failAt:            -1,
+			expectedCompleted: []int{0, 1, 2, 3},
+		},
+		{
+			description:       "canceled after a GPU finishes",
+			cancelAt:          1,
+			failAt:            -1,
+			expectedCompleted: []int{0, 1},
+			expectedPending:   []int{2, 3},
+			expectCanceled:    true,
+		},
+		{
+			description:       "canceled part way through a GPU",
+			cancelAt:          1,
+			stoppedMidway:     true,
+			failAt:            -1,
+			expectedCompleted: []int{0},
+			expectedPending:   []int{1, 2, 3},
+			expectCanceled:    true,
+		},
+		{
+			description:       "failure before cancel",
+			cancelAt:          -1,
+			failAt:            2,
+			expectedCompleted: []int{0, 1},
+			expectedErr:       failure,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			c := &Context{cancelState: cancelState{ctx: ctx}}
+			walk := c.stopAtSafePoint(func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+				if i == tc.failAt {
+					return failure
+				}
+				if i == tc.cancelAt {
+					cancel()
+					if tc.stoppedMidway {
+						return fmt.Errorf("stopped: %w", ctx.Err())
+					}
+				}
+				return nil
+			})
+
+			var err error
+			for i := 0; i < 4 && err == nil; i++ {
+				err = walk(nil, i, 0)
+			}
+			require.ErrorIs(t, err, tc.expectedErr)
+			require.Equal(t, tc.expectedCompleted, c.progress.completed)
+			require.Equal(t, tc.expectedPending, c.progress.pending)
+
+			err = c.canceledError()
+			if !tc.expectCanceled {
+				require.Nil(t, err)
+				return
+			}
+			var canceled *CanceledError
+			require.ErrorAs(t, err, &canceled)
+			require.ErrorIs(t, err, context.Canceled)
+			require.Equal(t, tc.expectedCompleted, canceled.Completed)
+			require.Equal(t, tc.expectedPending, canceled.Pending)
+		})
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// applyCCMode sets the CC mode of GPU 'i' to 'desired' if it is not already
+// in it. Changing the CC mode resets the GPU, so any pending MIG mode change
+// takes effect along with it.
+func (c *Context) applyCCMode(desired types.CCMode, i int) error {
+	manager, err := util.NewCCModeManager()
+	if err != nil {
+		return fmt.Errorf("error creating CC mode Manager: %w", err)
+	}
+
+	capable, err := manager.IsCCCapable(i)
+	if err != nil {
+		return fmt.Errorf("error checking CC capable: %w", err)
+	}
+	log.Debugf("    CC capable: %v", capable)
+
+	if !capable && desired == types.CCOff {
+		log.Debugf("    Skipping -- non CC-capable GPU with CC mode off")
+		return nil
+	}
+
+	if !capable {
+		return fmt.Errorf("cannot set CC mode on non CC-capable GPU")
+	}
+
+	current, err := manager.GetCCMode(i)
+	if err != nil {
+		return fmt.Errorf("error getting CC mode: %w", err)
+	}
+	log.Debugf("    Current CC mode: %v", current)
+
+	if current == desired {
+		return nil
+	}
+
+	log.Debugf("    Updating CC mode: %v", desired)
+	err = manager.SetCCMode(i, desired)
+	if err != nil {
+		return fmt.Errorf("error setting CC mode: %w", err)
+	}
+	c.markChanged(i)
+
+	return nil
+}
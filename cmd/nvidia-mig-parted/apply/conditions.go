@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// resetGPUs resets the GPUs with the given indices. It is a variable so that
+// tests can run without resetting anything.
+var resetGPUs = func(gpus []int) (string, error) {
+	return util.ResetGPUs(func(i int, _ types.DeviceID) bool {
+		return !slices.Contains(gpus, i)
+	})
+}
+
+// checkGPUs refuses to go ahead with changing 'gpus' while any of them is in
+// a condition that keeps it from being reconfigured (see
+// checkGPUConditions), or is busier than '--max-utilization'.
+func (c *Context) checkGPUs(gpus []int) error {
+	err := c.checkGPUConditions(gpus)
+	if err != nil {
+		return err
+	}
+	return c.checkUtilization(gpus)
+}
+
+// checkChangedGPUs runs checkGPUs for the GPUs that applying the selected MIG
+// config in 'c' would change, after checkReservations for the MIG devices it
+// would destroy. GPUs already matching it are left out, so that an apply
+// that changes nothing is never refused. Without the nvidia module loaded,
+// there is neither NVML to check the GPUs with nor anything that could be
+// using them, so nothing is checked.
+func checkChangedGPUs(c *Context) error {
+	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
+	if err != nil {
+		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
+	}
+	if !nvidiaModuleLoaded {
+		return nil
+	}
+
+	ops, err := PlanOperations(c)
+	if err != nil {
+		return fmt.Errorf("error planning operations: %v", err)
+	}
+	err = c.checkReservations(operationDestructions(ops))
+	if err != nil {
+		return err
+	}
+
+	var gpus []int
+	for _, g := range ops {
+		gpus = append(gpus, g.GPU)
+	}
+	return c.checkGPUs(gpus)
+}
+
+// checkGPUConditions refuses to go ahead while any of 'gpus' is pending a
+// drain or a reset, which would otherwise only fail part way through the
+// apply with an unrelated-looking error. With '--enable-gpu-reset', GPUs that
+// only need a reset are reset first instead.
+func (c *Context) checkGPUConditions(gpus []int) error {
+	if len(gpus) == 0 {
+		return nil
+	}
+
+	conditions, err := c.getGPUConditions(gpus)
+	if err != nil {
+		return err
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	var reset []int
+	for _, cond := range conditions {
+		if !cond.NeedsReset || !c.Flags.EnableGPUReset {
+			logGPUConditionHints(conditions)
+			return fmt.Errorf("GPU(s) not ready to be reconfigured: %v", util.JoinGPUConditions(conditions))
+		}
+		if !slices.Contains(reset, cond.GPU) {
+			reset = append(reset, cond.GPU)
+		}
+	}
+
+	log.Warnf("Resetting GPU(s) %v before reconfiguring them: %v", reset, util.JoinGPUConditions(conditions))
+	output, err := resetGPUs(reset)
+	if err != nil {
+		log.Errorf("\n%v", output)
+		return fmt.Errorf("error resetting GPU(s) %v: %w", reset, err)
+	}
+	log.Debugf("\n%v", output)
+
+	conditions, err = c.getGPUConditions(gpus)
+	if err != nil {
+		return err
+	}
+	if len(conditions) > 0 {
+		return fmt.Errorf("GPU(s) still not ready to be reconfigured after a reset: %v", util.JoinGPUConditions(conditions))
+	}
+	return nil
+}
+
+func (c *Context) getGPUConditions(gpus []int) ([]util.GPUCondition, error) {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	conditions, err := util.GetGPUConditions(c.Nvml, gpus)
+	if err != nil {
+		return nil, fmt.Errorf("error checking GPU conditions: %v", err)
+	}
+	return conditions, nil
+}
+
+// logGPUConditionHints suggests how to clear each kind of condition in
+// 'conditions'.
+func logGPUConditionHints(conditions []util.GPUCondition) {
+	var needsReset, drainPending bool
+	for _, cond := range conditions {
+		needsReset = needsReset || cond.NeedsReset
+		drainPending = drainPending || !cond.NeedsReset
+	}
+	if needsReset {
+		log.Warn("Reset the GPU(s) (e.g. with 'nvidia-smi -r'), or use '--enable-gpu-reset' to reset them before applying the MIG configuration")
+	}
+	if drainPending {
+		log.Warn("Undo the drain of the GPU(s) with 'nvidia-smi drain -p <bus-id> -m 0' before applying the MIG configuration")
+	}
+}
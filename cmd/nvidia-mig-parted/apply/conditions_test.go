@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+)
+
+// newConditionsTestServer returns a mock server whose GPU 0 is pending a row
+// remapping while '*remapPending' is set, and whose GPU 1 is pending a drain
+// if 'drainPending' is set. No GPU supports page retirement.
+func newConditionsTestServer(remapPending *bool, drainPending bool) *dgxa100.Server {
+	server := dgxa100.New()
+
+	for i, d := range server.Devices {
+		device := d.(*dgxa100.Device)
+		pending := func() bool { return false }
+		if i == 0 {
+			pending = func() bool { return *remapPending }
+		}
+		device.GetRemappedRowsFunc = func() (int, int, bool, bool, nvml.Return) {
+			return 0, 0, pending(), false, nvml.SUCCESS
+		}
+		device.GetRetiredPagesPendingStatusFunc = func() (nvml.EnableState, nvml.Return) {
+			return nvml.FEATURE_DISABLED, nvml.ERROR_NOT_SUPPORTED
+		}
+	}
+
+	// The mock GPUs all share the same PCI info, so give GPU 1 its own.
+	gpu1 := server.Devices[1].(*dgxa100.Device)
+	drained := nvml.PciInfo{Bus: 1}
+	gpu1.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+		return drained, nvml.SUCCESS
+	}
+	server.DeviceQueryDrainStateFunc = func(pciInfo *nvml.PciInfo) (nvml.EnableState, nvml.Return) {
+		if drainPending && *pciInfo == drained {
+			return nvml.FEATURE_ENABLED, nvml.SUCCESS
+		}
+		return nvml.FEATURE_DISABLED, nvml.SUCCESS
+	}
+
+	return server
+}
+
+func TestCheckGPUConditions(t *testing.T) {
+	defer func(f func([]int) (string, error)) { resetGPUs = f }(resetGPUs)
+
+	testCases := []struct {
+		description    string
+		remapPending   bool
+		drainPending   bool
+		enableGPUReset bool
+		resetClears    bool
+		expectedReset  []int
+		expectedError  string
+	}{
+		{
+			description: "No conditions",
+		},
+		{
+			description:   "Reset required",
+			remapPending:  true,
+			expectedError: "GPU(s) not ready to be reconfigured: GPU 0: reset required (row remapping pending)",
+		},
+		{
+			description:    "Reset required with GPU reset enabled",
+			remapPending:   true,
+			enableGPUReset: true,
+			resetClears:    true,
+			expectedReset:  []int{0},
+		},
+		{
+			description:    "Reset does not clear the condition",
+			remapPending:   true,
+			enableGPUReset: true,
+			expectedReset:  []int{0},
+			expectedError:  "GPU(s) still not ready to be reconfigured after a reset: GPU 0: reset required (row remapping pending)",
+		},
+		{
+			description:    "Drain pending is never reset",
+			remapPending:   true,
+			drainPending:   true,
+			enableGPUReset: true,
+			expectedError:  "GPU(s) not ready to be reconfigured: GPU 0: reset required (row remapping pending); GPU 1: drain pending",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			remapPending := tc.remapPending
+			var reset []int
+			resetGPUs = func(gpus []int) (string, error) {
+				reset = append(reset, gpus...)
+				if tc.resetClears {
+					remapPending = false
+				}
+				return "", nil
+			}
+
+			c := &Context{Flags: &Flags{EnableGPUReset: tc.enableGPUReset}}
+			c.Nvml = newConditionsTestServer(&remapPending, tc.drainPending)
+
+			err := c.checkGPUConditions([]int{0, 1})
+			if tc.expectedError == "" {
+				require.Nil(t, err)
+			} else {
+				require.EqualError(t, err, tc.expectedError)
+			}
+			require.Equal(t, tc.expectedReset, reset)
+		})
+	}
+}
@@ -22,26 +22,34 @@ import (
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 func ApplyMigConfig(c *Context) error {
-	err := util.NvmlInit(c.Nvml)
+	err := util.CheckPrivilege(migcaps.PrivilegeConfig)
 	if err != nil {
-		return fmt.Errorf("error initializing NVML: %v", err)
+		return err
+	}
+
+	err = util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
 	}
 	defer util.TryNvmlShutdown(c.Nvml)
 
-	return assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+	err = assert.WalkSelectedMigConfigForEachGPUInOrder(c.MigConfig, c.UnmanagedDevices, c.deviceOrder, c.stopAtSafePoint(c.skipLostGPUs(func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
 		modeManager, err := util.NewMigModeManager()
 		if err != nil {
-			return fmt.Errorf("error creating MIG mode Manager: %v", err)
+			return fmt.Errorf("error creating MIG mode Manager: %w", err)
 		}
 
 		capable, err := modeManager.IsMigCapable(i)
 		if err != nil {
-			return fmt.Errorf("error checking MIG capable: %v", err)
+			return fmt.Errorf("error checking MIG capable: %w", err)
 		}
 		log.Debugf("    MIG capable: %v\n", capable)
 
@@ -61,7 +69,7 @@ func ApplyMigConfig(c *Context) error {
 
 		m, err := modeManager.GetMigMode(i)
 		if err != nil {
-			return fmt.Errorf("error getting MIG mode: %v", err)
+			return fmt.Errorf("error getting MIG mode: %w", err)
 		}
 
 		if mc.MigEnabled && m == mode.Disabled {
@@ -79,26 +87,111 @@ func ApplyMigConfig(c *Context) error {
 
 		configManager, err := util.NewMigConfigManager()
 		if err != nil {
-			return fmt.Errorf("error creating MIG config Manager: %v", err)
+			return fmt.Errorf("error creating MIG config Manager: %w", err)
 		}
 
 		current, err := configManager.GetMigConfig(i)
 		if err != nil {
-			return fmt.Errorf("error getting MIGConfig: %v", err)
+			return fmt.Errorf("error getting MIGConfig: %w", err)
+		}
+
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
 		}
 
-		log.Debugf("    Updating MIG config: %v", mc.MigDevices)
+		layer := c.ComputeInstanceConfig.Select(i, d)
 
-		if current.Equals(mc.MigDevices) {
+		log.Debugf("    Updating MIG config: %v", layer.Apply(desired))
+
+		matches, err := util.MigConfigMatches(i, current, layer.Apply(desired))
+		if err != nil {
+			return fmt.Errorf("error comparing MIGConfig: %w", err)
+		}
+		if matches {
 			log.Debugf("    Skipping -- already set to desired value")
 			return nil
 		}
 
-		err = configManager.SetMigConfig(i, mc.MigDevices)
+		if len(layer) != 0 && gpuInstancesMatch(i, desired) {
+			log.Debugf("    GPU instances already set, only updating compute instances")
+			devices, err := configManager.SetComputeInstanceConfig(i, layer)
+			if err != nil {
+				return fmt.Errorf("error setting compute instances: %w", err)
+			}
+			c.Results = append(c.Results, Result{GPU: i, MigDevices: devices})
+			c.markChanged(i)
+			return nil
+		}
+
+		budget := permutationBudget(c.Flags, mc.PermutationBudget)
+		plan := func() ([]operation.Operation, error) {
+			return planMigConfigOperations(configManager, i, desired, mc.Placement, mc.PlacementExclusions)
+		}
+		devices, err := setMigConfig(c.cancelContext(), configManager, i, desired, plan, mc.PlacementExclusions, budget, c.checkReservations)
 		if err != nil {
-			return fmt.Errorf("error setting MIGConfig: %v", err)
+			return fmt.Errorf("error setting MIGConfig: %w", err)
+		}
+
+		if len(layer) != 0 {
+			devices, err = configManager.SetComputeInstanceConfig(i, layer)
+			if err != nil {
+				return fmt.Errorf("error setting compute instances: %w", err)
+			}
 		}
+		c.Results = append(c.Results, Result{GPU: i, MigDevices: devices})
+		c.markChanged(i)
 
 		return nil
-	})
+	})))
+	if err != nil {
+		return err
+	}
+
+	return c.canceledError()
+}
+
+// permutationBudget returns the permutation budget for a config entry. Fields
+// set in 'spec' override the defaults from the command line flags; fields it
+// leaves unset keep them.
+func permutationBudget(flags *Flags, spec *v1.PermutationBudgetSpec) config.PermutationBudget {
+	budget := config.PermutationBudget{
+		MaxAttempts: flags.MaxPermutationAttempts,
+		Timeout:     flags.PermutationTimeout,
+	}
+	if spec == nil {
+		return budget
+	}
+	if spec.MaxAttempts != 0 {
+		budget.MaxAttempts = spec.MaxAttempts
+	}
+	if spec.Timeout != "" {
+		budget.Timeout = spec.TimeoutDuration()
+	}
+	return budget
+}
+
+// gpuInstancesMatch checks if the GPU instances on 'gpu' are exactly those
+// created for 'desired', so that only their compute instances need updating.
+func gpuInstancesMatch(gpu int, desired types.MigConfig) bool {
+	instanceManager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return false
+	}
+
+	gis, err := instanceManager.ListGpuInstances(gpu)
+	if err != nil {
+		log.Debugf("    Unable to list GPU instances: %v", err)
+		return false
+	}
+
+	current := types.MigConfig{}
+	for _, gi := range gis {
+		current[gi.Profile]++
+	}
+
+	return current.Equals(desired)
 }
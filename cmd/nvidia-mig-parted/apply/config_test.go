@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+)
+
+func TestPermutationBudget(t *testing.T) {
+	flags := &Flags{
+		MaxPermutationAttempts: 50,
+		PermutationTimeout:     time.Minute,
+	}
+
+	testCases := []struct {
+		Description    string
+		Spec           *v1.PermutationBudgetSpec
+		ExpectedBudget config.PermutationBudget
+	}{
+		{
+			"No spec uses flags",
+			nil,
+			config.PermutationBudget{MaxAttempts: 50, Timeout: time.Minute},
+		},
+		{
+			"Empty spec uses flags",
+			&v1.PermutationBudgetSpec{},
+			config.PermutationBudget{MaxAttempts: 50, Timeout: time.Minute},
+		},
+		{
+			"Spec with only max-attempts keeps timeout flag",
+			&v1.PermutationBudgetSpec{MaxAttempts: 10},
+			config.PermutationBudget{MaxAttempts: 10, Timeout: time.Minute},
+		},
+		{
+			"Spec with only timeout keeps max-attempts flag",
+			&v1.PermutationBudgetSpec{Timeout: "30s"},
+			config.PermutationBudget{MaxAttempts: 50, Timeout: 30 * time.Second},
+		},
+		{
+			"Spec with both fields overrides flags",
+			&v1.PermutationBudgetSpec{MaxAttempts: 10, Timeout: "30s"},
+			config.PermutationBudget{MaxAttempts: 10, Timeout: 30 * time.Second},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			budget := permutationBudget(flags, tc.Spec)
+			require.Equal(t, tc.ExpectedBudget, budget)
+		})
+	}
+}
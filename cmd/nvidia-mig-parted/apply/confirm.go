@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// DestructiveChange describes the existing MIG devices on a GPU that applying
+// the selected MIG config would destroy.
+type DestructiveChange struct {
+	GPU      int
+	DeviceID types.DeviceID
+	Current  types.MigConfig
+}
+
+// GetDestructiveChanges walks the selected MIG config and returns every GPU
+// whose existing MIG devices would be destroyed by applying it, either
+// because MIG mode gets disabled or because the MIG devices differ.
+func GetDestructiveChanges(c *Context) ([]DestructiveChange, error) {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	var changes []DestructiveChange
+	err = assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		modeManager, err := util.NewMigModeManager()
+		if err != nil {
+			return fmt.Errorf("error creating MIG mode Manager: %w", err)
+		}
+
+		configManager, err := util.NewMigConfigManager()
+		if err != nil {
+			return fmt.Errorf("error creating MIG config Manager: %w", err)
+		}
+
+		change, err := getDestructiveChange(modeManager, configManager, mc, c.Flags.ModeOnly, i, d)
+		if err != nil {
+			return err
+		}
+		if change != nil {
+			changes = append(changes, *change)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// getDestructiveChange returns the change to GPU 'i' (with device ID 'd')
+// if applying 'mc' to it would destroy its existing MIG devices, or nil if
+// it would not.
+func getDestructiveChange(modeManager mode.Manager, configManager config.Manager, mc *v1.MigConfigSpec, modeOnly bool, i int, d types.DeviceID) (*DestructiveChange, error) {
+	capable, err := modeManager.IsMigCapable(i)
+	if err != nil {
+		return nil, fmt.Errorf("error checking MIG capable: %w", err)
+	}
+	if !capable {
+		return nil, nil
+	}
+
+	m, err := modeManager.GetMigMode(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG mode: %w", err)
+	}
+	if m != mode.Enabled {
+		return nil, nil
+	}
+
+	current, err := configManager.GetMigConfig(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIGConfig: %w", err)
+	}
+	if len(current.Flatten()) == 0 {
+		return nil, nil
+	}
+
+	destructive := !mc.MigEnabled
+	if mc.MigEnabled && !modeOnly {
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return nil, fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
+		}
+		destructive = !current.Equals(desired)
+	}
+
+	if !destructive {
+		return nil, nil
+	}
+	return &DestructiveChange{GPU: i, DeviceID: d, Current: current}, nil
+}
+
+// ConfirmDestructiveChanges lists 'changes' on 'out' and asks for
+// confirmation on 'in'. Only an explicit 'y' or 'yes' confirms.
+func ConfirmDestructiveChanges(in io.Reader, out io.Writer, selectedConfig string, changes []DestructiveChange) (bool, error) {
+	fmt.Fprintf(out, "Applying MIG config '%v' will destroy existing MIG devices:\n", selectedConfig)
+	for _, change := range changes {
+		fmt.Fprintf(out, "  GPU %d (%v): %d MIG devices %v\n", change.GPU, change.DeviceID, len(change.Current.Flatten()), change.Current)
+	}
+	fmt.Fprint(out, "Continue? [y/N] ")
+
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, fmt.Errorf("error reading confirmation: %w", err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	}
+	return false, nil
+}
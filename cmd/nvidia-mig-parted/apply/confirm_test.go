@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestGetDestructiveChange(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB}).
+		MustBuild()
+	modeManager := mode.NewMockNvmlMigModeManager(server)
+	configManager := config.NewMockNvmlMigConfigManager(server)
+
+	testCases := []struct {
+		description string
+		mc          v1.MigConfigSpec
+		modeOnly    bool
+		gpu         int
+		expected    *DestructiveChange
+	}{
+		{
+			"Same MIG devices",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 1}},
+			false,
+			0,
+			nil,
+		},
+		{
+			"Different MIG devices",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			false,
+			0,
+			&DestructiveChange{GPU: 0, DeviceID: 0x20B010DE, Current: types.MigConfig{"3g.20gb": 1}},
+		},
+		{
+			"Filled MIG devices differ",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 1}, Fill: "1g.5gb"},
+			false,
+			0,
+			&DestructiveChange{GPU: 0, DeviceID: 0x20B010DE, Current: types.MigConfig{"3g.20gb": 1}},
+		},
+		{
+			"MIG mode disabled",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: false},
+			false,
+			0,
+			&DestructiveChange{GPU: 0, DeviceID: 0x20B010DE, Current: types.MigConfig{"3g.20gb": 1}},
+		},
+		{
+			"Mode only",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			true,
+			0,
+			nil,
+		},
+		{
+			"No existing MIG devices",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: false},
+			false,
+			1,
+			nil,
+		},
+		{
+			"MIG mode currently disabled",
+			v1.MigConfigSpec{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			false,
+			2,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			change, err := getDestructiveChange(modeManager, configManager, &tc.mc, tc.modeOnly, tc.gpu, 0x20B010DE)
+			require.Nil(t, err, "Unexpected failure from getDestructiveChange")
+			require.Equal(t, tc.expected, change)
+		})
+	}
+}
+
+func TestConfirmDestructiveChanges(t *testing.T) {
+	changes := []DestructiveChange{
+		{GPU: 0, DeviceID: 0x20B010DE, Current: types.MigConfig{"3g.20gb": 2}},
+	}
+
+	testCases := []struct {
+		description string
+		input       string
+		expected    bool
+	}{
+		{"Yes", "yes\n", true},
+		{"Y", "y\n", true},
+		{"Upper case with spaces", "  YES \n", true},
+		{"No", "n\n", false},
+		{"Empty line", "\n", false},
+		{"EOF", "", false},
+		{"Yes without newline", "y", true},
+		{"Anything else", "sure\n", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var out bytes.Buffer
+			confirmed, err := ConfirmDestructiveChanges(strings.NewReader(tc.input), &out, "all-1g.5gb", changes)
+			require.Nil(t, err, "Unexpected failure from ConfirmDestructiveChanges")
+			require.Equal(t, tc.expected, confirmed)
+			require.Contains(t, out.String(), "Applying MIG config 'all-1g.5gb' will destroy existing MIG devices:")
+			require.Contains(t, out.String(), "GPU 0 (0x20B010DE): 2 MIG devices")
+			require.True(t, strings.HasSuffix(out.String(), "Continue? [y/N] "))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	cli "github.com/urfave/cli/v2"
+)
+
+// Exit codes of 'apply --detailed-exitcode', mirroring 'terraform plan
+// -detailed-exitcode'.
+const (
+	ExitCodeNoChanges = 0
+	ExitCodeError     = 1
+	ExitCodeChanged   = 2
+)
+
+// changeTracker is a 'MigConfigApplier' that records whether any change was
+// applied through it. Changes are only applied once asserting the current
+// state fails, so an apply that finds everything in place records none.
+type changeTracker struct {
+	MigConfigApplier
+	changed bool
+}
+
+var _ MigConfigApplier = (*changeTracker)(nil)
+
+func (t *changeTracker) ApplyMigMode() error {
+	t.changed = true
+	return t.MigConfigApplier.ApplyMigMode()
+}
+
+func (t *changeTracker) ApplyMigConfig() error {
+	t.changed = true
+	return t.MigConfigApplier.ApplyMigConfig()
+}
+
+// detailedExitCode returns the error that makes 'apply' exit with
+// 'ExitCodeChanged' if 'f.DetailedExitCode' is set and 'changed' is true,
+// and nil otherwise.
+func detailedExitCode(f *Flags, changed bool) error {
+	if !f.DetailedExitCode || !changed {
+		return nil
+	}
+	return cli.Exit("", ExitCodeChanged)
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+)
+
+// fakeApplier is a 'MigConfigApplier' whose MIG mode and MIG config
+// assertions fail until they have been applied.
+type fakeApplier struct {
+	modeApplied   bool
+	configApplied bool
+}
+
+func (a *fakeApplier) AssertMigMode() error {
+	if !a.modeApplied {
+		return fmt.Errorf("mode not applied")
+	}
+	return nil
+}
+
+func (a *fakeApplier) ApplyMigMode() error {
+	a.modeApplied = true
+	return nil
+}
+
+func (a *fakeApplier) AssertMigConfig() error {
+	if !a.configApplied {
+		return fmt.Errorf("config not applied")
+	}
+	return nil
+}
+
+func (a *fakeApplier) ApplyMigConfig() error {
+	a.configApplied = true
+	return nil
+}
+
+func TestChangeTracker(t *testing.T) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	c := cli.NewContext(cli.NewApp(), flag.NewFlagSet("apply", flag.ContinueOnError), nil)
+	c.Command = &cli.Command{}
+
+	applier := &fakeApplier{}
+
+	tracker := &changeTracker{MigConfigApplier: applier}
+	err := ApplyMigConfigWithHooks(logger, c, false, NewApplyHooks(nil), tracker)
+	require.Nil(t, err)
+	require.True(t, tracker.changed, "Expected changes on first apply")
+
+	tracker = &changeTracker{MigConfigApplier: applier}
+	err = ApplyMigConfigWithHooks(logger, c, false, NewApplyHooks(nil), tracker)
+	require.Nil(t, err)
+	require.False(t, tracker.changed, "Expected no changes on second apply")
+}
+
+func TestDetailedExitCode(t *testing.T) {
+	require.Nil(t, detailedExitCode(&Flags{}, true))
+	require.Nil(t, detailedExitCode(&Flags{DetailedExitCode: true}, false))
+
+	err := detailedExitCode(&Flags{DetailedExitCode: true}, true)
+	var exitCoder cli.ExitCoder
+	require.ErrorAs(t, err, &exitCoder)
+	require.Equal(t, ExitCodeChanged, exitCoder.ExitCode())
+}
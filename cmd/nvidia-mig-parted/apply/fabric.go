@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/fabric"
+)
+
+// Ways of detaching GPUs from the NVSwitch fabric while their MIG mode changes.
+const (
+	FabricCoordinationPartition = "partition"
+	FabricCoordinationService   = "service"
+	FabricCoordinationNone      = "none"
+)
+
+// fabricService is the subset of 'fabric.Service' used to stop Fabric Manager.
+type fabricService interface {
+	IsActive() bool
+	Stop() error
+	Start() error
+}
+
+// scopeToFabricPartition restricts the context to the GPUs in the fabric
+// partition selected by '--fabric-partition' by marking every other GPU as
+// unmanaged. 'pciBusIDs' holds the PCI bus ID of each GPU, in GPU index order.
+func (c *Context) scopeToFabricPartition(f fabric.Interface, pciBusIDs []string) error {
+	partition, err := fabric.GetPartition(f, c.Flags.FabricPartition)
+	if err != nil {
+		return err
+	}
+
+	outside := []int{}
+	for i, id := range pciBusIDs {
+		if !partition.Contains(id) {
+			outside = append(outside, i)
+		}
+	}
+	if len(outside) == len(pciBusIDs) {
+		return fmt.Errorf("fabric partition %v contains none of the GPUs on this node", partition.ID)
+	}
+
+	log.Debugf("  Fabric partition %v (active: %v) excludes GPUs %v", partition.ID, partition.Active, outside)
+	c.UnmanagedDevices = append(v1.UnmanagedDeviceSpecSlice{{Devices: outside}}, c.UnmanagedDevices...)
+	c.fabric = f
+	c.fabricPartition = partition
+	return nil
+}
+
+// detachFromFabric detaches the GPUs being reconfigured from the NVSwitch
+// fabric so that their MIG mode can be changed, using the method selected by
+// '--fabric-coordination'. It returns a function to reattach them afterwards.
+func (c *Context) detachFromFabric() (func() error, error) {
+	noop := func() error { return nil }
+
+	switch c.Flags.FabricCoordination {
+	case FabricCoordinationPartition:
+		if c.fabricPartition == nil || !c.fabricPartition.Active {
+			return noop, nil
+		}
+		id := c.fabricPartition.ID
+		log.Infof("Deactivating fabric partition %v while changing MIG mode", id)
+		err := c.fabric.Deactivate(id)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			log.Infof("Reactivating fabric partition %v", id)
+			return c.fabric.Activate(id)
+		}, nil
+	case FabricCoordinationService:
+		if c.fabricService == nil || !c.fabricService.IsActive() {
+			return noop, nil
+		}
+		log.Infof("Stopping Fabric Manager while changing MIG mode")
+		err := c.fabricService.Stop()
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			log.Infof("Restarting Fabric Manager")
+			return c.fabricService.Start()
+		}, nil
+	}
+
+	return noop, nil
+}
@@ -0,0 +1,220 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/pkg/fabric"
+)
+
+type fakeFabric struct {
+	partitions []fabric.Partition
+	calls      []string
+	err        error
+}
+
+func (f *fakeFabric) Partitions() ([]fabric.Partition, error) {
+	return f.partitions, nil
+}
+
+func (f *fakeFabric) Activate(id int) error {
+	f.calls = append(f.calls, fmt.Sprintf("activate %v", id))
+	return f.err
+}
+
+func (f *fakeFabric) Deactivate(id int) error {
+	f.calls = append(f.calls, fmt.Sprintf("deactivate %v", id))
+	return f.err
+}
+
+type fakeFabricService struct {
+	active bool
+	calls  []string
+}
+
+func (s *fakeFabricService) IsActive() bool { return s.active }
+func (s *fakeFabricService) Stop() error    { s.calls = append(s.calls, "stop"); return nil }
+func (s *fakeFabricService) Start() error   { s.calls = append(s.calls, "start"); return nil }
+
+func newFabricTestContext(partition int, coordination string) *Context {
+	flags := &Flags{
+		FabricPartition:    partition,
+		FabricCoordination: coordination,
+	}
+	return &Context{
+		Flags: flags,
+		Context: assert.Context{
+			Flags: &flags.Flags,
+		},
+	}
+}
+
+func TestScopeToFabricPartition(t *testing.T) {
+	f := &fakeFabric{
+		partitions: []fabric.Partition{
+			{ID: 0, Active: true, GPUs: []fabric.GPU{{PCIBusID: "00000000:07:00.0"}, {PCIBusID: "00000000:0F:00.0"}}},
+			{ID: 1, Active: false, GPUs: []fabric.GPU{{PCIBusID: "00000000:47:00.0"}}},
+			{ID: 2, Active: false, GPUs: []fabric.GPU{{PCIBusID: "00000000:99:00.0"}}},
+		},
+	}
+	pciBusIDs := []string{"0000:07:00.0", "0000:0f:00.0", "0000:47:00.0"}
+
+	testCases := []struct {
+		description       string
+		partition         int
+		expectedUnmanaged []int
+		expectedErr       string
+	}{
+		{
+			"Partition with several GPUs",
+			0,
+			[]int{2},
+			"",
+		},
+		{
+			"Partition with a single GPU",
+			1,
+			[]int{0, 1},
+			"",
+		},
+		{
+			"Partition without GPUs on this node",
+			2,
+			nil,
+			"contains none of the GPUs on this node",
+		},
+		{
+			"Unknown partition",
+			3,
+			nil,
+			"fabric partition 3 not found",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := newFabricTestContext(tc.partition, FabricCoordinationPartition)
+			c.UnmanagedDevices = v1.UnmanagedDeviceSpecSlice{{Devices: "all", DeviceFilter: "0x20B010DE"}}
+
+			err := c.scopeToFabricPartition(f, pciBusIDs)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.partition, c.fabricPartition.ID)
+			require.Len(t, c.UnmanagedDevices, 2)
+			require.Equal(t, tc.expectedUnmanaged, c.UnmanagedDevices[0].Devices)
+		})
+	}
+}
+
+func TestDetachFromFabric(t *testing.T) {
+	testCases := []struct {
+		description     string
+		coordination    string
+		partition       *fabric.Partition
+		serviceActive   bool
+		expectedFabric  []string
+		expectedService []string
+	}{
+		{
+			"Active partition is deactivated and reactivated",
+			FabricCoordinationPartition,
+			&fabric.Partition{ID: 3, Active: true},
+			true,
+			[]string{"deactivate 3", "activate 3"},
+			nil,
+		},
+		{
+			"Inactive partition is left alone",
+			FabricCoordinationPartition,
+			&fabric.Partition{ID: 3, Active: false},
+			true,
+			nil,
+			nil,
+		},
+		{
+			"No partition selected",
+			FabricCoordinationPartition,
+			nil,
+			true,
+			nil,
+			nil,
+		},
+		{
+			"Running service is stopped and restarted",
+			FabricCoordinationService,
+			&fabric.Partition{ID: 3, Active: true},
+			true,
+			nil,
+			[]string{"stop", "start"},
+		},
+		{
+			"Stopped service is left alone",
+			FabricCoordinationService,
+			nil,
+			false,
+			nil,
+			nil,
+		},
+		{
+			"No coordination",
+			FabricCoordinationNone,
+			&fabric.Partition{ID: 3, Active: true},
+			true,
+			nil,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			f := &fakeFabric{}
+			s := &fakeFabricService{active: tc.serviceActive}
+
+			c := newFabricTestContext(-1, tc.coordination)
+			c.fabric = f
+			c.fabricPartition = tc.partition
+			c.fabricService = s
+
+			reattach, err := c.detachFromFabric()
+			require.Nil(t, err)
+			require.Nil(t, reattach())
+			require.Equal(t, tc.expectedFabric, f.calls)
+			require.Equal(t, tc.expectedService, s.calls)
+		})
+	}
+}
+
+func TestDetachFromFabricError(t *testing.T) {
+	f := &fakeFabric{err: fmt.Errorf("fmpm failed")}
+
+	c := newFabricTestContext(3, FabricCoordinationPartition)
+	c.fabric = f
+	c.fabricPartition = &fabric.Partition{ID: 3, Active: true}
+
+	_, err := c.detachFromFabric()
+	require.ErrorContains(t, err, "fmpm failed")
+	require.Equal(t, []string{"deactivate 3"}, f.calls)
+}
@@ -0,0 +1,127 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// applyPreGpuConfigHooks runs the 'pre-gpu-config' hook for every GPU the
+// selected config enables MIG on (see runPreGpuConfigHooks).
+func (c *Context) applyPreGpuConfigHooks(envs hooks.EnvsMap, output bool) error {
+	deviceIDs, err := util.GetGPUDeviceIDs()
+	if err != nil {
+		return fmt.Errorf("error enumerating GPUs: %v", err)
+	}
+	uuids, err := util.GetGPUUUIDs()
+	if err != nil {
+		return fmt.Errorf("error enumerating GPU UUIDs: %v", err)
+	}
+	return c.runPreGpuConfigHooks(deviceIDs, uuids, envs, output)
+}
+
+// runPreGpuConfigHooks runs the 'pre-gpu-config' hook for every GPU (with
+// the device IDs in 'deviceIDs' and the UUIDs in 'uuids') that an entry of
+// the selected config enables MIG on. The hook is told which GPU it is run
+// for, and the MIG devices and fill profile the entry asks for on it,
+// through the environment. If it writes a MigConfig (as JSON) to stdout,
+// that MigConfig is validated and applied to the GPU instead, by recording
+// it as an override of the entry for the GPU. Writing nothing leaves the
+// GPU's config unchanged.
+func (c *Context) runPreGpuConfigHooks(deviceIDs []types.DeviceID, uuids []string, envs hooks.EnvsMap, output bool) error {
+	for j := range c.MigConfig {
+		mc := &c.MigConfig[j]
+		for i, deviceID := range deviceIDs {
+			if !mc.MatchesDeviceFilter(deviceID) || !mc.MatchesDevices(i) || c.UnmanagedDevices.Matches(i, deviceID) {
+				continue
+			}
+
+			uuid := ""
+			if i < len(uuids) {
+				uuid = uuids[i]
+			}
+
+			spec := mc.ForDevice(i, uuid)
+			if !spec.MigEnabled {
+				continue
+			}
+
+			desired, err := c.runPreGpuConfigHook(&spec, i, deviceID, uuid, envs, output)
+			if err != nil {
+				return fmt.Errorf("error running pre-gpu-config hook for GPU %v: %w", i, err)
+			}
+			if desired == nil {
+				continue
+			}
+
+			log.Infof("pre-gpu-config hook changed the MIG config of GPU %v to %v", i, desired)
+			key := strconv.Itoa(i)
+			if _, exists := mc.Overrides[uuid]; exists && uuid != "" {
+				key = uuid
+			}
+			if mc.Overrides == nil {
+				mc.Overrides = make(map[string]v1.MigConfigOverrideSpec)
+			}
+			mc.Overrides[key] = v1.MigConfigOverrideSpec{MigEnabled: true, MigDevices: desired}
+		}
+	}
+	return nil
+}
+
+// runPreGpuConfigHook runs the 'pre-gpu-config' hook for GPU 'gpu', whose
+// entry in the selected config is 'mc', and returns the MigConfig it wrote,
+// or nil if it wrote nothing (or 'null').
+func (c *Context) runPreGpuConfigHook(mc *v1.MigConfigSpec, gpu int, deviceID types.DeviceID, uuid string, envs hooks.EnvsMap, output bool) (types.MigConfig, error) {
+	migDevices, err := json.Marshal(mc.MigDevices)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling MIG devices: %v", err)
+	}
+
+	log.Debugf("Running pre-gpu-config hook for GPU %v", gpu)
+	out, err := c.Hooks.PreGpuConfig(envs.Combine(hooks.EnvsMap{
+		"MIG_PARTED_GPU":           strconv.Itoa(gpu),
+		"MIG_PARTED_GPU_DEVICE_ID": deviceID.String(),
+		"MIG_PARTED_GPU_UUID":      uuid,
+		"MIG_PARTED_MIG_DEVICES":   string(migDevices),
+		"MIG_PARTED_FILL":          mc.Fill,
+	}), output)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+
+	var desired types.MigConfig
+	err = json.Unmarshal(out, &desired)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing MigConfig written by hook: %v", err)
+	}
+	err = desired.AssertValidFormat()
+	if err != nil {
+		return nil, fmt.Errorf("invalid MigConfig written by hook: %v", err)
+	}
+	return desired, nil
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestRunPreGpuConfigHooks(t *testing.T) {
+	deviceIDs := []types.DeviceID{0x20B010DE, 0x20B010DE, 0x20B010DE}
+	uuids := []string{"GPU-0", "GPU-1", "GPU-2"}
+
+	testCases := []struct {
+		description       string
+		script            string
+		migConfig         v1.MigConfigSpecSlice
+		unmanaged         v1.UnmanagedDeviceSpecSlice
+		expectedOverrides []map[string]v1.MigConfigOverrideSpec
+		expectedFailure   bool
+	}{
+		{
+			"No output",
+			`true`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			nil,
+			[]map[string]v1.MigConfigOverrideSpec{nil},
+			false,
+		},
+		{
+			"Shrink a single GPU",
+			`if [ "$MIG_PARTED_GPU" = 1 ]; then echo '{"3g.20gb": 1}'; fi`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 2}},
+			},
+			nil,
+			[]map[string]v1.MigConfigOverrideSpec{
+				{"1": {MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 1}}},
+			},
+			false,
+		},
+		{
+			"Hook sees the config of each GPU",
+			`if [ "$MIG_PARTED_MIG_DEVICES" = '{"1g.5gb":7}' ] && [ "$MIG_PARTED_GPU_UUID" = GPU-2 ]; then echo '{"1g.5gb": 6}'; fi`,
+			v1.MigConfigSpecSlice{
+				{Devices: []int{0}, MigEnabled: false},
+				{Devices: []int{1, 2}, MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			nil,
+			[]map[string]v1.MigConfigOverrideSpec{
+				nil,
+				{"2": {MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 6}}},
+			},
+			false,
+		},
+		{
+			"Override keyed by UUID is replaced",
+			`echo '{"7g.40gb": 1}'`,
+			v1.MigConfigSpecSlice{
+				{
+					Devices:    []int{0},
+					MigEnabled: true,
+					MigDevices: types.MigConfig{"1g.5gb": 7},
+					Overrides: map[string]v1.MigConfigOverrideSpec{
+						"GPU-0": {MigEnabled: true, MigDevices: types.MigConfig{"2g.10gb": 3}},
+					},
+				},
+			},
+			nil,
+			[]map[string]v1.MigConfigOverrideSpec{
+				{"GPU-0": {MigEnabled: true, MigDevices: types.MigConfig{"7g.40gb": 1}}},
+			},
+			false,
+		},
+		{
+			"Unmanaged GPUs are skipped",
+			`echo '{"7g.40gb": 1}'`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			v1.UnmanagedDeviceSpecSlice{{Devices: []int{0, 1}}},
+			[]map[string]v1.MigConfigOverrideSpec{
+				{"2": {MigEnabled: true, MigDevices: types.MigConfig{"7g.40gb": 1}}},
+			},
+			false,
+		},
+		{
+			"Invalid JSON",
+			`echo 'not json'`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"Invalid MigConfig",
+			`echo '{"1g.5gb": -1}'`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"Failing hook",
+			`exit 1`,
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}},
+			},
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &Context{
+				Hooks: NewApplyHooks(hooks.HooksMap{
+					preGpuConfigHook: {{Command: "/bin/sh", Args: []string{"-c", tc.script}}},
+				}),
+				Context: assert.Context{
+					MigConfig:        tc.migConfig,
+					UnmanagedDevices: tc.unmanaged,
+				},
+			}
+
+			err := c.runPreGpuConfigHooks(deviceIDs, uuids, hooks.EnvsMap{}, false)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from runPreGpuConfigHooks")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from runPreGpuConfigHooks")
+
+			var overrides []map[string]v1.MigConfigOverrideSpec
+			for _, mc := range c.MigConfig {
+				overrides = append(overrides, mc.Overrides)
+			}
+			require.Equal(t, tc.expectedOverrides, overrides)
+		})
+	}
+}
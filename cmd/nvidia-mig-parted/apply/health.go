@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Ways of dealing with unhealthy GPUs, as selected by '--unhealthy-gpus'.
+const (
+	UnhealthyGPUsIgnore  = "ignore"
+	UnhealthyGPUsExclude = "exclude"
+	UnhealthyGPUsRefuse  = "refuse"
+)
+
+// DefaultMaxRetiredPages is the number of retired memory pages past which a
+// GPU is considered unhealthy by default.
+const DefaultMaxRetiredPages = 60
+
+// checkGPUHealth checks the health signals of the GPUs selected by the MIG
+// config (see util.GetUnhealthyGPUs) and, depending on '--unhealthy-gpus',
+// refuses to go ahead if any of them is unhealthy or excludes them from the
+// apply by marking them as unmanaged, reporting each in the results instead
+// of partitioning a dying GPU. Without the nvidia module loaded, there is no
+// NVML to check the GPUs with, so nothing is checked.
+func (c *Context) checkGPUHealth() error {
+	if c.Flags.UnhealthyGPUs == UnhealthyGPUsIgnore || c.Flags.UnhealthyGPUs == "" {
+		return nil
+	}
+
+	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
+	if err != nil {
+		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
+	}
+	if !nvidiaModuleLoaded {
+		return nil
+	}
+
+	log.Debugf("Checking health of GPUs...")
+
+	var gpus []int
+	err = assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		gpus = append(gpus, i)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.checkHealthOf(gpus)
+}
+
+// checkHealthOf refuses to go ahead if any of 'gpus' is unhealthy, or
+// excludes the unhealthy ones, as selected by '--unhealthy-gpus'.
+func (c *Context) checkHealthOf(gpus []int) error {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	conditions, err := util.GetUnhealthyGPUs(c.Nvml, gpus, util.HealthThresholds{
+		MaxUncorrectableECCErrors: c.Flags.MaxECCErrors,
+		MaxRetiredPages:           c.Flags.MaxRetiredPages,
+		ThermalThrottle:           !c.Flags.IgnoreThermalThrottle,
+	})
+	if err != nil {
+		return fmt.Errorf("error checking GPU health: %v", err)
+	}
+	if len(conditions) == 0 {
+		return nil
+	}
+	if c.Flags.UnhealthyGPUs == UnhealthyGPUsRefuse {
+		return fmt.Errorf("unhealthy GPU(s): %v", util.JoinGPUConditions(conditions))
+	}
+
+	reasons := make(map[int][]string)
+	for _, cond := range conditions {
+		reasons[cond.GPU] = append(reasons[cond.GPU], cond.Reason)
+	}
+	var excluded []int
+	for gpu := range reasons {
+		excluded = append(excluded, gpu)
+	}
+	sort.Ints(excluded)
+
+	for _, gpu := range excluded {
+		reason := strings.Join(reasons[gpu], ", ")
+		log.Warnf("Excluding unhealthy GPU %d: %v", gpu, reason)
+		c.Results = append(c.Results, Result{GPU: gpu, Excluded: reason})
+	}
+	c.UnmanagedDevices = append(v1.UnmanagedDeviceSpecSlice{{Devices: excluded}}, c.UnmanagedDevices...)
+	return nil
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+)
+
+// newHealthTestServer returns a mock server whose GPU 1 has 'ecc'
+// uncorrectable ECC errors and 'retired' retired pages, and whose GPU 2 is
+// thermally throttled. No other GPU reports anything amiss, and GPU 3 does
+// not support ECC at all.
+func newHealthTestServer(ecc uint64, retired int) *dgxa100.Server {
+	server := dgxa100.New()
+
+	for i, d := range server.Devices {
+		device := d.(*dgxa100.Device)
+		i := i
+		device.GetTotalEccErrorsFunc = func(nvml.MemoryErrorType, nvml.EccCounterType) (uint64, nvml.Return) {
+			switch i {
+			case 1:
+				return ecc, nvml.SUCCESS
+			case 3:
+				return 0, nvml.ERROR_NOT_SUPPORTED
+			}
+			return 0, nvml.SUCCESS
+		}
+		device.GetRetiredPagesFunc = func(cause nvml.PageRetirementCause) ([]uint64, nvml.Return) {
+			if i == 1 && cause == nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR {
+				return make([]uint64, retired), nvml.SUCCESS
+			}
+			return nil, nvml.SUCCESS
+		}
+		device.GetCurrentClocksThrottleReasonsFunc = func() (uint64, nvml.Return) {
+			if i == 2 {
+				return nvml.ClocksThrottleReasonHwThermalSlowdown, nvml.SUCCESS
+			}
+			return 0, nvml.SUCCESS
+		}
+	}
+
+	return server
+}
+
+func TestCheckHealthOf(t *testing.T) {
+	testCases := []struct {
+		description       string
+		policy            string
+		ecc               uint64
+		retired           int
+		ignoreThermal     bool
+		expectedExcluded  []Result
+		expectedUnmanaged []int
+		expectedError     string
+	}{
+		{
+			description:       "Thermal throttling",
+			policy:            UnhealthyGPUsExclude,
+			expectedExcluded:  []Result{{GPU: 2, Excluded: "thermal throttling"}},
+			expectedUnmanaged: []int{2},
+		},
+		{
+			description:   "Thermal throttling ignored",
+			policy:        UnhealthyGPUsExclude,
+			ignoreThermal: true,
+		},
+		{
+			description:   "Retired pages at the threshold",
+			policy:        UnhealthyGPUsExclude,
+			retired:       DefaultMaxRetiredPages,
+			ignoreThermal: true,
+		},
+		{
+			description:       "ECC errors and retired pages",
+			policy:            UnhealthyGPUsExclude,
+			ecc:               2,
+			retired:           DefaultMaxRetiredPages + 1,
+			expectedExcluded:  []Result{{GPU: 1, Excluded: "2 uncorrectable ECC errors, 61 retired pages"}, {GPU: 2, Excluded: "thermal throttling"}},
+			expectedUnmanaged: []int{1, 2},
+		},
+		{
+			description:   "Refused",
+			policy:        UnhealthyGPUsRefuse,
+			ecc:           1,
+			ignoreThermal: true,
+			expectedError: "unhealthy GPU(s): GPU 1: 1 uncorrectable ECC errors",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &Context{
+				Flags: &Flags{
+					UnhealthyGPUs:         tc.policy,
+					MaxRetiredPages:       DefaultMaxRetiredPages,
+					IgnoreThermalThrottle: tc.ignoreThermal,
+				},
+			}
+			c.Nvml = newHealthTestServer(tc.ecc, tc.retired)
+
+			err := c.checkHealthOf([]int{0, 1, 2, 3})
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedExcluded, c.Results)
+			if tc.expectedUnmanaged == nil {
+				require.Empty(t, c.UnmanagedDevices)
+				return
+			}
+			require.Equal(t, v1.UnmanagedDeviceSpecSlice{{Devices: tc.expectedUnmanaged}}, c.UnmanagedDevices)
+		})
+	}
+}
@@ -24,7 +24,10 @@ const (
 	applyStartHook     = "apply-start"
 	preApplyModeHook   = "pre-apply-mode"
 	preApplyConfigHook = "pre-apply-config"
+	preGpuConfigHook   = "pre-gpu-config"
 	applyExitHook      = "apply-exit"
+	gpuLostHook        = "gpu-lost"
+	mpsConfigHook      = "mps-config"
 )
 
 type applyHooks struct {
@@ -35,7 +38,10 @@ type ApplyHooks interface {
 	ApplyStart(envs hooks.EnvsMap, output bool) error
 	PreApplyMode(envs hooks.EnvsMap, output bool) error
 	PreApplyConfig(envs hooks.EnvsMap, output bool) error
+	PreGpuConfig(envs hooks.EnvsMap, output bool) ([]byte, error)
 	ApplyExit(envs hooks.EnvsMap, output bool) error
+	GpuLost(envs hooks.EnvsMap, output bool) error
+	MpsConfig(envs hooks.EnvsMap, output bool) error
 }
 
 var _ ApplyHooks = (*applyHooks)(nil)
@@ -56,6 +62,18 @@ func (h *applyHooks) PreApplyConfig(envs hooks.EnvsMap, output bool) error {
 	return h.Run(preApplyConfigHook, envs, output)
 }
 
+func (h *applyHooks) PreGpuConfig(envs hooks.EnvsMap, output bool) ([]byte, error) {
+	return h.Output(preGpuConfigHook, envs, output)
+}
+
 func (h *applyHooks) ApplyExit(envs hooks.EnvsMap, output bool) error {
 	return h.Run(applyExitHook, envs, output)
 }
+
+func (h *applyHooks) GpuLost(envs hooks.EnvsMap, output bool) error {
+	return h.Run(gpuLostHook, envs, output)
+}
+
+func (h *applyHooks) MpsConfig(envs hooks.EnvsMap, output bool) error {
+	return h.Run(mpsConfigHook, envs, output)
+}
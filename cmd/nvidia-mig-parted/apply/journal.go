@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+)
+
+// RecordApply appends the outcome of an apply that started at 'start' to the
+// journal configured in 'f', and writes its metrics to '--textfile-dir'. The
+// journal is informational only, so errors writing it are logged rather than
+// failing the apply.
+func RecordApply(f *Flags, selectedConfig string, start time.Time, err error) {
+	writeApplyMetrics(f, selectedConfig, start, err)
+	if f.JournalFile == "" {
+		return
+	}
+
+	entry := &journal.Entry{
+		Timestamp:        start,
+		ConfigFile:       journalPath(f.ConfigFile),
+		CIConfigFile:     journalPath(f.CIConfigFile),
+		PlanFile:         journalPath(f.PlanFile),
+		SelectedConfig:   selectedConfig,
+		CISelectedConfig: f.CISelectedConfig,
+		ModeOnly:         f.ModeOnly,
+		Trigger:          f.Trigger,
+		Outcome:          applyOutcome(err),
+		DurationMS:       time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	err = journal.Append(f.JournalFile, entry)
+	if err != nil {
+		log.Warnf("Error recording apply in journal: %v", err)
+	}
+}
+
+// applyOutcome returns the journal outcome of an apply that returned 'err'.
+func applyOutcome(err error) string {
+	var veto *hooks.VetoError
+	if errors.As(err, &veto) {
+		return journal.OutcomeDenied
+	}
+	var canceled *CanceledError
+	if errors.As(err, &canceled) {
+		return journal.OutcomeCanceled
+	}
+	if err != nil {
+		return journal.OutcomeFailed
+	}
+	return journal.OutcomeSucceeded
+}
+
+// journalPath makes 'path' absolute so that it can be found again by
+// commands run from a different working directory.
+func journalPath(path string) string {
+	if path == "" || util.IsStdio(path) {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	return abs
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/pkg/labels"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// recordLabels records the labels of the entry of the selected config that
+// each GPU was configured with in the store configured in 'c', replacing
+// those of the entry applied to it before. GPUs not selected by the config,
+// and those lost while applying it, keep their labels. As with the journal,
+// errors are logged rather than failing the apply.
+func recordLabels(c *Context) {
+	if c.Flags.StoreFile == "" {
+		return
+	}
+
+	s, err := store.Open(c.Flags.StoreFile)
+	if err != nil {
+		log.Warnf("Error recording labels of applied MIG config: %v", err)
+		return
+	}
+	defer s.Close()
+
+	registry := labels.NewRegistry(s)
+	err = assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if c.isLost(i) {
+			return nil
+		}
+		return registry.Set(i, c.Flags.SelectedConfig, mc.Labels)
+	})
+	if err != nil {
+		log.Warnf("Error recording labels of applied MIG config: %v", err)
+	}
+}
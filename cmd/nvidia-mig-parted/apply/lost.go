@@ -0,0 +1,102 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// skipLostGPUs wraps a per-GPU walk function so that GPUs already lost are
+// skipped and GPUs lost while running 'f' are handled by handleLostGPU.
+func (c *Context) skipLostGPUs(f func(*v1.MigConfigSpec, int, types.DeviceID) error) func(*v1.MigConfigSpec, int, types.DeviceID) error {
+	return func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if c.isLost(i) {
+			log.Debugf("    Skipping -- GPU lost")
+			return nil
+		}
+		return c.handleLostGPU(i, f(mc, i, d))
+	}
+}
+
+// handleLostGPU marks 'gpu' as failed and runs the 'gpu-lost' hook if 'err'
+// shows that it fell off the bus. The error is swallowed if '--keep-going'
+// is set so that the remaining GPUs can still be configured.
+func (c *Context) handleLostGPU(gpu int, err error) error {
+	if !errors.Is(err, nvmlerrors.ErrGpuLost) {
+		return err
+	}
+
+	if !c.isLost(gpu) {
+		log.Errorf("GPU %d has fallen off the bus: %v", gpu, err)
+		if c.lostGPUs == nil {
+			c.lostGPUs = make(map[int]error)
+		}
+		c.lostGPUs[gpu] = err
+		c.Results = append(c.Results, Result{GPU: gpu, Error: err.Error()})
+		c.runGpuLostHook(gpu)
+	}
+
+	if !c.Flags.KeepGoing {
+		return err
+	}
+
+	log.Warnf("Continuing with the remaining GPUs")
+	return nil
+}
+
+func (c *Context) runGpuLostHook(gpu int) {
+	if c.Hooks == nil || c.Context.Context == nil {
+		return
+	}
+
+	envs := GetHooksEnvsMap(c.Context.Context)
+	envs["MIG_PARTED_LOST_GPU"] = strconv.Itoa(gpu)
+
+	log.Debugf("Running gpu-lost hook")
+	err := c.Hooks.GpuLost(envs, c.Context.Context.Bool("debug"))
+	if err != nil {
+		log.Errorf("Error running gpu-lost hook: %v", err)
+	}
+}
+
+func (c *Context) isLost(gpu int) bool {
+	_, exists := c.lostGPUs[gpu]
+	return exists
+}
+
+// lostGPUsError returns an error listing every GPU lost while applying the
+// MIG config, or nil if none were lost.
+func (c *Context) lostGPUsError() error {
+	if len(c.lostGPUs) == 0 {
+		return nil
+	}
+
+	var gpus []int
+	for gpu := range c.lostGPUs {
+		gpus = append(gpus, gpu)
+	}
+	sort.Ints(gpus)
+
+	return fmt.Errorf("MIG configuration not applied to GPU(s) %v: %w", gpus, nvmlerrors.ErrGpuLost)
+}
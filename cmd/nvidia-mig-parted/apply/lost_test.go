@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestSkipLostGPUs(t *testing.T) {
+	lost := fmt.Errorf("error getting MIG mode: %w", nvmlerrors.New(nvml.ERROR_GPU_IS_LOST))
+	other := fmt.Errorf("error getting MIG mode: %w", nvmlerrors.New(nvml.ERROR_UNKNOWN))
+
+	testCases := []struct {
+		description   string
+		keepGoing     bool
+		errs          map[int]error
+		expectedErr   error
+		expectedCalls []int
+		expectedLost  []int
+	}{
+		{
+			"No errors",
+			false,
+			nil,
+			nil,
+			[]int{0, 1, 2},
+			nil,
+		},
+		{
+			"Lost GPU aborts",
+			false,
+			map[int]error{1: lost},
+			lost,
+			[]int{0, 1},
+			[]int{1},
+		},
+		{
+			"Lost GPU with keep-going",
+			true,
+			map[int]error{1: lost},
+			nil,
+			[]int{0, 1, 2},
+			[]int{1},
+		},
+		{
+			"Other errors still abort with keep-going",
+			true,
+			map[int]error{1: other},
+			other,
+			[]int{0, 1},
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			c := &Context{Flags: &Flags{KeepGoing: tc.keepGoing}}
+
+			var calls []int
+			f := c.skipLostGPUs(func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+				calls = append(calls, i)
+				return tc.errs[i]
+			})
+
+			var err error
+			for i := 0; i < 3 && err == nil; i++ {
+				err = f(nil, i, 0)
+			}
+			require.Equal(t, tc.expectedErr, err)
+			require.Equal(t, tc.expectedCalls, calls)
+
+			var lostGPUs []int
+			for _, r := range c.Results {
+				require.NotEmpty(t, r.Error)
+				lostGPUs = append(lostGPUs, r.GPU)
+			}
+			require.Equal(t, tc.expectedLost, lostGPUs)
+
+			// A second walk skips any GPU that was lost in the first one.
+			calls = nil
+			for i := 0; i < 3; i++ {
+				_ = f(nil, i, 0)
+			}
+			for _, gpu := range tc.expectedLost {
+				require.NotContains(t, calls, gpu)
+			}
+
+			if len(tc.expectedLost) == 0 {
+				require.Nil(t, c.lostGPUsError())
+				return
+			}
+			require.True(t, errors.Is(c.lostGPUsError(), nvmlerrors.ErrGpuLost))
+		})
+	}
+}
@@ -0,0 +1,37 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+)
+
+// logPlacementMetrics summarizes the work that placing the MIG devices of an
+// apply took, as counted in 'm'. Failed placements and permutations are
+// logged as warnings, since a config that keeps needing them is close to not
+// fitting on its GPUs at all.
+func logPlacementMetrics(m config.Metrics) {
+	if m.IsZero() {
+		return
+	}
+	logf := log.Infof
+	if m.PlacementFailures > 0 || m.FailedPermutations > 0 {
+		logf = log.Warnf
+	}
+	logf("Placement summary: %d of %d placements failed, %d of %d orderings of MIG devices failed, %d NVML calls retried",
+		m.PlacementFailures, m.PlacementAttempts, m.FailedPermutations, m.PermutationAttempts, m.NvmlRetries)
+}
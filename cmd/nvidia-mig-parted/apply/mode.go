@@ -22,31 +22,38 @@ import (
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
-	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 func ApplyMigMode(c *Context) error {
+	err := util.CheckPrivilege(migcaps.PrivilegeAdmin)
+	if err != nil {
+		return err
+	}
+
 	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
 	if err != nil {
-		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
+		return fmt.Errorf("error checking if nvidia module loaded: %w", err)
 	}
 
 	if nvidiaModuleLoaded {
 		err := util.NvmlInit(c.Nvml)
 		if err != nil {
-			return fmt.Errorf("error initializing NVML: %v", err)
+			return fmt.Errorf("error initializing NVML: %w", err)
 		}
 	}
 
 	deviceIDs, err := util.GetGPUDeviceIDs()
 	if err != nil {
-		return fmt.Errorf("error enumerating GPUs: %v", err)
+		return fmt.Errorf("error enumerating GPUs: %w", err)
 	}
 
 	pending := make([]bool, len(deviceIDs))
-	err = assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+	desired := make(map[int]mode.MigMode)
+	setMigMode := func(mc *v1.MigConfigSpec, i int) error {
 		desiredMode := mode.Disabled
 		if mc.MigEnabled {
 			desiredMode = mode.Enabled
@@ -54,12 +61,12 @@ func ApplyMigMode(c *Context) error {
 
 		manager, err := util.NewMigModeManager()
 		if err != nil {
-			return fmt.Errorf("error creating MIG mode Manager: %v", err)
+			return fmt.Errorf("error creating MIG mode Manager: %w", err)
 		}
 
 		capable, err := manager.IsMigCapable(i)
 		if err != nil {
-			return fmt.Errorf("error checking MIG capable: %v", err)
+			return fmt.Errorf("error checking MIG capable: %w", err)
 		}
 		log.Debugf("    MIG capable: %v\n", capable)
 
@@ -79,33 +86,66 @@ func ApplyMigMode(c *Context) error {
 
 		currentMode, err := manager.GetMigMode(i)
 		if err != nil {
-			return fmt.Errorf("error getting MIG mode: %v", err)
+			return fmt.Errorf("error getting MIG mode: %w", err)
 		}
 		log.Debugf("    Current MIG mode: %v", currentMode)
 
 		if nvidiaModuleLoaded && currentMode != mode.Disabled {
 			log.Debugf("    Clearing existing MIG configuration")
-			manager := config.NewNvmlMigConfigManager()
-			err := manager.ClearMigConfig(i)
+			manager, err := util.NewMigConfigManager()
 			if err != nil {
-				return fmt.Errorf("error clearing existing MIG configurations: %v", err)
+				return fmt.Errorf("error creating MIG config Manager: %w", err)
+			}
+			err = manager.ClearMigConfig(i)
+			if err != nil {
+				return fmt.Errorf("error clearing existing MIG configurations: %w", err)
 			}
 		}
 
+		desired[i] = desiredMode
+
 		log.Debugf("    Updating MIG mode: %v", desiredMode)
 		err = manager.SetMigMode(i, desiredMode)
 		if err != nil {
-			return fmt.Errorf("error setting MIG mode: %v", err)
+			return fmt.Errorf("error setting MIG mode: %w", err)
+		}
+		if currentMode != desiredMode {
+			c.markChanged(i)
 		}
 
 		pending[i], err = manager.IsMigModeChangePending(i)
 		if err != nil {
-			return fmt.Errorf("error checking pending MIG mode change: %v", err)
+			return fmt.Errorf("error checking pending MIG mode change: %w", err)
 		}
 		log.Debugf("    Mode change pending: %v", pending[i])
 
 		return nil
-	})
+	}
+
+	err = assert.WalkSelectedMigConfigForEachGPUInOrder(c.MigConfig, c.UnmanagedDevices, c.deviceOrder, c.stopAtSafePoint(c.skipLostGPUs(func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if mc.CCMode == "" {
+			return setMigMode(mc, i)
+		}
+
+		// MIG mode can only be enabled once CC mode is off, and CC mode can
+		// only be turned on once MIG mode is disabled.
+		if mc.CCMode.SupportsMig() {
+			err := c.applyCCMode(mc.CCMode, i)
+			if err != nil {
+				return err
+			}
+			return setMigMode(mc, i)
+		}
+
+		if mc.MigEnabled {
+			return fmt.Errorf("cannot enable MIG mode with CC mode %v", mc.CCMode)
+		}
+		err := setMigMode(mc, i)
+		if err != nil {
+			return err
+		}
+		return c.applyCCMode(mc.CCMode, i)
+	})))
 
 	if nvidiaModuleLoaded {
 		util.TryNvmlShutdown(c.Nvml)
@@ -115,18 +155,44 @@ func ApplyMigMode(c *Context) error {
 		return err
 	}
 
-	if c.Flags.SkipReset || !util.Any(pending) {
-		return nil
+	err = finishMigModeChange(c, deviceIDs, pending, desired, c.UnmanagedDevices.Matches)
+	if err != nil {
+		return err
+	}
+
+	return c.canceledError()
+}
+
+// finishMigModeChange resets all GPUs not selected by 'skip' if any of the
+// MIG mode changes in 'desired' are still pending (unless resets are
+// skipped), and records any that will only take effect after a reboot.
+func finishMigModeChange(c *Context, deviceIDs []types.DeviceID, pending []bool, desired map[int]mode.MigMode, skip func(int, types.DeviceID) bool) error {
+	if !util.Any(pending) {
+		return updateRebootMarker(c, deviceIDs, desired, reboot.ReasonModeChangePending)
+	}
+
+	if c.Flags.SkipReset {
+		return updateRebootMarker(c, deviceIDs, desired, reboot.ReasonResetSkipped)
 	}
 
 	log.Debugf("At least one mode change pending")
-	log.Debugf("Resetting all GPUs...")
-	output, err := util.ResetAllGPUs()
-	if err != nil {
+	log.Debugf("Resetting all managed GPUs...")
+	output, resetErr := util.ResetGPUs(func(i int, d types.DeviceID) bool {
+		return skip(i, d) || c.isLost(i)
+	})
+	if resetErr != nil {
 		log.Errorf("\n%v", output)
-		return fmt.Errorf("error resetting all GPUs: %v", err)
+	} else {
+		log.Debugf("\n%v", output)
+	}
+
+	err := updateRebootMarker(c, deviceIDs, desired, reboot.ReasonModeChangePending)
+	if resetErr != nil {
+		if err != nil {
+			log.Errorf("%v", err)
+		}
+		return fmt.Errorf("error resetting all GPUs: %w", resetErr)
 	}
-	log.Debugf("\n%v", output)
 
-	return nil
+	return err
 }
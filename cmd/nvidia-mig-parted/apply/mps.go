@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mps"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// updateMpsConfig writes the MPS config file configured in 'c' for the MIG
+// devices on every GPU whose entry in the selected config is marked 'mps',
+// and runs the 'mps-config' hook with it. If no GPU is, the file is removed
+// and the hook is not run.
+func updateMpsConfig(c *Context) error {
+	if c.Flags.MpsConfigFile == "" {
+		return nil
+	}
+
+	config, err := getMpsConfig(c)
+	if err != nil {
+		return fmt.Errorf("error getting MIG devices to share through MPS: %w", err)
+	}
+
+	if len(config.Devices) == 0 {
+		return mps.Clear(c.Flags.MpsConfigFile)
+	}
+
+	err = mps.Write(c.Flags.MpsConfigFile, config)
+	if err != nil {
+		return err
+	}
+
+	envs := GetHooksEnvsMap(c.Context.Context)
+	envs["MIG_PARTED_MPS_DEVICES"] = config.UUIDs()
+
+	log.Debugf("Running mps-config hook")
+	err = c.Hooks.MpsConfig(envs, c.Context.Context.Bool("debug"))
+	if err != nil {
+		return fmt.Errorf("error running mps-config hook: %w", err)
+	}
+
+	return nil
+}
+
+// getMpsConfig returns the MPS config for the MIG devices currently on every
+// GPU whose entry in the selected config is marked 'mps'. GPUs lost while
+// applying the config are left out.
+func getMpsConfig(c *Context) (*mps.Config, error) {
+	config := &mps.Config{Devices: []mps.Device{}}
+	if !hasMpsEntries(c.MigConfig) {
+		return config, nil
+	}
+
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %w", err)
+	}
+
+	err = assert.WalkSelectedMigConfigForEachGPUInOrder(c.MigConfig, c.UnmanagedDevices, c.deviceOrder, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if !mc.MPS || !mc.MigEnabled || c.isLost(i) {
+			return nil
+		}
+
+		device, ret := c.Nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		uuids, err := util.GetMigDeviceUUIDs(device)
+		if err != nil {
+			return fmt.Errorf("error getting MIG device UUIDs for GPU %d: %v", i, err)
+		}
+
+		devices, err := configManager.GetMigDevices(i)
+		if err != nil {
+			return fmt.Errorf("error getting MIG devices for GPU %d: %v", i, err)
+		}
+
+		for _, md := range devices {
+			uuid, exists := uuids[[2]uint32{md.GpuInstanceID, md.ComputeInstanceID}]
+			if !exists {
+				return fmt.Errorf("no UUID found for MIG device %v on GPU %d", md.Profile, i)
+			}
+			config.Devices = append(config.Devices, mps.NewDevice(i, uuid, md.Profile, c.Flags.MpsPipeDirectory, c.Flags.MpsLogDirectory))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// hasMpsEntries checks if any entry of 'migConfig' is marked 'mps'.
+func hasMpsEntries(migConfig v1.MigConfigSpecSlice) bool {
+	for _, mc := range migConfig {
+		if mc.MPS {
+			return true
+		}
+	}
+	return false
+}
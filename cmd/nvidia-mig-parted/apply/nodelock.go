@@ -0,0 +1,29 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+)
+
+// lockNode takes the node lock at '--node-lock-file', if set, so that
+// nothing else following its handshake (see util.LockNode) changes the GPUs
+// while the MIG config is applied. It returns the function releasing the
+// lock again.
+func (c *Context) lockNode() (func(), error) {
+	return util.LockNode(c.cancelContext(), log, c.Flags.NodeLockFile, c.Flags.NodeLockTimeout)
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/nodelock"
+)
+
+func TestLockNode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvml.lock")
+
+	c := &Context{Flags: &Flags{}}
+	unlock, err := c.lockNode()
+	require.Nil(t, err, "Unexpected failure from lockNode without a lock file")
+	unlock()
+
+	c.Flags.NodeLockFile = path
+	c.Flags.NodeLockTimeout = 200 * time.Millisecond
+	unlock, err = c.lockNode()
+	require.Nil(t, err, "Unexpected failure from lockNode")
+
+	holder, err := nodelock.Holder(path)
+	require.Nil(t, err, "Unexpected failure from Holder")
+	require.Regexp(t, "^"+util.NodeLockOwner+" ", holder)
+
+	_, err = nodelock.Acquire(context.Background(), path, "nvidia-device-plugin", 200*time.Millisecond)
+	require.True(t, errors.Is(err, nodelock.ErrTimeout), "Unexpected error from Acquire: %v", err)
+
+	unlock()
+
+	other, err := nodelock.Acquire(context.Background(), path, "nvidia-device-plugin", time.Second)
+	require.Nil(t, err, "Unexpected failure from Acquire")
+	defer other.Release()
+
+	_, err = c.lockNode()
+	require.True(t, errors.Is(err, nodelock.ErrTimeout), "Unexpected error from lockNode: %v", err)
+	require.Contains(t, err.Error(), "held by nvidia-device-plugin")
+}
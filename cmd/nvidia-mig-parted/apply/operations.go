@@ -0,0 +1,250 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// setMigConfig applies 'desired' to 'gpu' as a sequence of undoable
+// operations returned by 'plan', rolling them back if any of them fail. If
+// the operations cannot be planned or fail part way through, it falls back to
+// searching for a working order of MIG devices with 'configManager' (within
+// 'budget'). If 'ctx' is canceled, the operations stop at the next safe point
+// and are rolled back without falling back. The planned operations keep GPU
+// instances off the slices 'exclusions' reserve, except when falling back,
+// where NVML chooses their placement. As NVML cannot be made to honor
+// 'exclusions', there is no falling back if there are any. Nor is there if
+// another process changed the MIG devices of 'gpu' since the operations were
+// planned, as falling back would clear whatever it created, or if
+// 'checkReservations' refuses to destroy the MIG devices it clears.
+func setMigConfig(ctx context.Context, configManager config.Manager, gpu int, desired types.MigConfig, plan func() ([]operation.Operation, error), exclusions []types.PlacementExclusion, budget config.PermutationBudget, checkReservations func([]destruction) error) ([]types.MigDevice, error) {
+	ops, err := plan()
+	if err != nil && len(exclusions) > 0 {
+		return nil, fmt.Errorf("unable to place MIG devices outside of 'placement-exclusions': %w", err)
+	}
+	if err != nil {
+		log.Debugf("    Unable to plan MIG config operations: %v", err)
+		return fallBackToSetMigConfig(configManager, gpu, desired, budget, checkReservations)
+	}
+
+	engine := operation.NewEngine()
+	err = engine.RunContext(ctx, ops)
+	if err == nil {
+		return configManager.GetMigDevices(gpu)
+	}
+	if errors.Is(err, nvmlerrors.ErrGpuLost) {
+		return nil, err
+	}
+
+	log.Warnf("Rolling back MIG config on GPU %d: %v", gpu, err)
+	rerr := engine.Rollback()
+	if rerr != nil {
+		return nil, fmt.Errorf("%v: error rolling back: %w", err, rerr)
+	}
+	if ctx.Err() != nil || len(exclusions) > 0 || errors.Is(err, operation.ErrConcurrentModification) {
+		return nil, err
+	}
+
+	return fallBackToSetMigConfig(configManager, gpu, desired, budget, checkReservations)
+}
+
+// fallBackToSetMigConfig applies 'desired' to 'gpu' with 'configManager'
+// (within 'budget'), which first clears all MIG devices on 'gpu'. Unlike the
+// planned operations, these were never checked against the allocation
+// ledger, so it refuses to go ahead if 'checkReservations' refuses to destroy
+// any of them.
+func fallBackToSetMigConfig(configManager config.Manager, gpu int, desired types.MigConfig, budget config.PermutationBudget, checkReservations func([]destruction) error) ([]types.MigDevice, error) {
+	current, err := configManager.GetMigDevices(gpu)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG devices: %w", err)
+	}
+
+	var ds []destruction
+	for _, d := range current {
+		ds = append(ds, destruction{gpu: gpu, gi: d.GpuInstanceID})
+	}
+	err = checkReservations(ds)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fall back to recreating all MIG devices: %w", err)
+	}
+
+	return configManager.SetMigConfig(gpu, desired, config.WithPermutationBudget(budget))
+}
+
+// planMigConfigOperations returns the operations that replace the MIG devices
+// on 'gpu' with those in 'desired'. GPU instances that already hold MIG
+// devices from 'desired' are kept where possible, so that the workloads
+// running on them are not disrupted. New GPU instances are placed according
+// to 'preference', outside of the slices 'exclusions' reserve.
+func planMigConfigOperations(configManager config.Manager, gpu int, desired types.MigConfig, preference types.PlacementPreference, exclusions []types.PlacementExclusion) ([]operation.Operation, error) {
+	instanceManager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG instance Manager: %w", err)
+	}
+
+	return planOperations(configManager, instanceManager, gpu, desired, preference, exclusions)
+}
+
+// planOperations is 'planMigConfigOperations' with the operations bound to
+// 'instanceManager'.
+func planOperations(configManager config.Manager, instanceManager config.InstanceManager, gpu int, desired types.MigConfig, preference types.PlacementPreference, exclusions []types.PlacementExclusion) ([]operation.Operation, error) {
+	current, err := configManager.GetMigDevices(gpu)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG devices: %w", err)
+	}
+
+	planned, err := configManager.PlanMigConfig(gpu, desired, config.WithPreservedDevices(current), config.WithPlacementPreference(preference), config.WithPlacementExclusions(exclusions))
+	if err != nil {
+		return nil, fmt.Errorf("error planning MIG config: %w", err)
+	}
+
+	return operation.Plan(instanceManager, gpu, planned)
+}
+
+// GPUOperations holds the operations planned for a single GPU.
+type GPUOperations struct {
+	GPU        int
+	DeviceID   types.DeviceID
+	Operations []operation.Operation
+}
+
+// PlanOperations returns the exact operations that applying the selected MIG
+// config in 'c' would perform on each GPU, without performing any of them.
+// GPUs that need no changes are left out. MIG devices on a GPU whose MIG mode
+// first has to be enabled cannot be placed until the mode change has taken
+// effect, so only the mode change is returned for it.
+func PlanOperations(c *Context) ([]GPUOperations, error) {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	var gpus []GPUOperations
+	err = assert.WalkSelectedMigConfigForEachGPUInOrder(c.MigConfig, c.UnmanagedDevices, c.deviceOrder, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		ops, err := planGPUOperations(c, mc, i, d)
+		if err != nil {
+			return err
+		}
+		if len(ops) != 0 {
+			gpus = append(gpus, GPUOperations{GPU: i, DeviceID: d, Operations: ops})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return gpus, nil
+}
+
+// planGPUOperations returns the operations that applying 'mc' would perform
+// on GPU 'i'.
+func planGPUOperations(c *Context, mc *v1.MigConfigSpec, i int, d types.DeviceID) ([]operation.Operation, error) {
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %w", err)
+	}
+
+	capable, err := modeManager.IsMigCapable(i)
+	if err != nil {
+		return nil, fmt.Errorf("error checking MIG capable: %w", err)
+	}
+	if !capable && mc.MigEnabled && !mc.MatchesAllDevices() {
+		return nil, fmt.Errorf("cannot set MIG mode on non MIG-capable GPU")
+	}
+	if !capable {
+		return nil, nil
+	}
+
+	desiredMode := mode.Disabled
+	if mc.MigEnabled {
+		desiredMode = mode.Enabled
+	}
+
+	currentMode, err := modeManager.GetMigMode(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG mode: %w", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %w", err)
+	}
+
+	if currentMode != desiredMode {
+		var ops []operation.Operation
+		if currentMode == mode.Enabled {
+			ops, err = planMigConfigOperations(configManager, i, nil, "", nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ops = append(ops, &operation.EnableMode{Manager: modeManager, GPU: i, Mode: desiredMode})
+		if desiredMode == mode.Enabled && !c.Flags.ModeOnly {
+			log.Infof("MIG devices on GPU %d will be planned once MIG mode is enabled", i)
+		}
+		return ops, nil
+	}
+
+	if !mc.MigEnabled || c.Flags.ModeOnly {
+		return nil, nil
+	}
+
+	current, err := configManager.GetMigConfig(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIGConfig: %w", err)
+	}
+
+	desired := mc.MigDevices
+	if mc.Fill != "" {
+		desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+		if err != nil {
+			return nil, fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+		}
+	}
+
+	layer := c.ComputeInstanceConfig.Select(i, d)
+	matches, err := util.MigConfigMatches(i, current, layer.Apply(desired))
+	if err != nil {
+		return nil, fmt.Errorf("error comparing MIGConfig: %w", err)
+	}
+	if matches {
+		return nil, nil
+	}
+	if len(layer) != 0 && gpuInstancesMatch(i, desired) {
+		log.Infof("Compute instances on GPU %d will be split as %v", i, layer.Apply(desired))
+		return nil, nil
+	}
+	if len(layer) != 0 {
+		log.Infof("Compute instances on GPU %d will be split as %v after the planned operations", i, layer.Apply(desired))
+	}
+
+	return planMigConfigOperations(configManager, i, desired, mc.Placement, mc.PlacementExclusions)
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// failingOperation is an operation that always fails.
+type failingOperation struct{}
+
+func (failingOperation) Do() error      { return errors.New("injected failure") }
+func (failingOperation) Undo() error    { return nil }
+func (failingOperation) String() string { return "Fail" }
+
+func TestSetMigConfigKeepsReservedMigDevices(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description string
+		plan        func() ([]operation.Operation, error)
+	}{
+		{
+			"Operations cannot be planned",
+			func() ([]operation.Operation, error) {
+				return nil, errors.New("injected planning failure")
+			},
+		},
+		{
+			"Operations fail and are rolled back",
+			func() ([]operation.Operation, error) {
+				return []operation.Operation{failingOperation{}}, nil
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := testutil.NewServerBuilder().
+				WithGPU(testutil.GPU{
+					Model:      testutil.A100_SXM4_40GB,
+					MigEnabled: true,
+					GpuInstances: []testutil.GpuInstance{
+						{
+							Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+							ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+						},
+					},
+				}).
+				MustBuild()
+			configManager := config.NewMockNvmlMigConfigManager(server)
+
+			current, err := configManager.GetMigDevices(0)
+			require.Nil(t, err, "Unexpected failure from GetMigDevices")
+			require.Len(t, current, 1)
+
+			// The mock server does not expose MIG device handles, so
+			// present the only MIG device on the GPU as 'MIG-a'.
+			migDevice := &mock.Device{
+				GetUUIDFunc:              func() (string, nvml.Return) { return "MIG-a", nvml.SUCCESS },
+				GetGpuInstanceIdFunc:     func() (int, nvml.Return) { return int(current[0].GpuInstanceID), nvml.SUCCESS },
+				GetComputeInstanceIdFunc: func() (int, nvml.Return) { return int(current[0].ComputeInstanceID), nvml.SUCCESS },
+			}
+			device := server.Devices[0].(*testutil.Device)
+			device.GetMaxMigDeviceCountFunc = func() (int, nvml.Return) { return 1, nvml.SUCCESS }
+			device.GetMigDeviceHandleByIndexFunc = func(int) (nvml.Device, nvml.Return) { return migDevice, nvml.SUCCESS }
+
+			storeFile := filepath.Join(t.TempDir(), "state.db")
+			s, err := store.Open(storeFile)
+			require.Nil(t, err)
+			_, err = ledger.NewLedger(s).Reserve("MIG-a", 0, "slurm", 0, nil)
+			require.Nil(t, err)
+			require.Nil(t, s.Close())
+
+			c := &Context{Flags: &Flags{StoreFile: storeFile}}
+			c.Nvml = server
+
+			_, err = setMigConfig(context.Background(), configManager, 0, types.MigConfig{"1g.5gb": 7}, tc.plan, nil, config.PermutationBudget{}, c.checkReservations)
+			require.NotNil(t, err, "Unexpected success from setMigConfig")
+			require.Contains(t, err.Error(), "MIG-a (held by 'slurm')")
+
+			migConfig, err := configManager.GetMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from GetMigConfig")
+			require.Equal(t, types.MigConfig{"3g.20gb": 1}, migConfig)
+
+			// Without the reservation, it falls back as before.
+			c.Flags.StoreFile = ""
+			_, err = setMigConfig(context.Background(), configManager, 0, types.MigConfig{"1g.5gb": 7}, tc.plan, nil, config.PermutationBudget{}, c.checkReservations)
+			require.Nil(t, err, "Unexpected failure from setMigConfig")
+
+			migConfig, err = configManager.GetMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from GetMigConfig")
+			require.Equal(t, types.MigConfig{"1g.5gb": 7}, migConfig)
+		})
+	}
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/fabric"
+)
+
+// Orders in which apply can walk the GPUs on a node.
+const (
+	DeviceOrderIndex    = "index"
+	DeviceOrderPCI      = "pci"
+	DeviceOrderUUIDFile = "uuid-file"
+)
+
+// DeviceOrderer decides the order in which apply walks the GPUs on a node.
+type DeviceOrderer interface {
+	// Order returns the indices of all GPUs on the node in the order they
+	// should be walked, or nil to walk them in index order.
+	Order() ([]int, error)
+}
+
+// NewDeviceOrderer returns the 'DeviceOrderer' selected in 'f'.
+func NewDeviceOrderer(f *Flags) (DeviceOrderer, error) {
+	switch f.DeviceOrder {
+	case DeviceOrderIndex:
+		return indexOrder{}, nil
+	case DeviceOrderPCI:
+		return &pciOrder{pciBusIDs: util.GetGPUPciBusIDs}, nil
+	case DeviceOrderUUIDFile:
+		return &uuidFileOrder{path: f.DeviceOrderFile, uuids: util.GetGPUUUIDs}, nil
+	}
+	return nil, fmt.Errorf("unrecognized device order: %v", f.DeviceOrder)
+}
+
+// indexOrder walks GPUs in NVML index order.
+type indexOrder struct{}
+
+func (indexOrder) Order() ([]int, error) {
+	return nil, nil
+}
+
+// pciOrder walks GPUs in PCI bus ID order.
+type pciOrder struct {
+	pciBusIDs func() ([]string, error)
+}
+
+func (o *pciOrder) Order() ([]int, error) {
+	ids, err := o.pciBusIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error getting GPU PCI bus IDs: %v", err)
+	}
+	for i := range ids {
+		ids[i] = fabric.NormalizePCIBusID(ids[i])
+	}
+
+	order := make([]int, len(ids))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return ids[order[a]] < ids[order[b]]
+	})
+	return order, nil
+}
+
+// uuidFileOrder walks the GPUs listed (by UUID) in a file first, in the
+// order they are listed, followed by all other GPUs in index order.
+type uuidFileOrder struct {
+	path  string
+	uuids func() ([]string, error)
+}
+
+func (o *uuidFileOrder) Order() ([]int, error) {
+	data, err := util.ReadFile(o.path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading device order file: %v", err)
+	}
+	listed, err := ParseDeviceOrderFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing device order file: %v", err)
+	}
+
+	uuids, err := o.uuids()
+	if err != nil {
+		return nil, fmt.Errorf("error getting GPU UUIDs: %v", err)
+	}
+	indices := make(map[string]int)
+	for i, uuid := range uuids {
+		indices[uuid] = i
+	}
+
+	var order []int
+	seen := make([]bool, len(uuids))
+	for _, uuid := range listed {
+		i, exists := indices[uuid]
+		if !exists {
+			log.Debugf("GPU %v from device order file not found, ignoring", uuid)
+			continue
+		}
+		order = append(order, i)
+		seen[i] = true
+	}
+	for i := range uuids {
+		if !seen[i] {
+			order = append(order, i)
+		}
+	}
+	return order, nil
+}
+
+// ParseDeviceOrderFile parses a device order file, which lists one GPU UUID
+// per line. Blank lines and lines starting with '#' are ignored.
+func ParseDeviceOrderFile(data []byte) ([]string, error) {
+	var uuids []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if seen[line] {
+			return nil, fmt.Errorf("GPU %v listed more than once", line)
+		}
+		seen[line] = true
+		uuids = append(uuids, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPCIOrder(t *testing.T) {
+	o := &pciOrder{
+		pciBusIDs: func() ([]string, error) {
+			return []string{"0000:47:00.0", "00000000:07:00.0", "0000:0F:00.0", "0000:0a:00.0"}, nil
+		},
+	}
+	order, err := o.Order()
+	require.Nil(t, err)
+	require.Equal(t, []int{1, 3, 2, 0}, order)
+}
+
+func TestUUIDFileOrder(t *testing.T) {
+	uuids := []string{"GPU-a", "GPU-b", "GPU-c", "GPU-d"}
+
+	testCases := []struct {
+		description   string
+		contents      string
+		expectedOrder []int
+		expectedError bool
+	}{
+		{
+			description:   "all GPUs listed",
+			contents:      "GPU-d\nGPU-c\nGPU-b\nGPU-a\n",
+			expectedOrder: []int{3, 2, 1, 0},
+		},
+		{
+			description:   "unlisted GPUs walked last",
+			contents:      "# inventory order\nGPU-c\n\nGPU-a\n",
+			expectedOrder: []int{2, 0, 1, 3},
+		},
+		{
+			description:   "unknown GPUs ignored",
+			contents:      "GPU-x\nGPU-b\n",
+			expectedOrder: []int{1, 0, 2, 3},
+		},
+		{
+			description:   "duplicate GPU",
+			contents:      "GPU-b\nGPU-b\n",
+			expectedError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "order")
+			require.Nil(t, os.WriteFile(path, []byte(tc.contents), 0600))
+
+			o := &uuidFileOrder{
+				path:  path,
+				uuids: func() ([]string, error) { return uuids, nil },
+			}
+			order, err := o.Order()
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedOrder, order)
+		})
+	}
+}
@@ -0,0 +1,501 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// NewPlan plans the selected MIG config in 'c' as a versioned 'Plan' that
+// records the current state of every GPU it changes.
+func NewPlan(c *Context) (*planv1.Plan, error) {
+	gpus, err := PlanOperations(c)
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.NvmlInit(c.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	plan := &planv1.Plan{
+		Version:        planv1.Version,
+		SelectedConfig: c.Flags.SelectedConfig,
+		GPUs:           []planv1.GPUPlan{},
+	}
+	for _, g := range gpus {
+		state, err := getGPUState(g.GPU)
+		if err != nil {
+			return nil, fmt.Errorf("error getting state of GPU %d: %w", g.GPU, err)
+		}
+		steps, err := toSteps(g.Operations)
+		if err != nil {
+			return nil, fmt.Errorf("error converting operations on GPU %d: %w", g.GPU, err)
+		}
+		plan.GPUs = append(plan.GPUs, planv1.GPUPlan{
+			Index:    g.GPU,
+			DeviceID: g.DeviceID,
+			State:    state,
+			Steps:    steps,
+		})
+	}
+
+	return plan, nil
+}
+
+// ReadPlanFile reads and parses the plan file at 'path' ('-' for stdin).
+func ReadPlanFile(path string) (*planv1.Plan, error) {
+	b, err := util.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+	return planv1.Parse(b)
+}
+
+// getGPUState returns the MIG mode and GPU / compute instances of 'gpu'.
+func getGPUState(gpu int) (planv1.GPUState, error) {
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return planv1.GPUState{}, fmt.Errorf("error creating MIG mode Manager: %w", err)
+	}
+
+	m, err := modeManager.GetMigMode(gpu)
+	if err != nil {
+		return planv1.GPUState{}, fmt.Errorf("error getting MIG mode: %w", err)
+	}
+	if m != mode.Enabled {
+		return planv1.GPUState{}, nil
+	}
+
+	instanceManager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return planv1.GPUState{}, fmt.Errorf("error creating MIG instance Manager: %w", err)
+	}
+
+	gis, err := instanceManager.ListGpuInstances(gpu)
+	if err != nil {
+		return planv1.GPUState{}, fmt.Errorf("error listing GPU instances: %w", err)
+	}
+
+	cis, err := instanceManager.ListComputeInstances(gpu)
+	if err != nil {
+		return planv1.GPUState{}, fmt.Errorf("error listing compute instances: %w", err)
+	}
+
+	return planv1.GPUState{MigEnabled: true, GpuInstances: gis, ComputeInstances: cis}, nil
+}
+
+// toSteps converts 'ops' into plan steps. Operations sharing a GPU instance
+// are given steps with the same GPU instance ref.
+func toSteps(ops []operation.Operation) ([]planv1.Step, error) {
+	refs := make(map[*types.GpuInstance]int)
+	ref := func(gi *types.GpuInstance) *planv1.GpuInstanceRef {
+		r, exists := refs[gi]
+		if !exists {
+			r = len(refs)
+			refs[gi] = r
+		}
+		return &planv1.GpuInstanceRef{Ref: r, GpuInstance: *gi}
+	}
+
+	var steps []planv1.Step
+	for _, op := range ops {
+		step := planv1.Step{Description: op.String()}
+		switch o := op.(type) {
+		case *operation.EnableMode:
+			enabled := o.Mode == mode.Enabled
+			step.Type = planv1.SetMigMode
+			step.MigEnabled = &enabled
+		case *operation.CreateGI:
+			step.Type = planv1.CreateGpuInstance
+			step.GpuInstance = ref(o.GpuInstance)
+			step.Start = o.Start
+		case *operation.CreateCI:
+			step.Type = planv1.CreateComputeInstance
+			step.GpuInstance = ref(o.GpuInstance)
+			step.Profile = o.Profile
+			step.Start = o.Start
+		case *operation.DestroyCI:
+			device := o.Device
+			step.Type = planv1.DestroyComputeInstance
+			step.GpuInstance = ref(o.GpuInstance)
+			step.ComputeInstance = &device
+			step.Start = o.Start
+		case *operation.DestroyGI:
+			step.Type = planv1.DestroyGpuInstance
+			step.GpuInstance = ref(o.GpuInstance)
+		default:
+			return nil, fmt.Errorf("unsupported operation: %v", op)
+		}
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// fromSteps converts the plan 'steps' for 'gpu' back into operations that use
+// 'modeManager' and 'instanceManager'.
+func fromSteps(gpu int, steps []planv1.Step, modeManager mode.Manager, instanceManager config.InstanceManager) ([]operation.Operation, error) {
+	gis := make(map[int]*types.GpuInstance)
+	gpuInstance := func(step planv1.Step) (*types.GpuInstance, error) {
+		if step.GpuInstance == nil {
+			return nil, fmt.Errorf("missing 'gpu-instance' in '%v' step", step.Type)
+		}
+		gi, exists := gis[step.GpuInstance.Ref]
+		if !exists {
+			copied := step.GpuInstance.GpuInstance
+			gi = &copied
+			gis[step.GpuInstance.Ref] = gi
+		}
+		return gi, nil
+	}
+
+	var ops []operation.Operation
+	for _, step := range steps {
+		var op operation.Operation
+		switch step.Type {
+		case planv1.SetMigMode:
+			if step.MigEnabled == nil {
+				return nil, fmt.Errorf("missing 'mig-enabled' in '%v' step", step.Type)
+			}
+			m := mode.Disabled
+			if *step.MigEnabled {
+				m = mode.Enabled
+			}
+			op = &operation.EnableMode{Manager: modeManager, GPU: gpu, Mode: m}
+		case planv1.CreateGpuInstance:
+			gi, err := gpuInstance(step)
+			if err != nil {
+				return nil, err
+			}
+			op = &operation.CreateGI{Manager: instanceManager, GPU: gpu, GpuInstance: gi, Start: step.Start}
+		case planv1.CreateComputeInstance:
+			gi, err := gpuInstance(step)
+			if err != nil {
+				return nil, err
+			}
+			op = &operation.CreateCI{Manager: instanceManager, GPU: gpu, GpuInstance: gi, Profile: step.Profile, Start: step.Start}
+		case planv1.DestroyComputeInstance:
+			gi, err := gpuInstance(step)
+			if err != nil {
+				return nil, err
+			}
+			if step.ComputeInstance == nil {
+				return nil, fmt.Errorf("missing 'compute-instance' in '%v' step", step.Type)
+			}
+			op = &operation.DestroyCI{Manager: instanceManager, GPU: gpu, GpuInstance: gi, Device: *step.ComputeInstance, Start: step.Start}
+		case planv1.DestroyGpuInstance:
+			gi, err := gpuInstance(step)
+			if err != nil {
+				return nil, err
+			}
+			op = &operation.DestroyGI{Manager: instanceManager, GPU: gpu, GpuInstance: gi}
+		default:
+			return nil, fmt.Errorf("unknown step type: %v", step.Type)
+		}
+		ops = append(ops, op)
+	}
+
+	return ops, nil
+}
+
+// ApplyPlan applies exactly the steps in the plan file referenced in 'f'
+// (running all hooks along the way). It refuses to do so if any GPU in the
+// plan has changed since the plan was made. It returns the set of MIG devices
+// on each GPU whose MIG devices were changed.
+func ApplyPlan(c *cli.Context, f *Flags) ([]Result, error) {
+	results, _, err := applyPlan(c, f)
+	return results, err
+}
+
+// applyPlan is like ApplyPlan, but also returns whether any change was applied.
+func applyPlan(c *cli.Context, f *Flags) ([]Result, bool, error) {
+	log.Debugf("Parsing plan file...")
+	plan, err := ReadPlanFile(f.PlanFile)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing plan file: %v", err)
+	}
+
+	hooksSpec := &hooks.Spec{}
+	if f.HooksFile != "" {
+		log.Debugf("Parsing Hooks file...")
+		hooksSpec, err = ParseHooksFile(f.HooksFile)
+		if err != nil {
+			return nil, false, fmt.Errorf("error parsing hooks file: %v", err)
+		}
+	}
+
+	context := &Context{
+		Flags:   f,
+		Hooks:   NewApplyHooks(hooksSpec.Hooks),
+		Results: []Result{},
+		Context: assert.Context{
+			Context: c,
+			Flags:   &f.Flags,
+			Nvml:    nvml.New(),
+		},
+		cancelState: cancelState{ctx: c.Context},
+	}
+	applier := &planApplier{Context: context, plan: plan}
+
+	unlock, err := context.lockNode()
+	if err != nil {
+		return nil, false, err
+	}
+	defer unlock()
+
+	log.Debugf("Checking the plan against the current state of each GPU...")
+	err = applier.verify()
+	if err != nil {
+		return nil, false, fmt.Errorf("refusing to apply plan: %v", err)
+	}
+
+	err = context.checkReservations(planDestructions(plan))
+	if err != nil {
+		return nil, false, fmt.Errorf("refusing to apply plan: %w", err)
+	}
+
+	var gpus []int
+	for _, g := range plan.GPUs {
+		gpus = append(gpus, g.Index)
+	}
+	err = context.checkGPUs(gpus)
+	if err != nil {
+		return nil, false, fmt.Errorf("refusing to apply plan: %w", err)
+	}
+
+	start := time.Now()
+	tracker := &changeTracker{MigConfigApplier: applier}
+	err = ApplyMigConfigWithHooks(log, c, false, context.Hooks, tracker)
+	invalidateScratch(context)
+	RecordApply(f, plan.SelectedConfig, start, err)
+	auditApply(context, plan.SelectedConfig, err)
+	if err != nil {
+		if hint := nvmlErrorHint(err); hint != "" {
+			log.Warn(hint)
+		}
+		return nil, tracker.changed, fmt.Errorf("error applying plan with hooks: %w", err)
+	}
+
+	return context.Results, tracker.changed, nil
+}
+
+// planApplier is a 'MigConfigApplier' that applies the steps of a plan.
+// Steps for GPUs whose MIG mode changes are applied as part of the MIG mode
+// change; the steps for all other GPUs as part of the MIG config change.
+type planApplier struct {
+	*Context
+	plan *planv1.Plan
+
+	// deviceIDs, pending and desired record the MIG mode changes made by
+	// the plan, so that GPUs can be reset once they have all been made.
+	deviceIDs []types.DeviceID
+	pending   []bool
+	desired   map[int]mode.MigMode
+}
+
+var _ MigConfigApplier = (*planApplier)(nil)
+
+// verify checks that every GPU in the plan is still the same type of GPU in
+// the same state as when the plan was made.
+func (p *planApplier) verify() error {
+	err := util.NvmlInit(p.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(p.Nvml)
+
+	deviceIDs, err := util.GetGPUDeviceIDs()
+	if err != nil {
+		return fmt.Errorf("error enumerating GPUs: %w", err)
+	}
+
+	for _, g := range p.plan.GPUs {
+		if g.Index < 0 || g.Index >= len(deviceIDs) {
+			return fmt.Errorf("GPU %d in plan not found", g.Index)
+		}
+		if deviceIDs[g.Index] != g.DeviceID {
+			return fmt.Errorf("GPU %d is a %v, but the plan was made for a %v", g.Index, deviceIDs[g.Index], g.DeviceID)
+		}
+		state, err := getGPUState(g.Index)
+		if err != nil {
+			return fmt.Errorf("error getting state of GPU %d: %w", g.Index, err)
+		}
+		if !state.Equals(g.State) {
+			return fmt.Errorf("state of GPU %d has changed since the plan was made", g.Index)
+		}
+	}
+
+	return nil
+}
+
+// changesMigMode checks if the steps planned for 'g' change its MIG mode.
+func changesMigMode(g planv1.GPUPlan) bool {
+	for _, step := range g.Steps {
+		if step.Type == planv1.SetMigMode {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *planApplier) AssertMigMode() error {
+	for _, g := range p.plan.GPUs {
+		if changesMigMode(g) {
+			return fmt.Errorf("plan changes the MIG mode of GPU %d", g.Index)
+		}
+	}
+	return nil
+}
+
+func (p *planApplier) ApplyMigMode() error {
+	err := p.runSteps(true)
+	if err != nil {
+		return err
+	}
+	err = finishMigModeChange(p.Context, p.deviceIDs, p.pending, p.desired, func(i int, d types.DeviceID) bool {
+		_, exists := p.desired[i]
+		return !exists
+	})
+	if err != nil {
+		return err
+	}
+	return p.canceledError()
+}
+
+func (p *planApplier) AssertMigConfig() error {
+	for _, g := range p.plan.GPUs {
+		if !changesMigMode(g) && len(g.Steps) != 0 {
+			return fmt.Errorf("plan changes the MIG devices of GPU %d", g.Index)
+		}
+	}
+	return nil
+}
+
+func (p *planApplier) ApplyMigConfig() error {
+	err := p.runSteps(false)
+	if err != nil {
+		return err
+	}
+	return p.canceledError()
+}
+
+// runSteps runs the steps of every GPU in the plan whose MIG mode does (or
+// does not) change, depending on 'modeChanges'. The steps of each GPU are
+// rolled back if any of them fail. Once the apply is canceled, the steps stop
+// at the next safe point and the remaining GPUs are recorded as pending.
+func (p *planApplier) runSteps(modeChanges bool) error {
+	err := util.NvmlInit(p.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(p.Nvml)
+
+	p.deviceIDs, err = util.GetGPUDeviceIDs()
+	if err != nil {
+		return fmt.Errorf("error enumerating GPUs: %w", err)
+	}
+	p.pending = make([]bool, len(p.deviceIDs))
+	p.desired = make(map[int]mode.MigMode)
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG mode Manager: %w", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG config Manager: %w", err)
+	}
+
+	instanceManager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %w", err)
+	}
+
+	p.progress = progress{}
+	for _, g := range p.plan.GPUs {
+		if changesMigMode(g) != modeChanges || len(g.Steps) == 0 {
+			continue
+		}
+		if p.canceled() != nil {
+			p.progress.pending = append(p.progress.pending, g.Index)
+			continue
+		}
+		log.Debugf("Applying plan to GPU %d", g.Index)
+
+		ops, err := fromSteps(g.Index, g.Steps, modeManager, instanceManager)
+		if err != nil {
+			return fmt.Errorf("error reading steps for GPU %d: %w", g.Index, err)
+		}
+
+		engine := operation.NewEngine()
+		err = engine.RunContext(p.cancelContext(), ops)
+		if err != nil {
+			log.Warnf("Rolling back plan on GPU %d: %v", g.Index, err)
+			rerr := engine.Rollback()
+			if rerr != nil {
+				return fmt.Errorf("%w: error rolling back: %v", err, rerr)
+			}
+			if p.canceled() != nil && errors.Is(err, p.canceled()) {
+				p.progress.pending = append(p.progress.pending, g.Index)
+				continue
+			}
+			return err
+		}
+		p.progress.completed = append(p.progress.completed, g.Index)
+		p.markChanged(g.Index)
+
+		if modeChanges {
+			for _, op := range ops {
+				if o, ok := op.(*operation.EnableMode); ok {
+					p.desired[g.Index] = o.Mode
+				}
+			}
+			p.pending[g.Index], err = modeManager.IsMigModeChangePending(g.Index)
+			if err != nil {
+				return fmt.Errorf("error checking pending MIG mode change on GPU %d: %w", g.Index, err)
+			}
+			continue
+		}
+
+		devices, err := configManager.GetMigDevices(g.Index)
+		if err != nil {
+			return fmt.Errorf("error getting MIG devices on GPU %d: %w", g.Index, err)
+		}
+		p.Results = append(p.Results, Result{GPU: g.Index, MigDevices: devices})
+	}
+
+	return nil
+}
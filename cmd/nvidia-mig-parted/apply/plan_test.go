@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestStepsRoundTrip(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		MustBuild()
+	modeManager := mode.NewMockNvmlMigModeManager(server)
+	configManager := config.NewMockNvmlMigConfigManager(server)
+	instanceManager := config.NewMockNvmlInstanceManager(server)
+
+	planned, err := configManager.PlanMigConfig(0, types.MigConfig{"1c.2g.10gb": 2})
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+
+	ops, err := operation.Plan(instanceManager, 0, planned)
+	require.Nil(t, err, "Unexpected failure from Plan")
+	ops = append(ops, &operation.EnableMode{Manager: modeManager, GPU: 0, Mode: mode.Enabled})
+
+	steps, err := toSteps(ops)
+	require.Nil(t, err, "Unexpected failure from toSteps")
+
+	var stepTypes []string
+	var refs []int
+	for _, step := range steps {
+		stepTypes = append(stepTypes, step.Type)
+		if step.GpuInstance != nil {
+			refs = append(refs, step.GpuInstance.Ref)
+		}
+	}
+	require.Equal(t, []string{
+		planv1.DestroyComputeInstance,
+		planv1.DestroyGpuInstance,
+		planv1.CreateGpuInstance,
+		planv1.CreateComputeInstance,
+		planv1.CreateComputeInstance,
+		planv1.SetMigMode,
+	}, stepTypes)
+	require.Equal(t, []int{0, 0, 1, 1, 1}, refs)
+
+	b, err := json.Marshal(steps)
+	require.Nil(t, err, "Unexpected failure from Marshal")
+	var parsed []planv1.Step
+	err = json.Unmarshal(b, &parsed)
+	require.Nil(t, err, "Unexpected failure from Unmarshal")
+
+	ops, err = fromSteps(0, parsed, modeManager, instanceManager)
+	require.Nil(t, err, "Unexpected failure from fromSteps")
+	require.Len(t, ops, len(steps))
+	for i, op := range ops {
+		require.Equal(t, steps[i].Description, op.String())
+	}
+
+	engine := operation.NewEngine()
+	err = engine.Run(ops)
+	require.Nil(t, err, "Unexpected failure from Run")
+
+	current, err := configManager.GetMigConfig(0)
+	require.Nil(t, err, "Unexpected failure from GetMigConfig")
+	require.Equal(t, types.MigConfig{"1c.2g.10gb": 2}, current)
+
+	err = engine.Rollback()
+	require.Nil(t, err, "Unexpected failure from Rollback")
+
+	current, err = configManager.GetMigConfig(0)
+	require.Nil(t, err, "Unexpected failure from GetMigConfig")
+	require.Equal(t, types.MigConfig{"3g.20gb": 1}, current)
+}
+
+func TestFromStepsInvalid(t *testing.T) {
+	testCases := []struct {
+		description string
+		step        planv1.Step
+	}{
+		{"Unknown type", planv1.Step{Type: "bogus"}},
+		{"Missing MIG mode", planv1.Step{Type: planv1.SetMigMode}},
+		{"Missing GPU instance", planv1.Step{Type: planv1.CreateComputeInstance, Profile: "1g.5gb"}},
+		{"Missing compute instance", planv1.Step{Type: planv1.DestroyComputeInstance, GpuInstance: &planv1.GpuInstanceRef{}}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := fromSteps(0, []planv1.Step{tc.step}, nil, nil)
+			require.NotNil(t, err, "Unexpected success from fromSteps")
+		})
+	}
+}
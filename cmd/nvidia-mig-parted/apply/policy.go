@@ -0,0 +1,65 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/policy"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ParsePolicyFile parses a policy file and unmarshals it into a 'policy.Policy'.
+func ParsePolicyFile(policyFile string) (*policy.Policy, error) {
+	policyYaml, err := util.ReadFile(policyFile)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	p, err := policy.Parse(policyYaml)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal error: %v", err)
+	}
+
+	return p, nil
+}
+
+// CheckPolicy evaluates 'p' against the MIG devices the selected MIG config
+// assigns to each GPU on the node, logging every violation it finds.
+func CheckPolicy(c *Context, p *policy.Policy) error {
+	violated := false
+	err := assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if !mc.MigEnabled {
+			return nil
+		}
+		for _, v := range p.Check(d, mc.MigDevices) {
+			log.Errorf("GPU %v (%v): %v", i, d, util.Capitalize(v.Error()))
+			violated = true
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if violated {
+		return fmt.Errorf("selected MIG config violates policy")
+	}
+	return nil
+}
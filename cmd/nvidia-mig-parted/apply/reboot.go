@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// updateRebootMarker writes the reboot marker configured in 'c' if any GPU in
+// 'desired' still has a pending MIG mode change, and removes it otherwise.
+func updateRebootMarker(c *Context, deviceIDs []types.DeviceID, desired map[int]mode.MigMode, reason string) error {
+	if c.Flags.RebootMarkerFile == "" {
+		return nil
+	}
+
+	gpus, err := getPendingModeChanges(c, deviceIDs, desired)
+	if err != nil {
+		return fmt.Errorf("error checking for pending MIG mode changes: %w", err)
+	}
+
+	if len(gpus) == 0 {
+		return reboot.Clear(c.Flags.RebootMarkerFile)
+	}
+
+	log.Warnf("A reboot is required for the MIG mode change to take effect on %v GPU(s)", len(gpus))
+	marker := &reboot.Marker{
+		Reason:    reason,
+		GPUs:      gpus,
+		Timestamp: time.Now().UTC(),
+	}
+	return reboot.Write(c.Flags.RebootMarkerFile, marker)
+}
+
+// getPendingModeChanges returns every GPU in 'desired' whose MIG mode change
+// has not yet taken effect.
+func getPendingModeChanges(c *Context, deviceIDs []types.DeviceID, desired map[int]mode.MigMode) ([]reboot.GPU, error) {
+	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
+	if err != nil {
+		return nil, fmt.Errorf("error checking if nvidia module loaded: %w", err)
+	}
+
+	if nvidiaModuleLoaded {
+		err := util.NvmlInit(c.Nvml)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing NVML: %w", err)
+		}
+		defer util.TryNvmlShutdown(c.Nvml)
+	}
+
+	manager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %w", err)
+	}
+
+	var indices []int
+	for i := range desired {
+		if c.isLost(i) {
+			continue
+		}
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+
+	var gpus []reboot.GPU
+	for _, i := range indices {
+		pending, err := manager.IsMigModeChangePending(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking pending MIG mode change on GPU %v: %w", i, err)
+		}
+		if !pending {
+			continue
+		}
+
+		current, err := manager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode on GPU %v: %w", i, err)
+		}
+
+		gpus = append(gpus, reboot.GPU{
+			Index:       i,
+			DeviceID:    deviceIDs[i].String(),
+			CurrentMode: current.String(),
+			DesiredMode: desired[i].String(),
+		})
+	}
+
+	return gpus, nil
+}
@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+)
+
+// destruction is a GPU instance, or a single compute instance within it,
+// that applying a MIG config would destroy on 'gpu'.
+type destruction struct {
+	gpu int
+	gi  uint32
+	ci  *uint32
+}
+
+// destroys returns whether the MIG device with GPU instance 'gi' and compute
+// instance 'ci' on the GPU of 'd' is destroyed by it.
+func (d destruction) destroys(gi, ci uint32) bool {
+	return d.gi == gi && (d.ci == nil || *d.ci == ci)
+}
+
+// operationDestructions returns what the operations in 'gpus' would destroy.
+func operationDestructions(gpus []GPUOperations) []destruction {
+	var ds []destruction
+	for _, g := range gpus {
+		for _, op := range g.Operations {
+			switch o := op.(type) {
+			case *operation.DestroyCI:
+				ci := o.Device.ComputeInstanceID
+				ds = append(ds, destruction{gpu: o.GPU, gi: o.Device.GpuInstanceID, ci: &ci})
+			case *operation.DestroyGI:
+				ds = append(ds, destruction{gpu: o.GPU, gi: o.GpuInstance.ID})
+			}
+		}
+	}
+	return ds
+}
+
+// planDestructions returns what the steps in 'p' would destroy.
+func planDestructions(p *planv1.Plan) []destruction {
+	var ds []destruction
+	for _, g := range p.GPUs {
+		for _, s := range g.Steps {
+			switch {
+			case s.Type == planv1.DestroyComputeInstance && s.ComputeInstance != nil:
+				ci := s.ComputeInstance.ComputeInstanceID
+				ds = append(ds, destruction{gpu: g.Index, gi: s.ComputeInstance.GpuInstanceID, ci: &ci})
+			case s.Type == planv1.DestroyGpuInstance && s.GpuInstance != nil:
+				ds = append(ds, destruction{gpu: g.Index, gi: s.GpuInstance.ID})
+			}
+		}
+	}
+	return ds
+}
+
+// checkReservations refuses to go ahead if any of 'ds' would destroy a MIG
+// device reserved in the allocation ledger of the store configured in 'c'
+// (see 'nvidia-mig-parted daemon --ledger-address'). Reservations that have
+// expired are ignored.
+func (c *Context) checkReservations(ds []destruction) error {
+	if c.Flags.StoreFile == "" || len(ds) == 0 {
+		return nil
+	}
+
+	reservations, err := ledger.Load(c.Flags.StoreFile)
+	if err != nil {
+		return fmt.Errorf("error loading reservations: %w", err)
+	}
+	if len(reservations) == 0 {
+		return nil
+	}
+
+	reserved := make(map[string]ledger.Reservation)
+	for _, r := range reservations {
+		reserved[r.UUID] = r
+	}
+
+	err = util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	var held []string
+	uuids := make(map[int]map[[2]uint32]string)
+	for _, d := range ds {
+		if _, exists := uuids[d.gpu]; !exists {
+			device, ret := c.Nvml.DeviceGetHandleByIndex(d.gpu)
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting device handle of GPU %d: %v", d.gpu, ret)
+			}
+			uuids[d.gpu], err = util.GetMigDeviceUUIDs(device)
+			if err != nil {
+				return fmt.Errorf("error getting MIG devices of GPU %d: %w", d.gpu, err)
+			}
+		}
+		for ids, uuid := range uuids[d.gpu] {
+			r, exists := reserved[uuid]
+			if !exists || !d.destroys(ids[0], ids[1]) {
+				continue
+			}
+			held = append(held, fmt.Sprintf("GPU %d MIG device %s (held by '%s')", r.GPU, uuid, r.Holder))
+			delete(reserved, uuid)
+		}
+	}
+
+	if len(held) > 0 {
+		sort.Strings(held)
+		return fmt.Errorf("reserved MIG device(s) would be destroyed: %v", strings.Join(held, ", "))
+	}
+	return nil
+}
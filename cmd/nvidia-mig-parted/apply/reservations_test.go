@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+func TestCheckReservations(t *testing.T) {
+	server := dgxa100.New()
+	migDevice := func(uuid string, gi, ci uint32) nvml.Device {
+		return &mock.Device{
+			GetUUIDFunc:              func() (string, nvml.Return) { return uuid, nvml.SUCCESS },
+			GetGpuInstanceIdFunc:     func() (int, nvml.Return) { return int(gi), nvml.SUCCESS },
+			GetComputeInstanceIdFunc: func() (int, nvml.Return) { return int(ci), nvml.SUCCESS },
+		}
+	}
+	migDevices := []nvml.Device{migDevice("MIG-a", 1, 0), migDevice("MIG-b", 2, 0), migDevice("MIG-c", 2, 1)}
+	gpu := server.Devices[0].(*dgxa100.Device)
+	gpu.GetMaxMigDeviceCountFunc = func() (int, nvml.Return) {
+		return len(migDevices), nvml.SUCCESS
+	}
+	gpu.GetMigDeviceHandleByIndexFunc = func(i int) (nvml.Device, nvml.Return) {
+		return migDevices[i], nvml.SUCCESS
+	}
+
+	storeFile := filepath.Join(t.TempDir(), "state.db")
+	c := &Context{Flags: &Flags{StoreFile: storeFile}}
+	c.Nvml = server
+
+	ci := func(id uint32) *uint32 { return &id }
+	require.Nil(t, c.checkReservations([]destruction{{gpu: 0, gi: 2}}), "Unexpected failure without a store")
+
+	s, err := store.Open(storeFile)
+	require.Nil(t, err)
+	_, err = ledger.NewLedger(s).Reserve("MIG-c", 0, "slurm", 0, nil)
+	require.Nil(t, err)
+	require.Nil(t, s.Close())
+
+	testCases := []struct {
+		description string
+		ds          []destruction
+		expectedErr bool
+	}{
+		{"Nothing destroyed", nil, false},
+		{"Unreserved GPU instance destroyed", []destruction{{gpu: 0, gi: 1}}, false},
+		{"Unreserved compute instance destroyed", []destruction{{gpu: 0, gi: 2, ci: ci(0)}}, false},
+		{"Reserved compute instance destroyed", []destruction{{gpu: 0, gi: 2, ci: ci(1)}}, true},
+		{"GPU instance of reserved MIG device destroyed", []destruction{{gpu: 0, gi: 2}}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := c.checkReservations(tc.ds)
+			if tc.expectedErr {
+				require.NotNil(t, err)
+				require.Contains(t, err.Error(), "MIG-c (held by 'slurm')")
+				return
+			}
+			require.Nil(t, err)
+		})
+	}
+}
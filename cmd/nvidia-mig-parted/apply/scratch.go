@@ -0,0 +1,38 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"github.com/NVIDIA/mig-parted/pkg/scratch"
+)
+
+// invalidateScratch drops the leases of the scratch GPU instances on the GPUs
+// changed so far, kept in the store configured in 'c'. Whatever GPU instances
+// are left on them belong to the applied MIG config, even one created with
+// the ID, profile and placement of a scratch GPU instance destroyed along the
+// way, so none of them may be reaped. As with the labels, errors are logged
+// rather than failing the apply.
+func invalidateScratch(c *Context) {
+	if c.Flags.StoreFile == "" {
+		return
+	}
+
+	err := scratch.Invalidate(c.Flags.StoreFile, c.getChangedGPUs())
+	if err != nil {
+		log.Warnf("Error dropping leases of scratch GPU instances: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/scratch"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestInvalidateScratch(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "state.db")
+	gi := types.GpuInstance{Profile: "1g.5gb", ID: 7}
+
+	s, err := store.Open(storeFile)
+	require.Nil(t, err)
+	_, err = scratch.NewRegistry(s).Add(0, gi, "alice", time.Hour)
+	require.Nil(t, err)
+	_, err = scratch.NewRegistry(s).Add(1, gi, "bob", time.Hour)
+	require.Nil(t, err)
+	require.Nil(t, s.Close())
+
+	c := &Context{Flags: &Flags{StoreFile: storeFile}}
+	c.markChanged(0)
+	invalidateScratch(c)
+
+	s, err = store.Open(storeFile)
+	require.Nil(t, err)
+	defer s.Close()
+	leases, err := scratch.NewRegistry(s).List()
+	require.Nil(t, err)
+	require.Len(t, leases, 1, "Expected only the lease on the unchanged GPU to remain")
+	require.Equal(t, "bob", leases[0].Owner)
+}
@@ -0,0 +1,265 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"encoding/json"
+	"fmt"
+
+	cli "github.com/urfave/cli/v2"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/operation"
+	"github.com/NVIDIA/mig-parted/pkg/mig/shadow"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ShadowResult holds the outcome of simulating the selected MIG config on a
+// single GPU: every step applied to its shadow copy, in order, and the MIG
+// devices left on it afterwards. If a step failed, 'Error' holds the reason
+// and 'Steps' ends with the step that failed.
+type ShadowResult struct {
+	GPU        int               `json:"gpu"`
+	DeviceID   types.DeviceID    `json:"device-id"`
+	Steps      []planv1.Step     `json:"steps"`
+	MigEnabled bool              `json:"mig-enabled"`
+	MigDevices []types.MigDevice `json:"mig-devices"`
+	Error      string            `json:"error,omitempty"`
+
+	ops []operation.Operation
+}
+
+// shadowManagers holds the MIG managers bound to a shadow copy of the GPUs.
+type shadowManagers struct {
+	mode     mode.Manager
+	config   config.Manager
+	instance config.InstanceManager
+}
+
+func newShadowManagers(server *shadow.Server) *shadowManagers {
+	return &shadowManagers{
+		mode:     mode.NewMockNvmlMigModeManager(server),
+		config:   config.NewMockNvmlMigConfigManager(server),
+		instance: config.NewMockNvmlInstanceManager(server),
+	}
+}
+
+func shadowWrapper(c *cli.Context, f *Flags) error {
+	results, err := ShadowApply(c, f)
+	if err != nil && results == nil {
+		return err
+	}
+
+	if f.OutputFormat == JSONFormat {
+		output, merr := json.MarshalIndent(results, "", "  ")
+		if merr != nil {
+			return fmt.Errorf("error marshaling shadow apply results to JSON: %v", merr)
+		}
+		fmt.Println(string(output))
+		return err
+	}
+
+	for _, r := range results {
+		fmt.Printf("GPU %d:\n", r.GPU)
+		for _, step := range r.Steps {
+			fmt.Printf("  %v\n", step.Description)
+		}
+		if r.Error != "" {
+			fmt.Printf("  failed: %v\n", r.Error)
+			continue
+		}
+		if !r.MigEnabled {
+			fmt.Printf("  MIG disabled\n")
+			continue
+		}
+		for _, d := range r.MigDevices {
+			fmt.Printf("  %v: GPU instance %d at slices %d-%d, compute instance %d\n",
+				d.Profile, d.GpuInstanceID, d.GpuInstancePlacement.Start,
+				d.GpuInstancePlacement.Start+d.GpuInstancePlacement.Size-1, d.ComputeInstanceID)
+		}
+	}
+	return err
+}
+
+// ShadowApply parses the config files referenced in 'f' and simulates applying
+// the selected MIG config against an in-memory copy of the current state of
+// every GPU, built from their own profile and placement tables. Unlike
+// 'DryRun', the operations on each GPU are actually run against its copy, so
+// placement conflicts between them are caught, and GPUs whose MIG mode has
+// to be enabled first are planned in full. None of the GPUs are changed.
+// If the config fails to apply to any GPU, it returns the results for all
+// GPUs along with an error.
+func ShadowApply(c *cli.Context, f *Flags) ([]ShadowResult, error) {
+	context, err := newContext(c, f)
+	if err != nil {
+		return nil, err
+	}
+
+	err = util.NvmlInit(context.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(context.Nvml)
+
+	log.Debugf("Copying the current state of each GPU...")
+	server, err := shadow.Snapshot(context.Nvml)
+	if err != nil {
+		return nil, fmt.Errorf("error copying GPU state: %w", err)
+	}
+	managers := newShadowManagers(server)
+
+	results := []ShadowResult{}
+	failed := 0
+	err = assert.WalkSelectedMigConfigForEachGPUInOrder(context.MigConfig, context.UnmanagedDevices, context.deviceOrder, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		layer := context.ComputeInstanceConfig.Select(i, d)
+		result, err := shadowApplyGPU(managers, mc, layer, f.ModeOnly, i)
+		if err != nil {
+			return err
+		}
+		result.DeviceID = d
+		if result.Error != "" {
+			failed++
+		}
+		results = append(results, *result)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if failed != 0 {
+		return results, fmt.Errorf("MIG configuration cannot be applied to %d GPU(s)", failed)
+	}
+	return results, nil
+}
+
+// shadowApplyGPU simulates applying 'mc' (split by 'layer') to the shadow
+// copy of GPU 'i' behind 'managers'. Failures of the operations themselves
+// are recorded in the result; any other failure is returned as an error.
+func shadowApplyGPU(managers *shadowManagers, mc *v1.MigConfigSpec, layer types.ComputeInstanceLayer, modeOnly bool, i int) (*ShadowResult, error) {
+	result := &ShadowResult{GPU: i, Steps: []planv1.Step{}}
+
+	capable, err := managers.mode.IsMigCapable(i)
+	if err != nil {
+		return nil, fmt.Errorf("error checking MIG capable: %w", err)
+	}
+	if !capable && mc.MigEnabled && !mc.MatchesAllDevices() {
+		result.Error = "cannot set MIG mode on non MIG-capable GPU"
+		return result, nil
+	}
+	if !capable {
+		return result, nil
+	}
+
+	desiredMode := mode.Disabled
+	if mc.MigEnabled {
+		desiredMode = mode.Enabled
+	}
+
+	currentMode, err := managers.mode.GetMigMode(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG mode: %w", err)
+	}
+
+	// The shadow copy applies MIG mode changes immediately, so the MIG
+	// devices of a GPU whose mode is enabled are planned straight after.
+	if currentMode != desiredMode {
+		var ops []operation.Operation
+		if currentMode == mode.Enabled {
+			ops, err = planOperations(managers.config, managers.instance, i, nil, "", nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+		ops = append(ops, &operation.EnableMode{Manager: managers.mode, GPU: i, Mode: desiredMode})
+		if !runShadowOperations(result, ops) {
+			return result, nil
+		}
+	}
+
+	if mc.MigEnabled && !modeOnly {
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = managers.config.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return nil, fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
+		}
+
+		current, err := managers.config.GetMigConfig(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIGConfig: %w", err)
+		}
+
+		// Compute instance layers are planned together with the GPU
+		// instances they split, rather than as a separate pass.
+		if !current.Equals(layer.Apply(desired)) {
+			ops, err := planOperations(managers.config, managers.instance, i, layer.Apply(desired), mc.Placement, mc.PlacementExclusions)
+			if err != nil {
+				result.Error = fmt.Sprintf("error planning MIG config: %v", err)
+				return result, nil
+			}
+			if !runShadowOperations(result, ops) {
+				return result, nil
+			}
+		}
+	}
+
+	m, err := managers.mode.GetMigMode(i)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG mode: %w", err)
+	}
+	result.MigEnabled = m == mode.Enabled
+	if result.MigEnabled {
+		result.MigDevices, err = managers.config.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices: %w", err)
+		}
+	}
+
+	return result, nil
+}
+
+// runShadowOperations runs 'ops' one at a time, appending a step for each to
+// 'result'. It stops at the first operation that fails, recording why in
+// 'result', and reports whether all of them succeeded. Failed operations are
+// not rolled back, so that the result shows the state they failed in.
+func runShadowOperations(result *ShadowResult, ops []operation.Operation) bool {
+	// Steps are converted along with all earlier operations on the GPU, so
+	// that they refer to the same GPU instance by the same ref.
+	done := len(result.ops)
+	result.ops = append(result.ops, ops...)
+	steps, err := toSteps(result.ops)
+	if err != nil {
+		result.Error = err.Error()
+		return false
+	}
+	for j, op := range ops {
+		result.Steps = append(result.Steps, steps[done+j])
+		err := op.Do()
+		if err != nil {
+			result.Error = fmt.Sprintf("%v: %v", op, err)
+			return false
+		}
+	}
+	return true
+}
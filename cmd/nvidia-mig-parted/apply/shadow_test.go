@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/shadow"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func stepTypes(steps []planv1.Step) []string {
+	var names []string
+	for _, step := range steps {
+		names = append(names, step.Type)
+	}
+	return names
+}
+
+func TestShadowApplyGPU(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	live := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB}).
+		MustBuild()
+	server, err := shadow.Snapshot(live)
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+	managers := newShadowManagers(server)
+
+	mc := &v1.MigConfigSpec{
+		Devices:    "all",
+		MigEnabled: true,
+		MigDevices: types.MigConfig{"3g.20gb": 1, "2g.10gb": 1, "1g.5gb": 1},
+	}
+
+	result, err := shadowApplyGPU(managers, mc, nil, false, 0)
+	require.Nil(t, err, "Unexpected failure from shadowApplyGPU")
+	require.Empty(t, result.Error)
+	require.True(t, result.MigEnabled)
+	require.Equal(t, []string{
+		planv1.CreateGpuInstance,
+		planv1.CreateComputeInstance,
+		planv1.CreateGpuInstance,
+		planv1.CreateComputeInstance,
+	}, stepTypes(result.Steps))
+	require.Len(t, result.MigDevices, 3)
+
+	// MIG devices on a GPU whose MIG mode is enabled are simulated too.
+	result, err = shadowApplyGPU(managers, mc, nil, false, 1)
+	require.Nil(t, err, "Unexpected failure from shadowApplyGPU")
+	require.Empty(t, result.Error)
+	require.Equal(t, planv1.SetMigMode, result.Steps[0].Type)
+	require.Len(t, result.Steps, 7)
+	require.Len(t, result.MigDevices, 3)
+
+	result, err = shadowApplyGPU(managers, mc, nil, true, 1)
+	require.Nil(t, err, "Unexpected failure from shadowApplyGPU")
+	require.Empty(t, result.Steps)
+	require.Len(t, result.MigDevices, 3)
+
+	// The live GPUs are left untouched.
+	current, err := config.NewMockNvmlMigConfigManager(live).GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, types.MigConfig{"3g.20gb": 1}, current)
+	mode, _, ret := live.Devices[1].GetMigMode()
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, nvml.DEVICE_MIG_DISABLE, mode)
+}
+
+func TestShadowApplyGPUDoesNotFit(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	live := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		MustBuild()
+	server, err := shadow.Snapshot(live)
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+
+	mc := &v1.MigConfigSpec{
+		Devices:    "all",
+		MigEnabled: true,
+		MigDevices: types.MigConfig{"4g.20gb": 1, "3g.20gb": 1, "1g.5gb": 1},
+	}
+
+	result, err := shadowApplyGPU(newShadowManagers(server), mc, nil, false, 0)
+	require.Nil(t, err, "Unexpected failure from shadowApplyGPU")
+	require.NotEmpty(t, result.Error)
+	require.False(t, result.MigEnabled)
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"errors"
+	"time"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/telemetry"
+)
+
+// applyTelemetry emits the anonymized events for a single apply.
+type applyTelemetry struct {
+	sink      telemetry.Telemetry
+	start     time.Time
+	gpuModels map[string]int
+}
+
+func newApplyTelemetry(sink string) (*applyTelemetry, error) {
+	t, err := telemetry.New(sink)
+	if err != nil {
+		return nil, err
+	}
+
+	at := &applyTelemetry{sink: t}
+	if sink == "" {
+		return at, nil
+	}
+
+	deviceIDs, err := util.GetGPUDeviceIDs()
+	if err != nil {
+		log.Warnf("Error enumerating GPUs for telemetry: %v", err)
+		return at, nil
+	}
+	at.gpuModels = make(map[string]int)
+	for _, id := range deviceIDs {
+		at.gpuModels[id.String()]++
+	}
+
+	return at, nil
+}
+
+func (t *applyTelemetry) started() {
+	t.start = time.Now()
+	t.emit(telemetry.Event{
+		Type:      telemetry.ApplyStarted,
+		Timestamp: t.start,
+		GPUModels: t.gpuModels,
+	})
+}
+
+func (t *applyTelemetry) finished(err error) {
+	event := telemetry.Event{
+		Type:       telemetry.ApplySucceeded,
+		Timestamp:  time.Now(),
+		DurationMS: time.Since(t.start).Milliseconds(),
+		GPUModels:  t.gpuModels,
+	}
+	if err != nil {
+		event.Type = telemetry.ApplyFailed
+		event.ErrorClass = telemetryErrorClass(err)
+	}
+	t.emit(event)
+}
+
+// emit sends 'e' to the sink. Telemetry is best effort and must never fail
+// an apply, so errors are only logged.
+func (t *applyTelemetry) emit(e telemetry.Event) {
+	err := t.sink.Emit(e)
+	if err != nil {
+		log.Warnf("Error emitting telemetry event: %v", err)
+	}
+}
+
+func (t *applyTelemetry) close() {
+	err := t.sink.Close()
+	if err != nil {
+		log.Warnf("Error closing telemetry sink: %v", err)
+	}
+}
+
+// telemetryErrorClass maps 'err' onto a coarse class that is safe to report
+// without leaking any details of the node it happened on.
+func telemetryErrorClass(err error) string {
+	switch {
+	case errors.Is(err, nvmlerrors.ErrInUse):
+		return "in-use"
+	case errors.Is(err, nvmlerrors.ErrInsufficientResources):
+		return "insufficient-resources"
+	case errors.Is(err, nvmlerrors.ErrNeedsReset):
+		return "needs-reset"
+	case errors.Is(err, nvmlerrors.ErrVGPUGuest):
+		return "vgpu-guest"
+	case errors.Is(err, nvmlerrors.ErrNotSupported):
+		return "not-supported"
+	case errors.Is(err, config.ErrPermutationBudgetExhausted):
+		return "permutation-budget-exhausted"
+	case errors.As(err, new(*hooks.VetoError)):
+		return "vetoed-by-hook"
+	case errors.As(err, new(*CanceledError)):
+		return "canceled"
+	}
+	return "other"
+}
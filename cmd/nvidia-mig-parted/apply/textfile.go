@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/pkg/textfile"
+)
+
+// TextfileName is the name of the file 'apply --textfile-dir' writes its
+// metrics to.
+const TextfileName = "nvidia_mig_parted_apply.prom"
+
+// applyMetrics returns the metrics of an apply of 'selectedConfig' that
+// started at 'start' and returned 'err' at 'now'.
+func applyMetrics(selectedConfig string, start, now time.Time, err error) []textfile.Metric {
+	labels := map[string]string{"selected_config": selectedConfig}
+	success := 0.0
+	if err == nil {
+		success = 1
+	}
+
+	return []textfile.Metric{
+		{Name: "mig_parted_apply_success", Help: "Whether the last apply succeeded.", Type: textfile.Gauge, Labels: labels, Value: success},
+		{Name: "mig_parted_apply_timestamp_seconds", Help: "Time of the last apply, in seconds since the epoch.", Type: textfile.Gauge, Labels: labels, Value: float64(start.Unix())},
+		{Name: "mig_parted_apply_duration_seconds", Help: "Duration of the last apply, in seconds.", Type: textfile.Gauge, Labels: labels, Value: now.Sub(start).Seconds()},
+	}
+}
+
+// writeApplyMetrics writes the metrics of an apply of 'selectedConfig' that
+// started at 'start' and returned 'err' to '--textfile-dir' if it is set.
+// Like the journal, errors writing them are logged rather than failing the
+// apply.
+func writeApplyMetrics(f *Flags, selectedConfig string, start time.Time, err error) {
+	if f.TextfileDir == "" {
+		return
+	}
+	werr := textfile.Write(filepath.Join(f.TextfileDir, TextfileName), applyMetrics(selectedConfig, start, time.Now(), err))
+	if werr != nil {
+		log.Warnf("Error writing apply metrics: %v", werr)
+	}
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+)
+
+// utilizationSampleInterval is how often utilization is sampled within the
+// window set by '--utilization-window'.
+var utilizationSampleInterval = time.Second
+
+// busyDevice is a GPU, or a MIG device on it, whose utilization exceeded
+// '--max-utilization' while it was sampled.
+type busyDevice struct {
+	GPU         int
+	MigDevice   string
+	Utilization uint32
+}
+
+func (b busyDevice) String() string {
+	if b.MigDevice == "" {
+		return fmt.Sprintf("GPU %d (%d%%)", b.GPU, b.Utilization)
+	}
+	return fmt.Sprintf("GPU %d MIG device %s (%d%%)", b.GPU, b.MigDevice, b.Utilization)
+}
+
+// checkUtilization refuses to go ahead while any of 'gpus', or any of the
+// MIG devices on them, is busier than '--max-utilization'. With
+// '--utilization-wait' it keeps sampling until they are idle enough or the
+// wait is over, giving up early if the apply is canceled.
+func (c *Context) checkUtilization(gpus []int) error {
+	if c.Flags.MaxUtilization < 0 || len(gpus) == 0 {
+		return nil
+	}
+
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	deadline := time.Now().Add(c.Flags.UtilizationWait)
+	for {
+		log.Debugf("Sampling utilization of GPU(s) %v for %v...", gpus, c.Flags.UtilizationWindow)
+		busy, err := sampleUtilization(c.Nvml, gpus, uint32(c.Flags.MaxUtilization), c.Flags.UtilizationWindow)
+		if err != nil {
+			return fmt.Errorf("error sampling utilization: %v", err)
+		}
+		if len(busy) == 0 {
+			return nil
+		}
+		if !time.Now().Before(deadline) {
+			return fmt.Errorf("utilization above %d%%: %v", c.Flags.MaxUtilization, joinBusyDevices(busy))
+		}
+		log.Warnf("Waiting for utilization to drop below %d%%: %v", c.Flags.MaxUtilization, joinBusyDevices(busy))
+		if err := c.canceled(); err != nil {
+			return err
+		}
+	}
+}
+
+// sampleUtilization samples the utilization of 'gpus' and of the MIG devices
+// on them every utilizationSampleInterval for 'window', and returns those
+// whose peak utilization exceeded 'threshold'. NVML reports no utilization
+// for a GPU in MIG mode, so only its MIG devices are checked. Nor does it for
+// MIG devices on most drivers; these count as fully utilized while they run
+// any compute process instead.
+func sampleUtilization(nvmlLib nvml.Interface, gpus []int, threshold uint32, window time.Duration) ([]busyDevice, error) {
+	peaks := make(map[busyDevice]uint32)
+	deadline := time.Now().Add(window)
+	for {
+		for _, gpu := range gpus {
+			err := sampleGPUUtilization(nvmlLib, gpu, peaks)
+			if err != nil {
+				return nil, fmt.Errorf("GPU %d: %v", gpu, err)
+			}
+		}
+		if !time.Now().Add(utilizationSampleInterval).Before(deadline) {
+			break
+		}
+		time.Sleep(utilizationSampleInterval)
+	}
+
+	var busy []busyDevice
+	for d, peak := range peaks {
+		if peak > threshold {
+			d.Utilization = peak
+			busy = append(busy, d)
+		}
+	}
+	sort.Slice(busy, func(i, j int) bool {
+		if busy[i].GPU != busy[j].GPU {
+			return busy[i].GPU < busy[j].GPU
+		}
+		return busy[i].MigDevice < busy[j].MigDevice
+	})
+	return busy, nil
+}
+
+// sampleGPUUtilization records the current utilization of 'gpu' and of the
+// MIG devices on it in 'peaks', keeping the highest seen for each.
+func sampleGPUUtilization(nvmlLib nvml.Interface, gpu int, peaks map[busyDevice]uint32) error {
+	device, ret := nvmlLib.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting device handle: %v", ret)
+	}
+
+	rates, ret := device.GetUtilizationRates()
+	switch ret {
+	case nvml.SUCCESS:
+		recordPeak(peaks, busyDevice{GPU: gpu}, rates.Gpu)
+	case nvml.ERROR_NOT_SUPPORTED:
+	default:
+		return fmt.Errorf("error getting utilization: %v", ret)
+	}
+
+	count, ret := device.GetMaxMigDeviceCount()
+	if ret == nvml.ERROR_NOT_SUPPORTED {
+		return nil
+	}
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting max MIG device count: %v", ret)
+	}
+	for i := 0; i < count; i++ {
+		mig, ret := device.GetMigDeviceHandleByIndex(i)
+		if ret == nvml.ERROR_NOT_FOUND || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting MIG device handle %d: %v", i, ret)
+		}
+		uuid, ret := mig.GetUUID()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting MIG device UUID: %v", ret)
+		}
+		utilization, err := migDeviceUtilization(mig)
+		if err != nil {
+			return fmt.Errorf("MIG device %s: %v", uuid, err)
+		}
+		recordPeak(peaks, busyDevice{GPU: gpu, MigDevice: uuid}, utilization)
+	}
+	return nil
+}
+
+// migDeviceUtilization returns the utilization of 'mig', or 100% if NVML
+// cannot report it and the MIG device runs a compute process.
+func migDeviceUtilization(mig nvml.Device) (uint32, error) {
+	rates, ret := mig.GetUtilizationRates()
+	if ret == nvml.SUCCESS {
+		return rates.Gpu, nil
+	}
+	if ret != nvml.ERROR_NOT_SUPPORTED {
+		return 0, fmt.Errorf("error getting utilization: %v", ret)
+	}
+	processes, ret := mig.GetComputeRunningProcesses()
+	if ret != nvml.SUCCESS {
+		return 0, fmt.Errorf("error getting running compute processes: %v", ret)
+	}
+	if len(processes) > 0 {
+		return 100, nil
+	}
+	return 0, nil
+}
+
+func recordPeak(peaks map[busyDevice]uint32, d busyDevice, utilization uint32) {
+	if utilization > peaks[d] {
+		peaks[d] = utilization
+	}
+}
+
+func joinBusyDevices(busy []busyDevice) string {
+	var s []string
+	for _, b := range busy {
+		s = append(s, b.String())
+	}
+	return strings.Join(s, ", ")
+}
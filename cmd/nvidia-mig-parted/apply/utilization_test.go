@@ -0,0 +1,172 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package apply
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/stretchr/testify/require"
+)
+
+// newUtilizationTestServer returns a mock server whose GPU 0 is not in MIG
+// mode and reports 'gpuUtilization' in turn on each sample, and whose GPU 1
+// is in MIG mode with one idle MIG device and one running a compute process.
+func newUtilizationTestServer(gpuUtilization ...uint32) *dgxa100.Server {
+	server := dgxa100.New()
+
+	gpu0 := server.Devices[0].(*dgxa100.Device)
+	samples := 0
+	gpu0.GetUtilizationRatesFunc = func() (nvml.Utilization, nvml.Return) {
+		u := gpuUtilization[min(samples, len(gpuUtilization)-1)]
+		samples++
+		return nvml.Utilization{Gpu: u}, nvml.SUCCESS
+	}
+	gpu0.GetMaxMigDeviceCountFunc = func() (int, nvml.Return) {
+		return 0, nvml.ERROR_NOT_SUPPORTED
+	}
+
+	migDevice := func(uuid string, processes int) nvml.Device {
+		return &mock.Device{
+			GetUUIDFunc: func() (string, nvml.Return) {
+				return uuid, nvml.SUCCESS
+			},
+			GetUtilizationRatesFunc: func() (nvml.Utilization, nvml.Return) {
+				return nvml.Utilization{}, nvml.ERROR_NOT_SUPPORTED
+			},
+			GetComputeRunningProcessesFunc: func() ([]nvml.ProcessInfo, nvml.Return) {
+				return make([]nvml.ProcessInfo, processes), nvml.SUCCESS
+			},
+		}
+	}
+	migDevices := []nvml.Device{migDevice("MIG-idle", 0), nil, migDevice("MIG-busy", 1)}
+
+	gpu1 := server.Devices[1].(*dgxa100.Device)
+	gpu1.GetUtilizationRatesFunc = func() (nvml.Utilization, nvml.Return) {
+		return nvml.Utilization{}, nvml.ERROR_NOT_SUPPORTED
+	}
+	gpu1.GetMaxMigDeviceCountFunc = func() (int, nvml.Return) {
+		return len(migDevices), nvml.SUCCESS
+	}
+	gpu1.GetMigDeviceHandleByIndexFunc = func(i int) (nvml.Device, nvml.Return) {
+		if migDevices[i] == nil {
+			return nil, nvml.ERROR_NOT_FOUND
+		}
+		return migDevices[i], nvml.SUCCESS
+	}
+
+	return server
+}
+
+func TestSampleUtilization(t *testing.T) {
+	utilizationSampleInterval = time.Millisecond
+	defer func() { utilizationSampleInterval = time.Second }()
+
+	testCases := []struct {
+		description    string
+		gpus           []int
+		gpuUtilization []uint32
+		threshold      uint32
+		expectedBusy   []busyDevice
+	}{
+		{
+			"Idle GPU",
+			[]int{0},
+			[]uint32{0},
+			10,
+			nil,
+		},
+		{
+			"GPU at the threshold",
+			[]int{0},
+			[]uint32{10},
+			10,
+			nil,
+		},
+		{
+			"GPU busy during part of the window",
+			[]int{0},
+			[]uint32{0, 80, 0},
+			10,
+			[]busyDevice{{GPU: 0, Utilization: 80}},
+		},
+		{
+			"MIG device running a compute process",
+			[]int{1},
+			[]uint32{0},
+			10,
+			[]busyDevice{{GPU: 1, MigDevice: "MIG-busy", Utilization: 100}},
+		},
+		{
+			"Busy GPU not sampled",
+			[]int{1},
+			[]uint32{80},
+			10,
+			[]busyDevice{{GPU: 1, MigDevice: "MIG-busy", Utilization: 100}},
+		},
+		{
+			"Several busy GPUs",
+			[]int{1, 0},
+			[]uint32{80},
+			10,
+			[]busyDevice{
+				{GPU: 0, Utilization: 80},
+				{GPU: 1, MigDevice: "MIG-busy", Utilization: 100},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			server := newUtilizationTestServer(tc.gpuUtilization...)
+			busy, err := sampleUtilization(server, tc.gpus, tc.threshold, 10*time.Millisecond)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedBusy, busy)
+		})
+	}
+}
+
+func TestCheckUtilization(t *testing.T) {
+	utilizationSampleInterval = time.Millisecond
+	defer func() { utilizationSampleInterval = time.Second }()
+
+	newContext := func(server nvml.Interface, wait time.Duration) *Context {
+		c := &Context{
+			Flags: &Flags{
+				MaxUtilization:    10,
+				UtilizationWindow: time.Millisecond,
+				UtilizationWait:   wait,
+			},
+		}
+		c.Nvml = server
+		return c
+	}
+
+	err := newContext(newUtilizationTestServer(80), 0).checkUtilization([]int{0})
+	require.NotNil(t, err, "Unexpected success while GPU busy")
+	require.Contains(t, err.Error(), "GPU 0 (80%)")
+
+	err = newContext(newUtilizationTestServer(80, 80, 0), time.Minute).checkUtilization([]int{0})
+	require.Nil(t, err, "Unexpected failure once GPU idle")
+
+	c := newContext(newUtilizationTestServer(80), 0)
+	c.Flags.MaxUtilization = -1
+	require.Nil(t, c.checkUtilization([]int{0}), "Unexpected failure with gate disabled")
+}
@@ -17,10 +17,10 @@
 package assert
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
@@ -29,6 +29,11 @@ import (
 
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/resolver"
+	"github.com/NVIDIA/mig-parted/pkg/signature"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 
 	"sigs.k8s.io/yaml"
@@ -41,18 +46,33 @@ func GetLogger() *logrus.Logger {
 }
 
 type Flags struct {
-	ConfigFile     string
-	SelectedConfig string
-	SkipReset      bool
-	ModeOnly       bool
-	ValidConfig    bool
+	ConfigFile         string
+	SelectedConfig     string
+	SelectedConfigFrom string
+	CIConfigFile       string
+	CISelectedConfig   string
+	TrustedKeys        string
+	ConfigSignature    string
+	StrictProfiles     bool
+	SkipReset          bool
+	ModeOnly           bool
+	ValidConfig        bool
+	PendingAsSatisfied bool
+	Full               bool
+	Readiness          ReadinessFlags
+	DiffExitCode       bool
+	DriftStateFile     string
+	ConfigChecksum     string
+	TextfileDir        string
 }
 
 type Context struct {
 	*cli.Context
-	Flags     *Flags
-	MigConfig v1.MigConfigSpecSlice
-	Nvml      nvml.Interface
+	Flags                 *Flags
+	MigConfig             v1.MigConfigSpecSlice
+	ComputeInstanceConfig v1.ComputeInstanceConfigSpecSlice
+	UnmanagedDevices      v1.UnmanagedDeviceSpecSlice
+	Nvml                  nvml.Interface
 }
 
 func BuildCommand() *cli.Command {
@@ -62,6 +82,7 @@ func BuildCommand() *cli.Command {
 	// Create the 'assert' command
 	assert := cli.Command{}
 	assert.Name = "assert"
+	assert.Aliases = []string{"check"}
 	assert.Usage = "Assert that a specific MIG configuration is currently applied to the node"
 	assert.Action = func(c *cli.Context) error {
 		return assertWrapper(c, &assertFlags)
@@ -69,26 +90,72 @@ func BuildCommand() *cli.Command {
 
 	// Setup the flags for this command
 	assert.Flags = []cli.Flag{
+		util.ConfigFileFlag(&assertFlags.ConfigFile, "Path to the configuration file, or a directory of spec fragments to merge ('-' for stdin)"),
+		util.SelectedConfigFlag(&assertFlags.SelectedConfig, "The label of the mig-config from the config file to assert is applied to the node"),
+		util.SelectedConfigFromFlag(&assertFlags.SelectedConfigFrom),
 		&cli.StringFlag{
-			Name:        "config-file",
-			Aliases:     []string{"f"},
-			Usage:       "Path to the configuration file",
-			Destination: &assertFlags.ConfigFile,
-			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+			Name:        "ci-config-file",
+			Usage:       "Path to a configuration file whose 'compute-instance-configs' split the GPU instances of the selected config",
+			Destination: &assertFlags.CIConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CI_CONFIG_FILE"},
 		},
 		&cli.StringFlag{
-			Name:        "selected-config",
-			Aliases:     []string{"c"},
-			Usage:       "The label of the mig-config from the config file to assert is applied to the node",
-			Destination: &assertFlags.SelectedConfig,
-			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+			Name:        "ci-selected-config",
+			Usage:       "The label of the compute-instance-config from the ci-config-file to assert is applied to the node",
+			Destination: &assertFlags.CISelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_CI_SELECTED_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "trusted-keys",
+			Usage:       "Path to a public key (or a directory of them) that config files must be signed with ('cosign' PEM or 'minisign' keys)",
+			Destination: &assertFlags.TrustedKeys,
+			EnvVars:     []string{"MIG_PARTED_TRUSTED_KEYS"},
+		},
+		&cli.StringFlag{
+			Name:        "config-signature",
+			Usage:       "Path to the detached signature of the config file (defaults to the config file path with '.sig' appended)",
+			Destination: &assertFlags.ConfigSignature,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_SIGNATURE"},
+		},
+		&cli.BoolFlag{
+			Name:        "strict-profiles",
+			Usage:       "Reject MIG profiles in config files that are not written in their canonical form (e.g. '1G.5GB' or '01g.5gb')",
+			Destination: &assertFlags.StrictProfiles,
+			EnvVars:     []string{"MIG_PARTED_STRICT_PROFILES"},
+		},
+		util.ModeOnlyFlag(&assertFlags.ModeOnly, "Only assert the MIG mode setting from the selected config, not the configured MIG devices"),
+		&cli.BoolFlag{
+			Name:        "pending-as-satisfied",
+			Usage:       "Treat a pending MIG mode change to the selected MIG mode (which takes effect after a reboot) as satisfying the assertion",
+			Destination: &assertFlags.PendingAsSatisfied,
+			EnvVars:     []string{"MIG_PARTED_PENDING_AS_SATISFIED"},
 		},
 		&cli.BoolFlag{
-			Name:        "mode-only",
-			Aliases:     []string{"m"},
-			Usage:       "Only assert the MIG mode setting from the selected config, not the configured MIG devices",
-			Destination: &assertFlags.ModeOnly,
-			EnvVars:     []string{"MIG_PARTED_MODE_CHANGE_ONLY"},
+			Name:        "full",
+			Usage:       "Also assert that the node is ready for workloads: no reboot is pending, no GPU instances lack compute instances, persistence mode matches the selected config, and the device nodes of all MIG devices exist",
+			Destination: &assertFlags.Full,
+			EnvVars:     []string{"MIG_PARTED_ASSERT_FULL"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Usage:       "Path to the marker file signaling that a reboot is required, checked with '--full'",
+			Destination: &assertFlags.Readiness.RebootMarkerFile,
+			Value:       reboot.DefaultMarkerFile,
+			EnvVars:     []string{"MIG_PARTED_REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "mig-minors-file",
+			Usage:       "Path to the file listing the minor numbers of the nvidia-caps device nodes, checked with '--full'",
+			Destination: &assertFlags.Readiness.MigMinorsFile,
+			Value:       migcaps.DefaultMigMinorsFile,
+			EnvVars:     []string{"MIG_PARTED_MIG_MINORS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "devices-file",
+			Usage:       "Path to the file listing the major numbers of all character devices, checked with '--full'",
+			Destination: &assertFlags.Readiness.DevicesFile,
+			Value:       migcaps.DefaultDevicesFile,
+			EnvVars:     []string{"MIG_PARTED_DEVICES_FILE"},
 		},
 		&cli.BoolFlag{
 			Name:        "valid-config",
@@ -97,6 +164,25 @@ func BuildCommand() *cli.Command {
 			Destination: &assertFlags.ValidConfig,
 			EnvVars:     []string{"MIG_PARTED_VALID_CONFIG"},
 		},
+		&cli.BoolFlag{
+			Name:        "diff-exit-code",
+			Usage:       fmt.Sprintf("Exit with %v (rather than 1) if the node has drifted from the selected configuration", ExitCodeDrift),
+			Destination: &assertFlags.DiffExitCode,
+			EnvVars:     []string{"MIG_PARTED_DIFF_EXIT_CODE"},
+		},
+		&cli.StringFlag{
+			Name:        "drift-state-file",
+			Usage:       fmt.Sprintf("Path to record whether the node has drifted from the selected configuration in, for 'status' and the metrics of 'daemon' (e.g. %v)", drift.DefaultStateFile),
+			Destination: &assertFlags.DriftStateFile,
+			EnvVars:     []string{"MIG_PARTED_DRIFT_STATE_FILE"},
+		},
+		util.TextfileDirFlag(&assertFlags.TextfileDir),
+		&cli.StringFlag{
+			Name:        "config-checksum",
+			Usage:       "Checksum of the selected config when it was applied (e.g. from the 'nvidia.com/mig.config.checksum' node label), counting any change to it since as drift",
+			Destination: &assertFlags.ConfigChecksum,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_CHECKSUM"},
+		},
 	}
 
 	return &assert
@@ -121,39 +207,117 @@ func assertWrapper(c *cli.Context, f *Flags) error {
 		return fmt.Errorf("error selecting MIG config: %v", err)
 	}
 
+	ciConfig, err := GetSelectedComputeInstanceConfig(f)
+	if err != nil {
+		return fmt.Errorf("error selecting compute instance config: %v", err)
+	}
+
 	if f.ValidConfig {
 		fmt.Println("Selected MIG configuration is valid")
 		return nil
 	}
 
 	context := Context{
-		Context:   c,
-		Flags:     f,
-		MigConfig: migConfig,
-		Nvml:      nvml.New(),
+		Context:               c,
+		Flags:                 f,
+		MigConfig:             migConfig,
+		ComputeInstanceConfig: ciConfig,
+		UnmanagedDevices:      spec.UnmanagedDevices,
+		Nvml:                  nvml.New(),
+	}
+
+	start := time.Now()
+	message, err := assertSelectedConfig(&context)
+	writeAssertMetrics(f, start, err)
+	rerr := recordDrift(f, err)
+	if rerr != nil {
+		return fmt.Errorf("error recording drift state: %v", rerr)
+	}
+	if err != nil {
+		return diffExitCode(f, err)
+	}
+
+	fmt.Println(message)
+	return nil
+}
+
+// assertSelectedConfig asserts that the selected config in 'c' is applied to
+// the node, returning the message to report if it is. If the node has drifted
+// from it, the error wraps a *DriftError.
+func assertSelectedConfig(c *Context) (string, error) {
+	f := c.Flags
+
+	if f.ConfigChecksum != "" {
+		log.Debugf("Asserting config checksum...")
+		err := assertChecksum(c)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.Debugf("Asserting version requirements...")
+	err := AssertRequirements(c)
+	if err != nil {
+		return "", fmt.Errorf("Assertion failure: node incompatible with selected configuration: %v", err)
 	}
 
 	log.Debugf("Asserting MIG mode configuration...")
-	err = AssertMigMode(&context)
+	statuses, err := GetMigModeStatus(c)
+	if err != nil {
+		log.Debug(util.Capitalize(err.Error()))
+		return "", &assertionFailure{"Assertion failure: selected configuration not currently applied", err}
+	}
+
+	for _, s := range statuses {
+		if s.Pending != s.Current {
+			fmt.Printf("GPU %v: MIG mode %v\n", s.GPU, s.State())
+		}
+	}
+
+	err = reportGPUConditions(c, statuses)
+	if err != nil {
+		log.Warnf("Error checking GPU conditions: %v", err)
+	}
+
+	err = checkMigModeStatus(statuses, f.PendingAsSatisfied)
 	if err != nil {
 		log.Debug(util.Capitalize(err.Error()))
-		return fmt.Errorf("Assertion failure: selected configuration not currently applied")
+		if checkMigModeStatus(statuses, true) == nil {
+			return "", &assertionFailure{"Assertion failure: selected MIG mode settings only applied after a reboot", err}
+		}
+		return "", &assertionFailure{"Assertion failure: selected configuration not currently applied", err}
 	}
 
+	// Only set if the assertion is satisfied by pending MIG mode changes.
+	rebootRequired := checkMigModeStatus(statuses, false) != nil
+
 	if f.ModeOnly {
-		fmt.Println("Selected MIG mode settings from configuration currently applied")
-		return nil
+		if rebootRequired {
+			return "Selected MIG mode settings from configuration applied after a reboot", nil
+		}
+		return "Selected MIG mode settings from configuration currently applied", nil
 	}
 
 	log.Debugf("Asserting MIG device configuration...")
-	err = AssertMigConfig(&context)
+	err = AssertMigConfig(c)
 	if err != nil {
 		log.Debug(util.Capitalize(err.Error()))
-		return fmt.Errorf("Assertion failure: selected configuration not currently applied")
+		return "", &assertionFailure{"Assertion failure: selected configuration not currently applied", err}
 	}
 
-	fmt.Println("Selected MIG configuration currently applied")
-	return nil
+	if f.Full {
+		log.Debugf("Asserting node readiness...")
+		err = AssertNodeReady(c, statuses)
+		if err != nil {
+			return "", fmt.Errorf("Assertion failure: node not ready: %v", err)
+		}
+		return "Selected MIG configuration currently applied and node ready", nil
+	}
+
+	if rebootRequired {
+		return "Selected MIG configuration applied after a reboot", nil
+	}
+	return "Selected MIG configuration currently applied", nil
 }
 
 func CheckFlags(f *Flags) error {
@@ -164,23 +328,54 @@ func CheckFlags(f *Flags) error {
 	if len(missing) > 0 {
 		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
 	}
+	if util.IsStdio(f.ConfigFile) && util.IsStdio(f.CIConfigFile) {
+		return fmt.Errorf("only one of 'config-file' and 'ci-config-file' can be read from stdin")
+	}
+	if f.SelectedConfigFrom != "" {
+		if f.SelectedConfig != "" {
+			return fmt.Errorf("'selected-config-from' cannot be combined with 'selected-config'")
+		}
+		_, err := resolver.New(f.SelectedConfigFrom)
+		if err != nil {
+			return fmt.Errorf("invalid 'selected-config-from': %v", err)
+		}
+	}
+	if f.CISelectedConfig != "" && f.CIConfigFile == "" {
+		return fmt.Errorf("'ci-selected-config' requires 'ci-config-file'")
+	}
+	if f.ConfigSignature != "" && f.TrustedKeys == "" {
+		return fmt.Errorf("'config-signature' requires 'trusted-keys'")
+	}
+	if util.IsStdio(f.ConfigFile) && f.TrustedKeys != "" && f.ConfigSignature == "" {
+		return fmt.Errorf("'config-signature' is required to verify a config file read from stdin")
+	}
+	if f.Full && (f.ModeOnly || f.ValidConfig || f.PendingAsSatisfied) {
+		return fmt.Errorf("'full' cannot be combined with 'mode-only', 'valid-config' or 'pending-as-satisfied'")
+	}
+	if f.ValidConfig && (f.DiffExitCode || f.DriftStateFile != "" || f.ConfigChecksum != "" || f.TextfileDir != "") {
+		return fmt.Errorf("'valid-config' cannot be combined with 'diff-exit-code', 'drift-state-file', 'config-checksum' or 'textfile-dir'")
+	}
 	return nil
 }
 
+// ParseConfigFile parses the config file referenced in 'f'. If 'f.TrustedKeys'
+// is set, the config file must carry a valid signature from one of the keys.
+// If the config file is a directory, the spec fragments in it are merged
+// instead (see parseConfigDir).
 func ParseConfigFile(f *Flags) (*v1.Spec, error) {
-	var err error
-	var configYaml []byte
+	if util.IsDir(f.ConfigFile) {
+		return parseConfigDir(f)
+	}
 
-	if f.ConfigFile == "-" {
-		scanner := bufio.NewScanner(os.Stdin)
-		for scanner.Scan() {
-			configYaml = append(configYaml, scanner.Bytes()...)
-			configYaml = append(configYaml, '\n')
-		}
-	} else {
-		configYaml, err = os.ReadFile(f.ConfigFile)
+	configYaml, err := util.ReadFile(f.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	if f.TrustedKeys != "" {
+		err := verifyConfigFile(f, configYaml)
 		if err != nil {
-			return nil, fmt.Errorf("read error: %v", err)
+			return nil, fmt.Errorf("error verifying signature of '%v': %v", f.ConfigFile, err)
 		}
 	}
 
@@ -190,10 +385,30 @@ func ParseConfigFile(f *Flags) (*v1.Spec, error) {
 		return nil, fmt.Errorf("unmarshal error: %v", err)
 	}
 
+	if f.StrictProfiles {
+		err := spec.AssertStrictProfiles()
+		if err != nil {
+			return nil, fmt.Errorf("strict profile check failed: %v", err)
+		}
+	}
+
 	return &spec, nil
 }
 
+// GetSelectedMigConfig returns the mig-config of 'spec' selected by 'f',
+// looking its label up from 'selected-config-from' first if set, and
+// recording it in 'f.SelectedConfig'.
 func GetSelectedMigConfig(f *Flags, spec *v1.Spec) (v1.MigConfigSpecSlice, error) {
+	if f.SelectedConfig == "" && f.SelectedConfigFrom != "" {
+		log.Debugf("Resolving selected config from '%v'...", f.SelectedConfigFrom)
+		selected, err := resolver.Resolve(context.Background(), f.SelectedConfigFrom)
+		if err != nil {
+			return nil, err
+		}
+		log.Debugf("Resolved selected config '%v'", selected)
+		f.SelectedConfig = selected
+	}
+
 	if len(spec.MigConfigs) > 1 && f.SelectedConfig == "" {
 		return nil, fmt.Errorf("missing required flag 'selected-config' when more than one config available")
 	}
@@ -211,20 +426,96 @@ func GetSelectedMigConfig(f *Flags, spec *v1.Spec) (v1.MigConfigSpecSlice, error
 	return spec.MigConfigs[f.SelectedConfig], nil
 }
 
-func WalkSelectedMigConfigForEachGPU(migConfig v1.MigConfigSpecSlice, f func(*v1.MigConfigSpec, int, types.DeviceID) error) error {
+// verifyConfigFile checks that 'configYaml', read from the config file
+// referenced in 'f', is signed with one of the keys in 'f.TrustedKeys'.
+func verifyConfigFile(f *Flags, configYaml []byte) error {
+	verifier, err := signature.LoadVerifier(f.TrustedKeys)
+	if err != nil {
+		return fmt.Errorf("error loading trusted keys: %v", err)
+	}
+
+	signaturePath := f.ConfigSignature
+	if signaturePath == "" {
+		if util.IsStdio(f.ConfigFile) {
+			return fmt.Errorf("no signature given for config file read from stdin")
+		}
+		signaturePath = f.ConfigFile + signature.DefaultSuffix
+	}
+
+	return verifier.VerifyFile(configYaml, signaturePath)
+}
+
+// GetSelectedComputeInstanceConfig parses the 'ci-config-file' referenced in 'f' (if any) and returns the
+// compute-instance-config selected from it. A nil slice is returned if no 'ci-config-file' is set.
+func GetSelectedComputeInstanceConfig(f *Flags) (v1.ComputeInstanceConfigSpecSlice, error) {
+	if f.CIConfigFile == "" {
+		return nil, nil
+	}
+
+	spec, err := ParseConfigFile(&Flags{ConfigFile: f.CIConfigFile, TrustedKeys: f.TrustedKeys, StrictProfiles: f.StrictProfiles})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing ci-config file: %v", err)
+	}
+
+	if len(spec.ComputeInstanceConfigs) > 1 && f.CISelectedConfig == "" {
+		return nil, fmt.Errorf("missing required flag 'ci-selected-config' when more than one compute-instance-config available")
+	}
+
+	if len(spec.ComputeInstanceConfigs) == 1 && f.CISelectedConfig == "" {
+		for c := range spec.ComputeInstanceConfigs {
+			f.CISelectedConfig = c
+		}
+	}
+
+	if _, exists := spec.ComputeInstanceConfigs[f.CISelectedConfig]; !exists {
+		return nil, fmt.Errorf("selected compute-instance-config not present: %v", f.CISelectedConfig)
+	}
+
+	return spec.ComputeInstanceConfigs[f.CISelectedConfig], nil
+}
+
+// WalkSelectedMigConfigForEachGPU calls 'f' for every GPU matched by each entry in 'migConfig'. GPUs selected by
+// 'unmanaged' are skipped, unless an entry lists them explicitly by index, in which case an error is returned.
+func WalkSelectedMigConfigForEachGPU(migConfig v1.MigConfigSpecSlice, unmanaged v1.UnmanagedDeviceSpecSlice, f func(*v1.MigConfigSpec, int, types.DeviceID) error) error {
+	return WalkSelectedMigConfigForEachGPUInOrder(migConfig, unmanaged, nil, f)
+}
+
+// WalkSelectedMigConfigForEachGPUInOrder is like WalkSelectedMigConfigForEachGPU, but visits the GPUs matched by
+// each entry in 'migConfig' in 'order' (a permutation of GPU indices) rather than in index order. A nil 'order'
+// visits them in index order.
+func WalkSelectedMigConfigForEachGPUInOrder(migConfig v1.MigConfigSpecSlice, unmanaged v1.UnmanagedDeviceSpecSlice, order []int, f func(*v1.MigConfigSpec, int, types.DeviceID) error) error {
 	deviceIDs, err := util.GetGPUDeviceIDs()
 	if err != nil {
 		return fmt.Errorf("Error enumerating GPU device IDs: %v", err)
 	}
 
+	if order == nil {
+		order = make([]int, len(deviceIDs))
+		for i := range order {
+			order[i] = i
+		}
+	}
+	if err := checkOrder(order, len(deviceIDs)); err != nil {
+		return fmt.Errorf("invalid device order: %v", err)
+	}
+
+	var uuids []string
 	for _, mc := range migConfig {
+		if mc.HasUUIDOverrides() && uuids == nil {
+			uuids, err = util.GetGPUUUIDs()
+			if err != nil {
+				return fmt.Errorf("Error enumerating GPU UUIDs: %v", err)
+			}
+		}
+
 		if mc.DeviceFilter == nil {
 			log.Debugf("Walking MigConfig for (devices=%v)", mc.Devices)
 		} else {
 			log.Debugf("Walking MigConfig for (device-filter=%v, devices=%v)", mc.DeviceFilter, mc.Devices)
 		}
 
-		for i, deviceID := range deviceIDs {
+		for _, i := range order {
+			deviceID := deviceIDs[i]
 			if !mc.MatchesDeviceFilter(deviceID) {
 				continue
 			}
@@ -233,9 +524,35 @@ func WalkSelectedMigConfigForEachGPU(migConfig v1.MigConfigSpecSlice, f func(*v1
 				continue
 			}
 
+			if unmanaged.Matches(i, deviceID) {
+				if mc.ExplicitlyMatchesDevice(i) {
+					return fmt.Errorf("selected config targets unmanaged GPU %v: %v", i, deviceID)
+				}
+				log.Debugf("  GPU %v: %v (unmanaged, skipping)", i, deviceID)
+				continue
+			}
+
 			log.Debugf("  GPU %v: %v", i, deviceID)
 
-			migConfigSpec := mc
+			uuid := ""
+			if i < len(uuids) {
+				uuid = uuids[i]
+			}
+
+			migConfigSpec := mc.ForDevice(i, uuid)
+			if migConfigSpec.MigEnabled && (migConfigSpec.HasProfileIDs() || migConfigSpec.HasFractionalProfiles()) {
+				ids, err := util.GetMigProfileIDs(i)
+				if err != nil {
+					return fmt.Errorf("error getting MIG profile IDs of GPU %v: %v", i, err)
+				}
+				if err := migConfigSpec.ResolveProfileIDs(ids); err != nil {
+					return fmt.Errorf("error resolving MIG profile IDs for GPU %v: %v", i, err)
+				}
+				if err := migConfigSpec.ResolveFractionalProfiles(ids.Profiles()); err != nil {
+					return fmt.Errorf("error resolving fractional MIG profiles for GPU %v: %v", i, err)
+				}
+			}
+
 			err = f(&migConfigSpec, i, deviceID)
 			if err != nil {
 				return err
@@ -245,3 +562,21 @@ func WalkSelectedMigConfigForEachGPU(migConfig v1.MigConfigSpecSlice, f func(*v1
 
 	return nil
 }
+
+// checkOrder checks that 'order' lists each of 'n' GPU indices exactly once.
+func checkOrder(order []int, n int) error {
+	if len(order) != n {
+		return fmt.Errorf("expected %v GPUs, got %v", n, len(order))
+	}
+	seen := make([]bool, n)
+	for _, i := range order {
+		if i < 0 || i >= n {
+			return fmt.Errorf("GPU %v out of range", i)
+		}
+		if seen[i] {
+			return fmt.Errorf("GPU %v listed more than once", i)
+		}
+		seen[i] = true
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+)
+
+func TestGetSelectedMigConfigFrom(t *testing.T) {
+	var spec v1.Spec
+	err := yaml.Unmarshal([]byte(`
+version: v1
+mig-configs:
+  all-disabled:
+  - devices: all
+    mig-enabled: false
+  all-1g.5gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.5gb": 7
+`), &spec)
+	require.Nil(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/all-1g.5gb":
+			fmt.Fprintln(w, "all-1g.5gb")
+		case "/bogus":
+			fmt.Fprintln(w, "bogus")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		description     string
+		flags           Flags
+		expected        string
+		expectedFailure bool
+	}{
+		{
+			"Resolved config",
+			Flags{SelectedConfigFrom: server.URL + "/all-1g.5gb"},
+			"all-1g.5gb",
+			false,
+		},
+		{
+			"Resolved config not in the spec",
+			Flags{SelectedConfigFrom: server.URL + "/bogus"},
+			"",
+			true,
+		},
+		{
+			"Unreachable source",
+			Flags{SelectedConfigFrom: server.URL + "/missing"},
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			migConfig, err := GetSelectedMigConfig(&tc.flags, &spec)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from GetSelectedMigConfig")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from GetSelectedMigConfig")
+			require.Equal(t, tc.expected, tc.flags.SelectedConfig)
+			require.Equal(t, spec.MigConfigs[tc.expected], migConfig)
+		})
+	}
+}
+
+func TestCheckFlagsSelectedConfigFrom(t *testing.T) {
+	f := Flags{ConfigFile: "config.yaml", SelectedConfigFrom: "ec2:mig-config"}
+	require.Nil(t, CheckFlags(&f))
+
+	f.SelectedConfig = "all-disabled"
+	require.NotNil(t, CheckFlags(&f), "Unexpected success combining 'selected-config' and 'selected-config-from'")
+
+	f = Flags{ConfigFile: "config.yaml", SelectedConfigFrom: "bogus:mig-config"}
+	require.NotNil(t, CheckFlags(&f), "Unexpected success with an unknown scheme")
+}
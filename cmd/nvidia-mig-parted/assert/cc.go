@@ -0,0 +1,91 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// AssertCCMode asserts that every GPU selected by an entry of the MIG config
+// in 'c' that declares a 'cc-mode' is in that CC mode. A GPU that is not CC
+// capable only satisfies the assertion for CC mode 'off'.
+func AssertCCMode(c *Context) error {
+	if !hasCCMode(c.MigConfig) {
+		return nil
+	}
+
+	err := util.CheckPrivilege(migcaps.PrivilegeAdmin)
+	if err != nil {
+		return err
+	}
+
+	manager, err := util.NewCCModeManager()
+	if err != nil {
+		return fmt.Errorf("error creating CC mode Manager: %v", err)
+	}
+
+	return WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if mc.CCMode == "" {
+			return nil
+		}
+		log.Debugf("    Asserting CC mode: %v", mc.CCMode)
+
+		if mc.MigEnabled && !mc.CCMode.SupportsMig() {
+			return fmt.Errorf("unable to assert MIG mode enabled with CC mode %v", mc.CCMode)
+		}
+
+		capable, err := manager.IsCCCapable(i)
+		if err != nil {
+			return fmt.Errorf("error checking CC capable: %v", err)
+		}
+		log.Debugf("    CC capable: %v", capable)
+
+		if !capable && mc.CCMode == types.CCOff {
+			return nil
+		}
+
+		if !capable {
+			return fmt.Errorf("unable to assert CC mode %v on non CC-capable GPU", mc.CCMode)
+		}
+
+		current, err := manager.GetCCMode(i)
+		if err != nil {
+			return fmt.Errorf("error getting CC mode: %v", err)
+		}
+		log.Debugf("    Current CC mode: %v", current)
+
+		if current != mc.CCMode {
+			return driftErrorf("GPU %v: current CC mode (%v) different than CC mode being asserted", i, current)
+		}
+		return nil
+	})
+}
+
+// hasCCMode checks if any entry of 'migConfig' declares a CC mode.
+func hasCCMode(migConfig v1.MigConfigSpecSlice) bool {
+	for _, mc := range migConfig {
+		if mc.CCMode != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -21,6 +21,8 @@ import (
 
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/inventory"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
@@ -38,7 +40,18 @@ func AssertMigConfig(c *Context) error {
 	}
 
 	matched := make([]bool, len(deviceIDs))
-	err = WalkSelectedMigConfigForEachGPU(c.MigConfig, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+	var managed []int
+	for i, deviceID := range deviceIDs {
+		matched[i] = c.UnmanagedDevices.Matches(i, deviceID)
+		if !matched[i] {
+			managed = append(managed, i)
+		}
+	}
+
+	// The current MIG config of all managed GPUs is scanned in parallel the
+	// first time one is needed, rather than one GPU after the other.
+	var inv *inventory.Inventory
+	err = WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
 		modeManager, err := util.NewMigModeManager()
 		if err != nil {
 			return fmt.Errorf("error creating MIG Mode Manager: %v", err)
@@ -64,24 +77,58 @@ func AssertMigConfig(c *Context) error {
 			return nil
 		}
 
+		if c.Flags.PendingAsSatisfied {
+			pending, err := mode.GetPendingMigMode(modeManager, i)
+			if err != nil {
+				return fmt.Errorf("error getting pending MIG mode: %v", err)
+			}
+			// Once a pending MIG mode change takes effect, the GPU has no
+			// MIG devices, so only a config without any is satisfied by it.
+			if pending != m {
+				if mc.MigEnabled {
+					matched[i] = pending == mode.Enabled && mc.EnablesMigOnly()
+				} else {
+					matched[i] = pending == mode.Disabled
+				}
+				return nil
+			}
+		}
+
+		err = util.CheckPrivilege(migcaps.PrivilegeMonitor)
+		if err != nil {
+			return err
+		}
+
 		configManager, err := util.NewMigConfigManager()
 		if err != nil {
 			return fmt.Errorf("error creating MIG Config Manager: %v", err)
 		}
 
-		current, err := configManager.GetMigConfig(i)
+		if inv == nil {
+			inv = inventory.Collect(configManager, managed, inventory.DefaultWorkers)
+		}
+
+		current, err := inv.MigConfig(i)
 		if err != nil {
 			return fmt.Errorf("error getting MIGConfig: %v", err)
 		}
 
-		log.Debugf("    Asserting MIG config: %v", mc.MigDevices)
-
-		if current.Equals(mc.MigDevices) {
-			matched[i] = true
-			return nil
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
 		}
 
-		matched[i] = false
+		desired = c.ComputeInstanceConfig.Select(i, d).Apply(desired)
+
+		log.Debugf("    Asserting MIG config: %v", desired)
+
+		matched[i], err = util.MigConfigMatches(i, current, desired)
+		if err != nil {
+			return fmt.Errorf("error comparing MIGConfig: %v", err)
+		}
 		return nil
 	})
 
@@ -90,7 +137,7 @@ func AssertMigConfig(c *Context) error {
 	}
 
 	if util.CountTrue(matched) != len(deviceIDs) {
-		return fmt.Errorf("not all GPUs match the specified config")
+		return driftErrorf("not all GPUs match the specified config")
 	}
 
 	return nil
@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"path/filepath"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+)
+
+// parseConfigDir parses each spec fragment in the config directory referenced
+// in 'f' (e.g. /etc/nvidia-mig-manager/config.d) and merges them in the
+// lexical order of their names, so that each provisioning layer (base image,
+// site, team, ...) can own a fragment. A config defined in more than one
+// fragment is taken from the last one. If 'f.TrustedKeys' is set, each
+// fragment must carry its own signature.
+func parseConfigDir(f *Flags) (*v1.Spec, error) {
+	if f.ConfigSignature != "" {
+		return nil, fmt.Errorf("'config-signature' cannot be used with a config directory: each fragment must be signed separately")
+	}
+
+	paths, err := util.ConfigFragmentPaths(f.ConfigFile)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	var fragments []*v1.Spec
+	owners := make(map[string]string)
+	for _, path := range paths {
+		if util.IsDir(path) {
+			return nil, fmt.Errorf("fragment '%v' is a directory", filepath.Base(path))
+		}
+		fragmentFlags := *f
+		fragmentFlags.ConfigFile = path
+		fragment, err := ParseConfigFile(&fragmentFlags)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing fragment '%v': %v", filepath.Base(path), err)
+		}
+
+		for name := range fragment.MigConfigs {
+			if owner, exists := owners[name]; exists {
+				log.Debugf("Config '%v' from '%v' replaces the one from '%v'", name, filepath.Base(path), owner)
+			}
+			owners[name] = filepath.Base(path)
+		}
+		fragments = append(fragments, fragment)
+	}
+
+	return v1.Merge(fragments...), nil
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConfigDir(t *testing.T) {
+	dir := t.TempDir()
+	fragments := map[string]string{
+		"10-base.yaml": `version: v1
+mig-configs:
+  all-disabled:
+    - devices: all
+      mig-enabled: false
+  all-enabled:
+    - devices: all
+      mig-enabled: true
+      mig-devices: {}
+`,
+		"20-site.yml": `version: v1
+unmanaged-devices:
+  - devices: [0]
+`,
+		"30-team.yaml": `version: v1
+mig-configs:
+  all-enabled:
+    - devices: [1, 2]
+      mig-enabled: true
+      mig-devices: {}
+`,
+		"README":            "not a fragment",
+		".30-team.yaml.swp": "not a fragment either",
+	}
+	for name, content := range fragments {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	spec, err := ParseConfigFile(&Flags{ConfigFile: dir})
+	require.Nil(t, err, "Unexpected failure from ParseConfigFile")
+
+	require.Len(t, spec.MigConfigs, 2)
+	require.Len(t, spec.UnmanagedDevices, 1)
+	require.Equal(t, []int{1, 2}, spec.MigConfigs["all-enabled"][0].Devices, "Later fragment should replace config")
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "40-broken.yaml"), []byte("version: v2\n"), 0644))
+	_, err = ParseConfigFile(&Flags{ConfigFile: dir})
+	require.ErrorContains(t, err, "40-broken.yaml")
+
+	_, err = ParseConfigFile(&Flags{ConfigFile: t.TempDir()})
+	require.NotNil(t, err, "Unexpected success from ParseConfigFile on empty directory")
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2021, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+)
+
+// ExitCodeDrift is the exit code of 'assert --diff-exit-code' when the node
+// has drifted from the selected config. Any other failure exits with 1.
+const ExitCodeDrift = 2
+
+// DriftError is returned when the node does not match the selected config,
+// as opposed to when it cannot be checked against it.
+type DriftError struct {
+	Reason string
+}
+
+var _ error = (*DriftError)(nil)
+
+func (e *DriftError) Error() string {
+	return e.Reason
+}
+
+func driftErrorf(format string, a ...interface{}) error {
+	return &DriftError{Reason: fmt.Sprintf(format, a...)}
+}
+
+// IsDrift returns whether 'err' shows that the node has drifted from the
+// selected config.
+func IsDrift(err error) bool {
+	var d *DriftError
+	return errors.As(err, &d)
+}
+
+// assertionFailure is reported to the user as 'message', while the error it
+// wraps (only logged at debug level) tells why the assertion failed.
+type assertionFailure struct {
+	message string
+	err     error
+}
+
+func (e *assertionFailure) Error() string {
+	return e.message
+}
+
+func (e *assertionFailure) Unwrap() error {
+	return e.err
+}
+
+// recordDrift writes the outcome of asserting the selected config, 'err',
+// to '--drift-state-file' if it is set. Failures other than drift leave the
+// state file untouched, as they tell nothing about whether the node drifted.
+func recordDrift(f *Flags, err error) error {
+	if f.DriftStateFile == "" || (err != nil && !IsDrift(err)) {
+		return nil
+	}
+
+	state := &drift.State{
+		Result:         drift.ResultNone,
+		ConfigFile:     f.ConfigFile,
+		SelectedConfig: f.SelectedConfig,
+		Timestamp:      time.Now().UTC(),
+	}
+	var d *DriftError
+	if errors.As(err, &d) {
+		state.Result = drift.ResultDetected
+		state.Reason = d.Reason
+	}
+	return drift.Write(f.DriftStateFile, state)
+}
+
+// diffExitCode returns 'err' as is, unless '--diff-exit-code' is set and it
+// shows drift, in which case the error makes 'assert' exit with
+// ExitCodeDrift.
+func diffExitCode(f *Flags, err error) error {
+	if !f.DiffExitCode || !IsDrift(err) {
+		return err
+	}
+	return cli.Exit(err.Error(), ExitCodeDrift)
+}
+
+// assertChecksum asserts that the selected config in 'c' still has the
+// checksum it had when it was applied, '--config-checksum'. A config edited
+// since (e.g. in a ConfigMap) under the same name counts as drift, even if
+// the node happens to match its new contents.
+func assertChecksum(c *Context) error {
+	checksum, err := c.MigConfig.Checksum()
+	if err != nil {
+		return fmt.Errorf("error computing checksum of selected configuration: %v", err)
+	}
+	if checksum != c.Flags.ConfigChecksum {
+		return driftErrorf("Assertion failure: selected configuration changed since it was applied (checksum %v, applied %v)", checksum, c.Flags.ConfigChecksum)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright (c) 2021, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestRecordDrift(t *testing.T) {
+	f := &Flags{
+		ConfigFile:     "config.yaml",
+		SelectedConfig: "all-1g.10gb",
+		DriftStateFile: filepath.Join(t.TempDir(), "drift.json"),
+	}
+
+	err := recordDrift(f, fmt.Errorf("error initializing NVML"))
+	require.Nil(t, err, "Unexpected failure from recordDrift")
+	state, err := drift.Read(f.DriftStateFile)
+	require.Nil(t, err)
+	require.Nil(t, state, "Unexpected drift state recorded for a hard error")
+
+	drifted := &assertionFailure{"Assertion failure: selected configuration not currently applied", driftErrorf("not all GPUs match the specified config")}
+	err = recordDrift(f, drifted)
+	require.Nil(t, err, "Unexpected failure from recordDrift")
+	state, err = drift.Read(f.DriftStateFile)
+	require.Nil(t, err)
+	require.Equal(t, drift.ResultDetected, state.Result)
+	require.Equal(t, "not all GPUs match the specified config", state.Reason)
+	require.Equal(t, "all-1g.10gb", state.SelectedConfig)
+
+	err = recordDrift(f, nil)
+	require.Nil(t, err, "Unexpected failure from recordDrift")
+	state, err = drift.Read(f.DriftStateFile)
+	require.Nil(t, err)
+	require.Equal(t, drift.ResultNone, state.Result)
+	require.Empty(t, state.Reason)
+}
+
+func TestDiffExitCode(t *testing.T) {
+	drifted := &assertionFailure{"Assertion failure: selected configuration not currently applied", driftErrorf("GPU 0: current mode different than mode being asserted")}
+	failed := fmt.Errorf("Assertion failure: node incompatible with selected configuration")
+
+	require.Equal(t, drifted, diffExitCode(&Flags{}, drifted))
+	require.Equal(t, failed, diffExitCode(&Flags{DiffExitCode: true}, failed))
+
+	err := diffExitCode(&Flags{DiffExitCode: true}, drifted)
+	exitCoder, ok := err.(cli.ExitCoder)
+	require.True(t, ok, "Expected an exit code for drift")
+	require.Equal(t, ExitCodeDrift, exitCoder.ExitCode())
+	require.Equal(t, drifted.Error(), exitCoder.Error())
+}
+
+func TestAssertChecksum(t *testing.T) {
+	migConfig := v1.MigConfigSpecSlice{
+		{
+			Devices:    "all",
+			MigEnabled: true,
+			MigDevices: types.MigConfig{"1g.5gb": 7},
+		},
+	}
+	checksum, err := migConfig.Checksum()
+	require.Nil(t, err)
+
+	c := &Context{
+		Flags:     &Flags{ConfigChecksum: checksum},
+		MigConfig: migConfig,
+	}
+	require.Nil(t, assertChecksum(c), "Unexpected failure for unchanged config")
+
+	c.MigConfig = v1.MigConfigSpecSlice{
+		{
+			Devices:    "all",
+			MigEnabled: true,
+			MigDevices: types.MigConfig{"1g.5gb": 4},
+		},
+	}
+	err = assertChecksum(c)
+	require.NotNil(t, err, "Unexpected success for changed config")
+	require.True(t, IsDrift(err), "Expected a changed config to count as drift")
+}
@@ -0,0 +1,284 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ReadinessFlags holds the flags used by 'assert --full' to check that a node
+// is ready for workloads, beyond the selected MIG configuration being applied.
+type ReadinessFlags struct {
+	RebootMarkerFile string
+	MigMinorsFile    string
+	DevicesFile      string
+}
+
+// AssertNodeReady checks that the node is ready for workloads once the
+// selected MIG configuration is applied: no reboot is pending, no GPU is
+// pending a drain or a reset, no GPU instance is left without compute
+// instances, persistence mode matches the
+// selected config, and the device nodes of every MIG device exist. All
+// failed checks are reported together.
+func AssertNodeReady(c *Context, statuses []MigModeStatus) error {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	var failures []string
+	for _, check := range []func() error{
+		func() error { return assertNoRebootPending(c, statuses) },
+		func() error { return assertNoGPUConditions(c, statuses) },
+		func() error { return assertNoOrphanedGpuInstances(c) },
+		func() error { return assertPersistenceMode(c) },
+		func() error { return assertDeviceNodes(c) },
+	} {
+		err := check()
+		if err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func assertNoRebootPending(c *Context, statuses []MigModeStatus) error {
+	log.Debugf("Asserting no reboot is pending...")
+	var pending []int
+	for _, s := range statuses {
+		if s.Pending != s.Current {
+			pending = append(pending, s.GPU)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("reboot pending for MIG mode change on GPU(s) %v", pending)
+	}
+
+	marker, err := reboot.Read(c.Flags.Readiness.RebootMarkerFile)
+	if err != nil {
+		return err
+	}
+	if marker != nil {
+		return fmt.Errorf("reboot pending (%v)", marker.Reason)
+	}
+	return nil
+}
+
+func assertNoGPUConditions(c *Context, statuses []MigModeStatus) error {
+	log.Debugf("Asserting no GPU is pending a drain or a reset...")
+	conditions, err := util.GetGPUConditions(c.Nvml, statusGPUs(statuses))
+	if err != nil {
+		return fmt.Errorf("error checking GPU conditions: %v", err)
+	}
+	if len(conditions) > 0 {
+		return fmt.Errorf("%v", util.JoinGPUConditions(conditions))
+	}
+	return nil
+}
+
+func assertNoOrphanedGpuInstances(c *Context) error {
+	log.Debugf("Asserting no GPU instances without compute instances...")
+	instanceManager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	var orphaned []string
+	err = walkMigEnabledGPUs(c, func(mc *v1.MigConfigSpec, i int) error {
+		gis, err := instanceManager.ListGpuInstances(i)
+		if err != nil {
+			return fmt.Errorf("error listing GPU instances on GPU %v: %v", i, err)
+		}
+		cis, err := instanceManager.ListComputeInstances(i)
+		if err != nil {
+			return fmt.Errorf("error listing compute instances on GPU %v: %v", i, err)
+		}
+		for _, gi := range findOrphanedGpuInstances(gis, cis) {
+			orphaned = append(orphaned, fmt.Sprintf("GPU %v: %v (id %v)", i, gi.Profile, gi.ID))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(orphaned) > 0 {
+		return fmt.Errorf("GPU instances without compute instances: %v", strings.Join(orphaned, ", "))
+	}
+	return nil
+}
+
+func assertPersistenceMode(c *Context) error {
+	log.Debugf("Asserting persistence mode...")
+	var mismatched []int
+	err := WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if mc.PersistenceMode == nil {
+			return nil
+		}
+		device, ret := c.Nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for GPU %v: %v", i, ret)
+		}
+		current, ret := device.GetPersistenceMode()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting persistence mode for GPU %v: %v", i, ret)
+		}
+		if (current == nvml.FEATURE_ENABLED) != *mc.PersistenceMode {
+			mismatched = append(mismatched, i)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("persistence mode different than selected config on GPU(s) %v", mismatched)
+	}
+	return nil
+}
+
+func assertDeviceNodes(c *Context) error {
+	log.Debugf("Asserting device nodes exist for all MIG devices...")
+	minors, err := migcaps.ReadMigMinors(c.Flags.Readiness.MigMinorsFile)
+	if err != nil {
+		return fmt.Errorf("error reading MIG minors: %v", err)
+	}
+	capsMajor, err := migcaps.ReadMajor(c.Flags.Readiness.DevicesFile, migcaps.CapsDevicesName)
+	if err != nil {
+		return fmt.Errorf("error reading major number of '%v': %v", migcaps.CapsDevicesName, err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	var all []migcaps.MigDeviceCaps
+	err = walkMigEnabledGPUs(c, func(mc *v1.MigConfigSpec, i int) error {
+		device, ret := c.Nvml.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for GPU %v: %v", i, ret)
+		}
+		gpuMinor, ret := device.GetMinorNumber()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting minor number for GPU %v: %v", i, ret)
+		}
+		devices, err := configManager.GetMigDevices(i)
+		if err != nil {
+			return fmt.Errorf("error getting MIG devices for GPU %v: %v", i, err)
+		}
+		for _, d := range devices {
+			caps, err := migcaps.Resolve(minors, capsMajor, i, gpuMinor, d)
+			if err != nil {
+				return fmt.Errorf("error resolving device nodes for GPU %v: %v", i, err)
+			}
+			all = append(all, *caps)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	missing := missingDeviceNodes(all, func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	})
+	if len(missing) > 0 {
+		return fmt.Errorf("missing device nodes: %v", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// walkMigEnabledGPUs calls 'f' for every GPU selected by the config in 'c'
+// that currently has MIG mode enabled.
+func walkMigEnabledGPUs(c *Context, f func(*v1.MigConfigSpec, int) error) error {
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	return WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return fmt.Errorf("error checking MIG capable: %v", err)
+		}
+		if !capable {
+			return nil
+		}
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return fmt.Errorf("error getting MIG mode: %v", err)
+		}
+		if m != mode.Enabled {
+			return nil
+		}
+		return f(mc, i)
+	})
+}
+
+// findOrphanedGpuInstances returns the GPU instances in 'gis' that none of
+// the compute instances in 'cis' belong to.
+func findOrphanedGpuInstances(gis []types.GpuInstance, cis []types.MigDevice) []types.GpuInstance {
+	used := make(map[uint32]bool)
+	for _, ci := range cis {
+		used[ci.GpuInstanceID] = true
+	}
+
+	var orphaned []types.GpuInstance
+	for _, gi := range gis {
+		if !used[gi.ID] {
+			orphaned = append(orphaned, gi)
+		}
+	}
+	return orphaned
+}
+
+// missingDeviceNodes returns the (sorted, unique) paths of the device nodes
+// in 'all' for which 'exists' returns false.
+func missingDeviceNodes(all []migcaps.MigDeviceCaps, exists func(string) bool) []string {
+	seen := make(map[string]bool)
+	var missing []string
+	for _, c := range all {
+		for _, node := range c.DeviceNodes {
+			if seen[node.Path] {
+				continue
+			}
+			seen[node.Path] = true
+			if !exists(node.Path) {
+				missing = append(missing, node.Path)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
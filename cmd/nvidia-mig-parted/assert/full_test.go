@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestAssertNoRebootPending(t *testing.T) {
+	marker := filepath.Join(t.TempDir(), "reboot-required")
+	c := &Context{Flags: &Flags{Readiness: ReadinessFlags{RebootMarkerFile: marker}}}
+
+	enabled := []MigModeStatus{{GPU: 0, Current: mode.Enabled, Pending: mode.Enabled, Desired: mode.Enabled}}
+	require.Nil(t, assertNoRebootPending(c, enabled))
+
+	pending := []MigModeStatus{{GPU: 1, Current: mode.Enabled, Pending: mode.Disabled, Desired: mode.Enabled}}
+	require.ErrorContains(t, assertNoRebootPending(c, pending), "GPU(s) [1]")
+
+	require.Nil(t, reboot.Write(marker, &reboot.Marker{Reason: reboot.ReasonResetSkipped}))
+	require.ErrorContains(t, assertNoRebootPending(c, enabled), reboot.ReasonResetSkipped)
+}
+
+func TestFindOrphanedGpuInstances(t *testing.T) {
+	gis := []types.GpuInstance{
+		{Profile: "3g.20gb", ID: 1},
+		{Profile: "2g.10gb", ID: 5},
+		{Profile: "1g.5gb", ID: 13},
+	}
+	cis := []types.MigDevice{
+		{Profile: "1c.3g.20gb", GpuInstanceID: 1, ComputeInstanceID: 0},
+		{Profile: "2c.3g.20gb", GpuInstanceID: 1, ComputeInstanceID: 1},
+		{Profile: "1g.5gb", GpuInstanceID: 13, ComputeInstanceID: 0},
+	}
+
+	require.Equal(t, []types.GpuInstance{{Profile: "2g.10gb", ID: 5}}, findOrphanedGpuInstances(gis, cis))
+	require.Empty(t, findOrphanedGpuInstances(gis[:1], cis))
+}
+
+func TestMissingDeviceNodes(t *testing.T) {
+	all := []migcaps.MigDeviceCaps{
+		{DeviceNodes: []migcaps.DeviceNode{{Path: "/dev/nvidiactl"}, {Path: "/dev/nvidia0"}, {Path: "/dev/nvidia-caps/nvidia-cap21"}, {Path: "/dev/nvidia-caps/nvidia-cap22"}}},
+		{DeviceNodes: []migcaps.DeviceNode{{Path: "/dev/nvidiactl"}, {Path: "/dev/nvidia0"}, {Path: "/dev/nvidia-caps/nvidia-cap30"}, {Path: "/dev/nvidia-caps/nvidia-cap31"}}},
+	}
+	existing := map[string]bool{
+		"/dev/nvidiactl":                true,
+		"/dev/nvidia0":                  true,
+		"/dev/nvidia-caps/nvidia-cap21": true,
+		"/dev/nvidia-caps/nvidia-cap30": true,
+	}
+
+	missing := missingDeviceNodes(all, func(path string) bool { return existing[path] })
+	require.Equal(t, []string{"/dev/nvidia-caps/nvidia-cap22", "/dev/nvidia-caps/nvidia-cap31"}, missing)
+}
@@ -18,6 +18,7 @@ package assert
 
 import (
 	"fmt"
+	"strings"
 
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
@@ -25,26 +26,58 @@ import (
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
-func AssertMigMode(c *Context) error {
+// MigModeStatus holds the current and pending MIG mode of a single MIG
+// capable GPU, along with the MIG mode being asserted for it.
+type MigModeStatus struct {
+	GPU     int
+	Current mode.MigMode
+	Pending mode.MigMode
+	Desired mode.MigMode
+}
+
+// State describes the MIG mode of the GPU, e.g. 'enabled', or
+// 'enabled-pending-reboot' if MIG mode will only be enabled after a reboot.
+func (s *MigModeStatus) State() string {
+	if s.Pending != s.Current {
+		return strings.ToLower(s.Pending.String()) + "-pending-reboot"
+	}
+	return strings.ToLower(s.Current.String())
+}
+
+// Satisfied returns whether the GPU is in the desired MIG mode. If
+// 'pendingAsSatisfied' is set, a pending change to the desired MIG mode
+// counts as being in it.
+func (s *MigModeStatus) Satisfied(pendingAsSatisfied bool) bool {
+	if s.Current == s.Desired {
+		return true
+	}
+	return pendingAsSatisfied && s.Pending == s.Desired
+}
+
+// GetMigModeStatus returns the 'MigModeStatus' of each MIG capable GPU
+// selected by the MIG config in 'c'. GPUs that are not MIG capable are
+// skipped, unless MIG mode is asserted as enabled on them.
+func GetMigModeStatus(c *Context) ([]MigModeStatus, error) {
 	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
 	if err != nil {
-		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
+		return nil, fmt.Errorf("error checking if nvidia module loaded: %v", err)
 	}
 
 	if nvidiaModuleLoaded {
 		err := util.NvmlInit(c.Nvml)
 		if err != nil {
-			return fmt.Errorf("error initializing NVML: %v", err)
+			return nil, fmt.Errorf("error initializing NVML: %v", err)
 		}
 		defer util.TryNvmlShutdown(c.Nvml)
 	}
 
-	return WalkSelectedMigConfigForEachGPU(c.MigConfig, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+	var statuses []MigModeStatus
+	err = WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		desired := mode.Disabled
 		if mc.MigEnabled {
-			log.Debugf("    Asserting MIG mode: %v", mode.Enabled)
-		} else {
-			log.Debugf("    Asserting MIG mode: %v", mode.Disabled)
+			desired = mode.Enabled
 		}
+		log.Debugf("    Asserting MIG mode: %v", desired)
 
 		manager, err := util.NewMigModeManager()
 		if err != nil {
@@ -65,19 +98,97 @@ func AssertMigMode(c *Context) error {
 			return nil
 		}
 
-		m, err := manager.GetMigMode(i)
+		current, err := manager.GetMigMode(i)
 		if err != nil {
 			return fmt.Errorf("error getting MIG mode: %v", err)
 		}
-		log.Debugf("    Current MIG mode: %v", m)
+		log.Debugf("    Current MIG mode: %v", current)
 
-		if mc.MigEnabled && m == mode.Disabled {
-			return fmt.Errorf("current mode different than mode being asserted")
-		}
-		if !mc.MigEnabled && m == mode.Enabled {
-			return fmt.Errorf("current mode different than mode being asserted")
+		pending, err := mode.GetPendingMigMode(manager, i)
+		if err != nil {
+			return fmt.Errorf("error getting pending MIG mode: %v", err)
 		}
+		log.Debugf("    Pending MIG mode: %v", pending)
 
+		statuses = append(statuses, MigModeStatus{
+			GPU:     i,
+			Current: current,
+			Pending: pending,
+			Desired: desired,
+		})
 		return nil
 	})
+	if err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
+
+// AssertMigMode asserts that every GPU selected by the MIG config in 'c' is
+// in the desired MIG mode. A pending change to the desired MIG mode only
+// satisfies the assertion if 'c.Flags.PendingAsSatisfied' is set. The CC
+// mode declared by the MIG config is asserted first, as it gates MIG mode.
+func AssertMigMode(c *Context) error {
+	err := AssertCCMode(c)
+	if err != nil {
+		return err
+	}
+
+	statuses, err := GetMigModeStatus(c)
+	if err != nil {
+		return err
+	}
+	return checkMigModeStatus(statuses, c.Flags.PendingAsSatisfied)
+}
+
+func checkMigModeStatus(statuses []MigModeStatus, pendingAsSatisfied bool) error {
+	for _, s := range statuses {
+		if s.Satisfied(pendingAsSatisfied) {
+			continue
+		}
+		if s.Pending == s.Desired {
+			return driftErrorf("GPU %v: current mode different than mode being asserted (%v)", s.GPU, s.State())
+		}
+		return driftErrorf("GPU %v: current mode different than mode being asserted", s.GPU)
+	}
+	return nil
+}
+
+// statusGPUs returns the indices of the GPUs in 'statuses'.
+func statusGPUs(statuses []MigModeStatus) []int {
+	var gpus []int
+	for _, s := range statuses {
+		gpus = append(gpus, s.GPU)
+	}
+	return gpus
+}
+
+// reportGPUConditions prints the conditions of the GPUs in 'statuses' that
+// keep them from being reconfigured (see util.GPUCondition), so that they
+// are told apart from the config not being applied. Without the nvidia
+// module loaded, there is no NVML to query them with.
+func reportGPUConditions(c *Context, statuses []MigModeStatus) error {
+	nvidiaModuleLoaded, err := util.IsNvidiaModuleLoaded()
+	if err != nil {
+		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
+	}
+	if !nvidiaModuleLoaded {
+		return nil
+	}
+
+	err = util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	conditions, err := util.GetGPUConditions(c.Nvml, statusGPUs(statuses))
+	if err != nil {
+		return err
+	}
+	for _, cond := range conditions {
+		fmt.Println(cond)
+	}
+	return nil
 }
@@ -0,0 +1,112 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// AssertRequirements checks that every GPU selected by the config in 'c'
+// meets the driver, CUDA and VBIOS versions its entry 'requires'. All GPUs
+// that do not are reported together.
+func AssertRequirements(c *Context) error {
+	if !hasRequirements(c.MigConfig) {
+		return nil
+	}
+
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	installed := getSystemVersions(c.Nvml)
+
+	var failures []string
+	err = WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if mc.Requires == nil {
+			return nil
+		}
+		installed := installed
+		if mc.Requires.VBIOS != "" {
+			device, ret := c.Nvml.DeviceGetHandleByIndex(i)
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting device handle for GPU %v: %v", i, ret)
+			}
+			installed.VBIOS, ret = device.GetVbiosVersion()
+			if ret != nvml.SUCCESS {
+				log.Debugf("Unable to get VBIOS version for GPU %v: %v", i, ret)
+			}
+		}
+		err := mc.Requires.Check(installed)
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("GPU %v: %v", i, err))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// hasRequirements checks if any entry of 'migConfig' requires minimum versions.
+func hasRequirements(migConfig v1.MigConfigSpecSlice) bool {
+	for _, mc := range migConfig {
+		if mc.Requires != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// getSystemVersions returns the driver and CUDA versions of the node. A
+// version NVML cannot report is left empty, so that requiring it fails.
+func getSystemVersions(nvmlLib nvml.Interface) v1.InstalledVersions {
+	var installed v1.InstalledVersions
+
+	var ret nvml.Return
+	installed.Driver, ret = nvmlLib.SystemGetDriverVersion()
+	if ret != nvml.SUCCESS {
+		log.Debugf("Unable to get driver version: %v", ret)
+	}
+
+	cuda, ret := nvmlLib.SystemGetCudaDriverVersion()
+	if ret == nvml.SUCCESS {
+		installed.CUDA = formatCudaVersion(cuda)
+	} else {
+		log.Debugf("Unable to get CUDA driver version: %v", ret)
+	}
+
+	return installed
+}
+
+// formatCudaVersion formats a CUDA version as reported by NVML (e.g. 12020)
+// as '<major>.<minor>' (e.g. '12.2').
+func formatCudaVersion(version int) string {
+	return fmt.Sprintf("%d.%d", version/1000, (version%1000)/10)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/pkg/textfile"
+)
+
+// TextfileName is the name of the file 'assert --textfile-dir' writes its
+// metrics to.
+const TextfileName = "nvidia_mig_parted_assert.prom"
+
+// assertMetrics returns the metrics of an assert that started at 'start' and
+// returned 'err' at 'now'. Whether the node drifted is only reported when
+// the assert could tell, as with recordDrift.
+func assertMetrics(f *Flags, start, now time.Time, err error) []textfile.Metric {
+	labels := map[string]string{"selected_config": f.SelectedConfig}
+	success := 0.0
+	if err == nil {
+		success = 1
+	}
+
+	metrics := []textfile.Metric{
+		{Name: "mig_parted_assert_success", Help: "Whether the last assert found the selected MIG config applied.", Type: textfile.Gauge, Labels: labels, Value: success},
+		{Name: "mig_parted_assert_timestamp_seconds", Help: "Time of the last assert, in seconds since the epoch.", Type: textfile.Gauge, Labels: labels, Value: float64(start.Unix())},
+		{Name: "mig_parted_assert_duration_seconds", Help: "Duration of the last assert, in seconds.", Type: textfile.Gauge, Labels: labels, Value: now.Sub(start).Seconds()},
+	}
+	if err == nil || IsDrift(err) {
+		drifted := 0.0
+		if IsDrift(err) {
+			drifted = 1
+		}
+		metrics = append(metrics, textfile.Metric{Name: "mig_parted_drift_detected", Help: "Whether the last drift check found the node drifted from its MIG config.", Type: textfile.Gauge, Labels: labels, Value: drifted})
+	}
+	return metrics
+}
+
+// writeAssertMetrics writes the metrics of an assert that started at 'start'
+// and returned 'err' to '--textfile-dir' if it is set. The metrics are
+// informational only, so errors writing them are logged rather than failing
+// the assert.
+func writeAssertMetrics(f *Flags, start time.Time, err error) {
+	if f.TextfileDir == "" {
+		return
+	}
+	werr := textfile.Write(filepath.Join(f.TextfileDir, TextfileName), assertMetrics(f, start, time.Now(), err))
+	if werr != nil {
+		log.Warnf("Error writing assert metrics: %v", werr)
+	}
+}
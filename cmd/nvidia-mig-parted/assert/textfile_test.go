@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package assert
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteAssertMetrics(t *testing.T) {
+	f := &Flags{
+		SelectedConfig: "all-1g.10gb",
+		TextfileDir:    t.TempDir(),
+	}
+	read := func() string {
+		content, err := os.ReadFile(filepath.Join(f.TextfileDir, TextfileName))
+		require.Nil(t, err)
+		return string(content)
+	}
+	start := time.Unix(1704164645, 0)
+
+	writeAssertMetrics(f, start, nil)
+	content := read()
+	require.Contains(t, content, "mig_parted_assert_success{selected_config=\"all-1g.10gb\"} 1\n")
+	require.Contains(t, content, "mig_parted_assert_timestamp_seconds{selected_config=\"all-1g.10gb\"} 1704164645\n")
+	require.Contains(t, content, "# TYPE mig_parted_assert_duration_seconds gauge\n")
+	require.Contains(t, content, "mig_parted_drift_detected{selected_config=\"all-1g.10gb\"} 0\n")
+
+	writeAssertMetrics(f, start, driftErrorf("Assertion failure: selected configuration not currently applied"))
+	content = read()
+	require.Contains(t, content, "mig_parted_assert_success{selected_config=\"all-1g.10gb\"} 0\n")
+	require.Contains(t, content, "mig_parted_drift_detected{selected_config=\"all-1g.10gb\"} 1\n")
+
+	writeAssertMetrics(f, start, fmt.Errorf("error initializing NVML"))
+	content = read()
+	require.Contains(t, content, "mig_parted_assert_success{selected_config=\"all-1g.10gb\"} 0\n")
+	require.NotContains(t, content, "mig_parted_drift_detected", "Unexpected drift reported when the assert could not tell")
+}
@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capabilities
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/internal/info"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	JSONFormat = export.JSONFormat
+	YAMLFormat = export.YAMLFormat
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'capabilities' subcommand.
+type Flags struct {
+	OutputFormat string
+}
+
+// Capabilities describes what this build of mig-parted supports, as compiled
+// from its tables of GPU models and MIG config groups.
+type Capabilities struct {
+	Version      string        `json:"version"       yaml:"version"`
+	SpecVersion  string        `json:"spec-version"  yaml:"spec-version"`
+	GpuModels    []GpuModel    `json:"gpu-models"    yaml:"gpu-models"`
+	ConfigGroups []ConfigGroup `json:"config-groups" yaml:"config-groups"`
+}
+
+// GpuModel describes a GPU model that can be referenced by name in a spec.
+type GpuModel struct {
+	Name      string   `json:"name"       yaml:"name"`
+	DeviceIDs []string `json:"device-ids" yaml:"device-ids,flow"`
+}
+
+// ConfigGroup describes the MIG profiles and maximal MIG configs known for
+// a device ID without querying the device itself.
+type ConfigGroup struct {
+	DeviceID string            `json:"device-id" yaml:"device-id"`
+	Profiles []string          `json:"profiles"  yaml:"profiles,flow"`
+	Configs  []types.MigConfig `json:"configs"   yaml:"configs"`
+}
+
+// BuildCommand builds the 'capabilities' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	capabilitiesFlags := Flags{}
+
+	// Create the 'capabilities' command
+	capabilities := cli.Command{}
+	capabilities.Name = "capabilities"
+	capabilities.Usage = "Print the GPU models, MIG profiles and MIG config groups supported by this binary"
+	capabilities.Action = func(c *cli.Context) error {
+		return capabilitiesWrapper(c, &capabilitiesFlags)
+	}
+
+	// Setup the flags for this command
+	capabilities.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [json | yaml]",
+			Destination: &capabilitiesFlags.OutputFormat,
+			Value:       JSONFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+	}
+
+	return &capabilities
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case JSONFormat:
+	case YAMLFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	return nil
+}
+
+// GetCapabilities compiles the capabilities of this binary. Everything in it
+// is ordered so that the output only changes when the tables do. MIG profiles
+// are parsed through NVML, so the driver library must be installed (although
+// no GPU needs to be present).
+func GetCapabilities() *Capabilities {
+	c := &Capabilities{
+		Version:      info.GetVersionString(),
+		SpecVersion:  v1.Version,
+		GpuModels:    []GpuModel{},
+		ConfigGroups: []ConfigGroup{},
+	}
+
+	for _, name := range types.GpuModelNames() {
+		model := GpuModel{Name: name}
+		for _, id := range types.GpuModels[name] {
+			model.DeviceIDs = append(model.DeviceIDs, id.String())
+		}
+		c.GpuModels = append(c.GpuModels, model)
+	}
+
+	for id, group := range config.GetKnownMigConfigGroups() {
+		g := ConfigGroup{DeviceID: id.String()}
+		for _, mp := range group.GetDeviceTypes() {
+			g.Profiles = append(g.Profiles, mp.String())
+		}
+		g.Configs = append(g.Configs, group.GetPossibleConfigurations()...)
+		sort.Slice(g.Configs, func(i, j int) bool {
+			return configString(g.Configs[i]) < configString(g.Configs[j])
+		})
+		c.ConfigGroups = append(c.ConfigGroups, g)
+	}
+	sort.Slice(c.ConfigGroups, func(i, j int) bool {
+		return c.ConfigGroups[i].DeviceID < c.ConfigGroups[j].DeviceID
+	})
+
+	return c
+}
+
+// configString returns a stable string representation of 'mc' for sorting.
+func configString(mc types.MigConfig) string {
+	var profiles []string
+	for profile, count := range mc {
+		profiles = append(profiles, fmt.Sprintf("%v=%d", profile, count))
+	}
+	sort.Strings(profiles)
+	return fmt.Sprintf("%v", profiles)
+}
+
+func capabilitiesWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	return export.WriteOutput(os.Stdout, GetCapabilities(), &export.Flags{OutputFormat: f.OutputFormat})
+}
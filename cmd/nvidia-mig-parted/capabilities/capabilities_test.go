@@ -0,0 +1,61 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package capabilities
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	types.SetMockNVdevlib()
+	c := GetCapabilities()
+
+	require.Len(t, c.GpuModels, len(types.GpuModels))
+	for i := 1; i < len(c.GpuModels); i++ {
+		require.Less(t, c.GpuModels[i-1].Name, c.GpuModels[i].Name)
+	}
+	require.Contains(t, c.GpuModels, GpuModel{Name: "A100-PCIE-40GB", DeviceIDs: []string{"0x20B110DE", "0x20F110DE"}})
+
+	require.Len(t, c.ConfigGroups, 1)
+	group := c.ConfigGroups[0]
+	require.Equal(t, "0x20B010DE", group.DeviceID)
+	require.Contains(t, group.Profiles, "1g.5gb")
+	require.Contains(t, group.Profiles, "7g.40gb")
+	require.Contains(t, group.Configs, types.MigConfig{"1g.5gb": 7})
+	for i := 1; i < len(group.Configs); i++ {
+		require.Less(t, configString(group.Configs[i-1]), configString(group.Configs[i]))
+	}
+}
+
+func TestCapabilitiesOutputIsStable(t *testing.T) {
+	types.SetMockNVdevlib()
+	var first, second bytes.Buffer
+	require.Nil(t, export.WriteOutput(&first, GetCapabilities(), &export.Flags{OutputFormat: JSONFormat}))
+	require.Nil(t, export.WriteOutput(&second, GetCapabilities(), &export.Flags{OutputFormat: JSONFormat}))
+	require.Equal(t, first.String(), second.String())
+
+	var c Capabilities
+	require.Nil(t, json.Unmarshal(first.Bytes(), &c))
+	require.Equal(t, "v1", c.SpecVersion)
+}
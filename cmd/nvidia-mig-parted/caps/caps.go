@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package caps
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+)
+
+var log = logrus.New()
+
+const (
+	TextFormat         = "text"
+	JSONFormat         = "json"
+	YAMLFormat         = "yaml"
+	DevicesAllowFormat = "devices-allow"
+)
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'caps' subcommand.
+type Flags struct {
+	MigMinorsFile string
+	DevicesFile   string
+	OutputFormat  string
+}
+
+// BuildCommand builds the 'caps' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	capsFlags := Flags{}
+
+	// Create the 'caps' command
+	caps := cli.Command{}
+	caps.Name = "caps"
+	caps.Usage = "List the device nodes (and their major / minor numbers) needed to access each MIG device"
+	caps.Action = func(c *cli.Context) error {
+		return capsWrapper(c, &capsFlags)
+	}
+
+	// Setup the flags for this command
+	caps.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "mig-minors-file",
+			Usage:       "Path to the file listing the minor numbers of the nvidia-caps device nodes",
+			Destination: &capsFlags.MigMinorsFile,
+			Value:       migcaps.DefaultMigMinorsFile,
+			EnvVars:     []string{"MIG_PARTED_MIG_MINORS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "devices-file",
+			Usage:       "Path to the file listing the major numbers of all character devices",
+			Destination: &capsFlags.DevicesFile,
+			Value:       migcaps.DefaultDevicesFile,
+			EnvVars:     []string{"MIG_PARTED_DEVICES_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [text | json | yaml | devices-allow]",
+			Destination: &capsFlags.OutputFormat,
+			Value:       TextFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+	}
+
+	return &caps
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case TextFormat:
+	case JSONFormat:
+	case YAMLFormat:
+	case DevicesAllowFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	if f.MigMinorsFile == "" {
+		return fmt.Errorf("missing required flag 'mig-minors-file'")
+	}
+	if f.DevicesFile == "" {
+		return fmt.Errorf("missing required flag 'devices-file'")
+	}
+	return nil
+}
+
+// GetMigDeviceCaps returns the device nodes needed to access each MIG device
+// that currently exists on the GPUs of the node.
+func GetMigDeviceCaps(nvmlLib nvml.Interface, f *Flags) ([]migcaps.MigDeviceCaps, error) {
+	minors, err := migcaps.ReadMigMinors(f.MigMinorsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading MIG minors: %v", err)
+	}
+
+	capsMajor, err := migcaps.ReadMajor(f.DevicesFile, migcaps.CapsDevicesName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading major number of '%v': %v", migcaps.CapsDevicesName, err)
+	}
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	all := []migcaps.MigDeviceCaps{}
+	for i := 0; i < count; i++ {
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %d: %v", i, err)
+		}
+		if !capable {
+			continue
+		}
+
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %d: %v", i, err)
+		}
+		if m != mode.Enabled {
+			continue
+		}
+
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		gpuMinor, ret := device.GetMinorNumber()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting minor number for GPU %d: %v", i, ret)
+		}
+
+		devices, err := configManager.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices for GPU %d: %v", i, err)
+		}
+
+		for _, d := range devices {
+			c, err := migcaps.Resolve(minors, capsMajor, i, gpuMinor, d)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving device nodes for GPU %d: %v", i, err)
+			}
+			all = append(all, *c)
+		}
+	}
+
+	return all, nil
+}
+
+// WriteMigDeviceCaps writes 'all' to 'w' in the requested 'format'.
+func WriteMigDeviceCaps(w io.Writer, format string, all []migcaps.MigDeviceCaps) error {
+	switch format {
+	case JSONFormat, YAMLFormat:
+		return export.WriteOutput(w, all, &export.Flags{OutputFormat: format})
+	case DevicesAllowFormat:
+		for _, line := range migcaps.DevicesAllow(all) {
+			fmt.Fprintln(w, line)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GPU\tGI\tCI\tPROFILE\tDEVICE-NODES")
+	for _, c := range all {
+		var nodes []string
+		for _, node := range c.DeviceNodes {
+			nodes = append(nodes, node.Path)
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%v\t%v\n", c.GPU, c.GpuInstanceID, c.ComputeInstanceID, c.Profile, strings.Join(nodes, ","))
+	}
+	return tw.Flush()
+}
+
+func capsWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	all, err := GetMigDeviceCaps(nvmlLib, f)
+	if err != nil {
+		return fmt.Errorf("error getting MIG device capabilities: %v", err)
+	}
+
+	return WriteMigDeviceCaps(os.Stdout, f.OutputFormat, all)
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package caps
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestWriteMigDeviceCaps(t *testing.T) {
+	minors := migcaps.MigMinors{
+		"gpu0/gi1/access":     12,
+		"gpu0/gi1/ci0/access": 13,
+		"gpu0/gi1/ci1/access": 14,
+	}
+
+	var all []migcaps.MigDeviceCaps
+	for _, ci := range []uint32{0, 1} {
+		c, err := migcaps.Resolve(minors, 237, 0, 0, types.MigDevice{Profile: "1c.2g.10gb", GpuInstanceID: 1, ComputeInstanceID: ci})
+		require.Nil(t, err, "Unexpected failure from Resolve")
+		all = append(all, *c)
+	}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			TextFormat,
+			"GPU  GI  CI  PROFILE     DEVICE-NODES\n" +
+				"0    1   0   1c.2g.10gb  /dev/nvidiactl,/dev/nvidia0,/dev/nvidia-caps/nvidia-cap12,/dev/nvidia-caps/nvidia-cap13\n" +
+				"0    1   1   1c.2g.10gb  /dev/nvidiactl,/dev/nvidia0,/dev/nvidia-caps/nvidia-cap12,/dev/nvidia-caps/nvidia-cap14\n",
+		},
+		{
+			DevicesAllowFormat,
+			"c 195:255 rw\nc 195:0 rw\nc 237:12 rw\nc 237:13 rw\nc 237:14 rw\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			var output bytes.Buffer
+			err := WriteMigDeviceCaps(&output, tc.format, all)
+			require.Nil(t, err, "Unexpected failure from WriteMigDeviceCaps")
+			require.Equal(t, tc.expected, output.String())
+		})
+	}
+}
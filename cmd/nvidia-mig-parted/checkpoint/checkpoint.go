@@ -19,6 +19,7 @@ package checkpoint
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
@@ -27,7 +28,7 @@ import (
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
-	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v1"
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
 	"github.com/NVIDIA/mig-parted/pkg/mig/state"
 )
@@ -49,6 +50,7 @@ func BuildCommand() *cli.Command {
 	// Create the 'checkpoint' command
 	checkpoint := cli.Command{}
 	checkpoint.Name = "checkpoint"
+	checkpoint.Aliases = []string{"save"}
 	checkpoint.Usage = "Checkpoint MIG state to a checkpoint file"
 	checkpoint.Action = func(c *cli.Context) error {
 		return checkpointWrapper(c, &checkpointFlags)
@@ -56,13 +58,13 @@ func BuildCommand() *cli.Command {
 
 	// Setup the flags for this command
 	checkpoint.Flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:        "checkpoint-file",
-			Aliases:     []string{"f"},
-			Usage:       "Path to the checkpoint file",
-			Destination: &checkpointFlags.CheckpointFile,
-			EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
-		},
+		util.CheckpointFileFlag(&checkpointFlags.CheckpointFile, "Path to the checkpoint file ('-' for stdout)"),
+	}
+
+	// Register the subcommands of this command
+	checkpoint.Subcommands = []*cli.Command{
+		buildValidateCommand(),
+		buildToSpecCommand(),
 	}
 
 	return &checkpoint
@@ -80,6 +82,39 @@ func CheckFlags(f *Flags) error {
 	return nil
 }
 
+// GetState fetches the current MIG state of the node, along with the driver
+// version and the details of each GPU needed to validate it when restored.
+func GetState() (*checkpoint.State, error) {
+	nvmlLib := nvml.New()
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	migState, err := state.NewMigStateManager().Fetch()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching MIG state: %v", err)
+	}
+
+	driverVersion, ret := nvmlLib.SystemGetDriverVersion()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting driver version: %v", ret)
+	}
+
+	devices, err := GetDeviceInfos(nvmlLib, migState)
+	if err != nil {
+		return nil, fmt.Errorf("error getting device info: %v", err)
+	}
+
+	return &checkpoint.State{
+		Version:       checkpoint.Version,
+		DriverVersion: driverVersion,
+		Devices:       devices,
+		MigState:      *migState,
+	}, nil
+}
+
 func checkpointWrapper(c *cli.Context, f *Flags) error {
 	err := CheckFlags(f)
 	if err != nil {
@@ -87,21 +122,14 @@ func checkpointWrapper(c *cli.Context, f *Flags) error {
 		return err
 	}
 
-	nvml := nvml.New()
-	err = util.NvmlInit(nvml)
+	err = util.CheckNotTerminal(f.CheckpointFile, "a checkpoint")
 	if err != nil {
-		return fmt.Errorf("error initializing NVML: %v", err)
+		return err
 	}
-	defer util.TryNvmlShutdown(nvml)
 
-	migState, err := state.NewMigStateManager().Fetch()
+	state, err := GetState()
 	if err != nil {
-		return fmt.Errorf("error fetching MIG state: %v", err)
-	}
-
-	state := checkpoint.State{
-		Version:  checkpoint.Version,
-		MigState: *migState,
+		return err
 	}
 
 	j, err := json.Marshal(state)
@@ -109,15 +137,25 @@ func checkpointWrapper(c *cli.Context, f *Flags) error {
 		return fmt.Errorf("error marshalling MIG state to json: %v", err)
 	}
 
-	checkpointFile, err := os.Create(f.CheckpointFile)
-	if err != nil {
-		return fmt.Errorf("error creating checkpoint file: %w", err)
+	// Terminate output written to stdout with a newline so that it neither
+	// runs into the shell prompt nor breaks line-oriented consumers.
+	if util.IsStdio(f.CheckpointFile) {
+		j = append(j, '\n')
 	}
-	defer checkpointFile.Close()
-	if _, err := checkpointFile.Write(j); err != nil {
+
+	err = util.WriteToFile(f.CheckpointFile, func(w io.Writer) error {
+		_, err := w.Write(j)
+		return err
+	})
+	if err != nil {
 		return fmt.Errorf("error writing checkpoint file: %v", err)
 	}
 
+	// Keep stdout clean for the checkpoint itself when it is written there.
+	if util.IsStdio(f.CheckpointFile) {
+		fmt.Fprintln(os.Stderr, "MIG configuration checkpointed successfully")
+		return nil
+	}
 	fmt.Println("MIG configuration checkpointed successfully")
 	return nil
 }
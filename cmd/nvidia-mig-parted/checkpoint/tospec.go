@@ -0,0 +1,241 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"fmt"
+	"io"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+type ToSpecFlags struct {
+	CheckpointFile string
+	OutputFormat   string
+	OutputFile     string
+	ConfigLabel    string
+}
+
+func buildToSpecCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	toSpecFlags := ToSpecFlags{}
+
+	// Create the 'to-spec' command
+	toSpec := cli.Command{}
+	toSpec.Name = "to-spec"
+	toSpec.Usage = "Convert a checkpoint file into a MIG config spec holding the same profile counts"
+	toSpec.Action = func(c *cli.Context) error {
+		return toSpecWrapper(c, &toSpecFlags)
+	}
+
+	// Setup the flags for this command
+	toSpec.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "checkpoint-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the checkpoint file ('-' for stdin)",
+			Destination: &toSpecFlags.CheckpointFile,
+			EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [json | yaml]",
+			Destination: &toSpecFlags.OutputFormat,
+			Value:       export.YAMLFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:        "output-file",
+			Usage:       "File to write the output to ('-' for stdout)",
+			Destination: &toSpecFlags.OutputFile,
+			Value:       util.StdioPath,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "config-label",
+			Aliases:     []string{"l"},
+			Usage:       "Label to give the MIG config in the spec",
+			Destination: &toSpecFlags.ConfigLabel,
+			Value:       export.DefaultConfigLabel,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_LABEL"},
+		},
+	}
+
+	return &toSpec
+}
+
+func toSpecWrapper(c *cli.Context, f *ToSpecFlags) error {
+	err := CheckFlags(&Flags{CheckpointFile: f.CheckpointFile})
+	if err == nil {
+		err = export.CheckFlags(&export.Flags{OutputFormat: f.OutputFormat})
+	}
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	log.Debugf("Parsing checkpoint file...")
+	checkpointJson, err := util.ReadFile(f.CheckpointFile)
+	if err != nil {
+		return fmt.Errorf("error reading checkpoint file: %v", err)
+	}
+
+	checkpointed, err := checkpoint.ParseState(checkpointJson)
+	if err != nil {
+		return fmt.Errorf("error parsing checkpoint file: %v", err)
+	}
+
+	if !hasDeviceMemory(checkpointed) {
+		log.Debugf("Checkpoint does not record GPU memory sizes, reading them from the node...")
+		err := fillDeviceInfos(checkpointed)
+		if err != nil {
+			return fmt.Errorf("error getting device info missing from the checkpoint: %v", err)
+		}
+	}
+
+	spec, err := StateToSpec(checkpointed, f.ConfigLabel)
+	if err != nil {
+		return err
+	}
+
+	err = util.WriteToFile(f.OutputFile, func(w io.Writer) error {
+		return export.WriteOutput(w, spec, &export.Flags{OutputFormat: f.OutputFormat})
+	})
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
+
+// hasDeviceMemory checks if 's' records the memory size of each of its GPUs.
+func hasDeviceMemory(s *checkpoint.State) bool {
+	if len(s.Devices) != len(s.MigState.Devices) {
+		return false
+	}
+	for _, d := range s.Devices {
+		if d.Memory == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// fillDeviceInfos replaces the device info in 's' with that of the GPUs on
+// the node with the same UUIDs, for checkpoints too old to record it.
+func fillDeviceInfos(s *checkpoint.State) error {
+	nvmlLib := nvml.New()
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	devices, err := GetDeviceInfos(nvmlLib, &s.MigState)
+	if err != nil {
+		return err
+	}
+	s.Devices = devices
+	return nil
+}
+
+// StateToSpec converts the MIG state held in a checkpoint into a spec with a
+// single MIG config labeled 'label', in the same form 'export' would have
+// produced on the node when the checkpoint was taken. Each GPU's compute
+// instances are counted by profile; their placements and the GPU UUIDs are
+// not carried over.
+func StateToSpec(s *checkpoint.State, label string) (*v1.Spec, error) {
+	if len(s.Devices) != len(s.MigState.Devices) {
+		return nil, fmt.Errorf("checkpoint records device info for %d of %d GPUs", len(s.Devices), len(s.MigState.Devices))
+	}
+
+	var configSpecs v1.MigConfigSpecSlice
+	for i, deviceState := range s.MigState.Devices {
+		info := s.Devices[i]
+		if info.UUID != deviceState.UUID {
+			return nil, fmt.Errorf("device info for GPU %d is for '%v', not '%v'", i, info.UUID, deviceState.UUID)
+		}
+
+		migDevices := types.MigConfig{}
+		enabled := deviceState.MigMode == mode.Enabled
+		if enabled {
+			var err error
+			migDevices, err = migConfigFromDeviceState(&deviceState, info.Memory)
+			if err != nil {
+				return nil, fmt.Errorf("error converting MIG state of GPU %d: %v", i, err)
+			}
+		}
+
+		configSpecs = append(configSpecs, v1.MigConfigSpec{
+			DeviceFilter: []string{info.DeviceID.String()},
+			Devices:      []int{i},
+			MigEnabled:   enabled,
+			MigDevices:   migDevices,
+		})
+	}
+
+	if len(configSpecs) == 0 {
+		return nil, fmt.Errorf("no GPUs in checkpoint")
+	}
+
+	spec := v1.Spec{
+		Version: v1.Version,
+		MigConfigs: map[string]v1.MigConfigSpecSlice{
+			label: export.MergeMigConfigSpecs(configSpecs),
+		},
+	}
+
+	return &spec, nil
+}
+
+// migConfigFromDeviceState counts the compute instances in 'deviceState' by
+// profile, the same way a MIG config Manager reports the MIG config of a GPU.
+// GPU instances without any compute instances can't be expressed in a spec, so
+// they are left out.
+func migConfigFromDeviceState(deviceState *types.DeviceState, deviceMemory uint64) (types.MigConfig, error) {
+	if deviceMemory == 0 {
+		return nil, fmt.Errorf("memory size of '%v' unknown", deviceState.UUID)
+	}
+
+	migConfig := types.MigConfig{}
+	for _, giState := range deviceState.GpuInstances {
+		if giState.MemorySizeMB == 0 {
+			return nil, fmt.Errorf("checkpoint does not record the memory size of GPU instance profile %d", giState.ProfileID)
+		}
+		if len(giState.ComputeInstances) == 0 {
+			log.Warnf("Leaving out GPU instance at %+v on '%v', which has no compute instances", giState.Placement, deviceState.UUID)
+			continue
+		}
+		for _, ciState := range giState.ComputeInstances {
+			mp, err := types.NewMigProfile(giState.ProfileID, ciState.ProfileID, ciState.EngProfileID, giState.MemorySizeMB, deviceMemory)
+			if err != nil {
+				return nil, fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %v", giState.ProfileID, ciState.ProfileID, ciState.EngProfileID, err)
+			}
+			migConfig[mp.String()]++
+		}
+	}
+	return migConfig, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestStateToSpec(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	const a100 = types.DeviceID(0x20B010DE)
+	const memory = 40 * 1024 * 1024 * 1024
+
+	gi1g := func(start uint32) types.GpuInstanceState {
+		return types.GpuInstanceState{
+			ProfileID:        nvml.GPU_INSTANCE_PROFILE_1_SLICE,
+			SliceCount:       1,
+			MemorySizeMB:     5 * 1024,
+			Placement:        nvml.GpuInstancePlacement{Start: start, Size: 1},
+			ComputeInstances: []types.ComputeInstanceState{{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE}},
+		}
+	}
+	gi3g := types.GpuInstanceState{
+		ProfileID:    nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+		SliceCount:   3,
+		MemorySizeMB: 20 * 1024,
+		Placement:    nvml.GpuInstancePlacement{Start: 4, Size: 4},
+		ComputeInstances: []types.ComputeInstanceState{
+			{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+			{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+		},
+	}
+
+	testCases := []struct {
+		description     string
+		state           checkpoint.State
+		expected        v1.MigConfigSpecSlice
+		expectedFailure bool
+	}{
+		{
+			"Same config on every GPU",
+			checkpoint.State{
+				Devices: []checkpoint.DeviceInfo{
+					{UUID: "GPU-0", DeviceID: a100, Memory: memory},
+					{UUID: "GPU-1", DeviceID: a100, Memory: memory},
+				},
+				MigState: types.MigState{
+					Devices: []types.DeviceState{
+						{UUID: "GPU-0", MigMode: mode.Enabled, GpuInstances: []types.GpuInstanceState{gi1g(0), gi1g(1)}},
+						{UUID: "GPU-1", MigMode: mode.Enabled, GpuInstances: []types.GpuInstanceState{gi1g(0), gi1g(1)}},
+					},
+				},
+			},
+			v1.MigConfigSpecSlice{
+				{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 2}},
+			},
+			false,
+		},
+		{
+			"Different configs, split GPU instance and MIG disabled",
+			checkpoint.State{
+				Devices: []checkpoint.DeviceInfo{
+					{UUID: "GPU-0", DeviceID: a100, Memory: memory},
+					{UUID: "GPU-1", DeviceID: a100, Memory: memory},
+				},
+				MigState: types.MigState{
+					Devices: []types.DeviceState{
+						{UUID: "GPU-0", MigMode: mode.Enabled, GpuInstances: []types.GpuInstanceState{gi1g(0), gi3g}},
+						{UUID: "GPU-1", MigMode: mode.Disabled},
+					},
+				},
+			},
+			v1.MigConfigSpecSlice{
+				{Devices: []int{0}, MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 1, "1c.3g.20gb": 2}},
+				{Devices: []int{1}, MigEnabled: false, MigDevices: types.MigConfig{}},
+			},
+			false,
+		},
+		{
+			"Missing GPU memory size",
+			checkpoint.State{
+				Devices: []checkpoint.DeviceInfo{
+					{UUID: "GPU-0", DeviceID: a100},
+				},
+				MigState: types.MigState{
+					Devices: []types.DeviceState{
+						{UUID: "GPU-0", MigMode: mode.Enabled, GpuInstances: []types.GpuInstanceState{gi1g(0)}},
+					},
+				},
+			},
+			nil,
+			true,
+		},
+		{
+			"Missing device info",
+			checkpoint.State{
+				MigState: types.MigState{
+					Devices: []types.DeviceState{
+						{UUID: "GPU-0", MigMode: mode.Disabled},
+					},
+				},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			spec, err := StateToSpec(&tc.state, "restored")
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from StateToSpec")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from StateToSpec")
+			require.Equal(t, v1.Version, spec.Version)
+			require.Equal(t, tc.expected, spec.MigConfigs["restored"])
+		})
+	}
+}
@@ -0,0 +1,269 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"fmt"
+
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/state"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+type ValidateFlags struct {
+	CheckpointFile string
+	Relaxed        bool
+}
+
+func buildValidateCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	validateFlags := ValidateFlags{}
+
+	// Create the 'validate' command
+	validate := cli.Command{}
+	validate.Name = "validate"
+	validate.Usage = "Validate that a checkpoint file can be restored on the current node"
+	validate.Action = func(c *cli.Context) error {
+		return validateWrapper(c, &validateFlags)
+	}
+
+	// Setup the flags for this command
+	validate.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "checkpoint-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the checkpoint file ('-' for stdin)",
+			Destination: &validateFlags.CheckpointFile,
+			EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "relaxed",
+			Aliases:     []string{"r"},
+			Usage:       "Only require the same GPU models and counts, not the same GPU UUIDs",
+			Destination: &validateFlags.Relaxed,
+			EnvVars:     []string{"MIG_PARTED_RELAXED"},
+		},
+	}
+
+	return &validate
+}
+
+func validateWrapper(c *cli.Context, f *ValidateFlags) error {
+	err := CheckFlags(&Flags{CheckpointFile: f.CheckpointFile})
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	log.Debugf("Parsing checkpoint file...")
+	checkpointJson, err := util.ReadFile(f.CheckpointFile)
+	if err != nil {
+		return fmt.Errorf("error reading checkpoint file: %v", err)
+	}
+
+	checkpointed, err := checkpoint.ParseState(checkpointJson)
+	if err != nil {
+		return fmt.Errorf("error parsing checkpoint file: %v", err)
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	manager := state.NewMigStateManager()
+
+	log.Debugf("Fetching current MIG state...")
+	migState, err := manager.Fetch()
+	if err != nil {
+		return fmt.Errorf("error fetching MIG state: %v", err)
+	}
+
+	current, err := GetDeviceInfos(nvmlLib, migState)
+	if err != nil {
+		return fmt.Errorf("error getting device info: %v", err)
+	}
+
+	expected := checkpointed.Devices
+	if len(expected) == 0 {
+		for _, d := range checkpointed.MigState.Devices {
+			expected = append(expected, checkpoint.DeviceInfo{UUID: d.UUID})
+		}
+	}
+
+	problems := CompareDeviceInfos(expected, current, f.Relaxed)
+	if len(problems) == 0 {
+		migState := &checkpointed.MigState
+		if f.Relaxed {
+			migState, err = manager.Rebind(migState, DeviceModels(checkpointed.Devices))
+		}
+		if err == nil {
+			migState, err = manager.Remap(migState)
+		}
+		if err == nil {
+			err = manager.Validate(migState)
+		}
+		if err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	if len(problems) != 0 {
+		for _, p := range problems {
+			log.Errorf("%v", util.Capitalize(p))
+		}
+		return fmt.Errorf("checkpoint is not compatible with the current node")
+	}
+
+	if !SameUUIDs(expected, current) {
+		fmt.Println("Checkpoint is compatible with the current node when restored with --relaxed")
+		return nil
+	}
+
+	fmt.Println("Checkpoint is compatible with the current node")
+	return nil
+}
+
+// GetDeviceInfos returns a 'checkpoint.DeviceInfo' for each GPU in 'migState'.
+func GetDeviceInfos(nvmlLib nvml.Interface, migState *types.MigState) ([]checkpoint.DeviceInfo, error) {
+	var infos []checkpoint.DeviceInfo
+	for _, deviceState := range migState.Devices {
+		device, ret := nvmlLib.DeviceGetHandleByUUID(deviceState.UUID)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for '%v': %v", deviceState.UUID, ret)
+		}
+
+		name, ret := device.GetName()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device name for '%v': %v", deviceState.UUID, ret)
+		}
+
+		pciInfo, ret := device.GetPciInfo()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting PCI info for '%v': %v", deviceState.UUID, ret)
+		}
+
+		memory, ret := device.GetMemoryInfo()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting memory info for '%v': %v", deviceState.UUID, ret)
+		}
+
+		infos = append(infos, checkpoint.DeviceInfo{
+			UUID:     deviceState.UUID,
+			Name:     name,
+			DeviceID: types.DeviceID(pciInfo.PciDeviceId),
+			Memory:   memory.Total,
+		})
+	}
+	return infos, nil
+}
+
+// DeviceModels returns the model of each GPU in 'infos', for binding the GPUs
+// of a checkpoint to those of another node with 'state.Manager.Rebind()'.
+func DeviceModels(infos []checkpoint.DeviceInfo) []state.DeviceModel {
+	var models []state.DeviceModel
+	for _, info := range infos {
+		models = append(models, deviceModel(info))
+	}
+	return models
+}
+
+// deviceModel returns the model of the GPU described by 'info'.
+func deviceModel(info checkpoint.DeviceInfo) state.DeviceModel {
+	return state.DeviceModel{
+		Name:     info.Name,
+		DeviceID: info.DeviceID,
+		Memory:   info.Memory,
+	}
+}
+
+// SameUUIDs checks whether every GPU recorded in a checkpoint is present on
+// the node under the same UUID, i.e. whether the checkpoint can be restored
+// without 'relaxed'.
+func SameUUIDs(checkpointed, current []checkpoint.DeviceInfo) bool {
+	uuids := make(map[string]bool)
+	for _, c := range current {
+		uuids[c.UUID] = true
+	}
+	for _, d := range checkpointed {
+		if !uuids[d.UUID] {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareDeviceInfos compares the GPUs recorded in a checkpoint against the
+// GPUs currently present on a node and returns a description of each
+// incompatibility found. GPUs are matched by UUID unless 'relaxed' is set, in
+// which case they are matched by position and only their models must agree.
+// Models are only compared when they were recorded in the checkpoint, except
+// with 'relaxed', which needs them to match GPUs at all. The profiles and
+// placements of the checkpoint are not compared here, but by validating it
+// against the GPUs it is bound to with 'state.Manager.Validate()'.
+func CompareDeviceInfos(checkpointed, current []checkpoint.DeviceInfo, relaxed bool) []string {
+	var problems []string
+
+	if len(checkpointed) != len(current) {
+		problems = append(problems, fmt.Sprintf("checkpoint has %d MIG capable GPUs, node has %d", len(checkpointed), len(current)))
+	}
+
+	sameModel := func(a, b checkpoint.DeviceInfo) bool {
+		return deviceModel(a).Matches(deviceModel(b))
+	}
+
+	if relaxed {
+		for i := range checkpointed {
+			if i >= len(current) {
+				break
+			}
+			if checkpointed[i].DeviceID == 0 && checkpointed[i].Name == "" {
+				problems = append(problems, fmt.Sprintf("GPU %d has no model recorded in the checkpoint, so it cannot be matched with 'relaxed'", i))
+				continue
+			}
+			if !sameModel(checkpointed[i], current[i]) {
+				problems = append(problems, fmt.Sprintf("GPU %d is a '%v' in the checkpoint but a '%v' on the node", i, deviceModel(checkpointed[i]), deviceModel(current[i])))
+			}
+		}
+		return problems
+	}
+
+	byUUID := make(map[string]checkpoint.DeviceInfo)
+	for _, c := range current {
+		byUUID[c.UUID] = c
+	}
+
+	for _, d := range checkpointed {
+		c, exists := byUUID[d.UUID]
+		if !exists {
+			problems = append(problems, fmt.Sprintf("GPU '%v' from the checkpoint is not present on the node", d.UUID))
+			continue
+		}
+		if !sameModel(d, c) {
+			problems = append(problems, fmt.Sprintf("GPU '%v' is a '%v' in the checkpoint but a '%v' on the node", d.UUID, deviceModel(d), deviceModel(c)))
+		}
+	}
+
+	return problems
+}
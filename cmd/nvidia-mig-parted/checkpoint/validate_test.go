@@ -0,0 +1,166 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package checkpoint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
+)
+
+func TestCompareDeviceInfos(t *testing.T) {
+	a100 := func(uuid string) checkpoint.DeviceInfo {
+		return checkpoint.DeviceInfo{UUID: uuid, Name: "NVIDIA A100-SXM4-40GB", DeviceID: 0x20B010DE}
+	}
+	h100 := func(uuid string) checkpoint.DeviceInfo {
+		return checkpoint.DeviceInfo{UUID: uuid, Name: "NVIDIA H100 80GB HBM3", DeviceID: 0x233010DE}
+	}
+	withMemory := func(info checkpoint.DeviceInfo, gb uint64) checkpoint.DeviceInfo {
+		info.Memory = gb * 1024 * 1024 * 1024
+		return info
+	}
+
+	testCases := []struct {
+		Description  string
+		Checkpointed []checkpoint.DeviceInfo
+		Current      []checkpoint.DeviceInfo
+		Relaxed      bool
+		NumProblems  int
+	}{
+		{
+			"Identical",
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			false,
+			0,
+		},
+		{
+			"Different UUIDs",
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			[]checkpoint.DeviceInfo{a100("GPU-2"), a100("GPU-3")},
+			false,
+			2,
+		},
+		{
+			"Different UUIDs - relaxed",
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			[]checkpoint.DeviceInfo{a100("GPU-2"), a100("GPU-3")},
+			true,
+			0,
+		},
+		{
+			"Different models",
+			[]checkpoint.DeviceInfo{a100("GPU-0")},
+			[]checkpoint.DeviceInfo{h100("GPU-0")},
+			false,
+			1,
+		},
+		{
+			"Different models - relaxed",
+			[]checkpoint.DeviceInfo{a100("GPU-0")},
+			[]checkpoint.DeviceInfo{h100("GPU-1")},
+			true,
+			1,
+		},
+		{
+			"Different counts",
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			[]checkpoint.DeviceInfo{a100("GPU-0")},
+			false,
+			2,
+		},
+		{
+			"Different counts - relaxed",
+			[]checkpoint.DeviceInfo{a100("GPU-0"), a100("GPU-1")},
+			[]checkpoint.DeviceInfo{a100("GPU-2")},
+			true,
+			1,
+		},
+		{
+			"Model not recorded",
+			[]checkpoint.DeviceInfo{{UUID: "GPU-0"}},
+			[]checkpoint.DeviceInfo{h100("GPU-0")},
+			false,
+			0,
+		},
+		{
+			"Model not recorded - relaxed",
+			[]checkpoint.DeviceInfo{{UUID: "GPU-0"}},
+			[]checkpoint.DeviceInfo{h100("GPU-1")},
+			true,
+			1,
+		},
+		{
+			"Different memory - relaxed",
+			[]checkpoint.DeviceInfo{withMemory(a100("GPU-0"), 40)},
+			[]checkpoint.DeviceInfo{withMemory(a100("GPU-1"), 80)},
+			true,
+			1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			problems := CompareDeviceInfos(tc.Checkpointed, tc.Current, tc.Relaxed)
+			require.Len(t, problems, tc.NumProblems, "Unexpected problems: %v", problems)
+		})
+	}
+}
+
+func TestSameUUIDs(t *testing.T) {
+	testCases := []struct {
+		Description  string
+		Checkpointed []string
+		Current      []string
+		Expected     bool
+	}{
+		{
+			"Same UUIDs",
+			[]string{"GPU-0", "GPU-1"},
+			[]string{"GPU-0", "GPU-1"},
+			true,
+		},
+		{
+			"Same UUIDs in a different order",
+			[]string{"GPU-0", "GPU-1"},
+			[]string{"GPU-1", "GPU-0"},
+			true,
+		},
+		{
+			"Different UUIDs",
+			[]string{"GPU-0", "GPU-1"},
+			[]string{"GPU-0", "GPU-2"},
+			false,
+		},
+	}
+
+	infos := func(uuids []string) []checkpoint.DeviceInfo {
+		var infos []checkpoint.DeviceInfo
+		for _, uuid := range uuids {
+			infos = append(infos, checkpoint.DeviceInfo{UUID: uuid})
+		}
+		return infos
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			require.Equal(t, tc.Expected, SameUUIDs(infos(tc.Checkpointed), infos(tc.Current)))
+		})
+	}
+}
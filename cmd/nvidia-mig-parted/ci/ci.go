@@ -0,0 +1,379 @@
+/*
+ * Copyright (c) 2021, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/gi"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'ci' subcommands.
+type Flags struct {
+	GPU             int
+	GpuInstance     int
+	Profile         string
+	Placement       int
+	ID              int
+	OutputFormat    string
+	NodeLockFile    string
+	NodeLockTimeout time.Duration
+}
+
+// ComputeInstance is a compute instance along with the GPU it lives on.
+type ComputeInstance struct {
+	GPU int `json:"gpu"`
+	types.MigDevice
+}
+
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	ciFlags := Flags{}
+
+	gpuFlag := func(usage string) cli.Flag {
+		return &cli.IntFlag{
+			Name:        "gpu",
+			Aliases:     []string{"g"},
+			Usage:       usage,
+			Destination: &ciFlags.GPU,
+			Value:       gi.Unset,
+		}
+	}
+
+	gpuInstanceFlag := func(usage string) cli.Flag {
+		return &cli.IntFlag{
+			Name:        "gpu-instance",
+			Aliases:     []string{"i"},
+			Usage:       usage,
+			Destination: &ciFlags.GpuInstance,
+			Value:       gi.Unset,
+		}
+	}
+
+	outputFormatFlag := &cli.StringFlag{
+		Name:        "output-format",
+		Aliases:     []string{"o"},
+		Usage:       "Format for the output [text | json]",
+		Destination: &ciFlags.OutputFormat,
+		Value:       gi.TextFormat,
+		EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+	}
+
+	nodeLockFileFlag := util.NodeLockFileFlag(&ciFlags.NodeLockFile)
+	nodeLockTimeoutFlag := util.NodeLockTimeoutFlag(&ciFlags.NodeLockTimeout)
+
+	// Create the 'create' subcommand
+	create := cli.Command{}
+	create.Name = "create"
+	create.Usage = "Create a compute instance inside an existing GPU instance"
+	create.Action = func(c *cli.Context) error {
+		return createWrapper(c, &ciFlags)
+	}
+	create.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to create the compute instance on"),
+		gpuInstanceFlag("ID of the parent GPU instance"),
+		&cli.StringFlag{
+			Name:        "profile",
+			Aliases:     []string{"p"},
+			Usage:       "Profile of the compute instance to create, by name or numeric profile ID within the GPU instance (e.g. 1c.3g.20gb or 0)",
+			Destination: &ciFlags.Profile,
+		},
+		&cli.IntFlag{
+			Name:        "placement",
+			Usage:       "Slice of the GPU instance to start the compute instance at (defaults to any valid placement)",
+			Destination: &ciFlags.Placement,
+			Value:       gi.Unset,
+		},
+		outputFormatFlag,
+		nodeLockFileFlag,
+		nodeLockTimeoutFlag,
+	}
+
+	// Create the 'destroy' subcommand
+	destroy := cli.Command{}
+	destroy.Name = "destroy"
+	destroy.Usage = "Destroy a compute instance"
+	destroy.Action = func(c *cli.Context) error {
+		return destroyWrapper(c, &ciFlags)
+	}
+	destroy.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to destroy the compute instance on"),
+		gpuInstanceFlag("ID of the parent GPU instance"),
+		&cli.IntFlag{
+			Name:        "id",
+			Usage:       "ID of the compute instance to destroy",
+			Destination: &ciFlags.ID,
+			Value:       gi.Unset,
+		},
+		nodeLockFileFlag,
+		nodeLockTimeoutFlag,
+	}
+
+	// Create the 'list' subcommand
+	list := cli.Command{}
+	list.Name = "list"
+	list.Usage = "List the compute instances on MIG enabled GPUs"
+	list.Action = func(c *cli.Context) error {
+		return listWrapper(c, &ciFlags)
+	}
+	list.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to list compute instances for (defaults to all MIG enabled GPUs)"),
+		gpuInstanceFlag("Only list compute instances in the GPU instance with this ID"),
+		outputFormatFlag,
+	}
+
+	// Create the 'ci' command
+	ci := cli.Command{}
+	ci.Name = "ci"
+	ci.Usage = "Manage individual compute instances"
+	ci.Subcommands = []*cli.Command{
+		&create,
+		&destroy,
+		&list,
+	}
+
+	return &ci
+}
+
+func CheckCreateFlags(f *Flags) error {
+	if f.GPU < 0 {
+		return fmt.Errorf("missing or invalid 'gpu': %v", f.GPU)
+	}
+	if f.GpuInstance < 0 {
+		return fmt.Errorf("missing or invalid 'gpu-instance': %v", f.GpuInstance)
+	}
+	if f.Profile == "" {
+		return fmt.Errorf("missing 'profile'")
+	}
+	if f.Placement < gi.Unset {
+		return fmt.Errorf("invalid 'placement': %v", f.Placement)
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
+	return gi.CheckOutputFormat(f.OutputFormat)
+}
+
+func CheckDestroyFlags(f *Flags) error {
+	if f.GPU < 0 {
+		return fmt.Errorf("missing or invalid 'gpu': %v", f.GPU)
+	}
+	if f.GpuInstance < 0 {
+		return fmt.Errorf("missing or invalid 'gpu-instance': %v", f.GpuInstance)
+	}
+	if f.ID < 0 {
+		return fmt.Errorf("missing or invalid 'id': %v", f.ID)
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
+	return nil
+}
+
+func CheckListFlags(f *Flags) error {
+	if f.GPU < gi.Unset {
+		return fmt.Errorf("invalid 'gpu': %v", f.GPU)
+	}
+	if f.GpuInstance < gi.Unset {
+		return fmt.Errorf("invalid 'gpu-instance': %v", f.GpuInstance)
+	}
+	return gi.CheckOutputFormat(f.OutputFormat)
+}
+
+func createWrapper(c *cli.Context, f *Flags) error {
+	err := CheckCreateFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	var start *uint32
+	if f.Placement != gi.Unset {
+		s := uint32(f.Placement)
+		start = &s
+	}
+
+	profile, err := resolveProfileID(manager, f.GPU, uint32(f.GpuInstance), f.Profile)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Debugf("Creating compute instance '%v' in GPU instance %d on GPU %d", profile, f.GpuInstance, f.GPU)
+	ci, err := manager.CreateComputeInstance(f.GPU, uint32(f.GpuInstance), profile, start)
+	util.AuditGPUs(log, "ci create", fmt.Sprintf("%v in GPU instance %d", profile, f.GpuInstance), []int{f.GPU}, err)
+	if err != nil {
+		return fmt.Errorf("error creating compute instance: %w", err)
+	}
+
+	return WriteOutput(os.Stdout, []ComputeInstance{{f.GPU, *ci}}, f.OutputFormat)
+}
+
+// resolveProfileID translates 'profile' into a profile name if it was given as
+// a numeric profile ID. A bare number is the compute instance profile ID within
+// GPU instance 'giID'; the "<gi>/<ci>" form names both profile IDs explicitly.
+func resolveProfileID(manager config.InstanceManager, gpu int, giID uint32, profile string) (string, error) {
+	if !types.IsMigProfileID(profile) {
+		return profile, nil
+	}
+	id, err := types.ParseMigProfileID(profile)
+	if err != nil {
+		return "", err
+	}
+	ids, err := util.GetMigProfileIDs(gpu)
+	if err != nil {
+		return "", fmt.Errorf("error getting MIG profile IDs of GPU %d: %v", gpu, err)
+	}
+
+	if id.ComputeInstance == types.FullComputeInstance {
+		gis, err := manager.ListGpuInstances(gpu)
+		if err != nil {
+			return "", fmt.Errorf("error listing GPU instances for GPU %d: %w", gpu, err)
+		}
+		var giProfile string
+		for _, gi := range gis {
+			if gi.ID == giID {
+				giProfile = gi.Profile
+			}
+		}
+		if giProfile == "" {
+			return "", fmt.Errorf("no GPU instance %d on GPU %d", giID, gpu)
+		}
+		parent, err := ids.ID(giProfile)
+		if err != nil {
+			return "", err
+		}
+		id = &types.MigProfileID{GpuInstance: parent.GpuInstance, ComputeInstance: id.GpuInstance}
+	}
+
+	return ids.Name(id.String())
+}
+
+func destroyWrapper(c *cli.Context, f *Flags) error {
+	err := CheckDestroyFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Debugf("Destroying compute instance %d in GPU instance %d on GPU %d", f.ID, f.GpuInstance, f.GPU)
+	err = manager.DestroyComputeInstance(f.GPU, uint32(f.GpuInstance), uint32(f.ID))
+	util.AuditGPUs(log, "ci destroy", fmt.Sprintf("compute instance %d in GPU instance %d", f.ID, f.GpuInstance), []int{f.GPU}, err)
+	if err != nil {
+		return fmt.Errorf("error destroying compute instance: %w", err)
+	}
+
+	fmt.Printf("Destroyed compute instance %d in GPU instance %d on GPU %d\n", f.ID, f.GpuInstance, f.GPU)
+	return nil
+}
+
+func listWrapper(c *cli.Context, f *Flags) error {
+	err := CheckListFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	gpus := []int{f.GPU}
+	if f.GPU == gi.Unset {
+		gpus, err = util.GetMigEnabledGPUs()
+		if err != nil {
+			return fmt.Errorf("error getting MIG enabled GPUs: %v", err)
+		}
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	cis := []ComputeInstance{}
+	for _, gpu := range gpus {
+		list, err := manager.ListComputeInstances(gpu)
+		if err != nil {
+			return fmt.Errorf("error listing compute instances for GPU %d: %w", gpu, err)
+		}
+		for _, ci := range list {
+			if f.GpuInstance != gi.Unset && ci.GpuInstanceID != uint32(f.GpuInstance) {
+				continue
+			}
+			cis = append(cis, ComputeInstance{gpu, ci})
+		}
+	}
+
+	return WriteOutput(os.Stdout, cis, f.OutputFormat)
+}
+
+// WriteOutput writes 'cis' to 'w' as a table or as JSON.
+func WriteOutput(w io.Writer, cis []ComputeInstance, format string) error {
+	if format == gi.JSONFormat {
+		output, err := json.MarshalIndent(cis, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling compute instances to JSON: %v", err)
+		}
+		output = append(output, '\n')
+		if _, err := w.Write(output); err != nil {
+			return fmt.Errorf("error writing JSON output: %w", err)
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GPU\tGI ID\tCI ID\tPROFILE\tPLACEMENT")
+	for _, ci := range cis {
+		fmt.Fprintf(tw, "%d\t%d\t%d\t%s\t%d:%d\n", ci.GPU, ci.GpuInstanceID, ci.ComputeInstanceID, ci.Profile, ci.ComputeInstancePlacement.Start, ci.ComputeInstancePlacement.Size)
+	}
+	return tw.Flush()
+}
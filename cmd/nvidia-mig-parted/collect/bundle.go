@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package collect
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrorsFile is the name of the file in a support bundle that lists every
+// item that could not be collected, along with the reason why.
+const ErrorsFile = "errors.txt"
+
+// Item is a single file in a support bundle.
+type Item struct {
+	Name string
+	// Collect returns the content of the file. If it returns an error, the
+	// error is recorded in ErrorsFile and any content returned along with it
+	// (such as the partial output of a failed command) is still written.
+	Collect func() ([]byte, error)
+}
+
+// BundleName returns the name (without extension) of the support bundle for
+// 'hostname' collected at 'now'.
+func BundleName(hostname string, now time.Time) string {
+	return fmt.Sprintf("nvidia-mig-parted-support-%s-%s", hostname, now.UTC().Format("20060102T150405Z"))
+}
+
+// WriteBundle collects every item in 'items' and writes them to 'w' as a
+// gzipped tarball, with all files placed under the directory 'prefix'.
+// Collection is best effort: items that fail are listed in ErrorsFile
+// rather than aborting the bundle.
+func WriteBundle(w io.Writer, prefix string, items []Item, now time.Time) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	var failures []string
+	for _, item := range items {
+		log.Debugf("Collecting %v", item.Name)
+		data, err := item.Collect()
+		if err != nil {
+			log.Warnf("Error collecting %v: %v", item.Name, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", item.Name, err))
+		}
+		if err != nil && len(data) == 0 {
+			continue
+		}
+		err = writeFile(tw, path.Join(prefix, item.Name), data, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(failures) > 0 {
+		data := []byte(strings.Join(failures, "\n") + "\n")
+		err := writeFile(tw, path.Join(prefix, ErrorsFile), data, now)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := tw.Close()
+	if err != nil {
+		return fmt.Errorf("error closing tar archive: %w", err)
+	}
+	err = gz.Close()
+	if err != nil {
+		return fmt.Errorf("error closing gzip stream: %w", err)
+	}
+	return nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	err := tw.WriteHeader(header)
+	if err != nil {
+		return fmt.Errorf("error writing %v to bundle: %w", name, err)
+	}
+	_, err = tw.Write(data)
+	if err != nil {
+		return fmt.Errorf("error writing %v to bundle: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package collect
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+)
+
+func readBundle(t *testing.T, data []byte) map[string]string {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	require.Nil(t, err)
+	tr := tar.NewReader(gz)
+
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+		content, err := io.ReadAll(tr)
+		require.Nil(t, err)
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestWriteBundle(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	testCases := []struct {
+		description   string
+		items         []Item
+		expectedFiles map[string]string
+	}{
+		{
+			"All items collected",
+			[]Item{
+				{"a.txt", func() ([]byte, error) { return []byte("a"), nil }},
+				{"b.json", func() ([]byte, error) { return []byte("{}"), nil }},
+			},
+			map[string]string{
+				"bundle/a.txt":  "a",
+				"bundle/b.json": "{}",
+			},
+		},
+		{
+			"Failed item is listed in errors file",
+			[]Item{
+				{"a.txt", func() ([]byte, error) { return []byte("a"), nil }},
+				{"b.json", func() ([]byte, error) { return nil, fmt.Errorf("no GPUs") }},
+			},
+			map[string]string{
+				"bundle/a.txt":      "a",
+				"bundle/errors.txt": "b.json: no GPUs\n",
+			},
+		},
+		{
+			"Partial output of a failed item is kept",
+			[]Item{
+				{"smi.txt", func() ([]byte, error) { return []byte("partial"), fmt.Errorf("exit status 1") }},
+			},
+			map[string]string{
+				"bundle/smi.txt":    "partial",
+				"bundle/errors.txt": "smi.txt: exit status 1\n",
+			},
+		},
+		{
+			"No items",
+			nil,
+			map[string]string{},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var output bytes.Buffer
+			err := WriteBundle(&output, "bundle", tc.items, now)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedFiles, readBundle(t, output.Bytes()))
+		})
+	}
+}
+
+func TestBundleName(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 5, 0, time.FixedZone("CEST", 2*60*60))
+	require.Equal(t, "nvidia-mig-parted-support-node1-20240501T103005Z", BundleName("node1", now))
+}
+
+func TestCollectJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+
+	output, err := collectJournal(path, 2)
+	require.Nil(t, err)
+	require.Equal(t, "[]\n", string(output))
+
+	for _, config := range []string{"a", "b", "c"} {
+		require.Nil(t, journal.Append(path, &journal.Entry{SelectedConfig: config}))
+	}
+
+	output, err = collectJournal(path, 2)
+	require.Nil(t, err)
+	require.NotContains(t, string(output), `"a"`)
+	require.Contains(t, string(output), `"b"`)
+	require.Contains(t, string(output), `"c"`)
+}
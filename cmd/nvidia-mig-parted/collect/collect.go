@@ -0,0 +1,324 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package collect
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/checkpoint"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/status"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/internal/info"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Defaults for the 'collect' subcommand.
+const (
+	DefaultJournalEntries = 20
+	DefaultLogUnit        = "nvidia-mig-manager.service"
+	DefaultLogSince       = 24 * time.Hour
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'collect' subcommand.
+type Flags struct {
+	OutputDir        string
+	JournalFile      string
+	JournalEntries   int
+	RebootMarkerFile string
+	LogUnit          string
+	LogSince         time.Duration
+}
+
+// Versions holds the versions of every component involved in applying a MIG config.
+type Versions struct {
+	MigParted         string `json:"mig-parted"`
+	Kernel            string `json:"kernel,omitempty"`
+	DriverVersion     string `json:"driver-version,omitempty"`
+	NvmlVersion       string `json:"nvml-version,omitempty"`
+	CudaDriverVersion int    `json:"cuda-driver-version,omitempty"`
+}
+
+// BuildCommand builds the 'collect' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	collectFlags := Flags{}
+
+	// Create the 'collect' command
+	collect := cli.Command{}
+	collect.Name = "collect"
+	collect.Usage = "Collect a support bundle of the MIG state, apply journal, versions and logs of the node to attach to bug reports"
+	collect.Action = func(c *cli.Context) error {
+		return collectWrapper(c, &collectFlags)
+	}
+
+	// Setup the flags for this command
+	collect.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output-dir",
+			Usage:       "Directory to write the support bundle (a .tar.gz file) to",
+			Destination: &collectFlags.OutputDir,
+			Value:       ".",
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_DIR"},
+		},
+		&cli.StringFlag{
+			Name:        "journal-file",
+			Usage:       "Path to the journal 'apply' records the outcome of each apply in",
+			Destination: &collectFlags.JournalFile,
+			Value:       journal.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_JOURNAL_FILE"},
+		},
+		&cli.IntFlag{
+			Name:        "journal-entries",
+			Usage:       "Number of most recent journal entries to include",
+			Destination: &collectFlags.JournalEntries,
+			Value:       DefaultJournalEntries,
+			EnvVars:     []string{"MIG_PARTED_COLLECT_JOURNAL_ENTRIES"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Usage:       "Path to the marker written by 'apply' when a reboot is required",
+			Destination: &collectFlags.RebootMarkerFile,
+			Value:       reboot.DefaultMarkerFile,
+			EnvVars:     []string{"MIG_PARTED_REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "log-unit",
+			Usage:       "Systemd unit whose logs (including the output of its hooks) to include (disabled if empty)",
+			Destination: &collectFlags.LogUnit,
+			Value:       DefaultLogUnit,
+			EnvVars:     []string{"MIG_PARTED_COLLECT_LOG_UNIT"},
+		},
+		&cli.DurationFlag{
+			Name:        "log-since",
+			Usage:       "How far back to include logs from 'log-unit'",
+			Destination: &collectFlags.LogSince,
+			Value:       DefaultLogSince,
+			EnvVars:     []string{"MIG_PARTED_COLLECT_LOG_SINCE"},
+		},
+	}
+
+	return &collect
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	if f.OutputDir == "" {
+		return fmt.Errorf("missing required flag 'output-dir'")
+	}
+	if f.JournalEntries < 0 {
+		return fmt.Errorf("invalid 'journal-entries': %v", f.JournalEntries)
+	}
+	if f.LogSince <= 0 {
+		return fmt.Errorf("invalid 'log-since': %v", f.LogSince)
+	}
+	return nil
+}
+
+func collectWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("error getting hostname: %v", err)
+	}
+
+	err = os.MkdirAll(f.OutputDir, 0755)
+	if err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	now := time.Now()
+	name := BundleName(hostname, now)
+	bundlePath := filepath.Join(f.OutputDir, name+".tar.gz")
+
+	bundle, err := os.Create(bundlePath)
+	if err != nil {
+		return fmt.Errorf("error creating support bundle: %v", err)
+	}
+	err = WriteBundle(bundle, name, GetItems(c, f, now), now)
+	if cerr := bundle.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(bundlePath)
+		return fmt.Errorf("error writing support bundle: %v", err)
+	}
+
+	fmt.Println(bundlePath)
+	return nil
+}
+
+// GetItems returns the items collected into a support bundle at 'now'.
+func GetItems(c *cli.Context, f *Flags, now time.Time) []Item {
+	items := []Item{
+		{"versions.json", collectVersions},
+		{"export.yaml", func() ([]byte, error) { return collectExport(c) }},
+		{"checkpoint.json", collectCheckpoint},
+		{"status.json", func() ([]byte, error) { return collectStatus(f) }},
+		{"journal.json", func() ([]byte, error) { return collectJournal(f.JournalFile, f.JournalEntries) }},
+		{"nvidia-smi-q.txt", func() ([]byte, error) { return runCommand("nvidia-smi", "-q") }},
+	}
+	if f.LogUnit != "" {
+		since := now.Add(-f.LogSince).Format("2006-01-02 15:04:05")
+		items = append(items, Item{"hooks.log", func() ([]byte, error) {
+			return runCommand("journalctl", "--no-pager", "-o", "short-iso", "-u", f.LogUnit, "--since", since)
+		}})
+	}
+	return items
+}
+
+func collectVersions() ([]byte, error) {
+	versions := Versions{
+		MigParted: info.GetVersionString(),
+	}
+
+	osRelease, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	if err == nil {
+		versions.Kernel = strings.TrimSpace(string(osRelease))
+	}
+
+	nvmlErr := getDriverVersions(nvml.New(), &versions)
+
+	output, err := marshalJSON(versions)
+	if err != nil {
+		return nil, err
+	}
+	return output, nvmlErr
+}
+
+// getDriverVersions fills in the versions reported by NVML, leaving them empty
+// if NVML is unavailable.
+func getDriverVersions(nvmlLib nvml.Interface, versions *Versions) error {
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	var ret nvml.Return
+	versions.DriverVersion, ret = nvmlLib.SystemGetDriverVersion()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting driver version: %v", ret)
+	}
+	versions.NvmlVersion, ret = nvmlLib.SystemGetNVMLVersion()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting NVML version: %v", ret)
+	}
+	versions.CudaDriverVersion, ret = nvmlLib.SystemGetCudaDriverVersion()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting CUDA driver version: %v", ret)
+	}
+	return nil
+}
+
+func collectExport(c *cli.Context) ([]byte, error) {
+	flags := &export.Flags{
+		OutputFormat: export.YAMLFormat,
+		ConfigLabel:  export.DefaultConfigLabel,
+	}
+	spec, err := export.ExportMigConfigs(&export.Context{
+		Context: c,
+		Flags:   flags,
+		Nvml:    nvml.New(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var output bytes.Buffer
+	err = export.WriteOutput(&output, spec, flags)
+	if err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+func collectCheckpoint() ([]byte, error) {
+	state, err := checkpoint.GetState()
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(state)
+}
+
+func collectStatus(f *Flags) ([]byte, error) {
+	s, err := status.GetStatus(&status.Flags{
+		RebootMarkerFile: f.RebootMarkerFile,
+		JournalFile:      f.JournalFile,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return marshalJSON(s)
+}
+
+// collectJournal returns the last 'n' entries of the journal at 'path'.
+func collectJournal(path string, n int) ([]byte, error) {
+	entries, err := journal.Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > n {
+		entries = entries[len(entries)-n:]
+	}
+	if entries == nil {
+		entries = []journal.Entry{}
+	}
+	return marshalJSON(entries)
+}
+
+// runCommand runs 'name' and returns its combined output, even if it fails.
+func runCommand(name string, args ...string) ([]byte, error) {
+	output, err := exec.Command(name, args...).CombinedOutput() //nolint:gosec
+	if err != nil {
+		return output, fmt.Errorf("error running '%v': %w", strings.Join(append([]string{name}, args...), " "), err)
+	}
+	return output, nil
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	output, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling to JSON: %w", err)
+	}
+	return append(output, '\n'), nil
+}
@@ -0,0 +1,624 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/fabric"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/schedule"
+	"github.com/NVIDIA/mig-parted/pkg/scratch"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+var log = logrus.New()
+
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	DefaultWatchInterval       = 10 * time.Second
+	DefaultScratchReapInterval = time.Minute
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'daemon' subcommand.
+type Flags struct {
+	apply.Flags
+	WatchConfig    bool
+	WatchInterval  time.Duration
+	MetricsAddress string
+	LedgerAddress  string
+	EventsAddress  string
+	ScheduleFile   string
+
+	ScratchReapInterval time.Duration
+}
+
+// daemon re-applies the selected MIG config whenever it is told to reload or
+// notices that the config file has changed.
+type daemon struct {
+	configFile string
+	// apply applies 'selectedConfig' (or the one selected by the flags if
+	// empty), recording 'trigger' as what made the apply, if set.
+	apply      func(selectedConfig string, trigger string) error
+	reload     <-chan os.Signal
+	stop       <-chan os.Signal
+	watch      <-chan time.Time
+	configHash [sha256.Size]byte
+
+	// retry fires when a hook that vetoed the last apply asked for it to be
+	// retried. It is created by 'after', which defaults to time.After.
+	retry <-chan time.Time
+	after func(time.Duration) <-chan time.Time
+
+	// reap fires when it is time to destroy the scratch GPU instances whose
+	// TTL has passed with 'reapScratch', which returns how many it destroyed.
+	reap        <-chan time.Time
+	reapScratch func() (int, error)
+
+	// schedule switches the selected MIG config to that of its 'active'
+	// entry (if any) when 'nextSwitch' fires, once 'preSwitch' allows it.
+	// 'trigger' describes the switch until it is applied. The current time
+	// is read from 'now', which defaults to time.Now.
+	schedule   *schedule.Schedule
+	active     *schedule.Entry
+	trigger    string
+	nextSwitch <-chan time.Time
+	preSwitch  func(from, to *schedule.Entry, trigger string) error
+	now        func() time.Time
+}
+
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	daemonFlags := Flags{}
+
+	// Create the 'daemon' command
+	daemon := cli.Command{}
+	daemon.Name = "daemon"
+	daemon.Usage = "Apply a MIG configuration and keep running, re-applying it on SIGHUP or when the configuration file changes"
+	daemon.Action = func(c *cli.Context) error {
+		return daemonWrapper(c, &daemonFlags)
+	}
+
+	// Setup the flags for this command
+	daemon.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the configuration file, or a directory of spec fragments to merge",
+			Destination: &daemonFlags.ConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "selected-config",
+			Aliases:     []string{"c"},
+			Usage:       "The label of the mig-config from the config file to apply to the node",
+			Destination: &daemonFlags.SelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+		},
+		util.SelectedConfigFromFlag(&daemonFlags.SelectedConfigFrom),
+		&cli.StringFlag{
+			Name:        "trusted-keys",
+			Usage:       "Path to a public key (or a directory of them) that config files must be signed with ('cosign' PEM or 'minisign' keys)",
+			Destination: &daemonFlags.TrustedKeys,
+			EnvVars:     []string{"MIG_PARTED_TRUSTED_KEYS"},
+		},
+		&cli.StringFlag{
+			Name:        "config-signature",
+			Usage:       "Path to the detached signature of the config file (defaults to the config file path with '.sig' appended)",
+			Destination: &daemonFlags.ConfigSignature,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_SIGNATURE"},
+		},
+		&cli.BoolFlag{
+			Name:        "strict-profiles",
+			Usage:       "Reject MIG profiles in config files that are not written in their canonical form (e.g. '1G.5GB' or '01g.5gb')",
+			Destination: &daemonFlags.StrictProfiles,
+			EnvVars:     []string{"MIG_PARTED_STRICT_PROFILES"},
+		},
+		&cli.StringFlag{
+			Name:        "hooks-file",
+			Aliases:     []string{"k"},
+			Usage:       "Path to the hooks file",
+			Destination: &daemonFlags.HooksFile,
+			EnvVars:     []string{"MIG_PARTED_HOOKS_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "skip-reset",
+			Aliases:     []string{"s"},
+			Usage:       "Skip the GPU reset operation after applying the desired MIG mode to all GPUs",
+			Destination: &daemonFlags.SkipReset,
+			EnvVars:     []string{"MIG_PARTED_SKIP_RESET"},
+		},
+		&cli.BoolFlag{
+			Name:        "enable-gpu-reset",
+			Usage:       "Reset GPUs pending a row remapping or page retirement before reconfiguring them, rather than refusing to",
+			Destination: &daemonFlags.EnableGPUReset,
+			EnvVars:     []string{"MIG_PARTED_ENABLE_GPU_RESET"},
+		},
+		&cli.BoolFlag{
+			Name:        "keep-going",
+			Usage:       "Continue with the remaining GPUs if a GPU falls off the bus while applying the MIG config",
+			Destination: &daemonFlags.KeepGoing,
+			EnvVars:     []string{"MIG_PARTED_KEEP_GOING"},
+		},
+		&cli.StringFlag{
+			Name:        "policy-file",
+			Usage:       "Path to a policy file restricting which MIG configs may be applied",
+			Destination: &daemonFlags.PolicyFile,
+			EnvVars:     []string{"MIG_PARTED_POLICY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Usage:       "Path to write a JSON marker to when a reboot is required for a MIG mode change to take effect (disabled if empty)",
+			Destination: &daemonFlags.RebootMarkerFile,
+			Value:       reboot.DefaultMarkerFile,
+			EnvVars:     []string{"MIG_PARTED_REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "journal-file",
+			Usage:       "Path to the journal to record the outcome of each apply in, as reported by 'status' (disabled if empty)",
+			Destination: &daemonFlags.JournalFile,
+			Value:       journal.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_JOURNAL_FILE"},
+		},
+		&cli.IntFlag{
+			Name:        "fabric-partition",
+			Usage:       "Only apply the MIG config to the GPUs of this Fabric Manager partition, treating all other GPUs as unmanaged (-1 for all GPUs)",
+			Destination: &daemonFlags.FabricPartition,
+			Value:       -1,
+			EnvVars:     []string{"MIG_PARTED_FABRIC_PARTITION"},
+		},
+		&cli.StringFlag{
+			Name:        "fabric-coordination",
+			Usage:       "How to detach GPUs from the NVSwitch fabric while changing MIG mode: [partition | service | none]",
+			Destination: &daemonFlags.FabricCoordination,
+			Value:       apply.FabricCoordinationPartition,
+			EnvVars:     []string{"MIG_PARTED_FABRIC_COORDINATION"},
+		},
+		&cli.StringFlag{
+			Name:        "fmpm-path",
+			Usage:       "Path to the Fabric Manager partition manager CLI used with '--fabric-partition'",
+			Destination: &daemonFlags.FmpmPath,
+			Value:       fabric.DefaultFmpmPath,
+			EnvVars:     []string{"MIG_PARTED_FMPM_PATH"},
+		},
+		&cli.StringFlag{
+			Name:        "device-order",
+			Usage:       "Order in which to walk the GPUs on the node: [index | pci | uuid-file]",
+			Destination: &daemonFlags.DeviceOrder,
+			Value:       apply.DeviceOrderIndex,
+			EnvVars:     []string{"MIG_PARTED_DEVICE_ORDER"},
+		},
+		&cli.StringFlag{
+			Name:        "device-order-file",
+			Usage:       "Path to the file listing GPU UUIDs (one per line) in the order to walk them with '--device-order=uuid-file'",
+			Destination: &daemonFlags.DeviceOrderFile,
+			EnvVars:     []string{"MIG_PARTED_DEVICE_ORDER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "telemetry-sink",
+			Usage:       "Opt-in sink for anonymized apply events ('file:///path' or 'http(s)://...', disabled if empty)",
+			Destination: &daemonFlags.TelemetrySink,
+			EnvVars:     []string{"MIG_PARTED_TELEMETRY_SINK"},
+		},
+		&cli.IntFlag{
+			Name:        "max-utilization",
+			Usage:       "Refuse to change GPUs while their utilization, or that of one of their MIG devices, exceeds this percentage (-1 to disable)",
+			Destination: &daemonFlags.MaxUtilization,
+			Value:       -1,
+			EnvVars:     []string{"MIG_PARTED_MAX_UTILIZATION"},
+		},
+		&cli.DurationFlag{
+			Name:        "utilization-window",
+			Usage:       "How long to sample the utilization of the GPUs to change for with '--max-utilization'",
+			Destination: &daemonFlags.UtilizationWindow,
+			Value:       5 * time.Second,
+			EnvVars:     []string{"MIG_PARTED_UTILIZATION_WINDOW"},
+		},
+		&cli.DurationFlag{
+			Name:        "utilization-wait",
+			Usage:       "How long to keep sampling for the GPUs to change to become idle enough with '--max-utilization' before refusing (0 to refuse right away)",
+			Destination: &daemonFlags.UtilizationWait,
+			EnvVars:     []string{"MIG_PARTED_UTILIZATION_WAIT"},
+		},
+		util.TextfileDirFlag(&daemonFlags.TextfileDir),
+		&cli.StringFlag{
+			Name:        "unhealthy-gpus",
+			Usage:       "What to do with GPUs whose health signals are past their thresholds: [ignore | exclude | refuse]",
+			Destination: &daemonFlags.UnhealthyGPUs,
+			Value:       apply.UnhealthyGPUsIgnore,
+			EnvVars:     []string{"MIG_PARTED_UNHEALTHY_GPUS"},
+		},
+		&cli.Uint64Flag{
+			Name:        "max-ecc-errors",
+			Usage:       "Number of uncorrectable ECC errors since the driver was loaded past which a GPU is unhealthy",
+			Destination: &daemonFlags.MaxECCErrors,
+			EnvVars:     []string{"MIG_PARTED_MAX_ECC_ERRORS"},
+		},
+		&cli.IntFlag{
+			Name:        "max-retired-pages",
+			Usage:       "Number of retired memory pages past which a GPU is unhealthy",
+			Destination: &daemonFlags.MaxRetiredPages,
+			Value:       apply.DefaultMaxRetiredPages,
+			EnvVars:     []string{"MIG_PARTED_MAX_RETIRED_PAGES"},
+		},
+		&cli.BoolFlag{
+			Name:        "ignore-thermal-throttle",
+			Usage:       "Do not consider GPUs whose clocks are throttled for being too hot unhealthy",
+			Destination: &daemonFlags.IgnoreThermalThrottle,
+			EnvVars:     []string{"MIG_PARTED_IGNORE_THERMAL_THROTTLE"},
+		},
+		util.NodeLockFileFlag(&daemonFlags.NodeLockFile),
+		util.NodeLockTimeoutFlag(&daemonFlags.NodeLockTimeout),
+		&cli.BoolFlag{
+			Name:        "watch-config",
+			Aliases:     []string{"w"},
+			Usage:       "Re-apply the selected MIG config whenever the configuration file changes",
+			Destination: &daemonFlags.WatchConfig,
+			EnvVars:     []string{"MIG_PARTED_WATCH_CONFIG"},
+		},
+		&cli.DurationFlag{
+			Name:        "watch-interval",
+			Usage:       "How often to check the configuration file for changes when 'watch-config' is set",
+			Destination: &daemonFlags.WatchInterval,
+			Value:       DefaultWatchInterval,
+			EnvVars:     []string{"MIG_PARTED_WATCH_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "schedule-file",
+			Usage:       "Path to a schedule file of the MIG configs to switch to, and when, instead of the selected config",
+			Destination: &daemonFlags.ScheduleFile,
+			EnvVars:     []string{"MIG_PARTED_SCHEDULE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "metrics-address",
+			Usage:       "Address to serve placement metrics on in the Prometheus format (disabled if empty)",
+			Destination: &daemonFlags.MetricsAddress,
+			EnvVars:     []string{"MIG_PARTED_METRICS_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "ledger-address",
+			Usage:       "Address to serve the allocation ledger API on, for external schedulers to reserve MIG devices so they are never destroyed (disabled if empty)",
+			Destination: &daemonFlags.LedgerAddress,
+			EnvVars:     []string{"MIG_PARTED_LEDGER_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "events-address",
+			Usage:       "Address to stream the MIG devices created and destroyed by each apply on, as server-sent events (disabled if empty)",
+			Destination: &daemonFlags.EventsAddress,
+			EnvVars:     []string{"MIG_PARTED_EVENTS_ADDRESS"},
+		},
+		&cli.StringFlag{
+			Name:        "drift-state-file",
+			Usage:       "Path to the state file 'assert --drift-state-file' records the outcome of each drift check in, reported with the metrics",
+			Destination: &daemonFlags.DriftStateFile,
+			Value:       drift.DefaultStateFile,
+			EnvVars:     []string{"MIG_PARTED_DRIFT_STATE_FILE"},
+		},
+		&cli.DurationFlag{
+			Name:        "scratch-reap-interval",
+			Usage:       "How often to destroy the scratch GPU instances whose TTL has passed, reconciling back to the selected MIG config (0 to disable)",
+			Destination: &daemonFlags.ScratchReapInterval,
+			Value:       DefaultScratchReapInterval,
+			EnvVars:     []string{"MIG_PARTED_SCRATCH_REAP_INTERVAL"},
+		},
+		&cli.StringFlag{
+			Name:        "store-file",
+			Usage:       "Path to the store the labels of the applied MIG config, the leases of scratch GPU instances and the reservations of the allocation ledger are kept in",
+			Destination: &daemonFlags.StoreFile,
+			Value:       store.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
+		},
+	}
+
+	return &daemon
+}
+
+func daemonWrapper(c *cli.Context, f *Flags) error {
+	f.OutputFormat = apply.TextFormat
+	f.AssumeYes = true
+
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	defer signal.Stop(reload)
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	if f.MetricsAddress != "" {
+		defer serveMetrics(f.MetricsAddress, f.DriftStateFile)()
+	}
+
+	if f.LedgerAddress != "" {
+		defer serveLedger(f.LedgerAddress, f.StoreFile)()
+	}
+
+	// Without a server to stream them, changes are not tracked at all.
+	track := func(selectedConfig string, change func() error) error { return change() }
+	if f.EventsAddress != "" {
+		broker := newEventBroker()
+		defer serveEvents(f.EventsAddress, broker)()
+		track = func(selectedConfig string, change func() error) error {
+			return broker.track(selectedConfig, snapshotNode, change)
+		}
+	}
+
+	var watch <-chan time.Time
+	if f.WatchConfig {
+		ticker := time.NewTicker(f.WatchInterval)
+		defer ticker.Stop()
+		watch = ticker.C
+	}
+
+	var reap <-chan time.Time
+	if f.ScratchReapInterval > 0 {
+		ticker := time.NewTicker(f.ScratchReapInterval)
+		defer ticker.Stop()
+		reap = ticker.C
+	}
+
+	var s *schedule.Schedule
+	if f.ScheduleFile != "" {
+		s, err = ParseScheduleFile(f.ScheduleFile)
+		if err != nil {
+			return fmt.Errorf("error parsing schedule file: %v", err)
+		}
+	}
+
+	d := daemon{
+		configFile: f.ConfigFile,
+		reload:     reload,
+		stop:       stop,
+		watch:      watch,
+		apply: func(selectedConfig string, trigger string) error {
+			// Work on a copy of the flags so that state derived from one
+			// version of the config file (e.g. a defaulted selected-config)
+			// does not leak into the next.
+			flags := f.Flags
+			if selectedConfig != "" {
+				flags.SelectedConfig = selectedConfig
+			}
+			flags.Trigger = trigger
+			return track(flags.SelectedConfig, func() error {
+				results, err := apply.Apply(c, &flags)
+				for _, r := range results {
+					if r.Error != "" {
+						log.Errorf("Failed to apply MIG config on GPU %d: %v", r.GPU, r.Error)
+						continue
+					}
+					log.Infof("Created %d MIG devices on GPU %d", len(r.MigDevices), r.GPU)
+				}
+				return err
+			})
+		},
+		reap: reap,
+		reapScratch: func() (int, error) {
+			var reaped int
+			err := track("", func() error {
+				unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+				if err != nil {
+					return err
+				}
+				defer unlock()
+				reaped, err = reapScratch(f.StoreFile)
+				return err
+			})
+			return reaped, err
+		},
+		schedule: s,
+		preSwitch: func(from, to *schedule.Entry, trigger string) error {
+			return runPreSwitchHooks(c, f, selectedConfig(from, f.SelectedConfig), selectedConfig(to, f.SelectedConfig), trigger)
+		},
+	}
+
+	return d.run()
+}
+
+// reapScratch destroys the scratch GPU instances recorded in the store at
+// 'storeFile' whose TTL has passed, and returns how many it destroyed.
+func reapScratch(storeFile string) (int, error) {
+	s, err := store.Open(storeFile)
+	if err != nil {
+		return 0, err
+	}
+	defer s.Close()
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return 0, err
+	}
+
+	uuids := func(gpu int) (map[[2]uint32]string, error) {
+		return migDeviceUUIDs(nvml.New(), gpu)
+	}
+	reaped, held, err := scratch.NewRegistry(s).Reap(manager, uuids)
+	for _, l := range reaped {
+		util.AuditGPUs(log, "scratch reap", fmt.Sprintf("GPU instance %d", l.GpuInstance.ID), []int{l.GPU}, nil)
+		log.Infof("Destroyed scratch GPU instance %d of '%v' on GPU %d, expired at %v", l.GpuInstance.ID, l.Owner, l.GPU, l.Expires.Format(time.RFC3339))
+	}
+	for _, h := range held {
+		log.Infof("Not destroying scratch GPU instance %d of '%v' on GPU %d, expired at %v: MIG device %v is reserved by '%v'", h.GpuInstance.ID, h.Owner, h.GPU, h.Expires.Format(time.RFC3339), h.Reservation.UUID, h.Reservation.Holder)
+	}
+	return len(reaped), err
+}
+
+func CheckFlags(f *Flags) error {
+	err := apply.CheckFlags(&f.Flags)
+	if err != nil {
+		return err
+	}
+	if util.IsStdio(f.ConfigFile) || util.IsStdio(f.HooksFile) || util.IsStdio(f.ScheduleFile) {
+		return fmt.Errorf("the daemon cannot read its configuration from stdin")
+	}
+	if f.WatchConfig && f.WatchInterval <= 0 {
+		return fmt.Errorf("invalid 'watch-interval': %v", f.WatchInterval)
+	}
+	if f.LedgerAddress != "" && f.StoreFile == "" {
+		return fmt.Errorf("the allocation ledger requires a 'store-file' to keep reservations in")
+	}
+	if f.ScratchReapInterval < 0 {
+		return fmt.Errorf("invalid 'scratch-reap-interval': %v", f.ScratchReapInterval)
+	}
+	return nil
+}
+
+// run applies the selected MIG config once and then again on every reload
+// (or config file change) until told to stop. Failures to apply are logged
+// rather than returned so that a bad config push can be fixed by another.
+// Destroying expired scratch GPU instances also triggers an apply, to
+// reconcile the GPUs they were on back to the selected MIG config. With a
+// schedule, the MIG config of the entry in effect is applied instead, and
+// the daemon switches between them as the schedule says.
+func (d *daemon) run() error {
+	d.startSchedule()
+	d.reconcile("Applying initial MIG configuration")
+
+	for {
+		select {
+		case <-d.stop:
+			log.Info("Shutting down")
+			return nil
+		case <-d.reload:
+			d.reconcile("Received SIGHUP, reloading MIG configuration")
+		case <-d.retry:
+			d.reconcile("Retrying MIG configuration as requested by hook")
+		case <-d.nextSwitch:
+			d.switchConfig()
+		case <-d.watch:
+			changed, err := d.configChanged()
+			if err != nil {
+				log.Errorf("Error checking configuration file for changes: %v", err)
+				continue
+			}
+			if changed {
+				d.reconcile("Configuration file changed, reloading MIG configuration")
+			}
+		case <-d.reap:
+			reaped, err := d.reapScratch()
+			if err != nil {
+				log.Errorf("Error reaping scratch GPU instances: %v", err)
+			}
+			if reaped > 0 {
+				d.reconcile("Reaped expired scratch GPU instances, reconciling MIG configuration")
+			}
+		}
+	}
+}
+
+func (d *daemon) reconcile(reason string) {
+	log.Info(reason)
+
+	// Record the config we are about to apply so that a subsequent change
+	// is detected relative to it, even if applying it fails.
+	if _, err := d.configChanged(); err != nil {
+		log.Errorf("Error reading configuration file: %v", err)
+	}
+
+	// Any pending retry is superseded by this apply.
+	d.retry = nil
+
+	err := d.apply(selectedConfig(d.active, ""), d.trigger)
+	var veto *hooks.VetoError
+	if errors.As(err, &veto) && veto.RetryAfter() > 0 {
+		log.Warnf("MIG configuration not applied: %v; retrying in %v", veto, veto.RetryAfter())
+		d.retry = d.afterFunc()(veto.RetryAfter())
+		return
+	}
+	// The retry of a switch is still part of it; anything else is not.
+	d.trigger = ""
+	if veto != nil {
+		log.Warnf("MIG configuration not applied: %v", veto)
+		return
+	}
+	if err != nil {
+		log.Errorf("Error applying MIG configuration: %v", err)
+		return
+	}
+	log.Info("MIG configuration applied successfully")
+}
+
+// afterFunc returns the function creating the timers of the daemon.
+func (d *daemon) afterFunc() func(time.Duration) <-chan time.Time {
+	if d.after == nil {
+		return time.After
+	}
+	return d.after
+}
+
+// clock returns the current time.
+func (d *daemon) clock() time.Time {
+	if d.now == nil {
+		return time.Now()
+	}
+	return d.now()
+}
+
+// configChanged checks whether the contents of the config file differ from
+// the last time it was checked. For a config directory, the names and
+// contents of all of its fragments are checked.
+func (d *daemon) configChanged() (bool, error) {
+	paths := []string{d.configFile}
+	if util.IsDir(d.configFile) {
+		var err error
+		paths, err = util.ConfigFragmentPaths(d.configFile)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	h := sha256.New()
+	for _, path := range paths {
+		contents, err := util.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", path, len(contents))
+		h.Write(contents)
+	}
+
+	var hash [sha256.Size]byte
+	copy(hash[:], h.Sum(nil))
+	if hash == d.configHash {
+		return false, nil
+	}
+	d.configHash = hash
+	return true, nil
+}
@@ -0,0 +1,345 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/pkg/schedule"
+)
+
+func TestDaemonRun(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte("version: v1\n"), 0644))
+
+	reload := make(chan os.Signal)
+	stop := make(chan os.Signal)
+	watch := make(chan time.Time)
+	applied := make(chan struct{}, 10)
+
+	d := daemon{
+		configFile: configFile,
+		reload:     reload,
+		stop:       stop,
+		watch:      watch,
+		apply: func(string, string) error {
+			applied <- struct{}{}
+			return fmt.Errorf("failures should not stop the daemon")
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- d.run()
+	}()
+
+	// The initial apply.
+	<-applied
+
+	// An unchanged config file does not trigger an apply.
+	watch <- time.Now()
+
+	// A SIGHUP always triggers an apply.
+	reload <- syscall.SIGHUP
+	<-applied
+
+	// A changed config file triggers an apply.
+	require.Nil(t, os.WriteFile(configFile, []byte("version: v1\nmig-configs: {}\n"), 0644))
+	watch <- time.Now()
+	<-applied
+
+	stop <- syscall.SIGTERM
+	require.Nil(t, <-done)
+	require.Len(t, applied, 0, "Unexpected extra apply")
+}
+
+func TestDaemonRetryOnVeto(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte("version: v1\n"), 0644))
+
+	reload := make(chan os.Signal)
+	stop := make(chan os.Signal)
+	retry := make(chan time.Time)
+	applied := make(chan struct{}, 10)
+	scheduled := make(chan time.Duration, 10)
+
+	vetoes := []error{
+		&hooks.VetoError{Hook: "apply-start", Result: hooks.Result{Decision: hooks.DecisionRetry, RetryAfter: "5m"}},
+		&hooks.VetoError{Hook: "apply-start", Result: hooks.Result{Decision: hooks.DecisionDeny}},
+	}
+
+	d := daemon{
+		configFile: configFile,
+		reload:     reload,
+		stop:       stop,
+		apply: func(string, string) error {
+			applied <- struct{}{}
+			if len(vetoes) == 0 {
+				return nil
+			}
+			err := vetoes[0]
+			vetoes = vetoes[1:]
+			return fmt.Errorf("error applying MIG configuration with hooks: %w", err)
+		},
+		after: func(d time.Duration) <-chan time.Time {
+			scheduled <- d
+			return retry
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- d.run()
+	}()
+
+	// The initial apply is vetoed with a request to retry.
+	<-applied
+	require.Equal(t, 5*time.Minute, <-scheduled)
+
+	// The retry is denied outright, so no further retry is scheduled.
+	retry <- time.Now()
+	<-applied
+
+	// A SIGHUP still triggers an apply, which succeeds.
+	reload <- syscall.SIGHUP
+	<-applied
+
+	stop <- syscall.SIGTERM
+	require.Nil(t, <-done)
+	require.Len(t, applied, 0, "Unexpected extra apply")
+	require.Len(t, scheduled, 0, "Unexpected extra retry")
+}
+
+func TestDaemonReapScratch(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte("version: v1\n"), 0644))
+
+	stop := make(chan os.Signal)
+	reap := make(chan time.Time)
+	applied := make(chan struct{}, 10)
+	reaped := []int{0, 2}
+
+	d := daemon{
+		configFile: configFile,
+		stop:       stop,
+		reap:       reap,
+		apply: func(string, string) error {
+			applied <- struct{}{}
+			return nil
+		},
+		reapScratch: func() (int, error) {
+			n := reaped[0]
+			reaped = reaped[1:]
+			return n, nil
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- d.run()
+	}()
+
+	// The initial apply.
+	<-applied
+
+	// Nothing reaped does not trigger an apply.
+	reap <- time.Now()
+
+	// Reaping scratch GPU instances reconciles back to the selected config.
+	reap <- time.Now()
+	<-applied
+
+	stop <- syscall.SIGTERM
+	require.Nil(t, <-done)
+	require.Len(t, applied, 0, "Unexpected extra apply")
+}
+
+func TestDaemonSchedule(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, os.WriteFile(configFile, []byte("version: v1\n"), 0644))
+
+	s, err := schedule.Parse([]byte(`
+version: v1
+schedule:
+- name: day
+  cron: "0 8 * * *"
+  selected-config: all-1g.10gb
+- name: night
+  cron: "0 20 * * *"
+  selected-config: all-7g.80gb
+  max-lifetime: 10h
+`))
+	require.Nil(t, err)
+
+	type applied struct {
+		selectedConfig string
+		trigger        string
+	}
+
+	now := time.Date(2024, 3, 6, 12, 0, 0, 0, time.UTC)
+	stop := make(chan os.Signal)
+	nextSwitch := make(chan time.Time)
+	applies := make(chan applied, 10)
+	scheduled := make(chan time.Duration, 10)
+	switches := make(chan string, 10)
+	vetoes := []error{
+		&hooks.VetoError{Hook: PreSwitchHook, Result: hooks.Result{Decision: hooks.DecisionRetry, RetryAfter: "5m"}},
+	}
+
+	d := daemon{
+		configFile: configFile,
+		stop:       stop,
+		apply: func(selectedConfig string, trigger string) error {
+			applies <- applied{selectedConfig, trigger}
+			return nil
+		},
+		schedule: s,
+		preSwitch: func(from, to *schedule.Entry, trigger string) error {
+			switches <- trigger
+			if len(vetoes) == 0 {
+				return nil
+			}
+			err := vetoes[0]
+			vetoes = vetoes[1:]
+			return fmt.Errorf("error running pre-switch hook: %w", err)
+		},
+		now: func() time.Time {
+			return now
+		},
+		after: func(d time.Duration) <-chan time.Time {
+			scheduled <- d
+			return nextSwitch
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- d.run()
+	}()
+
+	// The initial apply is of the entry in effect, with the switch to the
+	// next one 8 hours later.
+	require.Equal(t, 8*time.Hour, <-scheduled)
+	a := <-applies
+	require.Equal(t, "all-1g.10gb", a.selectedConfig)
+	require.Contains(t, a.trigger, "schedule entry 'day'")
+
+	// The switch to the night is vetoed with a request to retry.
+	now = now.Add(8 * time.Hour)
+	nextSwitch <- now
+	require.Contains(t, <-switches, "from 'day' (all-1g.10gb) to 'night' (all-7g.80gb)")
+	require.Equal(t, 5*time.Minute, <-scheduled)
+
+	// The retry switches, and the max lifetime of the night is next.
+	now = now.Add(5 * time.Minute)
+	nextSwitch <- now
+	<-switches
+	require.Equal(t, 10*time.Hour-5*time.Minute, <-scheduled)
+	a = <-applies
+	require.Equal(t, "all-7g.80gb", a.selectedConfig)
+	require.Contains(t, a.trigger, "scheduled switch from 'day'")
+
+	// Once it is over, the default config is switched back to.
+	now = now.Add(10*time.Hour - 5*time.Minute)
+	nextSwitch <- now
+	require.Contains(t, <-switches, "to the default config")
+	require.Equal(t, 2*time.Hour, <-scheduled)
+	a = <-applies
+	require.Equal(t, "", a.selectedConfig)
+
+	stop <- syscall.SIGTERM
+	require.Nil(t, <-done)
+	require.Len(t, applies, 0, "Unexpected extra apply")
+	require.Len(t, switches, 0, "Unexpected extra switch")
+}
+
+func TestCheckFlags(t *testing.T) {
+	newFlags := func(configFile string, watch bool, interval time.Duration) Flags {
+		f := Flags{
+			WatchConfig:   watch,
+			WatchInterval: interval,
+		}
+		f.ConfigFile = configFile
+		f.OutputFormat = "text"
+		f.FabricPartition = -1
+		f.MaxUtilization = -1
+		f.FabricCoordination = apply.FabricCoordinationPartition
+		f.DeviceOrder = apply.DeviceOrderIndex
+		f.UnhealthyGPUs = apply.UnhealthyGPUsIgnore
+		return f
+	}
+
+	testCases := []struct {
+		description     string
+		flags           Flags
+		expectedFailure bool
+	}{
+		{
+			"Valid",
+			newFlags("config.yaml", true, time.Second),
+			false,
+		},
+		{
+			"Config from stdin",
+			newFlags("-", false, 0),
+			true,
+		},
+		{
+			"Schedule from stdin",
+			func() Flags {
+				f := newFlags("config.yaml", false, 0)
+				f.ScheduleFile = "-"
+				return f
+			}(),
+			true,
+		},
+		{
+			"Invalid watch interval",
+			newFlags("config.yaml", true, 0),
+			true,
+		},
+		{
+			"Invalid scratch reap interval",
+			func() Flags {
+				f := newFlags("config.yaml", false, 0)
+				f.ScratchReapInterval = -time.Minute
+				return f
+			}(),
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := CheckFlags(&tc.flags)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from CheckFlags")
+			} else {
+				require.Nil(t, err, "Unexpected failure from CheckFlags")
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const eventsPath = "/v1/events"
+
+// Types of ChangeEvent.
+const (
+	EventCreated   = "created"
+	EventDestroyed = "destroyed"
+	// EventResync tells subscribers the MIG devices of the node may have
+	// changed in ways not reported, so they must enumerate them again.
+	EventResync = "resync"
+)
+
+// eventBuffer is how many events a subscriber may fall behind by before it
+// is disconnected.
+const eventBuffer = 256
+
+// ChangeEvent describes a MIG device created or destroyed by an apply of the
+// daemon.
+type ChangeEvent struct {
+	ID             uint64    `json:"id"`
+	Type           string    `json:"type"`
+	Time           time.Time `json:"time"`
+	SelectedConfig string    `json:"selected-config,omitempty"`
+	GPU            int       `json:"gpu"`
+	UUID           string    `json:"uuid,omitempty"`
+	*types.MigDevice
+}
+
+// migDeviceState is a MIG device along with the GPU it is on and its UUID.
+type migDeviceState struct {
+	gpu    int
+	uuid   string
+	device types.MigDevice
+}
+
+// diffMigDevices returns the events turning the MIG devices in 'before' into
+// those in 'after', matching devices by UUID: first the destroyed devices,
+// then the created ones, each by GPU and placement.
+func diffMigDevices(before, after []migDeviceState) []ChangeEvent {
+	present := func(states []migDeviceState) map[string]bool {
+		uuids := make(map[string]bool)
+		for _, s := range states {
+			uuids[s.uuid] = true
+		}
+		return uuids
+	}
+	changes := func(eventType string, states []migDeviceState, other map[string]bool) []ChangeEvent {
+		var events []ChangeEvent
+		for i := range states {
+			s := &states[i]
+			if other[s.uuid] {
+				continue
+			}
+			events = append(events, ChangeEvent{Type: eventType, GPU: s.gpu, UUID: s.uuid, MigDevice: &s.device})
+		}
+		sort.SliceStable(events, func(i, j int) bool {
+			return events[i].GPU < events[j].GPU
+		})
+		return events
+	}
+
+	events := changes(EventDestroyed, before, present(after))
+	return append(events, changes(EventCreated, after, present(before))...)
+}
+
+// snapshotMigDevices returns the MIG devices on the MIG enabled GPUs of the
+// node, ordered by GPU and placement.
+func snapshotMigDevices(nvmlLib nvml.Interface, manager config.Manager) ([]migDeviceState, error) {
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	var states []migDeviceState
+	for i := 0; i < count; i++ {
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle of GPU %d: %v", i, ret)
+		}
+		mode, _, ret := device.GetMigMode()
+		if ret == nvml.ERROR_NOT_SUPPORTED || (ret == nvml.SUCCESS && mode != nvml.DEVICE_MIG_ENABLE) {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting MIG mode of GPU %d: %v", i, ret)
+		}
+		uuids, err := util.GetMigDeviceUUIDs(device)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG device UUIDs of GPU %d: %w", i, err)
+		}
+		devices, err := manager.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices of GPU %d: %w", i, err)
+		}
+		for _, d := range devices {
+			states = append(states, migDeviceState{
+				gpu:    i,
+				uuid:   uuids[[2]uint32{d.GpuInstanceID, d.ComputeInstanceID}],
+				device: d,
+			})
+		}
+	}
+	return states, nil
+}
+
+// eventBroker fans the change events of the daemon out to its subscribers.
+type eventBroker struct {
+	sync.Mutex
+	lastID      uint64
+	subscribers map[chan ChangeEvent]struct{}
+	now         func() time.Time
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subscribers: make(map[chan ChangeEvent]struct{}),
+		now:         time.Now,
+	}
+}
+
+// subscribe returns a channel receiving every event published from now on,
+// and the function to unsubscribe it again. The channel is closed if the
+// subscriber falls too far behind, so it learns that it missed events.
+func (b *eventBroker) subscribe() (<-chan ChangeEvent, func()) {
+	b.Lock()
+	defer b.Unlock()
+	events := make(chan ChangeEvent, eventBuffer)
+	b.subscribers[events] = struct{}{}
+	return events, func() {
+		b.Lock()
+		defer b.Unlock()
+		if _, exists := b.subscribers[events]; exists {
+			delete(b.subscribers, events)
+			close(events)
+		}
+	}
+}
+
+// close disconnects every subscriber.
+func (b *eventBroker) close() {
+	b.Lock()
+	defer b.Unlock()
+	for subscriber := range b.subscribers {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
+
+// publish numbers and timestamps 'events' and sends them to every
+// subscriber.
+func (b *eventBroker) publish(selectedConfig string, events []ChangeEvent) {
+	b.Lock()
+	defer b.Unlock()
+	now := b.now()
+	for _, e := range events {
+		b.lastID++
+		e.ID = b.lastID
+		e.Time = now
+		e.SelectedConfig = selectedConfig
+		for subscriber := range b.subscribers {
+			select {
+			case subscriber <- e:
+			default:
+				log.Warnf("Disconnecting change event subscriber that fell %d events behind", eventBuffer)
+				delete(b.subscribers, subscriber)
+				close(subscriber)
+			}
+		}
+	}
+}
+
+// track snapshots the MIG devices of the node with 'snapshot' before and
+// after calling 'change', and publishes the changes between the two. If
+// either snapshot fails, a single EventResync is published instead.
+func (b *eventBroker) track(selectedConfig string, snapshot func() ([]migDeviceState, error), change func() error) error {
+	before, berr := snapshot()
+	err := change()
+	after, aerr := snapshot()
+	if berr == nil {
+		berr = aerr
+	}
+	if berr != nil {
+		log.Warnf("Unable to tell which MIG devices changed, asking subscribers to resync: %v", berr)
+		b.publish(selectedConfig, []ChangeEvent{{Type: EventResync, GPU: -1}})
+		return err
+	}
+	b.publish(selectedConfig, diffMigDevices(before, after))
+	return err
+}
+
+// newEventsHandler returns an 'http.Handler' streaming the events published
+// to 'broker' as server-sent events on '/v1/events'. Each event carries its
+// ID, its type as the event name and the ChangeEvent as JSON data. Only
+// events published after subscribing are sent, so subscribers enumerate the
+// MIG devices once they are connected, and again whenever they reconnect.
+func newEventsHandler(broker *eventBroker) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(eventsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming not supported", http.StatusInternalServerError)
+			return
+		}
+
+		events, unsubscribe := broker.subscribe()
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case e, ok := <-events:
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					log.Errorf("Error marshaling change event: %v", err)
+					return
+				}
+				fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+				flusher.Flush()
+			}
+		}
+	})
+	return mux
+}
+
+// serveEvents streams the events published to 'broker' on 'address' until
+// the returned function is called.
+func serveEvents(address string, broker *eventBroker) func() {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           newEventsHandler(broker),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infof("Serving change events on %v", address)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error serving change events: %v", err)
+		}
+	}()
+
+	return func() {
+		// Shutdown waits for the streams to end, which they only do once
+		// their subscribers are gone.
+		broker.close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
+
+// snapshotNode returns the MIG devices currently on the node.
+func snapshotNode() ([]migDeviceState, error) {
+	manager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %w", err)
+	}
+	return snapshotMigDevices(nvml.New(), manager)
+}
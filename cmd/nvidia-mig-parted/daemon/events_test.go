@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newMigDeviceState(gpu int, uuid string, profile string, start uint32) migDeviceState {
+	return migDeviceState{
+		gpu:  gpu,
+		uuid: uuid,
+		device: types.MigDevice{
+			Profile:              profile,
+			GpuInstancePlacement: nvml.GpuInstancePlacement{Start: start},
+		},
+	}
+}
+
+func TestDiffMigDevices(t *testing.T) {
+	before := []migDeviceState{
+		newMigDeviceState(0, "MIG-a", "3g.20gb", 0),
+		newMigDeviceState(0, "MIG-b", "3g.20gb", 4),
+		newMigDeviceState(1, "MIG-c", "7g.40gb", 0),
+	}
+	after := []migDeviceState{
+		newMigDeviceState(0, "MIG-b", "3g.20gb", 4),
+		newMigDeviceState(0, "MIG-d", "1g.5gb", 0),
+		newMigDeviceState(0, "MIG-e", "1g.5gb", 1),
+		newMigDeviceState(1, "MIG-c", "7g.40gb", 0),
+	}
+
+	var changes []string
+	for _, e := range diffMigDevices(before, after) {
+		changes = append(changes, fmt.Sprintf("%v GPU %d %v %v@%d", e.Type, e.GPU, e.UUID, e.Profile, e.GpuInstancePlacement.Start))
+	}
+	require.Equal(t, []string{
+		"destroyed GPU 0 MIG-a 3g.20gb@0",
+		"created GPU 0 MIG-d 1g.5gb@0",
+		"created GPU 0 MIG-e 1g.5gb@1",
+	}, changes)
+
+	require.Empty(t, diffMigDevices(after, after))
+}
+
+func TestEventsHandler(t *testing.T) {
+	broker := newEventBroker()
+	broker.now = func() time.Time { return time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC) }
+	server := httptest.NewServer(newEventsHandler(broker))
+	defer server.Close()
+
+	w := httptest.NewRecorder()
+	newEventsHandler(broker).ServeHTTP(w, httptest.NewRequest(http.MethodPost, eventsPath, nil))
+	require.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	resp, err := http.Get(server.URL + eventsPath)
+	require.Nil(t, err, "Unexpected failure subscribing to events")
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(resp.Body)
+	next := func() (string, string, ChangeEvent) {
+		var id, name string
+		var event ChangeEvent
+		for {
+			line, err := reader.ReadString('\n')
+			require.Nil(t, err, "Unexpected end of event stream")
+			line = strings.TrimSuffix(line, "\n")
+			switch {
+			case line == "":
+				return id, name, event
+			case strings.HasPrefix(line, "id: "):
+				id = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				name = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				require.Nil(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event))
+			}
+		}
+	}
+
+	snapshots := [][]migDeviceState{
+		{newMigDeviceState(0, "MIG-a", "7g.40gb", 0)},
+		{newMigDeviceState(0, "MIG-b", "3g.20gb", 4)},
+	}
+	snapshot := func() ([]migDeviceState, error) {
+		if len(snapshots) == 0 {
+			return nil, fmt.Errorf("NVML unavailable")
+		}
+		s := snapshots[0]
+		snapshots = snapshots[1:]
+		return s, nil
+	}
+	applied := false
+	err = broker.track("all-3g.20gb", snapshot, func() error {
+		applied = true
+		return nil
+	})
+	require.Nil(t, err, "Unexpected failure from track")
+	require.True(t, applied)
+
+	id, name, event := next()
+	require.Equal(t, "1", id)
+	require.Equal(t, EventDestroyed, name)
+	require.Equal(t, "MIG-a", event.UUID)
+	require.Equal(t, "7g.40gb", event.Profile)
+	require.Equal(t, "all-3g.20gb", event.SelectedConfig)
+	require.Equal(t, broker.now(), event.Time)
+
+	id, name, event = next()
+	require.Equal(t, "2", id)
+	require.Equal(t, EventCreated, name)
+	require.Equal(t, "MIG-b", event.UUID)
+	require.Equal(t, uint32(4), event.GpuInstancePlacement.Start)
+
+	err = broker.track("", snapshot, func() error { return fmt.Errorf("apply failed") })
+	require.NotNil(t, err, "Unexpected success from track")
+
+	id, name, event = next()
+	require.Equal(t, "3", id)
+	require.Equal(t, EventResync, name)
+	require.Nil(t, event.MigDevice)
+
+	broker.close()
+	_, err = reader.ReadString('\n')
+	require.Equal(t, io.EOF, err)
+}
@@ -0,0 +1,257 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+// reservationsPath is where the allocation ledger API is served.
+const reservationsPath = "/v1/reservations"
+
+// errNoMigDevice is returned by a MIG device lookup when no MIG device with
+// the requested UUID exists on the node.
+var errNoMigDevice = errors.New("no such MIG device")
+
+// reserveRequest is the body of a POST to reservationsPath. 'TTL' is a
+// duration such as '2h', or empty to hold the reservation until released.
+type reserveRequest struct {
+	UUID     string            `json:"uuid"`
+	Holder   string            `json:"holder"`
+	TTL      string            `json:"ttl,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// newLedgerHandler returns an 'http.Handler' serving the allocation ledger
+// kept in the store returned by 'open' (opened anew for each request, so
+// that applies can use it in between):
+//
+//	GET    /v1/reservations                     lists the active reservations
+//	POST   /v1/reservations                     reserves a MIG device
+//	DELETE /v1/reservations/<uuid>?holder=<h>   releases a MIG device
+//
+// Only existing MIG devices can be reserved; 'lookup' returns the GPU a MIG
+// device is on, or errNoMigDevice.
+func newLedgerHandler(open func() (store.Store, error), lookup func(uuid string) (int, error)) http.Handler {
+	var mu sync.Mutex
+	withLedger := func(w http.ResponseWriter, f func(l *ledger.Ledger)) {
+		mu.Lock()
+		defer mu.Unlock()
+		s, err := open()
+		if err != nil {
+			http.Error(w, fmt.Sprintf("error opening store: %v", err), http.StatusInternalServerError)
+			return
+		}
+		defer s.Close()
+		f(ledger.NewLedger(s))
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(reservationsPath, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			withLedger(w, func(l *ledger.Ledger) {
+				reservations, err := l.List()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusInternalServerError)
+					return
+				}
+				if reservations == nil {
+					reservations = []ledger.Reservation{}
+				}
+				writeJSON(w, http.StatusOK, reservations)
+			})
+		case http.MethodPost:
+			reserve(w, r, withLedger, lookup)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	})
+	mux.HandleFunc(reservationsPath+"/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			w.Header().Set("Allow", "DELETE")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		uuid := strings.TrimPrefix(r.URL.Path, reservationsPath+"/")
+		holder := r.URL.Query().Get("holder")
+		withLedger(w, func(l *ledger.Ledger) {
+			err := l.Release(uuid, holder)
+			if err != nil {
+				http.Error(w, err.Error(), ledgerErrorStatus(err))
+				return
+			}
+			log.Infof("Released MIG device %v held by '%v'", uuid, holder)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	})
+	return mux
+}
+
+// reserve handles a POST of a reserveRequest.
+func reserve(w http.ResponseWriter, r *http.Request, withLedger func(http.ResponseWriter, func(*ledger.Ledger)), lookup func(string) (int, error)) {
+	var req reserveRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("error parsing request: %v", err), http.StatusBadRequest)
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl <= 0 {
+			http.Error(w, fmt.Sprintf("invalid 'ttl': %v", req.TTL), http.StatusBadRequest)
+			return
+		}
+	}
+	if req.UUID == "" || req.Holder == "" {
+		http.Error(w, "a reservation requires a 'uuid' and a 'holder'", http.StatusBadRequest)
+		return
+	}
+
+	gpu, err := lookup(req.UUID)
+	if err != nil {
+		http.Error(w, err.Error(), ledgerErrorStatus(err))
+		return
+	}
+
+	withLedger(w, func(l *ledger.Ledger) {
+		reservation, err := l.Reserve(req.UUID, gpu, req.Holder, ttl, req.Metadata)
+		if err != nil {
+			http.Error(w, err.Error(), ledgerErrorStatus(err))
+			return
+		}
+		log.Infof("Reserved GPU %d MIG device %v for '%v'", gpu, req.UUID, req.Holder)
+		writeJSON(w, http.StatusCreated, reservation)
+	})
+}
+
+// ledgerErrorStatus returns the HTTP status to report 'err' with.
+func ledgerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ledger.ErrReserved):
+		return http.StatusConflict
+	case errors.Is(err, store.ErrNotFound), errors.Is(err, errNoMigDevice):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// findMigDevice returns the GPU the MIG device 'uuid' is on, or
+// errNoMigDevice if there is none.
+func findMigDevice(nvmlLib nvml.Interface, uuid string) (int, error) {
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return 0, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return 0, fmt.Errorf("error getting device count: %v", ret)
+	}
+	for i := 0; i < count; i++ {
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("error getting device handle of GPU %d: %v", i, ret)
+		}
+		mode, _, ret := device.GetMigMode()
+		if ret == nvml.ERROR_NOT_SUPPORTED || (ret == nvml.SUCCESS && mode != nvml.DEVICE_MIG_ENABLE) {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("error getting MIG mode of GPU %d: %v", i, ret)
+		}
+		uuids, err := util.GetMigDeviceUUIDs(device)
+		if err != nil {
+			return 0, fmt.Errorf("error getting MIG devices of GPU %d: %w", i, err)
+		}
+		for _, u := range uuids {
+			if u == uuid {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("%w: %v", errNoMigDevice, uuid)
+}
+
+// migDeviceUUIDs returns the UUIDs of the MIG devices on GPU 'gpu', keyed by
+// the IDs of their GPU instance and compute instance.
+func migDeviceUUIDs(nvmlLib nvml.Interface, gpu int) (map[[2]uint32]string, error) {
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %w", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	device, ret := nvmlLib.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle of GPU %d: %v", gpu, ret)
+	}
+	return util.GetMigDeviceUUIDs(device)
+}
+
+// serveLedger serves the allocation ledger kept in the store at 'storeFile'
+// on 'address' until the returned function is called.
+func serveLedger(address string, storeFile string) func() {
+	open := func() (store.Store, error) {
+		return store.Open(storeFile)
+	}
+	lookup := func(uuid string) (int, error) {
+		return findMigDevice(nvml.New(), uuid)
+	}
+	server := &http.Server{
+		Addr:              address,
+		Handler:           newLedgerHandler(open, lookup),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infof("Serving the allocation ledger on %v", address)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error serving the allocation ledger: %v", err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+// nopCloser keeps a memory store alive across the requests that close it.
+type nopCloser struct {
+	store.Store
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestLedgerHandler(t *testing.T) {
+	s := nopCloser{store.NewMemoryStore()}
+	handler := newLedgerHandler(
+		func() (store.Store, error) { return s, nil },
+		func(uuid string) (int, error) {
+			if !strings.HasPrefix(uuid, "MIG-") {
+				return 0, fmt.Errorf("%w: %v", errNoMigDevice, uuid)
+			}
+			return 2, nil
+		},
+	)
+
+	do := func(method, target, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return w
+	}
+	list := func() []ledger.Reservation {
+		w := do(http.MethodGet, "/v1/reservations", "")
+		require.Equal(t, http.StatusOK, w.Code)
+		var reservations []ledger.Reservation
+		require.Nil(t, json.Unmarshal(w.Body.Bytes(), &reservations))
+		return reservations
+	}
+
+	require.Empty(t, list())
+
+	w := do(http.MethodPost, "/v1/reservations", `{"uuid": "MIG-a", "holder": "slurm", "ttl": "1h", "metadata": {"job": "42"}}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var reservation ledger.Reservation
+	require.Nil(t, json.Unmarshal(w.Body.Bytes(), &reservation))
+	require.Equal(t, 2, reservation.GPU)
+	require.NotNil(t, reservation.Expires)
+
+	testCases := []struct {
+		description    string
+		method         string
+		target         string
+		body           string
+		expectedStatus int
+	}{
+		{"Reserved by another holder", http.MethodPost, "/v1/reservations", `{"uuid": "MIG-a", "holder": "pbs"}`, http.StatusConflict},
+		{"Unknown MIG device", http.MethodPost, "/v1/reservations", `{"uuid": "GPU-a", "holder": "pbs"}`, http.StatusNotFound},
+		{"Missing holder", http.MethodPost, "/v1/reservations", `{"uuid": "MIG-b"}`, http.StatusBadRequest},
+		{"Invalid TTL", http.MethodPost, "/v1/reservations", `{"uuid": "MIG-b", "holder": "pbs", "ttl": "soon"}`, http.StatusBadRequest},
+		{"Invalid body", http.MethodPost, "/v1/reservations", `{`, http.StatusBadRequest},
+		{"Released by another holder", http.MethodDelete, "/v1/reservations/MIG-a?holder=pbs", "", http.StatusConflict},
+		{"Release of an unreserved MIG device", http.MethodDelete, "/v1/reservations/MIG-b?holder=pbs", "", http.StatusNotFound},
+		{"Unsupported method", http.MethodPut, "/v1/reservations", "", http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			w := do(tc.method, tc.target, tc.body)
+			require.Equal(t, tc.expectedStatus, w.Code, w.Body.String())
+		})
+	}
+
+	reservations := list()
+	require.Len(t, reservations, 1)
+	require.Equal(t, "slurm", reservations[0].Holder)
+	require.Equal(t, map[string]string{"job": "42"}, reservations[0].Metadata)
+
+	w = do(http.MethodDelete, "/v1/reservations/MIG-a?holder=slurm", "")
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	require.Empty(t, list())
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+)
+
+// newMetricsHandler returns an 'http.Handler' serving the Metrics returned
+// by 'metrics' on '/metrics' in the Prometheus text format, along with the
+// outcome of the last drift check recorded in 'driftStateFile' (if any).
+func newMetricsHandler(metrics func() config.Metrics, driftStateFile string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		m := metrics()
+		counters := []struct {
+			name  string
+			help  string
+			value uint64
+		}{
+			{"mig_parted_placement_attempts_total", "Number of MIG configs a placement was planned for.", m.PlacementAttempts},
+			{"mig_parted_placement_failures_total", "Number of MIG configs no placement was found for.", m.PlacementFailures},
+			{"mig_parted_permutation_attempts_total", "Number of orderings of MIG devices tried when creating them.", m.PermutationAttempts},
+			{"mig_parted_failed_permutations_total", "Number of orderings of MIG devices that failed to be created.", m.FailedPermutations},
+			{"mig_parted_nvml_retries_total", "Number of NVML calls retried when creating MIG devices.", m.NvmlRetries},
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		for _, c := range counters {
+			fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", c.name, c.help, c.name, c.name, c.value)
+		}
+		writeDriftMetrics(w, driftStateFile)
+	})
+	return mux
+}
+
+// writeDriftMetrics writes the gauges for the drift check recorded in
+// 'driftStateFile' to 'w'. Nothing is written until a drift check has been
+// recorded, so that a missing check is not mistaken for a node without drift.
+func writeDriftMetrics(w io.Writer, driftStateFile string) {
+	if driftStateFile == "" {
+		return
+	}
+	state, err := drift.Read(driftStateFile)
+	if err != nil {
+		log.Warnf("Error reading drift state: %v", err)
+		return
+	}
+	if state == nil {
+		return
+	}
+
+	detected := 0
+	if state.Detected() {
+		detected = 1
+	}
+	gauges := []struct {
+		name  string
+		help  string
+		value int64
+	}{
+		{"mig_parted_drift_detected", "Whether the last drift check found the node drifted from its MIG config.", int64(detected)},
+		{"mig_parted_drift_check_timestamp_seconds", "Time of the last drift check, in seconds since the epoch.", state.Timestamp.Unix()},
+	}
+	for _, g := range gauges {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", g.name, g.help, g.name, g.name, g.value)
+	}
+}
+
+// serveMetrics serves the Metrics of pkg/mig/config and the drift check
+// recorded in 'driftStateFile' on 'address' until the returned function is
+// called.
+func serveMetrics(address string, driftStateFile string) func() {
+	server := &http.Server{
+		Addr:              address,
+		Handler:           newMetricsHandler(config.GetMetrics, driftStateFile),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		log.Infof("Serving metrics on %v", address)
+		err := server.ListenAndServe()
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Error serving metrics: %v", err)
+		}
+	}()
+
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}
+}
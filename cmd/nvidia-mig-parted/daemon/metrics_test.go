@@ -0,0 +1,82 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	handler := newMetricsHandler(func() config.Metrics {
+		return config.Metrics{
+			PlacementAttempts:   5,
+			PlacementFailures:   1,
+			PermutationAttempts: 3,
+			FailedPermutations:  2,
+			NvmlRetries:         4,
+		}
+	}, "")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	body := w.Body.String()
+	require.Contains(t, body, "# TYPE mig_parted_placement_attempts_total counter\nmig_parted_placement_attempts_total 5\n")
+	require.Contains(t, body, "mig_parted_placement_failures_total 1\n")
+	require.Contains(t, body, "mig_parted_permutation_attempts_total 3\n")
+	require.Contains(t, body, "mig_parted_failed_permutations_total 2\n")
+	require.Contains(t, body, "mig_parted_nvml_retries_total 4\n")
+	require.NotContains(t, body, "mig_parted_drift")
+}
+
+func TestMetricsHandlerDrift(t *testing.T) {
+	driftStateFile := filepath.Join(t.TempDir(), "drift.json")
+	handler := newMetricsHandler(func() config.Metrics { return config.Metrics{} }, driftStateFile)
+
+	get := func() string {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+		return w.Body.String()
+	}
+
+	require.NotContains(t, get(), "mig_parted_drift", "Unexpected drift metrics before a drift check")
+
+	state := &drift.State{
+		Result:     drift.ResultDetected,
+		ConfigFile: "/etc/nvidia-mig-manager/config.yaml",
+		Timestamp:  time.Unix(1704164645, 0),
+	}
+	require.Nil(t, drift.Write(driftStateFile, state))
+	body := get()
+	require.Contains(t, body, "# TYPE mig_parted_drift_detected gauge\nmig_parted_drift_detected 1\n")
+	require.Contains(t, body, "mig_parted_drift_check_timestamp_seconds 1704164645\n")
+
+	state.Result = drift.ResultNone
+	require.Nil(t, drift.Write(driftStateFile, state))
+	require.Contains(t, get(), "mig_parted_drift_detected 0\n")
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	cli "github.com/urfave/cli/v2"
+
+	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/schedule"
+)
+
+// PreSwitchHook is run before each scheduled switch to another MIG config,
+// e.g. to drain the workloads on the GPUs of the node. It can veto the
+// switch (or ask for it to be retried later) like the 'apply-start' hook.
+const PreSwitchHook = "pre-switch"
+
+// ParseScheduleFile parses a schedule file and unmarshals it into a 'schedule.Schedule'.
+func ParseScheduleFile(scheduleFile string) (*schedule.Schedule, error) {
+	scheduleYaml, err := util.ReadFile(scheduleFile)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	s, err := schedule.Parse(scheduleYaml)
+	if err != nil {
+		return nil, fmt.Errorf("parse error: %v", err)
+	}
+
+	return s, nil
+}
+
+// describeEntry names the schedule entry 'e' in log messages and journal
+// entries, or the default MIG config if 'e' is nil.
+func describeEntry(e *schedule.Entry) string {
+	if e == nil {
+		return "the default config"
+	}
+	return fmt.Sprintf("'%v' (%v)", e.Name, e.SelectedConfig)
+}
+
+// selectedConfig returns the MIG config to apply while the schedule entry
+// 'e' is in effect, or 'defaultConfig' while none is.
+func selectedConfig(e *schedule.Entry, defaultConfig string) string {
+	if e == nil {
+		return defaultConfig
+	}
+	return e.SelectedConfig
+}
+
+// startSchedule picks the schedule entry in effect as the daemon starts,
+// if any, and arms the timer for the next switch.
+func (d *daemon) startSchedule() {
+	if d.schedule == nil {
+		return
+	}
+	now := d.clock()
+	active, since := d.schedule.Active(now)
+	if active != nil {
+		log.Infof("Schedule entry %v in effect since %v", describeEntry(active), since.Format(time.RFC3339))
+		d.active = active
+		d.trigger = fmt.Sprintf("schedule entry %v in effect since %v", describeEntry(active), since.Format(time.RFC3339))
+	}
+	d.armSwitch(now)
+}
+
+// armSwitch sets 'nextSwitch' to fire when the schedule entry in effect
+// next changes after 'now', if it ever does.
+func (d *daemon) armSwitch(now time.Time) {
+	d.nextSwitch = nil
+	next := d.schedule.Next(now)
+	if next.IsZero() {
+		return
+	}
+	log.Debugf("Next scheduled switch at %v", next.Format(time.RFC3339))
+	d.nextSwitch = d.afterFunc()(next.Sub(now))
+}
+
+// switchConfig switches to the MIG config of the schedule entry now in
+// effect, once the pre-switch hooks allow it. If they veto it, the current
+// MIG config is kept until the next switch, unless they ask for the switch
+// to be retried sooner.
+func (d *daemon) switchConfig() {
+	now := d.clock()
+	active, _ := d.schedule.Active(now)
+	if active == d.active {
+		d.armSwitch(now)
+		return
+	}
+
+	trigger := fmt.Sprintf("scheduled switch from %v to %v", describeEntry(d.active), describeEntry(active))
+	log.Infof("Running %s hook before %v", PreSwitchHook, trigger)
+	err := d.preSwitch(d.active, active, trigger)
+	var veto *hooks.VetoError
+	if errors.As(err, &veto) && veto.RetryAfter() > 0 {
+		log.Warnf("MIG configuration not switched: %v; retrying in %v", err, veto.RetryAfter())
+		d.nextSwitch = d.afterFunc()(veto.RetryAfter())
+		return
+	}
+	if err != nil {
+		log.Warnf("MIG configuration not switched: %v", err)
+		d.armSwitch(now)
+		return
+	}
+
+	d.active = active
+	d.trigger = trigger
+	d.armSwitch(now)
+	d.reconcile(fmt.Sprintf("Switching MIG configuration: %v", trigger))
+}
+
+// runPreSwitchHooks runs the pre-switch hooks in the hooks file of 'f'
+// before 'trigger' switches from the MIG config 'from' to 'to'. If they
+// fail, the switch is recorded in the journal, as it is not applied.
+func runPreSwitchHooks(c *cli.Context, f *Flags, from string, to string, trigger string) (rerr error) {
+	if f.HooksFile == "" {
+		return nil
+	}
+
+	start := time.Now()
+	defer func() {
+		if rerr != nil {
+			flags := f.Flags
+			flags.Trigger = trigger
+			apply.RecordApply(&flags, to, start, rerr)
+		}
+	}()
+
+	spec, err := apply.ParseHooksFile(f.HooksFile)
+	if err != nil {
+		return fmt.Errorf("error parsing hooks file: %v", err)
+	}
+
+	envs := apply.GetHooksEnvsMap(c).Combine(hooks.EnvsMap{
+		"MIG_PARTED_SELECTED_CONFIG": to,
+		"MIG_PARTED_PREVIOUS_CONFIG": from,
+		"MIG_PARTED_TRIGGER":         trigger,
+	})
+	err = spec.Hooks.Run(PreSwitchHook, envs, c.Bool("debug"))
+	if err != nil {
+		return fmt.Errorf("error running %s hook: %w", PreSwitchHook, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,353 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package examples
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/simulate"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	DisabledConfig = "all-disabled"
+	EnabledConfig  = "all-enabled"
+	BalancedConfig = "all-balanced"
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'examples' subcommand.
+type Flags struct {
+	WriteDir  string
+	GpuModels cli.StringSlice
+}
+
+// Model is a GPU model to generate example mig-configs for, along with the
+// device IDs its mig-configs apply to.
+type Model struct {
+	Name      string
+	DeviceIDs []types.DeviceID
+}
+
+// BuildCommand builds the 'examples' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	examplesFlags := Flags{}
+
+	// Create the 'examples' command
+	examples := cli.Command{}
+	examples.Name = "examples"
+	examples.Usage = "Write example spec files generated for the GPU models present on the node"
+	examples.Action = func(c *cli.Context) error {
+		return examplesWrapper(c, &examplesFlags)
+	}
+
+	// Setup the flags for this command
+	examples.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "write-dir",
+			Usage:       "Directory to write one spec file per example mig-config into",
+			Destination: &examplesFlags.WriteDir,
+			EnvVars:     []string{"MIG_PARTED_WRITE_DIR"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "gpu-model",
+			Usage:       fmt.Sprintf("GPU model to generate examples for instead of the GPUs present (may be repeated) [%v]", strings.Join(simulate.GpuModelNames(), " | ")),
+			Destination: &examplesFlags.GpuModels,
+			EnvVars:     []string{"MIG_PARTED_GPU_MODEL"},
+		},
+	}
+
+	return &examples
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	if f.WriteDir == "" {
+		return fmt.Errorf("missing required flag 'write-dir'")
+	}
+	for _, model := range f.GpuModels.Value() {
+		_, err := simulate.NewGpuModel(model)
+		if err != nil {
+			return fmt.Errorf("invalid 'gpu-model': %v", err)
+		}
+	}
+	return nil
+}
+
+// GetModels returns the GPU models named in 'f', or else those of the GPUs
+// present on the node. A GPU without MIG profile tables built into this
+// binary is skipped with a warning.
+func GetModels(f *Flags) ([]Model, error) {
+	var models []Model
+	for _, name := range f.GpuModels.Value() {
+		ids, err := types.GetGpuModelDeviceIDs(name)
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, Model{Name: name, DeviceIDs: ids})
+	}
+	if len(models) > 0 {
+		return models, nil
+	}
+
+	deviceIDs, err := util.GetGPUDeviceIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error enumerating GPUs: %v", err)
+	}
+
+	seen := make(map[types.DeviceID]bool)
+	for i, id := range deviceIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		name := modelName(id)
+		if name == "" {
+			log.Warnf("Skipping GPU %v: no MIG profile tables for device ID %v", i, id)
+			continue
+		}
+		if _, err := simulate.NewGpuModel(name); err != nil {
+			log.Warnf("Skipping GPU %v: %v", i, err)
+			continue
+		}
+		models = append(models, Model{Name: name, DeviceIDs: []types.DeviceID{id}})
+	}
+	if len(models) == 0 {
+		return nil, fmt.Errorf("no GPU with MIG profile tables found")
+	}
+	return models, nil
+}
+
+// modelName returns the name in types.GpuModels of the GPU model with device
+// ID 'id', or the empty string if there is none.
+func modelName(id types.DeviceID) string {
+	for _, name := range types.GpuModelNames() {
+		for _, modelID := range types.GpuModels[name] {
+			if modelID == id {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// Generate builds the example specs for 'models', keyed by the name of the
+// single mig-config each holds: all-disabled, all-enabled, all-balanced and
+// an all-<profile> for every GPU instance profile, filled to capacity. When
+// there is more than one model, each mig-config has an entry per model that
+// supports it, selected by a 'device-filter' on the model's device IDs.
+func Generate(models []Model) (map[string]*v1.Spec, error) {
+	configs := make(map[string]v1.MigConfigSpecSlice)
+	for _, model := range models {
+		modelConfigs, err := modelMigConfigs(model.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		var deviceFilter interface{}
+		if len(models) > 1 {
+			var ids []string
+			for _, id := range model.DeviceIDs {
+				ids = append(ids, id.String())
+			}
+			deviceFilter = ids
+			if len(ids) == 1 {
+				deviceFilter = ids[0]
+			}
+		}
+
+		for name, mc := range modelConfigs {
+			configs[name] = append(configs[name], v1.MigConfigSpec{
+				DeviceFilter: deviceFilter,
+				Devices:      "all",
+				MigEnabled:   mc != nil,
+				MigDevices:   mc,
+			})
+		}
+	}
+
+	specs := make(map[string]*v1.Spec)
+	for name, config := range configs {
+		specs[name] = &v1.Spec{
+			Version:    v1.Version,
+			MigConfigs: map[string]v1.MigConfigSpecSlice{name: config},
+		}
+	}
+	return specs, nil
+}
+
+// modelMigConfigs returns the example mig-configs of GPU model 'name', with
+// a nil MigConfig standing for MIG mode disabled.
+func modelMigConfigs(name string) (map[string]types.MigConfig, error) {
+	gpu, err := simulate.NewGpuModel(name)
+	if err != nil {
+		return nil, err
+	}
+	report, err := gpu.Report()
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG capacity of %v: %v", name, err)
+	}
+
+	configs := map[string]types.MigConfig{
+		DisabledConfig: nil,
+		EnabledConfig:  {},
+	}
+	for _, profile := range gpu.Profiles() {
+		if report.Capacity[profile] == 0 {
+			continue
+		}
+		label := "all-" + strings.ReplaceAll(profile, "+", ".")
+		configs[label] = types.MigConfig{profile: report.Capacity[profile]}
+	}
+
+	balanced, err := balancedMigConfig(gpu)
+	if err != nil {
+		return nil, fmt.Errorf("error building balanced MIG config of %v: %v", name, err)
+	}
+	if len(balanced) > 0 {
+		configs[BalancedConfig] = balanced
+	}
+
+	return configs, nil
+}
+
+// balancedMigConfig returns a mix of GPU instance sizes for 'gpu': one
+// instance of each size from 2 slices up to half of the GPU, largest first,
+// with the slices left filled with 1-slice instances. Only the profile with
+// the least memory and no attributes is used for each size.
+func balancedMigConfig(gpu *simulate.GPU) (types.MigConfig, error) {
+	bySlices := make(map[int]*types.MigProfile)
+	maxSlices := 0
+	for _, mp := range gpu.MigProfiles() {
+		maxSlices = max(maxSlices, mp.G)
+		if len(mp.Attributes) > 0 {
+			continue
+		}
+		if current, exists := bySlices[mp.G]; !exists || mp.GB < current.GB {
+			bySlices[mp.G] = mp
+		}
+	}
+
+	var sizes []int
+	for g := range bySlices {
+		if g > 1 && g <= maxSlices/2 {
+			sizes = append(sizes, g)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+
+	var profiles []string
+	for _, g := range sizes {
+		profiles = append(profiles, bySlices[g].String())
+	}
+	if one, exists := bySlices[1]; exists {
+		for {
+			fits, err := gpu.Fits(append(profiles, one.String())...)
+			if err != nil {
+				return nil, err
+			}
+			if !fits {
+				break
+			}
+			profiles = append(profiles, one.String())
+		}
+	}
+
+	config := types.MigConfig{}
+	for _, profile := range profiles {
+		config[profile]++
+	}
+	return config, nil
+}
+
+// WriteExamples writes each spec in 'specs' to '<name>.yaml' in 'dir',
+// creating it if needed, and returns the paths written in order.
+func WriteExamples(dir string, specs map[string]*v1.Spec) ([]string, error) {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating directory: %v", err)
+	}
+
+	var names []string
+	for name := range specs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var paths []string
+	for _, name := range names {
+		path := filepath.Join(dir, name+".yaml")
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating file: %v", err)
+		}
+		err = export.WriteOutput(file, specs[name], &export.Flags{OutputFormat: export.YAMLFormat})
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error writing '%v': %v", path, err)
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func examplesWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	models, err := GetModels(f)
+	if err != nil {
+		return err
+	}
+
+	specs, err := Generate(models)
+	if err != nil {
+		return err
+	}
+
+	paths, err := WriteExamples(f.WriteDir, specs)
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		log.Infof("Wrote %v", path)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package examples
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestCheckFlags(t *testing.T) {
+	testCases := []struct {
+		description string
+		flags       Flags
+		valid       bool
+	}{
+		{
+			"Missing write-dir",
+			Flags{},
+			false,
+		},
+		{
+			"Detected GPU models",
+			Flags{WriteDir: "examples"},
+			true,
+		},
+		{
+			"Known GPU model",
+			Flags{WriteDir: "examples", GpuModels: *cli.NewStringSlice("H100-SXM5-80GB")},
+			true,
+		},
+		{
+			"GPU model without MIG profile tables",
+			Flags{WriteDir: "examples", GpuModels: *cli.NewStringSlice("A30-24GB")},
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := CheckFlags(&tc.flags)
+			if tc.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description string
+		models      []Model
+		expected    map[string]v1.MigConfigSpecSlice
+	}{
+		{
+			"A100-SXM4-40GB",
+			[]Model{
+				{Name: "A100-SXM4-40GB", DeviceIDs: []types.DeviceID{0x20B010DE}},
+			},
+			map[string]v1.MigConfigSpecSlice{
+				"all-disabled":  {{Devices: "all", MigEnabled: false}},
+				"all-enabled":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{}}},
+				"all-balanced":  {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 2, "2g.10gb": 1, "3g.20gb": 1}}},
+				"all-1g.5gb":    {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}}},
+				"all-1g.5gb.me": {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb+me": 1}}},
+				"all-1g.10gb":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.10gb": 4}}},
+				"all-2g.10gb":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"2g.10gb": 3}}},
+				"all-3g.20gb":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 2}}},
+				"all-4g.20gb":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"4g.20gb": 1}}},
+				"all-7g.40gb":   {{Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"7g.40gb": 1}}},
+			},
+		},
+		{
+			"A100-PCIE-40GB and A100-SXM4-80GB",
+			[]Model{
+				{Name: "A100-PCIE-40GB", DeviceIDs: []types.DeviceID{0x20B110DE, 0x20F110DE}},
+				{Name: "A100-SXM4-80GB", DeviceIDs: []types.DeviceID{0x20B210DE}},
+			},
+			map[string]v1.MigConfigSpecSlice{
+				"all-disabled": {
+					{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: false},
+					{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: false},
+				},
+				"all-enabled": {
+					{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{}},
+					{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{}},
+				},
+				"all-balanced": {
+					{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 2, "2g.10gb": 1, "3g.20gb": 1}},
+					{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.10gb": 2, "2g.20gb": 1, "3g.40gb": 1}},
+				},
+				"all-1g.5gb":    {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}}},
+				"all-1g.5gb.me": {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb+me": 1}}},
+				"all-1g.10gb": {
+					{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.10gb": 4}},
+					{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.10gb": 7}},
+				},
+				"all-1g.10gb.me": {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.10gb+me": 1}}},
+				"all-1g.20gb":    {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"1g.20gb": 4}}},
+				"all-2g.10gb":    {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"2g.10gb": 3}}},
+				"all-2g.20gb":    {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"2g.20gb": 3}}},
+				"all-3g.20gb":    {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.20gb": 2}}},
+				"all-3g.40gb":    {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"3g.40gb": 2}}},
+				"all-4g.20gb":    {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"4g.20gb": 1}}},
+				"all-4g.40gb":    {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"4g.40gb": 1}}},
+				"all-7g.40gb":    {{DeviceFilter: []string{"0x20B110DE", "0x20F110DE"}, Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"7g.40gb": 1}}},
+				"all-7g.80gb":    {{DeviceFilter: "0x20B210DE", Devices: "all", MigEnabled: true, MigDevices: types.MigConfig{"7g.80gb": 1}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			specs, err := Generate(tc.models)
+			require.Nil(t, err)
+			require.Len(t, specs, len(tc.expected))
+			for name, expected := range tc.expected {
+				require.Contains(t, specs, name)
+				require.Equal(t, v1.Version, specs[name].Version)
+				require.Equal(t, map[string]v1.MigConfigSpecSlice{name: expected}, specs[name].MigConfigs)
+			}
+		})
+	}
+}
+
+func TestWriteExamples(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	specs, err := Generate([]Model{{Name: "A100-SXM4-40GB", DeviceIDs: []types.DeviceID{0x20B010DE}}})
+	require.Nil(t, err)
+
+	dir := filepath.Join(t.TempDir(), "examples")
+	paths, err := WriteExamples(dir, specs)
+	require.Nil(t, err)
+	require.Len(t, paths, len(specs))
+	require.Equal(t, filepath.Join(dir, "all-1g.10gb.yaml"), paths[0])
+
+	for name := range specs {
+		spec, err := assert.ParseConfigFile(&assert.Flags{ConfigFile: filepath.Join(dir, name+".yaml")})
+		require.Nil(t, err)
+		require.Contains(t, spec.MigConfigs, name)
+	}
+}
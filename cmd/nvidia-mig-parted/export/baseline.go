@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package export
+
+import (
+	"fmt"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ParseBaseline returns the config selected by 'f.BaselineConfig' from the
+// baseline file in 'f.Baseline'. The selection can be omitted if the file
+// holds a single config.
+func ParseBaseline(f *Flags) (v1.MigConfigSpecSlice, error) {
+	spec, err := assert.ParseConfigFile(&assert.Flags{ConfigFile: f.Baseline})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(spec.MigConfigs) > 1 && f.BaselineConfig == "" {
+		return nil, fmt.Errorf("missing required flag 'baseline-config' when more than one config available")
+	}
+
+	return assert.GetSelectedMigConfig(&assert.Flags{SelectedConfig: f.BaselineConfig}, spec)
+}
+
+// getBaselineMigConfigs returns the entry of 'baseline' for each GPU it
+// selects, keyed by GPU index, with any 'fill' resolved into the MIG devices
+// of that GPU. As with 'apply', a later entry wins over an earlier one.
+func getBaselineMigConfigs(baseline v1.MigConfigSpecSlice, unmanaged v1.UnmanagedDeviceSpecSlice) (map[int]*v1.MigConfigSpec, error) {
+	expected := make(map[int]*v1.MigConfigSpec)
+	err := assert.WalkSelectedMigConfigForEachGPU(baseline, unmanaged, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if mc.MigEnabled && mc.Fill != "" {
+			configManager, err := util.NewMigConfigManager()
+			if err != nil {
+				return fmt.Errorf("error creating MIG Config Manager: %v", err)
+			}
+			mc.MigDevices, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
+			mc.Fill = ""
+		}
+		expected[i] = mc
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expected, nil
+}
+
+// matchesBaseline checks if the exported 'spec' of a GPU is what its
+// 'expected' baseline entry declares. A GPU without a baseline entry never
+// matches.
+func matchesBaseline(spec *v1.MigConfigSpec, expected *v1.MigConfigSpec) bool {
+	if expected == nil {
+		return false
+	}
+	if spec.MigEnabled != expected.MigEnabled {
+		return false
+	}
+	return !spec.MigEnabled || spec.MigDevices.Equals(expected.MigDevices)
+}
@@ -18,10 +18,12 @@ package export
 
 import (
 	"fmt"
+	"maps"
 	"sort"
 
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
@@ -38,10 +40,23 @@ func ExportMigConfigs(c *Context) (*v1.Spec, error) {
 		return nil, fmt.Errorf("error enumerating GPUs: %v", err)
 	}
 
-	configSpecs := make(v1.MigConfigSpecSlice, len(deviceIDs))
+	var expected map[int]*v1.MigConfigSpec
+	if c.Baseline != nil {
+		expected, err = getBaselineMigConfigs(c.Baseline, c.UnmanagedDevices)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving baseline for each GPU: %v", err)
+		}
+	}
+
+	var allSpecs, configSpecs v1.MigConfigSpecSlice
 	for i, deviceID := range deviceIDs {
 		deviceFilter := deviceID.String()
 
+		if c.UnmanagedDevices.Matches(i, deviceID) {
+			log.Debugf("Skipping unmanaged GPU %v: %v", i, deviceID)
+			continue
+		}
+
 		modeManager, err := util.NewMigModeManager()
 		if err != nil {
 			return nil, fmt.Errorf("error creating MIG Mode Manager: %v", err)
@@ -62,6 +77,11 @@ func ExportMigConfigs(c *Context) (*v1.Spec, error) {
 
 		migDevices := types.MigConfig{}
 		if enabled {
+			err := util.CheckPrivilege(migcaps.PrivilegeMonitor)
+			if err != nil {
+				return nil, err
+			}
+
 			configManager, err := util.NewMigConfigManager()
 			if err != nil {
 				return nil, fmt.Errorf("error creating MIG Config Manager: %v", err)
@@ -71,27 +91,64 @@ func ExportMigConfigs(c *Context) (*v1.Spec, error) {
 			if err != nil {
 				return nil, fmt.Errorf("error getting MIGConfig: %v", err)
 			}
+
+			// An empty 'mig-devices' means MIG mode enabled without any
+			// MIG devices, so it cannot stand for GPU instances that merely
+			// have no compute instances in them.
+			matches, err := util.MigConfigMatches(i, migDevices, types.MigConfig{})
+			if err != nil {
+				return nil, fmt.Errorf("error checking for GPU instances: %v", err)
+			}
+			if len(migDevices) == 0 && !matches {
+				return nil, fmt.Errorf("GPU %v has GPU instances without compute instances, which a MIG config cannot express (create compute instances in them or destroy them first)", i)
+			}
 		}
 
-		configSpecs[i] = v1.MigConfigSpec{
+		spec := v1.MigConfigSpec{
 			DeviceFilter: []string{deviceFilter},
 			Devices:      []int{i},
 			MigEnabled:   enabled,
 			MigDevices:   migDevices,
 		}
+		if entry, exists := c.Labels[i]; exists {
+			spec.Labels = entry.Labels
+		}
+		allSpecs = append(allSpecs, spec)
+
+		// Like 'apply', a baseline entry for all devices does not cover
+		// GPUs that are not MIG capable.
+		if c.Baseline != nil && (matchesBaseline(&spec, expected[i]) || (!capable && expected[i] != nil && expected[i].MatchesAllDevices())) {
+			log.Debugf("Skipping GPU %v matching baseline: %v", i, deviceID)
+			continue
+		}
+		configSpecs = append(configSpecs, spec)
+	}
+
+	if len(allSpecs) == 0 {
+		return nil, fmt.Errorf("no managed GPUs to export")
 	}
 
 	spec := v1.Spec{
-		Version: v1.Version,
+		Version:          v1.Version,
+		UnmanagedDevices: c.UnmanagedDevices,
 		MigConfigs: map[string]v1.MigConfigSpecSlice{
-			c.Flags.ConfigLabel: mergeMigConfigSpecs(configSpecs),
+			c.Flags.ConfigLabel: mergeMigConfigSpecs(configSpecs, allSpecs),
 		},
 	}
 
+	// The strategy applies to the node as a whole, so all of its GPUs are
+	// checked against it, even those matching the baseline.
+	if c.Flags.MigStrategy != "" {
+		spec.MigStrategy = c.Flags.MigStrategy
+		for _, problem := range allSpecs.CheckMigStrategy(c.Flags.MigStrategy) {
+			log.Warnf("Current MIG config incompatible with the '%v' strategy: %v", c.Flags.MigStrategy, problem)
+		}
+	}
+
 	return &spec, nil
 }
 
-// mergeMigConfigSpecs merges the specs from a MigConfigSpecsSlice into a more
+// MergeMigConfigSpecs merges the specs from a MigConfigSpecsSlice into a more
 // compact form for better display.
 //
 // We assume the 'specs' argument is passed in from the ExportMigConfigs(), so we know
@@ -103,9 +160,18 @@ func ExportMigConfigs(c *Context) (*v1.Spec, error) {
 // '.DeviceFilter'.
 //
 // This allows us to simplify the logic below significantly.
-func mergeMigConfigSpecs(specs v1.MigConfigSpecSlice) v1.MigConfigSpecSlice {
-	// Merge the incoming specs by comparing their MigEnabled and MigDevices fields.
-	// For any two specs, if both of these are equal, then we merge them
+func MergeMigConfigSpecs(specs v1.MigConfigSpecSlice) v1.MigConfigSpecSlice {
+	return mergeMigConfigSpecs(specs, specs)
+}
+
+// mergeMigConfigSpecs is like MergeMigConfigSpecs, but for 'specs' that only
+// cover some of the devices on the node, e.g. those deviating from a
+// baseline. Every device on the node is represented in 'all' instead, so that
+// 'specs' are only collapsed to 'all' devices (or have their device filter
+// removed) when that still selects exactly the devices they cover.
+func mergeMigConfigSpecs(specs v1.MigConfigSpecSlice, all v1.MigConfigSpecSlice) v1.MigConfigSpecSlice {
+	// Merge the incoming specs by comparing their MigEnabled, MigDevices and Labels fields.
+	// For any two specs, if all of these are equal, then we merge them
 	// together and concatenate their device filter and devices lists.
 	merged := []v1.MigConfigSpec{}
 OUTER:
@@ -121,6 +187,9 @@ OUTER:
 			if !s.MigDevices.Equals(m.MigDevices) {
 				continue
 			}
+			if !maps.Equal(s.Labels, m.Labels) {
+				continue
+			}
 			merged[i].Devices = mergeAndSortIntSlices(m.Devices.([]int), s.Devices.([]int))
 			merged[i].DeviceFilter = mergeAndSortStringSlices(m.DeviceFilter.([]string), s.DeviceFilter.([]string))
 			continue OUTER
@@ -132,7 +201,7 @@ OUTER:
 	// This assumes the incoming MigConfigSpecSlice has
 	// a single entry in the device filter for each spec.
 	dfDevices := make(map[string][]int)
-	for _, s := range specs {
+	for _, s := range all {
 		df := s.DeviceFilter.([]string)[0]
 		dfDevices[df] = mergeAndSortIntSlices(dfDevices[df], s.Devices.([]int))
 	}
@@ -157,9 +226,9 @@ OUTER:
 		merged[i].Devices = "all"
 	}
 
-	// If there is only a single entry in the end,
-	// remove the device filter completely.
-	if len(merged) == 1 {
+	// If there is only a single entry in the end covering
+	// every device, remove the device filter completely.
+	if len(merged) == 1 && len(specs) == len(all) {
 		merged[0].DeviceFilter = nil
 	}
 
@@ -27,7 +27,12 @@ import (
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
+	fleet "github.com/NVIDIA/mig-parted/api/fleet/v1"
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/labels"
+	"github.com/NVIDIA/mig-parted/pkg/store"
 
 	yaml "gopkg.in/yaml.v2"
 )
@@ -45,14 +50,27 @@ const (
 )
 
 type Flags struct {
+	ConfigFile   string
 	OutputFormat string
+	OutputFile   string
 	ConfigLabel  string
+	AllNodes     bool
+	NodeName     string
+
+	Baseline       string
+	BaselineConfig string
+
+	StoreFile   string
+	MigStrategy string
 }
 
 type Context struct {
 	*cli.Context
-	Flags *Flags
-	Nvml  nvml.Interface
+	Flags            *Flags
+	UnmanagedDevices v1.UnmanagedDeviceSpecSlice
+	Baseline         v1.MigConfigSpecSlice
+	Labels           map[int]*labels.Entry
+	Nvml             nvml.Interface
 }
 
 func BuildCommand() *cli.Command {
@@ -62,6 +80,7 @@ func BuildCommand() *cli.Command {
 	// Create the 'export' command
 	export := cli.Command{}
 	export.Name = "export"
+	export.Aliases = []string{"get"}
 	export.Usage = "Export the MIG configuration from all GPUs in a compatible format"
 	export.Action = func(c *cli.Context) error {
 		return exportWrapper(c, &exportFlags)
@@ -69,13 +88,14 @@ func BuildCommand() *cli.Command {
 
 	// Setup the flags for this command
 	export.Flags = []cli.Flag{
+		util.ConfigFileFlag(&exportFlags.ConfigFile, "Path to a configuration file whose 'unmanaged-devices' are carried over to the export ('-' for stdin)"),
+		util.OutputFormatFlag(&exportFlags.OutputFormat, YAMLFormat, JSONFormat),
 		&cli.StringFlag{
-			Name:        "output-format",
-			Aliases:     []string{"o"},
-			Usage:       "Format for the output [json | yaml]",
-			Destination: &exportFlags.OutputFormat,
-			Value:       YAMLFormat,
-			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+			Name:        "output-file",
+			Usage:       "File to write the output to ('-' for stdout)",
+			Destination: &exportFlags.OutputFile,
+			Value:       util.StdioPath,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FILE"},
 		},
 		&cli.StringFlag{
 			Name:        "config-label",
@@ -85,6 +105,46 @@ func BuildCommand() *cli.Command {
 			Value:       DefaultConfigLabel,
 			EnvVars:     []string{"MIG_PARTED_CONFIG_LABEL"},
 		},
+		&cli.BoolFlag{
+			Name:        "all-nodes",
+			Aliases:     []string{"a"},
+			Usage:       "Export in the fleet aggregation format for merging with the exports of other nodes",
+			Destination: &exportFlags.AllNodes,
+			EnvVars:     []string{"MIG_PARTED_ALL_NODES"},
+		},
+		&cli.StringFlag{
+			Name:        "node-name",
+			Aliases:     []string{"n"},
+			Usage:       "Name of the node to record in the fleet aggregation format (defaults to the hostname)",
+			Destination: &exportFlags.NodeName,
+			EnvVars:     []string{"MIG_PARTED_NODE_NAME", "NODE_NAME"},
+		},
+		&cli.StringFlag{
+			Name:        "baseline",
+			Aliases:     []string{"b"},
+			Usage:       "Path to a configuration file to compare against, exporting only the GPUs that deviate from it",
+			Destination: &exportFlags.Baseline,
+			EnvVars:     []string{"MIG_PARTED_BASELINE"},
+		},
+		&cli.StringFlag{
+			Name:        "baseline-config",
+			Usage:       "Config in the 'baseline' file to compare against (optional if it has only one)",
+			Destination: &exportFlags.BaselineConfig,
+			EnvVars:     []string{"MIG_PARTED_BASELINE_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "store-file",
+			Usage:       "Path to the store 'apply' records the labels of the applied MIG config in, to carry them over to the export (disabled if empty)",
+			Destination: &exportFlags.StoreFile,
+			Value:       store.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "strategy",
+			Usage:       "MIG strategy of the Kubernetes device plugin to declare in the export, warning if the current MIG config is incompatible with it: [none | single | mixed]",
+			Destination: &exportFlags.MigStrategy,
+			EnvVars:     []string{"MIG_PARTED_MIG_STRATEGY"},
+		},
 	}
 
 	return &export
@@ -103,16 +163,63 @@ func exportWrapper(c *cli.Context, f *Flags) error {
 		Nvml:    nvml.New(),
 	}
 
+	if f.ConfigFile != "" {
+		log.Debugf("Parsing config file...")
+		spec, err := assert.ParseConfigFile(&assert.Flags{ConfigFile: f.ConfigFile})
+		if err != nil {
+			return fmt.Errorf("error parsing config file: %v", err)
+		}
+		context.UnmanagedDevices = spec.UnmanagedDevices
+	}
+
+	if f.Baseline != "" {
+		log.Debugf("Parsing baseline file...")
+		context.Baseline, err = ParseBaseline(f)
+		if err != nil {
+			return fmt.Errorf("error parsing baseline file: %v", err)
+		}
+	}
+
+	if f.StoreFile != "" {
+		log.Debugf("Reading labels of applied MIG config...")
+		context.Labels, err = labels.Load(f.StoreFile)
+		if err != nil {
+			log.Warnf("Error reading labels, exporting without them: %v", err)
+		}
+	}
+
 	spec, err := ExportMigConfigs(&context)
 	if err != nil {
 		return err
 	}
 
-	err = WriteOutput(os.Stdout, spec, f)
-	if err != nil {
-		return err
+	var output interface{} = spec
+	if f.AllNodes {
+		nodeName := f.NodeName
+		if nodeName == "" {
+			nodeName, err = os.Hostname()
+			if err != nil {
+				return fmt.Errorf("error getting hostname: %v", err)
+			}
+		}
+
+		output = &fleet.Spec{
+			Version: fleet.Version,
+			Nodes: []fleet.NodeSpec{
+				{
+					Name: nodeName,
+					Spec: *spec,
+				},
+			},
+		}
 	}
 
+	err = util.WriteToFile(f.OutputFile, func(w io.Writer) error {
+		return WriteOutput(w, output, f)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
 	return nil
 }
 
@@ -123,10 +230,20 @@ func CheckFlags(f *Flags) error {
 	default:
 		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
 	}
+	if f.BaselineConfig != "" && f.Baseline == "" {
+		return fmt.Errorf("'baseline-config' requires 'baseline'")
+	}
+	if f.MigStrategy != "" {
+		err := v1.AssertValidMigStrategy(f.MigStrategy)
+		if err != nil {
+			return fmt.Errorf("invalid 'strategy': %v", err)
+		}
+	}
 	return nil
 }
 
-func WriteOutput(w io.Writer, spec *v1.Spec, f *Flags) error {
+// WriteOutput writes 'spec' to 'w' in the output format selected in 'f'.
+func WriteOutput(w io.Writer, spec interface{}, f *Flags) error {
 	switch f.OutputFormat {
 	case YAMLFormat:
 		output, err := yaml.Marshal(spec)
@@ -141,6 +258,7 @@ func WriteOutput(w io.Writer, spec *v1.Spec, f *Flags) error {
 		if err != nil {
 			return fmt.Errorf("error unmarshaling MIG config to JSON: %v", err)
 		}
+		output = append(output, '\n')
 		if _, err := w.Write(output); err != nil {
 			return fmt.Errorf("error writing JSON output: %w", err)
 		}
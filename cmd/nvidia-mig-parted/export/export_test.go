@@ -22,6 +22,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 func TestMergeConfigSpecs(t *testing.T) {
@@ -192,12 +193,166 @@ func TestMergeConfigSpecs(t *testing.T) {
 				},
 			},
 		},
+		{
+			"Single Filter - Multi Device - Different Labels",
+			v1.MigConfigSpecSlice{
+				{
+					DeviceFilter: []string{"A100-SXM4-40GB"},
+					Devices:      []int{0},
+					MigEnabled:   false,
+					Labels:       map[string]string{"team": "vision"},
+				},
+				{
+					DeviceFilter: []string{"A100-SXM4-40GB"},
+					Devices:      []int{1},
+					MigEnabled:   false,
+				},
+				{
+					DeviceFilter: []string{"A100-SXM4-40GB"},
+					Devices:      []int{2},
+					MigEnabled:   false,
+					Labels:       map[string]string{"team": "vision"},
+				},
+			},
+			v1.MigConfigSpecSlice{
+				{
+					Devices:    []int{0, 2},
+					MigEnabled: false,
+					Labels:     map[string]string{"team": "vision"},
+				},
+				{
+					Devices:    []int{1},
+					MigEnabled: false,
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			merged := MergeMigConfigSpecs(tc.Input)
+			require.Equal(t, tc.Output, merged)
+		})
+	}
+}
+
+func TestMergeDeviatingConfigSpecs(t *testing.T) {
+	all := v1.MigConfigSpecSlice{
+		{
+			DeviceFilter: []string{"A100-SXM4-40GB"},
+			Devices:      []int{0},
+			MigEnabled:   true,
+			MigDevices:   types.MigConfig{"1g.5gb": 7},
+		},
+		{
+			DeviceFilter: []string{"A100-SXM4-40GB"},
+			Devices:      []int{1},
+			MigEnabled:   false,
+		},
+		{
+			DeviceFilter: []string{"A100-SXM4-80GB"},
+			Devices:      []int{2},
+			MigEnabled:   false,
+		},
+	}
+
+	testCases := []struct {
+		Description string
+		Input       v1.MigConfigSpecSlice
+		Output      v1.MigConfigSpecSlice
+	}{
+		{
+			"No Deviations",
+			v1.MigConfigSpecSlice{},
+			v1.MigConfigSpecSlice{},
+		},
+		{
+			"Some Devices Of A Filter",
+			v1.MigConfigSpecSlice{all[1]},
+			v1.MigConfigSpecSlice{
+				{
+					DeviceFilter: "A100-SXM4-40GB",
+					Devices:      []int{1},
+					MigEnabled:   false,
+				},
+			},
+		},
+		{
+			"All Devices Of A Filter",
+			v1.MigConfigSpecSlice{all[2]},
+			v1.MigConfigSpecSlice{
+				{
+					DeviceFilter: "A100-SXM4-80GB",
+					Devices:      "all",
+					MigEnabled:   false,
+				},
+			},
+		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.Description, func(t *testing.T) {
-			merged := mergeMigConfigSpecs(tc.Input)
+			merged := mergeMigConfigSpecs(tc.Input, all)
 			require.Equal(t, tc.Output, merged)
 		})
 	}
 }
+
+func TestMatchesBaseline(t *testing.T) {
+	enabled := &v1.MigConfigSpec{MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}}
+	disabled := &v1.MigConfigSpec{MigEnabled: false}
+
+	testCases := []struct {
+		Description string
+		Spec        *v1.MigConfigSpec
+		Expected    *v1.MigConfigSpec
+		Matches     bool
+	}{
+		{"Same MIG devices", enabled, &v1.MigConfigSpec{MigEnabled: true, MigDevices: types.MigConfig{"1g.5gb": 7}}, true},
+		{"Different MIG devices", enabled, &v1.MigConfigSpec{MigEnabled: true, MigDevices: types.MigConfig{"2g.10gb": 3}}, false},
+		{"Different MIG mode", enabled, disabled, false},
+		{"Both disabled", disabled, &v1.MigConfigSpec{MigEnabled: false, MigDevices: types.MigConfig{}}, true},
+		{"No baseline entry", disabled, nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			require.Equal(t, tc.Matches, matchesBaseline(tc.Spec, tc.Expected))
+		})
+	}
+}
+
+func TestCheckFlags(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Flags           Flags
+		ExpectedFailure bool
+	}{
+		{
+			"Stdout",
+			Flags{OutputFormat: YAMLFormat, OutputFile: "-"},
+			false,
+		},
+		{
+			"File",
+			Flags{OutputFormat: JSONFormat, OutputFile: "config.json"},
+			false,
+		},
+		{
+			"Unknown output format",
+			Flags{OutputFormat: "xml", OutputFile: "-"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := CheckFlags(&tc.Flags)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from CheckFlags")
+			} else {
+				require.Nil(t, err, "Unexpected failure from CheckFlags")
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// hoistGlobalFlags moves the top-level flags of 'app' that appear after the
+// subcommand in 'args' in front of it, so that they can be given anywhere on
+// the command line (e.g. 'apply -f config.yaml --debug'). Flags that the
+// subcommand (or one of its own subcommands) defines itself are left where
+// they are, as is everything after a '--'.
+func hoistGlobalFlags(args []string, app *cli.App) []string {
+	global := flagsByName(app.Flags)
+
+	i := 1
+	for ; i < len(args); i++ {
+		if args[i] == "--" {
+			return args
+		}
+		name, hasValue, ok := parseFlagArg(args[i])
+		if !ok {
+			break
+		}
+		if f, exists := global[name]; exists && !hasValue && takesValue(f) {
+			i++
+		}
+	}
+	if i >= len(args) {
+		return args
+	}
+
+	command := app.Command(args[i])
+	if command == nil {
+		return args
+	}
+	local := commandFlagNames(command)
+
+	var hoisted, rest []string
+	for j := i + 1; j < len(args); j++ {
+		if args[j] == "--" {
+			rest = append(rest, args[j:]...)
+			break
+		}
+		name, hasValue, ok := parseFlagArg(args[j])
+		f, isGlobal := global[name]
+		if !ok || !isGlobal || local[name] {
+			rest = append(rest, args[j])
+			continue
+		}
+		hoisted = append(hoisted, args[j])
+		if !hasValue && takesValue(f) && j+1 < len(args) {
+			j++
+			hoisted = append(hoisted, args[j])
+		}
+	}
+
+	result := append([]string{}, args[:i]...)
+	result = append(result, hoisted...)
+	result = append(result, args[i])
+	return append(result, rest...)
+}
+
+// parseFlagArg returns the name of the flag in 'arg' and whether its value
+// is given in it as well (i.e. '--name=value'). It returns false if 'arg' is
+// not a flag.
+func parseFlagArg(arg string) (string, bool, bool) {
+	if !strings.HasPrefix(arg, "-") {
+		return "", false, false
+	}
+	name := strings.TrimLeft(arg, "-")
+	if name == "" {
+		return "", false, false
+	}
+	name, _, hasValue := strings.Cut(name, "=")
+	return name, hasValue, true
+}
+
+// takesValue checks if flag 'f' is followed by a separate value argument
+// when not given as '--name=value'.
+func takesValue(f cli.Flag) bool {
+	_, isBool := f.(*cli.BoolFlag)
+	return !isBool
+}
+
+// flagsByName maps every name and alias of 'flags' to its flag.
+func flagsByName(flags []cli.Flag) map[string]cli.Flag {
+	names := make(map[string]cli.Flag)
+	for _, f := range flags {
+		for _, name := range f.Names() {
+			names[name] = f
+		}
+	}
+	return names
+}
+
+// commandFlagNames returns the set of names and aliases of all flags defined
+// by 'command' and its subcommands.
+func commandFlagNames(command *cli.Command) map[string]bool {
+	names := make(map[string]bool)
+	for _, f := range command.Flags {
+		for _, name := range f.Names() {
+			names[name] = true
+		}
+	}
+	for _, sub := range command.Subcommands {
+		for name := range commandFlagNames(sub) {
+			names[name] = true
+		}
+	}
+	return names
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+)
+
+func TestHoistGlobalFlags(t *testing.T) {
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		&cli.BoolFlag{Name: "debug", Aliases: []string{"d"}},
+		&cli.StringFlag{Name: "log-level"},
+		&cli.DurationFlag{Name: "timeout"},
+	}
+	app.Commands = []*cli.Command{
+		{
+			Name:    "apply",
+			Aliases: []string{"set"},
+			Flags: []cli.Flag{
+				&cli.StringFlag{Name: "config-file", Aliases: []string{"f"}},
+			},
+		},
+		{
+			Name: "other",
+			Flags: []cli.Flag{
+				&cli.BoolFlag{Name: "debug"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		Description string
+		Args        string
+		Expected    string
+	}{
+		{
+			"No global flags",
+			"mig-parted apply -f config.yaml",
+			"mig-parted apply -f config.yaml",
+		},
+		{
+			"Global flags before the subcommand",
+			"mig-parted -d --timeout 1m apply -f config.yaml",
+			"mig-parted -d --timeout 1m apply -f config.yaml",
+		},
+		{
+			"Global flags after the subcommand",
+			"mig-parted apply -f config.yaml --log-level warning -d --timeout=1m",
+			"mig-parted --log-level warning -d --timeout=1m apply -f config.yaml",
+		},
+		{
+			"Global flags after a subcommand alias",
+			"mig-parted --timeout 1m set --debug -f config.yaml",
+			"mig-parted --timeout 1m --debug set -f config.yaml",
+		},
+		{
+			"Global flags after '--'",
+			"mig-parted apply -f config.yaml -- --debug",
+			"mig-parted apply -f config.yaml -- --debug",
+		},
+		{
+			"Global flag also defined by the subcommand",
+			"mig-parted other --debug --timeout 1m",
+			"mig-parted --timeout 1m other --debug",
+		},
+		{
+			"Unknown subcommand",
+			"mig-parted unknown --debug",
+			"mig-parted unknown --debug",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			args := hoistGlobalFlags(strings.Fields(tc.Args), app)
+			require.Equal(t, tc.Expected, strings.Join(args, " "))
+		})
+	}
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fleet
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	fleet "github.com/NVIDIA/mig-parted/api/fleet/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+
+	"sigs.k8s.io/yaml"
+)
+
+var log = logrus.New()
+
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+type Flags struct {
+	OutputFormat string
+}
+
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	mergeFlags := Flags{}
+
+	// Create the 'merge' subcommand
+	merge := cli.Command{}
+	merge.Name = "merge"
+	merge.Usage = "Merge the fleet exports of multiple nodes into a single fleet manifest"
+	merge.ArgsUsage = "<fleet-export-file> [<fleet-export-file> ...]"
+	merge.Action = func(c *cli.Context) error {
+		return mergeWrapper(c, &mergeFlags)
+	}
+
+	// Setup the flags for this command
+	merge.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [json | yaml]",
+			Destination: &mergeFlags.OutputFormat,
+			Value:       export.YAMLFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+	}
+
+	// Create the 'fleet' command
+	fleet := cli.Command{}
+	fleet.Name = "fleet"
+	fleet.Usage = "Work with MIG configurations exported from multiple nodes"
+	fleet.Subcommands = []*cli.Command{
+		&merge,
+	}
+
+	return &fleet
+}
+
+func CheckFlags(f *Flags) error {
+	return export.CheckFlags(&export.Flags{OutputFormat: f.OutputFormat})
+}
+
+func mergeWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	if c.NArg() == 0 {
+		_ = cli.ShowSubcommandHelp(c)
+		return fmt.Errorf("at least one fleet export file is required")
+	}
+
+	var specs []*fleet.Spec
+	for _, file := range c.Args().Slice() {
+		log.Debugf("Parsing fleet export file '%v'...", file)
+		spec, err := ParseFleetFile(file)
+		if err != nil {
+			return fmt.Errorf("error parsing fleet export file '%v': %v", file, err)
+		}
+		specs = append(specs, spec)
+	}
+
+	merged, err := Merge(specs...)
+	if err != nil {
+		return fmt.Errorf("error merging fleet exports: %v", err)
+	}
+
+	return export.WriteOutput(os.Stdout, merged, &export.Flags{OutputFormat: f.OutputFormat})
+}
+
+// ParseFleetFile parses a fleet export file and unmarshals it into a 'fleet.Spec'.
+// A file name of '-' reads from stdin.
+func ParseFleetFile(file string) (*fleet.Spec, error) {
+	fleetYaml, err := util.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read error: %v", err)
+	}
+
+	var spec fleet.Spec
+	err = yaml.Unmarshal(fleetYaml, &spec)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshal error: %v", err)
+	}
+
+	return &spec, nil
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	fleet "github.com/NVIDIA/mig-parted/api/fleet/v1"
+)
+
+// Merge combines a set of fleet specs into a single fleet spec.
+// Nodes are sorted by name and grouped into config classes, where each class
+// holds the set of nodes whose exported MIG configs are identical. Classes are
+// ordered by size, with the largest class first.
+func Merge(specs ...*fleet.Spec) (*fleet.Spec, error) {
+	seen := make(map[string]bool)
+	var nodes []fleet.NodeSpec
+	for _, s := range specs {
+		for _, n := range s.Nodes {
+			if seen[n.Name] {
+				return nil, fmt.Errorf("duplicate node: %v", n.Name)
+			}
+			seen[n.Name] = true
+			nodes = append(nodes, n)
+		}
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+
+	var classes []fleet.ConfigClass
+	classIndex := make(map[string]int)
+	for _, n := range nodes {
+		if len(n.Spec.MigConfigs) != 1 {
+			return nil, fmt.Errorf("expected exactly one mig-config for node '%v', got %v", n.Name, len(n.Spec.MigConfigs))
+		}
+
+		for _, configs := range n.Spec.MigConfigs {
+			key, err := json.Marshal(configs)
+			if err != nil {
+				return nil, fmt.Errorf("error marshaling MIG config for node '%v': %v", n.Name, err)
+			}
+
+			i, exists := classIndex[string(key)]
+			if !exists {
+				i = len(classes)
+				classIndex[string(key)] = i
+				classes = append(classes, fleet.ConfigClass{MigConfigs: configs})
+			}
+			classes[i].Nodes = append(classes[i].Nodes, n.Name)
+		}
+	}
+
+	sort.SliceStable(classes, func(i, j int) bool {
+		return len(classes[i].Nodes) > len(classes[j].Nodes)
+	})
+	for i := range classes {
+		classes[i].Name = fmt.Sprintf("class-%d", i)
+	}
+
+	merged := fleet.Spec{
+		Version:       fleet.Version,
+		Nodes:         nodes,
+		ConfigClasses: classes,
+	}
+
+	return &merged, nil
+}
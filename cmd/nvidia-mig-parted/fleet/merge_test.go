@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fleet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	fleet "github.com/NVIDIA/mig-parted/api/fleet/v1"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newNodeSpec(name string, config v1.MigConfigSpecSlice) *fleet.Spec {
+	return &fleet.Spec{
+		Version: fleet.Version,
+		Nodes: []fleet.NodeSpec{
+			{
+				Name: name,
+				Spec: v1.Spec{
+					Version: v1.Version,
+					MigConfigs: map[string]v1.MigConfigSpecSlice{
+						"current": config,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestMerge(t *testing.T) {
+	disabled := v1.MigConfigSpecSlice{
+		{
+			Devices:    "all",
+			MigEnabled: false,
+		},
+	}
+	all1g := v1.MigConfigSpecSlice{
+		{
+			Devices:    "all",
+			MigEnabled: true,
+			MigDevices: types.MigConfig{
+				"1g.5gb": 7,
+			},
+		},
+	}
+
+	testCases := []struct {
+		Description     string
+		Input           []*fleet.Spec
+		Classes         map[string][]string
+		expectedFailure bool
+	}{
+		{
+			"Single node",
+			[]*fleet.Spec{
+				newNodeSpec("node-a", disabled),
+			},
+			map[string][]string{
+				"class-0": {"node-a"},
+			},
+			false,
+		},
+		{
+			"Multiple nodes - multiple classes",
+			[]*fleet.Spec{
+				newNodeSpec("node-c", disabled),
+				newNodeSpec("node-b", all1g),
+				newNodeSpec("node-a", all1g),
+			},
+			map[string][]string{
+				"class-0": {"node-a", "node-b"},
+				"class-1": {"node-c"},
+			},
+			false,
+		},
+		{
+			"Duplicate node",
+			[]*fleet.Spec{
+				newNodeSpec("node-a", disabled),
+				newNodeSpec("node-a", all1g),
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			merged, err := Merge(tc.Input...)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Merge")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Merge")
+
+			classes := make(map[string][]string)
+			for _, c := range merged.ConfigClasses {
+				classes[c.Name] = c.Nodes
+			}
+			require.Equal(t, tc.Classes, classes)
+
+			for i := 1; i < len(merged.Nodes); i++ {
+				require.Less(t, merged.Nodes[i-1].Name, merged.Nodes[i].Name)
+			}
+		})
+	}
+}
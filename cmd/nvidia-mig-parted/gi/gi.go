@@ -0,0 +1,518 @@
+/*
+ * Copyright (c) 2021, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/labels"
+	"github.com/NVIDIA/mig-parted/pkg/scratch"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+
+	// Unset is the value of an integer flag that was not provided.
+	Unset = -1
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'gi' subcommands.
+type Flags struct {
+	GPU             int
+	Profile         string
+	Placement       int
+	ID              int
+	OutputFormat    string
+	TTL             time.Duration
+	Owner           string
+	StoreFile       string
+	NodeLockFile    string
+	NodeLockTimeout time.Duration
+}
+
+// GpuInstance is a GPU instance along with the GPU it lives on, and the
+// labels of the entry of the MIG config last applied to that GPU.
+type GpuInstance struct {
+	GPU int `json:"gpu"`
+	types.GpuInstance
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	giFlags := Flags{}
+
+	gpuFlag := func(usage string) cli.Flag {
+		return &cli.IntFlag{
+			Name:        "gpu",
+			Aliases:     []string{"g"},
+			Usage:       usage,
+			Destination: &giFlags.GPU,
+			Value:       Unset,
+		}
+	}
+
+	ownerFlag := func(usage string) cli.Flag {
+		return &cli.StringFlag{
+			Name:        "owner",
+			Usage:       usage,
+			Destination: &giFlags.Owner,
+			EnvVars:     []string{"MIG_PARTED_SCRATCH_OWNER"},
+		}
+	}
+
+	storeFileFlag := &cli.StringFlag{
+		Name:        "store-file",
+		Usage:       "Path to the store the leases of scratch GPU instances and the labels of the applied MIG config are kept in",
+		Destination: &giFlags.StoreFile,
+		Value:       store.DefaultFile,
+		EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
+	}
+
+	nodeLockFileFlag := util.NodeLockFileFlag(&giFlags.NodeLockFile)
+	nodeLockTimeoutFlag := util.NodeLockTimeoutFlag(&giFlags.NodeLockTimeout)
+
+	outputFormatFlag := &cli.StringFlag{
+		Name:        "output-format",
+		Aliases:     []string{"o"},
+		Usage:       "Format for the output [text | json]",
+		Destination: &giFlags.OutputFormat,
+		Value:       TextFormat,
+		EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+	}
+
+	// Create the 'create' subcommand
+	create := cli.Command{}
+	create.Name = "create"
+	create.Usage = "Create a GPU instance on a MIG enabled GPU"
+	create.Action = func(c *cli.Context) error {
+		return createWrapper(c, &giFlags)
+	}
+	create.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to create the GPU instance on"),
+		&cli.StringFlag{
+			Name:        "profile",
+			Aliases:     []string{"p"},
+			Usage:       "Profile of the GPU instance to create, by name or numeric profile ID (e.g. 3g.20gb or 9)",
+			Destination: &giFlags.Profile,
+		},
+		&cli.IntFlag{
+			Name:        "placement",
+			Usage:       "Memory slice to start the GPU instance at (defaults to any valid placement)",
+			Destination: &giFlags.Placement,
+			Value:       Unset,
+		},
+		&cli.DurationFlag{
+			Name:        "ttl",
+			Usage:       "Create a scratch GPU instance, destroyed by 'daemon' once this long has passed unless claimed",
+			Destination: &giFlags.TTL,
+		},
+		ownerFlag("Owner of the scratch GPU instance, required with '--ttl'"),
+		storeFileFlag,
+		outputFormatFlag,
+		nodeLockFileFlag,
+		nodeLockTimeoutFlag,
+	}
+
+	// Create the 'destroy' subcommand
+	destroy := cli.Command{}
+	destroy.Name = "destroy"
+	destroy.Usage = "Destroy an empty GPU instance on a MIG enabled GPU"
+	destroy.Action = func(c *cli.Context) error {
+		return destroyWrapper(c, &giFlags)
+	}
+	destroy.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to destroy the GPU instance on"),
+		&cli.IntFlag{
+			Name:        "id",
+			Usage:       "ID of the GPU instance to destroy",
+			Destination: &giFlags.ID,
+			Value:       Unset,
+		},
+		storeFileFlag,
+		nodeLockFileFlag,
+		nodeLockTimeoutFlag,
+	}
+
+	// Create the 'claim' subcommand
+	claim := cli.Command{}
+	claim.Name = "claim"
+	claim.Usage = "Claim a scratch GPU instance, so that it is no longer destroyed once its TTL has passed"
+	claim.Action = func(c *cli.Context) error {
+		return claimWrapper(c, &giFlags)
+	}
+	claim.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU the scratch GPU instance is on"),
+		&cli.IntFlag{
+			Name:        "id",
+			Usage:       "ID of the scratch GPU instance to claim",
+			Destination: &giFlags.ID,
+			Value:       Unset,
+		},
+		ownerFlag("Owner of the scratch GPU instance"),
+		storeFileFlag,
+		nodeLockFileFlag,
+		nodeLockTimeoutFlag,
+	}
+
+	// Create the 'list' subcommand
+	list := cli.Command{}
+	list.Name = "list"
+	list.Usage = "List the GPU instances on MIG enabled GPUs"
+	list.Action = func(c *cli.Context) error {
+		return listWrapper(c, &giFlags)
+	}
+	list.Flags = []cli.Flag{
+		gpuFlag("Index of the GPU to list GPU instances for (defaults to all MIG enabled GPUs)"),
+		outputFormatFlag,
+		storeFileFlag,
+	}
+
+	// Create the 'gi' command
+	gi := cli.Command{}
+	gi.Name = "gi"
+	gi.Usage = "Manage individual GPU instances"
+	gi.Subcommands = []*cli.Command{
+		&create,
+		&destroy,
+		&claim,
+		&list,
+	}
+
+	return &gi
+}
+
+func CheckOutputFormat(format string) error {
+	switch format {
+	case TextFormat:
+	case JSONFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", format)
+	}
+	return nil
+}
+
+func CheckCreateFlags(f *Flags) error {
+	if f.GPU < 0 {
+		return fmt.Errorf("missing or invalid 'gpu': %v", f.GPU)
+	}
+	if f.Profile == "" {
+		return fmt.Errorf("missing 'profile'")
+	}
+	if f.Placement < Unset {
+		return fmt.Errorf("invalid 'placement': %v", f.Placement)
+	}
+	if f.TTL < 0 {
+		return fmt.Errorf("invalid 'ttl': %v", f.TTL)
+	}
+	if f.TTL > 0 && f.Owner == "" {
+		return fmt.Errorf("'ttl' requires 'owner'")
+	}
+	if f.TTL == 0 && f.Owner != "" {
+		return fmt.Errorf("'owner' requires 'ttl'")
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
+	return CheckOutputFormat(f.OutputFormat)
+}
+
+func CheckDestroyFlags(f *Flags) error {
+	if f.GPU < 0 {
+		return fmt.Errorf("missing or invalid 'gpu': %v", f.GPU)
+	}
+	if f.ID < 0 {
+		return fmt.Errorf("missing or invalid 'id': %v", f.ID)
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
+	return nil
+}
+
+func CheckClaimFlags(f *Flags) error {
+	err := CheckDestroyFlags(f)
+	if err != nil {
+		return err
+	}
+	if f.Owner == "" {
+		return fmt.Errorf("missing 'owner'")
+	}
+	return nil
+}
+
+func CheckListFlags(f *Flags) error {
+	if f.GPU < Unset {
+		return fmt.Errorf("invalid 'gpu': %v", f.GPU)
+	}
+	return CheckOutputFormat(f.OutputFormat)
+}
+
+func createWrapper(c *cli.Context, f *Flags) error {
+	err := CheckCreateFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	var start *uint32
+	if f.Placement != Unset {
+		s := uint32(f.Placement)
+		start = &s
+	}
+
+	profile, err := resolveProfileID(f.GPU, f.Profile)
+	if err != nil {
+		return err
+	}
+
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Debugf("Creating GPU instance '%v' on GPU %d", profile, f.GPU)
+	gi, err := manager.CreateGpuInstance(f.GPU, profile, start)
+	util.AuditGPUs(log, "gi create", profile, []int{f.GPU}, err)
+	if err != nil {
+		return fmt.Errorf("error creating GPU instance: %w", err)
+	}
+
+	if f.TTL > 0 {
+		err := addLease(f, *gi)
+		if err != nil {
+			derr := manager.DestroyGpuInstance(f.GPU, gi.ID)
+			util.AuditGPUs(log, "gi destroy", fmt.Sprintf("GPU instance %d", gi.ID), []int{f.GPU}, derr)
+			if derr != nil {
+				log.Errorf("Error destroying GPU instance %d on GPU %d: %v", gi.ID, f.GPU, derr)
+			}
+			return fmt.Errorf("error recording scratch GPU instance: %v", err)
+		}
+		log.Infof("GPU instance %d on GPU %d is a scratch GPU instance of '%v' for %v", gi.ID, f.GPU, f.Owner, f.TTL)
+	}
+
+	return WriteOutput(os.Stdout, []GpuInstance{{f.GPU, *gi, nil}}, f.OutputFormat)
+}
+
+// resolveProfileID translates 'profile' into a profile name if it was given as
+// a numeric profile ID of GPU 'gpu'.
+func resolveProfileID(gpu int, profile string) (string, error) {
+	if !types.IsMigProfileID(profile) {
+		return profile, nil
+	}
+	ids, err := util.GetMigProfileIDs(gpu)
+	if err != nil {
+		return "", fmt.Errorf("error getting MIG profile IDs of GPU %d: %v", gpu, err)
+	}
+	return ids.Name(profile)
+}
+
+func destroyWrapper(c *cli.Context, f *Flags) error {
+	err := CheckDestroyFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	log.Debugf("Destroying GPU instance %d on GPU %d", f.ID, f.GPU)
+	err = manager.DestroyGpuInstance(f.GPU, uint32(f.ID))
+	util.AuditGPUs(log, "gi destroy", fmt.Sprintf("GPU instance %d", f.ID), []int{f.GPU}, err)
+	if err != nil {
+		return fmt.Errorf("error destroying GPU instance: %w", err)
+	}
+
+	if f.StoreFile != "" {
+		err := scratch.Remove(f.StoreFile, f.GPU, uint32(f.ID))
+		if err != nil {
+			log.Warnf("Error dropping lease of GPU instance %d on GPU %d: %v", f.ID, f.GPU, err)
+		}
+	}
+
+	fmt.Printf("Destroyed GPU instance %d on GPU %d\n", f.ID, f.GPU)
+	return nil
+}
+
+// addLease records 'gi' as a scratch GPU instance of 'f.Owner' for 'f.TTL'.
+func addLease(f *Flags, gi types.GpuInstance) error {
+	s, err := store.Open(f.StoreFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	_, err = scratch.NewRegistry(s).Add(f.GPU, gi, f.Owner, f.TTL)
+	return err
+}
+
+func claimWrapper(c *cli.Context, f *Flags) error {
+	err := CheckClaimFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := store.Open(f.StoreFile)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	err = scratch.NewRegistry(s).Claim(f.GPU, uint32(f.ID), f.Owner)
+	if errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("GPU instance %d on GPU %d is not a scratch GPU instance", f.ID, f.GPU)
+	}
+	if err != nil {
+		return fmt.Errorf("error claiming GPU instance: %v", err)
+	}
+
+	fmt.Printf("Claimed GPU instance %d on GPU %d\n", f.ID, f.GPU)
+	return nil
+}
+
+func listWrapper(c *cli.Context, f *Flags) error {
+	err := CheckListFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	gpus := []int{f.GPU}
+	if f.GPU == Unset {
+		gpus, err = util.GetMigEnabledGPUs()
+		if err != nil {
+			return fmt.Errorf("error getting MIG enabled GPUs: %v", err)
+		}
+	}
+
+	manager, err := util.NewMigInstanceManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG instance Manager: %v", err)
+	}
+
+	entries, err := labels.Load(f.StoreFile)
+	if err != nil {
+		log.Warnf("Error getting labels of GPUs: %v", err)
+	}
+
+	gis := []GpuInstance{}
+	for _, gpu := range gpus {
+		list, err := manager.ListGpuInstances(gpu)
+		if err != nil {
+			return fmt.Errorf("error listing GPU instances for GPU %d: %w", gpu, err)
+		}
+		var gpuLabels map[string]string
+		if entry, exists := entries[gpu]; exists {
+			gpuLabels = entry.Labels
+		}
+		for _, gi := range list {
+			gis = append(gis, GpuInstance{gpu, gi, gpuLabels})
+		}
+	}
+
+	return WriteOutput(os.Stdout, gis, f.OutputFormat)
+}
+
+// WriteOutput writes 'gis' to 'w' as a table or as JSON.
+func WriteOutput(w io.Writer, gis []GpuInstance, format string) error {
+	if format == JSONFormat {
+		output, err := json.MarshalIndent(gis, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling GPU instances to JSON: %v", err)
+		}
+		output = append(output, '\n')
+		if _, err := w.Write(output); err != nil {
+			return fmt.Errorf("error writing JSON output: %w", err)
+		}
+		return nil
+	}
+
+	// The labels column is only shown if any GPU instance has labels.
+	withLabels := false
+	for _, gi := range gis {
+		withLabels = withLabels || len(gi.Labels) > 0
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	if withLabels {
+		fmt.Fprintln(tw, "GPU\tGI ID\tPROFILE\tPLACEMENT\tLABELS")
+	} else {
+		fmt.Fprintln(tw, "GPU\tGI ID\tPROFILE\tPLACEMENT")
+	}
+	for _, gi := range gis {
+		if withLabels {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%d:%d\t%s\n", gi.GPU, gi.ID, gi.Profile, gi.Placement.Start, gi.Placement.Size, formatLabels(gi.Labels))
+			continue
+		}
+		fmt.Fprintf(tw, "%d\t%d\t%s\t%d:%d\n", gi.GPU, gi.ID, gi.Profile, gi.Placement.Start, gi.Placement.Size)
+	}
+	return tw.Flush()
+}
+
+// formatLabels formats 'l' as a comma separated list of key=value pairs,
+// sorted by key.
+func formatLabels(l map[string]string) string {
+	var pairs []string
+	for k, v := range l {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
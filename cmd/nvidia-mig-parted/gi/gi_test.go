@@ -0,0 +1,176 @@
+/*
+ * Copyright (c) 2021, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package gi
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestWriteOutput(t *testing.T) {
+	gis := []GpuInstance{
+		{0, types.GpuInstance{Profile: "3g.20gb", ID: 1, Placement: nvml.GpuInstancePlacement{Start: 4, Size: 4}}, nil},
+		{1, types.GpuInstance{Profile: "1g.5gb", ID: 13, Placement: nvml.GpuInstancePlacement{Start: 6, Size: 1}}, nil},
+	}
+
+	testCases := []struct {
+		description string
+		format      string
+		expected    string
+	}{
+		{
+			"Text",
+			TextFormat,
+			"GPU  GI ID  PROFILE  PLACEMENT\n" +
+				"0    1      3g.20gb  4:4\n" +
+				"1    13     1g.5gb   6:1\n",
+		},
+		{
+			"JSON",
+			JSONFormat,
+			`[
+  {
+    "gpu": 0,
+    "profile": "3g.20gb",
+    "id": 1,
+    "placement": {
+      "Start": 4,
+      "Size": 4
+    }
+  },
+  {
+    "gpu": 1,
+    "profile": "1g.5gb",
+    "id": 13,
+    "placement": {
+      "Start": 6,
+      "Size": 1
+    }
+  }
+]
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := WriteOutput(&buf, gis, tc.format)
+			require.Nil(t, err, "Unexpected failure from WriteOutput")
+			require.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestWriteOutputWithLabels(t *testing.T) {
+	gis := []GpuInstance{
+		{0, types.GpuInstance{Profile: "3g.20gb", ID: 1, Placement: nvml.GpuInstancePlacement{Start: 4, Size: 4}}, map[string]string{"tier": "burst", "team": "vision"}},
+		{1, types.GpuInstance{Profile: "1g.5gb", ID: 13, Placement: nvml.GpuInstancePlacement{Start: 6, Size: 1}}, nil},
+	}
+
+	var buf bytes.Buffer
+	err := WriteOutput(&buf, gis, TextFormat)
+	require.Nil(t, err, "Unexpected failure from WriteOutput")
+	require.Equal(t, ""+
+		"GPU  GI ID  PROFILE  PLACEMENT  LABELS\n"+
+		"0    1      3g.20gb  4:4        team=vision,tier=burst\n"+
+		"1    13     1g.5gb   6:1        \n",
+		buf.String())
+
+	buf.Reset()
+	err = WriteOutput(&buf, gis[:1], JSONFormat)
+	require.Nil(t, err, "Unexpected failure from WriteOutput")
+	require.JSONEq(t, `[{
+		"gpu": 0,
+		"profile": "3g.20gb",
+		"id": 1,
+		"placement": {"Start": 4, "Size": 4},
+		"labels": {"team": "vision", "tier": "burst"}
+	}]`, buf.String())
+}
+
+func TestCheckCreateFlags(t *testing.T) {
+	testCases := []struct {
+		description     string
+		flags           Flags
+		expectedFailure bool
+	}{
+		{
+			"Valid",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat},
+			false,
+		},
+		{
+			"Valid with placement",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: 4, OutputFormat: JSONFormat},
+			false,
+		},
+		{
+			"Missing GPU",
+			Flags{GPU: Unset, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat},
+			true,
+		},
+		{
+			"Missing profile",
+			Flags{GPU: 0, Placement: Unset, OutputFormat: TextFormat},
+			true,
+		},
+		{
+			"Invalid output format",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: "yaml"},
+			true,
+		},
+		{
+			"Valid scratch",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat, TTL: time.Hour, Owner: "alice"},
+			false,
+		},
+		{
+			"Scratch without owner",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat, TTL: time.Hour},
+			true,
+		},
+		{
+			"Owner without TTL",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat, Owner: "alice"},
+			true,
+		},
+		{
+			"Negative TTL",
+			Flags{GPU: 0, Profile: "3g.20gb", Placement: Unset, OutputFormat: TextFormat, TTL: -time.Hour, Owner: "alice"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := CheckCreateFlags(&tc.flags)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from CheckCreateFlags")
+			} else {
+				require.Nil(t, err, "Unexpected failure from CheckCreateFlags")
+			}
+		})
+	}
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const DefaultListenAddress = ":8081"
+
+// Flags holds variables that represent the set of flags that can be passed to the 'health' subcommand.
+type Flags struct {
+	assert.Flags
+	ListenAddress string
+}
+
+// Status reports whether the selected MIG config is applied to the node and
+// whether a reboot (or GPU reset) is needed for a pending MIG mode change.
+type Status struct {
+	SelectedConfig string `json:"selected-config"`
+	ConfigApplied  bool   `json:"config-applied"`
+	RebootPending  bool   `json:"reboot-pending"`
+	Error          string `json:"error,omitempty"`
+}
+
+// Healthy checks if the 'Status' could be determined at all.
+func (s *Status) Healthy() bool {
+	return s.Error == ""
+}
+
+// Ready checks if the selected MIG config is fully applied and in effect.
+func (s *Status) Ready() bool {
+	return s.Healthy() && s.ConfigApplied && !s.RebootPending
+}
+
+// BuildCommand builds the 'health' subcommand.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	healthFlags := Flags{}
+
+	// Create the 'health' command
+	health := cli.Command{}
+	health.Name = "health"
+	health.Usage = "Serve /healthz and /readyz endpoints reporting whether a MIG config is applied to the node"
+	health.Action = func(c *cli.Context) error {
+		return healthWrapper(c, &healthFlags)
+	}
+
+	// Setup the flags for this command
+	health.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the configuration file",
+			Destination: &healthFlags.ConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "selected-config",
+			Aliases:     []string{"c"},
+			Usage:       "The label of the mig-config from the config file expected to be applied to the node",
+			Destination: &healthFlags.SelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "listen-address",
+			Aliases:     []string{"l"},
+			Usage:       "Address to serve the health endpoints on",
+			Destination: &healthFlags.ListenAddress,
+			Value:       DefaultListenAddress,
+			EnvVars:     []string{"MIG_PARTED_HEALTH_LISTEN_ADDRESS"},
+		},
+	}
+
+	return &health
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	var missing []string
+	if f.ConfigFile == "" {
+		missing = append(missing, "config-file")
+	}
+	if f.ListenAddress == "" {
+		missing = append(missing, "listen-address")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
+	}
+	if util.IsStdio(f.ConfigFile) {
+		return fmt.Errorf("the health server cannot read its configuration from stdin")
+	}
+	return nil
+}
+
+func healthWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	server := &http.Server{
+		Addr:              f.ListenAddress,
+		Handler:           NewHandler(func() *Status { return GetStatus(c, f) }),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(stop)
+
+	go func() {
+		<-stop
+		log.Info("Shutting down")
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	log.Infof("Serving health endpoints on %v", f.ListenAddress)
+	err = server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving health endpoints: %v", err)
+	}
+	return nil
+}
+
+// NewHandler returns an 'http.Handler' serving '/healthz' and '/readyz'. Both
+// report the 'Status' returned by 'status' as JSON. '/healthz' fails only if
+// the status could not be determined, '/readyz' also fails while the selected
+// MIG config is not applied or a reboot is pending.
+func NewHandler(status func() *Status) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		s := status()
+		writeStatus(w, s, s.Healthy())
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s := status()
+		writeStatus(w, s, s.Ready())
+	})
+	return mux
+}
+
+func writeStatus(w http.ResponseWriter, s *Status, ok bool) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := json.NewEncoder(w).Encode(s)
+	if err != nil {
+		log.Errorf("Error writing health status: %v", err)
+	}
+}
+
+// GetStatus determines the current 'Status' of the node. The config file is
+// re-read on every call so that updates to it are picked up immediately.
+func GetStatus(c *cli.Context, f *Flags) *Status {
+	// Work on a copy of the flags so that a defaulted selected-config from
+	// one version of the config file does not leak into the next.
+	flags := f.Flags
+	status := &Status{}
+
+	spec, err := assert.ParseConfigFile(&flags)
+	if err != nil {
+		status.Error = fmt.Sprintf("error parsing config file: %v", err)
+		return status
+	}
+
+	migConfig, err := assert.GetSelectedMigConfig(&flags, spec)
+	if err != nil {
+		status.Error = fmt.Sprintf("error selecting MIG config: %v", err)
+		return status
+	}
+	status.SelectedConfig = flags.SelectedConfig
+
+	assertContext := assert.Context{
+		Context:          c,
+		Flags:            &flags,
+		MigConfig:        migConfig,
+		UnmanagedDevices: spec.UnmanagedDevices,
+		Nvml:             nvml.New(),
+	}
+
+	status.RebootPending, err = isMigModeChangePending(&assertContext)
+	if err != nil {
+		status.Error = fmt.Sprintf("error checking for pending MIG mode changes: %v", err)
+		return status
+	}
+
+	err = assert.AssertMigMode(&assertContext)
+	if err == nil {
+		err = assert.AssertMigConfig(&assertContext)
+	}
+	if err != nil {
+		log.Debugf("Selected MIG config not applied: %v", err)
+	}
+	status.ConfigApplied = err == nil
+
+	return status
+}
+
+// isMigModeChangePending checks if any MIG capable GPU selected by the MIG
+// config in 'c' has a MIG mode change that only takes effect after a reboot
+// (or GPU reset).
+func isMigModeChangePending(c *assert.Context) (bool, error) {
+	err := util.NvmlInit(c.Nvml)
+	if err != nil {
+		return false, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(c.Nvml)
+
+	pending := false
+	err = assert.WalkSelectedMigConfigForEachGPU(c.MigConfig, c.UnmanagedDevices, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		manager, err := util.NewMigModeManager()
+		if err != nil {
+			return fmt.Errorf("error creating MIG mode Manager: %v", err)
+		}
+
+		capable, err := manager.IsMigCapable(i)
+		if err != nil {
+			return fmt.Errorf("error checking MIG capable: %v", err)
+		}
+		if !capable {
+			return nil
+		}
+
+		p, err := manager.IsMigModeChangePending(i)
+		if err != nil {
+			return fmt.Errorf("error checking for pending MIG mode change on GPU %v: %v", i, err)
+		}
+		pending = pending || p
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return pending, nil
+}
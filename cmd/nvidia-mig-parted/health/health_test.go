@@ -0,0 +1,83 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandler(t *testing.T) {
+	testCases := []struct {
+		description     string
+		status          Status
+		expectedHealthz int
+		expectedReadyz  int
+	}{
+		{
+			"Config applied",
+			Status{SelectedConfig: "all-1g.10gb", ConfigApplied: true},
+			http.StatusOK,
+			http.StatusOK,
+		},
+		{
+			"Config not applied",
+			Status{SelectedConfig: "all-1g.10gb"},
+			http.StatusOK,
+			http.StatusServiceUnavailable,
+		},
+		{
+			"Reboot pending",
+			Status{SelectedConfig: "all-1g.10gb", ConfigApplied: true, RebootPending: true},
+			http.StatusOK,
+			http.StatusServiceUnavailable,
+		},
+		{
+			"Status unknown",
+			Status{Error: "error parsing config file"},
+			http.StatusServiceUnavailable,
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			handler := NewHandler(func() *Status {
+				s := tc.status
+				return &s
+			})
+
+			for endpoint, expectedCode := range map[string]int{
+				"/healthz": tc.expectedHealthz,
+				"/readyz":  tc.expectedReadyz,
+			} {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, endpoint, nil))
+				require.Equal(t, expectedCode, w.Code, "Unexpected status code from %v", endpoint)
+
+				var status Status
+				err := json.Unmarshal(w.Body.Bytes(), &status)
+				require.Nil(t, err)
+				require.Equal(t, tc.status, status)
+			}
+		})
+	}
+}
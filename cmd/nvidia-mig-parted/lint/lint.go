@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lint
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/policy"
+	"github.com/NVIDIA/mig-parted/pkg/simulate"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'lint' subcommand.
+type Flags struct {
+	ConfigFile     string
+	SelectedConfig string
+	PolicyFile     string
+	MigStrategy    string
+	GpuModels      cli.StringSlice
+}
+
+// BuildCommand builds the 'lint' subcommand.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	lintFlags := Flags{}
+
+	// Create the 'lint' command
+	lint := cli.Command{}
+	lint.Name = "lint"
+	lint.Usage = "Check a configuration file for errors without touching any GPUs"
+	lint.Action = func(c *cli.Context) error {
+		return lintWrapper(c, &lintFlags)
+	}
+
+	// Setup the flags for this command
+	lint.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the configuration file ('-' for stdin)",
+			Destination: &lintFlags.ConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "selected-config",
+			Aliases:     []string{"c"},
+			Usage:       "The label of the mig-config from the config file to lint (all if unset)",
+			Destination: &lintFlags.SelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "policy-file",
+			Usage:       "Path to a policy file the mig-configs must comply with",
+			Destination: &lintFlags.PolicyFile,
+			EnvVars:     []string{"MIG_PARTED_POLICY_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "strategy",
+			Usage:       "MIG strategy of the Kubernetes device plugin to warn about mig-configs incompatible with: [none | single | mixed] (defaults to the 'mig-strategy' of the config file)",
+			Destination: &lintFlags.MigStrategy,
+			EnvVars:     []string{"MIG_PARTED_MIG_STRATEGY"},
+		},
+		&cli.StringSliceFlag{
+			Name:        "gpu-model",
+			Usage:       fmt.Sprintf("GPU model to check that the mig-configs fit on, from the tables built into this binary rather than any GPU (may be repeated) [%v]", strings.Join(simulate.GpuModelNames(), " | ")),
+			Destination: &lintFlags.GpuModels,
+			EnvVars:     []string{"MIG_PARTED_GPU_MODEL"},
+		},
+	}
+
+	return &lint
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	var missing []string
+	if f.ConfigFile == "" {
+		missing = append(missing, "config-file")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
+	}
+	if util.IsStdio(f.ConfigFile) && util.IsStdio(f.PolicyFile) {
+		return fmt.Errorf("only one of 'config-file' and 'policy-file' can be read from stdin")
+	}
+	if f.MigStrategy != "" {
+		err := v1.AssertValidMigStrategy(f.MigStrategy)
+		if err != nil {
+			return fmt.Errorf("invalid 'strategy': %v", err)
+		}
+	}
+	for _, model := range f.GpuModels.Value() {
+		_, err := simulate.NewGpuModel(model)
+		if err != nil {
+			return fmt.Errorf("invalid 'gpu-model': %v", err)
+		}
+	}
+	return nil
+}
+
+func lintWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	log.Debugf("Parsing config file...")
+	spec, err := assert.ParseConfigFile(&assert.Flags{ConfigFile: f.ConfigFile})
+	if err != nil {
+		return fmt.Errorf("error parsing config file: %v", err)
+	}
+
+	var p *policy.Policy
+	if f.PolicyFile != "" {
+		log.Debugf("Parsing policy file...")
+		p, err = apply.ParsePolicyFile(f.PolicyFile)
+		if err != nil {
+			return fmt.Errorf("error parsing policy file: %v", err)
+		}
+	}
+
+	problems, err := Lint(spec, f.SelectedConfig, p)
+	if err != nil {
+		return err
+	}
+
+	for _, model := range f.GpuModels.Value() {
+		modelProblems, err := LintGpuModel(spec, f.SelectedConfig, model)
+		if err != nil {
+			return err
+		}
+		problems = append(problems, modelProblems...)
+	}
+
+	strategy := f.MigStrategy
+	if strategy == "" {
+		strategy = spec.MigStrategy
+	}
+	if strategy != "" {
+		warnings, err := LintMigStrategy(spec, f.SelectedConfig, strategy)
+		if err != nil {
+			return err
+		}
+		for _, w := range warnings {
+			log.Warnf("%v", util.Capitalize(w))
+		}
+	}
+
+	if len(problems) != 0 {
+		for _, p := range problems {
+			log.Errorf("%v", util.Capitalize(p))
+		}
+		return fmt.Errorf("config file failed lint")
+	}
+
+	fmt.Println("Config file is valid")
+	return nil
+}
+
+// Lint checks the mig-config named 'selected' in 'spec' (or all of them if
+// 'selected' is empty) and returns a description of every problem found. If
+// 'p' is non-nil, each mig-config is also checked against it.
+func Lint(spec *v1.Spec, selected string, p *policy.Policy) ([]string, error) {
+	names, err := configNames(spec, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, name := range names {
+		for i := range spec.MigConfigs[name] {
+			if p == nil {
+				continue
+			}
+			for _, v := range p.CheckSpec(&spec.MigConfigs[name][i]) {
+				problems = append(problems, fmt.Sprintf("mig-config '%v' entry %v: %v", name, i, v))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+// LintMigStrategy checks the mig-config named 'selected' in 'spec' (or all
+// of them if 'selected' is empty) against the MIG strategy 'strategy' of
+// the Kubernetes device plugin, and returns a description of every
+// incompatibility found. These are only warnings, as whether they matter
+// depends on the GPUs of the node the mig-config is applied to.
+func LintMigStrategy(spec *v1.Spec, selected string, strategy string) ([]string, error) {
+	names, err := configNames(spec, selected)
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []string
+	for _, name := range names {
+		for _, problem := range spec.MigConfigs[name].CheckMigStrategy(strategy) {
+			warnings = append(warnings, fmt.Sprintf("mig-config '%v' %v", name, problem))
+		}
+	}
+
+	return warnings, nil
+}
+
+// LintGpuModel checks that each entry of the mig-config named 'selected'
+// in 'spec' (or of all of them if 'selected' is empty) that applies to GPU
+// model 'model' fits on it, and returns a description of every entry that
+// does not. The GPU is simulated from the tables built into this binary, so
+// no GPU needs to be present. Entries whose device filter leaves out the
+// model, and those disabling MIG, are skipped. Entries with a 'fill' profile
+// are filled with as many of it as fit, and fractional profiles (e.g. "half")
+// are resolved to the profiles of the model.
+func LintGpuModel(spec *v1.Spec, selected string, model string) ([]string, error) {
+	names, err := configNames(spec, selected)
+	if err != nil {
+		return nil, err
+	}
+	deviceIDs, err := types.GetGpuModelDeviceIDs(model)
+	if err != nil {
+		return nil, err
+	}
+
+	var problems []string
+	for _, name := range names {
+		for i := range spec.MigConfigs[name] {
+			mc := &spec.MigConfigs[name][i]
+			if !mc.MigEnabled || !matchesAnyDeviceID(mc, deviceIDs) {
+				continue
+			}
+			gpu, err := simulate.NewGpuModel(model)
+			if err != nil {
+				return nil, err
+			}
+			resolved := *mc
+			err = resolved.ResolveFractionalProfiles(gpu.MigProfiles())
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("mig-config '%v' entry %v cannot be resolved on %v: %v", name, i, model, err))
+				continue
+			}
+			config := resolved.MigDevices
+			if resolved.Fill != "" {
+				config = fillMigConfig(gpu, config, resolved.Fill)
+			}
+			err = gpu.Apply(config)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("mig-config '%v' entry %v does not fit on %v: %v", name, i, model, err))
+			}
+		}
+	}
+
+	return problems, nil
+}
+
+func matchesAnyDeviceID(mc *v1.MigConfigSpec, deviceIDs []types.DeviceID) bool {
+	for _, id := range deviceIDs {
+		if mc.MatchesDeviceFilter(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// fillMigConfig returns 'config' with as many more MIG devices of 'fill'
+// added to it as still fit on 'gpu'.
+func fillMigConfig(gpu *simulate.GPU, config types.MigConfig, fill string) types.MigConfig {
+	filled := config
+	for {
+		next := types.MigConfig{}
+		for profile, count := range filled {
+			next[profile] = count
+		}
+		next[fill]++
+		if gpu.Apply(next) != nil {
+			return filled
+		}
+		filled = next
+	}
+}
+
+// configNames returns the name 'selected' if it names a mig-config in
+// 'spec', or the names of all of them in order if 'selected' is empty.
+func configNames(spec *v1.Spec, selected string) ([]string, error) {
+	if selected != "" {
+		if _, exists := spec.MigConfigs[selected]; !exists {
+			return nil, fmt.Errorf("selected mig-config not present: %v", selected)
+		}
+		return []string{selected}, nil
+	}
+
+	var names []string
+	for name := range spec.MigConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,320 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package lint
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/policy"
+)
+
+func TestLint(t *testing.T) {
+	var spec v1.Spec
+	err := yaml.Unmarshal([]byte(`
+version: v1
+mig-configs:
+  all-disabled:
+  - devices: all
+    mig-enabled: false
+  all-1g.10gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.10gb": 7
+  h100-3g.40gb:
+  - device-filter: "0x233010DE"
+    devices: all
+    mig-enabled: true
+    mig-devices:
+      "3g.40gb": 2
+`), &spec)
+	require.Nil(t, err)
+
+	p, err := policy.Parse([]byte(`
+version: v1
+rules:
+- name: max-instances
+  max-instances: 4
+- name: no-small-profiles-on-h100
+  device-filter: "0x233010DE"
+  min-slices: 2
+`))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description      string
+		selected         string
+		policy           *policy.Policy
+		expectedProblems int
+		expectedFailure  bool
+	}{
+		{
+			"No policy",
+			"",
+			nil,
+			0,
+			false,
+		},
+		{
+			"All configs",
+			"",
+			p,
+			2,
+			false,
+		},
+		{
+			"Compliant config",
+			"h100-3g.40gb",
+			p,
+			0,
+			false,
+		},
+		{
+			"Disabled config",
+			"all-disabled",
+			p,
+			0,
+			false,
+		},
+		{
+			"Missing config",
+			"bogus",
+			p,
+			0,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			problems, err := Lint(&spec, tc.selected, tc.policy)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Lint")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Lint")
+			require.Len(t, problems, tc.expectedProblems)
+		})
+	}
+}
+
+func TestLintMigStrategy(t *testing.T) {
+	var spec v1.Spec
+	err := yaml.Unmarshal([]byte(`
+version: v1
+mig-configs:
+  all-disabled:
+  - devices: all
+    mig-enabled: false
+  all-1g.10gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.10gb": 7
+  all-balanced:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.10gb": 2
+      "2g.20gb": 1
+      "3g.40gb": 1
+`), &spec)
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description      string
+		selected         string
+		strategy         string
+		expectedWarnings int
+		expectedFailure  bool
+	}{
+		{
+			"All configs under single strategy",
+			"",
+			v1.MigStrategySingle,
+			1,
+			false,
+		},
+		{
+			"All configs under mixed strategy",
+			"",
+			v1.MigStrategyMixed,
+			0,
+			false,
+		},
+		{
+			"All configs under none strategy",
+			"",
+			v1.MigStrategyNone,
+			2,
+			false,
+		},
+		{
+			"Homogeneous config under single strategy",
+			"all-1g.10gb",
+			v1.MigStrategySingle,
+			0,
+			false,
+		},
+		{
+			"Missing config",
+			"bogus",
+			v1.MigStrategySingle,
+			0,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			warnings, err := LintMigStrategy(&spec, tc.selected, tc.strategy)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from LintMigStrategy")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from LintMigStrategy")
+			require.Len(t, warnings, tc.expectedWarnings)
+		})
+	}
+}
+
+func TestLintGpuModel(t *testing.T) {
+	var spec v1.Spec
+	err := yaml.Unmarshal([]byte(`
+version: v1
+mig-configs:
+  all-disabled:
+  - devices: all
+    mig-enabled: false
+  all-1g.10gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "1g.10gb": 7
+  all-3g.40gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "3g.40gb": 2
+  all-3g.20gb-fill-1g.5gb:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "3g.20gb": 1
+    fill: "1g.5gb"
+  all-half:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "half": 2
+  all-half-fill-quarter:
+  - devices: all
+    mig-enabled: true
+    mig-devices:
+      "half": 1
+    fill: "quarter"
+  h100-3g.40gb:
+  - device-filter: "0x233010DE"
+    devices: all
+    mig-enabled: true
+    mig-devices:
+      "3g.40gb": 3
+`), &spec)
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description      string
+		selected         string
+		model            string
+		expectedProblems int
+		expectedFailure  bool
+	}{
+		{
+			"All configs on A100-SXM4-40GB",
+			"",
+			"A100-SXM4-40GB",
+			2,
+			false,
+		},
+		{
+			"All configs on A100-SXM4-80GB",
+			"",
+			"A100-SXM4-80GB",
+			1,
+			false,
+		},
+		{
+			"All configs on H100-SXM5-80GB",
+			"",
+			"H100-SXM5-80GB",
+			2,
+			false,
+		},
+		{
+			"Filled config on A100-SXM4-40GB",
+			"all-3g.20gb-fill-1g.5gb",
+			"A100-SXM4-40GB",
+			0,
+			false,
+		},
+		{
+			"Fractional config on A100-SXM4-40GB",
+			"all-half",
+			"A100-SXM4-40GB",
+			0,
+			false,
+		},
+		{
+			"Filled fractional config on H100-SXM5-80GB",
+			"all-half-fill-quarter",
+			"H100-SXM5-80GB",
+			0,
+			false,
+		},
+		{
+			"Unknown model",
+			"",
+			"bogus",
+			0,
+			true,
+		},
+		{
+			"Missing config",
+			"bogus",
+			"A100-SXM4-40GB",
+			0,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			problems, err := LintGpuModel(&spec, tc.selected, tc.model)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from LintGpuModel")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from LintGpuModel")
+			require.Len(t, problems, tc.expectedProblems, "%v", problems)
+		})
+	}
+}
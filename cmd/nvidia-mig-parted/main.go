@@ -17,23 +17,50 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/capabilities"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/caps"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/checkpoint"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/ci"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/collect"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/daemon"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/examples"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/fleet"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/gi"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/health"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/lint"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/migrate"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/recommend"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/relocate"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/restore"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/schema"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/slurm"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/spec"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/status"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/visualize"
 	"github.com/NVIDIA/mig-parted/internal/info"
 )
 
 // Flags holds variables that represent the set of top level flags that can be passed to the mig-parted CLI.
 type Flags struct {
-	Debug bool
+	Debug    bool
+	LogLevel string
+	Backend  string
+	Timeout  time.Duration
+
+	NvmlLibrary string
+	DriverRoot  string
 }
 
 func main() {
@@ -52,10 +79,43 @@ func main() {
 		&cli.BoolFlag{
 			Name:        "debug",
 			Aliases:     []string{"d"},
-			Usage:       "Enable debug-level logging",
+			Usage:       "Enable debug-level logging (same as '--log-level=debug')",
 			Destination: &flags.Debug,
 			EnvVars:     []string{"MIG_PARTED_DEBUG"},
 		},
+		&cli.StringFlag{
+			Name:        "log-level",
+			Usage:       "Level to log at [panic | fatal | error | warning | info | debug | trace]",
+			Destination: &flags.LogLevel,
+			Value:       log.InfoLevel.String(),
+			EnvVars:     []string{"MIG_PARTED_LOG_LEVEL"},
+		},
+		&cli.StringFlag{
+			Name:        "backend",
+			Usage:       "Backend used to query and change MIG settings [auto | nvml | smi]; 'auto' falls back to nvidia-smi for operations NVML does not support",
+			Destination: &flags.Backend,
+			Value:       util.AutoBackend,
+			EnvVars:     []string{"MIG_PARTED_BACKEND"},
+		},
+		&cli.DurationFlag{
+			Name:        "timeout",
+			Usage:       "Time after which 'apply' stops at its next safe point (e.g. '10m', 0 for no limit)",
+			Destination: &flags.Timeout,
+			EnvVars:     []string{"MIG_PARTED_TIMEOUT"},
+		},
+		&cli.StringFlag{
+			Name:        "nvml-library",
+			Usage:       "Path to the NVML library to load, instead of searching the library path, the standard library directories and the driver root for it",
+			Destination: &flags.NvmlLibrary,
+			EnvVars:     []string{"MIG_PARTED_NVML_LIBRARY"},
+		},
+		&cli.StringFlag{
+			Name:        "driver-root",
+			Usage:       "Root of the NVIDIA driver installation (e.g. mounted by a driver container) to search for the NVML library if it is not in the library path",
+			Destination: &flags.DriverRoot,
+			Value:       util.DefaultDriverRoot,
+			EnvVars:     []string{"MIG_PARTED_DRIVER_ROOT"},
+		},
 	}
 
 	// Register the subcommands with the top-level CLI
@@ -65,29 +125,78 @@ func main() {
 		export.BuildCommand(),
 		checkpoint.BuildCommand(),
 		restore.BuildCommand(),
+		fleet.BuildCommand(),
+		visualize.BuildCommand(),
+		daemon.BuildCommand(),
+		gi.BuildCommand(),
+		ci.BuildCommand(),
+		lint.BuildCommand(),
+		health.BuildCommand(),
+		status.BuildCommand(),
+		recommend.BuildCommand(),
+		caps.BuildCommand(),
+		slurm.BuildCommand(),
+		collect.BuildCommand(),
+		relocate.BuildCommand(),
+		spec.BuildCommand(),
+		capabilities.BuildCommand(),
+		examples.BuildCommand(),
+		migrate.BuildCommand(),
+		schema.BuildCommand(),
 	}
 
-	// Set log-level for all subcommands
+	// Set log-level for all subcommands and bound the run by the timeout
+	cancel := func() {}
 	c.Before = func(c *cli.Context) error {
-		logLevel := log.InfoLevel
+		logLevel, err := log.ParseLevel(flags.LogLevel)
+		if err != nil {
+			return fmt.Errorf("invalid 'log-level': %v", flags.LogLevel)
+		}
 		if flags.Debug {
 			logLevel = log.DebugLevel
 		}
-		applyLog := apply.GetLogger()
-		applyLog.SetLevel(logLevel)
-		assertLog := assert.GetLogger()
-		assertLog.SetLevel(logLevel)
-		exportLog := export.GetLogger()
-		exportLog.SetLevel(logLevel)
-		checkpointLog := export.GetLogger()
-		checkpointLog.SetLevel(logLevel)
-		restoreLog := export.GetLogger()
-		restoreLog.SetLevel(logLevel)
-		return nil
+		for _, logger := range []*log.Logger{
+			apply.GetLogger(),
+			assert.GetLogger(),
+			export.GetLogger(),
+			checkpoint.GetLogger(),
+			restore.GetLogger(),
+			fleet.GetLogger(),
+			visualize.GetLogger(),
+			daemon.GetLogger(),
+			gi.GetLogger(),
+			ci.GetLogger(),
+			lint.GetLogger(),
+			health.GetLogger(),
+			status.GetLogger(),
+			recommend.GetLogger(),
+			caps.GetLogger(),
+			slurm.GetLogger(),
+			collect.GetLogger(),
+			relocate.GetLogger(),
+			spec.GetLogger(),
+			capabilities.GetLogger(),
+			examples.GetLogger(),
+			migrate.GetLogger(),
+			schema.GetLogger(),
+		} {
+			logger.SetLevel(logLevel)
+		}
+		if flags.Timeout > 0 {
+			c.Context, cancel = context.WithTimeout(c.Context, flags.Timeout)
+		}
+		// Not all subcommands need NVML, so failing to find it only fails
+		// those that do (with this error), unless a library was given.
+		err = util.LoadNvmlLibrary(flags.NvmlLibrary, flags.DriverRoot)
+		if err != nil && flags.NvmlLibrary != "" {
+			return err
+		}
+		return util.SetBackend(flags.Backend)
 	}
 
-	// Run the CLI
-	err := c.Run(os.Args)
+	// Run the CLI, accepting the top-level flags after the subcommand as well
+	err := c.Run(hoistGlobalFlags(os.Args, c))
+	cancel()
 	if err != nil {
 		log.Fatalf(util.Capitalize(err.Error()))
 	}
@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/api/spec/migrate"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'migrate-spec' subcommand.
+type Flags struct {
+	ConfigFile string
+	From       string
+	To         string
+	OutputFile string
+	InPlace    bool
+}
+
+// BuildCommand builds the 'migrate-spec' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	migrateFlags := Flags{}
+
+	// Create the 'migrate-spec' command
+	migrateSpec := cli.Command{}
+	migrateSpec.Name = "migrate-spec"
+	migrateSpec.Usage = "Rewrite a configuration file for a newer version of the spec, annotating anything that needs manual attention"
+	migrateSpec.Action = func(c *cli.Context) error {
+		return migrateWrapper(c, &migrateFlags)
+	}
+
+	// Setup the flags for this command
+	migrateSpec.Flags = []cli.Flag{
+		util.ConfigFileFlag(&migrateFlags.ConfigFile, "Path to the configuration file to migrate ('-' for stdin)"),
+		&cli.StringFlag{
+			Name:        "from",
+			Usage:       "Version of the spec the configuration file is written for (defaults to the 'version' it declares)",
+			Destination: &migrateFlags.From,
+			EnvVars:     []string{"MIG_PARTED_MIGRATE_FROM"},
+		},
+		&cli.StringFlag{
+			Name:        "to",
+			Usage:       fmt.Sprintf("Version of the spec to migrate the configuration file to [%v]", strings.Join(migrate.Versions, " | ")),
+			Destination: &migrateFlags.To,
+			EnvVars:     []string{"MIG_PARTED_MIGRATE_TO"},
+		},
+		&cli.StringFlag{
+			Name:        "output-file",
+			Usage:       "File to write the migrated configuration file to ('-' for stdout)",
+			Destination: &migrateFlags.OutputFile,
+			Value:       util.StdioPath,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "in-place",
+			Aliases:     []string{"i"},
+			Usage:       "Overwrite the configuration file with its migrated version instead of writing it to 'output-file'",
+			Destination: &migrateFlags.InPlace,
+			EnvVars:     []string{"MIG_PARTED_MIGRATE_IN_PLACE"},
+		},
+	}
+
+	return &migrateSpec
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	var missing []string
+	if f.ConfigFile == "" {
+		missing = append(missing, "config-file")
+	}
+	if f.To == "" {
+		missing = append(missing, "to")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
+	}
+	if f.InPlace && util.IsStdio(f.ConfigFile) {
+		return fmt.Errorf("'in-place' cannot be used when reading the config file from stdin")
+	}
+	if f.InPlace && !util.IsStdio(f.OutputFile) {
+		return fmt.Errorf("'in-place' cannot be combined with 'output-file'")
+	}
+	return nil
+}
+
+func migrateWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	content, err := util.ReadFile(f.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	log.Debugf("Migrating config file to %v...", f.To)
+	output, notes, err := migrate.Migrate(content, f.From, f.To)
+	if err != nil {
+		return fmt.Errorf("error migrating config file: %v", err)
+	}
+
+	for _, n := range notes {
+		log.Warnf("%v needs manual attention at %v", f.ConfigFile, n)
+	}
+
+	if f.InPlace {
+		return writeInPlace(f.ConfigFile, output)
+	}
+
+	err = util.WriteToFile(f.OutputFile, func(w io.Writer) error {
+		_, err := w.Write(output)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
+
+// writeInPlace atomically replaces the file at 'path' with 'content',
+// keeping its permissions.
+func writeInPlace(path string, content []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(info.Mode().Perm())
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		return fmt.Errorf("error writing config file: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckFlags(t *testing.T) {
+	testCases := []struct {
+		Description     string
+		Flags           Flags
+		ExpectedFailure bool
+	}{
+		{"Valid", Flags{ConfigFile: "config.yaml", To: "v1", OutputFile: "-"}, false},
+		{"Valid in place", Flags{ConfigFile: "config.yaml", To: "v1", OutputFile: "-", InPlace: true}, false},
+		{"Missing to", Flags{ConfigFile: "config.yaml", OutputFile: "-"}, true},
+		{"Missing config file", Flags{To: "v1", OutputFile: "-"}, true},
+		{"In place from stdin", Flags{ConfigFile: "-", To: "v1", OutputFile: "-", InPlace: true}, true},
+		{"In place with output file", Flags{ConfigFile: "config.yaml", To: "v1", OutputFile: "out.yaml", InPlace: true}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := CheckFlags(&tc.Flags)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from CheckFlags")
+			} else {
+				require.Nil(t, err, "Unexpected failure from CheckFlags")
+			}
+		})
+	}
+}
+
+func TestWriteInPlace(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.Nil(t, os.WriteFile(path, []byte("version: v1\n"), 0600))
+
+	err := writeInPlace(path, []byte("version: v2\n"))
+	require.Nil(t, err, "Unexpected failure from writeInPlace")
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, "version: v2\n", string(content))
+
+	info, err := os.Stat(path)
+	require.Nil(t, err)
+	require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	entries, err := os.ReadDir(dir)
+	require.Nil(t, err)
+	require.Len(t, entries, 1, "Temporary file left behind")
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recommend
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	TextFormat         = "text"
+	JSONFormat         = export.JSONFormat
+	YAMLFormat         = export.YAMLFormat
+	DefaultConfigLabel = "recommended"
+	DefaultTop         = 5
+)
+
+// Flags holds variables that represent the set of flags that can be passed to the 'recommend' subcommand.
+type Flags struct {
+	Workloads    cli.StringSlice
+	GPU          int
+	Top          int
+	OutputFormat string
+	ConfigLabel  string
+}
+
+// BuildCommand builds the 'recommend' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	recommendFlags := Flags{}
+
+	// Create the 'recommend' command
+	recommend := cli.Command{}
+	recommend.Name = "recommend"
+	recommend.Usage = "Recommend a MIG config for a GPU that best fits a mix of workloads"
+	recommend.Action = func(c *cli.Context) error {
+		return recommendWrapper(c, &recommendFlags)
+	}
+
+	// Setup the flags for this command
+	recommend.Flags = []cli.Flag{
+		&cli.StringSliceFlag{
+			Name:        "workload",
+			Aliases:     []string{"w"},
+			Usage:       "Workloads to fit as '[COUNTx]<slices>g.<memory>gb', e.g. '4x1g.5gb' (may be repeated)",
+			Destination: &recommendFlags.Workloads,
+			EnvVars:     []string{"MIG_PARTED_WORKLOADS"},
+		},
+		&cli.IntFlag{
+			Name:        "gpu",
+			Aliases:     []string{"g"},
+			Usage:       "Index of the GPU whose MIG config group the recommendation is drawn from",
+			Destination: &recommendFlags.GPU,
+			EnvVars:     []string{"MIG_PARTED_GPU"},
+		},
+		&cli.IntFlag{
+			Name:        "top",
+			Aliases:     []string{"n"},
+			Usage:       "Number of ranked configs to show with '--output-format=text'",
+			Destination: &recommendFlags.Top,
+			Value:       DefaultTop,
+			EnvVars:     []string{"MIG_PARTED_TOP"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [yaml | json | text]",
+			Destination: &recommendFlags.OutputFormat,
+			Value:       YAMLFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:        "config-label",
+			Aliases:     []string{"l"},
+			Usage:       "Label of the recommended config in the output spec",
+			Destination: &recommendFlags.ConfigLabel,
+			Value:       DefaultConfigLabel,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_LABEL"},
+		},
+	}
+
+	return &recommend
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case YAMLFormat:
+	case JSONFormat:
+	case TextFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	if len(f.Workloads.Value()) == 0 {
+		return fmt.Errorf("missing required flag 'workload'")
+	}
+	if f.GPU < 0 {
+		return fmt.Errorf("invalid 'gpu': %v", f.GPU)
+	}
+	if f.Top <= 0 {
+		return fmt.Errorf("invalid 'top': %v", f.Top)
+	}
+	return nil
+}
+
+// ParseWorkloads parses every workload passed in 'f'.
+func ParseWorkloads(f *Flags) ([]Workload, error) {
+	var workloads []Workload
+	for _, s := range f.Workloads.Value() {
+		w, err := ParseWorkload(strings.TrimSpace(s))
+		if err != nil {
+			return nil, err
+		}
+		workloads = append(workloads, w)
+	}
+	return workloads, nil
+}
+
+// BuildSpec builds a ready-to-apply spec enabling MIG with the config in
+// 'score' on all GPUs.
+func BuildSpec(label string, score Score) *v1.Spec {
+	return &v1.Spec{
+		Version: v1.Version,
+		MigConfigs: map[string]v1.MigConfigSpecSlice{
+			label: {
+				{
+					Devices:    "all",
+					MigEnabled: true,
+					MigDevices: score.Config,
+				},
+			},
+		},
+	}
+}
+
+// WriteRanking writes the first 'top' entries of 'scores' as a table.
+func WriteRanking(w io.Writer, scores []Score, top int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "RANK\tFITS\tUNPLACED\tWASTED-SLICES\tWASTED-MEMORY\tFRAGMENTS\tCONFIG")
+	for i, s := range scores {
+		if i == top {
+			break
+		}
+		fmt.Fprintf(tw, "%d\t%v\t%d\t%d\t%dgb\t%d\t%v\n", i+1, s.Fits, s.Unplaced, s.WastedSlices, s.WastedMemoryGB, s.Fragments, configString(s.Config))
+	}
+	return tw.Flush()
+}
+
+func recommendWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	workloads, err := ParseWorkloads(f)
+	if err != nil {
+		return fmt.Errorf("error parsing workloads: %v", err)
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	device, ret := nvmlLib.DeviceGetHandleByIndex(f.GPU)
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting device handle for GPU %d: %v", f.GPU, ret)
+	}
+
+	group, err := config.GetConfigGroupForDevice(device)
+	if err != nil {
+		return fmt.Errorf("error getting MIG config group for GPU %d: %v", f.GPU, err)
+	}
+
+	scores := Rank(group, workloads)
+
+	if f.OutputFormat == TextFormat {
+		return WriteRanking(os.Stdout, scores, f.Top)
+	}
+
+	if len(scores) == 0 || !scores[0].Fits {
+		return fmt.Errorf("no MIG config of GPU %d fits all requested workloads", f.GPU)
+	}
+
+	return export.WriteOutput(os.Stdout, BuildSpec(f.ConfigLabel, scores[0]), &export.Flags{OutputFormat: f.OutputFormat})
+}
@@ -0,0 +1,209 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recommend
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var workloadRegex = regexp.MustCompile(`^(?:([0-9]+)x)?([0-9]+)g\.([0-9]+)gb$`)
+
+// Workload describes a number of identical workloads, each needing a MIG
+// device with at least 'Slices' compute slices and 'MemoryGB' of memory.
+type Workload struct {
+	Count    int
+	Slices   int
+	MemoryGB int
+}
+
+// Score describes how well a MIG config fits a set of workloads.
+type Score struct {
+	Config types.MigConfig
+	// Fits is true if every workload can be given its own MIG device.
+	Fits bool
+	// Unplaced is the number of workloads left without a MIG device.
+	Unplaced int
+	// WastedSlices and WastedMemoryGB count the capacity of the assigned MIG
+	// devices beyond what their workloads asked for.
+	WastedSlices   int
+	WastedMemoryGB int
+	// Fragments counts the pieces the leftover capacity is split into: one
+	// per idle MIG device plus one if any slices are left unpartitioned.
+	Fragments int
+}
+
+// ParseWorkload parses a workload of the form '[COUNTx]<slices>g.<memory>gb'
+// (e.g. '4x1g.5gb').
+func ParseWorkload(workload string) (Workload, error) {
+	match := workloadRegex.FindStringSubmatch(workload)
+	if match == nil {
+		return Workload{}, fmt.Errorf("invalid workload '%v': expected '[COUNTx]<slices>g.<memory>gb'", workload)
+	}
+
+	w := Workload{Count: 1}
+	if match[1] != "" {
+		w.Count, _ = strconv.Atoi(match[1])
+	}
+	w.Slices, _ = strconv.Atoi(match[2])
+	w.MemoryGB, _ = strconv.Atoi(match[3])
+
+	if w.Count == 0 || w.Slices == 0 || w.MemoryGB == 0 {
+		return Workload{}, fmt.Errorf("invalid workload '%v': count, slices and memory must be non-zero", workload)
+	}
+
+	return w, nil
+}
+
+// String returns a 'Workload' in the format accepted by ParseWorkload.
+func (w Workload) String() string {
+	return fmt.Sprintf("%dx%dg.%dgb", w.Count, w.Slices, w.MemoryGB)
+}
+
+// Rank scores every possible configuration of 'group' against 'workloads'
+// and returns them best first. Configurations that fit all workloads always
+// rank above those that do not, followed by the least wasted capacity and
+// the least fragmented leftover capacity.
+func Rank(group types.MigConfigGroup, workloads []Workload) []Score {
+	profiles := make(map[string]*types.MigProfile)
+	totalSlices := 0
+	for _, mp := range group.GetDeviceTypes() {
+		profiles[mp.String()] = mp
+		if mp.G > totalSlices {
+			totalSlices = mp.G
+		}
+	}
+
+	var scores []Score
+	for _, config := range group.GetPossibleConfigurations() {
+		scores = append(scores, ScoreConfig(config, profiles, totalSlices, workloads))
+	}
+
+	sort.SliceStable(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.Fits != b.Fits {
+			return a.Fits
+		}
+		if a.Unplaced != b.Unplaced {
+			return a.Unplaced < b.Unplaced
+		}
+		if a.WastedSlices != b.WastedSlices {
+			return a.WastedSlices < b.WastedSlices
+		}
+		if a.WastedMemoryGB != b.WastedMemoryGB {
+			return a.WastedMemoryGB < b.WastedMemoryGB
+		}
+		if a.Fragments != b.Fragments {
+			return a.Fragments < b.Fragments
+		}
+		if ca, cb := countAttributes(a.Config, profiles), countAttributes(b.Config, profiles); ca != cb {
+			return ca < cb
+		}
+		return configString(a.Config) < configString(b.Config)
+	})
+
+	return scores
+}
+
+// ScoreConfig scores a single MIG config against 'workloads'. Workloads are
+// placed largest first, each on the smallest free MIG device large enough to
+// hold it. The 'profiles' map resolves the profile names used in 'config'.
+func ScoreConfig(config types.MigConfig, profiles map[string]*types.MigProfile, totalSlices int, workloads []Workload) Score {
+	var devices []*types.MigProfile
+	usedSlices := 0
+	for name, count := range config {
+		for i := 0; i < count; i++ {
+			devices = append(devices, profiles[name])
+			usedSlices += profiles[name].G
+		}
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].G != devices[j].G {
+			return devices[i].G < devices[j].G
+		}
+		if devices[i].GB != devices[j].GB {
+			return devices[i].GB < devices[j].GB
+		}
+		return len(devices[i].Attributes) < len(devices[j].Attributes)
+	})
+
+	sorted := make([]Workload, len(workloads))
+	copy(sorted, workloads)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Slices != sorted[j].Slices {
+			return sorted[i].Slices > sorted[j].Slices
+		}
+		return sorted[i].MemoryGB > sorted[j].MemoryGB
+	})
+
+	score := Score{Config: config}
+	assigned := make([]bool, len(devices))
+	for _, w := range sorted {
+		for n := 0; n < w.Count; n++ {
+			placed := false
+			for i, d := range devices {
+				if assigned[i] || d.G < w.Slices || d.GB < w.MemoryGB {
+					continue
+				}
+				assigned[i] = true
+				score.WastedSlices += d.G - w.Slices
+				score.WastedMemoryGB += d.GB - w.MemoryGB
+				placed = true
+				break
+			}
+			if !placed {
+				score.Unplaced++
+			}
+		}
+	}
+
+	for _, a := range assigned {
+		if !a {
+			score.Fragments++
+		}
+	}
+	if usedSlices < totalSlices {
+		score.Fragments++
+	}
+	score.Fits = score.Unplaced == 0
+
+	return score
+}
+
+func countAttributes(config types.MigConfig, profiles map[string]*types.MigProfile) int {
+	count := 0
+	for name, n := range config {
+		if len(profiles[name].Attributes) > 0 {
+			count += n
+		}
+	}
+	return count
+}
+
+func configString(config types.MigConfig) string {
+	var parts []string
+	for name, count := range config {
+		parts = append(parts, fmt.Sprintf("%v: %v", name, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,154 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package recommend
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestParseWorkload(t *testing.T) {
+	testCases := []struct {
+		workload        string
+		expected        Workload
+		expectedFailure bool
+	}{
+		{"4x1g.5gb", Workload{4, 1, 5}, false},
+		{"3g.20gb", Workload{1, 3, 20}, false},
+		{"10x2g.10gb", Workload{10, 2, 10}, false},
+		{"0x1g.5gb", Workload{}, true},
+		{"1c.1g.5gb", Workload{}, true},
+		{"4x", Workload{}, true},
+		{"small", Workload{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.workload, func(t *testing.T) {
+			w, err := ParseWorkload(tc.workload)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from ParseWorkload")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from ParseWorkload")
+			require.Equal(t, tc.expected, w)
+		})
+	}
+}
+
+func TestRank(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewDGXA100()
+	group, err := config.GetConfigGroupForDevice(server.Devices[0])
+	require.Nil(t, err, "Unexpected failure from GetConfigGroupForDevice")
+
+	testCases := []struct {
+		description    string
+		workloads      []Workload
+		expectedConfig types.MigConfig
+		expectedFits   bool
+	}{
+		{
+			"Exact fit",
+			[]Workload{{4, 1, 5}, {1, 3, 20}},
+			types.MigConfig{"1g.5gb": 4, "3g.20gb": 1},
+			true,
+		},
+		{
+			"Single large workload",
+			[]Workload{{1, 7, 40}},
+			types.MigConfig{"7g.40gb": 1},
+			true,
+		},
+		{
+			"Memory bound workload",
+			[]Workload{{1, 1, 10}},
+			types.MigConfig{"1g.10gb": 1, "2g.10gb": 1, "4g.20gb": 1},
+			true,
+		},
+		{
+			"Too many workloads",
+			[]Workload{{8, 1, 5}},
+			types.MigConfig{"1g.5gb": 7},
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			scores := Rank(group, tc.workloads)
+			require.NotEmpty(t, scores)
+			require.Equal(t, tc.expectedFits, scores[0].Fits)
+			require.Equal(t, tc.expectedConfig, scores[0].Config)
+			require.Nil(t, group.AssertValidConfiguration(scores[0].Config))
+		})
+	}
+}
+
+func TestScoreConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewDGXA100()
+	group, err := config.GetConfigGroupForDevice(server.Devices[0])
+	require.Nil(t, err, "Unexpected failure from GetConfigGroupForDevice")
+
+	profiles := make(map[string]*types.MigProfile)
+	for _, mp := range group.GetDeviceTypes() {
+		profiles[mp.String()] = mp
+	}
+
+	workloads := []Workload{{2, 1, 5}}
+
+	score := ScoreConfig(types.MigConfig{"2g.10gb": 2, "1g.5gb": 3}, profiles, 7, workloads)
+	require.Equal(t, Score{
+		Config:    types.MigConfig{"2g.10gb": 2, "1g.5gb": 3},
+		Fits:      true,
+		Fragments: 3,
+	}, score)
+
+	score = ScoreConfig(types.MigConfig{"3g.20gb": 1}, profiles, 7, workloads)
+	require.Equal(t, Score{
+		Config:         types.MigConfig{"3g.20gb": 1},
+		Fits:           false,
+		Unplaced:       1,
+		WastedSlices:   2,
+		WastedMemoryGB: 15,
+		Fragments:      1,
+	}, score)
+}
+
+func TestWriteRanking(t *testing.T) {
+	scores := []Score{
+		{Config: types.MigConfig{"1g.5gb": 4, "3g.20gb": 1}, Fits: true},
+		{Config: types.MigConfig{"7g.40gb": 1}, Unplaced: 4, WastedSlices: 4, WastedMemoryGB: 20},
+	}
+
+	var output bytes.Buffer
+	err := WriteRanking(&output, scores, 1)
+	require.Nil(t, err, "Unexpected failure from WriteRanking")
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	require.Len(t, lines, 2)
+	require.Contains(t, lines[1], "1g.5gb: 4, 3g.20gb: 1")
+}
@@ -0,0 +1,309 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package relocate
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	relocation "github.com/NVIDIA/mig-parted/pkg/relocate"
+)
+
+var log = logrus.New()
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+	YAMLFormat = "yaml"
+)
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'relocate' subcommand.
+type Flags struct {
+	PlanFile     string
+	Policy       string
+	OutputFormat string
+}
+
+// BuildCommand builds the 'relocate' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	relocateFlags := Flags{}
+
+	// Create the 'relocate' command
+	relocate := cli.Command{}
+	relocate.Name = "relocate"
+	relocate.Usage = "Plan where to move the workloads running on MIG devices that applying a plan would destroy"
+	relocate.Action = func(c *cli.Context) error {
+		return relocateWrapper(c, &relocateFlags)
+	}
+
+	// Setup the flags for this command
+	relocate.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "plan",
+			Aliases:     []string{"p"},
+			Usage:       "Path to a plan saved with 'apply --dry-run -o json' ('-' for stdin)",
+			Destination: &relocateFlags.PlanFile,
+			EnvVars:     []string{"MIG_PARTED_PLAN_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "policy",
+			Usage:       "Which surviving MIG devices a workload can be moved to: [same-profile | same-or-larger]",
+			Destination: &relocateFlags.Policy,
+			Value:       relocation.PolicySameProfile,
+			EnvVars:     []string{"MIG_PARTED_RELOCATION_POLICY"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [text | json | yaml]",
+			Destination: &relocateFlags.OutputFormat,
+			Value:       TextFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+	}
+
+	return &relocate
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	if f.PlanFile == "" {
+		return fmt.Errorf("missing required flag 'plan'")
+	}
+	switch f.Policy {
+	case relocation.PolicySameProfile:
+	case relocation.PolicySameOrLarger:
+	default:
+		return fmt.Errorf("unrecognized 'policy': %v", f.Policy)
+	}
+	switch f.OutputFormat {
+	case TextFormat:
+	case JSONFormat:
+	case YAMLFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	return nil
+}
+
+// GetMigDevices returns every MIG device that currently exists on the GPUs
+// of the node, along with the processes running on it.
+func GetMigDevices(nvmlLib nvml.Interface) ([]relocation.MigDevice, error) {
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	all := []relocation.MigDevice{}
+	for i := 0; i < count; i++ {
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %d: %v", i, err)
+		}
+		if !capable {
+			continue
+		}
+
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %d: %v", i, err)
+		}
+		if m != mode.Enabled {
+			continue
+		}
+
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		handles, err := getMigDeviceHandles(device)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG device handles for GPU %d: %v", i, err)
+		}
+
+		devices, err := configManager.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices for GPU %d: %v", i, err)
+		}
+
+		for _, d := range devices {
+			mig, exists := handles[[2]uint32{d.GpuInstanceID, d.ComputeInstanceID}]
+			if !exists {
+				return nil, fmt.Errorf("no MIG device handle found for GPU %d, GPU instance %d, compute instance %d", i, d.GpuInstanceID, d.ComputeInstanceID)
+			}
+
+			uuid, ret := mig.GetUUID()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("error getting UUID of MIG device on GPU %d: %v", i, ret)
+			}
+
+			processes, ret := mig.GetComputeRunningProcesses()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("error getting processes running on MIG device %v: %v", uuid, ret)
+			}
+
+			migDevice := relocation.MigDevice{
+				GPU:               i,
+				UUID:              uuid,
+				Profile:           d.Profile,
+				GpuInstanceID:     d.GpuInstanceID,
+				ComputeInstanceID: d.ComputeInstanceID,
+			}
+			for _, p := range processes {
+				migDevice.PIDs = append(migDevice.PIDs, p.Pid)
+			}
+			all = append(all, migDevice)
+		}
+	}
+
+	return all, nil
+}
+
+// getMigDeviceHandles returns the handles of all MIG devices on 'device',
+// keyed by their GPU instance and compute instance IDs.
+func getMigDeviceHandles(device nvml.Device) (map[[2]uint32]nvml.Device, error) {
+	maxCount, ret := device.GetMaxMigDeviceCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting max MIG device count: %v", ret)
+	}
+
+	handles := make(map[[2]uint32]nvml.Device)
+	for j := 0; j < maxCount; j++ {
+		mig, ret := device.GetMigDeviceHandleByIndex(j)
+		if ret == nvml.ERROR_NOT_FOUND {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting MIG device handle %d: %v", j, ret)
+		}
+
+		giID, ret := mig.GetGpuInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance ID of MIG device %d: %v", j, ret)
+		}
+
+		ciID, ret := mig.GetComputeInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting compute instance ID of MIG device %d: %v", j, ret)
+		}
+
+		handles[[2]uint32{uint32(giID), uint32(ciID)}] = mig
+	}
+
+	return handles, nil
+}
+
+// WriteRelocations writes 'relocations' to 'w' in the requested 'format'.
+func WriteRelocations(w io.Writer, format string, relocations []relocation.Relocation) error {
+	switch format {
+	case JSONFormat, YAMLFormat:
+		return export.WriteOutput(w, relocations, &export.Flags{OutputFormat: format})
+	}
+
+	if len(relocations) == 0 {
+		fmt.Fprintln(w, "No running workloads are affected")
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "GPU\tFROM\tPROFILE\tPIDS\tTO-GPU\tTO\tTO-PROFILE")
+	for _, r := range relocations {
+		var pids []string
+		for _, pid := range r.From.PIDs {
+			pids = append(pids, fmt.Sprintf("%d", pid))
+		}
+		to := "-\tnone\t-"
+		if r.To != nil {
+			to = fmt.Sprintf("%d\t%v\t%v", r.To.GPU, r.To.UUID, r.To.Profile)
+		}
+		fmt.Fprintf(tw, "%d\t%v\t%v\t%v\t%v\n", r.From.GPU, r.From.UUID, r.From.Profile, strings.Join(pids, ","), to)
+	}
+	return tw.Flush()
+}
+
+func relocateWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	log.Debugf("Reading plan file...")
+	plan, err := apply.ReadPlanFile(f.PlanFile)
+	if err != nil {
+		return fmt.Errorf("error reading plan file: %v", err)
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	log.Debugf("Listing MIG devices and the processes running on them...")
+	devices, err := GetMigDevices(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error getting MIG devices: %v", err)
+	}
+
+	relocations, err := relocation.Plan(devices, relocation.DestroyedBy(plan), f.Policy)
+	if err != nil {
+		return fmt.Errorf("error planning relocations: %v", err)
+	}
+
+	unplaced := 0
+	for _, r := range relocations {
+		if r.To == nil {
+			unplaced++
+		}
+	}
+	if unplaced > 0 {
+		log.Warnf("No compatible MIG device left for the workloads on %d MIG device(s)", unplaced)
+	}
+
+	return WriteRelocations(os.Stdout, f.OutputFormat, relocations)
+}
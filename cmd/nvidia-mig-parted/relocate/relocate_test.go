@@ -0,0 +1,66 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package relocate
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	relocation "github.com/NVIDIA/mig-parted/pkg/relocate"
+)
+
+func TestWriteRelocations(t *testing.T) {
+	relocations := []relocation.Relocation{
+		{
+			From: relocation.MigDevice{GPU: 0, UUID: "MIG-a", Profile: "1g.5gb", PIDs: []uint32{100, 101}},
+			To:   &relocation.MigDevice{GPU: 1, UUID: "MIG-b", Profile: "1g.5gb"},
+		},
+		{
+			From: relocation.MigDevice{GPU: 0, UUID: "MIG-c", Profile: "2g.10gb", PIDs: []uint32{200}},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		relocations []relocation.Relocation
+		expected    string
+	}{
+		{
+			"no relocations",
+			[]relocation.Relocation{},
+			"No running workloads are affected\n",
+		},
+		{
+			"relocations",
+			relocations,
+			"GPU  FROM   PROFILE  PIDS     TO-GPU  TO     TO-PROFILE\n" +
+				"0    MIG-a  1g.5gb   100,101  1       MIG-b  1g.5gb\n" +
+				"0    MIG-c  2g.10gb  200      -       none   -\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var output bytes.Buffer
+			err := WriteRelocations(&output, TextFormat, tc.relocations)
+			require.Nil(t, err, "Unexpected failure from WriteRelocations")
+			require.Equal(t, tc.expected, output.String())
+		})
+	}
+}
@@ -17,19 +17,22 @@
 package restore
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	cli "github.com/urfave/cli/v2"
 
-	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v1"
+	checkpoint "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
 	hooks "github.com/NVIDIA/mig-parted/api/hooks/v1"
 	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	migcheckpoint "github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/checkpoint"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
 	"github.com/NVIDIA/mig-parted/pkg/mig/state"
+	"github.com/NVIDIA/mig-parted/pkg/scratch"
+	"github.com/NVIDIA/mig-parted/pkg/store"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
@@ -40,9 +43,13 @@ func GetLogger() *logrus.Logger {
 }
 
 type Flags struct {
-	CheckpointFile string
-	HooksFile      string
-	ModeOnly       bool
+	CheckpointFile  string
+	HooksFile       string
+	ModeOnly        bool
+	Relaxed         bool
+	StoreFile       string
+	NodeLockFile    string
+	NodeLockTimeout time.Duration
 }
 
 type Context struct {
@@ -51,6 +58,9 @@ type Context struct {
 	Hooks           apply.ApplyHooks
 	MigState        *types.MigState
 	MigStateManager state.Manager
+	// changed records whether the MIG mode or MIG devices of any GPU were
+	// changed, as they are only restored if they differ from the checkpoint.
+	changed bool
 }
 
 func BuildCommand() *cli.Command {
@@ -60,6 +70,7 @@ func BuildCommand() *cli.Command {
 	// Create the 'restore' command
 	restore := cli.Command{}
 	restore.Name = "restore"
+	restore.Aliases = []string{"load"}
 	restore.Usage = "Restore MIG state from a checkpoint file"
 	restore.Action = func(c *cli.Context) error {
 		return restoreWrapper(c, &restoreFlags)
@@ -67,27 +78,25 @@ func BuildCommand() *cli.Command {
 
 	// Setup the flags for this command
 	restore.Flags = []cli.Flag{
-		&cli.StringFlag{
-			Name:        "checkpoint-file",
-			Aliases:     []string{"f"},
-			Usage:       "Path to the checkpoint file",
-			Destination: &restoreFlags.CheckpointFile,
-			EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
+		util.CheckpointFileFlag(&restoreFlags.CheckpointFile, "Path to the checkpoint file ('-' for stdin)"),
+		util.HooksFileFlag(&restoreFlags.HooksFile),
+		util.ModeOnlyFlag(&restoreFlags.ModeOnly, "Only change the MIG enabled setting from the checkpoint file, not configure any MIG devices"),
+		&cli.BoolFlag{
+			Name:        "relaxed",
+			Aliases:     []string{"r"},
+			Usage:       "Match the GPUs of the checkpoint to GPUs of the same model and memory on this node, instead of requiring the same GPU UUIDs",
+			Destination: &restoreFlags.Relaxed,
+			EnvVars:     []string{"MIG_PARTED_RELAXED"},
 		},
 		&cli.StringFlag{
-			Name:        "hooks-file",
-			Aliases:     []string{"k"},
-			Usage:       "Path to the hooks file",
-			Destination: &restoreFlags.HooksFile,
-			EnvVars:     []string{"MIG_PARTED_HOOKS_FILE"},
-		},
-		&cli.BoolFlag{
-			Name:        "mode-only",
-			Aliases:     []string{"m"},
-			Usage:       "Only change the MIG enabled setting from the checkpoint file, not configure any MIG devices",
-			Destination: &restoreFlags.ModeOnly,
-			EnvVars:     []string{"MIG_PARTED_MODE_CHANGE_ONLY"},
+			Name:        "store-file",
+			Usage:       "Path to the store to drop the leases of the scratch GPU instances replaced by the restored MIG state from (disabled if empty)",
+			Destination: &restoreFlags.StoreFile,
+			Value:       store.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
 		},
+		util.NodeLockFileFlag(&restoreFlags.NodeLockFile),
+		util.NodeLockTimeoutFlag(&restoreFlags.NodeLockTimeout),
 	}
 
 	return &restore
@@ -101,23 +110,28 @@ func CheckFlags(f *Flags) error {
 	if len(missing) > 0 {
 		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
 	}
+	if util.IsStdio(f.CheckpointFile) && util.IsStdio(f.HooksFile) {
+		return fmt.Errorf("only one of 'checkpoint-file' and 'hooks-file' can be read from stdin")
+	}
+	if f.NodeLockTimeout < 0 {
+		return fmt.Errorf("invalid 'node-lock-timeout': %v", f.NodeLockTimeout)
+	}
 
 	return nil
 }
 
 func ParseCheckpointFile(f *Flags) (*checkpoint.State, error) {
-	checkpointJson, err := os.ReadFile(f.CheckpointFile)
+	checkpointJson, err := util.ReadFile(f.CheckpointFile)
 	if err != nil {
 		return nil, fmt.Errorf("read error: %v", err)
 	}
 
-	var state checkpoint.State
-	err = json.Unmarshal(checkpointJson, &state)
+	state, err := checkpoint.ParseState(checkpointJson)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal error: %v", err)
 	}
 
-	return &state, nil
+	return state, nil
 }
 
 func (c *Context) AssertMigMode() error {
@@ -145,10 +159,12 @@ func (c *Context) AssertMigConfig() error {
 }
 
 func (c *Context) ApplyMigMode() error {
+	c.changed = true
 	return c.MigStateManager.RestoreMode(c.MigState)
 }
 
 func (c *Context) ApplyMigConfig() error {
+	c.changed = true
 	return c.MigStateManager.RestoreConfig(c.MigState)
 }
 
@@ -182,7 +198,53 @@ func restoreWrapper(c *cli.Context, f *Flags) error {
 		MigStateManager: state.NewMigStateManager(),
 	}
 
+	unlock, err := util.LockNode(c.Context, log, f.NodeLockFile, f.NodeLockTimeout)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if f.Relaxed {
+		if len(checkpoint.Devices) == 0 {
+			return fmt.Errorf("checkpoint cannot be restored with 'relaxed': it does not record the models of its GPUs")
+		}
+		log.Debugf("Matching checkpointed GPUs to GPUs of the same model on the current node...")
+		context.MigState, err = context.MigStateManager.Rebind(context.MigState, migcheckpoint.DeviceModels(checkpoint.Devices))
+		if err != nil {
+			return fmt.Errorf("checkpoint cannot be restored: %v", err)
+		}
+	}
+
+	log.Debugf("Remapping checkpointed MIG profiles to the current driver...")
+	context.MigState, err = context.MigStateManager.Remap(context.MigState)
+	if err != nil {
+		if checkpoint.DriverVersion != "" {
+			return fmt.Errorf("checkpoint taken with driver version %v cannot be restored: %v", checkpoint.DriverVersion, err)
+		}
+		return fmt.Errorf("checkpoint cannot be restored: %v", err)
+	}
+
+	log.Debugf("Validating checkpoint against current node...")
+	err = context.MigStateManager.Validate(context.MigState)
+	if err != nil {
+		if checkpoint.DriverVersion != "" {
+			return fmt.Errorf("checkpoint taken with driver version %v cannot be restored: %v", checkpoint.DriverVersion, err)
+		}
+		return fmt.Errorf("checkpoint cannot be restored: %v", err)
+	}
+
 	err = apply.ApplyMigConfigWithHooks(log, c, f.ModeOnly, context.Hooks, &context)
+	gpus := restoredGPUs(context.MigState)
+	util.AuditGPUs(log, "restore", f.CheckpointFile, gpus, err)
+	if f.StoreFile != "" && context.changed {
+		// The GPU instances restored are those of the checkpoint, even one
+		// with the ID, profile and placement of a scratch GPU instance
+		// destroyed along the way, so none of them may be reaped.
+		serr := scratch.Invalidate(f.StoreFile, gpus)
+		if serr != nil {
+			log.Warnf("Error dropping leases of scratch GPU instances: %v", serr)
+		}
+	}
 	if err != nil {
 		return fmt.Errorf("error applying MIG configuration with hooks: %v", err)
 	}
@@ -190,3 +252,23 @@ func restoreWrapper(c *cli.Context, f *Flags) error {
 	fmt.Println("MIG configuration restored successfully")
 	return nil
 }
+
+// restoredGPUs returns the indices of the GPUs in 'state', as far as they
+// can be found from their UUIDs.
+func restoredGPUs(state *types.MigState) []int {
+	uuids, err := util.GetGPUUUIDs()
+	if err != nil {
+		log.Debugf("Error getting GPU UUIDs: %v", err)
+		return nil
+	}
+
+	var gpus []int
+	for _, device := range state.Devices {
+		for i, uuid := range uuids {
+			if uuid == device.UUID {
+				gpus = append(gpus, i)
+			}
+		}
+	}
+	return gpus
+}
@@ -0,0 +1,57 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package restore
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/nodelock"
+)
+
+func TestRestoreWaitsForNodeLock(t *testing.T) {
+	dir := t.TempDir()
+	checkpointFile := filepath.Join(dir, "checkpoint.json")
+	err := os.WriteFile(checkpointFile, []byte(`{"version": "v2", "mig-state": {}}`), 0644)
+	require.Nil(t, err, "Unexpected failure writing checkpoint file")
+
+	// Hold the node lock as a concurrent 'apply' would.
+	lockFile := filepath.Join(dir, "nvml.lock")
+	unlock, err := util.LockNode(context.Background(), log, lockFile, time.Second)
+	require.Nil(t, err, "Unexpected failure from LockNode")
+	defer unlock()
+
+	c := cli.NewContext(cli.NewApp(), flag.NewFlagSet("restore", flag.ContinueOnError), nil)
+	c.Context = context.Background()
+	f := &Flags{
+		CheckpointFile:  checkpointFile,
+		NodeLockFile:    lockFile,
+		NodeLockTimeout: 100 * time.Millisecond,
+	}
+
+	err = restoreWrapper(c, f)
+	require.True(t, errors.Is(err, nodelock.ErrTimeout), "Unexpected error from restore: %v", err)
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/api/schema"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'schema' subcommand.
+type Flags struct {
+	Type string
+}
+
+// BuildCommand builds the 'schema' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	schemaFlags := Flags{}
+
+	// Create the 'schema' command
+	s := cli.Command{}
+	s.Name = "schema"
+	s.Usage = "Print the JSON Schema of a machine-readable output of mig-parted"
+	s.Action = func(c *cli.Context) error {
+		return schemaWrapper(c, &schemaFlags)
+	}
+
+	// Setup the flags for this command
+	s.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "type",
+			Aliases:     []string{"t"},
+			Usage:       "Output to print the schema of [" + strings.Join(schema.Types(), " | ") + "]",
+			Destination: &schemaFlags.Type,
+			EnvVars:     []string{"MIG_PARTED_SCHEMA_TYPE"},
+		},
+	}
+
+	return &s
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	if f.Type == "" {
+		return fmt.Errorf("missing required flag 'type'")
+	}
+	for _, t := range schema.Types() {
+		if f.Type == t {
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized 'type': %v", f.Type)
+}
+
+func schemaWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	b, err := schema.Get(f.Type)
+	if err != nil {
+		return err
+	}
+
+	_, err = os.Stdout.Write(b)
+	return err
+}
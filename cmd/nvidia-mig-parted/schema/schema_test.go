@@ -0,0 +1,337 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schema
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/api/schema"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/apply"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/export"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/status"
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var update = flag.Bool("update", false, "update the golden files in testdata")
+
+// TestContract checks that each machine-readable output is still written
+// exactly as recorded in its golden file, and that it is valid against the
+// schema published for it. Run with '-update' after an intended change to an
+// output, and update its schema to match.
+func TestContract(t *testing.T) {
+	timestamp := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deviceID := types.NewDeviceID(0x20B0, 0x10DE)
+	enabled := true
+	start := uint32(4)
+	gi := types.GpuInstance{Profile: "3g.20gb", ID: 2, Placement: nvml.GpuInstancePlacement{Start: 4, Size: 4}}
+	ci := types.MigDevice{
+		Profile:                  "3g.20gb",
+		GpuInstanceID:            2,
+		GpuInstancePlacement:     nvml.GpuInstancePlacement{Start: 4, Size: 4},
+		ComputeInstanceID:        0,
+		ComputeInstancePlacement: nvml.ComputeInstancePlacement{Start: 0, Size: 3},
+	}
+	entry := &journal.Entry{
+		Timestamp:      timestamp,
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-balanced",
+		Outcome:        journal.OutcomeSucceeded,
+		DurationMS:     1500,
+	}
+
+	outputs := map[string]func(w *bytes.Buffer) error{
+		schema.Export: func(w *bytes.Buffer) error {
+			spec := &v1.Spec{
+				Version: v1.Version,
+				MigConfigs: map[string]v1.MigConfigSpecSlice{
+					"current": {
+						{
+							DeviceFilter: deviceID.String(),
+							Devices:      []int{0},
+							MigEnabled:   true,
+							MigDevices:   types.MigConfig{"1g.5gb": 2, "3g.20gb": 1},
+							Labels:       map[string]string{"team": "vision"},
+						},
+						{
+							DeviceFilter: deviceID.String(),
+							Devices:      []int{1},
+							MigEnabled:   false,
+							MigDevices:   types.MigConfig{},
+						},
+					},
+				},
+			}
+			return export.WriteOutput(w, spec, &export.Flags{OutputFormat: export.JSONFormat})
+		},
+		schema.Plan: func(w *bytes.Buffer) error {
+			plan := &planv1.Plan{
+				Version:        planv1.Version,
+				SelectedConfig: "all-3g.20gb",
+				GPUs: []planv1.GPUPlan{
+					{
+						Index:    0,
+						DeviceID: deviceID,
+						State:    planv1.GPUState{MigEnabled: false},
+						Steps: []planv1.Step{
+							{Type: planv1.SetMigMode, Description: "GPU 0: set MIG mode to Enabled", MigEnabled: &enabled},
+							{Type: planv1.CreateGpuInstance, Description: "GPU 0: create GPU instance 3g.20gb", Profile: "3g.20gb", Start: &start, GpuInstance: &planv1.GpuInstanceRef{Ref: 0}},
+							{Type: planv1.CreateComputeInstance, Description: "GPU 0: create compute instance 3g.20gb", Profile: "3g.20gb", GpuInstance: &planv1.GpuInstanceRef{Ref: 0}},
+						},
+					},
+					{
+						Index:    1,
+						DeviceID: deviceID,
+						State: planv1.GPUState{
+							MigEnabled:       true,
+							GpuInstances:     []types.GpuInstance{gi},
+							ComputeInstances: []types.MigDevice{ci},
+						},
+						Steps: []planv1.Step{
+							{Type: planv1.DestroyComputeInstance, Description: "GPU 1: destroy compute instance 3g.20gb", GpuInstance: &planv1.GpuInstanceRef{Ref: 0, GpuInstance: gi}, ComputeInstance: &ci},
+							{Type: planv1.DestroyGpuInstance, Description: "GPU 1: destroy GPU instance 3g.20gb", GpuInstance: &planv1.GpuInstanceRef{Ref: 0, GpuInstance: gi}},
+						},
+					},
+				},
+			}
+			return apply.WritePlan(w, plan)
+		},
+		schema.Status: func(w *bytes.Buffer) error {
+			s := &status.Status{
+				RebootRequired: true,
+				Marker: &reboot.Marker{
+					Reason:    reboot.ReasonModeChangePending,
+					GPUs:      []reboot.GPU{{Index: 1, DeviceID: deviceID.String(), CurrentMode: "Disabled", DesiredMode: "Enabled"}},
+					Timestamp: timestamp,
+				},
+				Devices: []status.DeviceStatus{
+					{Index: 0, DeviceID: deviceID.String(), MigCapable: true, MigMode: "Enabled", MigDevices: types.MigConfig{"1g.5gb": 7}, Labels: map[string]string{"team": "vision"}},
+					{Index: 1, DeviceID: deviceID.String(), MigCapable: true, MigMode: "Disabled", MigModeChangePending: true},
+				},
+				AppliedConfig: entry,
+				Drift:         &status.Drift{Result: status.DriftDetected, Reason: "GPU 0: MIG devices differ"},
+				LastApply:     entry,
+				LastDrift: &drift.State{
+					Result:         drift.ResultDetected,
+					Reason:         "GPU 0: MIG devices differ",
+					ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+					SelectedConfig: "all-balanced",
+					Timestamp:      timestamp,
+				},
+			}
+			return status.WriteStatus(w, status.JSONFormat, s)
+		},
+	}
+
+	for _, typ := range schema.Types() {
+		t.Run(typ, func(t *testing.T) {
+			var output bytes.Buffer
+			require.Nil(t, outputs[typ](&output), "Unexpected failure writing output")
+
+			golden := filepath.Join("testdata", typ+".json")
+			if *update {
+				require.Nil(t, os.WriteFile(golden, output.Bytes(), 0644))
+			}
+			expected, err := os.ReadFile(golden)
+			require.Nil(t, err, "Unexpected failure reading golden file")
+			require.Equal(t, string(expected), output.String(), "Output changed: update the golden file and schema if intended")
+
+			b, err := schema.Get(typ)
+			require.Nil(t, err, "Unexpected failure getting schema")
+			var s map[string]interface{}
+			require.Nil(t, json.Unmarshal(b, &s), "Unexpected failure parsing schema")
+			var doc interface{}
+			require.Nil(t, json.Unmarshal(output.Bytes(), &doc), "Unexpected failure parsing output")
+			require.Nil(t, validate(s, s, doc, "$"))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	b, err := schema.Get(schema.Plan)
+	require.Nil(t, err)
+	var s map[string]interface{}
+	require.Nil(t, json.Unmarshal(b, &s))
+
+	testCases := []struct {
+		description string
+		doc         string
+		valid       bool
+	}{
+		{"Empty plan", `{"version": "v1", "gpus": []}`, true},
+		{"Missing version", `{"gpus": []}`, false},
+		{"Unknown version", `{"version": "v0", "gpus": []}`, false},
+		{"Unknown field", `{"version": "v1", "gpus": [], "extra": 1}`, false},
+		{"Unknown step type", `{"version": "v1", "gpus": [{"index": 0, "device-id": 1, "state": {"mig-enabled": false}, "steps": [{"type": "reboot", "description": ""}]}]}`, false},
+		{"Non-integer index", `{"version": "v1", "gpus": [{"index": 0.5, "device-id": 1, "state": {"mig-enabled": false}, "steps": []}]}`, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var doc interface{}
+			require.Nil(t, json.Unmarshal([]byte(tc.doc), &doc))
+			err := validate(s, s, doc, "$")
+			if tc.valid {
+				require.Nil(t, err)
+			} else {
+				require.NotNil(t, err)
+			}
+		})
+	}
+}
+
+// validate checks 'v' against the subset of JSON Schema used by the
+// published schemas, resolving references against 'root'.
+func validate(root, s map[string]interface{}, v interface{}, path string) error {
+	if ref, ok := s["$ref"].(string); ok {
+		def, ok := root["$defs"].(map[string]interface{})[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: unresolved reference %v", path, ref)
+		}
+		return validate(root, def, v, path)
+	}
+	if c, ok := s["const"]; ok && !reflect.DeepEqual(c, v) {
+		return fmt.Errorf("%v: %v is not %v", path, v, c)
+	}
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || reflect.DeepEqual(e, v)
+		}
+		if !found {
+			return fmt.Errorf("%v: %v is not one of %v", path, v, enum)
+		}
+	}
+	if anyOf, ok := s["anyOf"].([]interface{}); ok {
+		var errs []string
+		for _, sub := range anyOf {
+			err := validate(root, sub.(map[string]interface{}), v, path)
+			if err == nil {
+				errs = nil
+				break
+			}
+			errs = append(errs, err.Error())
+		}
+		if len(errs) > 0 {
+			return fmt.Errorf("%v: no alternative matches: %v", path, strings.Join(errs, "; "))
+		}
+	}
+	if typ, ok := s["type"]; ok && !matchesType(typ, v) {
+		return fmt.Errorf("%v: %v is not of type %v", path, v, typ)
+	}
+	if minimum, ok := s["minimum"].(float64); ok {
+		if n, ok := v.(float64); ok && n < minimum {
+			return fmt.Errorf("%v: %v is below %v", path, n, minimum)
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, r := range asSlice(s["required"]) {
+			if _, ok := v[r.(string)]; !ok {
+				return fmt.Errorf("%v: missing required field '%v'", path, r)
+			}
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		var keys []string
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub, ok := properties[k].(map[string]interface{})
+			if !ok {
+				switch additional := s["additionalProperties"].(type) {
+				case bool:
+					if !additional {
+						return fmt.Errorf("%v: unexpected field '%v'", path, k)
+					}
+					continue
+				case map[string]interface{}:
+					sub = additional
+				default:
+					continue
+				}
+			}
+			err := validate(root, sub, v[k], path+"."+k)
+			if err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		items, ok := s["items"].(map[string]interface{})
+		if !ok {
+			break
+		}
+		for i, item := range v {
+			err := validate(root, items, item, fmt.Sprintf("%v[%d]", path, i))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func matchesType(typ interface{}, v interface{}) bool {
+	if alternatives, ok := typ.([]interface{}); ok {
+		for _, t := range alternatives {
+			if matchesType(t, v) {
+				return true
+			}
+		}
+		return false
+	}
+	switch v := v.(type) {
+	case nil:
+		return typ == "null"
+	case bool:
+		return typ == "boolean"
+	case float64:
+		return typ == "number" || (typ == "integer" && v == math.Trunc(v))
+	case string:
+		return typ == "string"
+	case []interface{}:
+		return typ == "array"
+	case map[string]interface{}:
+		return typ == "object"
+	}
+	return false
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slurm
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GresDevice describes a single MIG device to expose to Slurm as a 'gpu' GRES.
+type GresDevice struct {
+	GPU               int
+	UUID              string
+	Profile           string
+	GpuInstanceID     uint32
+	ComputeInstanceID uint32
+	// OrderingKey identifies where the device sits on the node, as the GPU
+	// index followed by types.MigDevice.OrderingKey (e.g. '0:4:0'). Devices
+	// are listed in this order, so the GRES index Slurm assigns each of them
+	// stays the same across reconfigurations that recreate it in place.
+	OrderingKey string
+	Files       []string
+}
+
+// Inventory holds the MIG devices of a node to expose to Slurm.
+type Inventory struct {
+	NodeName string
+	Devices  []GresDevice
+}
+
+// GresType returns the Slurm GRES type for a MIG device with 'profile'.
+func GresType(prefix string, profile string) string {
+	return prefix + profile
+}
+
+// WriteGresConf writes a gres.conf snippet for 'inventory' to 'w'. If
+// 'autoDetect' is set, the MIG devices are left for Slurm to detect through
+// NVML instead of being listed one by one.
+func WriteGresConf(w io.Writer, inventory *Inventory, typePrefix string, autoDetect bool) {
+	fmt.Fprintf(w, "# gres.conf for %v (generated by nvidia-mig-parted)\n", inventory.NodeName)
+	if autoDetect {
+		fmt.Fprintln(w, "AutoDetect=nvml")
+		return
+	}
+
+	fmt.Fprintln(w, "AutoDetect=off")
+	for _, d := range inventory.Devices {
+		fmt.Fprintf(w, "# GPU %d, GPU instance %d, compute instance %d (order %v): %v\n", d.GPU, d.GpuInstanceID, d.ComputeInstanceID, d.OrderingKey, d.UUID)
+		fmt.Fprintf(w, "Name=gpu Type=%v MultipleFiles=%v\n", GresType(typePrefix, d.Profile), strings.Join(d.Files, ","))
+	}
+}
+
+// WriteSlurmConf writes the slurm.conf lines declaring the MIG devices in
+// 'inventory' to 'w'.
+func WriteSlurmConf(w io.Writer, inventory *Inventory, typePrefix string) {
+	counts := make(map[string]int)
+	for _, d := range inventory.Devices {
+		counts[GresType(typePrefix, d.Profile)]++
+	}
+
+	var gresTypes []string
+	for t := range counts {
+		gresTypes = append(gresTypes, t)
+	}
+	sort.Strings(gresTypes)
+
+	var gres []string
+	for _, t := range gresTypes {
+		gres = append(gres, fmt.Sprintf("gpu:%v:%d", t, counts[t]))
+	}
+
+	fmt.Fprintf(w, "# slurm.conf for %v (generated by nvidia-mig-parted)\n", inventory.NodeName)
+	fmt.Fprintln(w, "GresTypes=gpu")
+	if len(gres) == 0 {
+		fmt.Fprintf(w, "NodeName=%v\n", inventory.NodeName)
+		return
+	}
+	fmt.Fprintf(w, "NodeName=%v Gres=%v\n", inventory.NodeName, strings.Join(gres, ","))
+}
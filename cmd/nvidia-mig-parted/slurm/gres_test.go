@@ -0,0 +1,109 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slurm
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteInventory(t *testing.T) {
+	inventory := &Inventory{
+		NodeName: "node001",
+		Devices: []GresDevice{
+			{
+				GPU:           0,
+				UUID:          "MIG-11111111-1111-1111-1111-111111111111",
+				Profile:       "3g.20gb",
+				GpuInstanceID: 1,
+				OrderingKey:   "0:0:0",
+				Files:         []string{"/dev/nvidia0", "/dev/nvidia-caps/nvidia-cap12", "/dev/nvidia-caps/nvidia-cap13"},
+			},
+			{
+				GPU:           0,
+				UUID:          "MIG-22222222-2222-2222-2222-222222222222",
+				Profile:       "1g.5gb",
+				GpuInstanceID: 7,
+				OrderingKey:   "0:4:0",
+				Files:         []string{"/dev/nvidia0", "/dev/nvidia-caps/nvidia-cap66", "/dev/nvidia-caps/nvidia-cap67"},
+			},
+			{
+				GPU:           1,
+				UUID:          "MIG-33333333-3333-3333-3333-333333333333",
+				Profile:       "1g.5gb",
+				GpuInstanceID: 7,
+				OrderingKey:   "1:0:0",
+				Files:         []string{"/dev/nvidia1", "/dev/nvidia-caps/nvidia-cap201", "/dev/nvidia-caps/nvidia-cap202"},
+			},
+		},
+	}
+
+	testCases := []struct {
+		description string
+		flags       Flags
+		expected    string
+	}{
+		{
+			"All snippets",
+			Flags{Output: AllOutput},
+			`# gres.conf for node001 (generated by nvidia-mig-parted)
+AutoDetect=off
+# GPU 0, GPU instance 1, compute instance 0 (order 0:0:0): MIG-11111111-1111-1111-1111-111111111111
+Name=gpu Type=3g.20gb MultipleFiles=/dev/nvidia0,/dev/nvidia-caps/nvidia-cap12,/dev/nvidia-caps/nvidia-cap13
+# GPU 0, GPU instance 7, compute instance 0 (order 0:4:0): MIG-22222222-2222-2222-2222-222222222222
+Name=gpu Type=1g.5gb MultipleFiles=/dev/nvidia0,/dev/nvidia-caps/nvidia-cap66,/dev/nvidia-caps/nvidia-cap67
+# GPU 1, GPU instance 7, compute instance 0 (order 1:0:0): MIG-33333333-3333-3333-3333-333333333333
+Name=gpu Type=1g.5gb MultipleFiles=/dev/nvidia1,/dev/nvidia-caps/nvidia-cap201,/dev/nvidia-caps/nvidia-cap202
+
+# slurm.conf for node001 (generated by nvidia-mig-parted)
+GresTypes=gpu
+NodeName=node001 Gres=gpu:1g.5gb:2,gpu:3g.20gb:1
+`,
+		},
+		{
+			"Autodetected gres.conf",
+			Flags{Output: GresConfOutput, AutoDetect: true},
+			`# gres.conf for node001 (generated by nvidia-mig-parted)
+AutoDetect=nvml
+`,
+		},
+		{
+			"slurm.conf with type prefix",
+			Flags{Output: SlurmConfOutput, TypePrefix: "a100_"},
+			`# slurm.conf for node001 (generated by nvidia-mig-parted)
+GresTypes=gpu
+NodeName=node001 Gres=gpu:a100_1g.5gb:2,gpu:a100_3g.20gb:1
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var output bytes.Buffer
+			WriteInventory(&output, inventory, &tc.flags)
+			require.Equal(t, tc.expected, output.String())
+		})
+	}
+}
+
+func TestWriteSlurmConfNoDevices(t *testing.T) {
+	var output bytes.Buffer
+	WriteSlurmConf(&output, &Inventory{NodeName: "node001"}, "")
+	require.Equal(t, "# slurm.conf for node001 (generated by nvidia-mig-parted)\nGresTypes=gpu\nNodeName=node001\n", output.String())
+}
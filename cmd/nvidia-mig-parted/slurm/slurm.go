@@ -0,0 +1,276 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package slurm
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+)
+
+var log = logrus.New()
+
+const (
+	GresConfOutput  = "gres.conf"
+	SlurmConfOutput = "slurm.conf"
+	AllOutput       = "all"
+)
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'slurm-gres' subcommand.
+type Flags struct {
+	NodeName      string
+	TypePrefix    string
+	AutoDetect    bool
+	Output        string
+	MigMinorsFile string
+	DevicesFile   string
+}
+
+// BuildCommand builds the 'slurm-gres' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	slurmFlags := Flags{}
+
+	// Create the 'slurm-gres' command
+	slurm := cli.Command{}
+	slurm.Name = "slurm-gres"
+	slurm.Usage = "Generate the Slurm gres.conf / slurm.conf lines for the MIG devices currently on the node"
+	slurm.Action = func(c *cli.Context) error {
+		return slurmWrapper(c, &slurmFlags)
+	}
+
+	// Setup the flags for this command
+	slurm.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "node-name",
+			Usage:       "Name of the node in slurm.conf (defaults to the hostname)",
+			Destination: &slurmFlags.NodeName,
+			EnvVars:     []string{"MIG_PARTED_SLURM_NODE_NAME"},
+		},
+		&cli.StringFlag{
+			Name:        "type-prefix",
+			Usage:       "Prefix for the GRES type of each MIG device (e.g. 'a100_' to match the types detected by Slurm)",
+			Destination: &slurmFlags.TypePrefix,
+			EnvVars:     []string{"MIG_PARTED_SLURM_TYPE_PREFIX"},
+		},
+		&cli.BoolFlag{
+			Name:        "autodetect",
+			Usage:       "Let Slurm detect the MIG devices through NVML instead of listing them in gres.conf",
+			Destination: &slurmFlags.AutoDetect,
+			EnvVars:     []string{"MIG_PARTED_SLURM_AUTODETECT"},
+		},
+		&cli.StringFlag{
+			Name:        "output",
+			Aliases:     []string{"o"},
+			Usage:       "Which snippet to generate [gres.conf | slurm.conf | all]",
+			Destination: &slurmFlags.Output,
+			Value:       AllOutput,
+			EnvVars:     []string{"MIG_PARTED_SLURM_OUTPUT"},
+		},
+		&cli.StringFlag{
+			Name:        "mig-minors-file",
+			Usage:       "Path to the file listing the minor numbers of the nvidia-caps device nodes",
+			Destination: &slurmFlags.MigMinorsFile,
+			Value:       migcaps.DefaultMigMinorsFile,
+			EnvVars:     []string{"MIG_PARTED_MIG_MINORS_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "devices-file",
+			Usage:       "Path to the file listing the major numbers of all character devices",
+			Destination: &slurmFlags.DevicesFile,
+			Value:       migcaps.DefaultDevicesFile,
+			EnvVars:     []string{"MIG_PARTED_DEVICES_FILE"},
+		},
+	}
+
+	return &slurm
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	switch f.Output {
+	case GresConfOutput:
+	case SlurmConfOutput:
+	case AllOutput:
+	default:
+		return fmt.Errorf("unrecognized 'output': %v", f.Output)
+	}
+	if f.MigMinorsFile == "" {
+		return fmt.Errorf("missing required flag 'mig-minors-file'")
+	}
+	if f.DevicesFile == "" {
+		return fmt.Errorf("missing required flag 'devices-file'")
+	}
+	return nil
+}
+
+// GetInventory returns the MIG devices that currently exist on the GPUs of
+// the node, along with their UUIDs and the device files Slurm must grant
+// access to for each of them. They are ordered by GPU index and then by
+// where they are placed on the GPU, so that the same MIG config always yields
+// the same order.
+func GetInventory(nvmlLib nvml.Interface, f *Flags) (*Inventory, error) {
+	nodeName := f.NodeName
+	if nodeName == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return nil, fmt.Errorf("error getting hostname: %v", err)
+		}
+		nodeName = hostname
+	}
+
+	minors, err := migcaps.ReadMigMinors(f.MigMinorsFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading MIG minors: %v", err)
+	}
+
+	capsMajor, err := migcaps.ReadMajor(f.DevicesFile, migcaps.CapsDevicesName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading major number of '%v': %v", migcaps.CapsDevicesName, err)
+	}
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	inventory := &Inventory{NodeName: nodeName}
+	for i := 0; i < count; i++ {
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %d: %v", i, err)
+		}
+		if !capable {
+			continue
+		}
+
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %d: %v", i, err)
+		}
+		if m != mode.Enabled {
+			continue
+		}
+
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		gpuMinor, ret := device.GetMinorNumber()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting minor number for GPU %d: %v", i, ret)
+		}
+
+		uuids, err := util.GetMigDeviceUUIDs(device)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG device UUIDs for GPU %d: %v", i, err)
+		}
+
+		devices, err := configManager.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices for GPU %d: %v", i, err)
+		}
+
+		for _, d := range devices {
+			c, err := migcaps.Resolve(minors, capsMajor, i, gpuMinor, d)
+			if err != nil {
+				return nil, fmt.Errorf("error resolving device nodes for GPU %d: %v", i, err)
+			}
+
+			var files []string
+			for _, node := range c.DeviceNodes {
+				if node.Major == migcaps.NvidiaMajor && node.Minor == migcaps.NvidiactlMinor {
+					continue
+				}
+				files = append(files, node.Path)
+			}
+
+			inventory.Devices = append(inventory.Devices, GresDevice{
+				GPU:               i,
+				UUID:              uuids[[2]uint32{d.GpuInstanceID, d.ComputeInstanceID}],
+				Profile:           d.Profile,
+				GpuInstanceID:     d.GpuInstanceID,
+				ComputeInstanceID: d.ComputeInstanceID,
+				OrderingKey:       fmt.Sprintf("%d:%v", i, d.OrderingKey()),
+				Files:             files,
+			})
+		}
+	}
+
+	return inventory, nil
+}
+
+// WriteInventory writes the snippets selected by 'f.Output' for 'inventory' to 'w'.
+func WriteInventory(w io.Writer, inventory *Inventory, f *Flags) {
+	if f.Output != SlurmConfOutput {
+		WriteGresConf(w, inventory, f.TypePrefix, f.AutoDetect)
+	}
+	if f.Output == AllOutput {
+		fmt.Fprintln(w)
+	}
+	if f.Output != GresConfOutput {
+		WriteSlurmConf(w, inventory, f.TypePrefix)
+	}
+}
+
+func slurmWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	inventory, err := GetInventory(nvmlLib, f)
+	if err != nil {
+		return fmt.Errorf("error getting MIG inventory: %v", err)
+	}
+
+	WriteInventory(os.Stdout, inventory, f)
+	return nil
+}
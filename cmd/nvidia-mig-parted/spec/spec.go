@@ -0,0 +1,327 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spec
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	checkpointv2 "github.com/NVIDIA/mig-parted/api/checkpoint/v2"
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/checkpoint"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// ToCheckpointFlags holds variables that represent the set of flags that can be passed to the 'spec to-checkpoint' subcommand.
+type ToCheckpointFlags struct {
+	ConfigFile     string
+	SelectedConfig string
+	CheckpointFile string
+	FillFromDevice bool
+}
+
+// BuildCommand builds the 'spec' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create the 'spec' command
+	spec := cli.Command{}
+	spec.Name = "spec"
+	spec.Usage = "Convert MIG config specs into other formats"
+
+	// Register the subcommands of this command
+	spec.Subcommands = []*cli.Command{
+		buildToCheckpointCommand(),
+	}
+
+	return &spec
+}
+
+func buildToCheckpointCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	toCheckpointFlags := ToCheckpointFlags{}
+
+	// Create the 'to-checkpoint' command
+	toCheckpoint := cli.Command{}
+	toCheckpoint.Name = "to-checkpoint"
+	toCheckpoint.Usage = "Convert a MIG config into a checkpoint file that 'restore' can apply"
+	toCheckpoint.Action = func(c *cli.Context) error {
+		return toCheckpointWrapper(c, &toCheckpointFlags)
+	}
+
+	// Setup the flags for this command
+	toCheckpoint.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the configuration file ('-' for stdin)",
+			Destination: &toCheckpointFlags.ConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "selected-config",
+			Aliases:     []string{"c"},
+			Usage:       "The label of the mig-config from the config file to convert",
+			Destination: &toCheckpointFlags.SelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "checkpoint-file",
+			Usage:       "Path to write the checkpoint file to ('-' for stdout)",
+			Destination: &toCheckpointFlags.CheckpointFile,
+			Value:       util.StdioPath,
+			EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
+		},
+		&cli.BoolFlag{
+			Name:        "fill-from-device",
+			Usage:       "Fill in the GPU UUIDs, profile IDs and placements a spec does not hold from the GPUs on this node",
+			Destination: &toCheckpointFlags.FillFromDevice,
+			EnvVars:     []string{"MIG_PARTED_FILL_FROM_DEVICE"},
+		},
+	}
+
+	return &toCheckpoint
+}
+
+// CheckToCheckpointFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckToCheckpointFlags(f *ToCheckpointFlags) error {
+	var missing []string
+	if f.ConfigFile == "" {
+		missing = append(missing, "config-file")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("missing required flags '%v'", strings.Join(missing, ", "))
+	}
+	if !f.FillFromDevice {
+		return fmt.Errorf("a spec holds no GPU UUIDs or placements, so 'fill-from-device' is required to fill them in from this node")
+	}
+	return nil
+}
+
+func toCheckpointWrapper(c *cli.Context, f *ToCheckpointFlags) error {
+	err := CheckToCheckpointFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	err = util.CheckNotTerminal(f.CheckpointFile, "a checkpoint")
+	if err != nil {
+		return err
+	}
+
+	assertFlags := &assert.Flags{
+		ConfigFile:     f.ConfigFile,
+		SelectedConfig: f.SelectedConfig,
+	}
+
+	log.Debugf("Parsing config file...")
+	parsed, err := assert.ParseConfigFile(assertFlags)
+	if err != nil {
+		return fmt.Errorf("error parsing config file: %v", err)
+	}
+
+	log.Debugf("Selecting specific MIG config...")
+	migConfig, err := assert.GetSelectedMigConfig(assertFlags, parsed)
+	if err != nil {
+		return fmt.Errorf("error selecting MIG config: %v", err)
+	}
+
+	state, err := FillFromDevices(migConfig, parsed.UnmanagedDevices)
+	if err != nil {
+		return err
+	}
+
+	j, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("error marshalling MIG state to json: %v", err)
+	}
+	if util.IsStdio(f.CheckpointFile) {
+		j = append(j, '\n')
+	}
+
+	err = util.WriteToFile(f.CheckpointFile, func(w io.Writer) error {
+		_, err := w.Write(j)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error writing checkpoint file: %v", err)
+	}
+
+	if util.IsStdio(f.CheckpointFile) {
+		fmt.Fprintln(os.Stderr, "MIG configuration converted to a checkpoint successfully")
+		return nil
+	}
+	fmt.Println("MIG configuration converted to a checkpoint successfully")
+	return nil
+}
+
+// FillFromDevices builds the checkpoint 'migConfig' would leave behind on the
+// GPUs of this node it selects. The placements are those a MIG config Manager
+// would pick for each GPU, so MIG mode must already be enabled on every GPU
+// 'migConfig' enables it on.
+func FillFromDevices(migConfig v1.MigConfigSpecSlice, unmanaged v1.UnmanagedDeviceSpecSlice) (*checkpointv2.State, error) {
+	nvmlLib := nvml.New()
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	driverVersion, ret := nvmlLib.SystemGetDriverVersion()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting driver version: %v", ret)
+	}
+
+	uuids, err := util.GetGPUUUIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error enumerating GPU UUIDs: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG Config Manager: %v", err)
+	}
+
+	migState := types.MigState{}
+	err = assert.WalkSelectedMigConfigForEachGPU(migConfig, unmanaged, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if i >= len(uuids) {
+			return fmt.Errorf("no UUID for GPU %d", i)
+		}
+		if !mc.MigEnabled {
+			migState.Devices = append(migState.Devices, types.DeviceState{UUID: uuids[i], MigMode: mode.Disabled})
+			return nil
+		}
+
+		var err error
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
+		}
+
+		devices, err := configManager.PlanMigConfig(i, desired, config.WithPlacementPreference(mc.Placement), config.WithPlacementExclusions(mc.PlacementExclusions))
+		if err != nil {
+			return fmt.Errorf("error working out placements on GPU %d (MIG mode must already be enabled): %v", i, err)
+		}
+
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		deviceState, err := DeviceStateFromMigDevices(uuids[i], devices, func(giProfileID int) (nvml.GpuInstanceProfileInfo, error) {
+			info, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+			if ret != nvml.SUCCESS {
+				return info, fmt.Errorf("error getting GPU instance profile info for '%v': %v", giProfileID, ret)
+			}
+			return info, nil
+		})
+		if err != nil {
+			return fmt.Errorf("error converting MIG devices of GPU %d: %v", i, err)
+		}
+		migState.Devices = append(migState.Devices, *deviceState)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	devices, err := checkpoint.GetDeviceInfos(nvmlLib, &migState)
+	if err != nil {
+		return nil, fmt.Errorf("error getting device info: %v", err)
+	}
+
+	return &checkpointv2.State{
+		Version:       checkpointv2.Version,
+		DriverVersion: driverVersion,
+		Devices:       devices,
+		MigState:      migState,
+	}, nil
+}
+
+// DeviceStateFromMigDevices converts the MIG devices planned for a GPU into
+// the exact GPU and compute instances a checkpoint holds. MIG devices sharing
+// a GPU instance placement share a GPU instance. 'giProfileInfo' looks up the
+// slice count and memory size recorded with each GPU instance.
+func DeviceStateFromMigDevices(uuid string, devices []types.MigDevice, giProfileInfo func(int) (nvml.GpuInstanceProfileInfo, error)) (*types.DeviceState, error) {
+	var gis []*types.GpuInstanceState
+	byPlacement := make(map[nvml.GpuInstancePlacement]*types.GpuInstanceState)
+	for _, d := range devices {
+		mp, err := types.ParseMigProfile(d.Profile)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing MIG profile '%v': %v", d.Profile, err)
+		}
+
+		gi, exists := byPlacement[d.GpuInstancePlacement]
+		if !exists {
+			info, err := giProfileInfo(mp.GIProfileID)
+			if err != nil {
+				return nil, err
+			}
+			gi = &types.GpuInstanceState{
+				ProfileID:    mp.GIProfileID,
+				SliceCount:   info.SliceCount,
+				MemorySizeMB: info.MemorySizeMB,
+				Placement:    d.GpuInstancePlacement,
+			}
+			byPlacement[d.GpuInstancePlacement] = gi
+			gis = append(gis, gi)
+		}
+		if gi.ProfileID != mp.GIProfileID {
+			return nil, fmt.Errorf("MIG devices '%v' and GPU instance profile %d share the placement %+v", d.Profile, gi.ProfileID, d.GpuInstancePlacement)
+		}
+
+		gi.ComputeInstances = append(gi.ComputeInstances, types.ComputeInstanceState{
+			ProfileID:    mp.CIProfileID,
+			EngProfileID: mp.CIEngProfileID,
+		})
+	}
+
+	sort.SliceStable(gis, func(i, j int) bool {
+		return gis[i].Placement.Start < gis[j].Placement.Start
+	})
+
+	deviceState := &types.DeviceState{
+		UUID:    uuid,
+		MigMode: mode.Enabled,
+	}
+	for _, gi := range gis {
+		deviceState.GpuInstances = append(deviceState.GpuInstances, *gi)
+	}
+	return deviceState, nil
+}
@@ -0,0 +1,79 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package spec
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestDeviceStateFromMigDevices(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	giProfileInfo := func(giProfileID int) (nvml.GpuInstanceProfileInfo, error) {
+		switch giProfileID {
+		case nvml.GPU_INSTANCE_PROFILE_1_SLICE:
+			return nvml.GpuInstanceProfileInfo{SliceCount: 1, MemorySizeMB: 5 * 1024}, nil
+		case nvml.GPU_INSTANCE_PROFILE_3_SLICE:
+			return nvml.GpuInstanceProfileInfo{SliceCount: 3, MemorySizeMB: 20 * 1024}, nil
+		}
+		return nvml.GpuInstanceProfileInfo{}, fmt.Errorf("unsupported profile %d", giProfileID)
+	}
+
+	devices := []types.MigDevice{
+		{Profile: "1c.3g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 4}},
+		{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 1}},
+		{Profile: "1c.3g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 4}},
+	}
+
+	deviceState, err := DeviceStateFromMigDevices("GPU-0", devices, giProfileInfo)
+	require.Nil(t, err, "Unexpected failure from DeviceStateFromMigDevices")
+
+	expected := &types.DeviceState{
+		UUID:    "GPU-0",
+		MigMode: mode.Enabled,
+		GpuInstances: []types.GpuInstanceState{
+			{
+				ProfileID:        nvml.GPU_INSTANCE_PROFILE_1_SLICE,
+				SliceCount:       1,
+				MemorySizeMB:     5 * 1024,
+				Placement:        nvml.GpuInstancePlacement{Start: 0, Size: 1},
+				ComputeInstances: []types.ComputeInstanceState{{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE}},
+			},
+			{
+				ProfileID:    nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+				SliceCount:   3,
+				MemorySizeMB: 20 * 1024,
+				Placement:    nvml.GpuInstancePlacement{Start: 4, Size: 4},
+				ComputeInstances: []types.ComputeInstanceState{
+					{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+					{ProfileID: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+				},
+			},
+		},
+	}
+	require.Equal(t, expected, deviceState)
+
+	_, err = DeviceStateFromMigDevices("GPU-0", []types.MigDevice{{Profile: "2g.10gb"}}, giProfileInfo)
+	require.NotNil(t, err, "Unexpected success from DeviceStateFromMigDevices")
+}
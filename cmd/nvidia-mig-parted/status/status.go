@@ -0,0 +1,505 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/inventory"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	"github.com/NVIDIA/mig-parted/pkg/labels"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// Results of checking the node for drift from the last applied config.
+const (
+	DriftNone     = "none"
+	DriftDetected = "detected"
+	DriftUnknown  = "unknown"
+)
+
+// GetLogger returns the 'logrus.Logger' instance used by this package.
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+// Flags holds variables that represent the set of flags that can be passed to the 'status' subcommand.
+type Flags struct {
+	RebootMarkerFile string
+	JournalFile      string
+	DriftStateFile   string
+	StoreFile        string
+	OutputFormat     string
+}
+
+// Status summarizes the MIG state of the node: the MIG mode of each GPU, the
+// last applied config and whether the node has drifted from it, the outcome
+// of the last apply and of the last drift check recorded by 'assert', and whether the node must be rebooted for a MIG mode
+// change to take effect (along with the details recorded in the reboot marker).
+type Status struct {
+	RebootRequired bool `json:"reboot-required"`
+	*reboot.Marker
+	Devices       []DeviceStatus `json:"devices,omitempty"`
+	AppliedConfig *journal.Entry `json:"applied-config,omitempty"`
+	Drift         *Drift         `json:"drift,omitempty"`
+	LastApply     *journal.Entry `json:"last-apply,omitempty"`
+	LastDrift     *drift.State   `json:"last-drift-check,omitempty"`
+}
+
+// DeviceStatus is the MIG state of a single GPU.
+type DeviceStatus struct {
+	Index                int               `json:"index"`
+	DeviceID             string            `json:"device-id"`
+	MigCapable           bool              `json:"mig-capable"`
+	MigMode              string            `json:"mig-mode,omitempty"`
+	MigModeChangePending bool              `json:"mig-mode-change-pending,omitempty"`
+	MigDevices           types.MigConfig   `json:"mig-devices,omitempty"`
+	Labels               map[string]string `json:"labels,omitempty"`
+}
+
+// Drift is the result of checking whether the applied config is still in place.
+type Drift struct {
+	Result string `json:"result"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// BuildCommand builds the 'status' subcommand for injection into the main mig-parted CLI.
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	statusFlags := Flags{}
+
+	// Create the 'status' command
+	status := cli.Command{}
+	status.Name = "status"
+	status.Usage = "Summarize the MIG mode of each GPU, the applied MIG config, drift from it, the last apply and whether a reboot is required"
+	status.Action = func(c *cli.Context) error {
+		return statusWrapper(c, &statusFlags)
+	}
+
+	// Setup the flags for this command
+	status.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "reboot-marker-file",
+			Usage:       "Path to the marker written by 'apply' when a reboot is required",
+			Destination: &statusFlags.RebootMarkerFile,
+			Value:       reboot.DefaultMarkerFile,
+			EnvVars:     []string{"MIG_PARTED_REBOOT_MARKER_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "journal-file",
+			Usage:       "Path to the journal 'apply' records the outcome of each apply in",
+			Destination: &statusFlags.JournalFile,
+			Value:       journal.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_JOURNAL_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "drift-state-file",
+			Usage:       "Path to the state file 'assert --drift-state-file' records the outcome of each drift check in",
+			Destination: &statusFlags.DriftStateFile,
+			Value:       drift.DefaultStateFile,
+			EnvVars:     []string{"MIG_PARTED_DRIFT_STATE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "store-file",
+			Usage:       "Path to the store 'apply' records the labels of the applied MIG config in",
+			Destination: &statusFlags.StoreFile,
+			Value:       store.DefaultFile,
+			EnvVars:     []string{"MIG_PARTED_STORE_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [text | json]",
+			Destination: &statusFlags.OutputFormat,
+			Value:       TextFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+	}
+
+	return &status
+}
+
+// CheckFlags ensures that any required flags are provided and ensures they are well-formed.
+func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case TextFormat:
+	case JSONFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	if f.RebootMarkerFile == "" {
+		return fmt.Errorf("missing required flag 'reboot-marker-file'")
+	}
+	if f.JournalFile == "" {
+		return fmt.Errorf("missing required flag 'journal-file'")
+	}
+	return nil
+}
+
+// GetStatus reads the reboot marker, apply journal and drift state file
+// referenced in 'f'.
+// It does not query the GPUs; see 'GetDeviceStatus' and 'CheckDrift'.
+func GetStatus(f *Flags) (*Status, error) {
+	marker, err := reboot.Read(f.RebootMarkerFile)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := journal.Read(f.JournalFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastDrift *drift.State
+	if f.DriftStateFile != "" {
+		lastDrift, err = drift.Read(f.DriftStateFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	status := &Status{
+		RebootRequired: marker != nil,
+		Marker:         marker,
+		AppliedConfig:  journal.LastSucceeded(entries),
+		LastApply:      journal.Last(entries),
+		LastDrift:      lastDrift,
+	}
+	return status, nil
+}
+
+// GetDeviceStatus returns the MIG state of each GPU in 'deviceIDs'. The MIG
+// devices of the GPUs in MIG mode are collected in parallel.
+func GetDeviceStatus(modeManager mode.Manager, configManager config.Manager, deviceIDs []types.DeviceID) ([]DeviceStatus, error) {
+	var devices []DeviceStatus
+	var enabled []int
+	for i, deviceID := range deviceIDs {
+		device := DeviceStatus{
+			Index:    i,
+			DeviceID: deviceID.String(),
+		}
+
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %v: %w", i, err)
+		}
+		device.MigCapable = capable
+		if !capable {
+			devices = append(devices, device)
+			continue
+		}
+
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %v: %w", i, err)
+		}
+		device.MigMode = m.String()
+
+		device.MigModeChangePending, err = modeManager.IsMigModeChangePending(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking for pending MIG mode change on GPU %v: %w", i, err)
+		}
+
+		if m == mode.Enabled {
+			enabled = append(enabled, i)
+		}
+
+		devices = append(devices, device)
+	}
+
+	inv := inventory.Collect(configManager, enabled, inventory.DefaultWorkers)
+	for _, i := range enabled {
+		migDevices, err := inv.MigConfig(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG config for GPU %v: %w", i, err)
+		}
+		devices[i].MigDevices = migDevices
+	}
+	return devices, nil
+}
+
+// AddLabels sets the labels of each of 'devices' from the labels applied to
+// the GPUs, as returned by labels.Load.
+func AddLabels(devices []DeviceStatus, entries map[int]*labels.Entry) {
+	for i := range devices {
+		if entry, exists := entries[devices[i].Index]; exists {
+			devices[i].Labels = entry.Labels
+		}
+	}
+}
+
+// CheckDrift checks whether the config recorded in 'applied' is still
+// applied to the node by asserting it from the config file it was applied
+// from. The result is unknown if there is no such config file to assert from.
+func CheckDrift(c *cli.Context, applied *journal.Entry) *Drift {
+	if applied == nil {
+		return &Drift{Result: DriftUnknown, Reason: "no successful apply recorded"}
+	}
+	if applied.ConfigFile == "" {
+		return &Drift{Result: DriftUnknown, Reason: "config was applied from a plan"}
+	}
+	if util.IsStdio(applied.ConfigFile) || util.IsStdio(applied.CIConfigFile) {
+		return &Drift{Result: DriftUnknown, Reason: "config file was read from stdin"}
+	}
+
+	flags := assert.Flags{
+		ConfigFile:       applied.ConfigFile,
+		SelectedConfig:   applied.SelectedConfig,
+		CIConfigFile:     applied.CIConfigFile,
+		CISelectedConfig: applied.CISelectedConfig,
+	}
+
+	spec, err := assert.ParseConfigFile(&flags)
+	if err != nil {
+		return &Drift{Result: DriftUnknown, Reason: fmt.Sprintf("error parsing config file: %v", err)}
+	}
+
+	migConfig, err := assert.GetSelectedMigConfig(&flags, spec)
+	if err != nil {
+		return &Drift{Result: DriftUnknown, Reason: fmt.Sprintf("error selecting MIG config: %v", err)}
+	}
+
+	ciConfig, err := assert.GetSelectedComputeInstanceConfig(&flags)
+	if err != nil {
+		return &Drift{Result: DriftUnknown, Reason: fmt.Sprintf("error selecting compute instance config: %v", err)}
+	}
+
+	context := assert.Context{
+		Context:               c,
+		Flags:                 &flags,
+		MigConfig:             migConfig,
+		ComputeInstanceConfig: ciConfig,
+		UnmanagedDevices:      spec.UnmanagedDevices,
+		Nvml:                  nvml.New(),
+	}
+
+	err = assert.AssertMigMode(&context)
+	if err == nil && !applied.ModeOnly {
+		err = assert.AssertMigConfig(&context)
+	}
+	if assert.IsDrift(err) {
+		return &Drift{Result: DriftDetected, Reason: err.Error()}
+	}
+	if err != nil {
+		return &Drift{Result: DriftUnknown, Reason: fmt.Sprintf("error asserting config: %v", err)}
+	}
+
+	return &Drift{Result: DriftNone}
+}
+
+// WriteStatus writes 'status' to 'w' in the requested 'format'.
+func WriteStatus(w io.Writer, format string, status *Status) error {
+	if format == JSONFormat {
+		output, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshaling status to JSON: %v", err)
+		}
+		fmt.Fprintln(w, string(output))
+		return nil
+	}
+
+	for _, device := range status.Devices {
+		switch {
+		case !device.MigCapable:
+			fmt.Fprintf(w, "GPU %v (%v): MIG not supported\n", device.Index, device.DeviceID)
+		case device.MigModeChangePending:
+			fmt.Fprintf(w, "GPU %v (%v): MIG %v (change pending)\n", device.Index, device.DeviceID, device.MigMode)
+		case len(device.MigDevices) > 0:
+			fmt.Fprintf(w, "GPU %v (%v): MIG %v, %v\n", device.Index, device.DeviceID, device.MigMode, formatMigDevices(device.MigDevices))
+		default:
+			fmt.Fprintf(w, "GPU %v (%v): MIG %v\n", device.Index, device.DeviceID, device.MigMode)
+		}
+		if len(device.Labels) > 0 {
+			fmt.Fprintf(w, "  Labels: %v\n", formatLabels(device.Labels))
+		}
+	}
+
+	if status.LastApply != nil {
+		if status.AppliedConfig != nil {
+			fmt.Fprintf(w, "Applied config: %v\n", describeConfig(status.AppliedConfig))
+		} else {
+			fmt.Fprintln(w, "Applied config: none")
+		}
+	}
+
+	if status.Drift != nil {
+		if status.Drift.Reason != "" {
+			fmt.Fprintf(w, "Drift: %v (%v)\n", status.Drift.Result, status.Drift.Reason)
+		} else {
+			fmt.Fprintf(w, "Drift: %v\n", status.Drift.Result)
+		}
+	}
+
+	if last := status.LastDrift; last != nil {
+		if last.Reason != "" {
+			fmt.Fprintf(w, "Last drift check: %v against %v at %v (%v)\n", last.Result, describeDriftCheck(last), last.Timestamp.Format(time.RFC3339), last.Reason)
+		} else {
+			fmt.Fprintf(w, "Last drift check: %v against %v at %v\n", last.Result, describeDriftCheck(last), last.Timestamp.Format(time.RFC3339))
+		}
+	}
+
+	if last := status.LastApply; last != nil {
+		duration := time.Duration(last.DurationMS) * time.Millisecond
+		fmt.Fprintf(w, "Last apply: %v at %v (took %v)\n", last.Outcome, last.Timestamp.Format(time.RFC3339), duration)
+		if last.Trigger != "" {
+			fmt.Fprintf(w, "  Triggered by %v\n", last.Trigger)
+		}
+		if last.Error != "" {
+			fmt.Fprintf(w, "  %v\n", last.Error)
+		}
+	}
+
+	if !status.RebootRequired {
+		fmt.Fprintln(w, "Reboot required: no")
+		return nil
+	}
+
+	if status.Marker == nil {
+		fmt.Fprintf(w, "Reboot required: yes (%v)\n", reboot.ReasonModeChangePending)
+		return nil
+	}
+
+	fmt.Fprintf(w, "Reboot required: yes (%v)\n", status.Reason)
+	for _, gpu := range status.GPUs {
+		fmt.Fprintf(w, "  GPU %v (%v): MIG mode %v -> %v\n", gpu.Index, gpu.DeviceID, gpu.CurrentMode, gpu.DesiredMode)
+	}
+	return nil
+}
+
+// formatMigDevices formats 'migDevices' as a list of profiles with their
+// counts, sorted by profile.
+func formatMigDevices(migDevices types.MigConfig) string {
+	var profiles []string
+	for profile, count := range migDevices {
+		profiles = append(profiles, fmt.Sprintf("%v x%v", profile, count))
+	}
+	sort.Strings(profiles)
+	return strings.Join(profiles, ", ")
+}
+
+// formatLabels formats 'l' as a list of key=value pairs, sorted by key.
+func formatLabels(l map[string]string) string {
+	var pairs []string
+	for k, v := range l {
+		pairs = append(pairs, fmt.Sprintf("%v=%v", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// describeConfig describes the config applied by 'entry' in a single line.
+func describeConfig(entry *journal.Entry) string {
+	from := entry.ConfigFile
+	if from == "" {
+		from = entry.PlanFile
+	}
+	return fmt.Sprintf("%v from %v at %v", entry.SelectedConfig, from, entry.Timestamp.Format(time.RFC3339))
+}
+
+// describeDriftCheck describes the config the drift check in 'state' was made against.
+func describeDriftCheck(state *drift.State) string {
+	if state.SelectedConfig == "" {
+		return state.ConfigFile
+	}
+	return fmt.Sprintf("%v from %v", state.SelectedConfig, state.ConfigFile)
+}
+
+func statusWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	status, err := GetStatus(f)
+	if err != nil {
+		return fmt.Errorf("error getting status: %v", err)
+	}
+
+	// The GPUs are queried on a best effort basis, so that the recorded
+	// state can still be reported on a node where NVML is unavailable.
+	status.Devices, err = getDeviceStatus()
+	if err != nil {
+		log.Warnf("Error getting MIG state of GPUs: %v", err)
+	}
+	if f.StoreFile != "" {
+		entries, err := labels.Load(f.StoreFile)
+		if err != nil {
+			log.Warnf("Error getting labels of GPUs: %v", err)
+		}
+		AddLabels(status.Devices, entries)
+	}
+	for _, device := range status.Devices {
+		status.RebootRequired = status.RebootRequired || device.MigModeChangePending
+	}
+
+	if status.LastApply != nil {
+		status.Drift = CheckDrift(c, status.AppliedConfig)
+	}
+
+	return WriteStatus(os.Stdout, f.OutputFormat, status)
+}
+
+func getDeviceStatus() ([]DeviceStatus, error) {
+	nvmlLib := nvml.New()
+	err := util.NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	deviceIDs, err := util.GetGPUDeviceIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error enumerating GPUs: %v", err)
+	}
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG Mode Manager: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG Config Manager: %v", err)
+	}
+
+	return GetDeviceStatus(modeManager, configManager, deviceIDs)
+}
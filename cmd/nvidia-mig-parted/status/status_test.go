@@ -0,0 +1,250 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package status
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/drift"
+	"github.com/NVIDIA/mig-parted/pkg/journal"
+	"github.com/NVIDIA/mig-parted/pkg/labels"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/reboot"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestStatus(t *testing.T) {
+	f := &Flags{
+		RebootMarkerFile: filepath.Join(t.TempDir(), "reboot-required"),
+		JournalFile:      filepath.Join(t.TempDir(), "apply-journal.jsonl"),
+		OutputFormat:     JSONFormat,
+	}
+
+	status, err := GetStatus(f)
+	require.Nil(t, err, "Unexpected failure from GetStatus")
+	require.False(t, status.RebootRequired)
+
+	var output bytes.Buffer
+	err = WriteStatus(&output, JSONFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.JSONEq(t, `{"reboot-required": false}`, output.String())
+
+	output.Reset()
+	err = WriteStatus(&output, TextFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.Equal(t, "Reboot required: no\n", output.String())
+
+	marker := &reboot.Marker{
+		Reason: reboot.ReasonResetSkipped,
+		GPUs: []reboot.GPU{
+			{Index: 0, DeviceID: "0x20B010DE", CurrentMode: "Disabled", DesiredMode: "Enabled"},
+		},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	err = reboot.Write(f.RebootMarkerFile, marker)
+	require.Nil(t, err, "Unexpected failure from Write")
+
+	status, err = GetStatus(f)
+	require.Nil(t, err, "Unexpected failure from GetStatus")
+	require.True(t, status.RebootRequired)
+
+	output.Reset()
+	err = WriteStatus(&output, JSONFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.JSONEq(t, `{
+		"reboot-required": true,
+		"reason": "gpu-reset-skipped",
+		"gpus": [{"index": 0, "device-id": "0x20B010DE", "current-mode": "Disabled", "desired-mode": "Enabled"}],
+		"timestamp": "2024-01-02T03:04:05Z"
+	}`, output.String())
+
+	output.Reset()
+	err = WriteStatus(&output, TextFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.Equal(t, "Reboot required: yes (gpu-reset-skipped)\n  GPU 0 (0x20B010DE): MIG mode Disabled -> Enabled\n", output.String())
+}
+
+func TestStatusWithJournal(t *testing.T) {
+	f := &Flags{
+		RebootMarkerFile: filepath.Join(t.TempDir(), "reboot-required"),
+		JournalFile:      filepath.Join(t.TempDir(), "apply-journal.jsonl"),
+		OutputFormat:     TextFormat,
+	}
+
+	succeeded := &journal.Entry{
+		Timestamp:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-1g.5gb",
+		Outcome:        journal.OutcomeSucceeded,
+		DurationMS:     1500,
+	}
+	failed := &journal.Entry{
+		Timestamp:      time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC),
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-7g.40gb",
+		Outcome:        journal.OutcomeFailed,
+		Error:          "error applying MIG configuration with hooks: in use",
+		DurationMS:     250,
+	}
+	require.Nil(t, journal.Append(f.JournalFile, succeeded))
+	require.Nil(t, journal.Append(f.JournalFile, failed))
+
+	status, err := GetStatus(f)
+	require.Nil(t, err, "Unexpected failure from GetStatus")
+	require.Equal(t, succeeded, status.AppliedConfig)
+	require.Equal(t, failed, status.LastApply)
+
+	status.Devices = []DeviceStatus{
+		{Index: 0, DeviceID: "0x20B010DE", MigCapable: true, MigMode: "Enabled", MigDevices: types.MigConfig{"1g.5gb": 6, "1c.3g.20gb": 1}},
+		{Index: 1, DeviceID: "0x20B010DE", MigCapable: true, MigMode: "Disabled", MigModeChangePending: true},
+		{Index: 2, DeviceID: "0x1EB810DE"},
+	}
+	AddLabels(status.Devices, map[int]*labels.Entry{
+		0: {GPU: 0, SelectedConfig: "all-1g.5gb", Labels: map[string]string{"tier": "burst", "team": "vision"}},
+		3: {GPU: 3, SelectedConfig: "all-1g.5gb", Labels: map[string]string{"team": "audio"}},
+	})
+	status.Drift = &Drift{Result: DriftDetected, Reason: "GPU 0 has unexpected MIG devices"}
+
+	var output bytes.Buffer
+	err = WriteStatus(&output, TextFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.Equal(t, ""+
+		"GPU 0 (0x20B010DE): MIG Enabled, 1c.3g.20gb x1, 1g.5gb x6\n"+
+		"  Labels: team=vision, tier=burst\n"+
+		"GPU 1 (0x20B010DE): MIG Disabled (change pending)\n"+
+		"GPU 2 (0x1EB810DE): MIG not supported\n"+
+		"Applied config: all-1g.5gb from /etc/nvidia-mig-manager/config.yaml at 2024-01-02T03:04:05Z\n"+
+		"Drift: detected (GPU 0 has unexpected MIG devices)\n"+
+		"Last apply: failed at 2024-01-03T03:04:05Z (took 250ms)\n"+
+		"  error applying MIG configuration with hooks: in use\n"+
+		"Reboot required: no\n",
+		output.String())
+}
+
+func TestStatusWithDriftState(t *testing.T) {
+	f := &Flags{
+		RebootMarkerFile: filepath.Join(t.TempDir(), "reboot-required"),
+		JournalFile:      filepath.Join(t.TempDir(), "apply-journal.jsonl"),
+		DriftStateFile:   filepath.Join(t.TempDir(), "drift.json"),
+		OutputFormat:     TextFormat,
+	}
+
+	state := &drift.State{
+		Result:         drift.ResultDetected,
+		Reason:         "not all GPUs match the specified config",
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-1g.5gb",
+		Timestamp:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	require.Nil(t, drift.Write(f.DriftStateFile, state))
+
+	status, err := GetStatus(f)
+	require.Nil(t, err, "Unexpected failure from GetStatus")
+	require.Equal(t, state, status.LastDrift)
+
+	var output bytes.Buffer
+	err = WriteStatus(&output, TextFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.Equal(t, ""+
+		"Last drift check: detected against all-1g.5gb from /etc/nvidia-mig-manager/config.yaml at 2024-01-02T03:04:05Z (not all GPUs match the specified config)\n"+
+		"Reboot required: no\n",
+		output.String())
+
+	output.Reset()
+	err = WriteStatus(&output, JSONFormat, status)
+	require.Nil(t, err, "Unexpected failure from WriteStatus")
+	require.JSONEq(t, `{
+		"reboot-required": false,
+		"last-drift-check": {
+			"result": "detected",
+			"reason": "not all GPUs match the specified config",
+			"config-file": "/etc/nvidia-mig-manager/config.yaml",
+			"selected-config": "all-1g.5gb",
+			"timestamp": "2024-01-02T03:04:05Z"
+		}
+	}`, output.String())
+}
+
+func TestCheckDriftUnknown(t *testing.T) {
+	testCases := []struct {
+		description string
+		applied     *journal.Entry
+		reason      string
+	}{
+		{
+			"no successful apply",
+			nil,
+			"no successful apply recorded",
+		},
+		{
+			"applied from plan",
+			&journal.Entry{PlanFile: "/tmp/plan.json", Outcome: journal.OutcomeSucceeded},
+			"config was applied from a plan",
+		},
+		{
+			"applied from stdin",
+			&journal.Entry{ConfigFile: "-", Outcome: journal.OutcomeSucceeded},
+			"config file was read from stdin",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			drift := CheckDrift(nil, tc.applied)
+			require.Equal(t, &Drift{Result: DriftUnknown, Reason: tc.reason}, drift)
+		})
+	}
+}
+
+func TestGetDeviceStatus(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE},
+				},
+			},
+		}).
+		WithGPU(testutil.GPU{
+			Model: testutil.A100_SXM4_40GB,
+		}).
+		MustBuild()
+	modeManager := mode.NewMockNvmlMigModeManager(server)
+	configManager := config.NewMockNvmlMigConfigManager(server)
+
+	deviceID := types.NewDeviceID(0x20B0, 0x10DE)
+	devices, err := GetDeviceStatus(modeManager, configManager, []types.DeviceID{deviceID, deviceID})
+	require.Nil(t, err, "Unexpected failure from GetDeviceStatus")
+	require.Equal(t, []DeviceStatus{
+		{Index: 0, DeviceID: "0x20B010DE", MigCapable: true, MigMode: "Enabled", MigDevices: types.MigConfig{"1c.3g.20gb": 1, "2c.3g.20gb": 1}},
+		{Index: 1, DeviceID: "0x20B010DE", MigCapable: true, MigMode: "Disabled"},
+	}, devices)
+}
@@ -0,0 +1,54 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/mig-parted/pkg/audit"
+)
+
+// Audit records a mutating invocation of 'command' in the system journal.
+// 'result' is its outcome and 'err' why it did not succeed, if it did not.
+// The audit entry is informational only, so errors writing it are logged
+// to 'log' rather than failing the invocation.
+func Audit(log *logrus.Logger, command string, config string, gpus []int, result string, err error) {
+	entry := &audit.Entry{
+		Command: command,
+		Config:  config,
+		GPUs:    gpus,
+		Result:  result,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	aerr := audit.Log(entry)
+	if aerr != nil {
+		log.Warnf("Error recording %s in the audit log: %v", command, aerr)
+	}
+}
+
+// AuditGPUs is like Audit for commands that either change all of 'gpus' or,
+// if they fail with 'err', none of them.
+func AuditGPUs(log *logrus.Logger, command string, config string, gpus []int, err error) {
+	if err != nil {
+		Audit(log, command, config, nil, audit.ResultFailed, err)
+		return
+	}
+	Audit(log, command, config, gpus, audit.ResultSucceeded, nil)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+// GPUCondition is a state of a GPU that keeps it from being reconfigured
+// until it is dealt with. Without checking for it up front, NVML only
+// reports it part way through an apply, as an unrelated-looking failure.
+type GPUCondition struct {
+	GPU    int
+	Reason string
+	// NeedsReset is set if resetting the GPU clears the condition.
+	NeedsReset bool
+}
+
+func (c GPUCondition) String() string {
+	return fmt.Sprintf("GPU %d: %s", c.GPU, c.Reason)
+}
+
+// GetGPUConditions returns the conditions of 'gpus' that keep them from
+// being reconfigured: a pending drain, or a pending row remapping or page
+// retirement, both of which need a GPU reset to complete. Conditions that
+// NVML cannot query on a GPU are skipped. NVML must be initialized.
+func GetGPUConditions(nvmlLib nvml.Interface, gpus []int) ([]GPUCondition, error) {
+	var conditions []GPUCondition
+	for _, gpu := range gpus {
+		c, err := getGPUConditions(nvmlLib, gpu)
+		if err != nil {
+			return nil, fmt.Errorf("GPU %d: %v", gpu, err)
+		}
+		conditions = append(conditions, c...)
+	}
+	return conditions, nil
+}
+
+func getGPUConditions(nvmlLib nvml.Interface, gpu int) ([]GPUCondition, error) {
+	device, ret := nvmlLib.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %v", ret)
+	}
+
+	var conditions []GPUCondition
+
+	pciInfo, ret := device.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting PCI info: %v", ret)
+	}
+	drain, ret := nvmlLib.DeviceQueryDrainState(&pciInfo)
+	if queryable(ret) {
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error querying drain state: %v", ret)
+		}
+		if drain == nvml.FEATURE_ENABLED {
+			conditions = append(conditions, GPUCondition{GPU: gpu, Reason: "drain pending"})
+		}
+	}
+
+	_, _, remapPending, _, ret := device.GetRemappedRows()
+	if queryable(ret) {
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting remapped rows: %v", ret)
+		}
+		if remapPending {
+			conditions = append(conditions, GPUCondition{GPU: gpu, Reason: "reset required (row remapping pending)", NeedsReset: true})
+		}
+	}
+
+	retirePending, ret := device.GetRetiredPagesPendingStatus()
+	if queryable(ret) {
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting retired pages pending status: %v", ret)
+		}
+		if retirePending == nvml.FEATURE_ENABLED {
+			conditions = append(conditions, GPUCondition{GPU: gpu, Reason: "reset required (page retirement pending)", NeedsReset: true})
+		}
+	}
+
+	return conditions, nil
+}
+
+// queryable returns whether 'ret' leaves a condition worth reporting, as
+// opposed to one the GPU (or the caller) cannot query at all.
+func queryable(ret nvml.Return) bool {
+	err := nvmlerrors.New(ret)
+	return !nvmlerrors.IsUnsupported(err) && ret != nvml.ERROR_NO_PERMISSION
+}
+
+// JoinGPUConditions returns 'conditions' as a single line.
+func JoinGPUConditions(conditions []GPUCondition) string {
+	var s []string
+	for _, c := range conditions {
+		s = append(s, c.String())
+	}
+	return strings.Join(s, "; ")
+}
@@ -21,6 +21,7 @@ import (
 	"os/exec"
 	"strings"
 
+	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 	"github.com/NVIDIA/go-nvlib/pkg/nvpci"
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
@@ -39,14 +40,20 @@ func GetGPUDeviceIDs() ([]types.DeviceID, error) {
 }
 
 func ResetAllGPUs() (string, error) {
+	return ResetGPUs(func(int, types.DeviceID) bool { return false })
+}
+
+// ResetGPUs resets every GPU except those for which 'skip' returns true. GPUs
+// are passed to 'skip' with the same index they have in GetGPUDeviceIDs().
+func ResetGPUs(skip func(int, types.DeviceID) bool) (string, error) {
 	nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
 	if err != nil {
 		return "", fmt.Errorf("error checking if nvidia module loaded: %v", err)
 	}
 	if nvidiaModuleLoaded {
-		return nvmlResetAllGPUs()
+		return nvmlResetGPUs(skip)
 	}
-	return pciResetAllGPUs()
+	return pciResetGPUs(skip)
 }
 
 func pciVisitGPUs(visit func(*nvpci.NvidiaPCIDevice) error) error {
@@ -100,8 +107,122 @@ func nvmlGetGPUDeviceIDs() ([]types.DeviceID, error) {
 	return ids, nil
 }
 
-func pciResetAllGPUs() (string, error) {
+// GetGPUUUIDs returns the UUID of every GPU, in the same order as
+// GetGPUDeviceIDs(). UUIDs are only available through NVML, so the nvidia
+// module must be loaded.
+func GetGPUUUIDs() ([]string, error) {
+	nvmlLib := nvml.New()
+	err := NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer TryNvmlShutdown(nvmlLib)
+
+	var uuids []string
+	err = pciVisitGPUs(func(gpu *nvpci.NvidiaPCIDevice) error {
+		device, ret := nvmlLib.DeviceGetHandleByPciBusId(gpu.Address)
+		if ret != nvml.SUCCESS {
+			return nil
+		}
+
+		uuid, ret := device.GetUUID()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting UUID of GPU %v: %v", gpu.Address, ret)
+		}
+
+		uuids = append(uuids, uuid)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uuids, nil
+}
+
+// GetMigProfileIDs returns the numeric profile IDs of the MIG profiles
+// supported by GPU 'gpu'. GPU instance profile IDs differ between GPU models,
+// so they are always queried from the device itself through NVML.
+func GetMigProfileIDs(gpu int) (types.MigProfileIDs, error) {
+	nvmlLib := nvml.New()
+	err := NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer TryNvmlShutdown(nvmlLib)
+
+	handle, ret := nvmlLib.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %v", ret)
+	}
+
+	device, err := nvdev.New(nvdev.WithNvml(nvmlLib)).NewDevice(handle)
+	if err != nil {
+		return nil, fmt.Errorf("error creating device: %v", err)
+	}
+
+	profiles, err := device.GetMigProfiles()
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG profiles: %v", err)
+	}
+
+	ids := make(types.MigProfileIDs)
+	for _, p := range profiles {
+		info := p.GetInfo()
+		if info.CIEngProfileID != nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED {
+			continue
+		}
+		giInfo, ret := handle.GetGpuInstanceProfileInfo(info.GIProfileID)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %v", p, ret)
+		}
+		ids.Add(int(giInfo.Id), info.CIProfileID, &types.MigProfile{MigProfileInfo: info})
+	}
+	return ids, nil
+}
+
+// GetGPUPciBusIDs returns the PCI bus ID of every GPU, in the same order as
+// GetGPUDeviceIDs().
+func GetGPUPciBusIDs() ([]string, error) {
+	nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
+	if err != nil {
+		return nil, fmt.Errorf("error checking if nvidia module loaded: %v", err)
+	}
+
+	visible := func(string) bool { return true }
+	if nvidiaModuleLoaded {
+		nvmlLib := nvml.New()
+		err := NvmlInit(nvmlLib)
+		if err != nil {
+			return nil, fmt.Errorf("error initializing NVML: %v", err)
+		}
+		defer TryNvmlShutdown(nvmlLib)
+
+		visible = func(address string) bool {
+			_, ret := nvmlLib.DeviceGetHandleByPciBusId(address)
+			return ret == nvml.SUCCESS
+		}
+	}
+
+	var ids []string
+	err = pciVisitGPUs(func(gpu *nvpci.NvidiaPCIDevice) error {
+		if visible(gpu.Address) {
+			ids = append(ids, gpu.Address)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func pciResetGPUs(skip func(int, types.DeviceID) bool) (string, error) {
+	i := 0
 	err := pciVisitGPUs(func(gpu *nvpci.NvidiaPCIDevice) error {
+		defer func() { i++ }()
+		if skip(i, types.NewDeviceID(gpu.Device, gpu.Vendor)) {
+			return nil
+		}
 		err := gpu.Reset()
 		if err != nil {
 			return fmt.Errorf("error resetting GPU %v: %v", gpu.Address, err)
@@ -111,7 +232,7 @@ func pciResetAllGPUs() (string, error) {
 	return "", err
 }
 
-func nvmlGetGPUPciBusIds() ([]string, error) {
+func nvmlGetGPUPciBusIds(skip func(int, types.DeviceID) bool) ([]string, error) {
 	nvmlLib := nvml.New()
 	err := NvmlInit(nvmlLib)
 	if err != nil {
@@ -120,13 +241,19 @@ func nvmlGetGPUPciBusIds() ([]string, error) {
 	defer TryNvmlShutdown(nvmlLib)
 
 	var ids []string
+	i := 0
 	err = pciVisitGPUs(func(gpu *nvpci.NvidiaPCIDevice) error {
+		_, ret := nvmlLib.DeviceGetHandleByPciBusId(gpu.Address)
+		if ret != nvml.SUCCESS {
+			return nil
+		}
+		defer func() { i++ }()
+
 		if !gpu.Is3DController() {
 			return nil
 		}
 
-		_, ret := nvmlLib.DeviceGetHandleByPciBusId(gpu.Address)
-		if ret != nvml.SUCCESS {
+		if skip(i, types.NewDeviceID(gpu.Device, gpu.Vendor)) {
 			return nil
 		}
 
@@ -140,8 +267,8 @@ func nvmlGetGPUPciBusIds() ([]string, error) {
 	return ids, nil
 }
 
-func nvmlResetAllGPUs() (string, error) {
-	pciBusIDs, err := nvmlGetGPUPciBusIds()
+func nvmlResetGPUs(skip func(int, types.DeviceID) bool) (string, error) {
+	pciBusIDs, err := nvmlGetGPUPciBusIds(skip)
 	if err != nil {
 		return "", fmt.Errorf("error getting GPU pci bus IDs: %v", err)
 	}
@@ -154,3 +281,42 @@ func nvmlResetAllGPUs() (string, error) {
 	output, err := cmd.CombinedOutput()
 	return string(output), err
 }
+
+// GetMigDeviceUUIDs returns the UUID of every MIG device on 'device', keyed
+// by its GPU instance and compute instance IDs.
+func GetMigDeviceUUIDs(device nvml.Device) (map[[2]uint32]string, error) {
+	maxCount, ret := device.GetMaxMigDeviceCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting max MIG device count: %v", ret)
+	}
+
+	uuids := make(map[[2]uint32]string)
+	for j := 0; j < maxCount; j++ {
+		mig, ret := device.GetMigDeviceHandleByIndex(j)
+		if ret == nvml.ERROR_NOT_FOUND {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting MIG device handle %d: %v", j, ret)
+		}
+
+		giID, ret := mig.GetGpuInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance ID of MIG device %d: %v", j, ret)
+		}
+
+		ciID, ret := mig.GetComputeInstanceId()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting compute instance ID of MIG device %d: %v", j, ret)
+		}
+
+		uuid, ret := mig.GetUUID()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting UUID of MIG device %d: %v", j, ret)
+		}
+
+		uuids[[2]uint32{uint32(giID), uint32(ciID)}] = uuid
+	}
+
+	return uuids, nil
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"strings"
+
+	cli "github.com/urfave/cli/v2"
+)
+
+// The constructors below build the flags shared by the apply, assert,
+// export, checkpoint and restore commands, so that each of them spells,
+// abbreviates and reads them from the environment in the same way.
+
+// ConfigFileFlag returns the '--config-file' ('-f') flag.
+func ConfigFileFlag(destination *string, usage string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "config-file",
+		Aliases:     []string{"f"},
+		Usage:       usage,
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+	}
+}
+
+// SelectedConfigFlag returns the '--selected-config' ('-c') flag.
+func SelectedConfigFlag(destination *string, usage string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "selected-config",
+		Aliases:     []string{"c"},
+		Usage:       usage,
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+	}
+}
+
+// SelectedConfigFromFlag returns the '--selected-config-from' flag.
+func SelectedConfigFromFlag(destination *string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "selected-config-from",
+		Usage:       "Where to look up the label of the mig-config to select instead of 'selected-config' [ec2:<tag> | gce:<attribute> | azure:<tag> | http(s)://<url>]",
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG_FROM"},
+	}
+}
+
+// CheckpointFileFlag returns the '--checkpoint-file' ('-f') flag.
+func CheckpointFileFlag(destination *string, usage string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "checkpoint-file",
+		Aliases:     []string{"f"},
+		Usage:       usage,
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_CHECKPOINT_FILE"},
+	}
+}
+
+// HooksFileFlag returns the '--hooks-file' ('-k') flag.
+func HooksFileFlag(destination *string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "hooks-file",
+		Aliases:     []string{"k"},
+		Usage:       "Path to the hooks file ('-' for stdin)",
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_HOOKS_FILE"},
+	}
+}
+
+// ModeOnlyFlag returns the '--mode-only' ('-m') flag.
+func ModeOnlyFlag(destination *bool, usage string) *cli.BoolFlag {
+	return &cli.BoolFlag{
+		Name:        "mode-only",
+		Aliases:     []string{"m"},
+		Usage:       usage,
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_MODE_CHANGE_ONLY"},
+	}
+}
+
+// OutputFormatFlag returns the '--output-format' ('-o') flag, accepting any
+// of 'formats' and defaulting to the first of them.
+func OutputFormatFlag(destination *string, formats ...string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "output-format",
+		Aliases:     []string{"o"},
+		Usage:       fmt.Sprintf("Format for the output [%v]", strings.Join(formats, " | ")),
+		Destination: destination,
+		Value:       formats[0],
+		EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+	}
+}
+
+// TextfileDirFlag returns the '--textfile-dir' flag.
+func TextfileDirFlag(destination *string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "textfile-dir",
+		Usage:       "Directory of the node_exporter textfile collector to write metrics of each run to (disabled if empty)",
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_TEXTFILE_DIR"},
+	}
+}
@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// HealthThresholds are the limits past which a GPU is considered unhealthy
+// by GetUnhealthyGPUs.
+type HealthThresholds struct {
+	// MaxUncorrectableECCErrors is the number of uncorrectable ECC errors
+	// since the driver was loaded that a GPU may have.
+	MaxUncorrectableECCErrors uint64
+	// MaxRetiredPages is the number of memory pages a GPU may have retired.
+	MaxRetiredPages int
+	// ThermalThrottle marks GPUs whose clocks are throttled because they
+	// are too hot as unhealthy.
+	ThermalThrottle bool
+}
+
+// GetUnhealthyGPUs returns a condition for each health signal of 'gpus'
+// that is past 't': uncorrectable ECC errors, retired pages and thermal
+// throttling. Signals that NVML cannot query on a GPU (e.g. ECC on a GPU
+// with ECC disabled) are skipped. NVML must be initialized.
+func GetUnhealthyGPUs(nvmlLib nvml.Interface, gpus []int, t HealthThresholds) ([]GPUCondition, error) {
+	var conditions []GPUCondition
+	for _, gpu := range gpus {
+		c, err := getUnhealthyConditions(nvmlLib, gpu, t)
+		if err != nil {
+			return nil, fmt.Errorf("GPU %d: %v", gpu, err)
+		}
+		conditions = append(conditions, c...)
+	}
+	return conditions, nil
+}
+
+func getUnhealthyConditions(nvmlLib nvml.Interface, gpu int, t HealthThresholds) ([]GPUCondition, error) {
+	device, ret := nvmlLib.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %v", ret)
+	}
+
+	var conditions []GPUCondition
+
+	ecc, ret := device.GetTotalEccErrors(nvml.MEMORY_ERROR_TYPE_UNCORRECTED, nvml.VOLATILE_ECC)
+	if queryable(ret) {
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting uncorrectable ECC errors: %v", ret)
+		}
+		if ecc > t.MaxUncorrectableECCErrors {
+			conditions = append(conditions, GPUCondition{GPU: gpu, Reason: fmt.Sprintf("%d uncorrectable ECC errors", ecc)})
+		}
+	}
+
+	retired := 0
+	for _, cause := range []nvml.PageRetirementCause{nvml.PAGE_RETIREMENT_CAUSE_MULTIPLE_SINGLE_BIT_ECC_ERRORS, nvml.PAGE_RETIREMENT_CAUSE_DOUBLE_BIT_ECC_ERROR} {
+		pages, ret := device.GetRetiredPages(cause)
+		if !queryable(ret) {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting retired pages: %v", ret)
+		}
+		retired += len(pages)
+	}
+	if retired > t.MaxRetiredPages {
+		conditions = append(conditions, GPUCondition{GPU: gpu, Reason: fmt.Sprintf("%d retired pages", retired)})
+	}
+
+	if t.ThermalThrottle {
+		reasons, ret := device.GetCurrentClocksThrottleReasons()
+		if queryable(ret) {
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("error getting clock throttle reasons: %v", ret)
+			}
+			if reasons&(nvml.ClocksThrottleReasonHwThermalSlowdown|nvml.ClocksThrottleReasonSwThermalSlowdown) != 0 {
+				conditions = append(conditions, GPUCondition{GPU: gpu, Reason: "thermal throttling"})
+			}
+		}
+	}
+
+	return conditions, nil
+}
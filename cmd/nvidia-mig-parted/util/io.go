@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// StdioPath is the special path used to refer to stdin or stdout.
+const StdioPath = "-"
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// ReadFile reads the full contents of 'path', or of stdin if 'path' is '-'.
+func ReadFile(path string) ([]byte, error) {
+	if path == StdioPath {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(path)
+}
+
+// CreateFile creates (or truncates) the file at 'path' for writing.
+// If 'path' is '-', stdout is returned instead and closing it is a no-op.
+func CreateFile(path string) (io.WriteCloser, error) {
+	if path == StdioPath {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
+
+// WriteToFile calls 'write' with the file at 'path' (or stdout if 'path' is
+// '-'), created as by CreateFile, and closes it again. An error closing the
+// file is returned too, as the output may not have been written out in full.
+func WriteToFile(path string, write func(w io.Writer) error) error {
+	f, err := CreateFile(path)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// CheckNotTerminal returns an error if 'path' is '-' and stdout is a
+// terminal, for output that is meant to be saved rather than read, such as a
+// checkpoint, and that a terminal would only garble.
+func CheckNotTerminal(path string, what string) error {
+	if IsStdio(path) && IsTerminal(os.Stdout) {
+		return fmt.Errorf("refusing to write %v to a terminal; redirect stdout or write it to a file instead", what)
+	}
+	return nil
+}
+
+// IsDir checks if 'path' is a directory. Stdin never is one.
+func IsDir(path string) bool {
+	if IsStdio(path) {
+		return false
+	}
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// ConfigFragmentPaths returns the paths of the '*.yaml' and '*.yml' files in
+// the config directory 'dir', in the lexical order of their names that they
+// are merged in. Hidden files (e.g. editor swap files) are skipped.
+func ConfigFragmentPaths(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		switch filepath.Ext(name) {
+		case ".yaml", ".yml":
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no '*.yaml' or '*.yml' files in '%v'", dir)
+	}
+	sort.Strings(names)
+
+	var paths []string
+	for _, name := range names {
+		paths = append(paths, filepath.Join(dir, name))
+	}
+	return paths, nil
+}
+
+// IsStdio checks if 'path' refers to stdin or stdout.
+func IsStdio(path string) bool {
+	return path == StdioPath
+}
+
+// IsTerminal checks if 'f' is attached to a terminal. Other character
+// devices, such as /dev/null, are not terminals.
+func IsTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
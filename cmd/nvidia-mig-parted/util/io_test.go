@@ -0,0 +1,235 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// withStdio replaces stdin and stdout with files in a temporary directory
+// for the duration of the test, with 'stdin' as the contents of stdin. It
+// returns the path of the file standing in for stdout.
+func withStdio(t *testing.T, stdin string) string {
+	dir := t.TempDir()
+
+	stdinPath := filepath.Join(dir, "stdin")
+	err := os.WriteFile(stdinPath, []byte(stdin), 0644)
+	require.Nil(t, err, "Unexpected failure writing stdin")
+	in, err := os.Open(stdinPath)
+	require.Nil(t, err, "Unexpected failure opening stdin")
+
+	stdoutPath := filepath.Join(dir, "stdout")
+	out, err := os.Create(stdoutPath)
+	require.Nil(t, err, "Unexpected failure creating stdout")
+
+	savedStdin, savedStdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = in, out
+	t.Cleanup(func() {
+		os.Stdin, os.Stdout = savedStdin, savedStdout
+		in.Close()
+		out.Close()
+	})
+
+	return stdoutPath
+}
+
+func TestReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	err := os.WriteFile(path, []byte("from file"), 0644)
+	require.Nil(t, err, "Unexpected failure writing file")
+
+	withStdio(t, "from stdin")
+
+	testCases := []struct {
+		Description     string
+		Path            string
+		Expected        string
+		ExpectedFailure bool
+	}{
+		{
+			"Stdin",
+			StdioPath,
+			"from stdin",
+			false,
+		},
+		{
+			"File",
+			path,
+			"from file",
+			false,
+		},
+		{
+			"Missing file",
+			path + ".missing",
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			contents, err := ReadFile(tc.Path)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from ReadFile")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from ReadFile")
+			require.Equal(t, tc.Expected, string(contents))
+		})
+	}
+}
+
+func TestCreateFile(t *testing.T) {
+	dir := t.TempDir()
+	stdout := withStdio(t, "")
+
+	testCases := []struct {
+		Description     string
+		Path            string
+		ExpectedPath    string
+		ExpectedFailure bool
+	}{
+		{
+			"Stdout",
+			StdioPath,
+			stdout,
+			false,
+		},
+		{
+			"File",
+			filepath.Join(dir, "output.yaml"),
+			filepath.Join(dir, "output.yaml"),
+			false,
+		},
+		{
+			"Missing directory",
+			filepath.Join(dir, "missing", "output.yaml"),
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			w, err := CreateFile(tc.Path)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from CreateFile")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from CreateFile")
+
+			_, err = w.Write([]byte(tc.Description))
+			require.Nil(t, err, "Unexpected failure writing output")
+			err = w.Close()
+			require.Nil(t, err, "Unexpected failure closing output")
+
+			contents, err := os.ReadFile(tc.ExpectedPath)
+			require.Nil(t, err, "Unexpected failure reading output")
+			require.Equal(t, tc.Description, string(contents))
+		})
+	}
+
+	// Closing stdout must leave it usable for later output.
+	_, err := os.Stdout.Write([]byte(" again"))
+	require.Nil(t, err, "Stdout closed by CreateFile")
+}
+
+func TestWriteToFile(t *testing.T) {
+	dir := t.TempDir()
+	stdout := withStdio(t, "")
+
+	testCases := []struct {
+		Description     string
+		Path            string
+		Write           func(w io.Writer) error
+		ExpectedPath    string
+		ExpectedFailure bool
+	}{
+		{
+			"Stdout",
+			StdioPath,
+			func(w io.Writer) error {
+				_, err := w.Write([]byte("output"))
+				return err
+			},
+			stdout,
+			false,
+		},
+		{
+			"File",
+			filepath.Join(dir, "output.yaml"),
+			func(w io.Writer) error {
+				_, err := w.Write([]byte("output"))
+				return err
+			},
+			filepath.Join(dir, "output.yaml"),
+			false,
+		},
+		{
+			"Write failure",
+			filepath.Join(dir, "failed.yaml"),
+			func(w io.Writer) error {
+				return fmt.Errorf("write failed")
+			},
+			"",
+			true,
+		},
+		{
+			"Create failure",
+			filepath.Join(dir, "missing", "output.yaml"),
+			func(w io.Writer) error {
+				return nil
+			},
+			"",
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			err := WriteToFile(tc.Path, tc.Write)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from WriteToFile")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from WriteToFile")
+
+			contents, err := os.ReadFile(tc.ExpectedPath)
+			require.Nil(t, err, "Unexpected failure reading output")
+			require.Equal(t, "output", string(contents))
+		})
+	}
+}
+
+func TestCheckNotTerminal(t *testing.T) {
+	withStdio(t, "")
+
+	// Stdout is a regular file, which is not a terminal.
+	require.Nil(t, CheckNotTerminal(StdioPath, "a checkpoint"))
+	require.Nil(t, CheckNotTerminal(filepath.Join(t.TempDir(), "checkpoint.json"), "a checkpoint"))
+
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	require.Nil(t, err, "Unexpected failure opening %v", os.DevNull)
+	defer devNull.Close()
+	require.False(t, IsTerminal(devNull), "%v reported as a terminal", os.DevNull)
+}
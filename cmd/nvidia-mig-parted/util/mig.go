@@ -19,17 +19,70 @@ package util
 import (
 	"fmt"
 
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
 	"github.com/NVIDIA/mig-parted/pkg/mig/config"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const (
+	// AutoBackend uses NVML and falls back to nvidia-smi for any operation
+	// that NVML reports as unsupported.
+	AutoBackend = "auto"
+	// NvmlBackend only uses NVML.
+	NvmlBackend = "nvml"
+	// SmiBackend only uses nvidia-smi.
+	SmiBackend = "smi"
 )
 
+var backend = AutoBackend
+
+// SetBackend selects the backend used by the MIG mode and config Managers
+// returned from this package.
+func SetBackend(b string) error {
+	switch b {
+	case AutoBackend:
+	case NvmlBackend:
+	case SmiBackend:
+	default:
+		return fmt.Errorf("unrecognized backend: %v", b)
+	}
+	backend = b
+	return nil
+}
+
+// NewMigModeManager returns a MIG mode Manager for the selected backend. Inside
+// a vGPU guest VM, the returned Manager refuses to change the MIG mode.
 func NewMigModeManager() (mode.Manager, error) {
+	manager, err := newMigModeManager()
+	if err != nil {
+		return nil, err
+	}
+
+	guest, err := IsVGPUGuest()
+	if err != nil {
+		return nil, err
+	}
+	if guest {
+		return mode.NewVGPUGuestMigModeManager(manager), nil
+	}
+
+	return manager, nil
+}
+
+func newMigModeManager() (mode.Manager, error) {
 	nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
 	if err != nil {
 		return nil, fmt.Errorf("error checking if nvidia module loaded: %v", err)
 	}
 	if !nvidiaModuleLoaded {
-		return mode.NewPciMigModeManager(), nil
+		return newPciMigModeManager()
+	}
+
+	if backend == SmiBackend {
+		return mode.NewSmiMigModeManager(), nil
 	}
 
 	nvmlSupported, err := IsNVMLVersionSupported()
@@ -37,28 +90,187 @@ func NewMigModeManager() (mode.Manager, error) {
 		return nil, fmt.Errorf("error checking NVML version: %v", err)
 	}
 	if !nvmlSupported {
-		return mode.NewPciMigModeManager(), nil
+		return newPciMigModeManager()
 	}
 
-	return mode.NewNvmlMigModeManager(), nil
+	if backend == NvmlBackend {
+		return mode.NewNvmlMigModeManager(), nil
+	}
+
+	return mode.NewFallbackMigModeManager(mode.NewNvmlMigModeManager(), mode.NewSmiMigModeManager()), nil
+}
+
+// newPciMigModeManager returns a MIG mode Manager working on the PCI config
+// space of the GPUs, which only root can access.
+func newPciMigModeManager() (mode.Manager, error) {
+	err := CheckPrivilege(migcaps.PrivilegeAdmin)
+	if err != nil {
+		return nil, err
+	}
+	return mode.NewPciMigModeManager(), nil
 }
 
+// NewMigConfigManager returns a MIG config Manager for the selected backend.
+// Inside a vGPU guest VM, the returned Manager leaves the GPU instances created
+// by the hypervisor untouched.
 func NewMigConfigManager() (config.Manager, error) {
+	manager, err := newMigConfigManager()
+	if err != nil {
+		return nil, err
+	}
+
+	guest, err := IsVGPUGuest()
+	if err != nil {
+		return nil, err
+	}
+	if guest {
+		return config.NewVGPUGuestMigConfigManager(manager), nil
+	}
+
+	return manager, nil
+}
+
+func newMigConfigManager() (config.Manager, error) {
+	if backend == SmiBackend {
+		nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
+		if err != nil {
+			return nil, fmt.Errorf("error checking if nvidia module loaded: %v", err)
+		}
+		if !nvidiaModuleLoaded {
+			return nil, fmt.Errorf("nvidia module not loaded")
+		}
+		return config.NewSmiMigConfigManager(), nil
+	}
+
+	err := assertNvmlMigSupported()
+	if err != nil {
+		return nil, err
+	}
+
+	if backend == NvmlBackend {
+		return config.NewNvmlMigConfigManager(), nil
+	}
+
+	return config.NewFallbackMigConfigManager(config.NewNvmlMigConfigManager(), config.NewSmiMigConfigManager()), nil
+}
+
+// NewMigInstanceManager returns a Manager for individual GPU and compute
+// instances. Inside a vGPU guest VM, GPU instances cannot be created or destroyed.
+func NewMigInstanceManager() (config.InstanceManager, error) {
+	err := assertNvmlMigSupported()
+	if err != nil {
+		return nil, err
+	}
+
+	guest, err := IsVGPUGuest()
+	if err != nil {
+		return nil, err
+	}
+	if guest {
+		return config.NewVGPUGuestInstanceManager(config.NewNvmlInstanceManager()), nil
+	}
+
+	return config.NewNvmlInstanceManager(), nil
+}
+
+// MigConfigMatches is config.MigConfigMatches with the Manager returned by
+// NewMigInstanceManager, which is only created if 'desired' is empty.
+func MigConfigMatches(gpu int, current types.MigConfig, desired types.MigConfig) (bool, error) {
+	if len(desired) != 0 || !current.Equals(desired) {
+		return current.Equals(desired), nil
+	}
+	manager, err := NewMigInstanceManager()
+	if err != nil {
+		return false, err
+	}
+	return config.MigConfigMatches(manager, gpu, current, desired)
+}
+
+// NewCCModeManager returns a Manager for the CC mode of each GPU, which runs
+// the gpu-admin-tools script found on the PATH.
+func NewCCModeManager() (mode.CCManager, error) {
+	busIDs, err := GetGPUPciBusIDs()
+	if err != nil {
+		return nil, fmt.Errorf("error getting PCI bus IDs of GPUs: %v", err)
+	}
+	return mode.NewGpuToolsCCModeManager(mode.DefaultGpuToolsPath, busIDs), nil
+}
+
+// IsVGPUGuest checks if we are running inside a vGPU guest VM, where the MIG
+// mode and GPU instances of each GPU are owned by the hypervisor.
+func IsVGPUGuest() (bool, error) {
 	nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
 	if err != nil {
-		return nil, fmt.Errorf("error checking if nvidia module loaded: %v", err)
+		return false, fmt.Errorf("error checking if nvidia module loaded: %v", err)
+	}
+	if !nvidiaModuleLoaded {
+		return false, nil
+	}
+
+	guest, err := mode.IsVGPUGuest(nvml.New())
+	if err != nil {
+		return false, fmt.Errorf("error checking for vGPU guest: %v", err)
+	}
+	return guest, nil
+}
+
+func assertNvmlMigSupported() error {
+	nvidiaModuleLoaded, err := IsNvidiaModuleLoaded()
+	if err != nil {
+		return fmt.Errorf("error checking if nvidia module loaded: %v", err)
 	}
 	if !nvidiaModuleLoaded {
-		return nil, fmt.Errorf("nvidia module not loaded")
+		return fmt.Errorf("nvidia module not loaded")
 	}
 
 	nvmlSupported, err := IsNVMLVersionSupported()
 	if err != nil {
-		return nil, fmt.Errorf("error checking NVML version: %v", err)
+		return fmt.Errorf("error checking NVML version: %v", err)
 	}
 	if !nvmlSupported {
-		return nil, fmt.Errorf("NVML version unsupported for performing MIG operations")
+		return fmt.Errorf("NVML version unsupported for performing MIG operations")
+	}
+
+	return nil
+}
+
+// GetMigEnabledGPUs returns the indices of all GPUs that currently have MIG
+// mode enabled.
+func GetMigEnabledGPUs() ([]int, error) {
+	nvmlLib := nvml.New()
+	err := NvmlInit(nvmlLib)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer TryNvmlShutdown(nvmlLib)
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	modeManager, err := NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	var gpus []int
+	for i := 0; i < count; i++ {
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %d: %v", i, err)
+		}
+		if !capable {
+			continue
+		}
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %d: %v", i, err)
+		}
+		if m == mode.Enabled {
+			gpus = append(gpus, i)
+		}
 	}
 
-	return config.NewNvmlMigConfigManager(), nil
+	return gpus, nil
 }
@@ -0,0 +1,80 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/mig-parted/pkg/nodelock"
+)
+
+// NodeLockOwner is the name nvidia-mig-parted records itself under while it
+// holds the node lock.
+const NodeLockOwner = "nvidia-mig-parted"
+
+// DefaultNodeLockTimeout is how long to wait for the node lock by default.
+const DefaultNodeLockTimeout = 5 * time.Minute
+
+// NodeLockFileFlag returns the '--node-lock-file' flag.
+func NodeLockFileFlag(destination *string) *cli.StringFlag {
+	return &cli.StringFlag{
+		Name:        "node-lock-file",
+		Usage:       fmt.Sprintf("Lock file to hold while changing the GPUs, shared with the other components configuring them (e.g. '%v' for those of the GPU operator)", nodelock.DefaultPath),
+		Destination: destination,
+		EnvVars:     []string{"MIG_PARTED_NODE_LOCK_FILE"},
+	}
+}
+
+// NodeLockTimeoutFlag returns the '--node-lock-timeout' flag.
+func NodeLockTimeoutFlag(destination *time.Duration) *cli.DurationFlag {
+	return &cli.DurationFlag{
+		Name:        "node-lock-timeout",
+		Usage:       "How long to wait for the other components to release '--node-lock-file' before failing (0 to wait forever)",
+		Destination: destination,
+		Value:       DefaultNodeLockTimeout,
+		EnvVars:     []string{"MIG_PARTED_NODE_LOCK_TIMEOUT"},
+	}
+}
+
+// LockNode takes the node lock at 'path', if set, so that neither the other
+// commands of nvidia-mig-parted nor the other components following its
+// handshake (see package nodelock) change the GPUs through NVML until the
+// returned function releases it again. Every command changing the MIG
+// devices of a GPU takes it first.
+func LockNode(ctx context.Context, log *logrus.Logger, path string, timeout time.Duration) (func(), error) {
+	if path == "" {
+		return func() {}, nil
+	}
+
+	log.Debugf("Taking node lock '%v'...", path)
+	lock, err := nodelock.Acquire(ctx, path, NodeLockOwner, timeout)
+	if err != nil {
+		return nil, fmt.Errorf("error taking node lock: %w", err)
+	}
+
+	return func() {
+		err := lock.Release()
+		if err != nil {
+			log.Warnf("Error releasing node lock '%v': %v", path, err)
+		}
+	}, nil
+}
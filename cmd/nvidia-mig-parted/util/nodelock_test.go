@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/nodelock"
+)
+
+func TestLockNodeSerializesWriters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvml.lock")
+	log := logrus.New()
+
+	// Each writer stands in for a command changing the GPUs (e.g. 'apply'
+	// and 'restore') and records whether another one was changing them at
+	// the same time.
+	var mutex sync.Mutex
+	var active, overlaps int
+	write := func() error {
+		unlock, err := LockNode(context.Background(), log, path, 5*time.Second)
+		if err != nil {
+			return err
+		}
+		defer unlock()
+
+		mutex.Lock()
+		active++
+		if active > 1 {
+			overlaps++
+		}
+		mutex.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mutex.Lock()
+		active--
+		mutex.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 20)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- write()
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err, "Unexpected failure from LockNode")
+	}
+	require.Equal(t, 0, overlaps, "Writers changed the GPUs at the same time")
+}
+
+func TestLockNodeTimeout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvml.lock")
+	log := logrus.New()
+
+	unlock, err := LockNode(context.Background(), log, "", time.Second)
+	require.Nil(t, err, "Unexpected failure from LockNode without a lock file")
+	unlock()
+
+	unlock, err = LockNode(context.Background(), log, path, time.Second)
+	require.Nil(t, err, "Unexpected failure from LockNode")
+	defer unlock()
+
+	_, err = LockNode(context.Background(), log, path, 100*time.Millisecond)
+	require.True(t, errors.Is(err, nodelock.ErrTimeout), "Unexpected error from LockNode: %v", err)
+	require.Contains(t, err.Error(), "held by "+NodeLockOwner)
+}
@@ -50,7 +50,7 @@ func IsNVMLVersionSupported() (bool, error) {
 
 	ret := nvmlLib.Init()
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error initializing NVML: %v", ret)
+		return false, fmt.Errorf("error initializing NVML: %v", nvmlInitError(ret))
 	}
 	defer func() {
 		ret := nvmlLib.Shutdown()
@@ -87,7 +87,7 @@ func NvmlInit(nvmlLib nvml.Interface) error {
 	}
 	ret := nvmlLib.Init()
 	if ret != nvml.SUCCESS {
-		return ret
+		return nvmlInitError(ret)
 	}
 	return nil
 }
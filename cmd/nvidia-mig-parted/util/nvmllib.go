@@ -0,0 +1,212 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/go-nvml/pkg/dl"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+const (
+	// NvmlLibraryName is the name go-nvml loads the NVML library by.
+	NvmlLibraryName = "libnvidia-ml.so.1"
+	// DefaultDriverRoot is where the GPU operator's driver container mounts
+	// the root of its filesystem on the host.
+	DefaultDriverRoot = "/run/nvidia/driver"
+)
+
+// kernelModuleVersionFile reports the version of the loaded NVIDIA kernel
+// module. It is a variable so that it can be replaced in tests.
+var kernelModuleVersionFile = "/proc/driver/nvidia/version"
+
+// nvmlLibraryDirs are the directories distributions install the NVML library
+// to, searched under both the root and the driver root.
+var nvmlLibraryDirs = []string{
+	"/usr/lib64",
+	"/usr/lib/x86_64-linux-gnu",
+	"/usr/lib/aarch64-linux-gnu",
+	"/usr/lib",
+	"/lib64",
+	"/lib/x86_64-linux-gnu",
+	"/lib/aarch64-linux-gnu",
+	"/usr/local/nvidia/lib64",
+}
+
+// kernelModuleVersionRegex matches the version of the loaded NVIDIA kernel
+// module in /proc/driver/nvidia/version.
+var kernelModuleVersionRegex = regexp.MustCompile(`Kernel Module\s+([0-9.]+)`)
+
+// openLibrary loads the shared library at 'path' for the lifetime of the
+// process. It is a variable so that it can be replaced in tests.
+var openLibrary = func(path string) error {
+	return dl.New(path, dl.RTLD_LAZY|dl.RTLD_GLOBAL).Open()
+}
+
+// nvmlLibrary records the outcome of LoadNvmlLibrary, to explain why NVML
+// later fails to initialize.
+var nvmlLibrary struct {
+	path string
+	err  error
+}
+
+// LoadNvmlLibrary loads the NVML library so that go-nvml finds it by name,
+// wherever it is installed. By default, the dynamic loader is tried first
+// (honoring LD_LIBRARY_PATH), then the directories in LD_LIBRARY_PATH and the
+// standard library directories of the common distributions, both on the root
+// filesystem and under 'driverRoot' (where a driver container mounts the
+// driver). Once a library has been loaded from a full path, loading it again
+// by name returns that same library. If 'path' is set, only the library at
+// 'path' is loaded.
+//
+// An error is returned if no library can be loaded. It is also reported by
+// NvmlInit when NVML then fails to initialize, so that commands that do not
+// need NVML can ignore it.
+func LoadNvmlLibrary(path string, driverRoot string) error {
+	nvmlLibrary.path, nvmlLibrary.err = loadNvmlLibrary(path, driverRoot)
+	if nvmlLibrary.err == nil {
+		log.Debugf("Loaded NVML library from %v", nvmlLibrary.path)
+	}
+	return nvmlLibrary.err
+}
+
+func loadNvmlLibrary(path string, driverRoot string) (string, error) {
+	if path != "" {
+		err := openLibrary(path)
+		if err != nil {
+			return "", fmt.Errorf("error loading NVML library: %v", err)
+		}
+		return path, nil
+	}
+
+	err := openLibrary(NvmlLibraryName)
+	if err == nil {
+		return NvmlLibraryName, nil
+	}
+	errs := []string{err.Error()}
+
+	candidates := NvmlLibraryCandidates(driverRoot)
+	for _, candidate := range candidates {
+		err := openLibrary(candidate)
+		if err == nil {
+			return candidate, nil
+		}
+		errs = append(errs, err.Error())
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%v not found in the library path, in %v or under %v; is the NVIDIA driver installed (or its container running)?", NvmlLibraryName, strings.Join(nvmlLibraryDirs, ", "), driverRoot)
+	}
+	return "", fmt.Errorf("error loading NVML library: %v", strings.Join(errs, "; "))
+}
+
+// NvmlLibraryCandidates returns the paths the NVML library exists at in the
+// directories of LD_LIBRARY_PATH, then in the standard library directories
+// on the root filesystem and under 'driverRoot' (if set).
+func NvmlLibraryCandidates(driverRoot string) []string {
+	var dirs []string
+	for _, dir := range filepath.SplitList(os.Getenv("LD_LIBRARY_PATH")) {
+		if dir != "" {
+			dirs = append(dirs, dir)
+		}
+	}
+	dirs = append(dirs, nvmlLibraryDirs...)
+	if driverRoot != "" && driverRoot != "/" {
+		for _, dir := range nvmlLibraryDirs {
+			dirs = append(dirs, filepath.Join(driverRoot, dir))
+		}
+	}
+
+	var candidates []string
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		candidate := filepath.Join(dir, NvmlLibraryName)
+		if seen[candidate] {
+			continue
+		}
+		seen[candidate] = true
+		if _, err := os.Stat(candidate); err == nil {
+			candidates = append(candidates, candidate)
+		}
+	}
+	return candidates
+}
+
+// nvmlInitError explains why NVML failed to initialize with 'ret', as far as
+// it can be told from the library that was loaded and the driver.
+func nvmlInitError(ret nvml.Return) error {
+	switch ret {
+	case nvml.ERROR_LIBRARY_NOT_FOUND:
+		if nvmlLibrary.err != nil {
+			return fmt.Errorf("%w: %v", ret, nvmlLibrary.err)
+		}
+		return fmt.Errorf("%w: %v could not be loaded; is the NVIDIA driver installed (or its container running)?", ret, NvmlLibraryName)
+	case nvml.ERROR_DRIVER_NOT_LOADED:
+		return fmt.Errorf("%w: the NVIDIA kernel module is not loaded; is the driver (or its container) still starting?", ret)
+	case nvml.ERROR_LIB_RM_VERSION_MISMATCH:
+		return fmt.Errorf("%w: the NVML library (%v) does not match the loaded NVIDIA kernel module (%v); reboot after upgrading the driver, or make sure the library comes from the running driver", ret, nvmlLibraryVersion(), kernelModuleVersion())
+	case nvml.ERROR_NO_PERMISSION:
+		return fmt.Errorf("%w: insufficient permission to access the /dev/nvidia* device nodes", ret)
+	}
+	return ret
+}
+
+// nvmlLibraryVersion returns the driver version of the loaded NVML library,
+// taken from the name of the file its path resolves to, if it can be found.
+func nvmlLibraryVersion() string {
+	path := nvmlLibrary.path
+	if !filepath.IsAbs(path) {
+		candidates := NvmlLibraryCandidates("")
+		if len(candidates) == 0 {
+			return "unknown version"
+		}
+		path = candidates[0]
+	}
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "unknown version"
+	}
+	_, version, found := strings.Cut(filepath.Base(resolved), ".so.")
+	if !found || version == "1" {
+		return resolved
+	}
+	return version
+}
+
+// kernelModuleVersion returns the version of the loaded NVIDIA kernel module.
+func kernelModuleVersion() string {
+	contents, err := os.ReadFile(kernelModuleVersionFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "not loaded"
+	}
+	if err != nil {
+		return "unknown version"
+	}
+	match := kernelModuleVersionRegex.FindSubmatch(contents)
+	if match == nil {
+		return "unknown version"
+	}
+	return string(match[1])
+}
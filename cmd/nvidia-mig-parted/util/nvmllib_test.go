@@ -0,0 +1,307 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// setupNvmlLibraryDirs replaces the standard library directories with
+// '/lib-a' and '/lib-b', creates an NVML library in each of 'dirs' under
+// 'driverRoot', and clears LD_LIBRARY_PATH.
+func setupNvmlLibraryDirs(t *testing.T, driverRoot string, dirs ...string) {
+	saved := nvmlLibraryDirs
+	t.Cleanup(func() { nvmlLibraryDirs = saved })
+	nvmlLibraryDirs = []string{"/lib-a", "/lib-b"}
+
+	for _, dir := range dirs {
+		createNvmlLibrary(t, filepath.Join(driverRoot, dir))
+	}
+
+	t.Setenv("LD_LIBRARY_PATH", "")
+}
+
+func createNvmlLibrary(t *testing.T, dir string) {
+	err := os.MkdirAll(dir, 0755)
+	require.Nil(t, err, "Unexpected failure creating library directory")
+	err = os.WriteFile(filepath.Join(dir, NvmlLibraryName), nil, 0644)
+	require.Nil(t, err, "Unexpected failure creating library")
+}
+
+func TestNvmlLibraryCandidates(t *testing.T) {
+	driverRoot := t.TempDir()
+	ldDir := t.TempDir()
+	setupNvmlLibraryDirs(t, driverRoot, "/lib-a", "/lib-b")
+	createNvmlLibrary(t, ldDir)
+
+	testCases := []struct {
+		Description        string
+		LdLibraryPath      string
+		DriverRoot         string
+		ExpectedCandidates []string
+	}{
+		{
+			"Driver root",
+			"",
+			driverRoot,
+			[]string{
+				filepath.Join(driverRoot, "lib-a", NvmlLibraryName),
+				filepath.Join(driverRoot, "lib-b", NvmlLibraryName),
+			},
+		},
+		{
+			"No driver root",
+			"",
+			"",
+			nil,
+		},
+		{
+			"LD_LIBRARY_PATH before driver root",
+			ldDir,
+			driverRoot,
+			[]string{
+				filepath.Join(ldDir, NvmlLibraryName),
+				filepath.Join(driverRoot, "lib-a", NvmlLibraryName),
+				filepath.Join(driverRoot, "lib-b", NvmlLibraryName),
+			},
+		},
+		{
+			"LD_LIBRARY_PATH split and empty entries skipped",
+			strings.Join([]string{"", t.TempDir(), ldDir, ""}, string(os.PathListSeparator)),
+			"",
+			[]string{
+				filepath.Join(ldDir, NvmlLibraryName),
+			},
+		},
+		{
+			"Duplicates removed",
+			strings.Join([]string{filepath.Join(driverRoot, "lib-b"), ldDir, ldDir + "/"}, string(os.PathListSeparator)),
+			driverRoot,
+			[]string{
+				filepath.Join(driverRoot, "lib-b", NvmlLibraryName),
+				filepath.Join(ldDir, NvmlLibraryName),
+				filepath.Join(driverRoot, "lib-a", NvmlLibraryName),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			t.Setenv("LD_LIBRARY_PATH", tc.LdLibraryPath)
+			candidates := NvmlLibraryCandidates(tc.DriverRoot)
+			require.Equal(t, tc.ExpectedCandidates, candidates)
+		})
+	}
+}
+
+func TestLoadNvmlLibrary(t *testing.T) {
+	driverRoot := t.TempDir()
+	setupNvmlLibraryDirs(t, driverRoot, "/lib-a", "/lib-b")
+	libA := filepath.Join(driverRoot, "lib-a", NvmlLibraryName)
+	libB := filepath.Join(driverRoot, "lib-b", NvmlLibraryName)
+
+	defer func(f func(string) error) { openLibrary = f }(openLibrary)
+
+	testCases := []struct {
+		Description     string
+		Path            string
+		DriverRoot      string
+		Loadable        []string
+		ExpectedPath    string
+		ExpectedOpened  []string
+		ExpectedErrors  []string
+		ExpectedFailure bool
+	}{
+		{
+			"Explicit path",
+			"/custom/libnvidia-ml.so",
+			driverRoot,
+			[]string{"/custom/libnvidia-ml.so", NvmlLibraryName},
+			"/custom/libnvidia-ml.so",
+			[]string{"/custom/libnvidia-ml.so"},
+			nil,
+			false,
+		},
+		{
+			"Explicit path not loadable",
+			"/custom/libnvidia-ml.so",
+			driverRoot,
+			[]string{NvmlLibraryName},
+			"",
+			[]string{"/custom/libnvidia-ml.so"},
+			[]string{"/custom/libnvidia-ml.so"},
+			true,
+		},
+		{
+			"Dynamic loader first",
+			"",
+			driverRoot,
+			[]string{NvmlLibraryName, libA},
+			NvmlLibraryName,
+			[]string{NvmlLibraryName},
+			nil,
+			false,
+		},
+		{
+			"Candidates in order",
+			"",
+			driverRoot,
+			[]string{libB},
+			libB,
+			[]string{NvmlLibraryName, libA, libB},
+			nil,
+			false,
+		},
+		{
+			"Errors of all candidates aggregated",
+			"",
+			driverRoot,
+			nil,
+			"",
+			[]string{NvmlLibraryName, libA, libB},
+			[]string{NvmlLibraryName, libA, libB},
+			true,
+		},
+		{
+			"No candidates",
+			"",
+			"",
+			nil,
+			"",
+			[]string{NvmlLibraryName},
+			[]string{"not found"},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			var opened []string
+			openLibrary = func(path string) error {
+				opened = append(opened, path)
+				for _, l := range tc.Loadable {
+					if l == path {
+						return nil
+					}
+				}
+				return fmt.Errorf("%v: cannot open shared object file", path)
+			}
+
+			path, err := loadNvmlLibrary(tc.Path, tc.DriverRoot)
+			require.Equal(t, tc.ExpectedOpened, opened)
+			if tc.ExpectedFailure {
+				require.NotNil(t, err, "Unexpected success from loadNvmlLibrary")
+				for _, e := range tc.ExpectedErrors {
+					require.Contains(t, err.Error(), e)
+				}
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from loadNvmlLibrary")
+			require.Equal(t, tc.ExpectedPath, path)
+		})
+	}
+}
+
+func TestNvmlInitError(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "libnvidia-ml.so.550.54.15")
+	err := os.WriteFile(lib, nil, 0644)
+	require.Nil(t, err, "Unexpected failure creating library")
+	link := filepath.Join(dir, NvmlLibraryName)
+	err = os.Symlink(lib, link)
+	require.Nil(t, err, "Unexpected failure creating library link")
+
+	versionFile := filepath.Join(dir, "version")
+	err = os.WriteFile(versionFile, []byte("NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.104.05  Sat Aug 19 01:15:15 UTC 2023\n"), 0644)
+	require.Nil(t, err, "Unexpected failure creating version file")
+
+	defer func(f string) { kernelModuleVersionFile = f }(kernelModuleVersionFile)
+	defer func(path string, err error) {
+		nvmlLibrary.path, nvmlLibrary.err = path, err
+	}(nvmlLibrary.path, nvmlLibrary.err)
+
+	testCases := []struct {
+		Description       string
+		Ret               nvml.Return
+		LibraryPath       string
+		LibraryErr        error
+		VersionFile       string
+		ExpectedContained []string
+	}{
+		{
+			"Library not loaded",
+			nvml.ERROR_LIBRARY_NOT_FOUND,
+			"",
+			fmt.Errorf("libnvidia-ml.so.1 not found under /run/nvidia/driver"),
+			versionFile,
+			[]string{"not found under /run/nvidia/driver"},
+		},
+		{
+			"Library not found without load error",
+			nvml.ERROR_LIBRARY_NOT_FOUND,
+			"",
+			nil,
+			versionFile,
+			[]string{"could not be loaded", "is the NVIDIA driver installed"},
+		},
+		{
+			"Driver mismatch",
+			nvml.ERROR_LIB_RM_VERSION_MISMATCH,
+			link,
+			nil,
+			versionFile,
+			[]string{"NVML library (550.54.15)", "kernel module (535.104.05)"},
+		},
+		{
+			"Driver mismatch without kernel module",
+			nvml.ERROR_LIB_RM_VERSION_MISMATCH,
+			link,
+			nil,
+			filepath.Join(dir, "missing"),
+			[]string{"NVML library (550.54.15)", "kernel module (not loaded)"},
+		},
+		{
+			"Driver not loaded",
+			nvml.ERROR_DRIVER_NOT_LOADED,
+			link,
+			nil,
+			versionFile,
+			[]string{"kernel module is not loaded"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			nvmlLibrary.path, nvmlLibrary.err = tc.LibraryPath, tc.LibraryErr
+			kernelModuleVersionFile = tc.VersionFile
+
+			err := nvmlInitError(tc.Ret)
+			require.ErrorIs(t, err, tc.Ret)
+			for _, s := range tc.ExpectedContained {
+				require.Contains(t, err.Error(), s)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	migcaps "github.com/NVIDIA/mig-parted/pkg/mig/caps"
+)
+
+// CheckPrivilege checks that the process holds 'p' before the operations
+// needing it are attempted, so that running without it fails with an error
+// saying what to change rather than with NVML's generic 'Insufficient
+// Permissions'. The read-only paths ('export', 'assert') only need
+// migcaps.PrivilegeMonitor, and only for GPUs with MIG mode enabled.
+func CheckPrivilege(p migcaps.Privilege) error {
+	return migcaps.NewPrivilegeChecker().Check(p)
+}
@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package visualize
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const (
+	textSliceWidth = 10
+	svgSliceWidth  = 90
+	svgRowHeight   = 36
+	svgGpuHeight   = 80
+	svgMargin      = 10
+	freeLabel      = "free"
+)
+
+// GpuLayout describes which MIG devices occupy which memory slices of a GPU.
+type GpuLayout struct {
+	Index      int
+	Name       string
+	MigEnabled bool
+	NumSlices  int
+	Devices    []types.MigDevice
+}
+
+// segment is a contiguous range of memory slices that is either occupied by
+// a single GPU instance or free.
+type segment struct {
+	start int
+	size  int
+	label string
+	free  bool
+}
+
+// segments splits the memory slices of a GPU into the ranges occupied by each
+// of its GPU instances and the free ranges in between, ordered by start slice.
+func (l *GpuLayout) segments() []segment {
+	type key struct{ start, size int }
+	profiles := make(map[key][]string)
+	for _, d := range l.Devices {
+		k := key{int(d.GpuInstancePlacement.Start), int(d.GpuInstancePlacement.Size)}
+		profiles[k] = append(profiles[k], d.Profile)
+	}
+
+	var segments []segment
+	for k, p := range profiles {
+		segments = append(segments, segment{
+			start: k.start,
+			size:  k.size,
+			label: formatProfiles(p),
+		})
+	}
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start < segments[j].start
+	})
+
+	var all []segment
+	next := 0
+	for _, s := range segments {
+		if s.start > next {
+			all = append(all, segment{start: next, size: s.start - next, label: freeLabel, free: true})
+		}
+		all = append(all, s)
+		if s.start+s.size > next {
+			next = s.start + s.size
+		}
+	}
+	if next < l.NumSlices {
+		all = append(all, segment{start: next, size: l.NumSlices - next, label: freeLabel, free: true})
+	}
+
+	return all
+}
+
+// formatProfiles builds the label for a GPU instance from the profiles of the
+// compute instances it holds, e.g. "1c.4g.20gb x2".
+func formatProfiles(profiles []string) string {
+	counts := make(map[string]int)
+	var unique []string
+	for _, p := range profiles {
+		if counts[p] == 0 {
+			unique = append(unique, p)
+		}
+		counts[p]++
+	}
+	sort.Strings(unique)
+
+	var labels []string
+	for _, p := range unique {
+		if counts[p] > 1 {
+			labels = append(labels, fmt.Sprintf("%s x%d", p, counts[p]))
+			continue
+		}
+		labels = append(labels, p)
+	}
+	return strings.Join(labels, ", ")
+}
+
+// RenderText writes an ASCII diagram of each GPU in 'layouts' to 'w'.
+func RenderText(w io.Writer, layouts []GpuLayout) error {
+	var b strings.Builder
+	for i, l := range layouts {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "GPU %d: %s\n", l.Index, l.Name)
+		if !l.MigEnabled {
+			b.WriteString("  MIG disabled\n")
+			continue
+		}
+
+		segments := l.segments()
+
+		var indices strings.Builder
+		for s := 0; s < l.NumSlices; s++ {
+			fmt.Fprintf(&indices, "%-*d", textSliceWidth, s)
+		}
+		fmt.Fprintf(&b, "  %s\n", strings.TrimRight(indices.String(), " "))
+
+		border := func() {
+			b.WriteString("  ")
+			for _, s := range segments {
+				b.WriteString("+")
+				b.WriteString(strings.Repeat("-", s.size*textSliceWidth-1))
+			}
+			b.WriteString("+\n")
+		}
+
+		border()
+		b.WriteString("  ")
+		for _, s := range segments {
+			width := s.size*textSliceWidth - 1
+			label := " " + s.label
+			if len(label) > width {
+				label = label[:width]
+			}
+			fmt.Fprintf(&b, "|%-*s", width, label)
+		}
+		b.WriteString("|\n")
+		border()
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// RenderSVG writes an SVG diagram of each GPU in 'layouts' to 'w'.
+func RenderSVG(w io.Writer, layouts []GpuLayout) error {
+	maxSlices := 1
+	for _, l := range layouts {
+		if l.NumSlices > maxSlices {
+			maxSlices = l.NumSlices
+		}
+	}
+	width := 2*svgMargin + maxSlices*svgSliceWidth
+	height := 2*svgMargin + len(layouts)*svgGpuHeight
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" font-family="monospace" font-size="12">`+"\n", width, height)
+	for i, l := range layouts {
+		y := svgMargin + i*svgGpuHeight
+		fmt.Fprintf(&b, `  <text x="%d" y="%d">GPU %d: %s</text>`+"\n", svgMargin, y+14, l.Index, html.EscapeString(l.Name))
+		y += 24
+
+		if !l.MigEnabled {
+			fmt.Fprintf(&b, `  <text x="%d" y="%d">MIG disabled</text>`+"\n", svgMargin, y+svgRowHeight/2)
+			continue
+		}
+
+		for _, s := range l.segments() {
+			x := svgMargin + s.start*svgSliceWidth
+			fill := "#76b900"
+			if s.free {
+				fill = "#eeeeee"
+			}
+			fmt.Fprintf(&b, `  <rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#000000"/>`+"\n", x, y, s.size*svgSliceWidth, svgRowHeight, fill)
+			fmt.Fprintf(&b, `  <text x="%d" y="%d" text-anchor="middle" dominant-baseline="middle">%s</text>`+"\n", x+s.size*svgSliceWidth/2, y+svgRowHeight/2, html.EscapeString(s.label))
+		}
+	}
+	b.WriteString("</svg>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package visualize
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newMigDevice(profile string, start, size uint32) types.MigDevice {
+	return types.MigDevice{
+		Profile:              profile,
+		GpuInstancePlacement: nvml.GpuInstancePlacement{Start: start, Size: size},
+	}
+}
+
+func TestRenderText(t *testing.T) {
+	testCases := []struct {
+		description string
+		layouts     []GpuLayout
+		expected    string
+	}{
+		{
+			"MIG disabled",
+			[]GpuLayout{
+				{
+					Index: 0,
+					Name:  "NVIDIA A100-SXM4-40GB",
+				},
+			},
+			"GPU 0: NVIDIA A100-SXM4-40GB\n" +
+				"  MIG disabled\n",
+		},
+		{
+			"Empty",
+			[]GpuLayout{
+				{
+					Index:      0,
+					Name:       "NVIDIA A100-SXM4-40GB",
+					MigEnabled: true,
+					NumSlices:  2,
+				},
+			},
+			"GPU 0: NVIDIA A100-SXM4-40GB\n" +
+				"  0         1\n" +
+				"  +-------------------+\n" +
+				"  | free              |\n" +
+				"  +-------------------+\n",
+		},
+		{
+			"Fragmented",
+			[]GpuLayout{
+				{
+					Index:      0,
+					Name:       "NVIDIA A100-SXM4-40GB",
+					MigEnabled: true,
+					NumSlices:  8,
+					Devices: []types.MigDevice{
+						newMigDevice("1g.5gb", 5, 1),
+						newMigDevice("3g.20gb", 0, 4),
+						newMigDevice("1c.2g.10gb", 6, 2),
+						newMigDevice("1c.2g.10gb", 6, 2),
+					},
+				},
+			},
+			"GPU 0: NVIDIA A100-SXM4-40GB\n" +
+				"  0         1         2         3         4         5         6         7\n" +
+				"  +---------------------------------------+---------+---------+-------------------+\n" +
+				"  | 3g.20gb                               | free    | 1g.5gb  | 1c.2g.10gb x2     |\n" +
+				"  +---------------------------------------+---------+---------+-------------------+\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var b strings.Builder
+			err := RenderText(&b, tc.layouts)
+			require.Nil(t, err, "Unexpected failure from RenderText")
+			require.Equal(t, tc.expected, b.String())
+		})
+	}
+}
+
+func TestRenderSVG(t *testing.T) {
+	layouts := []GpuLayout{
+		{
+			Index:      0,
+			Name:       "NVIDIA A100-SXM4-40GB",
+			MigEnabled: true,
+			NumSlices:  8,
+			Devices: []types.MigDevice{
+				newMigDevice("7g.40gb", 0, 8),
+			},
+		},
+	}
+
+	var b strings.Builder
+	err := RenderSVG(&b, layouts)
+	require.Nil(t, err, "Unexpected failure from RenderSVG")
+
+	svg := b.String()
+	require.True(t, strings.HasPrefix(svg, "<svg "))
+	require.True(t, strings.HasSuffix(svg, "</svg>\n"))
+	require.Contains(t, svg, ">7g.40gb</text>")
+	require.NotContains(t, svg, ">free</text>")
+}
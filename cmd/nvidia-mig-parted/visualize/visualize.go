@@ -0,0 +1,305 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package visualize
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/sirupsen/logrus"
+	cli "github.com/urfave/cli/v2"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/assert"
+	"github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted/util"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var log = logrus.New()
+
+func GetLogger() *logrus.Logger {
+	return log
+}
+
+const (
+	TextFormat = "text"
+	SVGFormat  = "svg"
+)
+
+type Flags struct {
+	assert.Flags
+	OutputFormat string
+	OutputFile   string
+}
+
+func BuildCommand() *cli.Command {
+	// Create a flags struct to hold our flags
+	visualizeFlags := Flags{}
+
+	// Create the 'visualize' command
+	visualize := cli.Command{}
+	visualize.Name = "visualize"
+	visualize.Usage = "Render the placement of MIG devices on each GPU from the current or a proposed MIG config"
+	visualize.Action = func(c *cli.Context) error {
+		return visualizeWrapper(c, &visualizeFlags)
+	}
+
+	// Setup the flags for this command
+	visualize.Flags = []cli.Flag{
+		&cli.StringFlag{
+			Name:        "config-file",
+			Aliases:     []string{"f"},
+			Usage:       "Path to the configuration file to render a proposed config from ('-' for stdin)",
+			Destination: &visualizeFlags.ConfigFile,
+			EnvVars:     []string{"MIG_PARTED_CONFIG_FILE"},
+		},
+		&cli.StringFlag{
+			Name:        "selected-config",
+			Aliases:     []string{"c"},
+			Usage:       "The label of the mig-config from the config file to render",
+			Destination: &visualizeFlags.SelectedConfig,
+			EnvVars:     []string{"MIG_PARTED_SELECTED_CONFIG"},
+		},
+		&cli.StringFlag{
+			Name:        "output-format",
+			Aliases:     []string{"o"},
+			Usage:       "Format for the output [text | svg]",
+			Destination: &visualizeFlags.OutputFormat,
+			Value:       TextFormat,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FORMAT"},
+		},
+		&cli.StringFlag{
+			Name:        "output-file",
+			Usage:       "File to write the output to ('-' for stdout)",
+			Destination: &visualizeFlags.OutputFile,
+			Value:       util.StdioPath,
+			EnvVars:     []string{"MIG_PARTED_OUTPUT_FILE"},
+		},
+	}
+
+	return &visualize
+}
+
+func visualizeWrapper(c *cli.Context, f *Flags) error {
+	err := CheckFlags(f)
+	if err != nil {
+		_ = cli.ShowSubcommandHelp(c)
+		return err
+	}
+
+	var migConfig v1.MigConfigSpecSlice
+	var unmanaged v1.UnmanagedDeviceSpecSlice
+	if f.ConfigFile != "" {
+		log.Debugf("Parsing config file...")
+		spec, err := assert.ParseConfigFile(&f.Flags)
+		if err != nil {
+			return fmt.Errorf("error parsing config file: %v", err)
+		}
+
+		log.Debugf("Selecting specific MIG config...")
+		migConfig, err = assert.GetSelectedMigConfig(&f.Flags, spec)
+		if err != nil {
+			return fmt.Errorf("error selecting MIG config: %v", err)
+		}
+		unmanaged = spec.UnmanagedDevices
+	}
+
+	nvmlLib := nvml.New()
+	err = util.NvmlInit(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error initializing NVML: %v", err)
+	}
+	defer util.TryNvmlShutdown(nvmlLib)
+
+	layouts, err := GetCurrentLayouts(nvmlLib)
+	if err != nil {
+		return fmt.Errorf("error getting current MIG device layout: %v", err)
+	}
+
+	if migConfig != nil {
+		err = applyProposedLayouts(migConfig, unmanaged, layouts)
+		if err != nil {
+			return fmt.Errorf("error getting proposed MIG device layout: %v", err)
+		}
+	}
+
+	err = util.WriteToFile(f.OutputFile, func(w io.Writer) error {
+		if f.OutputFormat == SVGFormat {
+			return RenderSVG(w, layouts)
+		}
+		return RenderText(w, layouts)
+	})
+	if err != nil {
+		return fmt.Errorf("error writing output file: %v", err)
+	}
+	return nil
+}
+
+func CheckFlags(f *Flags) error {
+	switch f.OutputFormat {
+	case TextFormat:
+	case SVGFormat:
+	default:
+		return fmt.Errorf("unrecognized 'output-format': %v", f.OutputFormat)
+	}
+	if f.ConfigFile == "" && f.SelectedConfig != "" {
+		return fmt.Errorf("'selected-config' requires 'config-file' to be set")
+	}
+	return nil
+}
+
+// GetCurrentLayouts returns the layout of the MIG devices currently present
+// on every GPU of the node.
+func GetCurrentLayouts(nvmlLib nvml.Interface) ([]GpuLayout, error) {
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %v", ret)
+	}
+
+	modeManager, err := util.NewMigModeManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG mode Manager: %v", err)
+	}
+
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return nil, fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	var layouts []GpuLayout
+	for i := 0; i < count; i++ {
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for GPU %d: %v", i, ret)
+		}
+
+		name, ret := device.GetName()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device name for GPU %d: %v", i, ret)
+		}
+
+		layout := GpuLayout{
+			Index: i,
+			Name:  name,
+		}
+
+		capable, err := modeManager.IsMigCapable(i)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable for GPU %d: %v", i, err)
+		}
+		if !capable {
+			layouts = append(layouts, layout)
+			continue
+		}
+
+		m, err := modeManager.GetMigMode(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG mode for GPU %d: %v", i, err)
+		}
+		if m != mode.Enabled {
+			layouts = append(layouts, layout)
+			continue
+		}
+
+		layout.MigEnabled = true
+		layout.NumSlices, err = getNumMemorySlices(device)
+		if err != nil {
+			return nil, fmt.Errorf("error getting number of memory slices for GPU %d: %v", i, err)
+		}
+
+		layout.Devices, err = configManager.GetMigDevices(i)
+		if err != nil {
+			return nil, fmt.Errorf("error getting MIG devices for GPU %d: %v", i, err)
+		}
+
+		layouts = append(layouts, layout)
+	}
+
+	return layouts, nil
+}
+
+// applyProposedLayouts replaces the MIG devices in 'layouts' with the ones
+// that would result from applying 'migConfig'. GPUs selected by 'unmanaged'
+// keep their current layout.
+func applyProposedLayouts(migConfig v1.MigConfigSpecSlice, unmanaged v1.UnmanagedDeviceSpecSlice, layouts []GpuLayout) error {
+	configManager, err := util.NewMigConfigManager()
+	if err != nil {
+		return fmt.Errorf("error creating MIG config Manager: %v", err)
+	}
+
+	return assert.WalkSelectedMigConfigForEachGPU(migConfig, unmanaged, func(mc *v1.MigConfigSpec, i int, d types.DeviceID) error {
+		if i >= len(layouts) {
+			return fmt.Errorf("no layout found for GPU %d", i)
+		}
+
+		if !mc.MigEnabled {
+			layouts[i].MigEnabled = false
+			layouts[i].Devices = nil
+			return nil
+		}
+
+		if !layouts[i].MigEnabled {
+			return fmt.Errorf("MIG mode must be enabled on GPU %d to render a proposed config", i)
+		}
+
+		desired := mc.MigDevices
+		if mc.Fill != "" {
+			desired, err = configManager.FillMigConfig(i, mc.MigDevices, mc.Fill)
+			if err != nil {
+				return fmt.Errorf("error filling MIGConfig with '%v': %v", mc.Fill, err)
+			}
+		}
+
+		layouts[i].Devices, err = configManager.PlanMigConfig(i, desired, config.WithPlacementPreference(mc.Placement), config.WithPlacementExclusions(mc.PlacementExclusions))
+		if err != nil {
+			return fmt.Errorf("error planning MIGConfig: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// getNumMemorySlices returns the number of memory slices available on
+// 'device' as the furthest extent of any possible GPU instance placement.
+func getNumMemorySlices(device nvml.Device) (int, error) {
+	numSlices := 0
+	for giProfileID := 0; giProfileID < nvml.GPU_INSTANCE_PROFILE_COUNT; giProfileID++ {
+		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("error getting GPU instance profile info for '%v': %v", giProfileID, ret)
+		}
+
+		placements, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return 0, fmt.Errorf("error getting GPU instance possible placements for '%v': %v", giProfileID, ret)
+		}
+
+		for _, p := range placements {
+			if int(p.Start+p.Size) > numSlices {
+				numSlices = int(p.Start + p.Size)
+			}
+		}
+	}
+	return numSlices, nil
+}
@@ -133,6 +133,11 @@ if [ "${CDI_ENABLED}" = "true" ]; then
 	fi
 fi
 
+# Let nvidia-mig-parted find the NVML library of a containerized driver
+if [ "${DRIVER_ROOT_CTR_PATH}" != "" ]; then
+	export MIG_PARTED_DRIVER_ROOT="${DRIVER_ROOT_CTR_PATH}"
+fi
+
 HOST_GPU_CLIENT_SERVICES=(${HOST_GPU_CLIENT_SERVICES//,/ })
 HOST_GPU_CLIENT_SERVICES_STOPPED=()
 
@@ -0,0 +1,28 @@
+#!/usr/bin/env bash
+
+# Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+CURRDIR="$(cd "$( dirname $(readlink -f "${BASH_SOURCE[0]}"))" >/dev/null 2>&1 && pwd)"
+
+source ${CURRDIR}/utils.sh
+
+: "${MIG_PARTED_CONFIG_FILE:=${CURRDIR}/config.yaml}"
+: "${MIG_PARTED_SELECTED_CONFIG:=$(nvidia-mig-manager::service::get_selected_config)}"
+: "${MIG_PARTED_SELECTED_CONFIG:?Environment variable must be set before calling this script}"
+
+export MIG_PARTED_CONFIG_FILE
+export MIG_PARTED_SELECTED_CONFIG
+
+exec nvidia-mig-parted health
@@ -18,6 +18,7 @@
 
 SERVICE_ROOT="nvidia-mig-manager"
 SERVICE_NAME="${SERVICE_ROOT}.service"
+HEALTH_SERVICE_NAME="${SERVICE_ROOT}-health.service"
 
 MIG_PARTED_NAME="nvidia-mig-parted"
 MIG_PARTED_GO_GET_PATH="github.com/NVIDIA/mig-parted/cmd/${MIG_PARTED_NAME}"
@@ -52,9 +53,11 @@ ${DOCKER} run --rm \
 	"
 
 cp ${SERVICE_NAME}       ${SYSTEMD_DIR}
+cp ${HEALTH_SERVICE_NAME} ${SYSTEMD_DIR}
 cp ${MIG_PARTED_NAME}.sh ${PROFILED_DIR}
 cp override.conf         ${OVERRIDE_DIR}
 cp service.sh            ${CONFIG_DIR}
+cp health.sh             ${CONFIG_DIR}
 cp utils.sh              ${CONFIG_DIR}
 cp hooks.sh              ${CONFIG_DIR}
 cp hooks-default.yaml    ${CONFIG_DIR}
@@ -62,9 +65,11 @@ cp hooks-minimal.yaml    ${CONFIG_DIR}
 cp config-default.yaml    ${CONFIG_DIR}
 
 chmod a+r ${SYSTEMD_DIR}/${SERVICE_NAME}
+chmod a+r ${SYSTEMD_DIR}/${HEALTH_SERVICE_NAME}
 chmod a+r ${PROFILED_DIR}/${MIG_PARTED_NAME}.sh
 chmod a+r ${OVERRIDE_DIR}/override.conf
 chmod a+r ${CONFIG_DIR}/service.sh
+chmod a+r ${CONFIG_DIR}/health.sh
 chmod a+r ${CONFIG_DIR}/utils.sh
 chmod a+r ${CONFIG_DIR}/hooks.sh
 chmod a+r ${CONFIG_DIR}/hooks-default.yaml
@@ -72,9 +77,11 @@ chmod a+r ${CONFIG_DIR}/hooks-minimal.yaml
 chmod a+r ${CONFIG_DIR}/config-default.yaml
 
 chmod ug+x ${CONFIG_DIR}/service.sh
+chmod ug+x ${CONFIG_DIR}/health.sh
 
 systemctl daemon-reload
 systemctl enable ${SERVICE_NAME}
+systemctl enable ${HEALTH_SERVICE_NAME}
 
 function maybe_add_hooks_symlink() {
   if [ -e ${CONFIG_DIR}/hooks.yaml ]; then
@@ -16,6 +16,7 @@
 
 SERVICE_ROOT="nvidia-mig-manager"
 SERVICE_NAME="${SERVICE_ROOT}.service"
+HEALTH_SERVICE_NAME="${SERVICE_ROOT}-health.service"
 
 MIG_PARTED_NAME="nvidia-mig-parted"
 MIG_PARTED_GO_GET_PATH="github.com/NVIDIA/mig-parted/cmd/${MIG_PARTED_NAME}"
@@ -27,6 +28,7 @@ CONFIG_DIR="/etc/${SERVICE_ROOT}"
 OVERRIDE_DIR="/etc/systemd/system/${SERVICE_NAME}.d"
 PROFILED_DIR="/etc/profile.d"
 
+systemctl disable --now ${HEALTH_SERVICE_NAME}
 systemctl disable ${SERVICE_NAME}
 systemctl daemon-reload
 
@@ -36,4 +38,5 @@ rm -rf ${OVERRIDE_DIR}
 
 rm ${BINARY_DIR}/${MIG_PARTED_NAME}
 rm ${SYSTEMD_DIR}/${SERVICE_NAME}
+rm ${SYSTEMD_DIR}/${HEALTH_SERVICE_NAME}
 rm ${PROFILED_DIR}/${MIG_PARTED_NAME}.sh
@@ -80,6 +80,14 @@ cat << EOF > /etc/systemd/system/nvidia-mig-manager.service.d/override.conf
 Environment="MIG_PARTED_SELECTED_CONFIG=${selected_config}"
 EOF
 	systemctl daemon-reload
+	systemctl try-restart nvidia-mig-manager-health.service
+}
+
+function nvidia-mig-manager::service::get_selected_config() {
+	systemctl show --property=Environment --value nvidia-mig-manager.service \
+		| tr ' ' '\n' \
+		| grep -e "^MIG_PARTED_SELECTED_CONFIG=" \
+		| cut -d'=' -f2-
 }
 
 function nvidia-mig-manager::service::start_systemd_services() {
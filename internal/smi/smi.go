@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package smi
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DefaultPath is the nvidia-smi binary run by New().
+const DefaultPath = "nvidia-smi"
+
+// Interface runs nvidia-smi with a set of arguments and returns its output.
+type Interface interface {
+	Run(args ...string) (string, error)
+}
+
+type smi struct {
+	path string
+}
+
+var _ Interface = (*smi)(nil)
+
+// New returns an Interface that runs the nvidia-smi found on the PATH.
+func New() Interface {
+	return &smi{DefaultPath}
+}
+
+// Run runs nvidia-smi with 'args' and returns its combined stdout and stderr.
+// A non-zero exit status is returned as an error that includes the output.
+func (s *smi) Run(args ...string) (string, error) {
+	cmd := exec.Command(s.path, args...) //nolint:gosec
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return string(output), fmt.Errorf("error running '%v %v': %v: %v", s.path, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// ParseTable parses the rows out of one of the boxed tables that nvidia-smi
+// prints (e.g. for 'nvidia-smi mig -lgi'). Only the rows after the '===='
+// header separator are returned, each split into its whitespace separated
+// fields.
+func ParseTable(output string) [][]string {
+	var rows [][]string
+	inBody := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "|=") {
+			inBody = true
+			continue
+		}
+		if !inBody || !strings.HasPrefix(line, "|") {
+			continue
+		}
+		fields := strings.Fields(strings.Trim(line, "|"))
+		if len(fields) == 0 {
+			continue
+		}
+		rows = append(rows, fields)
+	}
+	return rows
+}
+
+// ParseCSV parses the output of an nvidia-smi '--format=csv,noheader' query
+// into its rows of trimmed values.
+func ParseCSV(output string) [][]string {
+	var rows [][]string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		var row []string
+		for _, v := range strings.Split(line, ",") {
+			row = append(row, strings.TrimSpace(v))
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// IsNotFound checks whether 'output' is nvidia-smi reporting that there were
+// no instances to list or destroy.
+func IsNotFound(output string) bool {
+	return strings.Contains(output, "No GPU instances found") ||
+		strings.Contains(output, "No compute instances found") ||
+		strings.Contains(output, "Not Found")
+}
@@ -0,0 +1,50 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package smi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTable(t *testing.T) {
+	output := `+-------------------------------------------------------+
+| GPU instances:                                        |
+| GPU   Name             Profile  Instance   Placement  |
+|                          ID       ID       Start:Size |
+|=======================================================|
+|   0  MIG 3g.20gb          9        2          0:4     |
++-------------------------------------------------------+
+|   0  MIG 1g.5gb+me       20        7          4:1     |
++-------------------------------------------------------+
+`
+	expected := [][]string{
+		{"0", "MIG", "3g.20gb", "9", "2", "0:4"},
+		{"0", "MIG", "1g.5gb+me", "20", "7", "4:1"},
+	}
+	require.Equal(t, expected, ParseTable(output))
+}
+
+func TestParseCSV(t *testing.T) {
+	output := "Enabled, Disabled\n[N/A], [N/A]\n\n"
+	expected := [][]string{
+		{"Enabled", "Disabled"},
+		{"[N/A]", "[N/A]"},
+	}
+	require.Equal(t, expected, ParseCSV(output))
+}
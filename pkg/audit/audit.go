@@ -0,0 +1,208 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package audit records every invocation that changes (or tries to change)
+// how the GPUs are partitioned as a structured entry in the system journal,
+// falling back to syslog where journald is not running. Host security
+// auditing then captures who changed the partitioning and when, independently
+// of the logs of mig-parted itself.
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"log/syslog"
+	"net"
+	"os"
+	"os/user"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MessageID identifies the audit entries of mig-parted in the journal,
+	// e.g. 'journalctl MESSAGE_ID=7dbc525e94ee49aea89b543cff43d267'.
+	MessageID = "7dbc525e94ee49aea89b543cff43d267"
+	// Identifier is the syslog identifier of the audit entries.
+	Identifier = "nvidia-mig-parted"
+
+	// DefaultJournalSocket is where journald receives native entries.
+	DefaultJournalSocket = "/run/systemd/journal/socket"
+
+	// ResultSucceeded and ResultFailed are the results of an invocation
+	// that has no more specific outcome.
+	ResultSucceeded = "succeeded"
+	ResultFailed    = "failed"
+
+	priorityNotice = 5
+	priorityError  = 3
+)
+
+// Entry is the audit record of a single mutating invocation.
+//   - Command is the subcommand that was run, e.g. "apply" or "gi create".
+//   - Config names what was applied, e.g. the selected MIG config.
+//   - GPUs lists the indices of the GPUs that were changed.
+//   - Result is the outcome of the invocation, e.g. "succeeded" or "failed".
+//   - Error is why it did not succeed, if it did not.
+type Entry struct {
+	Command string
+	Config  string
+	GPUs    []int
+	Result  string
+	Error   string
+}
+
+// Fields returns the journal fields of 'e', including the user running the
+// invocation (and the user who ran it through sudo, if any).
+func (e *Entry) Fields() map[string]string {
+	fields := map[string]string{
+		"MESSAGE":                 e.message(),
+		"MESSAGE_ID":              MessageID,
+		"PRIORITY":                strconv.Itoa(priorityNotice),
+		"SYSLOG_IDENTIFIER":       Identifier,
+		"MIG_PARTED_COMMAND":      e.Command,
+		"MIG_PARTED_UID":          strconv.Itoa(os.Getuid()),
+		"MIG_PARTED_GPUS_CHANGED": joinInts(e.GPUs),
+		"MIG_PARTED_RESULT":       e.Result,
+	}
+	if e.Error != "" {
+		fields["PRIORITY"] = strconv.Itoa(priorityError)
+		fields["MIG_PARTED_ERROR"] = e.Error
+	}
+	if e.Config != "" {
+		fields["MIG_PARTED_CONFIG"] = e.Config
+	}
+	if u, err := user.Current(); err == nil {
+		fields["MIG_PARTED_USER"] = u.Username
+	}
+	if sudoUser := os.Getenv("SUDO_USER"); sudoUser != "" {
+		fields["MIG_PARTED_SUDO_USER"] = sudoUser
+	}
+	return fields
+}
+
+func (e *Entry) message() string {
+	gpus := "no GPUs"
+	if len(e.GPUs) > 0 {
+		gpus = "GPUs " + joinInts(e.GPUs)
+	}
+	config := ""
+	if e.Config != "" {
+		config = fmt.Sprintf(" '%v'", e.Config)
+	}
+	return fmt.Sprintf("nvidia-mig-parted %v%v %v, changing %v", e.Command, config, e.Result, gpus)
+}
+
+// Log sends 'e' to journald through the socket at DefaultJournalSocket, or to
+// syslog if that fails.
+func Log(e *Entry) error {
+	fields := e.Fields()
+	err := sendToJournal(DefaultJournalSocket, fields)
+	if err == nil {
+		return nil
+	}
+
+	serr := sendToSyslog(fields)
+	if serr != nil {
+		return fmt.Errorf("error sending audit entry to journald (%v) and syslog (%w)", err, serr)
+	}
+	return nil
+}
+
+// sendToJournal sends 'fields' as a single entry over the native journald
+// protocol to the socket at 'path'.
+func sendToJournal(path string, fields map[string]string) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write(encodeJournalEntry(fields))
+	return err
+}
+
+// encodeJournalEntry encodes 'fields' in the native journald protocol. Values
+// spanning several lines are length-prefixed rather than written as is.
+func encodeJournalEntry(fields map[string]string) []byte {
+	var buf bytes.Buffer
+	for _, key := range sortedKeys(fields) {
+		value := fields[key]
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&buf, "%s=%s\n", key, value)
+			continue
+		}
+		buf.WriteString(key)
+		buf.WriteByte('\n')
+		_ = binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
+
+// sendToSyslog sends 'fields' to the authpriv facility of syslog as the
+// message followed by the other fields as 'key=value' pairs.
+func sendToSyslog(fields map[string]string) error {
+	priority := syslog.LOG_NOTICE
+	if fields["PRIORITY"] == strconv.Itoa(priorityError) {
+		priority = syslog.LOG_ERR
+	}
+
+	w, err := syslog.New(priority|syslog.LOG_AUTHPRIV, Identifier)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	message := formatSyslogMessage(fields)
+	if priority == syslog.LOG_ERR {
+		return w.Err(message)
+	}
+	return w.Notice(message)
+}
+
+// formatSyslogMessage formats 'fields' as a single syslog message.
+func formatSyslogMessage(fields map[string]string) string {
+	parts := []string{fields["MESSAGE"]}
+	for _, key := range sortedKeys(fields) {
+		switch key {
+		case "MESSAGE", "PRIORITY", "SYSLOG_IDENTIFIER":
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s=%q", key, fields[key]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func sortedKeys(fields map[string]string) []string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, 0, len(values))
+	for _, v := range values {
+		strs = append(strs, strconv.Itoa(v))
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package audit
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFields(t *testing.T) {
+	entry := &Entry{
+		Command: "apply",
+		Config:  "all-1g.5gb",
+		GPUs:    []int{0, 2},
+		Result:  "failed",
+		Error:   "error setting MIG mode",
+	}
+
+	fields := entry.Fields()
+	require.Equal(t, MessageID, fields["MESSAGE_ID"])
+	require.Equal(t, "3", fields["PRIORITY"])
+	require.Equal(t, "apply", fields["MIG_PARTED_COMMAND"])
+	require.Equal(t, "all-1g.5gb", fields["MIG_PARTED_CONFIG"])
+	require.Equal(t, "0,2", fields["MIG_PARTED_GPUS_CHANGED"])
+	require.Equal(t, "failed", fields["MIG_PARTED_RESULT"])
+	require.Equal(t, "error setting MIG mode", fields["MIG_PARTED_ERROR"])
+	require.Equal(t, "nvidia-mig-parted apply 'all-1g.5gb' failed, changing GPUs 0,2", fields["MESSAGE"])
+	require.NotEmpty(t, fields["MIG_PARTED_UID"])
+
+	fields = (&Entry{Command: "gi destroy", Result: "succeeded"}).Fields()
+	require.Equal(t, "5", fields["PRIORITY"])
+	require.Equal(t, "nvidia-mig-parted gi destroy succeeded, changing no GPUs", fields["MESSAGE"])
+	require.NotContains(t, fields, "MIG_PARTED_ERROR")
+}
+
+func TestEncodeJournalEntry(t *testing.T) {
+	encoded := encodeJournalEntry(map[string]string{
+		"MESSAGE":          "hello",
+		"MIG_PARTED_ERROR": "a\nb",
+	})
+	expected := "MESSAGE=hello\nMIG_PARTED_ERROR\n\x03\x00\x00\x00\x00\x00\x00\x00a\nb\n"
+	require.Equal(t, expected, string(encoded))
+}
+
+func TestSendToJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	require.Nil(t, err)
+	defer conn.Close()
+
+	err = sendToJournal(path, map[string]string{"MESSAGE": "hello"})
+	require.Nil(t, err, "Unexpected failure from sendToJournal")
+
+	buf := make([]byte, 1024)
+	n, err := conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "MESSAGE=hello\n", string(buf[:n]))
+
+	err = sendToJournal(filepath.Join(t.TempDir(), "missing"), map[string]string{"MESSAGE": "hello"})
+	require.NotNil(t, err, "Unexpected success sending to a missing socket")
+}
+
+func TestFormatSyslogMessage(t *testing.T) {
+	message := formatSyslogMessage(map[string]string{
+		"MESSAGE":            "nvidia-mig-parted apply succeeded",
+		"PRIORITY":           "5",
+		"SYSLOG_IDENTIFIER":  Identifier,
+		"MIG_PARTED_COMMAND": "apply",
+		"MIG_PARTED_RESULT":  "succeeded",
+	})
+	require.Equal(t, `nvidia-mig-parted apply succeeded MIG_PARTED_COMMAND="apply" MIG_PARTED_RESULT="succeeded"`, message)
+}
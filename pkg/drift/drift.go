@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package drift reads and writes the state file in which 'assert' records
+// whether the node has drifted from the MIG config it asserts, for 'status'
+// and the metrics endpoint of 'daemon' to report.
+package drift
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultStateFile is the well-known location of the drift state file.
+const DefaultStateFile = "/var/lib/nvidia-mig-manager/drift.json"
+
+// Results of a drift check.
+const (
+	ResultNone     = "none"
+	ResultDetected = "detected"
+)
+
+// State is the content of the drift state file: the outcome of the last
+// drift check, and what it checked the node against.
+type State struct {
+	Result         string    `json:"result"`
+	Reason         string    `json:"reason,omitempty"`
+	ConfigFile     string    `json:"config-file"`
+	SelectedConfig string    `json:"selected-config,omitempty"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// Detected returns whether the drift check recorded in 's' found drift.
+func (s *State) Detected() bool {
+	return s.Result == ResultDetected
+}
+
+// Write atomically writes 'state' to 'path', creating its parent directory if needed.
+func Write(path string, state *State) error {
+	output, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling drift state: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating drift state directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(output, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing drift state: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing drift state: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads the state at 'path'. It returns nil if no drift check has been recorded.
+func Read(path string) (*State, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading drift state: %w", err)
+	}
+
+	var state State
+	err = json.Unmarshal(content, &state)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling drift state: %w", err)
+	}
+
+	return &state, nil
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package drift
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvidia-mig-manager", "drift.json")
+
+	state, err := Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Nil(t, state, "Unexpected state before Write")
+
+	expected := &State{
+		Result:         ResultDetected,
+		Reason:         "GPU 0: MIG mode Disabled, expected Enabled",
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-1g.10gb",
+		Timestamp:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	err = Write(path, expected)
+	require.Nil(t, err, "Unexpected failure from Write")
+
+	state, err = Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Equal(t, expected, state)
+	require.True(t, state.Detected())
+
+	expected.Result = ResultNone
+	expected.Reason = ""
+	err = Write(path, expected)
+	require.Nil(t, err, "Unexpected failure from Write")
+
+	state, err = Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.False(t, state.Detected())
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package fabric discovers the GPU partitions managed by NVIDIA Fabric Manager
+// on NVSwitch based systems running in shared NVSwitch virtualization mode, and
+// coordinates with Fabric Manager around operations (such as MIG mode changes)
+// that require the GPUs to be detached from the NVSwitch fabric.
+package fabric
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Defaults for locating Fabric Manager on the host.
+const (
+	DefaultFmpmPath    = "fmpm"
+	DefaultServiceName = "nvidia-fabricmanager"
+)
+
+// GPU describes a GPU that is a member of a fabric partition.
+type GPU struct {
+	PhysicalID int    `json:"physicalId"`
+	UUID       string `json:"uuid,omitempty"`
+	PCIBusID   string `json:"pciBusId"`
+}
+
+// Partition describes a fabric partition, i.e. a set of GPUs that share the
+// NVSwitch fabric and are activated and deactivated together.
+type Partition struct {
+	ID     int
+	Active bool
+	GPUs   []GPU
+}
+
+// Interface is the set of operations on fabric partitions needed by mig-parted.
+type Interface interface {
+	Partitions() ([]Partition, error)
+	Activate(id int) error
+	Deactivate(id int) error
+}
+
+type fmpm struct {
+	path string
+	run  func(name string, args ...string) ([]byte, error)
+}
+
+var _ Interface = (*fmpm)(nil)
+
+// NewFmpm returns an 'Interface' backed by the Fabric Manager partition
+// manager CLI ('fmpm') at 'path'.
+func NewFmpm(path string) Interface {
+	return &fmpm{
+		path: path,
+		run:  run,
+	}
+}
+
+func run(name string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command(name, args...) //nolint:gosec
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%w: %v", err, msg)
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// Partitions lists all fabric partitions known to Fabric Manager.
+func (f *fmpm) Partitions() ([]Partition, error) {
+	output, err := f.run(f.path, "-l")
+	if err != nil {
+		return nil, fmt.Errorf("error listing fabric partitions: %w", err)
+	}
+	return ParsePartitions(output)
+}
+
+// Activate activates the fabric partition with the given ID.
+func (f *fmpm) Activate(id int) error {
+	_, err := f.run(f.path, "-a", strconv.Itoa(id))
+	if err != nil {
+		return fmt.Errorf("error activating fabric partition %v: %w", id, err)
+	}
+	return nil
+}
+
+// Deactivate deactivates the fabric partition with the given ID.
+func (f *fmpm) Deactivate(id int) error {
+	_, err := f.run(f.path, "-d", strconv.Itoa(id))
+	if err != nil {
+		return fmt.Errorf("error deactivating fabric partition %v: %w", id, err)
+	}
+	return nil
+}
+
+// ParsePartitions parses the JSON partition list printed by 'fmpm -l'.
+func ParsePartitions(data []byte) ([]Partition, error) {
+	var list struct {
+		PartitionInfo []struct {
+			ID       int   `json:"partitionId"`
+			IsActive int   `json:"isActive"`
+			GPUs     []GPU `json:"gpuInfo"`
+		} `json:"partitionInfo"`
+	}
+	err := json.Unmarshal(data, &list)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fabric partition list: %w", err)
+	}
+
+	var partitions []Partition
+	for _, p := range list.PartitionInfo {
+		partitions = append(partitions, Partition{
+			ID:     p.ID,
+			Active: p.IsActive != 0,
+			GPUs:   p.GPUs,
+		})
+	}
+	return partitions, nil
+}
+
+// GetPartition returns the fabric partition with the given ID.
+func GetPartition(f Interface, id int) (*Partition, error) {
+	partitions, err := f.Partitions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range partitions {
+		if partitions[i].ID == id {
+			return &partitions[i], nil
+		}
+	}
+	return nil, fmt.Errorf("fabric partition %v not found", id)
+}
+
+// Contains checks if the GPU at 'pciBusID' is a member of the partition.
+func (p *Partition) Contains(pciBusID string) bool {
+	for _, gpu := range p.GPUs {
+		if NormalizePCIBusID(gpu.PCIBusID) == NormalizePCIBusID(pciBusID) {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizePCIBusID converts a PCI bus ID into the canonical
+// 'dddd:bb:dd.f' form, so that the 8 digit domains reported by Fabric Manager
+// and NVML compare equal to the 4 digit domains used in sysfs.
+func NormalizePCIBusID(id string) string {
+	id = strings.ToLower(strings.TrimSpace(id))
+	domain, rest, found := strings.Cut(id, ":")
+	if !found || strings.Count(rest, ":") != 1 {
+		return id
+	}
+	d, err := strconv.ParseUint(domain, 16, 32)
+	if err != nil {
+		return id
+	}
+	return fmt.Sprintf("%04x:%s", d, rest)
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fabric
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const partitionList = `{
+  "partitionInfo": [
+    {
+      "partitionId": 0,
+      "isActive": 1,
+      "numGpus": 2,
+      "gpuInfo": [
+        {"physicalId": 1, "uuid": "GPU-a", "pciBusId": "00000000:07:00.0", "numEnabledNvLinks": 18},
+        {"physicalId": 2, "uuid": "GPU-b", "pciBusId": "00000000:0F:00.0", "numEnabledNvLinks": 18}
+      ]
+    },
+    {
+      "partitionId": 1,
+      "isActive": 0,
+      "numGpus": 1,
+      "gpuInfo": [
+        {"physicalId": 3, "uuid": "GPU-c", "pciBusId": "00000000:47:00.0", "numEnabledNvLinks": 18}
+      ]
+    }
+  ],
+  "numPartitions": 2,
+  "version": 1
+}`
+
+func TestParsePartitions(t *testing.T) {
+	partitions, err := ParsePartitions([]byte(partitionList))
+	require.Nil(t, err)
+	require.Equal(t, []Partition{
+		{
+			ID:     0,
+			Active: true,
+			GPUs: []GPU{
+				{PhysicalID: 1, UUID: "GPU-a", PCIBusID: "00000000:07:00.0"},
+				{PhysicalID: 2, UUID: "GPU-b", PCIBusID: "00000000:0F:00.0"},
+			},
+		},
+		{
+			ID:     1,
+			Active: false,
+			GPUs: []GPU{
+				{PhysicalID: 3, UUID: "GPU-c", PCIBusID: "00000000:47:00.0"},
+			},
+		},
+	}, partitions)
+
+	_, err = ParsePartitions([]byte("not json"))
+	require.NotNil(t, err)
+}
+
+func TestPartitionContains(t *testing.T) {
+	partitions, err := ParsePartitions([]byte(partitionList))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		pciBusID string
+		expected bool
+	}{
+		{"00000000:07:00.0", true},
+		{"0000:07:00.0", true},
+		{"0000:0f:00.0", true},
+		{"0000:47:00.0", false},
+		{"0001:07:00.0", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pciBusID, func(t *testing.T) {
+			require.Equal(t, tc.expected, partitions[0].Contains(tc.pciBusID))
+		})
+	}
+}
+
+func TestFmpm(t *testing.T) {
+	var calls []string
+	var failures map[string]error
+	f := &fmpm{
+		path: "/usr/bin/fmpm",
+		run: func(name string, args ...string) ([]byte, error) {
+			call := strings.Join(append([]string{name}, args...), " ")
+			calls = append(calls, call)
+			if err := failures[call]; err != nil {
+				return nil, err
+			}
+			return []byte(partitionList), nil
+		},
+	}
+
+	partition, err := GetPartition(f, 1)
+	require.Nil(t, err)
+	require.Equal(t, 1, partition.ID)
+
+	_, err = GetPartition(f, 7)
+	require.ErrorContains(t, err, "fabric partition 7 not found")
+
+	require.Nil(t, f.Deactivate(1))
+	require.Nil(t, f.Activate(1))
+
+	failures = map[string]error{"/usr/bin/fmpm -a 0": fmt.Errorf("exit status 1")}
+	require.ErrorContains(t, f.Activate(0), "error activating fabric partition 0")
+
+	require.Equal(t, []string{
+		"/usr/bin/fmpm -l",
+		"/usr/bin/fmpm -l",
+		"/usr/bin/fmpm -d 1",
+		"/usr/bin/fmpm -a 1",
+		"/usr/bin/fmpm -a 0",
+	}, calls)
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package fabric
+
+import (
+	"fmt"
+)
+
+// Service controls the Fabric Manager systemd service.
+type Service struct {
+	Name string
+	run  func(name string, args ...string) ([]byte, error)
+}
+
+// NewService returns a 'Service' for the systemd unit called 'name'.
+func NewService(name string) *Service {
+	return &Service{
+		Name: name,
+		run:  run,
+	}
+}
+
+// IsActive checks if the service is currently running.
+func (s *Service) IsActive() bool {
+	_, err := s.run("systemctl", "-q", "is-active", s.Name)
+	return err == nil
+}
+
+// Stop stops the service.
+func (s *Service) Stop() error {
+	_, err := s.run("systemctl", "stop", s.Name)
+	if err != nil {
+		return fmt.Errorf("error stopping %v: %w", s.Name, err)
+	}
+	return nil
+}
+
+// Start starts the service.
+func (s *Service) Start() error {
+	_, err := s.run("systemctl", "start", s.Name)
+	if err != nil {
+		return fmt.Errorf("error starting %v: %w", s.Name, err)
+	}
+	return nil
+}
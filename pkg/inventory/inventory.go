@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package inventory collects the MIG config of many GPUs at once. Walking the
+// GPU and compute instances of a GPU takes an NVML call per instance, so
+// scanning a fully partitioned node one GPU after the other is slow. Each GPU
+// is locked separately by a config.Manager, so their scans are run in
+// parallel instead, by a bounded pool of workers.
+package inventory
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// DefaultWorkers is the number of GPUs scanned at a time unless told otherwise.
+// It matches the number of GPUs on the largest nodes.
+const DefaultWorkers = 8
+
+// Inventory holds the MIG config collected for each GPU, or the error
+// collecting it returned.
+type Inventory struct {
+	gpus map[int]result
+}
+
+type result struct {
+	migConfig types.MigConfig
+	err       error
+}
+
+// Collect queries the MIG config of each GPU in 'gpus' through 'manager',
+// scanning up to 'workers' GPUs at a time (DefaultWorkers if 'workers' is
+// not positive). An error querying one GPU does not stop the others from
+// being scanned; it is returned by MigConfig for that GPU instead.
+func Collect(manager config.Manager, gpus []int, workers int) *Inventory {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	results := make([]result, len(gpus))
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(workers, len(gpus)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				migConfig, err := manager.GetMigConfig(gpus[i])
+				results[i] = result{migConfig, err}
+			}
+		}()
+	}
+	for i := range gpus {
+		indices <- i
+	}
+	close(indices)
+	wg.Wait()
+
+	inventory := &Inventory{gpus: make(map[int]result, len(gpus))}
+	for i, gpu := range gpus {
+		inventory.gpus[gpu] = results[i]
+	}
+	return inventory
+}
+
+// MigConfig returns the MIG config collected for 'gpu'.
+func (i *Inventory) MigConfig(gpu int) (types.MigConfig, error) {
+	r, exists := i.gpus[gpu]
+	if !exists {
+		return nil, fmt.Errorf("GPU %v not in inventory", gpu)
+	}
+	return r.migConfig, r.err
+}
@@ -0,0 +1,121 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package inventory
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// fakeManager is a config.Manager whose GPUs each hold 'instances' MIG
+// devices, every one of which takes 'latency' to walk, like an NVML call.
+type fakeManager struct {
+	config.Manager
+	instances int
+	latency   time.Duration
+	failing   map[int]bool
+
+	sync.Mutex
+	active    int
+	maxActive int
+}
+
+func (m *fakeManager) GetMigConfig(gpu int) (types.MigConfig, error) {
+	m.Lock()
+	m.active++
+	m.maxActive = max(m.maxActive, m.active)
+	m.Unlock()
+	defer func() {
+		m.Lock()
+		m.active--
+		m.Unlock()
+	}()
+
+	if m.failing[gpu] {
+		return nil, fmt.Errorf("error asserting MIG enabled")
+	}
+	for i := 0; i < m.instances; i++ {
+		time.Sleep(m.latency)
+	}
+	return types.MigConfig{"1g.10gb": m.instances}, nil
+}
+
+func TestCollect(t *testing.T) {
+	testCases := []struct {
+		description string
+		gpus        []int
+		workers     int
+	}{
+		{"No GPUs", nil, 0},
+		{"One worker", []int{0, 1, 2, 3}, 1},
+		{"Fewer workers than GPUs", []int{0, 1, 2, 3, 4, 5, 6, 7}, 3},
+		{"More workers than GPUs", []int{1, 3}, 8},
+		{"Default workers", []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := &fakeManager{
+				instances: 7,
+				latency:   time.Millisecond,
+				failing:   map[int]bool{2: true},
+			}
+
+			inventory := Collect(manager, tc.gpus, tc.workers)
+
+			workers := tc.workers
+			if workers <= 0 {
+				workers = DefaultWorkers
+			}
+			require.LessOrEqual(t, manager.maxActive, workers, "Too many GPUs scanned at a time")
+
+			for _, gpu := range tc.gpus {
+				migConfig, err := inventory.MigConfig(gpu)
+				if gpu == 2 {
+					require.NotNil(t, err, "Unexpected success for failing GPU")
+					continue
+				}
+				require.Nil(t, err, "Unexpected failure for GPU %v", gpu)
+				require.Equal(t, types.MigConfig{"1g.10gb": 7}, migConfig)
+			}
+
+			_, err := inventory.MigConfig(100)
+			require.NotNil(t, err, "Unexpected success for GPU not scanned")
+		})
+	}
+}
+
+// BenchmarkCollect scans a node of 8 GPUs with 7 MIG devices each (56 in
+// total), one GPU at a time and in parallel.
+func BenchmarkCollect(b *testing.B) {
+	gpus := []int{0, 1, 2, 3, 4, 5, 6, 7}
+	for _, workers := range []int{1, 2, 4, DefaultWorkers} {
+		b.Run(fmt.Sprintf("workers=%v", workers), func(b *testing.B) {
+			manager := &fakeManager{instances: 7, latency: 100 * time.Microsecond}
+			for i := 0; i < b.N; i++ {
+				Collect(manager, gpus, workers)
+			}
+		})
+	}
+}
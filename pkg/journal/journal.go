@@ -0,0 +1,136 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package journal records the outcome of each MIG config apply in an
+// append-only file, so that the last applied config can be reported later.
+package journal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultFile is the well-known location of the apply journal.
+const DefaultFile = "/var/lib/nvidia-mig-manager/apply-journal.jsonl"
+
+// Outcomes of an apply.
+const (
+	OutcomeSucceeded = "succeeded"
+	OutcomeFailed    = "failed"
+	OutcomeDenied    = "denied"
+	OutcomeCanceled  = "canceled"
+)
+
+// Entry records a single apply.
+type Entry struct {
+	Timestamp        time.Time `json:"timestamp"`
+	ConfigFile       string    `json:"config-file,omitempty"`
+	CIConfigFile     string    `json:"ci-config-file,omitempty"`
+	PlanFile         string    `json:"plan-file,omitempty"`
+	SelectedConfig   string    `json:"selected-config,omitempty"`
+	CISelectedConfig string    `json:"ci-selected-config,omitempty"`
+	ModeOnly         bool      `json:"mode-only,omitempty"`
+	Trigger          string    `json:"trigger,omitempty"`
+	Outcome          string    `json:"outcome"`
+	Error            string    `json:"error,omitempty"`
+	DurationMS       int64     `json:"duration-ms"`
+}
+
+// Succeeded returns whether the apply recorded in 'e' succeeded.
+func (e *Entry) Succeeded() bool {
+	return e.Outcome == OutcomeSucceeded
+}
+
+// Append appends 'entry' to the journal at 'path', creating the journal and
+// its parent directory if needed.
+func Append(path string, entry *Entry) error {
+	output, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("error marshaling journal entry: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating journal directory: %w", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening journal: %w", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(output, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing journal entry: %w", err)
+	}
+
+	return nil
+}
+
+// Read returns all entries in the journal at 'path', oldest first. It
+// returns no entries if the journal does not exist. Lines that cannot be
+// parsed (e.g. a line truncated by a crash mid-write) are skipped.
+func Read(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening journal: %w", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading journal: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Last returns the most recent entry in 'entries', or nil if there is none.
+func Last(entries []Entry) *Entry {
+	if len(entries) == 0 {
+		return nil
+	}
+	return &entries[len(entries)-1]
+}
+
+// LastSucceeded returns the most recent entry in 'entries' whose apply
+// succeeded, or nil if there is none.
+func LastSucceeded(entries []Entry) *Entry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if entries[i].Succeeded() {
+			return &entries[i]
+		}
+	}
+	return nil
+}
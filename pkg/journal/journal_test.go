@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package journal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvidia-mig-manager", "apply-journal.jsonl")
+
+	entries, err := Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Empty(t, entries, "Unexpected entries before Append")
+	require.Nil(t, Last(entries))
+	require.Nil(t, LastSucceeded(entries))
+
+	succeeded := &Entry{
+		Timestamp:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-1g.5gb",
+		Outcome:        OutcomeSucceeded,
+		DurationMS:     1200,
+	}
+	failed := &Entry{
+		Timestamp:      time.Date(2024, 1, 3, 3, 4, 5, 0, time.UTC),
+		ConfigFile:     "/etc/nvidia-mig-manager/config.yaml",
+		SelectedConfig: "all-7g.40gb",
+		Outcome:        OutcomeFailed,
+		Error:          "error applying MIG configuration",
+		DurationMS:     300,
+	}
+
+	require.Nil(t, Append(path, succeeded), "Unexpected failure from Append")
+	require.Nil(t, Append(path, failed), "Unexpected failure from Append")
+
+	entries, err = Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Equal(t, []Entry{*succeeded, *failed}, entries)
+	require.Equal(t, failed, Last(entries))
+	require.Equal(t, succeeded, LastSucceeded(entries))
+}
+
+func TestReadSkipsTruncatedEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "apply-journal.jsonl")
+	content := `{"timestamp":"2024-01-02T03:04:05Z","selected-config":"all-disabled","outcome":"succeeded","duration-ms":10}
+{"timestamp":"2024-01-03T03:04:05Z","selected-con`
+	err := os.WriteFile(path, []byte(content), 0644)
+	require.Nil(t, err)
+
+	entries, err := Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Len(t, entries, 1)
+	require.Equal(t, "all-disabled", entries[0].SelectedConfig)
+}
@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package labels keeps the labels of the 'mig-configs' entry last applied to
+// each GPU (e.g. "team=vision" or "tier=burst"). Labels only record the
+// intent of the operator: they are never written to the GPUs themselves, but
+// are kept in the store on the node so that they can be reported alongside
+// the GPU instances they were applied with.
+package labels
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+// Entry records the labels applied to a GPU, along with the selected config
+// they were applied from.
+type Entry struct {
+	GPU            int               `json:"gpu"`
+	SelectedConfig string            `json:"selected-config,omitempty"`
+	Labels         map[string]string `json:"labels"`
+}
+
+// Registry keeps the labels applied to each GPU on the node in a store.Store.
+type Registry struct {
+	store store.Store
+}
+
+// NewRegistry returns a Registry keeping its labels in 's'.
+func NewRegistry(s store.Store) *Registry {
+	return &Registry{store: s}
+}
+
+// Set records 'labels' as applied to 'gpu' from 'selectedConfig', replacing
+// any labels applied to it before. Setting no labels removes them.
+func (r *Registry) Set(gpu int, selectedConfig string, labels map[string]string) error {
+	if len(labels) == 0 {
+		err := r.store.Delete(store.BucketLabels, strconv.Itoa(gpu))
+		if err != nil {
+			return fmt.Errorf("error removing labels of GPU %v: %w", gpu, err)
+		}
+		return nil
+	}
+
+	value, err := json.Marshal(&Entry{GPU: gpu, SelectedConfig: selectedConfig, Labels: labels})
+	if err != nil {
+		return fmt.Errorf("error marshaling labels: %w", err)
+	}
+	err = r.store.Put(store.BucketLabels, strconv.Itoa(gpu), value)
+	if err != nil {
+		return fmt.Errorf("error storing labels of GPU %v: %w", gpu, err)
+	}
+	return nil
+}
+
+// Get returns the labels applied to 'gpu', or store.ErrNotFound if it has none.
+func (r *Registry) Get(gpu int) (*Entry, error) {
+	value, err := r.store.Get(store.BucketLabels, strconv.Itoa(gpu))
+	if err != nil {
+		return nil, err
+	}
+	var entry Entry
+	err = json.Unmarshal(value, &entry)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing labels of GPU %v: %w", gpu, err)
+	}
+	return &entry, nil
+}
+
+// List returns the labels applied to every GPU that has any, keyed by GPU index.
+func (r *Registry) List() (map[int]*Entry, error) {
+	keys, err := r.store.Keys(store.BucketLabels)
+	if err != nil {
+		return nil, fmt.Errorf("error listing labels: %w", err)
+	}
+	entries := make(map[int]*Entry)
+	for _, k := range keys {
+		gpu, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid labels key '%v': %w", k, err)
+		}
+		entry, err := r.Get(gpu)
+		if err != nil {
+			return nil, err
+		}
+		entries[gpu] = entry
+	}
+	return entries, nil
+}
+
+// Load returns the labels applied to every GPU, as recorded in the store at
+// 'storeFile'. Commands that only report labels use it so as not to create
+// the store where none exists: no labels are returned if it does not.
+func Load(storeFile string) (map[int]*Entry, error) {
+	_, err := os.Stat(storeFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[int]*Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking for store: %w", err)
+	}
+
+	s, err := store.Open(storeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return NewRegistry(s).List()
+}
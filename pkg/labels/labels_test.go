@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package labels
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry(store.NewMemoryStore())
+
+	_, err := registry.Get(0)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	require.Nil(t, registry.Set(0, "all-balanced", map[string]string{"team": "vision"}))
+	require.Nil(t, registry.Set(1, "all-balanced", map[string]string{"tier": "burst"}))
+	require.Nil(t, registry.Set(2, "all-balanced", nil))
+
+	entry, err := registry.Get(0)
+	require.Nil(t, err)
+	require.Equal(t, &Entry{GPU: 0, SelectedConfig: "all-balanced", Labels: map[string]string{"team": "vision"}}, entry)
+
+	require.Nil(t, registry.Set(0, "all-1g.5gb", map[string]string{"tier": "burst"}))
+	require.Nil(t, registry.Set(1, "all-1g.5gb", nil))
+
+	entries, err := registry.List()
+	require.Nil(t, err)
+	require.Equal(t, map[int]*Entry{
+		0: {GPU: 0, SelectedConfig: "all-1g.5gb", Labels: map[string]string{"tier": "burst"}},
+	}, entries)
+}
+
+func TestLoad(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "state.db")
+
+	entries, err := Load(storeFile)
+	require.Nil(t, err)
+	require.Empty(t, entries)
+	require.NoFileExists(t, storeFile, "Unexpected store created by Load")
+
+	s, err := store.Open(storeFile)
+	require.Nil(t, err)
+	require.Nil(t, NewRegistry(s).Set(3, "all-balanced", map[string]string{"team": "vision"}))
+	require.Nil(t, s.Close())
+
+	entries, err = Load(storeFile)
+	require.Nil(t, err)
+	require.Equal(t, map[string]string{"team": "vision"}, entries[3].Labels)
+}
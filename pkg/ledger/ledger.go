@@ -0,0 +1,179 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package ledger keeps the allocation ledger of the node: the existing MIG
+// devices that external schedulers (e.g. HPC schedulers placing jobs
+// themselves) have reserved by UUID. Reconfiguring the node never destroys a
+// MIG device while it is reserved.
+package ledger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+// ErrReserved is returned when a MIG device is reserved by another holder.
+var ErrReserved = errors.New("reserved")
+
+// Reservation records that 'Holder' has reserved the MIG device 'UUID' on
+// 'GPU', along with arbitrary 'Metadata' (e.g. the ID of a job) and, unless
+// it is held until released, when it expires.
+type Reservation struct {
+	UUID     string            `json:"uuid"`
+	GPU      int               `json:"gpu"`
+	Holder   string            `json:"holder"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Reserved time.Time         `json:"reserved"`
+	Expires  *time.Time        `json:"expires,omitempty"`
+}
+
+// Expired returns whether 'r' has expired at 'now'.
+func (r *Reservation) Expired(now time.Time) bool {
+	return r.Expires != nil && !now.Before(*r.Expires)
+}
+
+// Ledger keeps the reservations of the MIG devices on the node in a
+// store.Store.
+type Ledger struct {
+	store store.Store
+	now   func() time.Time
+}
+
+// NewLedger returns a Ledger keeping its reservations in 's'.
+func NewLedger(s store.Store) *Ledger {
+	return &Ledger{store: s, now: time.Now}
+}
+
+// Reserve reserves the MIG device 'uuid' on 'gpu' for 'holder' with
+// 'metadata', for 'ttl' (or until released if 0). Reserving a MIG device
+// already reserved by 'holder' renews its reservation. It returns
+// ErrReserved if the MIG device is reserved by another holder.
+func (l *Ledger) Reserve(uuid string, gpu int, holder string, ttl time.Duration, metadata map[string]string) (*Reservation, error) {
+	if uuid == "" || holder == "" {
+		return nil, fmt.Errorf("a reservation requires a MIG device UUID and a holder")
+	}
+
+	now := l.now()
+	current, err := l.Get(uuid)
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		return nil, err
+	}
+	if current != nil && current.Holder != holder && !current.Expired(now) {
+		return nil, fmt.Errorf("MIG device %v is %w by '%v'", uuid, ErrReserved, current.Holder)
+	}
+
+	r := &Reservation{
+		UUID:     uuid,
+		GPU:      gpu,
+		Holder:   holder,
+		Metadata: metadata,
+		Reserved: now.UTC(),
+	}
+	if ttl > 0 {
+		expires := now.Add(ttl).UTC()
+		r.Expires = &expires
+	}
+
+	value, err := json.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling reservation: %w", err)
+	}
+	err = l.store.Put(store.BucketReservations, uuid, value)
+	if err != nil {
+		return nil, fmt.Errorf("error storing reservation: %w", err)
+	}
+	return r, nil
+}
+
+// Release drops the reservation of the MIG device 'uuid' held by 'holder'.
+// It returns store.ErrNotFound if the MIG device is not reserved, and
+// ErrReserved if it is reserved by another holder.
+func (l *Ledger) Release(uuid string, holder string) error {
+	r, err := l.Get(uuid)
+	if err != nil {
+		return err
+	}
+	if r.Holder != holder && !r.Expired(l.now()) {
+		return fmt.Errorf("MIG device %v is %w by '%v'", uuid, ErrReserved, r.Holder)
+	}
+	err = l.store.Delete(store.BucketReservations, uuid)
+	if err != nil {
+		return fmt.Errorf("error removing reservation: %w", err)
+	}
+	return nil
+}
+
+// Get returns the reservation of the MIG device 'uuid', or store.ErrNotFound
+// if it is not reserved.
+func (l *Ledger) Get(uuid string) (*Reservation, error) {
+	value, err := l.store.Get(store.BucketReservations, uuid)
+	if err != nil {
+		return nil, err
+	}
+	var r Reservation
+	err = json.Unmarshal(value, &r)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing reservation of MIG device %v: %w", uuid, err)
+	}
+	return &r, nil
+}
+
+// List returns the reservations that have not expired, ordered by MIG
+// device UUID.
+func (l *Ledger) List() ([]Reservation, error) {
+	keys, err := l.store.Keys(store.BucketReservations)
+	if err != nil {
+		return nil, fmt.Errorf("error listing reservations: %w", err)
+	}
+	now := l.now()
+	var reservations []Reservation
+	for _, k := range keys {
+		r, err := l.Get(k)
+		if err != nil {
+			return nil, err
+		}
+		if r.Expired(now) {
+			continue
+		}
+		reservations = append(reservations, *r)
+	}
+	return reservations, nil
+}
+
+// Load returns the reservations that have not expired in the store at
+// 'storeFile', without creating the store if it does not exist yet.
+func Load(storeFile string) ([]Reservation, error) {
+	_, err := os.Stat(storeFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking for store: %w", err)
+	}
+
+	s, err := store.Open(storeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	return NewLedger(s).List()
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package ledger
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/store"
+)
+
+func TestLedger(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	l := NewLedger(store.NewMemoryStore())
+	l.now = func() time.Time { return now }
+
+	_, err := l.Get("MIG-a")
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	r, err := l.Reserve("MIG-a", 0, "slurm", time.Hour, map[string]string{"job": "42"})
+	require.Nil(t, err)
+	require.Equal(t, now.Add(time.Hour), *r.Expires)
+
+	_, err = l.Reserve("MIG-a", 0, "pbs", 0, nil)
+	require.ErrorIs(t, err, ErrReserved, "Unexpected reservation of a MIG device reserved by another holder")
+	require.ErrorIs(t, l.Release("MIG-a", "pbs"), ErrReserved, "Unexpected release by another holder")
+
+	r, err = l.Reserve("MIG-a", 0, "slurm", 2*time.Hour, map[string]string{"job": "43"})
+	require.Nil(t, err, "Unexpected failure renewing a reservation")
+	require.Equal(t, map[string]string{"job": "43"}, r.Metadata)
+
+	_, err = l.Reserve("MIG-b", 1, "pbs", 0, nil)
+	require.Nil(t, err)
+	require.Nil(t, l.Release("MIG-b", "pbs"))
+	require.ErrorIs(t, l.Release("MIG-b", "pbs"), store.ErrNotFound)
+
+	reservations, err := l.List()
+	require.Nil(t, err)
+	require.Len(t, reservations, 1)
+	require.Equal(t, "MIG-a", reservations[0].UUID)
+
+	now = now.Add(2 * time.Hour)
+	reservations, err = l.List()
+	require.Nil(t, err)
+	require.Empty(t, reservations, "Unexpected expired reservation listed")
+
+	_, err = l.Reserve("MIG-a", 0, "pbs", 0, nil)
+	require.Nil(t, err, "Unexpected failure reserving a MIG device whose reservation expired")
+	r, err = l.Get("MIG-a")
+	require.Nil(t, err)
+	require.Nil(t, r.Expires)
+
+	_, err = l.Reserve("", 0, "pbs", 0, nil)
+	require.NotNil(t, err, "Unexpected reservation without a UUID")
+}
+
+func TestLoad(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "state.db")
+
+	reservations, err := Load(storeFile)
+	require.Nil(t, err)
+	require.Empty(t, reservations)
+	require.NoFileExists(t, storeFile, "Unexpected store created by Load")
+
+	s, err := store.Open(storeFile)
+	require.Nil(t, err)
+	_, err = NewLedger(s).Reserve("MIG-a", 3, "slurm", 0, nil)
+	require.Nil(t, err)
+	require.Nil(t, s.Close())
+
+	reservations, err = Load(storeFile)
+	require.Nil(t, err)
+	require.Len(t, reservations, 1)
+	require.Equal(t, 3, reservations[0].GPU)
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package caps maps MIG devices to the nvidia-caps device nodes that a
+// process needs access to in order to use them, so that containers or jobs
+// can be confined to a MIG device through the device cgroup.
+package caps
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const (
+	// DefaultMigMinorsFile lists the minor number of every nvidia-caps
+	// device node that can be created for MIG devices.
+	DefaultMigMinorsFile = "/proc/driver/nvidia-caps/mig-minors"
+	// DefaultDevicesFile lists the major number of every character device.
+	DefaultDevicesFile = "/proc/devices"
+
+	// CapsDevicesName is the name of the nvidia-caps devices in DefaultDevicesFile.
+	CapsDevicesName = "nvidia-caps"
+	// CapsDeviceDir is the directory holding the nvidia-caps device nodes.
+	CapsDeviceDir = "/dev/nvidia-caps"
+
+	// NvidiaMajor is the major number of the /dev/nvidia* device nodes.
+	NvidiaMajor = 195
+	// NvidiactlMinor is the minor number of /dev/nvidiactl.
+	NvidiactlMinor = 255
+)
+
+// MigMinors maps the entries of DefaultMigMinorsFile (e.g.
+// "gpu0/gi1/ci0/access") to their minor numbers.
+type MigMinors map[string]int
+
+// DeviceNode describes a character device node.
+type DeviceNode struct {
+	Path  string `json:"path"`
+	Major int    `json:"major"`
+	Minor int    `json:"minor"`
+}
+
+// MigDeviceCaps describes the device nodes that give access to a MIG device.
+type MigDeviceCaps struct {
+	GPU int `json:"gpu"`
+	types.MigDevice
+	GpuInstanceAccessMinor     int          `json:"gpu-instance-access-minor"`
+	ComputeInstanceAccessMinor int          `json:"compute-instance-access-minor"`
+	DeviceNodes                []DeviceNode `json:"device-nodes"`
+}
+
+// ParseMigMinors parses the contents of DefaultMigMinorsFile from 'r'.
+func ParseMigMinors(r io.Reader) (MigMinors, error) {
+	minors := make(MigMinors)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("malformed line: %q", scanner.Text())
+		}
+		minor, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid minor number for '%v': %w", fields[0], err)
+		}
+		minors[fields[0]] = minor
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return minors, nil
+}
+
+// ReadMigMinors reads and parses the MIG minors file at 'path'.
+func ReadMigMinors(path string) (MigMinors, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return ParseMigMinors(file)
+}
+
+// ParseMajor returns the major number of the character devices called 'name'
+// in the contents of DefaultDevicesFile read from 'r'.
+func ParseMajor(r io.Reader, name string) (int, error) {
+	scanner := bufio.NewScanner(r)
+	inCharDevices := false
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "Character devices:":
+			inCharDevices = true
+			continue
+		case strings.HasSuffix(line, "devices:"):
+			inCharDevices = false
+			continue
+		}
+		fields := strings.Fields(line)
+		if !inCharDevices || len(fields) != 2 || fields[1] != name {
+			continue
+		}
+		return strconv.Atoi(fields[0])
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no character devices found for '%v'", name)
+}
+
+// ReadMajor reads the devices file at 'path' and returns the major number of
+// the character devices called 'name'.
+func ReadMajor(path string, name string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	return ParseMajor(file, name)
+}
+
+// Resolve returns the device nodes that give access to 'device' on the GPU
+// with index 'gpu' and minor number 'gpuMinor'. The nvidia-caps entries are
+// looked up in 'minors' and use the major number 'capsMajor'.
+func Resolve(minors MigMinors, capsMajor int, gpu int, gpuMinor int, device types.MigDevice) (*MigDeviceCaps, error) {
+	giPath := fmt.Sprintf("gpu%d/gi%d/access", gpuMinor, device.GpuInstanceID)
+	giMinor, exists := minors[giPath]
+	if !exists {
+		return nil, fmt.Errorf("no minor number found for '%v'", giPath)
+	}
+
+	ciPath := fmt.Sprintf("gpu%d/gi%d/ci%d/access", gpuMinor, device.GpuInstanceID, device.ComputeInstanceID)
+	ciMinor, exists := minors[ciPath]
+	if !exists {
+		return nil, fmt.Errorf("no minor number found for '%v'", ciPath)
+	}
+
+	return &MigDeviceCaps{
+		GPU:                        gpu,
+		MigDevice:                  device,
+		GpuInstanceAccessMinor:     giMinor,
+		ComputeInstanceAccessMinor: ciMinor,
+		DeviceNodes: []DeviceNode{
+			{Path: "/dev/nvidiactl", Major: NvidiaMajor, Minor: NvidiactlMinor},
+			{Path: fmt.Sprintf("/dev/nvidia%d", gpuMinor), Major: NvidiaMajor, Minor: gpuMinor},
+			{Path: fmt.Sprintf("%s/nvidia-cap%d", CapsDeviceDir, giMinor), Major: capsMajor, Minor: giMinor},
+			{Path: fmt.Sprintf("%s/nvidia-cap%d", CapsDeviceDir, ciMinor), Major: capsMajor, Minor: ciMinor},
+		},
+	}, nil
+}
+
+// DevicesAllow returns the lines to write to a cgroup v1 'devices.allow'
+// file to give read / write access to all of 'caps'. Device nodes shared
+// between MIG devices are only listed once.
+func DevicesAllow(caps []MigDeviceCaps) []string {
+	var lines []string
+	seen := make(map[string]bool)
+	for _, c := range caps {
+		for _, node := range c.DeviceNodes {
+			line := fmt.Sprintf("c %d:%d rw", node.Major, node.Minor)
+			if seen[line] {
+				continue
+			}
+			seen[line] = true
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package caps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const migMinors = `config 1
+monitor 2
+gpu0/gi1/access 12
+gpu0/gi1/ci0/access 13
+gpu0/gi2/access 21
+gpu0/gi2/ci0/access 22
+gpu1/gi1/access 147
+gpu1/gi1/ci0/access 148
+`
+
+const devices = `Character devices:
+  1 mem
+195 nvidia-frontend
+237 nvidia-caps
+510 nvidia-uvm
+
+Block devices:
+237 nvidia-caps-bogus
+259 blkext
+`
+
+func TestParseMigMinors(t *testing.T) {
+	minors, err := ParseMigMinors(strings.NewReader(migMinors))
+	require.Nil(t, err, "Unexpected failure from ParseMigMinors")
+	require.Len(t, minors, 8)
+	require.Equal(t, 13, minors["gpu0/gi1/ci0/access"])
+
+	_, err = ParseMigMinors(strings.NewReader("gpu0/gi1/access"))
+	require.NotNil(t, err, "Unexpected success parsing line without minor number")
+
+	_, err = ParseMigMinors(strings.NewReader("gpu0/gi1/access x"))
+	require.NotNil(t, err, "Unexpected success parsing invalid minor number")
+}
+
+func TestParseMajor(t *testing.T) {
+	testCases := []struct {
+		description     string
+		name            string
+		expected        int
+		expectedFailure bool
+	}{
+		{"Character device", "nvidia-caps", 237, false},
+		{"Block device only", "nvidia-caps-bogus", 0, true},
+		{"Missing device", "bogus", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			major, err := ParseMajor(strings.NewReader(devices), tc.name)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from ParseMajor")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from ParseMajor")
+			require.Equal(t, tc.expected, major)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	minors, err := ParseMigMinors(strings.NewReader(migMinors))
+	require.Nil(t, err, "Unexpected failure from ParseMigMinors")
+
+	device := types.MigDevice{Profile: "1g.5gb", GpuInstanceID: 2, ComputeInstanceID: 0}
+	caps, err := Resolve(minors, 237, 3, 0, device)
+	require.Nil(t, err, "Unexpected failure from Resolve")
+	require.Equal(t, 3, caps.GPU)
+	require.Equal(t, 21, caps.GpuInstanceAccessMinor)
+	require.Equal(t, 22, caps.ComputeInstanceAccessMinor)
+	require.Equal(t, []DeviceNode{
+		{Path: "/dev/nvidiactl", Major: 195, Minor: 255},
+		{Path: "/dev/nvidia0", Major: 195, Minor: 0},
+		{Path: "/dev/nvidia-caps/nvidia-cap21", Major: 237, Minor: 21},
+		{Path: "/dev/nvidia-caps/nvidia-cap22", Major: 237, Minor: 22},
+	}, caps.DeviceNodes)
+
+	_, err = Resolve(minors, 237, 0, 0, types.MigDevice{GpuInstanceID: 3})
+	require.NotNil(t, err, "Unexpected success resolving missing GPU instance")
+
+	_, err = Resolve(minors, 237, 0, 0, types.MigDevice{GpuInstanceID: 1, ComputeInstanceID: 1})
+	require.NotNil(t, err, "Unexpected success resolving missing compute instance")
+}
+
+func TestDevicesAllow(t *testing.T) {
+	minors, err := ParseMigMinors(strings.NewReader(migMinors))
+	require.Nil(t, err, "Unexpected failure from ParseMigMinors")
+
+	var all []MigDeviceCaps
+	for _, gi := range []uint32{1, 2} {
+		caps, err := Resolve(minors, 237, 0, 0, types.MigDevice{GpuInstanceID: gi})
+		require.Nil(t, err, "Unexpected failure from Resolve")
+		all = append(all, *caps)
+	}
+
+	require.Equal(t, []string{
+		"c 195:255 rw",
+		"c 195:0 rw",
+		"c 237:12 rw",
+		"c 237:13 rw",
+		"c 237:21 rw",
+		"c 237:22 rw",
+	}, DevicesAllow(all))
+}
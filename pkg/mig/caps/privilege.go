@@ -0,0 +1,178 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package caps
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Privilege is the access to the GPUs that an operation on them needs.
+// Reading the MIG mode of a GPU through NVML needs none of them, so neither
+// 'export' nor 'assert' has to run as root where the device nodes allow it.
+//   - PrivilegeMonitor lists the GPU and compute instances of a GPU. It is
+//     granted to root and to anyone who can read the 'mig/monitor' nvidia-caps
+//     device node (or /proc file).
+//   - PrivilegeConfig creates and destroys GPU and compute instances. It is
+//     granted to root, to holders of CAP_SYS_ADMIN and to anyone who can read
+//     the 'mig/config' nvidia-caps device node (or /proc file).
+//   - PrivilegeAdmin changes the MIG or CC mode of a GPU, or reads either
+//     without NVML (from the PCI config space of the GPU, or through
+//     gpu-admin-tools). Only root is granted it.
+type Privilege string
+
+const (
+	PrivilegeMonitor Privilege = "monitor"
+	PrivilegeConfig  Privilege = "config"
+	PrivilegeAdmin   Privilege = "admin"
+)
+
+const (
+	// DefaultCapabilitiesDir holds the /proc files that the MIG capabilities
+	// are checked against when the driver does not expose them as nvidia-caps
+	// device nodes.
+	DefaultCapabilitiesDir = "/proc/driver/nvidia/capabilities/mig"
+	// DefaultStatusFile is where the effective capabilities of the process
+	// are read from.
+	DefaultStatusFile = "/proc/self/status"
+
+	// capSysAdmin is the bit of CAP_SYS_ADMIN in the capability sets.
+	capSysAdmin = 21
+)
+
+// ErrInsufficientPrivilege is wrapped by the errors returned from
+// PrivilegeChecker.Check.
+var ErrInsufficientPrivilege = errors.New("insufficient privilege")
+
+// PrivilegeChecker checks whether the process holds a Privilege. Its fields
+// describe the process and the node, so that they can be faked in tests.
+type PrivilegeChecker struct {
+	Euid            int
+	SysAdmin        bool
+	MigMinorsFile   string
+	CapabilitiesDir string
+	CanRead         func(path string) bool
+}
+
+// NewPrivilegeChecker returns a PrivilegeChecker for the current process.
+func NewPrivilegeChecker() *PrivilegeChecker {
+	return &PrivilegeChecker{
+		Euid:            os.Geteuid(),
+		SysAdmin:        hasSysAdmin(DefaultStatusFile),
+		MigMinorsFile:   DefaultMigMinorsFile,
+		CapabilitiesDir: DefaultCapabilitiesDir,
+		CanRead: func(path string) bool {
+			file, err := os.Open(path)
+			if err != nil {
+				return false
+			}
+			file.Close()
+			return true
+		},
+	}
+}
+
+// Check returns an error naming what to change for the process to hold 'p',
+// or nil if it already does. If the node does not say who is granted a MIG
+// capability (e.g. because the driver is not loaded), the process is assumed
+// to hold it and NVML is left to report otherwise.
+func (c *PrivilegeChecker) Check(p Privilege) error {
+	if c.Euid == 0 {
+		return nil
+	}
+
+	switch p {
+	case PrivilegeMonitor, PrivilegeConfig:
+	case PrivilegeAdmin:
+		return fmt.Errorf("%w: %v requires root", ErrInsufficientPrivilege, p.describe())
+	default:
+		return fmt.Errorf("unknown privilege: %v", p)
+	}
+
+	if p == PrivilegeConfig && c.SysAdmin {
+		return nil
+	}
+
+	path := c.capabilityPath(p)
+	if path == "" || c.CanRead(path) {
+		return nil
+	}
+
+	if p == PrivilegeConfig {
+		return fmt.Errorf("%w: %v requires root, CAP_SYS_ADMIN or read access to %v (the 'mig/%v' capability)", ErrInsufficientPrivilege, p.describe(), path, p)
+	}
+	return fmt.Errorf("%w: %v requires root or read access to %v (the 'mig/%v' capability)", ErrInsufficientPrivilege, p.describe(), path, p)
+}
+
+// capabilityPath returns the file whose read access grants the MIG
+// capability for 'p': its nvidia-caps device node if the driver lists one,
+// its /proc file otherwise, or "" if there is neither.
+func (c *PrivilegeChecker) capabilityPath(p Privilege) string {
+	minors, err := ReadMigMinors(c.MigMinorsFile)
+	if err == nil {
+		if minor, exists := minors[string(p)]; exists {
+			return filepath.Join(CapsDeviceDir, fmt.Sprintf("nvidia-cap%d", minor))
+		}
+	}
+
+	path := filepath.Join(c.CapabilitiesDir, string(p))
+	if _, err := os.Stat(path); err != nil {
+		return ""
+	}
+	return path
+}
+
+func (p Privilege) describe() string {
+	switch p {
+	case PrivilegeMonitor:
+		return "listing MIG devices"
+	case PrivilegeConfig:
+		return "creating or destroying MIG devices"
+	case PrivilegeAdmin:
+		return "changing the MIG or CC mode, or reading either without NVML,"
+	}
+	return string(p)
+}
+
+// hasSysAdmin checks whether CAP_SYS_ADMIN is in the effective capabilities
+// listed in the status file at 'path'.
+func hasSysAdmin(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !found {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return false
+		}
+		return caps&(1<<capSysAdmin) != 0
+	}
+	return false
+}
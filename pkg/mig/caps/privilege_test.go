@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package caps
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivilegeCheck(t *testing.T) {
+	dir := t.TempDir()
+	minorsFile := filepath.Join(dir, "mig-minors")
+	require.Nil(t, os.WriteFile(minorsFile, []byte(migMinors), 0644))
+
+	procDir := filepath.Join(dir, "capabilities")
+	require.Nil(t, os.MkdirAll(procDir, 0755))
+	require.Nil(t, os.WriteFile(filepath.Join(procDir, "monitor"), nil, 0644))
+
+	testCases := []struct {
+		description     string
+		checker         PrivilegeChecker
+		privilege       Privilege
+		expectedFailure bool
+	}{
+		{
+			"Root holds every privilege",
+			PrivilegeChecker{Euid: 0},
+			PrivilegeAdmin,
+			false,
+		},
+		{
+			"Admin requires root",
+			PrivilegeChecker{Euid: 1000, SysAdmin: true},
+			PrivilegeAdmin,
+			true,
+		},
+		{
+			"Monitor with readable device node",
+			PrivilegeChecker{Euid: 1000, MigMinorsFile: minorsFile, CanRead: func(path string) bool { return path == "/dev/nvidia-caps/nvidia-cap2" }},
+			PrivilegeMonitor,
+			false,
+		},
+		{
+			"Monitor without readable device node",
+			PrivilegeChecker{Euid: 1000, MigMinorsFile: minorsFile, CanRead: func(string) bool { return false }},
+			PrivilegeMonitor,
+			true,
+		},
+		{
+			"Monitor with readable proc file",
+			PrivilegeChecker{Euid: 1000, CapabilitiesDir: procDir, CanRead: func(path string) bool { return path == filepath.Join(procDir, "monitor") }},
+			PrivilegeMonitor,
+			false,
+		},
+		{
+			"Config with CAP_SYS_ADMIN",
+			PrivilegeChecker{Euid: 1000, SysAdmin: true, MigMinorsFile: minorsFile, CanRead: func(string) bool { return false }},
+			PrivilegeConfig,
+			false,
+		},
+		{
+			"Config without CAP_SYS_ADMIN or readable device node",
+			PrivilegeChecker{Euid: 1000, MigMinorsFile: minorsFile, CanRead: func(string) bool { return false }},
+			PrivilegeConfig,
+			true,
+		},
+		{
+			"No MIG capabilities on the node",
+			PrivilegeChecker{Euid: 1000, CapabilitiesDir: filepath.Join(dir, "missing"), CanRead: func(string) bool { return false }},
+			PrivilegeConfig,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.checker.Check(tc.privilege)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Check")
+				require.True(t, errors.Is(err, ErrInsufficientPrivilege))
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Check")
+		})
+	}
+}
+
+func TestHasSysAdmin(t *testing.T) {
+	dir := t.TempDir()
+
+	withSysAdmin := filepath.Join(dir, "with")
+	require.Nil(t, os.WriteFile(withSysAdmin, []byte("Name:\ttest\nCapEff:\t000001ffffffffff\n"), 0644))
+	require.True(t, hasSysAdmin(withSysAdmin))
+
+	withoutSysAdmin := filepath.Join(dir, "without")
+	require.Nil(t, os.WriteFile(withoutSysAdmin, []byte("Name:\ttest\nCapEff:\t0000000000000000\n"), 0644))
+	require.False(t, hasSysAdmin(withoutSysAdmin))
+
+	require.False(t, hasSysAdmin(filepath.Join(dir, "missing")))
+}
@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPermutationBudgetExhausted is returned (wrapped) by SetMigConfig when it
+// gives up trying different orderings of a MIG config because its
+// PermutationBudget has run out.
+var ErrPermutationBudgetExhausted = errors.New("permutation budget exhausted")
+
+// PermutationBudget bounds the work SetMigConfig does while searching for an
+// ordering of MIG devices that can be created successfully. A zero value for
+// either field means that dimension is unbounded.
+type PermutationBudget struct {
+	MaxAttempts int
+	Timeout     time.Duration
+}
+
+// SetOption is an option that can be passed to SetMigConfig.
+type SetOption func(*PermutationBudget)
+
+// WithPermutationBudget bounds the number of orderings SetMigConfig will try.
+func WithPermutationBudget(budget PermutationBudget) SetOption {
+	return func(b *PermutationBudget) {
+		*b = budget
+	}
+}
+
+// exhausted checks whether 'attempts' made since 'start' use up the budget.
+func (b *PermutationBudget) exhausted(attempts int, start time.Time) bool {
+	if b.MaxAttempts > 0 && attempts >= b.MaxAttempts {
+		return true
+	}
+	if b.Timeout > 0 && time.Since(start) >= b.Timeout {
+		return true
+	}
+	return false
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestIteratePermutationsWithBudget(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	config := types.MigConfig{
+		"1g.5gb":  2,
+		"2g.10gb": 1,
+		"3g.20gb": 1,
+	}
+	errFailed := errors.New("failed")
+
+	testCases := []struct {
+		description      string
+		budget           PermutationBudget
+		delay            time.Duration
+		expectedAttempts int
+	}{
+		{
+			"Max attempts",
+			PermutationBudget{MaxAttempts: 3},
+			0,
+			3,
+		},
+		{
+			"Timeout",
+			PermutationBudget{Timeout: 20 * time.Millisecond},
+			50 * time.Millisecond,
+			1,
+		},
+		{
+			"Both, max attempts first",
+			PermutationBudget{MaxAttempts: 1, Timeout: time.Hour},
+			0,
+			1,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			attempts := 0
+			err := iteratePermutationsUntilSuccess(config, tc.budget, func(perm []*types.MigProfile) error {
+				attempts++
+				time.Sleep(tc.delay)
+				return fmt.Errorf("attempt %d: %w", attempts, errFailed)
+			})
+			require.NotNil(t, err, "Unexpected success from iteratePermutationsUntilSuccess")
+			require.ErrorIs(t, err, ErrPermutationBudgetExhausted)
+			require.ErrorIs(t, err, errFailed)
+			require.Equal(t, tc.expectedAttempts, attempts)
+		})
+	}
+}
+
+func TestIteratePermutationsWithinBudget(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	config := types.MigConfig{
+		"1g.5gb":  2,
+		"2g.10gb": 1,
+	}
+
+	attempts := 0
+	err := iteratePermutationsUntilSuccess(config, PermutationBudget{MaxAttempts: 2}, func(perm []*types.MigProfile) error {
+		attempts++
+		if attempts == 2 {
+			return nil
+		}
+		return fmt.Errorf("attempt %d failed", attempts)
+	})
+	require.Nil(t, err, "Unexpected failure from iteratePermutationsUntilSuccess")
+	require.Equal(t, 2, attempts)
+}
+
+func TestIteratePermutationsChargesPriorAttempts(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	config := types.MigConfig{
+		"1g.5gb":  2,
+		"2g.10gb": 1,
+	}
+	errPredicted := errors.New("predicted ordering failed")
+
+	testCases := []struct {
+		description      string
+		budget           PermutationBudget
+		expectedAttempts int
+	}{
+		{
+			"Budget used up by the prior attempt",
+			PermutationBudget{MaxAttempts: 1},
+			0,
+		},
+		{
+			"Budget shared with the prior attempt",
+			PermutationBudget{MaxAttempts: 3},
+			2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			prior := &permutationAttempts{start: time.Now(), count: 1, lastErr: errPredicted}
+
+			attempts := 0
+			err := iteratePermutations(config, tc.budget, prior, func(perm []*types.MigProfile) error {
+				attempts++
+				return fmt.Errorf("attempt %d failed", attempts)
+			})
+			require.NotNil(t, err, "Unexpected success from iteratePermutations")
+			require.ErrorIs(t, err, ErrPermutationBudgetExhausted)
+			require.Equal(t, tc.expectedAttempts, attempts)
+			require.Equal(t, tc.budget.MaxAttempts, prior.count)
+			if tc.expectedAttempts == 0 {
+				require.ErrorIs(t, err, errPredicted)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// deviceCache holds the per-GPU data that cannot change while MIG mode is
+// enabled (memory size, GPU instance profile infos, and the placements those
+// profiles can take), keyed by device UUID. Applying a config queries this
+// data for every ordering of its MIG devices, so caching it avoids repeating
+// the same NVML calls many times over. The cache lives as long as the Manager
+// that owns it, which is created anew for each run.
+type deviceCache struct {
+	sync.Mutex
+	devices map[string]*deviceCacheEntry
+}
+
+type deviceCacheEntry struct {
+	memory         *nvml.Memory
+	giProfileInfos map[int]giProfileInfoResult
+	giPlacements   map[uint32][]nvml.GpuInstancePlacement
+}
+
+// giProfileInfoResult is the outcome of querying a GPU instance profile. A
+// profile the GPU does not support stays unsupported, so that is cached too.
+type giProfileInfoResult struct {
+	info nvml.GpuInstanceProfileInfo
+	ret  nvml.Return
+}
+
+// cachedDevice wraps an nvml.Device, answering queries for immutable data from
+// a deviceCache and passing everything else through.
+type cachedDevice struct {
+	nvml.Device
+	cache *deviceCache
+	entry *deviceCacheEntry
+}
+
+func newDeviceCache() *deviceCache {
+	return &deviceCache{devices: make(map[string]*deviceCacheEntry)}
+}
+
+// wrap returns 'device' with its immutable queries served from the cache. If
+// the cache is disabled (nil) or the UUID of 'device' cannot be determined,
+// 'device' is returned as is.
+func (c *deviceCache) wrap(device nvml.Device) nvml.Device {
+	if c == nil {
+		return device
+	}
+
+	uuid, ret := device.GetUUID()
+	if ret != nvml.SUCCESS {
+		return device
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	entry, exists := c.devices[uuid]
+	if !exists {
+		entry = &deviceCacheEntry{
+			giProfileInfos: make(map[int]giProfileInfoResult),
+			giPlacements:   make(map[uint32][]nvml.GpuInstancePlacement),
+		}
+		c.devices[uuid] = entry
+	}
+
+	return &cachedDevice{Device: device, cache: c, entry: entry}
+}
+
+func (d *cachedDevice) GetMemoryInfo() (nvml.Memory, nvml.Return) {
+	d.cache.Lock()
+	defer d.cache.Unlock()
+	if d.entry.memory != nil {
+		return *d.entry.memory, nvml.SUCCESS
+	}
+	memory, ret := d.Device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return memory, ret
+	}
+	d.entry.memory = &memory
+	return memory, nvml.SUCCESS
+}
+
+func (d *cachedDevice) GetGpuInstanceProfileInfo(giProfileID int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+	d.cache.Lock()
+	defer d.cache.Unlock()
+	if result, exists := d.entry.giProfileInfos[giProfileID]; exists {
+		return result.info, result.ret
+	}
+	info, ret := d.Device.GetGpuInstanceProfileInfo(giProfileID)
+	if ret == nvml.SUCCESS || ret == nvml.ERROR_NOT_SUPPORTED {
+		d.entry.giProfileInfos[giProfileID] = giProfileInfoResult{info, ret}
+	}
+	return info, ret
+}
+
+// GetGpuInstancePossiblePlacements caches placements by profile ID, which is
+// the only part of 'info' NVML uses to look them up.
+func (d *cachedDevice) GetGpuInstancePossiblePlacements(info *nvml.GpuInstanceProfileInfo) ([]nvml.GpuInstancePlacement, nvml.Return) {
+	d.cache.Lock()
+	defer d.cache.Unlock()
+	if placements, exists := d.entry.giPlacements[info.Id]; exists {
+		return append([]nvml.GpuInstancePlacement(nil), placements...), nvml.SUCCESS
+	}
+	placements, ret := d.Device.GetGpuInstancePossiblePlacements(info)
+	if ret != nvml.SUCCESS {
+		return placements, ret
+	}
+	d.entry.giPlacements[info.Id] = placements
+	return append([]nvml.GpuInstancePlacement(nil), placements...), nvml.SUCCESS
+}
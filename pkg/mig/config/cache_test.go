@@ -0,0 +1,188 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// nvmlCallCounts counts the calls made to the NVML device queries that
+// deviceCache answers.
+type nvmlCallCounts struct {
+	memoryInfo    int
+	giProfileInfo int
+	giPlacements  int
+}
+
+// countNvmlCalls instruments the device queries of every GPU of 'server',
+// delaying each by 'delay' to simulate the cost of a real NVML call.
+func countNvmlCalls(server *testutil.Server, delay time.Duration) *nvmlCallCounts {
+	counts := &nvmlCallCounts{}
+	for _, d := range server.Devices {
+		device := d.(*testutil.Device)
+		getMemoryInfo := device.GetMemoryInfoFunc
+		device.GetMemoryInfoFunc = func() (nvml.Memory, nvml.Return) {
+			counts.memoryInfo++
+			time.Sleep(delay)
+			return getMemoryInfo()
+		}
+		getGpuInstanceProfileInfo := device.GetGpuInstanceProfileInfoFunc
+		device.GetGpuInstanceProfileInfoFunc = func(giProfileID int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+			counts.giProfileInfo++
+			time.Sleep(delay)
+			return getGpuInstanceProfileInfo(giProfileID)
+		}
+		getGpuInstancePossiblePlacements := device.GetGpuInstancePossiblePlacementsFunc
+		device.GetGpuInstancePossiblePlacementsFunc = func(info *nvml.GpuInstanceProfileInfo) ([]nvml.GpuInstancePlacement, nvml.Return) {
+			counts.giPlacements++
+			time.Sleep(delay)
+			return getGpuInstancePossiblePlacements(info)
+		}
+	}
+	return counts
+}
+
+func newCacheTestManager(server *testutil.Server, cache *deviceCache) *nvmlMigConfigManager {
+	return &nvmlMigConfigManager{nvml: server, nvlib: nvlib.NewMock(server), cache: cache}
+}
+
+// applyMigConfig performs the sequence of queries a single apply makes on a
+// GPU: filling the config, planning it, and then setting it.
+func applyMigConfig(m *nvmlMigConfigManager, gpu int) error {
+	config, err := m.FillMigConfig(gpu, types.MigConfig{"3g.20gb": 1}, "1g.5gb")
+	if err != nil {
+		return err
+	}
+	_, err = m.PlanMigConfig(gpu, config)
+	if err != nil {
+		return err
+	}
+	_, err = m.SetMigConfig(gpu, config)
+	if err != nil {
+		return err
+	}
+	_, err = m.GetMigConfig(gpu)
+	return err
+}
+
+func TestDeviceCache(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		Description string
+		Cache       *deviceCache
+		Cached      bool
+	}{
+		{"Cache enabled", newDeviceCache(), true},
+		{"Cache disabled", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Description, func(t *testing.T) {
+			server := testutil.NewServerBuilder().
+				WithGPUs(2, testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+				MustBuild()
+			counts := countNvmlCalls(server, 0)
+			manager := newCacheTestManager(server, tc.Cache)
+
+			require.Nil(t, applyMigConfig(manager, 0))
+			first := *counts
+
+			require.Nil(t, applyMigConfig(manager, 0))
+			require.Nil(t, applyMigConfig(manager, 0))
+			repeated := *counts
+
+			require.Nil(t, applyMigConfig(manager, 1))
+
+			if !tc.Cached {
+				require.Greater(t, repeated.giProfileInfo, first.giProfileInfo)
+				require.Greater(t, repeated.giPlacements, first.giPlacements)
+				return
+			}
+
+			// Repeated applies on the same GPU are answered from the cache,
+			// while a different GPU (with a different UUID) is queried anew.
+			require.Equal(t, 1, first.memoryInfo)
+			require.Equal(t, first, repeated)
+			require.Equal(t, 2*first.memoryInfo, counts.memoryInfo)
+			require.Equal(t, 2*first.giProfileInfo, counts.giProfileInfo)
+			require.Equal(t, 2*first.giPlacements, counts.giPlacements)
+		})
+	}
+}
+
+func TestDeviceCachePlacementsNotShared(t *testing.T) {
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		MustBuild()
+	device := newDeviceCache().wrap(server.Devices[0])
+
+	info, ret := device.GetGpuInstanceProfileInfo(nvml.GPU_INSTANCE_PROFILE_1_SLICE)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	placements, ret := device.GetGpuInstancePossiblePlacements(&info)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.NotEmpty(t, placements)
+	placements[0].Start = 42
+
+	placements, ret = device.GetGpuInstancePossiblePlacements(&info)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.NotEqual(t, uint32(42), placements[0].Start)
+}
+
+// BenchmarkApplyMigConfig measures the queries of repeated applies on a GPU
+// with and without the device cache. Each NVML query is delayed to simulate
+// the cost of a call into the driver, which dominates on real hardware.
+func BenchmarkApplyMigConfig(b *testing.B) {
+	types.SetMockNVdevlib()
+
+	benchmarks := []struct {
+		Description string
+		NewCache    func() *deviceCache
+	}{
+		{"cached", newDeviceCache},
+		{"uncached", func() *deviceCache { return nil }},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.Description, func(b *testing.B) {
+			server := testutil.NewServerBuilder().
+				WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+				MustBuild()
+			counts := countNvmlCalls(server, 50*time.Microsecond)
+			manager := newCacheTestManager(server, bm.NewCache())
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := applyMigConfig(manager, 0)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(counts.memoryInfo+counts.giProfileInfo+counts.giPlacements)/float64(b.N), "nvml-calls/op")
+		})
+	}
+}
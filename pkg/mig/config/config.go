@@ -19,24 +19,48 @@ package config
 import (
 	"fmt"
 	"strings"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
-	"github.com/NVIDIA/mig-parted/internal/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
+// Manager represents the set of operations for getting / setting the MIG
+// configuration of the GPUs on a node. The Manager returned by
+// NewNvmlMigConfigManager is safe for concurrent use by multiple goroutines:
+// operations on the same GPU are serialized, while operations on different
+// GPUs may proceed in parallel.
 type Manager interface {
 	GetMigConfig(gpu int) (types.MigConfig, error)
-	SetMigConfig(gpu int, config types.MigConfig) error
+	SetMigConfig(gpu int, config types.MigConfig, opts ...SetOption) ([]types.MigDevice, error)
 	ClearMigConfig(gpu int) error
+	FillMigConfig(gpu int, config types.MigConfig, fill string) (types.MigConfig, error)
+	GetMigDevices(gpu int) ([]types.MigDevice, error)
+	PlanMigConfig(gpu int, config types.MigConfig, opts ...PlanOption) ([]types.MigDevice, error)
+	SetComputeInstanceConfig(gpu int, layer types.ComputeInstanceLayer) ([]types.MigDevice, error)
 }
 
 type nvmlMigConfigManager struct {
 	nvml  nvml.Interface
 	nvlib nvlib.Interface
+
+	// initLock guards initCount, the number of outstanding calls to init()
+	// that have not yet been matched by a call to shutdown().
+	initLock  sync.Mutex
+	initCount int
+
+	// deviceLocks holds a *sync.Mutex per GPU index.
+	deviceLocks sync.Map
+
+	// cache holds immutable per-GPU data queried from NVML. A nil cache
+	// disables caching.
+	cache *deviceCache
 }
 
 var _ Manager = (*nvmlMigConfigManager)(nil)
@@ -49,33 +73,86 @@ func tryNvmlShutdown(nvmlLib nvml.Interface) {
 }
 
 func NewNvmlMigConfigManager() Manager {
-	return &nvmlMigConfigManager{nvml.New(), nvlib.New()}
+	return &nvmlMigConfigManager{nvml: nvml.New(), nvlib: nvlib.New(), cache: newDeviceCache()}
 }
 
 func NewMockNvmlMigConfigManager(nvml nvml.Interface) Manager {
-	return &nvmlMigConfigManager{nvml, nvlib.NewMock(nvml)}
+	return &nvmlMigConfigManager{nvml: nvml, nvlib: nvlib.NewMock(nvml), cache: newDeviceCache()}
+}
+
+// init initializes NVML on first use and reference counts subsequent calls so
+// that concurrent (and nested) operations share a single initialization.
+func (m *nvmlMigConfigManager) init() nvml.Return {
+	m.initLock.Lock()
+	defer m.initLock.Unlock()
+	if m.initCount == 0 {
+		ret := m.nvml.Init()
+		if ret != nvml.SUCCESS {
+			return ret
+		}
+	}
+	m.initCount++
+	return nvml.SUCCESS
+}
+
+// shutdown releases a reference taken by init() and shuts NVML down once the
+// last reference has been released.
+func (m *nvmlMigConfigManager) shutdown() {
+	m.initLock.Lock()
+	defer m.initLock.Unlock()
+	if m.initCount == 0 {
+		return
+	}
+	m.initCount--
+	if m.initCount == 0 {
+		tryNvmlShutdown(m.nvml)
+	}
+}
+
+// lockDevice serializes operations on 'gpu' and returns the function to
+// release the lock again.
+func (m *nvmlMigConfigManager) lockDevice(gpu int) func() {
+	l, _ := m.deviceLocks.LoadOrStore(gpu, &sync.Mutex{})
+	mutex := l.(*sync.Mutex)
+	mutex.Lock()
+	return mutex.Unlock
+}
+
+// deviceGetHandleByIndex returns the handle of 'gpu', with its immutable
+// queries answered from the cache where possible.
+func (m *nvmlMigConfigManager) deviceGetHandleByIndex(gpu int) (nvml.Device, nvml.Return) {
+	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, ret
+	}
+	return m.cache.wrap(device), nvml.SUCCESS
 }
 
 func (m *nvmlMigConfigManager) GetMigConfig(gpu int) (types.MigConfig, error) {
-	ret := m.nvml.Init()
+	defer m.lockDevice(gpu)()
+	return m.getMigConfig(gpu)
+}
+
+func (m *nvmlMigConfigManager) getMigConfig(gpu int) (types.MigConfig, error) {
+	ret := m.init()
 	if ret != nvml.SUCCESS {
-		return nil, fmt.Errorf("error initializing NVML: %v", ret)
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
-	defer tryNvmlShutdown(m.nvml)
+	defer m.shutdown()
 
-	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
+	device, ret := m.deviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return nil, fmt.Errorf("error getting device handle: %v", ret)
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	deviceMemory, ret := device.GetMemoryInfo()
 	if ret != nvml.SUCCESS {
-		return nil, fmt.Errorf("error getting device memory: %v", ret)
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
 	}
 
 	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
 	if err != nil {
-		return nil, fmt.Errorf("error asserting MIG enabled: %v", err)
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
 	}
 
 	migConfig := types.MigConfig{}
@@ -83,52 +160,68 @@ func (m *nvmlMigConfigManager) GetMigConfig(gpu int) (types.MigConfig, error) {
 		err := m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
 			mp, err := types.NewMigProfile(giProfileID, ciProfileID, ciEngProfileID, giProfileInfo.MemorySizeMB, deviceMemory.Total)
 			if err != nil {
-				return fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %v", giProfileID, ciProfileID, ciEngProfileID, err)
+				return fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %w", giProfileID, ciProfileID, ciEngProfileID, err)
 			}
 			migConfig[mp.String()]++
 			return nil
 		})
 		if err != nil {
-			return fmt.Errorf("error walking compute instances for '%v': %v", giProfileID, err)
+			return fmt.Errorf("error walking compute instances for '%v': %w", giProfileID, err)
 		}
 		return nil
 	})
 	if err != nil {
-		return nil, fmt.Errorf("error walking gpu instances for '%v': %v", gpu, err)
+		return nil, fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
 	}
 
 	return migConfig, nil
 }
 
-func (m *nvmlMigConfigManager) SetMigConfig(gpu int, config types.MigConfig) error {
-	ret := m.nvml.Init()
+// SetMigConfig applies 'config' to 'gpu' and returns the set of MIG devices that were created.
+// Different orderings of the MIG devices in 'config' are tried until one succeeds or the
+// PermutationBudget passed via 'opts' (unbounded by default) runs out.
+// The orderings are tried in a fixed order, starting from the canonical one
+// of MigConfig.Flatten, so applying the same config to a cleared GPU always
+// creates its MIG devices in the same order and hence at the same placements.
+// The devices are returned in the order of types.SortMigDevices.
+func (m *nvmlMigConfigManager) SetMigConfig(gpu int, config types.MigConfig, opts ...SetOption) ([]types.MigDevice, error) {
+	defer m.lockDevice(gpu)()
+
+	var budget PermutationBudget
+	for _, opt := range opts {
+		opt(&budget)
+	}
+
+	ret := m.init()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error initializing NVML: %v", ret)
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
-	defer tryNvmlShutdown(m.nvml)
+	defer m.shutdown()
 
-	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
+	device, ret := m.deviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error getting device handle: %v", ret)
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	deviceMemory, ret := device.GetMemoryInfo()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error getting device memory: %v", ret)
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
 	}
 
 	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
 	if err != nil {
-		return fmt.Errorf("error asserting MIG enabled: %v", err)
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
 	}
 
-	err = iteratePermutationsUntilSuccess(config, func(mps []*types.MigProfile) error {
+	var devices []types.MigDevice
+	create := func(mps []*types.MigProfile) error {
+		devices = nil
 		clearAttempts := 0
 		maxClearAttempts := 1
 		for {
-			existingConfig, err := m.GetMigConfig(gpu)
+			existingConfig, err := m.getMigConfig(gpu)
 			if err != nil {
-				return fmt.Errorf("error getting existing MigConfig: %v", err)
+				return fmt.Errorf("error getting existing MigConfig: %w", err)
 			}
 
 			if len(existingConfig.Flatten()) == 0 {
@@ -139,9 +232,12 @@ func (m *nvmlMigConfigManager) SetMigConfig(gpu int, config types.MigConfig) err
 				return fmt.Errorf("exceeded maximum attempts to clear MigConfig")
 			}
 
-			err = m.ClearMigConfig(gpu)
+			if clearAttempts > 0 {
+				metrics.nvmlRetries.Add(1)
+			}
+			err = m.clearMigConfig(gpu)
 			if err != nil {
-				return fmt.Errorf("error clearing MigConfig: %v", err)
+				return fmt.Errorf("error clearing MigConfig: %w", err)
 			}
 
 			clearAttempts++
@@ -152,7 +248,7 @@ func (m *nvmlMigConfigManager) SetMigConfig(gpu int, config types.MigConfig) err
 		for _, mp := range mps {
 			giProfileInfo, ret := device.GetGpuInstanceProfileInfo(mp.GIProfileID)
 			if ret != nvml.SUCCESS {
-				return fmt.Errorf("error getting GPU instance profile info for '%v': %v", mp, ret)
+				return fmt.Errorf("error getting GPU instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
 			}
 			reuseGI := (gi != nil) && (lastGIProfileID == mp.GIProfileID)
 			lastGIProfileID = mp.GIProfileID
@@ -161,99 +257,170 @@ func (m *nvmlMigConfigManager) SetMigConfig(gpu int, config types.MigConfig) err
 				if !reuseGI {
 					gi, ret = device.CreateGpuInstance(&giProfileInfo)
 					if ret != nvml.SUCCESS {
-						return fmt.Errorf("error creating GPU instance for '%v': %v", mp, ret)
+						return fmt.Errorf("error creating GPU instance for '%v': %w", mp, nvmlerrors.New(ret))
 					}
 				}
 
 				ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(mp.CIProfileID, mp.CIEngProfileID)
 				if ret != nvml.SUCCESS {
 					if reuseGI {
+						metrics.nvmlRetries.Add(1)
 						reuseGI = false
 						continue
 					}
-					return fmt.Errorf("error getting Compute instance profile info for '%v': %v", mp, ret)
+					return fmt.Errorf("error getting Compute instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
 				}
 
-				_, ret = gi.CreateComputeInstance(&ciProfileInfo)
+				ci, ret := gi.CreateComputeInstance(&ciProfileInfo)
 				if ret != nvml.SUCCESS {
 					if reuseGI {
+						metrics.nvmlRetries.Add(1)
 						reuseGI = false
 						continue
 					}
-					return fmt.Errorf("error creating Compute instance for '%v': %v", mp, ret)
+					return fmt.Errorf("error creating Compute instance for '%v': %w", mp, nvmlerrors.New(ret))
 				}
 
 				valid, err := types.NewMigProfile(mp.GIProfileID, mp.CIProfileID, mp.CIEngProfileID, giProfileInfo.MemorySizeMB, deviceMemory.Total)
 				if err != nil {
-					return fmt.Errorf("error creating new MIG profile for %v: %v", mp, err)
+					return fmt.Errorf("error creating new MIG profile for %v: %w", mp, err)
 				}
 				if !mp.Equals(valid) {
 					if reuseGI {
+						metrics.nvmlRetries.Add(1)
 						reuseGI = false
 						continue
 					}
 					return fmt.Errorf("unsupported MIG Device specified %v, expected %v instead", mp, valid)
 				}
 
+				giInfo, ret := gi.GetInfo()
+				if ret != nvml.SUCCESS {
+					return fmt.Errorf("error getting GPU instance info for '%v': %w", mp, nvmlerrors.New(ret))
+				}
+
+				ciInfo, ret := ci.GetInfo()
+				if ret != nvml.SUCCESS {
+					return fmt.Errorf("error getting Compute instance info for '%v': %w", mp, nvmlerrors.New(ret))
+				}
+
+				devices = append(devices, types.MigDevice{
+					Profile:                  mp.String(),
+					GpuInstanceID:            giInfo.Id,
+					GpuInstancePlacement:     giInfo.Placement,
+					ComputeInstanceID:        ciInfo.Id,
+					ComputeInstancePlacement: ciInfo.Placement,
+				})
+
 				break
 			}
 		}
 
 		return nil
-	})
+	}
+
+	// Work out which ordering of the MIG devices should succeed from the
+	// (cached) placement data first, so that only that ordering has to be
+	// created through NVML. Should the prediction be wrong, every ordering is
+	// tried in turn. The predicted ordering counts against the budget.
+	attempts := &permutationAttempts{start: time.Now()}
+	mps := config.Flatten()
+	if model, err := newPlacementModel(device, mps); err != nil {
+		log.Debugf("Unable to predict a feasible ordering of MIG devices on GPU %d: %v", gpu, err)
+	} else if chosen, ok := findFeasiblePermutation(mps, model); ok {
+		attempts.count++
+		metrics.permutationAttempts.Add(1)
+		err := create(chosen)
+		if err == nil {
+			types.SortMigDevices(devices)
+			return devices, nil
+		}
+		metrics.failedPermutations.Add(1)
+		attempts.lastErr = err
+		log.Warnf("Predicted ordering of MIG devices on GPU %d failed, trying all orderings: %v", gpu, err)
+	}
+
+	err = iteratePermutations(config, budget, attempts, create)
 	if err != nil {
-		e := m.ClearMigConfig(gpu)
+		e := m.clearMigConfig(gpu)
 		if e != nil {
 			log.Errorf("Error clearing MIG config on GPU %d, erroneous devices may persist", gpu)
 		}
-		return fmt.Errorf("error attempting multiple config orderings: %v", err)
+		if e := explainUnplaceableConfig(device, config); e != nil {
+			return nil, fmt.Errorf("invalid MIG config for GPU %d: %w", gpu, e)
+		}
+		return nil, fmt.Errorf("error attempting multiple config orderings: %w", err)
 	}
+	types.SortMigDevices(devices)
 
-	return nil
+	return devices, nil
 }
 
 func (m *nvmlMigConfigManager) ClearMigConfig(gpu int) error {
-	ret := m.nvml.Init()
+	defer m.lockDevice(gpu)()
+	return m.clearMigConfig(gpu)
+}
+
+func (m *nvmlMigConfigManager) clearMigConfig(gpu int) error {
+	ret := m.init()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error initializing NVML: %v", ret)
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
-	defer tryNvmlShutdown(m.nvml)
+	defer m.shutdown()
 
-	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
+	device, ret := m.deviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error getting device handle: %v", ret)
+		return fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
 	if err != nil {
-		return fmt.Errorf("error asserting MIG enabled: %v", err)
+		return fmt.Errorf("error asserting MIG enabled: %w", err)
 	}
 
 	err = m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
 		err := m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
 			ret := ci.Destroy()
 			if ret != nvml.SUCCESS {
-				return fmt.Errorf("error destroying Compute instance for profile '(%v, %v)': %v", ciProfileID, ciEngProfileID, ret)
+				return fmt.Errorf("error destroying Compute instance for profile '(%v, %v)': %w", ciProfileID, ciEngProfileID, nvmlerrors.New(ret))
 			}
 			return nil
 		})
 		if err != nil {
-			return fmt.Errorf("error walking compute instances for '%v': %v", giProfileID, err)
+			return fmt.Errorf("error walking compute instances for '%v': %w", giProfileID, err)
 		}
 
 		ret := gi.Destroy()
 		if ret != nvml.SUCCESS {
-			return fmt.Errorf("error destroying GPU instance for profile '%v': %v", giProfileID, ret)
+			return fmt.Errorf("error destroying GPU instance for profile '%v': %w", giProfileID, nvmlerrors.New(ret))
 		}
 		return nil
 	})
 	if err != nil {
-		return fmt.Errorf("error walking gpu instances for '%v': %v", gpu, err)
+		return fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
 	}
 	return nil
 }
 
-func iteratePermutationsUntilSuccess(config types.MigConfig, f func([]*types.MigProfile) error) error {
+// permutationAttempts records the orderings of a MIG config tried so far,
+// to charge them against its PermutationBudget.
+type permutationAttempts struct {
+	start time.Time
+	count int
+	// lastErr is the error from the most recent ordering, so that callers
+	// can inspect the underlying failure once all orderings have been
+	// exhausted.
+	lastErr error
+}
+
+func iteratePermutationsUntilSuccess(config types.MigConfig, budget PermutationBudget, f func([]*types.MigProfile) error) error {
+	return iteratePermutations(config, budget, &permutationAttempts{start: time.Now()}, f)
+}
+
+// iteratePermutations calls 'f' with each ordering of the MIG devices in
+// 'config' until it succeeds, counting the orderings already recorded in
+// 'attempts' against 'budget'.
+func iteratePermutations(config types.MigConfig, budget PermutationBudget, attempts *permutationAttempts, f func([]*types.MigProfile) error) error {
 	shouldSwap := func(mps []*types.MigProfile, start, curr int) bool {
 		for i := start; i < curr; i++ {
 			if mps[i] == mps[curr] {
@@ -263,13 +430,27 @@ func iteratePermutationsUntilSuccess(config types.MigConfig, f func([]*types.Mig
 		return true
 	}
 
+	stopped := false
+
 	var iterate func(mps []*types.MigProfile, f func([]*types.MigProfile) error, index int) error
 	iterate = func(mps []*types.MigProfile, f func([]*types.MigProfile) error, i int) error {
+		if stopped {
+			return ErrPermutationBudgetExhausted
+		}
+
 		if i >= len(mps) {
+			if budget.exhausted(attempts.count, attempts.start) {
+				stopped = true
+				return ErrPermutationBudgetExhausted
+			}
+			attempts.count++
+			metrics.permutationAttempts.Add(1)
 			err := f(mps)
 			if err != nil {
+				metrics.failedPermutations.Add(1)
 				e := err.Error()
 				log.Error(strings.ToUpper(e[0:1]) + e[1:])
+				attempts.lastErr = err
 			}
 			return err
 		}
@@ -284,11 +465,23 @@ func iteratePermutationsUntilSuccess(config types.MigConfig, f func([]*types.Mig
 				}
 
 				mps[i], mps[j] = mps[j], mps[i]
+
+				if stopped {
+					return err
+				}
 			}
 		}
 
-		return fmt.Errorf("all orderings failed")
+		return fmt.Errorf("all orderings failed: %w", attempts.lastErr)
 	}
 
-	return iterate(config.Flatten(), f, 0)
+	err := iterate(config.Flatten(), f, 0)
+	if stopped {
+		elapsed := time.Since(attempts.start).Round(time.Millisecond)
+		if attempts.lastErr == nil {
+			return fmt.Errorf("%w after %d attempts in %v", ErrPermutationBudgetExhausted, attempts.count, elapsed)
+		}
+		return fmt.Errorf("%w after %d attempts in %v, last error: %w", ErrPermutationBudgetExhausted, attempts.count, elapsed, attempts.lastErr)
+	}
+	return err
 }
@@ -0,0 +1,215 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ciProfileIDsBySliceCount maps the slice count of a GPU instance to the
+// compute instance profile that spans all of it.
+var ciProfileIDsBySliceCount = map[uint32]int{
+	1: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE,
+	2: nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE,
+	3: nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE,
+	4: nvml.COMPUTE_INSTANCE_PROFILE_4_SLICE,
+	6: nvml.COMPUTE_INSTANCE_PROFILE_6_SLICE,
+	7: nvml.COMPUTE_INSTANCE_PROFILE_7_SLICE,
+	8: nvml.COMPUTE_INSTANCE_PROFILE_8_SLICE,
+}
+
+// deviceMigConfigGroup is a MigConfigGroup derived from the GPU instance
+// profiles and placements reported by a device at runtime.
+type deviceMigConfigGroup struct {
+	types.MigConfigGroupBase
+	deviceTypes []*types.MigProfile
+	profiles    []deviceGpuInstanceProfile
+}
+
+var _ types.MigConfigGroup = (*deviceMigConfigGroup)(nil)
+
+// deviceGpuInstanceProfile is a GPU instance profile supported by a device
+// along with the placements it can be created at.
+type deviceGpuInstanceProfile struct {
+	profile    *types.MigProfile
+	maxCount   int
+	placements []nvml.GpuInstancePlacement
+}
+
+// GetConfigGroupForDevice returns the MigConfigGroup holding every maximal
+// MIG configuration that can be applied to 'device'. The group is built from
+// the GPU instance profiles, instance counts and placements that the device
+// itself reports, i.e. the same information used when applying a config, so
+// it works for any MIG capable GPU and not just those in
+// GetKnownMigConfigGroups(). NVML must already be initialized.
+//
+// The device types in the group are the profiles whose compute instance spans
+// a full GPU instance. Configurations with compute instances that share a GPU
+// instance (e.g. '1c.4g.20gb') are validated against the number of GPU
+// instances they require.
+func GetConfigGroupForDevice(device nvml.Device) (types.MigConfigGroup, error) {
+	deviceMemory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
+	}
+
+	var profiles []deviceGpuInstanceProfile
+	for giProfileID := 0; giProfileID < nvml.GPU_INSTANCE_PROFILE_COUNT; giProfileID++ {
+		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		if giProfileInfo.InstanceCount == 0 {
+			continue
+		}
+
+		ciProfileID, exists := ciProfileIDsBySliceCount[giProfileInfo.SliceCount]
+		if !exists {
+			return nil, fmt.Errorf("no compute instance profile spans a %d slice GPU instance", giProfileInfo.SliceCount)
+		}
+
+		mp, err := types.NewMigProfile(giProfileID, ciProfileID, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED, giProfileInfo.MemorySizeMB, deviceMemory.Total)
+		if err != nil {
+			return nil, fmt.Errorf("error creating new MIG profile for (%v, %v): %w", giProfileID, ciProfileID, err)
+		}
+
+		placements, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+
+		profiles = append(profiles, deviceGpuInstanceProfile{
+			profile:    mp,
+			maxCount:   int(giProfileInfo.InstanceCount),
+			placements: placements,
+		})
+	}
+
+	group := &deviceMigConfigGroup{profiles: profiles}
+	for _, p := range profiles {
+		group.deviceTypes = append(group.deviceTypes, p.profile)
+	}
+	group.Configs = enumerateMaximalConfigs(profiles)
+
+	return group, nil
+}
+
+func (m *deviceMigConfigGroup) GetDeviceTypes() []*types.MigProfile {
+	return m.deviceTypes
+}
+
+// AssertValidConfiguration checks that the GPU instances required by 'config'
+// fit together on the device. If they do not, the error names the rule they
+// break (see explainInvalidConfiguration).
+func (m *deviceMigConfigGroup) AssertValidConfiguration(config types.MigConfig) error {
+	err := config.AssertValidFormat()
+	if err != nil {
+		return fmt.Errorf("invalid MigConfig: %v", err)
+	}
+
+	required := make(types.MigConfig)
+	for profile, count := range config {
+		if count == 0 {
+			continue
+		}
+		mp, err := types.ParseMigProfile(profile)
+		if err != nil {
+			return fmt.Errorf("error parsing profile '%v': %v", profile, err)
+		}
+		giProfile := m.deviceTypeForGpuInstanceProfile(mp.GIProfileID)
+		if giProfile == nil {
+			return fmt.Errorf("profile '%v' is not supported by this device (supported profiles: %v)", profile, m.deviceTypes)
+		}
+		required[giProfile.String()] += numGpuInstancesRequired(mp, count)
+	}
+
+	err = m.MigConfigGroupBase.AssertValidConfiguration(required)
+	if err != nil {
+		return m.explainInvalidConfiguration(required)
+	}
+	return nil
+}
+
+func (m *deviceMigConfigGroup) deviceTypeForGpuInstanceProfile(giProfileID int) *types.MigProfile {
+	for _, mp := range m.deviceTypes {
+		if mp.GIProfileID == giProfileID {
+			return mp
+		}
+	}
+	return nil
+}
+
+// enumerateMaximalConfigs returns every combination of GPU instances from
+// 'profiles' that can be placed on the device together and that has no room
+// left for another GPU instance.
+func enumerateMaximalConfigs(profiles []deviceGpuInstanceProfile) []types.MigConfig {
+	counts := make([]int, len(profiles))
+	var required [][]nvml.GpuInstancePlacement
+
+	canAdd := func(i int) bool {
+		if counts[i] >= profiles[i].maxCount {
+			return false
+		}
+		return canPlaceGpuInstances(append(required, profiles[i].placements))
+	}
+
+	var configs []types.MigConfig
+	var iterate func(start int)
+	iterate = func(start int) {
+		maximal := true
+		for i := range profiles {
+			if !canAdd(i) {
+				continue
+			}
+			maximal = false
+			if i < start {
+				continue
+			}
+			counts[i]++
+			required = append(required, profiles[i].placements)
+			iterate(i)
+			required = required[:len(required)-1]
+			counts[i]--
+		}
+		if !maximal {
+			return
+		}
+		config := make(types.MigConfig)
+		for i, c := range counts {
+			if c > 0 {
+				config[profiles[i].profile.String()] = c
+			}
+		}
+		configs = append(configs, config)
+	}
+	iterate(0)
+
+	sort.SliceStable(configs, func(i, j int) bool {
+		return fmt.Sprintf("%v", configs[i].Flatten()) < fmt.Sprintf("%v", configs[j].Flatten())
+	})
+
+	return configs
+}
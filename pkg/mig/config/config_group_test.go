@@ -0,0 +1,170 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestGetConfigGroupForDevice(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewDGXA100()
+	group, err := GetConfigGroupForDevice(server.Devices[0])
+	require.Nil(t, err, "Unexpected failure from GetConfigGroupForDevice")
+
+	var deviceTypes []string
+	for _, mp := range group.GetDeviceTypes() {
+		deviceTypes = append(deviceTypes, mp.String())
+	}
+	require.ElementsMatch(t, []string{"1g.5gb", "1g.5gb+me", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"}, deviceTypes)
+
+	for _, c := range group.GetPossibleConfigurations() {
+		require.Nil(t, group.AssertValidConfiguration(c), "Possible configuration %v not valid", c)
+	}
+
+	testCases := []struct {
+		description     string
+		config          types.MigConfig
+		expectedFailure bool
+	}{
+		{
+			"Empty",
+			types.MigConfig{},
+			false,
+		},
+		{
+			"All 1g.5gb",
+			types.MigConfig{"1g.5gb": 7},
+			false,
+		},
+		{
+			"Mixed profiles",
+			types.MigConfig{"3g.20gb": 1, "2g.10gb": 1, "1g.5gb": 2},
+			false,
+		},
+		{
+			"Shared GPU instances",
+			types.MigConfig{"1c.4g.20gb": 4, "3g.20gb": 1},
+			false,
+		},
+		{
+			"Too many 1g.5gb",
+			types.MigConfig{"1g.5gb": 8},
+			true,
+		},
+		{
+			"Too many media extensions",
+			types.MigConfig{"1g.5gb+me": 2},
+			true,
+		},
+		{
+			"Does not fit",
+			types.MigConfig{"7g.40gb": 1, "1g.5gb": 1},
+			true,
+		},
+		{
+			"Shared GPU instances do not fit",
+			types.MigConfig{"1c.4g.20gb": 5, "3g.20gb": 1},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := group.AssertValidConfiguration(tc.config)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from AssertValidConfiguration")
+			} else {
+				require.Nil(t, err, "Unexpected failure from AssertValidConfiguration")
+			}
+		})
+	}
+}
+
+func TestGetConfigGroupForDeviceError(t *testing.T) {
+	server := testutil.NewDGXA100()
+	device := server.Devices[0].(*testutil.Device)
+	device.GetMemoryInfoFunc = func() (nvml.Memory, nvml.Return) {
+		return nvml.Memory{}, nvml.ERROR_UNKNOWN
+	}
+
+	_, err := GetConfigGroupForDevice(device)
+	require.NotNil(t, err, "Unexpected success from GetConfigGroupForDevice")
+}
+
+func TestGetConfigGroupForHeterogeneousDevices(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB}).
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_80GB}).
+		MustBuild()
+
+	expectedDeviceTypes := [][]string{
+		{"1g.5gb", "1g.5gb+me", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"},
+		{"1g.10gb", "1g.10gb+me", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"},
+	}
+
+	for i, expected := range expectedDeviceTypes {
+		group, err := GetConfigGroupForDevice(server.Devices[i])
+		require.Nil(t, err, "Unexpected failure from GetConfigGroupForDevice")
+
+		var deviceTypes []string
+		for _, mp := range group.GetDeviceTypes() {
+			deviceTypes = append(deviceTypes, mp.String())
+		}
+		require.ElementsMatch(t, expected, deviceTypes, "GPU %v", i)
+	}
+}
+
+// BenchmarkGetPossibleConfigurations measures enumerating the configurations
+// of a GPU from the profiles it reports. The known config groups are only
+// computed once per process, so they are not included.
+func BenchmarkGetPossibleConfigurations(b *testing.B) {
+	types.SetMockNVdevlib()
+
+	models := []testutil.GPUModel{
+		testutil.A100_SXM4_40GB,
+		testutil.A100_SXM4_80GB,
+		testutil.H100_SXM5_80GB,
+	}
+	for _, model := range models {
+		server := testutil.NewServerBuilder().
+			WithGPU(testutil.GPU{Model: model, MigEnabled: true}).
+			MustBuild()
+		b.Run(fmt.Sprintf("device/%v", model.Name), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				group, err := GetConfigGroupForDevice(server.Devices[0])
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(group.GetPossibleConfigurations()) == 0 {
+					b.Fatal("Unexpected empty set of configurations")
+				}
+			}
+		})
+	}
+}
@@ -17,28 +17,33 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
+	"sync"
 	"testing"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
-	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
 
-	"github.com/NVIDIA/mig-parted/internal/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 func NewMockLunaServerMigConfigManager() Manager {
-	nvml := dgxa100.New()
+	nvml := testutil.NewDGXA100()
 	nvlib := nvlib.NewMock(nvml)
-	return &nvmlMigConfigManager{nvml, nvlib}
+	return &nvmlMigConfigManager{nvml: nvml, nvlib: nvlib}
 }
 
 func EnableMigMode(manager Manager, gpu int) (nvml.Return, nvml.Return) {
 	m := manager.(*nvmlMigConfigManager)
-	n := m.nvml.(*dgxa100.Server)
+	n := m.nvml.(*testutil.Server)
 	r1, r2 := n.Devices[gpu].SetMigMode(nvml.DEVICE_MIG_ENABLE)
 	return r1, r2
 }
@@ -68,7 +73,7 @@ func TestGetSetMigConfig(t *testing.T) {
 		t.Run(tc.description, func(t *testing.T) {
 			t.Parallel()
 
-			nvmlLib := dgxa100.New()
+			nvmlLib := testutil.NewDGXA100()
 			manager := NewMockLunaServerMigConfigManager()
 
 			numGPUs, ret := nvmlLib.DeviceGetCount()
@@ -80,8 +85,9 @@ func TestGetSetMigConfig(t *testing.T) {
 				require.Equal(t, nvml.SUCCESS, r1)
 				require.Equal(t, nvml.SUCCESS, r2)
 
-				err := manager.SetMigConfig(i, tc.config)
+				devices, err := manager.SetMigConfig(i, tc.config)
 				require.Nil(t, err, "Unexpected failure from SetMigConfig")
+				require.Len(t, devices, len(tc.config.Flatten()), "Unexpected number of MIG devices returned from SetMigConfig")
 
 				config, err := manager.GetMigConfig(i)
 				require.Nil(t, err, "Unexpected failure from GetMigConfig")
@@ -122,7 +128,7 @@ func TestClearMigConfig(t *testing.T) {
 			require.Equal(t, nvml.SUCCESS, r1)
 			require.Equal(t, nvml.SUCCESS, r2)
 
-			err := manager.SetMigConfig(0, tc.config)
+			_, err := manager.SetMigConfig(0, tc.config)
 			require.Nil(t, err, "Unexpected failure from SetMigConfig")
 
 			err = manager.ClearMigConfig(0)
@@ -135,23 +141,149 @@ func TestClearMigConfig(t *testing.T) {
 	}
 }
 
-func TestIteratePermutationsUntilSuccess(t *testing.T) {
-	factorial := func(n int) int {
-		product := 1
-		for i := 1; i <= n; i++ {
-			product *= i
+func TestSetMigConfigOrderIsStable(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	configs := []types.MigConfig{
+		{"1g.5gb": 7},
+		{"1g.5gb": 2, "2g.10gb": 1, "3g.20gb": 1},
+		{"1c.3g.20gb": 1, "2c.3g.20gb": 1, "1g.5gb": 1, "1g.5gb+me": 1},
+	}
+
+	// The mock assigns no placements and hands out ever increasing instance
+	// IDs, so only the profiles are compared across repeated applies.
+	profiles := func(devices []types.MigDevice) []string {
+		var p []string
+		for _, d := range devices {
+			p = append(p, d.Profile)
 		}
-		return product
+		return p
+	}
+
+	for _, config := range configs {
+		t.Run(fmt.Sprintf("%v", config.Flatten()), func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			var first []string
+			for i := 0; i < 5; i++ {
+				created, err := manager.SetMigConfig(0, config)
+				require.Nil(t, err, "Unexpected failure from SetMigConfig")
+
+				listed, err := manager.GetMigDevices(0)
+				require.Nil(t, err, "Unexpected failure from GetMigDevices")
+				require.Equal(t, created, listed, "MIG devices listed in a different order than created")
+
+				if first == nil {
+					first = profiles(created)
+				}
+				require.Equal(t, first, profiles(created), "MIG devices created in a different order on apply %d", i)
+
+				err = manager.ClearMigConfig(0)
+				require.Nil(t, err, "Unexpected failure from ClearMigConfig")
+			}
+		})
+	}
+}
+
+func TestConcurrentSetMigConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+	m := manager.(*nvmlMigConfigManager)
+
+	numGPUs, ret := m.nvml.DeviceGetCount()
+	require.Equal(t, nvml.SUCCESS, ret, "Unexpected return value from DeviceGetCount")
+
+	for i := 0; i < numGPUs; i++ {
+		r1, r2 := EnableMigMode(manager, i)
+		require.Equal(t, nvml.SUCCESS, r1)
+		require.Equal(t, nvml.SUCCESS, r2)
+	}
+
+	config := types.MigConfig{
+		"1g.5gb":  2,
+		"2g.10gb": 1,
+		"3g.20gb": 1,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*numGPUs)
+	for i := 0; i < numGPUs; i++ {
+		// Two writers per GPU to exercise the per-device lock.
+		for j := 0; j < 2; j++ {
+			wg.Add(1)
+			go func(gpu int) {
+				defer wg.Done()
+				_, err := manager.SetMigConfig(gpu, config)
+				errs <- err
+			}(i)
+		}
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		require.Nil(t, err, "Unexpected failure from SetMigConfig")
 	}
 
-	uniquePermutations := func(mc types.MigConfig) int {
-		perms := factorial(len(mc.Flatten()))
-		for _, v := range mc {
-			perms /= factorial(v)
+	for i := 0; i < numGPUs; i++ {
+		current, err := manager.GetMigConfig(i)
+		require.Nil(t, err, "Unexpected failure from GetMigConfig")
+		require.True(t, current.Equals(config), "Unexpected MIG config on GPU %d: %v", i, current)
+	}
+
+	require.Equal(t, 0, m.initCount, "Unbalanced NVML init / shutdown")
+}
+
+func factorial(n int) int {
+	product := 1
+	for i := 1; i <= n; i++ {
+		product *= i
+	}
+	return product
+}
+
+// uniquePermutations returns the number of distinct orderings of the MIG
+// devices in 'mc', i.e. the number of attempts SetMigConfig makes on it
+// before giving up.
+func uniquePermutations(mc types.MigConfig) int {
+	perms := factorial(len(mc.Flatten()))
+	for _, v := range mc {
+		perms /= factorial(v)
+	}
+	return perms
+}
+
+// worstCaseMigConfigs returns the configurations of an A100-SXM4-40GB that
+// are most expensive to apply: the one with the most MIG devices, and the one
+// with the most distinct orderings of its MIG devices.
+func worstCaseMigConfigs(tb testing.TB) map[string]types.MigConfig {
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		MustBuild()
+	mcg, err := GetConfigGroupForDevice(server.Devices[0])
+	require.Nil(tb, err, "Unexpected failure from GetConfigGroupForDevice")
+
+	var mostDevices, mostPermutations types.MigConfig
+	for _, mc := range mcg.GetPossibleConfigurations() {
+		if mostDevices == nil || len(mc.Flatten()) > len(mostDevices.Flatten()) {
+			mostDevices = mc
+		}
+		if mostPermutations == nil || uniquePermutations(mc) > uniquePermutations(mostPermutations) {
+			mostPermutations = mc
 		}
-		return perms
 	}
+	return map[string]types.MigConfig{
+		"most-devices":      mostDevices,
+		"most-permutations": mostPermutations,
+	}
+}
 
+func TestIteratePermutationsUntilSuccess(t *testing.T) {
 	mcg := NewA100_SXM4_40GB_MigConfigGroup()
 
 	type testCase struct {
@@ -187,7 +319,7 @@ func TestIteratePermutationsUntilSuccess(t *testing.T) {
 			t.Parallel()
 
 			iteration := 0
-			err := iteratePermutationsUntilSuccess(tc.config, func(perm []*types.MigProfile) error {
+			err := iteratePermutationsUntilSuccess(tc.config, PermutationBudget{}, func(perm []*types.MigProfile) error {
 				iteration++
 				if iteration == tc.successAfter {
 					return nil
@@ -203,3 +335,50 @@ func TestIteratePermutationsUntilSuccess(t *testing.T) {
 		})
 	}
 }
+
+func BenchmarkIteratePermutationsUntilSuccess(b *testing.B) {
+	types.SetMockNVdevlib()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for name, mc := range worstCaseMigConfigs(b) {
+		mc := mc
+		// Every ordering fails, so all of them are visited.
+		b.Run(fmt.Sprintf("%v/all-fail", name), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				err := iteratePermutationsUntilSuccess(mc, PermutationBudget{}, func([]*types.MigProfile) error {
+					return errors.New("failed")
+				})
+				if err == nil {
+					b.Fatal("Unexpected success from iteratePermutationsUntilSuccess")
+				}
+			}
+			b.ReportMetric(float64(uniquePermutations(mc)), "orderings/op")
+		})
+	}
+}
+
+func BenchmarkSetMigConfig(b *testing.B) {
+	types.SetMockNVdevlib()
+
+	for name, mc := range worstCaseMigConfigs(b) {
+		mc := mc
+		// Each iteration clears the MIG devices created by the previous one
+		// before creating its own, as when re-applying a config.
+		b.Run(name, func(b *testing.B) {
+			manager := NewMockLunaServerMigConfigManager()
+			r1, r2 := EnableMigMode(manager, 0)
+			if r1 != nvml.SUCCESS || r2 != nvml.SUCCESS {
+				b.Fatalf("Unexpected failure enabling MIG mode: %v, %v", r1, r2)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := manager.SetMigConfig(0, mc)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// explainInvalidConfiguration returns an error naming the first rule that the
+// GPU instances in 'required' (a count per profile in the group) break on the
+// device. The rules are checked against the table of profiles the group was
+// built from, from the most to the least fundamental:
+//   - the slices of all GPU instances exceed those of the device
+//   - the memory of all GPU instances exceeds that of the device
+//   - more GPU instances of a profile than the device supports
+//   - the GPU instances cannot be placed together, even though they pass
+//     all of the above
+func (m *deviceMigConfigGroup) explainInvalidConfiguration(required types.MigConfig) error {
+	profiles := make(map[string]deviceGpuInstanceProfile)
+	for _, p := range m.profiles {
+		profiles[p.profile.String()] = p
+	}
+
+	var names []string
+	for name, count := range required {
+		if count > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	slices, memory := 0, 0
+	for _, name := range names {
+		slices += profiles[name].profile.G * required[name]
+		memory += profiles[name].profile.GB * required[name]
+	}
+
+	deviceSlices, deviceMemory := m.deviceSlices(), m.deviceMemory()
+	if slices > deviceSlices {
+		return fmt.Errorf("total slices exceeded: %v require %d slices, but the device only has %d", describeGpuInstances(required, names), slices, deviceSlices)
+	}
+	if memory > deviceMemory {
+		return fmt.Errorf("memory overcommitted: %v require %dgb of memory, but the device only has %dgb", describeGpuInstances(required, names), memory, deviceMemory)
+	}
+	for _, name := range names {
+		if required[name] > profiles[name].maxCount {
+			return fmt.Errorf("too many GPU instances of profile '%v': %d are required, but the device supports at most %d", name, required[name], profiles[name].maxCount)
+		}
+	}
+
+	err := fmt.Errorf("incompatible combination: %v fit in the %d slices and %dgb of memory of the device, but not at the same time", describeGpuInstances(required, names), deviceSlices, deviceMemory)
+	closest := m.closestConfiguration(required)
+	if closest == nil {
+		return err
+	}
+	var closestNames []string
+	for name := range closest {
+		closestNames = append(closestNames, name)
+	}
+	sort.Strings(closestNames)
+	return fmt.Errorf("%w (closest valid configuration: %v)", err, describeGpuInstances(closest, closestNames))
+}
+
+// deviceSlices returns the number of compute slices of the device, i.e. those
+// of its largest GPU instance profile.
+func (m *deviceMigConfigGroup) deviceSlices() int {
+	slices := 0
+	for _, p := range m.profiles {
+		if p.profile.G > slices {
+			slices = p.profile.G
+		}
+	}
+	return slices
+}
+
+// deviceMemory returns the memory of the device in GB, i.e. that of its
+// largest GPU instance profile.
+func (m *deviceMigConfigGroup) deviceMemory() int {
+	memory := 0
+	for _, p := range m.profiles {
+		if p.profile.GB > memory {
+			memory = p.profile.GB
+		}
+	}
+	return memory
+}
+
+// closestConfiguration returns the configuration of the group holding the
+// most of the GPU instances in 'required', preferring the first one found.
+func (m *deviceMigConfigGroup) closestConfiguration(required types.MigConfig) types.MigConfig {
+	var closest types.MigConfig
+	best := 0
+	for _, c := range m.Configs {
+		common := 0
+		for name, count := range required {
+			common += min(count, c[name])
+		}
+		if common > best {
+			closest, best = c, common
+		}
+	}
+	return closest
+}
+
+// describeGpuInstances formats the counts in 'config' of the profiles in
+// 'names', e.g. "GPU instances 1g.5gb x2, 3g.20gb x1".
+func describeGpuInstances(config types.MigConfig, names []string) string {
+	var counts []string
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%v x%d", name, config[name]))
+	}
+	return "GPU instances " + strings.Join(counts, ", ")
+}
+
+// explainUnplaceableConfig returns why 'config' can never be applied to
+// 'device', or nil if it would fit on the device once it is empty (or the
+// reason cannot be worked out).
+func explainUnplaceableConfig(device nvml.Device, config types.MigConfig) error {
+	group, err := GetConfigGroupForDevice(device)
+	if err != nil {
+		return nil
+	}
+	return group.AssertValidConfiguration(config)
+}
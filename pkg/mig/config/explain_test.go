@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestExplainInvalidConfiguration(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewDGXA100()
+	group, err := GetConfigGroupForDevice(server.Devices[0])
+	require.Nil(t, err, "Unexpected failure from GetConfigGroupForDevice")
+
+	// The placements of the mock device leave no combination passing every
+	// other rule that cannot be placed, so one is made up by dropping every
+	// configuration but two from the group.
+	restricted := *group.(*deviceMigConfigGroup)
+	restricted.Configs = []types.MigConfig{
+		{"4g.20gb": 1, "3g.20gb": 1},
+		{"7g.40gb": 1},
+	}
+
+	// Likewise, the mock only knows the profiles of the device, so one is
+	// dropped from the group to stand for an unsupported profile.
+	unsupported := *group.(*deviceMigConfigGroup)
+	unsupported.deviceTypes = nil
+	for _, mp := range group.GetDeviceTypes() {
+		if mp.String() != "7g.40gb" {
+			unsupported.deviceTypes = append(unsupported.deviceTypes, mp)
+		}
+	}
+
+	testCases := []struct {
+		description string
+		group       types.MigConfigGroup
+		config      types.MigConfig
+		expected    string
+	}{
+		{
+			"Unsupported profile",
+			&unsupported,
+			types.MigConfig{"7g.40gb": 1},
+			"profile '7g.40gb' is not supported by this device (supported profiles: [1g.5gb 2g.10gb 3g.20gb 4g.20gb 1g.5gb+me 1g.10gb])",
+		},
+		{
+			"Total slices exceeded",
+			group,
+			types.MigConfig{"7g.40gb": 1, "1g.5gb": 1},
+			"total slices exceeded: GPU instances 1g.5gb x1, 7g.40gb x1 require 8 slices, but the device only has 7",
+		},
+		{
+			"Total slices exceeded by shared GPU instances",
+			group,
+			types.MigConfig{"1c.4g.20gb": 5, "3g.20gb": 1},
+			"total slices exceeded: GPU instances 3g.20gb x1, 4g.20gb x2 require 11 slices, but the device only has 7",
+		},
+		{
+			"Memory overcommitted",
+			group,
+			types.MigConfig{"3g.20gb": 2, "1g.10gb": 1},
+			"memory overcommitted: GPU instances 1g.10gb x1, 3g.20gb x2 require 50gb of memory, but the device only has 40gb",
+		},
+		{
+			"Too many GPU instances of a profile",
+			group,
+			types.MigConfig{"1g.5gb+me": 2},
+			"too many GPU instances of profile '1g.5gb+me': 2 are required, but the device supports at most 1",
+		},
+		{
+			"Incompatible combination",
+			&restricted,
+			types.MigConfig{"4g.20gb": 1, "2g.10gb": 1},
+			"incompatible combination: GPU instances 2g.10gb x1, 4g.20gb x1 fit in the 7 slices and 40gb of memory of the device, but not at the same time (closest valid configuration: GPU instances 3g.20gb x1, 4g.20gb x1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.group.AssertValidConfiguration(tc.config)
+			require.NotNil(t, err, "Unexpected success from AssertValidConfiguration")
+			require.Equal(t, tc.expected, err.Error())
+		})
+	}
+}
+
+func TestPlanMigConfigExplainsFailure(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+	r1, r2 := EnableMigMode(manager, 0)
+	require.Equal(t, nvml.SUCCESS, r1)
+	require.Equal(t, nvml.SUCCESS, r2)
+
+	_, err := manager.PlanMigConfig(0, types.MigConfig{"7g.40gb": 1, "1g.5gb": 1})
+	require.NotNil(t, err, "Unexpected success from PlanMigConfig")
+	require.Contains(t, err.Error(), "total slices exceeded")
+}
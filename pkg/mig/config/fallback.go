@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+type fallbackMigConfigManager struct {
+	primary  Manager
+	fallback Manager
+}
+
+var _ Manager = (*fallbackMigConfigManager)(nil)
+
+// NewFallbackMigConfigManager returns a Manager that runs each operation
+// against 'primary' and retries it against 'fallback' whenever 'primary'
+// reports that the operation is not supported.
+func NewFallbackMigConfigManager(primary, fallback Manager) Manager {
+	return &fallbackMigConfigManager{primary, fallback}
+}
+
+func (m *fallbackMigConfigManager) GetMigConfig(gpu int) (types.MigConfig, error) {
+	config, err := m.primary.GetMigConfig(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for GetMigConfig: %v", err)
+		return m.fallback.GetMigConfig(gpu)
+	}
+	return config, err
+}
+
+func (m *fallbackMigConfigManager) SetMigConfig(gpu int, config types.MigConfig, opts ...SetOption) ([]types.MigDevice, error) {
+	devices, err := m.primary.SetMigConfig(gpu, config, opts...)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for SetMigConfig: %v", err)
+		return m.fallback.SetMigConfig(gpu, config, opts...)
+	}
+	return devices, err
+}
+
+func (m *fallbackMigConfigManager) ClearMigConfig(gpu int) error {
+	err := m.primary.ClearMigConfig(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for ClearMigConfig: %v", err)
+		return m.fallback.ClearMigConfig(gpu)
+	}
+	return err
+}
+
+func (m *fallbackMigConfigManager) FillMigConfig(gpu int, config types.MigConfig, fill string) (types.MigConfig, error) {
+	filled, err := m.primary.FillMigConfig(gpu, config, fill)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for FillMigConfig: %v", err)
+		return m.fallback.FillMigConfig(gpu, config, fill)
+	}
+	return filled, err
+}
+
+func (m *fallbackMigConfigManager) GetMigDevices(gpu int) ([]types.MigDevice, error) {
+	devices, err := m.primary.GetMigDevices(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for GetMigDevices: %v", err)
+		return m.fallback.GetMigDevices(gpu)
+	}
+	return devices, err
+}
+
+func (m *fallbackMigConfigManager) PlanMigConfig(gpu int, config types.MigConfig, opts ...PlanOption) ([]types.MigDevice, error) {
+	devices, err := m.primary.PlanMigConfig(gpu, config, opts...)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for PlanMigConfig: %v", err)
+		return m.fallback.PlanMigConfig(gpu, config, opts...)
+	}
+	return devices, err
+}
+
+func (m *fallbackMigConfigManager) SetComputeInstanceConfig(gpu int, layer types.ComputeInstanceLayer) ([]types.MigDevice, error) {
+	devices, err := m.primary.SetComputeInstanceConfig(gpu, layer)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for SetComputeInstanceConfig: %v", err)
+		return m.fallback.SetComputeInstanceConfig(gpu, layer)
+	}
+	return devices, err
+}
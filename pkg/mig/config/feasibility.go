@@ -0,0 +1,206 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// feasibilityBatchSize is the number of orderings of a MIG config that are
+// evaluated concurrently before checking whether any of them is feasible.
+const feasibilityBatchSize = 256
+
+// maxFeasibilityChecks bounds the number of orderings evaluated before giving
+// up on finding a feasible one up front.
+const maxFeasibilityChecks = 1 << 20
+
+// placementModel holds the (cached) placement data needed to predict whether
+// SetMigConfig can create the MIG devices of a config in a given order,
+// without making any NVML calls.
+type placementModel struct {
+	placements map[int][]nvml.GpuInstancePlacement
+}
+
+// newPlacementModel gathers the possible GPU instance placements of every
+// profile in 'mps' from 'device'.
+func newPlacementModel(device nvml.Device, mps []*types.MigProfile) (*placementModel, error) {
+	model := &placementModel{placements: make(map[int][]nvml.GpuInstancePlacement)}
+	for _, mp := range mps {
+		if _, exists := model.placements[mp.GIProfileID]; exists {
+			continue
+		}
+		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(mp.GIProfileID)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		placements, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		model.placements[mp.GIProfileID] = placements
+	}
+	return model, nil
+}
+
+// feasible predicts whether creating 'mps' in order the way SetMigConfig does
+// succeeds. Like SetMigConfig, a compute instance is only added to the
+// previous GPU instance if both have the same GPU instance profile, and only
+// if it can be placed there; a new GPU instance is created for it otherwise.
+// Compute instances of 'C' slices are placed at the lowest free offset that is
+// a multiple of 'C'. NVML picks the placement of each GPU instance itself, so
+// the ordering is considered feasible if all of the GPU instances it creates
+// can be placed at once.
+func (pm *placementModel) feasible(mps []*types.MigProfile) bool {
+	var required [][]nvml.GpuInstancePlacement
+	lastGIProfileID := -1
+	var used []bool
+	for _, mp := range mps {
+		if mp.GIProfileID == lastGIProfileID && placeComputeInstance(used, mp.C) {
+			continue
+		}
+		required = append(required, pm.placements[mp.GIProfileID])
+		lastGIProfileID = mp.GIProfileID
+		used = make([]bool, mp.G)
+		placeComputeInstance(used, mp.C)
+	}
+	return canPlaceGpuInstances(required)
+}
+
+// placeComputeInstance marks the slices of a compute instance of 'size'
+// slices as used in 'used', the compute slices of a GPU instance, at the
+// lowest free offset that is a multiple of 'size'. It returns false if there
+// is no such offset.
+func placeComputeInstance(used []bool, size int) bool {
+	if size <= 0 {
+		return false
+	}
+	for start := 0; start+size <= len(used); start += size {
+		free := true
+		for i := start; i < start+size; i++ {
+			if used[i] {
+				free = false
+				break
+			}
+		}
+		if !free {
+			continue
+		}
+		for i := start; i < start+size; i++ {
+			used[i] = true
+		}
+		return true
+	}
+	return false
+}
+
+// findFeasiblePermutation returns the first ordering of 'mps' (in the order
+// iteratePermutationsUntilSuccess would try them) that 'model' predicts to be
+// feasible. Orderings are evaluated concurrently in batches, so the result is
+// the same as evaluating them one by one.
+func findFeasiblePermutation(mps []*types.MigProfile, model *placementModel) ([]*types.MigProfile, bool) {
+	var chosen []*types.MigProfile
+	var batch [][]*types.MigProfile
+	checked := 0
+
+	evaluate := func() bool {
+		feasible := make([]bool, len(batch))
+		next := make(chan int)
+		var wg sync.WaitGroup
+		for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range next {
+					feasible[i] = model.feasible(batch[i])
+				}
+			}()
+		}
+		for i := range batch {
+			next <- i
+		}
+		close(next)
+		wg.Wait()
+
+		for i, ok := range feasible {
+			if ok {
+				chosen = batch[i]
+				return true
+			}
+		}
+		batch = batch[:0]
+		return false
+	}
+
+	found := false
+	visitPermutations(mps, func(p []*types.MigProfile) bool {
+		batch = append(batch, append([]*types.MigProfile(nil), p...))
+		checked++
+		if len(batch) == feasibilityBatchSize || checked == maxFeasibilityChecks {
+			found = evaluate()
+		}
+		return !found && checked < maxFeasibilityChecks
+	})
+	if !found && len(batch) > 0 {
+		found = evaluate()
+	}
+
+	return chosen, found
+}
+
+// visitPermutations calls 'visit' with each distinct ordering of 'mps', in the
+// same order as iteratePermutationsUntilSuccess, until 'visit' returns false.
+// The slice passed to 'visit' is only valid for the duration of the call.
+func visitPermutations(mps []*types.MigProfile, visit func([]*types.MigProfile) bool) {
+	mps = append([]*types.MigProfile(nil), mps...)
+
+	shouldSwap := func(start, curr int) bool {
+		for i := start; i < curr; i++ {
+			if mps[i] == mps[curr] {
+				return false
+			}
+		}
+		return true
+	}
+
+	var iterate func(i int) bool
+	iterate = func(i int) bool {
+		if i >= len(mps) {
+			return visit(mps)
+		}
+		for j := i; j < len(mps); j++ {
+			if !shouldSwap(i, j) {
+				continue
+			}
+			mps[i], mps[j] = mps[j], mps[i]
+			more := iterate(i + 1)
+			mps[i], mps[j] = mps[j], mps[i]
+			if !more {
+				return false
+			}
+		}
+		return true
+	}
+
+	iterate(0)
+}
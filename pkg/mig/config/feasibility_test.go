@@ -0,0 +1,131 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newTestPlacementModel() *placementModel {
+	return &placementModel{
+		placements: map[int][]nvml.GpuInstancePlacement{
+			nvml.GPU_INSTANCE_PROFILE_1_SLICE: {{Start: 0, Size: 1}, {Start: 1, Size: 1}, {Start: 2, Size: 1}, {Start: 3, Size: 1}, {Start: 4, Size: 1}, {Start: 5, Size: 1}, {Start: 6, Size: 1}},
+			nvml.GPU_INSTANCE_PROFILE_3_SLICE: {{Start: 0, Size: 4}, {Start: 4, Size: 4}},
+			nvml.GPU_INSTANCE_PROFILE_4_SLICE: {{Start: 0, Size: 4}},
+		},
+	}
+}
+
+func parseProfiles(t *testing.T, profiles ...string) []*types.MigProfile {
+	parsed := make(map[string]*types.MigProfile)
+	var mps []*types.MigProfile
+	for _, profile := range profiles {
+		mp, exists := parsed[profile]
+		if !exists {
+			var err error
+			mp, err = types.ParseMigProfile(profile)
+			require.Nil(t, err, "Unexpected failure from ParseMigProfile")
+			parsed[profile] = mp
+		}
+		mps = append(mps, mp)
+	}
+	return mps
+}
+
+func profileNames(mps []*types.MigProfile) []string {
+	var names []string
+	for _, mp := range mps {
+		names = append(names, mp.String())
+	}
+	return names
+}
+
+func TestPlacementModelFeasible(t *testing.T) {
+	types.SetMockNVdevlib()
+	model := newTestPlacementModel()
+
+	testCases := []struct {
+		description string
+		profiles    []string
+		feasible    bool
+	}{
+		{"4g and 3g", []string{"4g.20gb", "3g.20gb"}, true},
+		{"Two 3g", []string{"3g.20gb", "3g.20gb"}, true},
+		{"Three 3g", []string{"3g.20gb", "3g.20gb", "3g.20gb"}, false},
+		{"4g and four 1g", []string{"4g.20gb", "1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb"}, false},
+		{"Compute instances sharing a GPU instance", []string{"4g.20gb", "1c.3g.20gb", "1c.3g.20gb", "1c.3g.20gb"}, true},
+		{"Compute instances split across GPU instances", []string{"1c.3g.20gb", "4g.20gb", "1c.3g.20gb", "1c.3g.20gb"}, false},
+		{"Mixed compute instances sharing a GPU instance", []string{"2c.3g.20gb", "1c.3g.20gb", "4g.20gb"}, true},
+		{"Mixed compute instances not fitting a GPU instance", []string{"1c.3g.20gb", "2c.3g.20gb", "4g.20gb"}, false},
+		{"Mixed compute instances of a full GPU instance", []string{"4g.20gb", "1c.4g.20gb", "3g.20gb"}, false},
+		{"Compute instances of different GPU instance profiles", []string{"1c.4g.20gb", "1c.3g.20gb", "1c.4g.20gb"}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.feasible, model.feasible(parseProfiles(t, tc.profiles...)))
+		})
+	}
+}
+
+func TestFindFeasiblePermutation(t *testing.T) {
+	types.SetMockNVdevlib()
+	model := newTestPlacementModel()
+
+	// Only orderings that keep the compute instances of the 3g GPU instance
+	// together are feasible.
+	mps := parseProfiles(t, "1c.3g.20gb", "4g.20gb", "1c.3g.20gb", "1c.3g.20gb")
+	chosen, ok := findFeasiblePermutation(mps, model)
+	require.True(t, ok, "Expected a feasible ordering")
+	require.True(t, model.feasible(chosen))
+	require.Equal(t, []string{"1c.3g.20gb", "1c.3g.20gb", "1c.3g.20gb", "4g.20gb"}, profileNames(chosen))
+
+	// The first feasible ordering must be the one a sequential search finds.
+	var sequential []*types.MigProfile
+	visitPermutations(mps, func(p []*types.MigProfile) bool {
+		if model.feasible(p) {
+			sequential = append([]*types.MigProfile(nil), p...)
+			return false
+		}
+		return true
+	})
+	require.Equal(t, sequential, chosen)
+
+	_, ok = findFeasiblePermutation(parseProfiles(t, "4g.20gb", "4g.20gb"), model)
+	require.False(t, ok, "Unexpected feasible ordering")
+}
+
+func TestVisitPermutations(t *testing.T) {
+	types.SetMockNVdevlib()
+	mps := parseProfiles(t, "1g.5gb", "1g.5gb", "3g.20gb", "4g.20gb")
+
+	seen := make(map[string]bool)
+	visitPermutations(mps, func(p []*types.MigProfile) bool {
+		key := strings.Join(profileNames(p), ",")
+		require.False(t, seen[key], "Ordering %v visited twice", key)
+		seen[key] = true
+		return true
+	})
+	// 4! / 2! distinct orderings of two identical and two distinct profiles.
+	require.Len(t, seen, 12)
+}
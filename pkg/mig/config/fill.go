@@ -0,0 +1,198 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// FillMigConfig returns a copy of 'config' with the 'fill' profile added as
+// many times as will fit on 'gpu' alongside the profiles already in 'config'.
+// The calculation is based on the GPU instance placements reported by the
+// device itself, so the same 'config' can expand differently on GPUs with
+// different memory sizes.
+func (m *nvmlMigConfigManager) FillMigConfig(gpu int, config types.MigConfig, fill string) (types.MigConfig, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, ret := m.deviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+	}
+
+	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
+	}
+
+	fillProfile, err := types.ParseMigProfile(fill)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing fill profile '%v': %w", fill, err)
+	}
+	if fillProfile.C != fillProfile.G {
+		return nil, fmt.Errorf("fill profile '%v' must occupy a full GPU instance", fill)
+	}
+
+	placements := make(map[int][]nvml.GpuInstancePlacement)
+	getPlacements := func(giProfileID int) ([]nvml.GpuInstancePlacement, *nvml.GpuInstanceProfileInfo, error) {
+		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+		if ret != nvml.SUCCESS {
+			return nil, nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		if _, exists := placements[giProfileID]; !exists {
+			p, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+			if ret != nvml.SUCCESS {
+				return nil, nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", giProfileID, nvmlerrors.New(ret))
+			}
+			placements[giProfileID] = p
+		}
+		return placements[giProfileID], &giProfileInfo, nil
+	}
+
+	var required [][]nvml.GpuInstancePlacement
+	for profile, count := range config {
+		if count == 0 {
+			continue
+		}
+		mp, err := types.ParseMigProfile(profile)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing profile '%v': %w", profile, err)
+		}
+		p, _, err := getPlacements(mp.GIProfileID)
+		if err != nil {
+			return nil, err
+		}
+		for i := 0; i < numGpuInstancesRequired(mp, count); i++ {
+			required = append(required, p)
+		}
+	}
+
+	if !canPlaceGpuInstances(required) {
+		return nil, fmt.Errorf("unable to place existing profiles before adding fill profile '%v'", fill)
+	}
+
+	fillPlacements, fillProfileInfo, err := getPlacements(fillProfile.GIProfileID)
+	if err != nil {
+		return nil, err
+	}
+
+	count := 0
+	for count < int(fillProfileInfo.InstanceCount) {
+		if !canPlaceGpuInstances(append(required, fillPlacements)) {
+			break
+		}
+		required = append(required, fillPlacements)
+		count++
+	}
+
+	filled := make(types.MigConfig)
+	for k, v := range config {
+		filled[k] = v
+	}
+	if count > 0 {
+		filled[fill] += count
+	}
+
+	return filled, nil
+}
+
+// numGpuInstancesRequired returns the number of GPU instances needed to hold
+// 'count' compute instances of profile 'mp'. Compute instances of the same
+// profile are packed into a shared GPU instance wherever possible.
+func numGpuInstancesRequired(mp *types.MigProfile, count int) int {
+	perGI := 1
+	if mp.C > 0 && mp.C < mp.G {
+		perGI = mp.G / mp.C
+	}
+	return (count + perGI - 1) / perGI
+}
+
+// canPlaceGpuInstances checks whether a set of GPU instances can be placed on
+// a device simultaneously. Each entry in 'required' holds the list of possible
+// placements for a single GPU instance.
+func canPlaceGpuInstances(required [][]nvml.GpuInstancePlacement) bool {
+	_, ok := placeGpuInstances(required, types.PlacementPacked)
+	return ok
+}
+
+// placeGpuInstances finds a non-overlapping placement for each GPU instance in
+// 'required' and returns them in the same order as 'required'. Where more than
+// one placement would work, the one favoured by 'preference' is chosen.
+func placeGpuInstances(required [][]nvml.GpuInstancePlacement, preference types.PlacementPreference) ([]nvml.GpuInstancePlacement, bool) {
+	order := make([]int, len(required))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return maxPlacementSize(required[order[i]]) > maxPlacementSize(required[order[j]])
+	})
+
+	chosen := make([]nvml.GpuInstancePlacement, len(required))
+	half := (placementSpan(required) + 1) / 2
+
+	var place func(i int, used uint64) bool
+	place = func(i int, used uint64) bool {
+		if i == len(order) {
+			return true
+		}
+		for _, p := range orderPlacements(required[order[i]], used, half, preference) {
+			mask := placementMask(p)
+			if used&mask != 0 {
+				continue
+			}
+			chosen[order[i]] = p
+			if place(i+1, used|mask) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !place(0, 0) {
+		return nil, false
+	}
+	return chosen, true
+}
+
+func maxPlacementSize(placements []nvml.GpuInstancePlacement) uint32 {
+	var size uint32
+	for _, p := range placements {
+		if p.Size > size {
+			size = p.Size
+		}
+	}
+	return size
+}
+
+func placementMask(p nvml.GpuInstancePlacement) uint64 {
+	var mask uint64
+	for i := p.Start; i < p.Start+p.Size; i++ {
+		mask |= 1 << i
+	}
+	return mask
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestFillMigConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description     string
+		config          types.MigConfig
+		fill            string
+		expected        types.MigConfig
+		expectedFailure bool
+	}{
+		{
+			"Fill empty config",
+			types.MigConfig{},
+			"1g.5gb",
+			types.MigConfig{
+				"1g.5gb": 7,
+			},
+			false,
+		},
+		{
+			"Fill after 3g.20gb",
+			types.MigConfig{
+				"3g.20gb": 1,
+			},
+			"1g.5gb",
+			types.MigConfig{
+				"3g.20gb": 1,
+				"1g.5gb":  4,
+			},
+			false,
+		},
+		{
+			"Fill after 1g.5gb",
+			types.MigConfig{
+				"1g.5gb": 2,
+			},
+			"2g.10gb",
+			types.MigConfig{
+				"1g.5gb":  2,
+				"2g.10gb": 2,
+			},
+			false,
+		},
+		{
+			"Fill with no room left",
+			types.MigConfig{
+				"7g.40gb": 1,
+			},
+			"1g.5gb",
+			types.MigConfig{
+				"7g.40gb": 1,
+			},
+			false,
+		},
+		{
+			"Fill with shared GPU instance",
+			types.MigConfig{
+				"1c.4g.20gb": 2,
+			},
+			"1g.5gb",
+			types.MigConfig{
+				"1c.4g.20gb": 2,
+				"1g.5gb":     3,
+			},
+			false,
+		},
+		{
+			"Fill with compute instance profile",
+			types.MigConfig{},
+			"1c.2g.10gb",
+			nil,
+			true,
+		},
+		{
+			"Existing profiles do not fit",
+			types.MigConfig{
+				"7g.40gb": 2,
+			},
+			"1g.5gb",
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			config, err := manager.FillMigConfig(0, tc.config, tc.fill)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from FillMigConfig")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from FillMigConfig")
+			require.Equal(t, tc.expected, config)
+		})
+	}
+}
+
+// possiblePlacements returns the placements of each GPU instance profile of
+// the first GPU of 'server', keyed by profile ID.
+func possiblePlacements(tb testing.TB, server *testutil.Server) map[int][]nvml.GpuInstancePlacement {
+	placements := make(map[int][]nvml.GpuInstancePlacement)
+	device := server.Devices[0]
+	for giProfileID := 0; giProfileID < nvml.GPU_INSTANCE_PROFILE_COUNT; giProfileID++ {
+		info, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+		if ret != nvml.SUCCESS {
+			continue
+		}
+		p, ret := device.GetGpuInstancePossiblePlacements(&info)
+		require.Equal(tb, nvml.SUCCESS, ret)
+		placements[giProfileID] = p
+	}
+	return placements
+}
+
+func BenchmarkPlaceGpuInstances(b *testing.B) {
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		MustBuild()
+	placements := possiblePlacements(b, server)
+
+	repeat := func(giProfileID int, count int) [][]nvml.GpuInstancePlacement {
+		var required [][]nvml.GpuInstancePlacement
+		for i := 0; i < count; i++ {
+			required = append(required, placements[giProfileID])
+		}
+		return required
+	}
+
+	// The unplaceable cases are the worst case, as every assignment of
+	// placements is tried before giving up.
+	benchmarks := []struct {
+		Description string
+		Required    [][]nvml.GpuInstancePlacement
+		Placeable   bool
+	}{
+		{"7x1-slice", repeat(nvml.GPU_INSTANCE_PROFILE_1_SLICE, 7), true},
+		{"8x1-slice", repeat(nvml.GPU_INSTANCE_PROFILE_1_SLICE, 8), false},
+		{
+			"mixed",
+			append(append(repeat(nvml.GPU_INSTANCE_PROFILE_3_SLICE, 1), repeat(nvml.GPU_INSTANCE_PROFILE_2_SLICE, 1)...), repeat(nvml.GPU_INSTANCE_PROFILE_1_SLICE, 2)...),
+			true,
+		},
+		{
+			"mixed-overcommitted",
+			append(append(repeat(nvml.GPU_INSTANCE_PROFILE_3_SLICE, 2), repeat(nvml.GPU_INSTANCE_PROFILE_2_SLICE, 1)...), repeat(nvml.GPU_INSTANCE_PROFILE_1_SLICE, 2)...),
+			false,
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.Description, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, ok := placeGpuInstances(bm.Required, types.PlacementPacked)
+				if ok != bm.Placeable {
+					b.Fatalf("Unexpected result from placeGpuInstances: %v", ok)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkFillMigConfig(b *testing.B) {
+	types.SetMockNVdevlib()
+
+	benchmarks := []struct {
+		Description string
+		Config      types.MigConfig
+		Fill        string
+	}{
+		{"empty", types.MigConfig{}, "1g.5gb"},
+		{"mixed", types.MigConfig{"3g.20gb": 1, "2g.10gb": 1}, "1g.5gb"},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.Description, func(b *testing.B) {
+			server := testutil.NewServerBuilder().
+				WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+				MustBuild()
+			manager := NewMockNvmlMigConfigManager(server)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := manager.FillMigConfig(0, bm.Config, bm.Fill)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,421 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// InstanceManager represents the set of operations for managing individual
+// GPU instances and compute instances, as opposed to a full MIG config.
+type InstanceManager interface {
+	ListGpuInstances(gpu int) ([]types.GpuInstance, error)
+	CreateGpuInstance(gpu int, profile string, start *uint32) (*types.GpuInstance, error)
+	DestroyGpuInstance(gpu int, giID uint32) error
+	ListComputeInstances(gpu int) ([]types.MigDevice, error)
+	CreateComputeInstance(gpu int, giID uint32, profile string, start *uint32) (*types.MigDevice, error)
+	DestroyComputeInstance(gpu int, giID uint32, ciID uint32) error
+}
+
+var _ InstanceManager = (*nvmlMigConfigManager)(nil)
+
+// errFound is used to stop walking GPU / compute instances early.
+var errFound = errors.New("found")
+
+func NewNvmlInstanceManager() InstanceManager {
+	return &nvmlMigConfigManager{nvml: nvml.New(), nvlib: nvlib.New(), cache: newDeviceCache()}
+}
+
+func NewMockNvmlInstanceManager(nvml nvml.Interface) InstanceManager {
+	return &nvmlMigConfigManager{nvml: nvml, nvlib: nvlib.NewMock(nvml), cache: newDeviceCache()}
+}
+
+// ListGpuInstances returns the GPU instances that currently exist on 'gpu'.
+func (m *nvmlMigConfigManager) ListGpuInstances(gpu int) ([]types.GpuInstance, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, err := m.getMigEnabledDevice(gpu)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceMemory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
+	}
+
+	var gis []types.GpuInstance
+	err = m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
+		info, err := newGpuInstance(gi, giProfileID, giProfileInfo, deviceMemory.Total)
+		if err != nil {
+			return err
+		}
+		gis = append(gis, *info)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
+	}
+
+	return gis, nil
+}
+
+// CreateGpuInstance creates a single GPU instance of 'profile' on 'gpu'. If
+// 'start' is non-nil, the GPU instance is created at the placement beginning
+// at that memory slice; otherwise NVML picks the placement.
+func (m *nvmlMigConfigManager) CreateGpuInstance(gpu int, profile string, start *uint32) (*types.GpuInstance, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, err := m.getMigEnabledDevice(gpu)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceMemory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
+	}
+
+	mp, err := types.ParseMigProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing profile '%v': %w", profile, err)
+	}
+	if mp.C != mp.G {
+		return nil, fmt.Errorf("profile '%v' is not a GPU instance profile", profile)
+	}
+
+	giProfileInfo, ret := device.GetGpuInstanceProfileInfo(mp.GIProfileID)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	var gi nvml.GpuInstance
+	if start == nil {
+		gi, ret = device.CreateGpuInstance(&giProfileInfo)
+	} else {
+		var placements []nvml.GpuInstancePlacement
+		placements, ret = device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		placement, err := findGpuInstancePlacement(placements, *start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid placement for '%v': %w", mp, err)
+		}
+		gi, ret = device.CreateGpuInstanceWithPlacement(&giProfileInfo, placement)
+	}
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error creating GPU instance for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	return newGpuInstance(gi, mp.GIProfileID, giProfileInfo, deviceMemory.Total)
+}
+
+// DestroyGpuInstance destroys the GPU instance with ID 'giID' on 'gpu'. The
+// GPU instance must not contain any compute instances.
+func (m *nvmlMigConfigManager) DestroyGpuInstance(gpu int, giID uint32) error {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, err := m.getMigEnabledDevice(gpu)
+	if err != nil {
+		return err
+	}
+
+	gi, _, err := m.findGpuInstance(device, giID)
+	if err != nil {
+		return err
+	}
+
+	numCIs := 0
+	err = m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
+		numCIs++
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking compute instances for GPU instance %d: %w", giID, err)
+	}
+	if numCIs > 0 {
+		return fmt.Errorf("GPU instance %d still has %d compute instances: %w", giID, numCIs, nvmlerrors.New(nvml.ERROR_IN_USE))
+	}
+
+	ret = gi.Destroy()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error destroying GPU instance %d: %w", giID, nvmlerrors.New(ret))
+	}
+
+	return nil
+}
+
+// ListComputeInstances returns the compute instances that currently exist on
+// 'gpu' along with the GPU instances they belong to.
+func (m *nvmlMigConfigManager) ListComputeInstances(gpu int) ([]types.MigDevice, error) {
+	return m.GetMigDevices(gpu)
+}
+
+// CreateComputeInstance creates a single compute instance of 'profile' inside
+// the GPU instance with ID 'giID' on 'gpu'. If 'start' is non-nil, the
+// compute instance is created at the placement beginning at that slice of the
+// GPU instance; otherwise NVML picks the placement.
+func (m *nvmlMigConfigManager) CreateComputeInstance(gpu int, giID uint32, profile string, start *uint32) (*types.MigDevice, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, err := m.getMigEnabledDevice(gpu)
+	if err != nil {
+		return nil, err
+	}
+
+	mp, err := types.ParseMigProfile(profile)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing profile '%v': %w", profile, err)
+	}
+
+	gi, giProfileID, err := m.findGpuInstance(device, giID)
+	if err != nil {
+		return nil, err
+	}
+	if giProfileID != mp.GIProfileID {
+		return nil, fmt.Errorf("profile '%v' does not match the profile of GPU instance %d", profile, giID)
+	}
+
+	ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(mp.CIProfileID, mp.CIEngProfileID)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting Compute instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	var ci nvml.ComputeInstance
+	if start == nil {
+		ci, ret = gi.CreateComputeInstance(&ciProfileInfo)
+	} else {
+		var placements []nvml.ComputeInstancePlacement
+		placements, ret = gi.GetComputeInstancePossiblePlacements(&ciProfileInfo)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting Compute instance possible placements for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		placement, err := findComputeInstancePlacement(placements, *start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid placement for '%v': %w", mp, err)
+		}
+		ci, ret = gi.CreateComputeInstanceWithPlacement(&ciProfileInfo, placement)
+	}
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error creating Compute instance for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	giInfo, ret := gi.GetInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting GPU instance info for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	ciInfo, ret := ci.GetInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting Compute instance info for '%v': %w", mp, nvmlerrors.New(ret))
+	}
+
+	return &types.MigDevice{
+		Profile:                  mp.String(),
+		GpuInstanceID:            giInfo.Id,
+		GpuInstancePlacement:     giInfo.Placement,
+		ComputeInstanceID:        ciInfo.Id,
+		ComputeInstancePlacement: ciInfo.Placement,
+	}, nil
+}
+
+// DestroyComputeInstance destroys the compute instance with ID 'ciID' inside
+// the GPU instance with ID 'giID' on 'gpu'.
+func (m *nvmlMigConfigManager) DestroyComputeInstance(gpu int, giID uint32, ciID uint32) error {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, err := m.getMigEnabledDevice(gpu)
+	if err != nil {
+		return err
+	}
+
+	gi, _, err := m.findGpuInstance(device, giID)
+	if err != nil {
+		return err
+	}
+
+	var found nvml.ComputeInstance
+	err = m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
+		ciInfo, ret := ci.GetInfo()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting Compute instance info: %w", nvmlerrors.New(ret))
+		}
+		if ciInfo.Id == ciID {
+			found = ci
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return fmt.Errorf("error walking compute instances for GPU instance %d: %w", giID, err)
+	}
+	if found == nil {
+		return fmt.Errorf("compute instance %d not found in GPU instance %d", ciID, giID)
+	}
+
+	ret = found.Destroy()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error destroying compute instance %d: %w", ciID, nvmlerrors.New(ret))
+	}
+
+	return nil
+}
+
+func (m *nvmlMigConfigManager) getMigEnabledDevice(gpu int) (nvml.Device, error) {
+	device, ret := m.deviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+	}
+
+	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
+	}
+
+	return device, nil
+}
+
+// findGpuInstance returns the GPU instance with ID 'giID' on 'device' along
+// with its GPU instance profile ID.
+func (m *nvmlMigConfigManager) findGpuInstance(device nvml.Device, giID uint32) (nvml.GpuInstance, int, error) {
+	var found nvml.GpuInstance
+	var foundProfileID int
+	err := m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
+		giInfo, ret := gi.GetInfo()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting GPU instance info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		if giInfo.Id == giID {
+			found = gi
+			foundProfileID = giProfileID
+			return errFound
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFound) {
+		return nil, 0, fmt.Errorf("error walking gpu instances: %w", err)
+	}
+	if found == nil {
+		return nil, 0, fmt.Errorf("GPU instance %d not found", giID)
+	}
+	return found, foundProfileID, nil
+}
+
+// newGpuInstance builds a 'types.GpuInstance' for 'gi'. The profile is named
+// after the compute instance profile that spans the full GPU instance.
+func newGpuInstance(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo, deviceMemory uint64) (*types.GpuInstance, error) {
+	giInfo, ret := gi.GetInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting GPU instance info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+	}
+
+	for ciProfileID := 0; ciProfileID < nvml.COMPUTE_INSTANCE_PROFILE_COUNT; ciProfileID++ {
+		ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(ciProfileID, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED)
+		if ret != nvml.SUCCESS {
+			continue
+		}
+		if ciProfileInfo.SliceCount != giProfileInfo.SliceCount {
+			continue
+		}
+		mp, err := types.NewMigProfile(giProfileID, ciProfileID, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED, giProfileInfo.MemorySizeMB, deviceMemory)
+		if err != nil {
+			return nil, fmt.Errorf("error creating new MIG profile for (%v, %v): %w", giProfileID, ciProfileID, err)
+		}
+		return &types.GpuInstance{
+			Profile:   mp.String(),
+			ID:        giInfo.Id,
+			Placement: giInfo.Placement,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unable to determine profile of GPU instance %d", giInfo.Id)
+}
+
+func findGpuInstancePlacement(placements []nvml.GpuInstancePlacement, start uint32) (*nvml.GpuInstancePlacement, error) {
+	for i := range placements {
+		if placements[i].Start == start {
+			return &placements[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no possible placement starts at %d", start)
+}
+
+func findComputeInstancePlacement(placements []nvml.ComputeInstancePlacement, start uint32) (*nvml.ComputeInstancePlacement, error) {
+	for i := range placements {
+		if placements[i].Start == start {
+			return &placements[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no possible placement starts at %d", start)
+}
+
+// MigConfigMatches returns whether 'gpu', whose MIG config is 'current',
+// matches 'desired'. A MigConfig only counts GPU instances with compute
+// instances in them, so an empty 'desired' (MIG mode enabled without any MIG
+// devices) only matches a GPU without any GPU instances at all. Otherwise a
+// GPU left with GPU instances but no compute instances would pass for one.
+func MigConfigMatches(m InstanceManager, gpu int, current types.MigConfig, desired types.MigConfig) (bool, error) {
+	if !current.Equals(desired) {
+		return false, nil
+	}
+	if len(desired) != 0 {
+		return true, nil
+	}
+	gis, err := m.ListGpuInstances(gpu)
+	if err != nil {
+		return false, fmt.Errorf("error listing GPU instances: %w", err)
+	}
+	return len(gis) == 0, nil
+}
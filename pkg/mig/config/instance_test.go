@@ -0,0 +1,183 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestCreateDestroyGpuInstance(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	start := func(s uint32) *uint32 { return &s }
+
+	testCases := []struct {
+		description       string
+		profile           string
+		start             *uint32
+		expectedPlacement nvml.GpuInstancePlacement
+		expectedFailure   bool
+	}{
+		{
+			"Any placement",
+			"3g.20gb",
+			nil,
+			nvml.GpuInstancePlacement{},
+			false,
+		},
+		{
+			"Explicit placement",
+			"1g.5gb",
+			start(3),
+			nvml.GpuInstancePlacement{Start: 3, Size: 1},
+			false,
+		},
+		{
+			"Invalid placement",
+			"3g.20gb",
+			start(1),
+			nvml.GpuInstancePlacement{},
+			true,
+		},
+		{
+			"Compute instance profile",
+			"1c.3g.20gb",
+			nil,
+			nvml.GpuInstancePlacement{},
+			true,
+		},
+		{
+			"Invalid profile",
+			"bogus",
+			nil,
+			nvml.GpuInstancePlacement{},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			im := manager.(InstanceManager)
+
+			gi, err := im.CreateGpuInstance(0, tc.profile, tc.start)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from CreateGpuInstance")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from CreateGpuInstance")
+			require.Equal(t, tc.profile, gi.Profile)
+			require.Equal(t, tc.expectedPlacement, gi.Placement)
+
+			gis, err := im.ListGpuInstances(0)
+			require.Nil(t, err, "Unexpected failure from ListGpuInstances")
+			require.Equal(t, []types.GpuInstance{*gi}, gis)
+
+			err = im.DestroyGpuInstance(0, gi.ID)
+			require.Nil(t, err, "Unexpected failure from DestroyGpuInstance")
+
+			gis, err = im.ListGpuInstances(0)
+			require.Nil(t, err, "Unexpected failure from ListGpuInstances")
+			require.Len(t, gis, 0)
+		})
+	}
+}
+
+func TestCreateDestroyComputeInstance(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+
+	r1, r2 := EnableMigMode(manager, 0)
+	require.Equal(t, nvml.SUCCESS, r1)
+	require.Equal(t, nvml.SUCCESS, r2)
+
+	im := manager.(InstanceManager)
+
+	gi, err := im.CreateGpuInstance(0, "4g.20gb", nil)
+	require.Nil(t, err, "Unexpected failure from CreateGpuInstance")
+
+	_, err = im.CreateComputeInstance(0, gi.ID, "1c.3g.20gb", nil)
+	require.NotNil(t, err, "Unexpected success creating compute instance with mismatched profile")
+
+	_, err = im.CreateComputeInstance(0, gi.ID+1, "1c.4g.20gb", nil)
+	require.NotNil(t, err, "Unexpected success creating compute instance in missing GPU instance")
+
+	ci, err := im.CreateComputeInstance(0, gi.ID, "1c.4g.20gb", nil)
+	require.Nil(t, err, "Unexpected failure from CreateComputeInstance")
+	require.Equal(t, "1c.4g.20gb", ci.Profile)
+	require.Equal(t, gi.ID, ci.GpuInstanceID)
+
+	cis, err := im.ListComputeInstances(0)
+	require.Nil(t, err, "Unexpected failure from ListComputeInstances")
+	require.Len(t, cis, 1)
+	require.Equal(t, ci.ComputeInstanceID, cis[0].ComputeInstanceID)
+
+	err = im.DestroyGpuInstance(0, gi.ID)
+	require.NotNil(t, err, "Unexpected success destroying GPU instance with compute instances")
+
+	err = im.DestroyComputeInstance(0, gi.ID, ci.ComputeInstanceID+1)
+	require.NotNil(t, err, "Unexpected success destroying missing compute instance")
+
+	err = im.DestroyComputeInstance(0, gi.ID, ci.ComputeInstanceID)
+	require.Nil(t, err, "Unexpected failure from DestroyComputeInstance")
+
+	err = im.DestroyGpuInstance(0, gi.ID)
+	require.Nil(t, err, "Unexpected failure from DestroyGpuInstance")
+}
+
+func TestMigConfigMatches(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+
+	r1, r2 := EnableMigMode(manager, 0)
+	require.Equal(t, nvml.SUCCESS, r1)
+	require.Equal(t, nvml.SUCCESS, r2)
+
+	im := manager.(InstanceManager)
+
+	matches, err := MigConfigMatches(im, 0, types.MigConfig{}, types.MigConfig{})
+	require.Nil(t, err)
+	require.True(t, matches, "Expected a GPU without GPU instances to match an empty config")
+
+	_, err = im.CreateGpuInstance(0, "3g.20gb", nil)
+	require.Nil(t, err)
+
+	current, err := manager.GetMigConfig(0)
+	require.Nil(t, err)
+	require.Empty(t, current, "Expected a GPU instance without compute instances to be left out")
+
+	matches, err = MigConfigMatches(im, 0, current, types.MigConfig{})
+	require.Nil(t, err)
+	require.False(t, matches, "Expected a GPU instance without compute instances not to match an empty config")
+
+	matches, err = MigConfigMatches(im, 0, types.MigConfig{"1g.5gb": 1}, types.MigConfig{"1g.5gb": 2})
+	require.Nil(t, err)
+	require.False(t, matches)
+}
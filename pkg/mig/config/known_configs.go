@@ -18,7 +18,6 @@ package config
 
 import (
 	"fmt"
-	"sort"
 
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
@@ -103,9 +102,7 @@ func (m *a100_sxm4_40gb_MigConfigGroup) init() {
 			return Break
 		}
 
-		sort.Slice(mps, func(i, j int) bool {
-			return mps[i].String() < mps[j].String()
-		})
+		types.SortMigProfiles(mps)
 
 		str := fmt.Sprintf("%v", mps)
 		if _, exists := configs[str]; !exists {
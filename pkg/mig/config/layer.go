@@ -0,0 +1,151 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// SetComputeInstanceConfig splits each existing GPU instance on 'gpu' into
+// the compute instances 'layer' lists for its profile, without touching the
+// GPU instances themselves. GPU instances whose profile has no entry in
+// 'layer' are left with a single compute instance spanning all of them.
+// It returns the full set of MIG devices on 'gpu' afterwards.
+func (m *nvmlMigConfigManager) SetComputeInstanceConfig(gpu int, layer types.ComputeInstanceLayer) ([]types.MigDevice, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, ret := m.deviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+	}
+
+	deviceMemory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
+	}
+
+	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
+	}
+
+	var devices []types.MigDevice
+	err = m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
+		gpuInstance, err := newGpuInstance(gi, giProfileID, giProfileInfo, deviceMemory.Total)
+		if err != nil {
+			return err
+		}
+
+		desired, exists := layer[gpuInstance.Profile]
+		if !exists {
+			desired = types.MigConfig{gpuInstance.Profile: 1}
+		}
+
+		err = m.reconcileComputeInstances(gi, giProfileID, giProfileInfo, deviceMemory.Total, desired)
+		if err != nil {
+			return fmt.Errorf("error setting compute instances of GPU instance %d to %v: %w", gpuInstance.ID, desired, err)
+		}
+
+		return m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
+			mp, err := types.NewMigProfile(giProfileID, ciProfileID, ciEngProfileID, giProfileInfo.MemorySizeMB, deviceMemory.Total)
+			if err != nil {
+				return fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %w", giProfileID, ciProfileID, ciEngProfileID, err)
+			}
+			ciInfo, ret := ci.GetInfo()
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting Compute instance info for '%v': %w", mp, nvmlerrors.New(ret))
+			}
+			devices = append(devices, types.MigDevice{
+				Profile:                  mp.String(),
+				GpuInstanceID:            gpuInstance.ID,
+				GpuInstancePlacement:     gpuInstance.Placement,
+				ComputeInstanceID:        ciInfo.Id,
+				ComputeInstancePlacement: ciInfo.Placement,
+			})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
+	}
+
+	return devices, nil
+}
+
+// reconcileComputeInstances replaces the compute instances of 'gi' with
+// those in 'desired', unless they already match.
+func (m *nvmlMigConfigManager) reconcileComputeInstances(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo, deviceMemory uint64, desired types.MigConfig) error {
+	mps := desired.Flatten()
+	if len(mps) == 0 {
+		return fmt.Errorf("invalid compute instances: %v", desired)
+	}
+	for _, mp := range mps {
+		if mp.GIProfileID != giProfileID {
+			return fmt.Errorf("compute instance '%v' does not belong in this GPU instance", mp)
+		}
+	}
+
+	current := types.MigConfig{}
+	var cis []nvml.ComputeInstance
+	err := m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
+		mp, err := types.NewMigProfile(giProfileID, ciProfileID, ciEngProfileID, giProfileInfo.MemorySizeMB, deviceMemory)
+		if err != nil {
+			return fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %w", giProfileID, ciProfileID, ciEngProfileID, err)
+		}
+		current[mp.String()]++
+		cis = append(cis, ci)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error walking compute instances: %w", err)
+	}
+
+	if current.Equals(types.NewMigConfig(mps)) {
+		return nil
+	}
+
+	for _, ci := range cis {
+		ret := ci.Destroy()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error destroying Compute instance: %w", nvmlerrors.New(ret))
+		}
+	}
+
+	for _, mp := range mps {
+		ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(mp.CIProfileID, mp.CIEngProfileID)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting Compute instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		_, ret = gi.CreateComputeInstance(&ciProfileInfo)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error creating Compute instance for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,129 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestSetComputeInstanceConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	base := types.MigConfig{"2g.10gb": 2, "3g.20gb": 1}
+
+	testCases := []struct {
+		description     string
+		layer           types.ComputeInstanceLayer
+		expectedConfig  types.MigConfig
+		expectedFailure bool
+	}{
+		{
+			"Empty layer",
+			nil,
+			base,
+			false,
+		},
+		{
+			"Split one profile",
+			types.ComputeInstanceLayer{
+				"2g.10gb": {"1c.2g.10gb": 2},
+			},
+			types.MigConfig{"1c.2g.10gb": 4, "3g.20gb": 1},
+			false,
+		},
+		{
+			"Split all profiles",
+			types.ComputeInstanceLayer{
+				"2g.10gb": {"1c.2g.10gb": 2},
+				"3g.20gb": {"2c.3g.20gb": 1, "1c.3g.20gb": 1},
+			},
+			types.MigConfig{"1c.2g.10gb": 4, "2c.3g.20gb": 1, "1c.3g.20gb": 1},
+			false,
+		},
+		{
+			"Unused profile",
+			types.ComputeInstanceLayer{
+				"7g.40gb": {"1c.7g.40gb": 7},
+			},
+			base,
+			false,
+		},
+		{
+			"Mismatched compute instance profile",
+			types.ComputeInstanceLayer{
+				"2g.10gb": {"1c.3g.20gb": 1},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			// Create each GPU instance explicitly, since the mock places
+			// multiple compute instances into a single GPU instance when
+			// going through SetMigConfig.
+			im := manager.(InstanceManager)
+			for _, profile := range []string{"3g.20gb", "2g.10gb", "2g.10gb"} {
+				gi, err := im.CreateGpuInstance(0, profile, nil)
+				require.Nil(t, err, "Unexpected failure from CreateGpuInstance")
+				_, err = im.CreateComputeInstance(0, gi.ID, profile, nil)
+				require.Nil(t, err, "Unexpected failure from CreateComputeInstance")
+			}
+
+			gis, err := im.ListGpuInstances(0)
+			require.Nil(t, err, "Unexpected failure from ListGpuInstances")
+
+			devices, err := manager.SetComputeInstanceConfig(0, tc.layer)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from SetComputeInstanceConfig")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from SetComputeInstanceConfig")
+			require.Len(t, devices, len(tc.expectedConfig.Flatten()))
+
+			config, err := manager.GetMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from GetMigConfig")
+			require.Equal(t, tc.expectedConfig, config)
+			require.Equal(t, tc.layer.Apply(base), config)
+
+			after, err := im.ListGpuInstances(0)
+			require.Nil(t, err, "Unexpected failure from ListGpuInstances")
+			require.ElementsMatch(t, gis, after, "GPU instances changed")
+
+			// Removing the layer again restores a single compute instance per GPU instance.
+			_, err = manager.SetComputeInstanceConfig(0, nil)
+			require.Nil(t, err, "Unexpected failure from SetComputeInstanceConfig")
+
+			config, err = manager.GetMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from GetMigConfig")
+			require.Equal(t, base, config)
+		})
+	}
+}
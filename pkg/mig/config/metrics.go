@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"sync/atomic"
+)
+
+// Metrics counts the work done by this package to place MIG devices since
+// the process started. Configs that only just fit on a GPU show up as many
+// failed permutations and retried NVML calls per apply.
+//   - PlacementAttempts counts the configs PlanMigConfig tried to place.
+//   - PlacementFailures counts those it found no placement for.
+//   - PermutationAttempts counts the orderings of MIG devices that
+//     SetMigConfig tried to create.
+//   - FailedPermutations counts those that failed.
+//   - NvmlRetries counts the NVML calls SetMigConfig retried, e.g. creating
+//     a compute instance in a new GPU instance after the existing one could
+//     not hold it.
+type Metrics struct {
+	PlacementAttempts   uint64 `json:"placement-attempts"`
+	PlacementFailures   uint64 `json:"placement-failures"`
+	PermutationAttempts uint64 `json:"permutation-attempts"`
+	FailedPermutations  uint64 `json:"failed-permutations"`
+	NvmlRetries         uint64 `json:"nvml-retries"`
+}
+
+var metrics struct {
+	placementAttempts   atomic.Uint64
+	placementFailures   atomic.Uint64
+	permutationAttempts atomic.Uint64
+	failedPermutations  atomic.Uint64
+	nvmlRetries         atomic.Uint64
+}
+
+// GetMetrics returns a snapshot of the Metrics of this package.
+func GetMetrics() Metrics {
+	return Metrics{
+		PlacementAttempts:   metrics.placementAttempts.Load(),
+		PlacementFailures:   metrics.placementFailures.Load(),
+		PermutationAttempts: metrics.permutationAttempts.Load(),
+		FailedPermutations:  metrics.failedPermutations.Load(),
+		NvmlRetries:         metrics.nvmlRetries.Load(),
+	}
+}
+
+// Sub returns the Metrics counted between the snapshots 'o' and 'm'.
+func (m Metrics) Sub(o Metrics) Metrics {
+	return Metrics{
+		PlacementAttempts:   m.PlacementAttempts - o.PlacementAttempts,
+		PlacementFailures:   m.PlacementFailures - o.PlacementFailures,
+		PermutationAttempts: m.PermutationAttempts - o.PermutationAttempts,
+		FailedPermutations:  m.FailedPermutations - o.FailedPermutations,
+		NvmlRetries:         m.NvmlRetries - o.NvmlRetries,
+	}
+}
+
+// IsZero checks whether nothing at all was counted in 'm'.
+func (m Metrics) IsZero() bool {
+	return m == Metrics{}
+}
@@ -0,0 +1,52 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestMetrics(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+	r1, r2 := EnableMigMode(manager, 0)
+	require.Equal(t, nvml.SUCCESS, r1)
+	require.Equal(t, nvml.SUCCESS, r2)
+
+	before := GetMetrics()
+
+	_, err := manager.PlanMigConfig(0, types.MigConfig{"3g.20gb": 1, "1g.5gb": 2})
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+	_, err = manager.PlanMigConfig(0, types.MigConfig{"7g.40gb": 1, "1g.5gb": 1})
+	require.NotNil(t, err, "Unexpected success from PlanMigConfig")
+	_, err = manager.SetMigConfig(0, types.MigConfig{"1g.5gb": 7})
+	require.Nil(t, err, "Unexpected failure from SetMigConfig")
+
+	delta := GetMetrics().Sub(before)
+	require.Equal(t, uint64(2), delta.PlacementAttempts)
+	require.Equal(t, uint64(1), delta.PlacementFailures)
+	require.Equal(t, uint64(1), delta.PermutationAttempts)
+	require.Equal(t, uint64(0), delta.FailedPermutations)
+	require.False(t, delta.IsZero())
+}
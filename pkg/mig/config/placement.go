@@ -0,0 +1,364 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// GetMigDevices returns the set of MIG devices currently present on 'gpu',
+// including where each of them is placed on the device. They are returned in
+// the order of types.SortMigDevices, so that the same config always lists its
+// devices in the same order, whatever order NVML walks them in.
+func (m *nvmlMigConfigManager) GetMigDevices(gpu int) ([]types.MigDevice, error) {
+	defer m.lockDevice(gpu)()
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, ret := m.deviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+	}
+
+	deviceMemory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device memory: %w", nvmlerrors.New(ret))
+	}
+
+	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
+	}
+
+	var devices []types.MigDevice
+	err = m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
+		giInfo, ret := gi.GetInfo()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting GPU instance info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		err := m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
+			mp, err := types.NewMigProfile(giProfileID, ciProfileID, ciEngProfileID, giProfileInfo.MemorySizeMB, deviceMemory.Total)
+			if err != nil {
+				return fmt.Errorf("error creating new MIG profile for (%v, %v, %v): %w", giProfileID, ciProfileID, ciEngProfileID, err)
+			}
+			ciInfo, ret := ci.GetInfo()
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting Compute instance info for '%v': %w", mp, nvmlerrors.New(ret))
+			}
+			devices = append(devices, types.MigDevice{
+				Profile:                  mp.String(),
+				GpuInstanceID:            giInfo.Id,
+				GpuInstancePlacement:     giInfo.Placement,
+				ComputeInstanceID:        ciInfo.Id,
+				ComputeInstancePlacement: ciInfo.Placement,
+			})
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("error walking compute instances for '%v': %w", giProfileID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
+	}
+	types.SortMigDevices(devices)
+
+	return devices, nil
+}
+
+// PlanOption is an option that can be passed to PlanMigConfig.
+type PlanOption func(*planOptions)
+
+type planOptions struct {
+	preserved  []types.MigDevice
+	preference types.PlacementPreference
+	exclusions []types.PlacementExclusion
+}
+
+// WithPreservedDevices makes PlanMigConfig keep the GPU instances holding
+// 'devices' (as returned by GetMigDevices) where they are, wherever their
+// compute instances are exactly those of a GPU instance in the config. This
+// lets them be left alone rather than being destroyed and recreated.
+func WithPreservedDevices(devices []types.MigDevice) PlanOption {
+	return func(o *planOptions) {
+		o.preserved = devices
+	}
+}
+
+// WithPlacementPreference makes PlanMigConfig choose between the placements
+// that would work for a GPU instance according to 'preference'.
+func WithPlacementPreference(preference types.PlacementPreference) PlanOption {
+	return func(o *planOptions) {
+		o.preference = preference
+	}
+}
+
+// WithPlacementExclusions makes PlanMigConfig keep the GPU instances of each
+// profile off the slices that 'exclusions' reserve from it. Planning fails if
+// the config cannot be placed on the remaining slices.
+func WithPlacementExclusions(exclusions []types.PlacementExclusion) PlanOption {
+	return func(o *planOptions) {
+		o.exclusions = exclusions
+	}
+}
+
+// PlanMigConfig works out where each MIG device in 'config' would be placed
+// on 'gpu' without making any changes to the device. Only the profile and GPU
+// instance placement of the returned MIG devices are populated.
+func (m *nvmlMigConfigManager) PlanMigConfig(gpu int, config types.MigConfig, opts ...PlanOption) ([]types.MigDevice, error) {
+	defer m.lockDevice(gpu)()
+
+	var o planOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	ret := m.init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer m.shutdown()
+
+	device, ret := m.deviceGetHandleByIndex(gpu)
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+	}
+
+	err := m.nvlib.Mig.Device(device).AssertMigEnabled()
+	if err != nil {
+		return nil, fmt.Errorf("error asserting MIG enabled: %w", err)
+	}
+
+	profiles := make([]string, 0, len(config))
+	for profile, count := range config {
+		if count > 0 {
+			profiles = append(profiles, profile)
+		}
+	}
+	sort.Strings(profiles)
+
+	// Each GPU instance is planned along with the profiles of the compute
+	// instances it will hold.
+	var required [][]nvml.GpuInstancePlacement
+	var contents [][]string
+	for _, profile := range profiles {
+		mp, err := types.ParseMigProfile(profile)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing profile '%v': %w", profile, err)
+		}
+
+		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(mp.GIProfileID)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		placements, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", mp, nvmlerrors.New(ret))
+		}
+		placements = excludePlacements(placements, o.exclusions, profile)
+
+		remaining := config[profile]
+		numGIs := numGpuInstancesRequired(mp, remaining)
+		for i := 0; i < numGIs; i++ {
+			perGI := (remaining + numGIs - i - 1) / (numGIs - i)
+			var cis []string
+			for j := 0; j < perGI; j++ {
+				cis = append(cis, profile)
+			}
+			remaining -= perGI
+			required = append(required, placements)
+			contents = append(contents, cis)
+		}
+	}
+
+	metrics.placementAttempts.Add(1)
+	chosen, ok := placePreservingGpuInstances(required, contents, o.preserved, o.preference)
+	if !ok {
+		metrics.placementFailures.Add(1)
+		if err := explainUnplaceableConfig(device, config); err != nil {
+			return nil, fmt.Errorf("unable to place all MIG devices in config on GPU %d: %w", gpu, err)
+		}
+		return nil, fmt.Errorf("unable to place all MIG devices in config on GPU %d", gpu)
+	}
+
+	var devices []types.MigDevice
+	for i, cis := range contents {
+		for _, profile := range cis {
+			devices = append(devices, types.MigDevice{
+				Profile:              profile,
+				GpuInstancePlacement: chosen[i],
+			})
+		}
+	}
+
+	return devices, nil
+}
+
+// placePreservingGpuInstances places the GPU instances in 'required' (each
+// holding the compute instances in the same entry of 'contents') like
+// placeGpuInstances, except that it keeps the GPU instances of 'preserved'
+// in place where they hold exactly the same compute instances. They are
+// considered in order of placement, and each is only kept if all other GPU
+// instances can still be placed around it. The remaining GPU instances are
+// placed according to 'preference'.
+func placePreservingGpuInstances(required [][]nvml.GpuInstancePlacement, contents [][]string, preserved []types.MigDevice, preference types.PlacementPreference) ([]nvml.GpuInstancePlacement, bool) {
+	type existing struct {
+		placement nvml.GpuInstancePlacement
+		profiles  []string
+	}
+	byID := make(map[uint32]*existing)
+	var gis []*existing
+	for _, d := range preserved {
+		gi, ok := byID[d.GpuInstanceID]
+		if !ok {
+			gi = &existing{placement: d.GpuInstancePlacement}
+			byID[d.GpuInstanceID] = gi
+			gis = append(gis, gi)
+		}
+		gi.profiles = append(gi.profiles, d.Profile)
+	}
+	sort.Slice(gis, func(i, j int) bool { return gis[i].placement.Start < gis[j].placement.Start })
+
+	pinned := make([][]nvml.GpuInstancePlacement, len(required))
+	copy(pinned, required)
+	kept := make([]bool, len(required))
+	for _, gi := range gis {
+		// A GPU instance without a known placement cannot be kept.
+		if gi.placement.Size == 0 {
+			continue
+		}
+		sort.Strings(gi.profiles)
+		for i := range required {
+			if kept[i] || !sameProfiles(contents[i], gi.profiles) || !containsPlacement(required[i], gi.placement) {
+				continue
+			}
+			pinned[i] = []nvml.GpuInstancePlacement{gi.placement}
+			if canPlaceGpuInstances(pinned) {
+				kept[i] = true
+				break
+			}
+			pinned[i] = required[i]
+		}
+	}
+
+	return placeGpuInstances(pinned, preference)
+}
+
+// orderPlacements returns 'placements' in the order they should be tried for
+// a GPU instance, given the slices already 'used' by other GPU instances.
+// Slices below 'half' make up the lower half of the device.
+func orderPlacements(placements []nvml.GpuInstancePlacement, used uint64, half uint32, preference types.PlacementPreference) []nvml.GpuInstancePlacement {
+	switch preference {
+	case types.PlacementHighFirst:
+		ordered := append([]nvml.GpuInstancePlacement(nil), placements...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			return ordered[i].Start > ordered[j].Start
+		})
+		return ordered
+	case types.PlacementBalanced:
+		// Prefer the half of the device with the fewest slices in use,
+		// and the lowest free slices within it.
+		lower := uint64(1)<<half - 1
+		load := func(p nvml.GpuInstancePlacement) int {
+			if p.Start < half {
+				return bits.OnesCount64(used & lower)
+			}
+			return bits.OnesCount64(used &^ lower)
+		}
+		ordered := append([]nvml.GpuInstancePlacement(nil), placements...)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			if load(ordered[i]) != load(ordered[j]) {
+				return load(ordered[i]) < load(ordered[j])
+			}
+			return ordered[i].Start < ordered[j].Start
+		})
+		return ordered
+	}
+	return placements
+}
+
+// placementSpan returns the number of slices covered by the placements in
+// 'required'.
+func placementSpan(required [][]nvml.GpuInstancePlacement) uint32 {
+	var span uint32
+	for _, placements := range required {
+		for _, p := range placements {
+			if p.Start+p.Size > span {
+				span = p.Start + p.Size
+			}
+		}
+	}
+	return span
+}
+
+// excludePlacements returns the placements in 'placements' that do not
+// overlap any of the slices 'exclusions' reserve from 'profile'.
+func excludePlacements(placements []nvml.GpuInstancePlacement, exclusions []types.PlacementExclusion, profile string) []nvml.GpuInstancePlacement {
+	var reserved uint64
+	for _, e := range exclusions {
+		if e.Excludes(profile) {
+			reserved |= e.Mask()
+		}
+	}
+	if reserved == 0 {
+		return placements
+	}
+
+	var allowed []nvml.GpuInstancePlacement
+	for _, p := range placements {
+		if placementMask(p)&reserved == 0 {
+			allowed = append(allowed, p)
+		}
+	}
+	return allowed
+}
+
+// sameProfiles checks whether 'a' and the sorted 'b' hold the same profiles.
+func sameProfiles(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	sorted := append([]string(nil), a...)
+	sort.Strings(sorted)
+	for i := range sorted {
+		if sorted[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsPlacement(placements []nvml.GpuInstancePlacement, p nvml.GpuInstancePlacement) bool {
+	for _, q := range placements {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
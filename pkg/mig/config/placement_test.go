@@ -0,0 +1,306 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestPlanMigConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description     string
+		config          types.MigConfig
+		expected        []types.MigDevice
+		expectedFailure bool
+	}{
+		{
+			"Empty config",
+			types.MigConfig{},
+			nil,
+			false,
+		},
+		{
+			"Mixed profiles",
+			types.MigConfig{
+				"3g.20gb": 1,
+				"1g.5gb":  2,
+			},
+			[]types.MigDevice{
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 1}},
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 5, Size: 1}},
+				{Profile: "3g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 4}},
+			},
+			false,
+		},
+		{
+			"Shared GPU instance",
+			types.MigConfig{
+				"1c.4g.20gb": 2,
+			},
+			[]types.MigDevice{
+				{Profile: "1c.4g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 4}},
+				{Profile: "1c.4g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 4}},
+			},
+			false,
+		},
+		{
+			"Config does not fit",
+			types.MigConfig{
+				"7g.40gb": 1,
+				"1g.5gb":  1,
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			devices, err := manager.PlanMigConfig(0, tc.config)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from PlanMigConfig")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+			require.Equal(t, tc.expected, devices)
+		})
+	}
+}
+
+func TestPlanMigConfigWithPlacementPreference(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		preference types.PlacementPreference
+		expected   []uint32
+	}{
+		{"", []uint32{0, 1, 2, 3}},
+		{types.PlacementPacked, []uint32{0, 1, 2, 3}},
+		{types.PlacementBalanced, []uint32{0, 4, 1, 5}},
+		{types.PlacementHighFirst, []uint32{6, 5, 4, 3}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.preference), func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			devices, err := manager.PlanMigConfig(0, types.MigConfig{"1g.5gb": 4}, WithPlacementPreference(tc.preference))
+			require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+
+			var starts []uint32
+			for _, d := range devices {
+				starts = append(starts, d.GpuInstancePlacement.Start)
+			}
+			require.Equal(t, tc.expected, starts)
+		})
+	}
+}
+
+func TestPlanMigConfigWithPlacementExclusions(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description string
+		config      types.MigConfig
+		exclusions  []types.PlacementExclusion
+		expected    []uint32
+	}{
+		{
+			"Slices reserved from profile",
+			types.MigConfig{"1g.5gb": 4},
+			[]types.PlacementExclusion{{Slices: "0-1", Profiles: []string{"1g.5gb"}}},
+			[]uint32{2, 3, 4, 5},
+		},
+		{
+			"Slices reserved from other profile",
+			types.MigConfig{"1g.5gb": 4},
+			[]types.PlacementExclusion{{Slices: "0-1", Profiles: []string{"2g.10gb"}}},
+			[]uint32{0, 1, 2, 3},
+		},
+		{
+			"Not enough slices left",
+			types.MigConfig{"1g.5gb": 7},
+			[]types.PlacementExclusion{{Slices: "0-1", Profiles: []string{"1g.5gb"}}},
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			devices, err := manager.PlanMigConfig(0, tc.config, WithPlacementExclusions(tc.exclusions))
+			if tc.expected == nil {
+				require.NotNil(t, err, "Unexpected success from PlanMigConfig")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+
+			var starts []uint32
+			for _, d := range devices {
+				starts = append(starts, d.GpuInstancePlacement.Start)
+			}
+			require.Equal(t, tc.expected, starts)
+		})
+	}
+}
+
+func TestPlanMigConfigWithPreservedDevices(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	var all1g []types.MigDevice
+	for i := uint32(0); i < 7; i++ {
+		all1g = append(all1g, types.MigDevice{
+			Profile:              "1g.5gb",
+			GpuInstanceID:        i + 7,
+			GpuInstancePlacement: nvml.GpuInstancePlacement{Start: i, Size: 1},
+		})
+	}
+
+	testCases := []struct {
+		description string
+		preserved   []types.MigDevice
+		config      types.MigConfig
+		expected    []types.MigDevice
+	}{
+		{
+			"Shrink keeps lowest matching GPU instances",
+			all1g,
+			types.MigConfig{
+				"3g.20gb": 1,
+				"1g.5gb":  3,
+			},
+			[]types.MigDevice{
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 1}},
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 1, Size: 1}},
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 2, Size: 1}},
+				{Profile: "3g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 4}},
+			},
+		},
+		{
+			"Keeps only GPU instances not in the way",
+			all1g,
+			types.MigConfig{
+				"4g.20gb": 1,
+				"1g.5gb":  3,
+			},
+			[]types.MigDevice{
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 1}},
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 5, Size: 1}},
+				{Profile: "1g.5gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 6, Size: 1}},
+				{Profile: "4g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 4}},
+			},
+		},
+		{
+			"Compute instances must match",
+			[]types.MigDevice{
+				{Profile: "1c.3g.20gb", GpuInstanceID: 1, GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 4, Size: 4}},
+			},
+			types.MigConfig{
+				"3g.20gb": 1,
+			},
+			[]types.MigDevice{
+				{Profile: "3g.20gb", GpuInstancePlacement: nvml.GpuInstancePlacement{Start: 0, Size: 4}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockLunaServerMigConfigManager()
+
+			r1, r2 := EnableMigMode(manager, 0)
+			require.Equal(t, nvml.SUCCESS, r1)
+			require.Equal(t, nvml.SUCCESS, r2)
+
+			devices, err := manager.PlanMigConfig(0, tc.config, WithPreservedDevices(tc.preserved))
+			require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+			require.Equal(t, tc.expected, devices)
+		})
+	}
+}
+
+func TestGetMigDevices(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	manager := NewMockLunaServerMigConfigManager()
+
+	r1, r2 := EnableMigMode(manager, 0)
+	require.Equal(t, nvml.SUCCESS, r1)
+	require.Equal(t, nvml.SUCCESS, r2)
+
+	config := types.MigConfig{
+		"3g.20gb": 1,
+		"1g.5gb":  2,
+	}
+	_, err := manager.SetMigConfig(0, config)
+	require.Nil(t, err, "Unexpected failure from SetMigConfig")
+
+	devices, err := manager.GetMigDevices(0)
+	require.Nil(t, err, "Unexpected failure from GetMigDevices")
+
+	found := types.MigConfig{}
+	for _, d := range devices {
+		found[d.Profile]++
+	}
+	require.Equal(t, config, found)
+}
+
+func BenchmarkPlanMigConfig(b *testing.B) {
+	types.SetMockNVdevlib()
+
+	for name, mc := range worstCaseMigConfigs(b) {
+		mc := mc
+		b.Run(name, func(b *testing.B) {
+			server := testutil.NewServerBuilder().
+				WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+				MustBuild()
+			manager := NewMockNvmlMigConfigManager(server)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := manager.PlanMigConfig(0, mc)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
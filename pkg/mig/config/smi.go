@@ -0,0 +1,305 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/internal/smi"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+var (
+	smiCreatedGpuInstanceRegexp     = regexp.MustCompile(`created GPU instance ID\s+(\d+)`)
+	smiCreatedComputeInstanceRegexp = regexp.MustCompile(`created compute instance ID\s+(\d+)`)
+)
+
+type smiMigConfigManager struct {
+	smi smi.Interface
+}
+
+var _ Manager = (*smiMigConfigManager)(nil)
+
+// smiInstance is a single row of the GPU or compute instance tables printed
+// by 'nvidia-smi mig -lgi' and 'nvidia-smi mig -lci'.
+type smiInstance struct {
+	GpuInstanceID uint32
+	ID            uint32
+	Profile       string
+	Start         uint32
+	Size          uint32
+}
+
+// NewSmiMigConfigManager returns a Manager that shells out to nvidia-smi
+// instead of calling into NVML.
+func NewSmiMigConfigManager() Manager {
+	return &smiMigConfigManager{smi.New()}
+}
+
+func NewMockSmiMigConfigManager(s smi.Interface) Manager {
+	return &smiMigConfigManager{s}
+}
+
+func (m *smiMigConfigManager) GetMigConfig(gpu int) (types.MigConfig, error) {
+	cis, err := m.listInstances(gpu, "-lci")
+	if err != nil {
+		return nil, fmt.Errorf("error listing compute instances: %w", err)
+	}
+
+	migConfig := types.MigConfig{}
+	for _, ci := range cis {
+		migConfig[ci.Profile]++
+	}
+
+	return migConfig, nil
+}
+
+// SetMigConfig applies 'config' to 'gpu' and returns the set of MIG devices that were created.
+// Different orderings of the MIG devices in 'config' are tried until one succeeds or the
+// PermutationBudget passed via 'opts' (unbounded by default) runs out.
+func (m *smiMigConfigManager) SetMigConfig(gpu int, config types.MigConfig, opts ...SetOption) ([]types.MigDevice, error) {
+	var budget PermutationBudget
+	for _, opt := range opts {
+		opt(&budget)
+	}
+
+	err := iteratePermutationsUntilSuccess(config, budget, func(mps []*types.MigProfile) error {
+		err := m.ClearMigConfig(gpu)
+		if err != nil {
+			return fmt.Errorf("error clearing MigConfig: %w", err)
+		}
+
+		lastGIProfileID := -1
+		giID := -1
+		for _, mp := range mps {
+			reuseGI := (giID >= 0) && (lastGIProfileID == mp.GIProfileID)
+			lastGIProfileID = mp.GIProfileID
+
+			for {
+				if !reuseGI {
+					giID, err = m.createGpuInstance(gpu, mp.GIProfileID)
+					if err != nil {
+						return fmt.Errorf("error creating GPU instance for '%v': %w", mp, err)
+					}
+				}
+
+				err = m.createComputeInstance(gpu, giID, mp.CIProfileID)
+				if err != nil {
+					if reuseGI {
+						reuseGI = false
+						continue
+					}
+					return fmt.Errorf("error creating Compute instance for '%v': %w", mp, err)
+				}
+
+				break
+			}
+		}
+
+		created, err := m.GetMigConfig(gpu)
+		if err != nil {
+			return fmt.Errorf("error getting created MigConfig: %w", err)
+		}
+		if !created.Equals(config) {
+			return fmt.Errorf("created MigConfig %v does not match requested MigConfig %v", created, config)
+		}
+
+		return nil
+	})
+	if err != nil {
+		e := m.ClearMigConfig(gpu)
+		if e != nil {
+			log.Errorf("Error clearing MIG config on GPU %d, erroneous devices may persist", gpu)
+		}
+		return nil, fmt.Errorf("error attempting multiple config orderings: %w", err)
+	}
+
+	return m.GetMigDevices(gpu)
+}
+
+func (m *smiMigConfigManager) ClearMigConfig(gpu int) error {
+	cis, err := m.listInstances(gpu, "-lci")
+	if err != nil {
+		return fmt.Errorf("error listing compute instances: %w", err)
+	}
+	if len(cis) > 0 {
+		_, err := m.smi.Run("mig", "-i", strconv.Itoa(gpu), "-dci")
+		if err != nil {
+			return fmt.Errorf("error destroying compute instances: %w", err)
+		}
+	}
+
+	gis, err := m.listInstances(gpu, "-lgi")
+	if err != nil {
+		return fmt.Errorf("error listing GPU instances: %w", err)
+	}
+	if len(gis) > 0 {
+		_, err := m.smi.Run("mig", "-i", strconv.Itoa(gpu), "-dgi")
+		if err != nil {
+			return fmt.Errorf("error destroying GPU instances: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// FillMigConfig needs the GPU instance placement rules, which nvidia-smi does not expose in a parseable form.
+func (m *smiMigConfigManager) FillMigConfig(gpu int, config types.MigConfig, fill string) (types.MigConfig, error) {
+	return nil, fmt.Errorf("filling a MigConfig with nvidia-smi: %w", nvmlerrors.ErrNotSupported)
+}
+
+func (m *smiMigConfigManager) GetMigDevices(gpu int) ([]types.MigDevice, error) {
+	gis, err := m.listInstances(gpu, "-lgi")
+	if err != nil {
+		return nil, fmt.Errorf("error listing GPU instances: %w", err)
+	}
+
+	cis, err := m.listInstances(gpu, "-lci")
+	if err != nil {
+		return nil, fmt.Errorf("error listing compute instances: %w", err)
+	}
+
+	giPlacements := make(map[uint32]nvml.GpuInstancePlacement)
+	for _, gi := range gis {
+		giPlacements[gi.ID] = nvml.GpuInstancePlacement{Start: gi.Start, Size: gi.Size}
+	}
+
+	var devices []types.MigDevice
+	for _, ci := range cis {
+		devices = append(devices, types.MigDevice{
+			Profile:                  ci.Profile,
+			GpuInstanceID:            ci.GpuInstanceID,
+			GpuInstancePlacement:     giPlacements[ci.GpuInstanceID],
+			ComputeInstanceID:        ci.ID,
+			ComputeInstancePlacement: nvml.ComputeInstancePlacement{Start: ci.Start, Size: ci.Size},
+		})
+	}
+	types.SortMigDevices(devices)
+
+	return devices, nil
+}
+
+// PlanMigConfig needs the GPU instance placement rules, which nvidia-smi does not expose in a parseable form.
+func (m *smiMigConfigManager) PlanMigConfig(gpu int, config types.MigConfig, opts ...PlanOption) ([]types.MigDevice, error) {
+	return nil, fmt.Errorf("planning a MigConfig with nvidia-smi: %w", nvmlerrors.ErrNotSupported)
+}
+
+// SetComputeInstanceConfig is only supported through NVML.
+func (m *smiMigConfigManager) SetComputeInstanceConfig(gpu int, layer types.ComputeInstanceLayer) ([]types.MigDevice, error) {
+	return nil, fmt.Errorf("setting compute instances with nvidia-smi: %w", nvmlerrors.ErrNotSupported)
+}
+
+// listInstances parses the table printed by 'nvidia-smi mig -i <gpu> <flag>',
+// where 'flag' is either '-lgi' or '-lci'. The columns of both tables end in
+// 'Name Profile-ID Instance-ID Start:Size'; the compute instance table is
+// additionally prefixed with the ID of the GPU instance each row belongs to.
+func (m *smiMigConfigManager) listInstances(gpu int, flag string) ([]smiInstance, error) {
+	output, err := m.smi.Run("mig", "-i", strconv.Itoa(gpu), flag)
+	if err != nil {
+		if smi.IsNotFound(output) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var instances []smiInstance
+	for _, row := range smi.ParseTable(output) {
+		n := len(row)
+		if n < 5 {
+			return nil, fmt.Errorf("unexpected row in nvidia-smi output: %v", row)
+		}
+
+		mp, err := types.ParseMigProfile(row[n-4])
+		if err != nil {
+			return nil, fmt.Errorf("error parsing profile '%v': %w", row[n-4], err)
+		}
+
+		id, err := strconv.ParseUint(row[n-2], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing instance ID '%v': %w", row[n-2], err)
+		}
+
+		start, size, found := strings.Cut(row[n-1], ":")
+		if !found {
+			return nil, fmt.Errorf("error parsing placement '%v'", row[n-1])
+		}
+		placementStart, err := strconv.ParseUint(start, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing placement '%v': %w", row[n-1], err)
+		}
+		placementSize, err := strconv.ParseUint(size, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing placement '%v': %w", row[n-1], err)
+		}
+
+		instance := smiInstance{
+			ID:      uint32(id),
+			Profile: mp.String(),
+			Start:   uint32(placementStart),
+			Size:    uint32(placementSize),
+		}
+
+		if flag == "-lci" {
+			giID, err := strconv.ParseUint(row[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("error parsing GPU instance ID '%v': %w", row[1], err)
+			}
+			instance.GpuInstanceID = uint32(giID)
+		} else {
+			instance.GpuInstanceID = uint32(id)
+		}
+
+		instances = append(instances, instance)
+	}
+
+	return instances, nil
+}
+
+func (m *smiMigConfigManager) createGpuInstance(gpu int, giProfileID int) (int, error) {
+	output, err := m.smi.Run("mig", "-i", strconv.Itoa(gpu), "-cgi", strconv.Itoa(giProfileID))
+	if err != nil {
+		return -1, err
+	}
+
+	match := smiCreatedGpuInstanceRegexp.FindStringSubmatch(output)
+	if match == nil {
+		return -1, fmt.Errorf("unexpected output from nvidia-smi: %q", output)
+	}
+
+	return strconv.Atoi(match[1])
+}
+
+func (m *smiMigConfigManager) createComputeInstance(gpu int, giID int, ciProfileID int) error {
+	output, err := m.smi.Run("mig", "-i", strconv.Itoa(gpu), "-gi", strconv.Itoa(giID), "-cci", strconv.Itoa(ciProfileID))
+	if err != nil {
+		return err
+	}
+
+	if !smiCreatedComputeInstanceRegexp.MatchString(output) {
+		return fmt.Errorf("unexpected output from nvidia-smi: %q", output)
+	}
+
+	return nil
+}
@@ -0,0 +1,168 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// mockSmi emulates just enough of 'nvidia-smi mig' on a single GPU to
+// exercise the smiMigConfigManager.
+type mockSmi struct {
+	giNames  map[int]string
+	ciNames  map[[2]int]string
+	gis      []smiInstance
+	cis      []smiInstance
+	giProfID map[uint32]int
+	nextID   uint32
+}
+
+func newMockSmi(profiles ...string) *mockSmi {
+	m := &mockSmi{
+		giNames:  make(map[int]string),
+		ciNames:  make(map[[2]int]string),
+		giProfID: make(map[uint32]int),
+	}
+	for _, p := range profiles {
+		mp := types.MustParseMigProfile(p)
+		if mp.C == mp.G {
+			m.giNames[mp.GIProfileID] = p
+		}
+		m.ciNames[[2]int{mp.GIProfileID, mp.CIProfileID}] = p
+	}
+	return m
+}
+
+func (m *mockSmi) Run(args ...string) (string, error) {
+	cmd := strings.Join(args, " ")
+	switch {
+	case strings.HasSuffix(cmd, "-lgi"):
+		if len(m.gis) == 0 {
+			return "No GPU instances found: Not Found\n", fmt.Errorf("exit status 6")
+		}
+		var b strings.Builder
+		b.WriteString("| GPU   Name   Profile  Instance   Placement  |\n|=======|\n")
+		for _, gi := range m.gis {
+			fmt.Fprintf(&b, "|   0  MIG %s  %d  %d  %d:%d |\n", gi.Profile, m.giProfID[gi.ID], gi.ID, gi.Start, gi.Size)
+		}
+		return b.String(), nil
+	case strings.HasSuffix(cmd, "-lci"):
+		if len(m.cis) == 0 {
+			return "No compute instances found: Not Found\n", fmt.Errorf("exit status 6")
+		}
+		var b strings.Builder
+		b.WriteString("| GPU  GPU-Instance  Name   Profile  Instance   Placement  |\n|=======|\n")
+		for _, ci := range m.cis {
+			fmt.Fprintf(&b, "|   0  %d  MIG %s  0  %d  %d:%d |\n", ci.GpuInstanceID, ci.Profile, ci.ID, ci.Start, ci.Size)
+		}
+		return b.String(), nil
+	case strings.HasSuffix(cmd, "-dci"):
+		m.cis = nil
+		return "Successfully destroyed compute instances\n", nil
+	case strings.HasSuffix(cmd, "-dgi"):
+		m.gis = nil
+		return "Successfully destroyed GPU instances\n", nil
+	case len(args) == 5 && args[3] == "-cgi":
+		profileID, _ := strconv.Atoi(args[4])
+		name, ok := m.giNames[profileID]
+		if !ok {
+			return "Unable to create a GPU instance: Not Supported\n", fmt.Errorf("exit status 3")
+		}
+		m.nextID++
+		m.gis = append(m.gis, smiInstance{ID: m.nextID, GpuInstanceID: m.nextID, Profile: name, Size: 1})
+		m.giProfID[m.nextID] = profileID
+		return fmt.Sprintf("Successfully created GPU instance ID %2d on GPU  0 using profile MIG %s (ID %2d)\n", m.nextID, name, profileID), nil
+	case len(args) == 7 && args[5] == "-cci":
+		giID, _ := strconv.Atoi(args[4])
+		ciProfileID, _ := strconv.Atoi(args[6])
+		name, ok := m.ciNames[[2]int{m.giProfID[uint32(giID)], ciProfileID}]
+		if !ok {
+			return "Unable to create a compute instance: Not Supported\n", fmt.Errorf("exit status 3")
+		}
+		m.cis = append(m.cis, smiInstance{ID: uint32(len(m.cis)), GpuInstanceID: uint32(giID), Profile: name, Size: 1})
+		return fmt.Sprintf("Successfully created compute instance ID  0 on GPU  0 GPU instance ID %2d using profile MIG %s (ID  0)\n", giID, name), nil
+	}
+	return "", fmt.Errorf("unexpected command: nvidia-smi %v", cmd)
+}
+
+func TestSmiGetSetMigConfig(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	testCases := []struct {
+		description string
+		config      types.MigConfig
+	}{
+		{
+			"Empty",
+			types.MigConfig{},
+		},
+		{
+			"Single profile",
+			types.MigConfig{"3g.20gb": 2},
+		},
+		{
+			"Mixed profiles",
+			types.MigConfig{"3g.20gb": 1, "1g.5gb": 2},
+		},
+		{
+			"Shared GPU instance",
+			types.MigConfig{"1c.4g.20gb": 2, "3g.20gb": 1},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			smi := newMockSmi("1g.5gb", "3g.20gb", "4g.20gb", "1c.4g.20gb")
+			manager := NewMockSmiMigConfigManager(smi)
+
+			devices, err := manager.SetMigConfig(0, tc.config)
+			require.Nil(t, err, "Unexpected failure from SetMigConfig")
+			require.Len(t, devices, len(tc.config.Flatten()))
+
+			config, err := manager.GetMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from GetMigConfig")
+			require.True(t, tc.config.Equals(config), "%v != %v", tc.config, config)
+
+			if _, ok := tc.config["1c.4g.20gb"]; ok {
+				require.Len(t, smi.gis, 2, "Expected compute instances to share a GPU instance")
+			}
+
+			err = manager.ClearMigConfig(0)
+			require.Nil(t, err, "Unexpected failure from ClearMigConfig")
+			require.Len(t, smi.gis, 0)
+			require.Len(t, smi.cis, 0)
+		})
+	}
+}
+
+func TestSmiSetMigConfigUnsupportedProfile(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	smi := newMockSmi("1g.5gb")
+	manager := NewMockSmiMigConfigManager(smi)
+
+	_, err := manager.SetMigConfig(0, types.MigConfig{"1g.5gb": 1, "3g.20gb": 1})
+	require.NotNil(t, err, "Unexpected success from SetMigConfig")
+	require.Len(t, smi.gis, 0, "Expected GPU instances to be cleared after failure")
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+type vgpuGuestMigConfigManager struct {
+	Manager
+}
+
+var _ Manager = (*vgpuGuestMigConfigManager)(nil)
+
+// NewVGPUGuestMigConfigManager wraps 'm' for use inside a vGPU guest VM. The
+// GPU instances of a MIG-backed vGPU are created by the hypervisor, so only
+// configs matching the existing GPU instances can be "set" (as a no-op) and
+// clearing the config fails with nvmlerrors.ErrVGPUGuest. Compute instances
+// remain under the control of the guest.
+func NewVGPUGuestMigConfigManager(m Manager) Manager {
+	return &vgpuGuestMigConfigManager{m}
+}
+
+func (m *vgpuGuestMigConfigManager) SetMigConfig(gpu int, config types.MigConfig, opts ...SetOption) ([]types.MigDevice, error) {
+	current, err := m.Manager.GetMigConfig(gpu)
+	if err != nil {
+		return nil, err
+	}
+	if !current.Equals(config) {
+		return nil, fmt.Errorf("error setting MIG config on GPU %v: %w", gpu, nvmlerrors.ErrVGPUGuest)
+	}
+	return m.Manager.GetMigDevices(gpu)
+}
+
+func (m *vgpuGuestMigConfigManager) ClearMigConfig(gpu int) error {
+	return fmt.Errorf("error clearing MIG config on GPU %v: %w", gpu, nvmlerrors.ErrVGPUGuest)
+}
+
+type vgpuGuestInstanceManager struct {
+	InstanceManager
+}
+
+var _ InstanceManager = (*vgpuGuestInstanceManager)(nil)
+
+// NewVGPUGuestInstanceManager wraps 'm' for use inside a vGPU guest VM, where
+// GPU instances can be listed but not created or destroyed.
+func NewVGPUGuestInstanceManager(m InstanceManager) InstanceManager {
+	return &vgpuGuestInstanceManager{m}
+}
+
+func (m *vgpuGuestInstanceManager) CreateGpuInstance(gpu int, profile string, start *uint32) (*types.GpuInstance, error) {
+	return nil, fmt.Errorf("error creating GPU instance on GPU %v: %w", gpu, nvmlerrors.ErrVGPUGuest)
+}
+
+func (m *vgpuGuestInstanceManager) DestroyGpuInstance(gpu int, giID uint32) error {
+	return fmt.Errorf("error destroying GPU instance on GPU %v: %w", gpu, nvmlerrors.ErrVGPUGuest)
+}
@@ -0,0 +1,92 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// newVGPUGuestServer returns a server with a single MIG-backed vGPU whose
+// 3g.20gb GPU instance was created by the hypervisor.
+func newVGPUGuestServer(computeInstances ...int) *testutil.Server {
+	return testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			VGPUGuest:  true,
+			GpuInstances: []testutil.GpuInstance{
+				{Profile: nvml.GPU_INSTANCE_PROFILE_3_SLICE, ComputeInstances: computeInstances},
+			},
+		}).
+		MustBuild()
+}
+
+func TestVGPUGuestMigConfigManager(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := newVGPUGuestServer(nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE)
+	manager := NewVGPUGuestMigConfigManager(NewMockNvmlMigConfigManager(server))
+
+	current, err := manager.GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, types.MigConfig{"3g.20gb": 1}, current)
+
+	devices, err := manager.SetMigConfig(0, types.MigConfig{"3g.20gb": 1})
+	require.Nil(t, err, "Setting the current MIG config should be a no-op")
+	require.Len(t, devices, 1)
+
+	_, err = manager.SetMigConfig(0, types.MigConfig{"1g.5gb": 7})
+	require.ErrorIs(t, err, nvmlerrors.ErrVGPUGuest)
+
+	err = manager.ClearMigConfig(0)
+	require.ErrorIs(t, err, nvmlerrors.ErrVGPUGuest)
+
+	current, err = manager.GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, types.MigConfig{"3g.20gb": 1}, current)
+}
+
+func TestVGPUGuestInstanceManager(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := newVGPUGuestServer()
+	im := NewVGPUGuestInstanceManager(NewMockNvmlInstanceManager(server))
+
+	gis, err := im.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Len(t, gis, 1)
+
+	_, err = im.CreateGpuInstance(0, "1g.5gb", nil)
+	require.ErrorIs(t, err, nvmlerrors.ErrVGPUGuest)
+
+	err = im.DestroyGpuInstance(0, gis[0].ID)
+	require.ErrorIs(t, err, nvmlerrors.ErrVGPUGuest)
+
+	ci, err := im.CreateComputeInstance(0, gis[0].ID, "1c.3g.20gb", nil)
+	require.Nil(t, err, "Compute instances should still be managed by the guest")
+
+	err = im.DestroyComputeInstance(0, gis[0].ID, ci.ComputeInstanceID)
+	require.Nil(t, err)
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// DefaultGpuToolsPath is the gpu-admin-tools script run by
+// NewGpuToolsCCModeManager() to query and change the CC mode of a GPU, which
+// neither NVML nor nvidia-smi can do on a per-GPU basis.
+const DefaultGpuToolsPath = "nvidia_gpu_tools.py"
+
+// CCManager queries and changes the CC mode of the GPUs on the node.
+type CCManager interface {
+	IsCCCapable(gpu int) (bool, error)
+	GetCCMode(gpu int) (types.CCMode, error)
+	SetCCMode(gpu int, mode types.CCMode) error
+}
+
+// ccModeRegex matches the CC mode reported by 'nvidia_gpu_tools.py --query-cc-mode'.
+var ccModeRegex = regexp.MustCompile(`CC mode is (\w+)`)
+
+type gpuToolsCCModeManager struct {
+	run    func(args ...string) (string, error)
+	busIDs []string
+}
+
+var _ CCManager = (*gpuToolsCCModeManager)(nil)
+
+// NewGpuToolsCCModeManager returns a CCManager that shells out to the
+// gpu-admin-tools script at 'path' for the GPUs with the PCI bus IDs in
+// 'busIDs' (indexed like every other GPU index).
+func NewGpuToolsCCModeManager(path string, busIDs []string) CCManager {
+	run := func(args ...string) (string, error) {
+		cmd := exec.Command(path, args...) //nolint:gosec
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			return string(output), fmt.Errorf("error running '%v %v': %v: %v", path, strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+		}
+		return string(output), nil
+	}
+	return NewMockGpuToolsCCModeManager(run, busIDs)
+}
+
+func NewMockGpuToolsCCModeManager(run func(args ...string) (string, error), busIDs []string) CCManager {
+	return &gpuToolsCCModeManager{run, busIDs}
+}
+
+func (m *gpuToolsCCModeManager) busID(gpu int) (string, error) {
+	if gpu < 0 || gpu >= len(m.busIDs) {
+		return "", fmt.Errorf("no PCI bus ID for GPU %v", gpu)
+	}
+	return m.busIDs[gpu], nil
+}
+
+// queryCCMode returns the CC mode of 'gpu', or an empty mode if the script
+// does not report one (i.e. the GPU is not CC capable).
+func (m *gpuToolsCCModeManager) queryCCMode(gpu int) (types.CCMode, error) {
+	busID, err := m.busID(gpu)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := m.run("--gpu-bdf="+busID, "--query-cc-mode")
+	match := ccModeRegex.FindStringSubmatch(output)
+	if match == nil {
+		if err != nil && !strings.Contains(output, "not support") {
+			return "", err
+		}
+		return "", nil
+	}
+
+	mode := types.CCMode(strings.ToLower(match[1]))
+	err = mode.AssertValid()
+	if err != nil {
+		return "", fmt.Errorf("unexpected output from gpu-admin-tools: %v", err)
+	}
+	return mode, nil
+}
+
+func (m *gpuToolsCCModeManager) IsCCCapable(gpu int) (bool, error) {
+	mode, err := m.queryCCMode(gpu)
+	if err != nil {
+		return false, fmt.Errorf("error querying CC mode: %w", err)
+	}
+	return mode != "", nil
+}
+
+func (m *gpuToolsCCModeManager) GetCCMode(gpu int) (types.CCMode, error) {
+	mode, err := m.queryCCMode(gpu)
+	if err != nil {
+		return "", fmt.Errorf("error querying CC mode: %w", err)
+	}
+	if mode == "" {
+		return "", fmt.Errorf("error getting CC mode: GPU %v is not CC capable: %w", gpu, nvmlerrors.ErrNotSupported)
+	}
+	return mode, nil
+}
+
+// SetCCMode sets the CC mode of 'gpu' to 'mode', resetting the GPU for the
+// change to take effect.
+func (m *gpuToolsCCModeManager) SetCCMode(gpu int, mode types.CCMode) error {
+	err := mode.AssertValid()
+	if err != nil {
+		return err
+	}
+
+	busID, err := m.busID(gpu)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.run("--gpu-bdf="+busID, "--set-cc-mode="+string(mode), "--reset-after-cc-mode-switch")
+	if err != nil {
+		return fmt.Errorf("error setting CC mode: %w", err)
+	}
+
+	return nil
+}
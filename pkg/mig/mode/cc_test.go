@@ -0,0 +1,118 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// mockGpuTools returns a canned CC mode query result for the GPU at
+// 0000:45:00.0 and records the last CC mode that was set.
+type mockGpuTools struct {
+	query    string
+	queryErr error
+	set      string
+}
+
+func (m *mockGpuTools) Run(args ...string) (string, error) {
+	cmd := strings.Join(args, " ")
+	switch {
+	case cmd == "--gpu-bdf=0000:45:00.0 --query-cc-mode":
+		return m.query, m.queryErr
+	case strings.HasPrefix(cmd, "--gpu-bdf=0000:45:00.0 --set-cc-mode=") && strings.HasSuffix(cmd, " --reset-after-cc-mode-switch"):
+		m.set = strings.TrimPrefix(args[1], "--set-cc-mode=")
+		return "", nil
+	}
+	return "", fmt.Errorf("unexpected command: nvidia_gpu_tools.py %v", cmd)
+}
+
+func TestGpuToolsCCMode(t *testing.T) {
+	testCases := []struct {
+		description     string
+		query           string
+		queryErr        error
+		expectedCapable bool
+		expectedMode    types.CCMode
+	}{
+		{
+			"CC on",
+			"2024-01-02 INFO GPU 0000:45:00.0 H100-PCIE 0x2331 BAR0 0x0 CC mode is on\n",
+			nil,
+			true,
+			types.CCOn,
+		},
+		{
+			"CC devtools",
+			"2024-01-02 INFO GPU 0000:45:00.0 H100-PCIE 0x2331 BAR0 0x0 CC mode is devtools\n",
+			nil,
+			true,
+			types.CCDevTools,
+		},
+		{
+			"Not CC capable",
+			"2024-01-02 ERROR GPU 0000:45:00.0 A100-PCIE-40GB does not support CC\n",
+			errors.New("exit status 1"),
+			false,
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			tools := &mockGpuTools{query: tc.query, queryErr: tc.queryErr}
+			manager := NewMockGpuToolsCCModeManager(tools.Run, []string{"0000:45:00.0"})
+
+			capable, err := manager.IsCCCapable(0)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedCapable, capable)
+
+			mode, err := manager.GetCCMode(0)
+			if !tc.expectedCapable {
+				require.True(t, errors.Is(err, nvmlerrors.ErrNotSupported))
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedMode, mode)
+
+			err = manager.SetCCMode(0, types.CCOff)
+			require.Nil(t, err)
+			require.Equal(t, "off", tools.set)
+		})
+	}
+}
+
+func TestGpuToolsCCModeErrors(t *testing.T) {
+	tools := &mockGpuTools{queryErr: errors.New("no such file or directory")}
+	manager := NewMockGpuToolsCCModeManager(tools.Run, []string{"0000:45:00.0"})
+
+	_, err := manager.IsCCCapable(0)
+	require.NotNil(t, err, "Unexpected success querying CC mode with a failing script")
+
+	_, err = manager.IsCCCapable(1)
+	require.NotNil(t, err, "Unexpected success querying CC mode of an unknown GPU")
+
+	err = manager.SetCCMode(0, "bogus")
+	require.NotNil(t, err, "Unexpected success setting an unknown CC mode")
+}
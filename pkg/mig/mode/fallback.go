@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+type fallbackMigModeManager struct {
+	primary  Manager
+	fallback Manager
+}
+
+var _ Manager = (*fallbackMigModeManager)(nil)
+
+// NewFallbackMigModeManager returns a Manager that runs each operation
+// against 'primary' and retries it against 'fallback' whenever 'primary'
+// reports that the operation is not supported.
+func NewFallbackMigModeManager(primary, fallback Manager) Manager {
+	return &fallbackMigModeManager{primary, fallback}
+}
+
+func (m *fallbackMigModeManager) IsMigCapable(gpu int) (bool, error) {
+	capable, err := m.primary.IsMigCapable(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for IsMigCapable: %v", err)
+		return m.fallback.IsMigCapable(gpu)
+	}
+	return capable, err
+}
+
+func (m *fallbackMigModeManager) GetMigMode(gpu int) (MigMode, error) {
+	mode, err := m.primary.GetMigMode(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for GetMigMode: %v", err)
+		return m.fallback.GetMigMode(gpu)
+	}
+	return mode, err
+}
+
+func (m *fallbackMigModeManager) SetMigMode(gpu int, mode MigMode) error {
+	err := m.primary.SetMigMode(gpu, mode)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for SetMigMode: %v", err)
+		return m.fallback.SetMigMode(gpu, mode)
+	}
+	return err
+}
+
+func (m *fallbackMigModeManager) IsMigModeChangePending(gpu int) (bool, error) {
+	pending, err := m.primary.IsMigModeChangePending(gpu)
+	if nvmlerrors.IsUnsupported(err) {
+		log.Debugf("Falling back to alternate backend for IsMigModeChangePending: %v", err)
+		return m.fallback.IsMigModeChangePending(gpu)
+	}
+	return pending, err
+}
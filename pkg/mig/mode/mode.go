@@ -17,7 +17,7 @@
 package mode
 
 import (
-	"github.com/NVIDIA/mig-parted/internal/nvlib/mig"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib/mig"
 )
 
 type MigMode = mig.Mode
@@ -33,3 +33,25 @@ type Manager interface {
 	SetMigMode(gpu int, mode MigMode) error
 	IsMigModeChangePending(gpu int) (bool, error)
 }
+
+// GetPendingMigMode returns the MIG mode 'gpu' will be in once any pending
+// MIG mode change has taken effect (i.e. after a reboot or GPU reset).
+func GetPendingMigMode(m Manager, gpu int) (MigMode, error) {
+	current, err := m.GetMigMode(gpu)
+	if err != nil {
+		return -1, err
+	}
+
+	pending, err := m.IsMigModeChangePending(gpu)
+	if err != nil {
+		return -1, err
+	}
+	if !pending {
+		return current, nil
+	}
+
+	if current == Enabled {
+		return Disabled, nil
+	}
+	return Enabled, nil
+}
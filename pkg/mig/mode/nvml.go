@@ -22,6 +22,8 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
 )
 
 type nvmlMigModeManager struct {
@@ -48,13 +50,13 @@ func NewMockNvmlMigModeManager(nvml nvml.Interface) Manager {
 func (m *nvmlMigModeManager) IsMigCapable(gpu int) (bool, error) {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error initializing NVML: %v", ret)
+		return false, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error getting device handle: %v", ret)
+		return false, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	_, _, ret = device.GetMigMode()
@@ -62,7 +64,7 @@ func (m *nvmlMigModeManager) IsMigCapable(gpu int) (bool, error) {
 		return false, nil
 	}
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error getting Mig mode: %v", ret)
+		return false, fmt.Errorf("error getting Mig mode: %w", nvmlerrors.New(ret))
 	}
 
 	return true, nil
@@ -71,18 +73,18 @@ func (m *nvmlMigModeManager) IsMigCapable(gpu int) (bool, error) {
 func (m *nvmlMigModeManager) GetMigMode(gpu int) (MigMode, error) {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return -1, fmt.Errorf("error initializing NVML: %v", ret)
+		return -1, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return -1, fmt.Errorf("error getting device handle: %v", ret)
+		return -1, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	current, _, ret := device.GetMigMode()
 	if ret != nvml.SUCCESS {
-		return -1, fmt.Errorf("error getting Mig mode settings: %v", ret)
+		return -1, fmt.Errorf("error getting Mig mode settings: %w", nvmlerrors.New(ret))
 	}
 
 	switch current {
@@ -98,13 +100,13 @@ func (m *nvmlMigModeManager) GetMigMode(gpu int) (MigMode, error) {
 func (m *nvmlMigModeManager) SetMigMode(gpu int, mode MigMode) error {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error initializing NVML: %v", ret)
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error getting device handle: %v", ret)
+		return fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	switch mode {
@@ -116,7 +118,7 @@ func (m *nvmlMigModeManager) SetMigMode(gpu int, mode MigMode) error {
 		return fmt.Errorf("unknown Mig mode selected: %v", mode)
 	}
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error setting Mig mode: %v", ret)
+		return fmt.Errorf("error setting Mig mode: %w", nvmlerrors.New(ret))
 	}
 
 	return nil
@@ -125,18 +127,18 @@ func (m *nvmlMigModeManager) SetMigMode(gpu int, mode MigMode) error {
 func (m *nvmlMigModeManager) IsMigModeChangePending(gpu int) (bool, error) {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error initializing NVML: %v", ret)
+		return false, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error getting device handle: %v", ret)
+		return false, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 	}
 
 	current, pending, ret := device.GetMigMode()
 	if ret != nvml.SUCCESS {
-		return false, fmt.Errorf("error getting Mig mode settings: %v", ret)
+		return false, fmt.Errorf("error getting Mig mode settings: %w", nvmlerrors.New(ret))
 	}
 
 	if current == pending {
@@ -23,7 +23,7 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
-	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
 )
 
 type Return = nvml.Return
@@ -37,10 +37,10 @@ type mockNvmlA100Device struct {
 }
 
 func NewMockNvmlLunaServer() *nvmlMigModeManager {
-	mls := dgxa100.New()
+	mls := testutil.NewDGXA100()
 	for i := 0; i < 8; i++ {
 		mls.Devices[i] = &mockNvmlA100Device{
-			Device:         dgxa100.NewDevice(i),
+			Device:         mls.Devices[i],
 			migCapable:     true,
 			driverBusy:     false,
 			currentMigMode: nvml.DEVICE_MIG_DISABLE,
@@ -83,7 +83,7 @@ func TestNvmlIsMigCapable(t *testing.T) {
 			require.Nil(t, err, "Unexpected failure from IsMigCapable")
 			require.True(t, capable)
 
-			server := manager.nvml.(*dgxa100.Server)
+			server := manager.nvml.(*testutil.Server)
 			device := server.Devices[i].(*mockNvmlA100Device)
 			device.migCapable = false
 
@@ -117,7 +117,7 @@ func TestNvmlEnableDisableMig(t *testing.T) {
 			require.Nil(t, err, "Unexpected failure from GetMigMode")
 			require.Equal(t, Disabled, mode)
 
-			server := manager.nvml.(*dgxa100.Server)
+			server := manager.nvml.(*testutil.Server)
 			device := server.Devices[i].(*mockNvmlA100Device)
 			device.driverBusy = true
 
@@ -134,3 +134,25 @@ func TestNvmlEnableDisableMig(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPendingMigMode(t *testing.T) {
+	manager := NewMockNvmlLunaServer()
+	server := manager.nvml.(*testutil.Server)
+	device := server.Devices[0].(*mockNvmlA100Device)
+
+	pending, err := GetPendingMigMode(manager, 0)
+	require.Nil(t, err, "Unexpected failure from GetPendingMigMode")
+	require.Equal(t, Disabled, pending)
+
+	device.driverBusy = true
+	err = manager.SetMigMode(0, Enabled)
+	require.Nil(t, err, "Unexpected failure from SetMigMode")
+
+	current, err := manager.GetMigMode(0)
+	require.Nil(t, err, "Unexpected failure from GetMigMode")
+	require.Equal(t, Disabled, current)
+
+	pending, err = GetPendingMigMode(manager, 0)
+	require.Nil(t, err, "Unexpected failure from GetPendingMigMode")
+	require.Equal(t, Enabled, pending)
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/NVIDIA/mig-parted/internal/smi"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+type smiMigModeManager struct {
+	smi smi.Interface
+}
+
+var _ Manager = (*smiMigModeManager)(nil)
+
+// NewSmiMigModeManager returns a Manager that shells out to nvidia-smi
+// instead of calling into NVML.
+func NewSmiMigModeManager() Manager {
+	return &smiMigModeManager{smi.New()}
+}
+
+func NewMockSmiMigModeManager(s smi.Interface) Manager {
+	return &smiMigModeManager{s}
+}
+
+// queryMigMode returns the current and pending MIG mode of 'gpu' as reported
+// by nvidia-smi ('Enabled', 'Disabled' or '[N/A]').
+func (m *smiMigModeManager) queryMigMode(gpu int) (string, string, error) {
+	output, err := m.smi.Run("-i", strconv.Itoa(gpu), "--query-gpu=mig.mode.current,mig.mode.pending", "--format=csv,noheader")
+	if err != nil {
+		return "", "", err
+	}
+
+	rows := smi.ParseCSV(output)
+	if len(rows) != 1 || len(rows[0]) != 2 {
+		return "", "", fmt.Errorf("unexpected output from nvidia-smi: %q", output)
+	}
+
+	return rows[0][0], rows[0][1], nil
+}
+
+func parseSmiMigMode(s string) (MigMode, error) {
+	switch s {
+	case Enabled.String():
+		return Enabled, nil
+	case Disabled.String():
+		return Disabled, nil
+	}
+	return -1, fmt.Errorf("unknown MIG mode: %v", s)
+}
+
+func (m *smiMigModeManager) IsMigCapable(gpu int) (bool, error) {
+	current, _, err := m.queryMigMode(gpu)
+	if err != nil {
+		return false, fmt.Errorf("error querying MIG mode: %w", err)
+	}
+	_, err = parseSmiMigMode(current)
+	return err == nil, nil
+}
+
+func (m *smiMigModeManager) GetMigMode(gpu int) (MigMode, error) {
+	current, _, err := m.queryMigMode(gpu)
+	if err != nil {
+		return -1, fmt.Errorf("error querying MIG mode: %w", err)
+	}
+	mode, err := parseSmiMigMode(current)
+	if err != nil {
+		return -1, fmt.Errorf("error getting Mig mode: %v: %w", err, nvmlerrors.ErrNotSupported)
+	}
+	return mode, nil
+}
+
+func (m *smiMigModeManager) SetMigMode(gpu int, mode MigMode) error {
+	var value string
+	switch mode {
+	case Enabled:
+		value = "1"
+	case Disabled:
+		value = "0"
+	default:
+		return fmt.Errorf("unknown Mig mode selected: %v", mode)
+	}
+
+	_, err := m.smi.Run("-i", strconv.Itoa(gpu), "-mig", value)
+	if err != nil {
+		return fmt.Errorf("error setting MIG mode: %w", err)
+	}
+
+	return nil
+}
+
+func (m *smiMigModeManager) IsMigModeChangePending(gpu int) (bool, error) {
+	current, pending, err := m.queryMigMode(gpu)
+	if err != nil {
+		return false, fmt.Errorf("error querying MIG mode: %w", err)
+	}
+	return current != pending, nil
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+// mockSmi returns a canned MIG mode query result for GPU 0 and records the
+// last MIG mode that was set.
+type mockSmi struct {
+	query string
+	set   string
+}
+
+func (m *mockSmi) Run(args ...string) (string, error) {
+	cmd := strings.Join(args, " ")
+	switch cmd {
+	case "-i 0 --query-gpu=mig.mode.current,mig.mode.pending --format=csv,noheader":
+		return m.query, nil
+	case "-i 0 -mig 0", "-i 0 -mig 1":
+		m.set = args[3]
+		return "", nil
+	}
+	return "", fmt.Errorf("unexpected command: nvidia-smi %v", cmd)
+}
+
+func TestSmiMigMode(t *testing.T) {
+	testCases := []struct {
+		description     string
+		query           string
+		expectedCapable bool
+		expectedMode    MigMode
+		expectedPending bool
+	}{
+		{
+			"Enabled",
+			"Enabled, Enabled\n",
+			true,
+			Enabled,
+			false,
+		},
+		{
+			"Disabled, enable pending",
+			"Disabled, Enabled\n",
+			true,
+			Disabled,
+			true,
+		},
+		{
+			"Not MIG capable",
+			"[N/A], [N/A]\n",
+			false,
+			-1,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockSmiMigModeManager(&mockSmi{query: tc.query})
+
+			capable, err := manager.IsMigCapable(0)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedCapable, capable)
+
+			mode, err := manager.GetMigMode(0)
+			if !tc.expectedCapable {
+				require.ErrorIs(t, err, nvmlerrors.ErrNotSupported)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedMode, mode)
+
+			pending, err := manager.IsMigModeChangePending(0)
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedPending, pending)
+		})
+	}
+}
+
+func TestSmiSetMigMode(t *testing.T) {
+	smi := &mockSmi{}
+	manager := NewMockSmiMigModeManager(smi)
+
+	require.Nil(t, manager.SetMigMode(0, Enabled))
+	require.Equal(t, "1", smi.set)
+
+	require.Nil(t, manager.SetMigMode(0, Disabled))
+	require.Equal(t, "0", smi.set)
+}
+
+func TestFallbackMigModeManager(t *testing.T) {
+	unsupported := &unsupportedMigModeManager{}
+	manager := NewFallbackMigModeManager(unsupported, NewMockSmiMigModeManager(&mockSmi{query: "Enabled, Enabled\n"}))
+
+	mode, err := manager.GetMigMode(0)
+	require.Nil(t, err)
+	require.Equal(t, Enabled, mode)
+}
+
+type unsupportedMigModeManager struct{}
+
+func (m *unsupportedMigModeManager) IsMigCapable(gpu int) (bool, error) {
+	return false, fmt.Errorf("error: %w", nvmlerrors.ErrNotSupported)
+}
+
+func (m *unsupportedMigModeManager) GetMigMode(gpu int) (MigMode, error) {
+	return -1, fmt.Errorf("error: %w", nvmlerrors.ErrNotSupported)
+}
+
+func (m *unsupportedMigModeManager) SetMigMode(gpu int, mode MigMode) error {
+	return fmt.Errorf("error: %w", nvmlerrors.ErrNotSupported)
+}
+
+func (m *unsupportedMigModeManager) IsMigModeChangePending(gpu int) (bool, error) {
+	return false, fmt.Errorf("error: %w", nvmlerrors.ErrNotSupported)
+}
@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+type vgpuGuestMigModeManager struct {
+	Manager
+}
+
+var _ Manager = (*vgpuGuestMigModeManager)(nil)
+
+// NewVGPUGuestMigModeManager wraps 'm' for use inside a vGPU guest VM, where
+// the MIG mode of a GPU is owned by the hypervisor. MIG mode can still be
+// queried, but any attempt to change it fails with nvmlerrors.ErrVGPUGuest.
+func NewVGPUGuestMigModeManager(m Manager) Manager {
+	return &vgpuGuestMigModeManager{m}
+}
+
+func (m *vgpuGuestMigModeManager) SetMigMode(gpu int, mode MigMode) error {
+	current, err := m.Manager.GetMigMode(gpu)
+	if err != nil {
+		return err
+	}
+	if current == mode {
+		return nil
+	}
+	return fmt.Errorf("error setting MIG mode on GPU %v: %w", gpu, nvmlerrors.ErrVGPUGuest)
+}
+
+// IsVGPUGuest checks if any of the GPUs visible through 'nvmlLib' is a vGPU,
+// i.e. if we are running inside a vGPU guest VM.
+func IsVGPUGuest(nvmlLib nvml.Interface) (bool, error) {
+	ret := nvmlLib.Init()
+	if ret != nvml.SUCCESS {
+		return false, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer tryNvmlShutdown(nvmlLib)
+
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return false, fmt.Errorf("error getting device count: %w", nvmlerrors.New(ret))
+	}
+
+	for i := 0; i < count; i++ {
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return false, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+		}
+
+		virtualizationMode, ret := device.GetVirtualizationMode()
+		if ret == nvml.ERROR_NOT_SUPPORTED {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return false, fmt.Errorf("error getting virtualization mode: %w", nvmlerrors.New(ret))
+		}
+		if virtualizationMode == nvml.GPU_VIRTUALIZATION_MODE_VGPU {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,93 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mode
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+)
+
+func TestIsVGPUGuest(t *testing.T) {
+	testCases := []struct {
+		description string
+		gpus        []testutil.GPU
+		expected    bool
+	}{
+		{
+			"Bare metal",
+			[]testutil.GPU{
+				{Model: testutil.A100_SXM4_40GB},
+				{Model: testutil.A100_SXM4_40GB},
+			},
+			false,
+		},
+		{
+			"vGPU guest",
+			[]testutil.GPU{
+				{Model: testutil.A100_SXM4_40GB, MigEnabled: true, VGPUGuest: true},
+			},
+			true,
+		},
+		{
+			"No GPUs",
+			nil,
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			builder := testutil.NewServerBuilder()
+			for _, gpu := range tc.gpus {
+				builder.WithGPU(gpu)
+			}
+
+			guest, err := IsVGPUGuest(builder.MustBuild())
+			require.Nil(t, err)
+			require.Equal(t, tc.expected, guest)
+		})
+	}
+}
+
+func TestVGPUGuestMigModeManager(t *testing.T) {
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true, VGPUGuest: true}).
+		MustBuild()
+	manager := NewVGPUGuestMigModeManager(NewMockNvmlMigModeManager(server))
+
+	capable, err := manager.IsMigCapable(0)
+	require.Nil(t, err)
+	require.True(t, capable)
+
+	mode, err := manager.GetMigMode(0)
+	require.Nil(t, err)
+	require.Equal(t, Enabled, mode)
+
+	err = manager.SetMigMode(0, Enabled)
+	require.Nil(t, err, "Setting the current MIG mode should be a no-op")
+
+	err = manager.SetMigMode(0, Disabled)
+	require.ErrorIs(t, err, nvmlerrors.ErrVGPUGuest)
+
+	mode, err = manager.GetMigMode(0)
+	require.Nil(t, err)
+	require.Equal(t, Enabled, mode)
+}
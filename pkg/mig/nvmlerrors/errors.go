@@ -0,0 +1,100 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nvmlerrors classifies the nvml.Return values surfaced by the MIG
+// managers so that callers can react to a class of failure with errors.Is
+// instead of matching on error strings.
+package nvmlerrors
+
+import (
+	"errors"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+var (
+	// ErrInUse indicates that the operation failed because the GPU (or one of
+	// its MIG devices) is currently in use by another process.
+	ErrInUse = errors.New("in use")
+	// ErrNotSupported indicates that the operation is not supported by the GPU.
+	ErrNotSupported = errors.New("not supported")
+	// ErrInsufficientResources indicates that the GPU does not have enough free
+	// resources left to satisfy the operation.
+	ErrInsufficientResources = errors.New("insufficient resources")
+	// ErrNeedsReset indicates that the GPU must be reset before the operation
+	// can succeed.
+	ErrNeedsReset = errors.New("needs reset")
+	// ErrGpuLost indicates that the GPU has fallen off the bus (e.g. after an
+	// Xid 79) and can no longer be reached.
+	ErrGpuLost = errors.New("gpu lost")
+	// ErrNoPermission indicates that the process lacks the privilege the
+	// operation needs, e.g. access to the nvidia-caps device nodes.
+	ErrNoPermission = errors.New("no permission")
+	// ErrVGPUGuest indicates that the operation cannot be performed from
+	// inside a vGPU guest VM, because the hypervisor owns the setting.
+	ErrVGPUGuest = errors.New("not permitted inside a vGPU guest")
+)
+
+// Error wraps an nvml.Return that was not nvml.SUCCESS.
+type Error struct {
+	Return nvml.Return
+}
+
+var _ error = (*Error)(nil)
+
+// New returns an *Error for 'ret', or nil if 'ret' is nvml.SUCCESS.
+func New(ret nvml.Return) error {
+	if ret == nvml.SUCCESS {
+		return nil
+	}
+	return &Error{ret}
+}
+
+func (e *Error) Error() string {
+	return e.Return.Error()
+}
+
+// Unwrap returns the underlying nvml.Return so that errors.Is can also be
+// used to match against a specific nvml.Return value.
+func (e *Error) Unwrap() error {
+	return e.Return
+}
+
+// Is reports whether 'e' falls into the failure class given by 'target'.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrInUse:
+		return e.Return == nvml.ERROR_IN_USE
+	case ErrNotSupported:
+		return e.Return == nvml.ERROR_NOT_SUPPORTED
+	case ErrInsufficientResources:
+		return e.Return == nvml.ERROR_INSUFFICIENT_RESOURCES
+	case ErrNeedsReset:
+		return e.Return == nvml.ERROR_RESET_REQUIRED
+	case ErrGpuLost:
+		return e.Return == nvml.ERROR_GPU_IS_LOST
+	case ErrNoPermission:
+		return e.Return == nvml.ERROR_NO_PERMISSION
+	}
+	return false
+}
+
+// IsUnsupported reports whether 'err' means the operation is not available
+// through NVML, either because the GPU does not support it or because the
+// loaded driver does not export the function it needs.
+func IsUnsupported(err error) bool {
+	return errors.Is(err, ErrNotSupported) || errors.Is(err, nvml.ERROR_FUNCTION_NOT_FOUND)
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nvmlerrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+func TestErrorClasses(t *testing.T) {
+	classes := []error{
+		ErrInUse,
+		ErrNotSupported,
+		ErrInsufficientResources,
+		ErrNeedsReset,
+		ErrGpuLost,
+		ErrNoPermission,
+	}
+
+	testCases := []struct {
+		description string
+		ret         nvml.Return
+		expected    error
+	}{
+		{
+			"In use",
+			nvml.ERROR_IN_USE,
+			ErrInUse,
+		},
+		{
+			"Not supported",
+			nvml.ERROR_NOT_SUPPORTED,
+			ErrNotSupported,
+		},
+		{
+			"Insufficient resources",
+			nvml.ERROR_INSUFFICIENT_RESOURCES,
+			ErrInsufficientResources,
+		},
+		{
+			"Reset required",
+			nvml.ERROR_RESET_REQUIRED,
+			ErrNeedsReset,
+		},
+		{
+			"GPU lost",
+			nvml.ERROR_GPU_IS_LOST,
+			ErrGpuLost,
+		},
+		{
+			"No permission",
+			nvml.ERROR_NO_PERMISSION,
+			ErrNoPermission,
+		},
+		{
+			"Unclassified",
+			nvml.ERROR_UNKNOWN,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			err := fmt.Errorf("error doing something: %w", New(tc.ret))
+
+			for _, class := range classes {
+				require.Equal(t, class == tc.expected, errors.Is(err, class), "Unexpected match for class '%v'", class)
+			}
+			require.True(t, errors.Is(err, tc.ret))
+
+			var e *Error
+			require.True(t, errors.As(err, &e))
+			require.Equal(t, tc.ret, e.Return)
+		})
+	}
+}
+
+func TestNewSuccess(t *testing.T) {
+	require.Nil(t, New(nvml.SUCCESS))
+}
+
+func TestIsUnsupported(t *testing.T) {
+	testCases := []struct {
+		description string
+		err         error
+		expected    bool
+	}{
+		{"Nil", nil, false},
+		{"Not supported", fmt.Errorf("wrapped: %w", New(nvml.ERROR_NOT_SUPPORTED)), true},
+		{"Function not found", fmt.Errorf("wrapped: %w", New(nvml.ERROR_FUNCTION_NOT_FOUND)), true},
+		{"Sentinel", fmt.Errorf("wrapped: %w", ErrNotSupported), true},
+		{"In use", fmt.Errorf("wrapped: %w", New(nvml.ERROR_IN_USE)), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, IsUnsupported(tc.err))
+		})
+	}
+}
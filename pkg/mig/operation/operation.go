@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package operation breaks changes to the MIG state of a GPU into discrete
+// operations that can each be undone, so that a failed change can be rolled
+// back and a planned change can be shown exactly before it is made.
+package operation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Operation is a single change to the MIG state of a GPU.
+type Operation interface {
+	// Do performs the operation.
+	Do() error
+	// Undo reverts the effects of a successful call to Do.
+	Undo() error
+	// String describes the operation.
+	String() string
+}
+
+// ErrConcurrentModification is wrapped by the errors of operations that find
+// the instance they are about to destroy changed since they were planned,
+// e.g. by 'nvidia-smi mig' run alongside the apply. Destroying whatever now
+// has the planned ID could take down an instance other than the one planned.
+var ErrConcurrentModification = errors.New("concurrent modification")
+
+// destroyer is implemented by operations that remove MIG devices from a GPU.
+type destroyer interface {
+	destroys()
+}
+
+// Engine performs operations in order and records the ones that succeeded so
+// that they can be rolled back.
+type Engine struct {
+	performed []Operation
+}
+
+// NewEngine creates an Engine that has not performed any operations yet.
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+// Run performs each of 'ops' in order, stopping at the first one that fails.
+func (e *Engine) Run(ops []Operation) error {
+	return e.RunContext(context.Background(), ops)
+}
+
+// RunContext is like Run, but stops early if 'ctx' is done when a safe point
+// is reached. Safe points are before the first operation and between the
+// operations that destroy MIG devices and those that create them, so that a
+// GPU is never left with a partially created MIG device. The error returned
+// when stopping early wraps ctx.Err().
+func (e *Engine) RunContext(ctx context.Context, ops []Operation) error {
+	for i, op := range ops {
+		if isSafePoint(ops, i) && ctx.Err() != nil {
+			return fmt.Errorf("stopped before '%v': %w", op, ctx.Err())
+		}
+		log.Debugf("    %v", op)
+		err := op.Do()
+		if err != nil {
+			return fmt.Errorf("error performing '%v': %w", op, err)
+		}
+		e.performed = append(e.performed, op)
+	}
+	return nil
+}
+
+// isSafePoint checks if the apply may stop before ops[i].
+func isSafePoint(ops []Operation, i int) bool {
+	if i == 0 {
+		return true
+	}
+	_, prev := ops[i-1].(destroyer)
+	_, next := ops[i].(destroyer)
+	return prev && !next
+}
+
+// Rollback undoes every operation performed so far, most recent first. It
+// attempts to undo all of them even if some fail, and returns the combined
+// errors.
+func (e *Engine) Rollback() error {
+	var errs []error
+	for i := len(e.performed) - 1; i >= 0; i-- {
+		op := e.performed[i]
+		log.Debugf("    Undo %v", op)
+		err := op.Undo()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error undoing '%v': %w", op, err))
+		}
+	}
+	e.performed = nil
+	return errors.Join(errs...)
+}
+
+// Performed returns the operations performed (and not yet rolled back) so far.
+func (e *Engine) Performed() []Operation {
+	return e.performed
+}
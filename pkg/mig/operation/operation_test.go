@@ -0,0 +1,199 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeOperation struct {
+	name    string
+	doErr   error
+	undoErr error
+	log     *[]string
+}
+
+func (o *fakeOperation) Do() error {
+	*o.log = append(*o.log, "do "+o.name)
+	return o.doErr
+}
+
+func (o *fakeOperation) Undo() error {
+	*o.log = append(*o.log, "undo "+o.name)
+	return o.undoErr
+}
+
+func (o *fakeOperation) String() string {
+	return o.name
+}
+
+func TestEngine(t *testing.T) {
+	testCases := []struct {
+		description       string
+		doErrs            map[string]error
+		undoErrs          map[string]error
+		expectedPerformed int
+		expectedRunError  bool
+		expectedUndoError bool
+		expectedLog       []string
+	}{
+		{
+			"All operations succeed",
+			nil,
+			nil,
+			3,
+			false,
+			false,
+			[]string{"do a", "do b", "do c", "undo c", "undo b", "undo a"},
+		},
+		{
+			"Failure stops the run",
+			map[string]error{"b": fmt.Errorf("failed")},
+			nil,
+			1,
+			true,
+			false,
+			[]string{"do a", "do b", "undo a"},
+		},
+		{
+			"Undo failure does not stop the rollback",
+			nil,
+			map[string]error{"b": fmt.Errorf("failed")},
+			3,
+			false,
+			true,
+			[]string{"do a", "do b", "do c", "undo c", "undo b", "undo a"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var log []string
+			var ops []Operation
+			for _, name := range []string{"a", "b", "c"} {
+				ops = append(ops, &fakeOperation{name, tc.doErrs[name], tc.undoErrs[name], &log})
+			}
+
+			engine := NewEngine()
+			err := engine.Run(ops)
+			if tc.expectedRunError {
+				require.NotNil(t, err, "Unexpected success from Run")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Run")
+			}
+			require.Len(t, engine.Performed(), tc.expectedPerformed)
+
+			err = engine.Rollback()
+			if tc.expectedUndoError {
+				require.NotNil(t, err, "Unexpected success from Rollback")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Rollback")
+			}
+			require.Len(t, engine.Performed(), 0)
+			require.Equal(t, tc.expectedLog, log)
+		})
+	}
+}
+
+// cancelingOperation calls 'cancel' (if set) once it has been performed.
+type cancelingOperation struct {
+	fakeOperation
+	cancel func()
+}
+
+func (o *cancelingOperation) Do() error {
+	err := o.fakeOperation.Do()
+	if o.cancel != nil {
+		o.cancel()
+	}
+	return err
+}
+
+type cancelingDestroyOperation struct {
+	cancelingOperation
+}
+
+func (o *cancelingDestroyOperation) destroys() {}
+
+func TestEngineRunContext(t *testing.T) {
+	testCases := []struct {
+		description string
+		// cancelAfter is the name of the operation after which the
+		// context is canceled, or "" to cancel it before running.
+		cancelAfter string
+		expectedLog []string
+	}{
+		{
+			"Canceled before the first operation",
+			"",
+			nil,
+		},
+		{
+			"Canceled while destroying stops before creating",
+			"destroy-a",
+			[]string{"do destroy-a", "do destroy-b"},
+		},
+		{
+			"Canceled while creating does not stop",
+			"create-a",
+			[]string{"do destroy-a", "do destroy-b", "do create-a", "do create-b"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tc.cancelAfter == "" {
+				cancel()
+			}
+
+			var log []string
+			newOperation := func(name string) cancelingOperation {
+				op := cancelingOperation{fakeOperation: fakeOperation{name: name, log: &log}}
+				if name == tc.cancelAfter {
+					op.cancel = cancel
+				}
+				return op
+			}
+			create := func(name string) *cancelingOperation {
+				op := newOperation(name)
+				return &op
+			}
+			ops := []Operation{
+				&cancelingDestroyOperation{newOperation("destroy-a")},
+				&cancelingDestroyOperation{newOperation("destroy-b")},
+				create("create-a"),
+				create("create-b"),
+			}
+
+			engine := NewEngine()
+			err := engine.RunContext(ctx, ops)
+			if len(tc.expectedLog) == len(ops) {
+				require.Nil(t, err)
+			} else {
+				require.ErrorIs(t, err, context.Canceled)
+			}
+			require.Equal(t, tc.expectedLog, log)
+			require.Len(t, engine.Performed(), len(tc.expectedLog))
+		})
+	}
+}
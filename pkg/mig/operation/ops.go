@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// EnableMode sets the MIG mode of a GPU.
+type EnableMode struct {
+	Manager mode.Manager
+	GPU     int
+	Mode    mode.MigMode
+
+	previous mode.MigMode
+}
+
+// CreateGI creates a GPU instance. 'GpuInstance' holds the profile (and the
+// placement, if 'Start' is set) on input and is updated with the ID and
+// placement of the GPU instance once it has been created.
+type CreateGI struct {
+	Manager     config.InstanceManager
+	GPU         int
+	GpuInstance *types.GpuInstance
+	Start       *uint32
+}
+
+// CreateCI creates a compute instance inside a GPU instance.
+type CreateCI struct {
+	Manager     config.InstanceManager
+	GPU         int
+	GpuInstance *types.GpuInstance
+	Profile     string
+	Start       *uint32
+
+	created *types.MigDevice
+}
+
+// DestroyCI destroys a compute instance. Undo recreates it with the same
+// profile (and placement, if 'Start' is set) in the same GPU instance.
+type DestroyCI struct {
+	Manager     config.InstanceManager
+	GPU         int
+	GpuInstance *types.GpuInstance
+	Device      types.MigDevice
+	Start       *uint32
+}
+
+// DestroyGI destroys an empty GPU instance. Undo recreates it at the same
+// placement and updates 'GpuInstance' with its new ID.
+type DestroyGI struct {
+	Manager     config.InstanceManager
+	GPU         int
+	GpuInstance *types.GpuInstance
+}
+
+var _ Operation = (*EnableMode)(nil)
+var _ Operation = (*CreateGI)(nil)
+var _ Operation = (*CreateCI)(nil)
+var _ Operation = (*DestroyCI)(nil)
+var _ Operation = (*DestroyGI)(nil)
+
+var _ destroyer = (*DestroyCI)(nil)
+var _ destroyer = (*DestroyGI)(nil)
+
+func (o *EnableMode) Do() error {
+	previous, err := o.Manager.GetMigMode(o.GPU)
+	if err != nil {
+		return fmt.Errorf("error getting MIG mode: %w", err)
+	}
+	o.previous = previous
+	return o.Manager.SetMigMode(o.GPU, o.Mode)
+}
+
+func (o *EnableMode) Undo() error {
+	return o.Manager.SetMigMode(o.GPU, o.previous)
+}
+
+func (o *EnableMode) String() string {
+	return fmt.Sprintf("GPU %d: set MIG mode to %v", o.GPU, o.Mode)
+}
+
+func (o *CreateGI) Do() error {
+	gi, err := o.Manager.CreateGpuInstance(o.GPU, o.GpuInstance.Profile, o.Start)
+	if err != nil {
+		return err
+	}
+	*o.GpuInstance = *gi
+	return nil
+}
+
+func (o *CreateGI) Undo() error {
+	return o.Manager.DestroyGpuInstance(o.GPU, o.GpuInstance.ID)
+}
+
+func (o *CreateGI) String() string {
+	if o.Start == nil {
+		return fmt.Sprintf("GPU %d: create GPU instance %v", o.GPU, o.GpuInstance.Profile)
+	}
+	return fmt.Sprintf("GPU %d: create GPU instance %v at slice %d", o.GPU, o.GpuInstance.Profile, *o.Start)
+}
+
+func (o *CreateCI) Do() error {
+	ci, err := o.Manager.CreateComputeInstance(o.GPU, o.GpuInstance.ID, o.Profile, o.Start)
+	if err != nil {
+		return err
+	}
+	o.created = ci
+	return nil
+}
+
+func (o *CreateCI) Undo() error {
+	return o.Manager.DestroyComputeInstance(o.GPU, o.GpuInstance.ID, o.created.ComputeInstanceID)
+}
+
+func (o *CreateCI) String() string {
+	s := fmt.Sprintf("GPU %d: create compute instance %v in GPU instance %v", o.GPU, o.Profile, describeGpuInstance(o.GpuInstance))
+	if o.Start != nil {
+		s += fmt.Sprintf(" at slice %d", *o.Start)
+	}
+	return s
+}
+
+func (o *DestroyCI) Do() error {
+	err := checkUnchanged(o.Manager, o.GPU, o.GpuInstance, &o.Device)
+	if err != nil {
+		return err
+	}
+	return o.Manager.DestroyComputeInstance(o.GPU, o.GpuInstance.ID, o.Device.ComputeInstanceID)
+}
+
+func (o *DestroyCI) Undo() error {
+	ci, err := o.Manager.CreateComputeInstance(o.GPU, o.GpuInstance.ID, o.Device.Profile, o.Start)
+	if err != nil {
+		return err
+	}
+	o.Device = *ci
+	return nil
+}
+
+func (o *DestroyCI) String() string {
+	return fmt.Sprintf("GPU %d: destroy compute instance %d (%v) in GPU instance %v", o.GPU, o.Device.ComputeInstanceID, o.Device.Profile, describeGpuInstance(o.GpuInstance))
+}
+
+func (o *DestroyGI) Do() error {
+	err := checkUnchanged(o.Manager, o.GPU, o.GpuInstance, nil)
+	if err != nil {
+		return err
+	}
+	return o.Manager.DestroyGpuInstance(o.GPU, o.GpuInstance.ID)
+}
+
+func (o *DestroyGI) Undo() error {
+	start := o.GpuInstance.Placement.Start
+	gi, err := o.Manager.CreateGpuInstance(o.GPU, o.GpuInstance.Profile, &start)
+	if err != nil {
+		return err
+	}
+	*o.GpuInstance = *gi
+	return nil
+}
+
+func (o *DestroyCI) destroys() {}
+
+func (o *DestroyGI) destroys() {}
+
+func (o *DestroyGI) String() string {
+	return fmt.Sprintf("GPU %d: destroy GPU instance %v", o.GPU, describeGpuInstance(o.GpuInstance))
+}
+
+// describeGpuInstance names a GPU instance by its ID once it exists, or by
+// its profile and placement while it is only planned.
+func describeGpuInstance(gi *types.GpuInstance) string {
+	return fmt.Sprintf("%d (%v at slice %d)", gi.ID, gi.Profile, gi.Placement.Start)
+}
+
+// checkUnchanged checks that 'gi' is still on 'gpu' with the same ID, profile
+// and placement as when it was planned, and that it holds exactly 'ci' if
+// set (or nothing otherwise), right before it or 'ci' is destroyed. The IDs
+// of destroyed instances are reused, so anything else means another process
+// changed the GPU since, and a destroy by ID would hit the wrong instance.
+func checkUnchanged(manager config.InstanceManager, gpu int, gi *types.GpuInstance, ci *types.MigDevice) error {
+	changed := func(format string, a ...interface{}) error {
+		return fmt.Errorf("%w of GPU %d: %v", ErrConcurrentModification, gpu, fmt.Sprintf(format, a...))
+	}
+
+	gis, err := manager.ListGpuInstances(gpu)
+	if err != nil {
+		return fmt.Errorf("error listing GPU instances: %w", err)
+	}
+	var current *types.GpuInstance
+	for i := range gis {
+		if gis[i].ID == gi.ID {
+			current = &gis[i]
+		}
+	}
+	if current == nil {
+		return changed("GPU instance %v no longer exists", describeGpuInstance(gi))
+	}
+	if current.Profile != gi.Profile || current.Placement != gi.Placement {
+		return changed("GPU instance %v is now %v", describeGpuInstance(gi), describeGpuInstance(current))
+	}
+
+	cis, err := manager.ListComputeInstances(gpu)
+	if err != nil {
+		return fmt.Errorf("error listing compute instances: %w", err)
+	}
+	var contained []types.MigDevice
+	for _, d := range cis {
+		if d.GpuInstanceID == gi.ID {
+			contained = append(contained, d)
+		}
+	}
+	if ci == nil {
+		if len(contained) > 0 {
+			return changed("GPU instance %v still holds %d compute instance(s)", describeGpuInstance(gi), len(contained))
+		}
+		return nil
+	}
+	for _, d := range contained {
+		if d.ComputeInstanceID != ci.ComputeInstanceID {
+			continue
+		}
+		if d.Profile != ci.Profile || d.ComputeInstancePlacement != ci.ComputeInstancePlacement {
+			return changed("compute instance %d (%v) in GPU instance %v is now %v", ci.ComputeInstanceID, ci.Profile, describeGpuInstance(gi), d.Profile)
+		}
+		return nil
+	}
+	return changed("compute instance %d (%v) in GPU instance %v no longer exists", ci.ComputeInstanceID, ci.Profile, describeGpuInstance(gi))
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// ciPrefix matches the compute instance part of a MIG profile, e.g. the "1c."
+// in "1c.2g.10gb".
+var ciPrefix = regexp.MustCompile(`^\d+c\.`)
+
+// Plan returns the operations that take 'gpu' from its current set of GPU and
+// compute instances to the MIG devices in 'planned', as returned by
+// PlanMigConfig. Existing GPU instances that are already placed and populated
+// as planned are left alone; all other compute and GPU instances are
+// destroyed before the remaining planned ones are created.
+func Plan(manager config.InstanceManager, gpu int, planned []types.MigDevice) ([]Operation, error) {
+	gis, err := manager.ListGpuInstances(gpu)
+	if err != nil {
+		return nil, fmt.Errorf("error listing GPU instances: %w", err)
+	}
+	cis, err := manager.ListComputeInstances(gpu)
+	if err != nil {
+		return nil, fmt.Errorf("error listing compute instances: %w", err)
+	}
+
+	starts := make(map[uint32][]types.MigDevice)
+	for _, d := range planned {
+		starts[d.GpuInstancePlacement.Start] = append(starts[d.GpuInstancePlacement.Start], d)
+	}
+
+	var ops []Operation
+	for i := range gis {
+		gi := &gis[i]
+		var contained []types.MigDevice
+		for _, ci := range cis {
+			if ci.GpuInstanceID == gi.ID {
+				contained = append(contained, ci)
+			}
+		}
+		if isPlanned(gi, contained, starts[gi.Placement.Start]) {
+			delete(starts, gi.Placement.Start)
+			continue
+		}
+		for _, ci := range contained {
+			op := &DestroyCI{Manager: manager, GPU: gpu, GpuInstance: gi, Device: ci}
+			if len(contained) > 1 {
+				start := ci.ComputeInstancePlacement.Start
+				op.Start = &start
+			}
+			ops = append(ops, op)
+		}
+		ops = append(ops, &DestroyGI{Manager: manager, GPU: gpu, GpuInstance: gi})
+	}
+
+	var order []uint32
+	for start := range starts {
+		order = append(order, start)
+	}
+	sort.Slice(order, func(i, j int) bool { return order[i] < order[j] })
+
+	for _, start := range order {
+		devices := starts[start]
+		gi := &types.GpuInstance{
+			Profile:   ciPrefix.ReplaceAllString(devices[0].Profile, ""),
+			Placement: devices[0].GpuInstancePlacement,
+		}
+		giStart := start
+		ops = append(ops, &CreateGI{Manager: manager, GPU: gpu, GpuInstance: gi, Start: &giStart})
+		for _, d := range devices {
+			ops = append(ops, &CreateCI{Manager: manager, GPU: gpu, GpuInstance: gi, Profile: d.Profile})
+		}
+	}
+
+	return ops, nil
+}
+
+// isPlanned checks whether 'gi', holding the compute instances in
+// 'contained', is exactly the GPU instance planned to hold 'devices'.
+func isPlanned(gi *types.GpuInstance, contained []types.MigDevice, devices []types.MigDevice) bool {
+	if len(devices) == 0 || len(devices) != len(contained) {
+		return false
+	}
+	if gi.Placement != devices[0].GpuInstancePlacement {
+		return false
+	}
+	if gi.Profile != ciPrefix.ReplaceAllString(devices[0].Profile, "") {
+		return false
+	}
+
+	current := types.MigConfig{}
+	for _, ci := range contained {
+		current[ci.Profile]++
+	}
+	desired := types.MigConfig{}
+	for _, d := range devices {
+		desired[d.Profile]++
+	}
+	return current.Equals(desired)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package operation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newMockServer() *testutil.Server {
+	return testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		MustBuild()
+}
+
+func listProfiles(t *testing.T, manager config.InstanceManager) []string {
+	gis, err := manager.ListGpuInstances(0)
+	require.Nil(t, err, "Unexpected failure from ListGpuInstances")
+	cis, err := manager.ListComputeInstances(0)
+	require.Nil(t, err, "Unexpected failure from ListComputeInstances")
+
+	var profiles []string
+	for _, gi := range gis {
+		profiles = append(profiles, gi.Profile)
+	}
+	for _, ci := range cis {
+		profiles = append(profiles, ci.Profile)
+	}
+	return profiles
+}
+
+func TestPlan(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := newMockServer()
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	planned, err := config.NewMockNvmlMigConfigManager(server).PlanMigConfig(0, types.MigConfig{"1g.5gb": 2})
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+
+	ops, err := Plan(manager, 0, planned)
+	require.Nil(t, err, "Unexpected failure from Plan")
+
+	var descriptions []string
+	for _, op := range ops {
+		descriptions = append(descriptions, op.String())
+	}
+	require.Equal(t, []string{
+		"GPU 0: destroy compute instance 0 (3g.20gb) in GPU instance 0 (3g.20gb at slice 0)",
+		"GPU 0: destroy GPU instance 0 (3g.20gb at slice 0)",
+		"GPU 0: create GPU instance 1g.5gb at slice 0",
+		"GPU 0: create compute instance 1g.5gb in GPU instance 0 (1g.5gb at slice 0)",
+		"GPU 0: create GPU instance 1g.5gb at slice 1",
+		"GPU 0: create compute instance 1g.5gb in GPU instance 0 (1g.5gb at slice 1)",
+	}, descriptions)
+
+	engine := NewEngine()
+	err = engine.Run(ops)
+	require.Nil(t, err, "Unexpected failure from Run")
+	require.ElementsMatch(t, []string{"1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb"}, listProfiles(t, manager))
+
+	err = engine.Rollback()
+	require.Nil(t, err, "Unexpected failure from Rollback")
+	require.ElementsMatch(t, []string{"3g.20gb", "3g.20gb"}, listProfiles(t, manager))
+}
+
+func TestPlanPreservesMatchingInstances(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: true}).
+		MustBuild()
+	manager := config.NewMockNvmlInstanceManager(server)
+	configManager := config.NewMockNvmlMigConfigManager(server)
+
+	planned, err := configManager.PlanMigConfig(0, types.MigConfig{"1g.5gb": 7})
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+	ops, err := Plan(manager, 0, planned)
+	require.Nil(t, err, "Unexpected failure from Plan")
+	require.Nil(t, NewEngine().Run(ops), "Unexpected failure from Run")
+
+	current, err := configManager.GetMigDevices(0)
+	require.Nil(t, err, "Unexpected failure from GetMigDevices")
+
+	planned, err = configManager.PlanMigConfig(0, types.MigConfig{"1g.5gb": 3, "3g.20gb": 1}, config.WithPreservedDevices(current))
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+	ops, err = Plan(manager, 0, planned)
+	require.Nil(t, err, "Unexpected failure from Plan")
+
+	var descriptions []string
+	for _, op := range ops {
+		descriptions = append(descriptions, op.String())
+	}
+	require.Len(t, descriptions, 10)
+	require.Equal(t, "GPU 0: create GPU instance 3g.20gb at slice 4", descriptions[8])
+
+	require.Nil(t, NewEngine().Run(ops), "Unexpected failure from Run")
+	devices, err := configManager.GetMigDevices(0)
+	require.Nil(t, err, "Unexpected failure from GetMigDevices")
+	var preserved []types.MigDevice
+	for _, d := range current {
+		if d.GpuInstancePlacement.Start < 3 {
+			preserved = append(preserved, d)
+		}
+	}
+	require.Len(t, preserved, 3)
+	require.Subset(t, devices, preserved, "Preserved MIG devices changed")
+}
+
+func TestRollbackAfterFailure(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := newMockServer()
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	planned, err := config.NewMockNvmlMigConfigManager(server).PlanMigConfig(0, types.MigConfig{"1g.5gb": 1})
+	require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+
+	ops, err := Plan(manager, 0, planned)
+	require.Nil(t, err, "Unexpected failure from Plan")
+
+	// Compute instances cannot be created in a missing GPU instance.
+	ops = append(ops, &CreateCI{Manager: manager, GPU: 0, GpuInstance: &types.GpuInstance{ID: 100}, Profile: "1g.5gb"})
+
+	engine := NewEngine()
+	err = engine.Run(ops)
+	require.NotNil(t, err, "Unexpected success from Run")
+	require.Len(t, engine.Performed(), len(ops)-1)
+
+	err = engine.Rollback()
+	require.Nil(t, err, "Unexpected failure from Rollback")
+	require.ElementsMatch(t, []string{"3g.20gb", "3g.20gb"}, listProfiles(t, manager))
+}
+
+func TestConcurrentModification(t *testing.T) {
+	testCases := []struct {
+		description string
+		modify      func(t *testing.T, manager config.InstanceManager)
+		expected    []string
+	}{
+		{
+			"Compute instance destroyed",
+			func(t *testing.T, manager config.InstanceManager) {
+				require.Nil(t, manager.DestroyComputeInstance(0, 0, 0))
+			},
+			[]string{"3g.20gb"},
+		},
+		{
+			"GPU instance replaced",
+			func(t *testing.T, manager config.InstanceManager) {
+				require.Nil(t, manager.DestroyComputeInstance(0, 0, 0))
+				require.Nil(t, manager.DestroyGpuInstance(0, 0))
+				gi, err := manager.CreateGpuInstance(0, "1g.5gb", nil)
+				require.Nil(t, err)
+				_, err = manager.CreateComputeInstance(0, gi.ID, "1g.5gb", nil)
+				require.Nil(t, err)
+			},
+			[]string{"1g.5gb", "1g.5gb"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			types.SetMockNVdevlib()
+
+			server := newMockServer()
+			manager := config.NewMockNvmlInstanceManager(server)
+
+			planned, err := config.NewMockNvmlMigConfigManager(server).PlanMigConfig(0, types.MigConfig{"1g.5gb": 2})
+			require.Nil(t, err, "Unexpected failure from PlanMigConfig")
+			ops, err := Plan(manager, 0, planned)
+			require.Nil(t, err, "Unexpected failure from Plan")
+
+			tc.modify(t, manager)
+
+			engine := NewEngine()
+			err = engine.Run(ops)
+			require.ErrorIs(t, err, ErrConcurrentModification)
+			require.Empty(t, engine.Performed())
+			require.ElementsMatch(t, tc.expected, listProfiles(t, manager), "Instances changed after detecting a concurrent modification")
+		})
+	}
+}
+
+func TestEnableMode(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB}).MustBuild()
+	manager := mode.NewMockNvmlMigModeManager(server)
+
+	op := &EnableMode{Manager: manager, GPU: 0, Mode: mode.Enabled}
+	require.Equal(t, "GPU 0: set MIG mode to Enabled", op.String())
+
+	err := op.Do()
+	require.Nil(t, err, "Unexpected failure from Do")
+	m, err := manager.GetMigMode(0)
+	require.Nil(t, err, "Unexpected failure from GetMigMode")
+	require.Equal(t, mode.Enabled, m)
+
+	err = op.Undo()
+	require.Nil(t, err, "Unexpected failure from Undo")
+	m, err = manager.GetMigMode(0)
+	require.Nil(t, err, "Unexpected failure from GetMigMode")
+	require.Equal(t, mode.Disabled, m)
+}
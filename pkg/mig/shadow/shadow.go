@@ -0,0 +1,463 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package shadow models the MIG state of GPUs in memory, so that a change to
+// it can be simulated against a copy of the live state without touching any
+// hardware. A Server is an nvml.Interface, so the same code that changes the
+// MIG state of real GPUs can be run against it unmodified.
+package shadow
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+)
+
+// Server is an in-memory NVML server holding a shadow copy of each GPU.
+type Server struct {
+	mock.Interface
+	mock.ExtendedInterface
+	Devices []*Device
+}
+
+// Device is the shadow copy of a GPU. Its profile and placement tables are
+// those of the GPU it was copied from. GPU instances are only created where
+// the tables allow them to be, and where they do not overlap an existing GPU
+// instance.
+type Device struct {
+	mock.Device
+	sync.Mutex
+
+	UUID       string
+	Name       string
+	PciInfo    nvml.PciInfo
+	Memory     nvml.Memory
+	MigCapable bool
+	MigMode    int
+
+	// GpuInstanceProfiles and GpuInstancePlacements are keyed by GPU
+	// instance profile ID.
+	GpuInstanceProfiles   map[int]nvml.GpuInstanceProfileInfo
+	GpuInstancePlacements map[int][]nvml.GpuInstancePlacement
+	// ComputeInstanceProfiles is keyed by GPU instance profile ID and then
+	// by compute instance profile ID. Only compute instances that share the
+	// engines of their GPU instance are modelled.
+	ComputeInstanceProfiles map[int]map[int]nvml.ComputeInstanceProfileInfo
+
+	gpuInstances []*GpuInstance
+}
+
+// GpuInstance is a GPU instance on a shadow Device.
+type GpuInstance struct {
+	mock.GpuInstance
+	device           *Device
+	info             nvml.GpuInstanceInfo
+	profile          nvml.GpuInstanceProfileInfo
+	computeInstances []*ComputeInstance
+}
+
+// ComputeInstance is a compute instance in a shadow GpuInstance.
+type ComputeInstance struct {
+	mock.ComputeInstance
+	gpuInstance *GpuInstance
+	info        nvml.ComputeInstanceInfo
+}
+
+var _ nvml.Interface = (*Server)(nil)
+var _ nvml.Device = (*Device)(nil)
+var _ nvml.GpuInstance = (*GpuInstance)(nil)
+var _ nvml.ComputeInstance = (*ComputeInstance)(nil)
+
+// NewServer returns a Server holding 'devices', in index order.
+func NewServer(devices ...*Device) *Server {
+	s := &Server{Devices: devices}
+	s.setMockFuncs()
+	return s
+}
+
+// NewDevice returns a MIG capable Device with MIG mode disabled and empty
+// profile tables, to be filled in by the caller.
+func NewDevice() *Device {
+	d := &Device{
+		MigCapable:              true,
+		MigMode:                 nvml.DEVICE_MIG_DISABLE,
+		GpuInstanceProfiles:     make(map[int]nvml.GpuInstanceProfileInfo),
+		GpuInstancePlacements:   make(map[int][]nvml.GpuInstancePlacement),
+		ComputeInstanceProfiles: make(map[int]map[int]nvml.ComputeInstanceProfileInfo),
+	}
+	d.setMockFuncs()
+	return d
+}
+
+func (s *Server) setMockFuncs() {
+	s.ExtensionsFunc = func() nvml.ExtendedInterface {
+		return s
+	}
+
+	s.LookupSymbolFunc = func(symbol string) error {
+		return nil
+	}
+
+	s.InitFunc = func() nvml.Return {
+		return nvml.SUCCESS
+	}
+
+	s.ShutdownFunc = func() nvml.Return {
+		return nvml.SUCCESS
+	}
+
+	s.DeviceGetCountFunc = func() (int, nvml.Return) {
+		return len(s.Devices), nvml.SUCCESS
+	}
+
+	s.DeviceGetHandleByIndexFunc = func(index int) (nvml.Device, nvml.Return) {
+		if index < 0 || index >= len(s.Devices) {
+			return nil, nvml.ERROR_INVALID_ARGUMENT
+		}
+		return s.Devices[index], nvml.SUCCESS
+	}
+}
+
+func (d *Device) setMockFuncs() {
+	d.GetUUIDFunc = func() (string, nvml.Return) {
+		return d.UUID, nvml.SUCCESS
+	}
+
+	d.GetNameFunc = func() (string, nvml.Return) {
+		return d.Name, nvml.SUCCESS
+	}
+
+	d.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+		return d.PciInfo, nvml.SUCCESS
+	}
+
+	d.GetMemoryInfoFunc = func() (nvml.Memory, nvml.Return) {
+		return d.Memory, nvml.SUCCESS
+	}
+
+	d.GetVirtualizationModeFunc = func() (nvml.GpuVirtualizationMode, nvml.Return) {
+		return nvml.GPU_VIRTUALIZATION_MODE_NONE, nvml.SUCCESS
+	}
+
+	d.GetMigModeFunc = func() (int, int, nvml.Return) {
+		if !d.MigCapable {
+			return 0, 0, nvml.ERROR_NOT_SUPPORTED
+		}
+		d.Lock()
+		defer d.Unlock()
+		return d.MigMode, d.MigMode, nvml.SUCCESS
+	}
+
+	// MIG mode changes take effect immediately, i.e. the shadow copy
+	// assumes any GPU reset or reboot they need has already happened.
+	d.SetMigModeFunc = func(mode int) (nvml.Return, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		if mode == nvml.DEVICE_MIG_DISABLE && len(d.gpuInstances) != 0 {
+			return nvml.ERROR_IN_USE, nvml.ERROR_IN_USE
+		}
+		d.MigMode = mode
+		return nvml.SUCCESS, nvml.SUCCESS
+	}
+
+	d.GetGpuInstanceProfileInfoFunc = func(giProfileId int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+		if giProfileId < 0 || giProfileId >= nvml.GPU_INSTANCE_PROFILE_COUNT {
+			return nvml.GpuInstanceProfileInfo{}, nvml.ERROR_INVALID_ARGUMENT
+		}
+		info, exists := d.GpuInstanceProfiles[giProfileId]
+		if !exists {
+			return nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED
+		}
+		return info, nvml.SUCCESS
+	}
+
+	d.GetGpuInstancePossiblePlacementsFunc = func(info *nvml.GpuInstanceProfileInfo) ([]nvml.GpuInstancePlacement, nvml.Return) {
+		placements, exists := d.GpuInstancePlacements[int(info.Id)]
+		if !exists {
+			return nil, nvml.ERROR_NOT_SUPPORTED
+		}
+		return placements, nvml.SUCCESS
+	}
+
+	d.CreateGpuInstanceFunc = func(info *nvml.GpuInstanceProfileInfo) (nvml.GpuInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		for _, p := range d.GpuInstancePlacements[int(info.Id)] {
+			if d.canPlaceGpuInstance(info, p) == nvml.SUCCESS {
+				return d.createGpuInstance(info, p), nvml.SUCCESS
+			}
+		}
+		return nil, nvml.ERROR_INSUFFICIENT_RESOURCES
+	}
+
+	d.CreateGpuInstanceWithPlacementFunc = func(info *nvml.GpuInstanceProfileInfo, placement *nvml.GpuInstancePlacement) (nvml.GpuInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		if !containsGpuInstancePlacement(d.GpuInstancePlacements[int(info.Id)], *placement) {
+			return nil, nvml.ERROR_INVALID_ARGUMENT
+		}
+		ret := d.canPlaceGpuInstance(info, *placement)
+		if ret != nvml.SUCCESS {
+			return nil, ret
+		}
+		return d.createGpuInstance(info, *placement), nvml.SUCCESS
+	}
+
+	d.GetGpuInstancesFunc = func(info *nvml.GpuInstanceProfileInfo) ([]nvml.GpuInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		var gis []nvml.GpuInstance
+		for _, gi := range d.gpuInstances {
+			if gi.info.ProfileId == info.Id {
+				gis = append(gis, gi)
+			}
+		}
+		return gis, nvml.SUCCESS
+	}
+}
+
+// canPlaceGpuInstance checks whether a GPU instance of profile 'info' can be
+// created at 'placement'. The device must be locked.
+func (d *Device) canPlaceGpuInstance(info *nvml.GpuInstanceProfileInfo, placement nvml.GpuInstancePlacement) nvml.Return {
+	if d.MigMode != nvml.DEVICE_MIG_ENABLE {
+		return nvml.ERROR_NOT_SUPPORTED
+	}
+	count := 0
+	for _, gi := range d.gpuInstances {
+		if gi.info.ProfileId == info.Id {
+			count++
+		}
+		if overlaps(gi.info.Placement.Start, gi.info.Placement.Size, placement.Start, placement.Size) {
+			return nvml.ERROR_INSUFFICIENT_RESOURCES
+		}
+	}
+	if count >= int(info.InstanceCount) {
+		return nvml.ERROR_INSUFFICIENT_RESOURCES
+	}
+	return nvml.SUCCESS
+}
+
+// createGpuInstance creates a GPU instance of profile 'info' at 'placement'
+// with the lowest free ID. The device must be locked.
+func (d *Device) createGpuInstance(info *nvml.GpuInstanceProfileInfo, placement nvml.GpuInstancePlacement) *GpuInstance {
+	var ids []uint32
+	for _, gi := range d.gpuInstances {
+		ids = append(ids, gi.info.Id)
+	}
+	gi := &GpuInstance{
+		device:  d,
+		profile: *info,
+		info: nvml.GpuInstanceInfo{
+			Device:    d,
+			Id:        lowestFreeID(ids, 1),
+			ProfileId: info.Id,
+			Placement: placement,
+		},
+	}
+	gi.setMockFuncs()
+	d.gpuInstances = append(d.gpuInstances, gi)
+	return gi
+}
+
+func (gi *GpuInstance) setMockFuncs() {
+	d := gi.device
+
+	gi.GetInfoFunc = func() (nvml.GpuInstanceInfo, nvml.Return) {
+		return gi.info, nvml.SUCCESS
+	}
+
+	gi.GetComputeInstanceProfileInfoFunc = func(ciProfileId int, ciEngProfileId int) (nvml.ComputeInstanceProfileInfo, nvml.Return) {
+		if ciProfileId < 0 || ciProfileId >= nvml.COMPUTE_INSTANCE_PROFILE_COUNT {
+			return nvml.ComputeInstanceProfileInfo{}, nvml.ERROR_INVALID_ARGUMENT
+		}
+		if ciEngProfileId < 0 || ciEngProfileId >= nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_COUNT {
+			return nvml.ComputeInstanceProfileInfo{}, nvml.ERROR_INVALID_ARGUMENT
+		}
+		if ciEngProfileId != nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED {
+			return nvml.ComputeInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED
+		}
+		info, exists := d.ComputeInstanceProfiles[int(gi.info.ProfileId)][ciProfileId]
+		if !exists {
+			return nvml.ComputeInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED
+		}
+		return info, nvml.SUCCESS
+	}
+
+	gi.GetComputeInstancePossiblePlacementsFunc = func(info *nvml.ComputeInstanceProfileInfo) ([]nvml.ComputeInstancePlacement, nvml.Return) {
+		return computeInstancePlacements(gi.profile.SliceCount, info.SliceCount), nvml.SUCCESS
+	}
+
+	gi.CreateComputeInstanceFunc = func(info *nvml.ComputeInstanceProfileInfo) (nvml.ComputeInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		for _, p := range computeInstancePlacements(gi.profile.SliceCount, info.SliceCount) {
+			if gi.canPlaceComputeInstance(info, p) == nvml.SUCCESS {
+				return gi.createComputeInstance(info, p), nvml.SUCCESS
+			}
+		}
+		return nil, nvml.ERROR_INSUFFICIENT_RESOURCES
+	}
+
+	gi.CreateComputeInstanceWithPlacementFunc = func(info *nvml.ComputeInstanceProfileInfo, placement *nvml.ComputeInstancePlacement) (nvml.ComputeInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		if !containsComputeInstancePlacement(computeInstancePlacements(gi.profile.SliceCount, info.SliceCount), *placement) {
+			return nil, nvml.ERROR_INVALID_ARGUMENT
+		}
+		ret := gi.canPlaceComputeInstance(info, *placement)
+		if ret != nvml.SUCCESS {
+			return nil, ret
+		}
+		return gi.createComputeInstance(info, *placement), nvml.SUCCESS
+	}
+
+	gi.GetComputeInstancesFunc = func(info *nvml.ComputeInstanceProfileInfo) ([]nvml.ComputeInstance, nvml.Return) {
+		d.Lock()
+		defer d.Unlock()
+		var cis []nvml.ComputeInstance
+		for _, ci := range gi.computeInstances {
+			if ci.info.ProfileId == info.Id {
+				cis = append(cis, ci)
+			}
+		}
+		return cis, nvml.SUCCESS
+	}
+
+	gi.DestroyFunc = func() nvml.Return {
+		d.Lock()
+		defer d.Unlock()
+		if len(gi.computeInstances) != 0 {
+			return nvml.ERROR_IN_USE
+		}
+		for i, other := range d.gpuInstances {
+			if other == gi {
+				d.gpuInstances = append(d.gpuInstances[:i], d.gpuInstances[i+1:]...)
+				return nvml.SUCCESS
+			}
+		}
+		return nvml.ERROR_NOT_FOUND
+	}
+}
+
+// canPlaceComputeInstance checks whether a compute instance of profile 'info'
+// can be created at 'placement'. The device must be locked.
+func (gi *GpuInstance) canPlaceComputeInstance(info *nvml.ComputeInstanceProfileInfo, placement nvml.ComputeInstancePlacement) nvml.Return {
+	count := 0
+	for _, ci := range gi.computeInstances {
+		if ci.info.ProfileId == info.Id {
+			count++
+		}
+		if overlaps(ci.info.Placement.Start, ci.info.Placement.Size, placement.Start, placement.Size) {
+			return nvml.ERROR_INSUFFICIENT_RESOURCES
+		}
+	}
+	if count >= int(info.InstanceCount) {
+		return nvml.ERROR_INSUFFICIENT_RESOURCES
+	}
+	return nvml.SUCCESS
+}
+
+// createComputeInstance creates a compute instance of profile 'info' at
+// 'placement' with the lowest free ID. The device must be locked.
+func (gi *GpuInstance) createComputeInstance(info *nvml.ComputeInstanceProfileInfo, placement nvml.ComputeInstancePlacement) *ComputeInstance {
+	var ids []uint32
+	for _, ci := range gi.computeInstances {
+		ids = append(ids, ci.info.Id)
+	}
+	ci := &ComputeInstance{
+		gpuInstance: gi,
+		info: nvml.ComputeInstanceInfo{
+			Device:      gi.device,
+			GpuInstance: gi,
+			Id:          lowestFreeID(ids, 0),
+			ProfileId:   info.Id,
+			Placement:   placement,
+		},
+	}
+	ci.setMockFuncs()
+	gi.computeInstances = append(gi.computeInstances, ci)
+	return ci
+}
+
+func (ci *ComputeInstance) setMockFuncs() {
+	gi := ci.gpuInstance
+
+	ci.GetInfoFunc = func() (nvml.ComputeInstanceInfo, nvml.Return) {
+		return ci.info, nvml.SUCCESS
+	}
+
+	ci.DestroyFunc = func() nvml.Return {
+		gi.device.Lock()
+		defer gi.device.Unlock()
+		for i, other := range gi.computeInstances {
+			if other == ci {
+				gi.computeInstances = append(gi.computeInstances[:i], gi.computeInstances[i+1:]...)
+				return nvml.SUCCESS
+			}
+		}
+		return nvml.ERROR_NOT_FOUND
+	}
+}
+
+// computeInstancePlacements returns the placements of a compute instance
+// spanning 'size' slices of a GPU instance spanning 'giSize' slices. Compute
+// instances are aligned to their own size.
+func computeInstancePlacements(giSize uint32, size uint32) []nvml.ComputeInstancePlacement {
+	var placements []nvml.ComputeInstancePlacement
+	if size == 0 {
+		return nil
+	}
+	for start := uint32(0); start+size <= giSize; start += size {
+		placements = append(placements, nvml.ComputeInstancePlacement{Start: start, Size: size})
+	}
+	return placements
+}
+
+func overlaps(start1, size1, start2, size2 uint32) bool {
+	return start1 < start2+size2 && start2 < start1+size1
+}
+
+// lowestFreeID returns the lowest ID of at least 'first' not in 'ids'.
+func lowestFreeID(ids []uint32, first uint32) uint32 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	id := first
+	for _, used := range ids {
+		if used == id {
+			id++
+		}
+	}
+	return id
+}
+
+func containsGpuInstancePlacement(placements []nvml.GpuInstancePlacement, p nvml.GpuInstancePlacement) bool {
+	for _, q := range placements {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
+
+func containsComputeInstancePlacement(placements []nvml.ComputeInstancePlacement, p nvml.ComputeInstancePlacement) bool {
+	for _, q := range placements {
+		if q == p {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shadow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newLiveServer(t *testing.T) *testutil.Server {
+	return testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB}).
+		MustBuild()
+}
+
+func TestSnapshot(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	live := newLiveServer(t)
+	server, err := Snapshot(live)
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+	require.Len(t, server.Devices, 2)
+
+	liveConfig, err := config.NewMockNvmlMigConfigManager(live).GetMigConfig(0)
+	require.Nil(t, err)
+	current, err := config.NewMockNvmlMigConfigManager(server).GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, liveConfig, current)
+
+	// The mock does not report placements, so the first free one is assumed.
+	devices, err := config.NewMockNvmlMigConfigManager(server).GetMigDevices(0)
+	require.Nil(t, err)
+	require.Len(t, devices, 1)
+	require.Equal(t, nvml.GpuInstancePlacement{Start: 0, Size: 4}, devices[0].GpuInstancePlacement)
+
+	mode, _, ret := server.Devices[1].GetMigMode()
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, nvml.DEVICE_MIG_DISABLE, mode)
+}
+
+func TestShadowChangesLeaveLiveStateAlone(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	live := newLiveServer(t)
+	server, err := Snapshot(live)
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+
+	manager := config.NewMockNvmlMigConfigManager(server)
+	err = manager.ClearMigConfig(0)
+	require.Nil(t, err, "Unexpected failure from ClearMigConfig")
+	_, err = manager.SetMigConfig(0, types.MigConfig{"1g.5gb": 7})
+	require.Nil(t, err, "Unexpected failure from SetMigConfig")
+
+	current, err := manager.GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, types.MigConfig{"1g.5gb": 7}, current)
+
+	liveConfig, err := config.NewMockNvmlMigConfigManager(live).GetMigConfig(0)
+	require.Nil(t, err)
+	require.Equal(t, types.MigConfig{"3g.20gb": 1}, liveConfig)
+}
+
+func TestPlacementConflicts(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server, err := Snapshot(newLiveServer(t))
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+
+	start := func(s uint32) *uint32 { return &s }
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	// The existing 3g.20gb GPU instance occupies slices 0-3.
+	_, err = manager.CreateGpuInstance(0, "1g.5gb", start(2))
+	require.NotNil(t, err, "Unexpected success creating an overlapping GPU instance")
+
+	gi, err := manager.CreateGpuInstance(0, "3g.20gb", nil)
+	require.Nil(t, err, "Unexpected failure from CreateGpuInstance")
+	require.Equal(t, nvml.GpuInstancePlacement{Start: 4, Size: 4}, gi.Placement)
+
+	_, err = manager.CreateGpuInstance(0, "1g.5gb", nil)
+	require.NotNil(t, err, "Unexpected success creating a GPU instance on a full GPU")
+
+	err = manager.DestroyGpuInstance(0, gi.ID)
+	require.Nil(t, err, "Unexpected failure destroying an empty GPU instance")
+
+	gis, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Len(t, gis, 1)
+	err = manager.DestroyGpuInstance(0, gis[0].ID)
+	require.NotNil(t, err, "Unexpected success destroying a GPU instance holding compute instances")
+}
+
+func TestSetMigModeInUse(t *testing.T) {
+	server, err := Snapshot(newLiveServer(t))
+	require.Nil(t, err, "Unexpected failure from Snapshot")
+
+	ret, _ := server.Devices[0].SetMigMode(nvml.DEVICE_MIG_DISABLE)
+	require.Equal(t, nvml.ERROR_IN_USE, ret)
+
+	ret, _ = server.Devices[1].SetMigMode(nvml.DEVICE_MIG_ENABLE)
+	require.Equal(t, nvml.SUCCESS, ret)
+	mode, _, _ := server.Devices[1].GetMigMode()
+	require.Equal(t, nvml.DEVICE_MIG_ENABLE, mode)
+}
@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package shadow
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+)
+
+// computeInstanceProfileIDs maps the slice count of a compute instance to
+// its profile ID.
+var computeInstanceProfileIDs = map[uint32]int{
+	1: nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE,
+	2: nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE,
+	3: nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE,
+	4: nvml.COMPUTE_INSTANCE_PROFILE_4_SLICE,
+	6: nvml.COMPUTE_INSTANCE_PROFILE_6_SLICE,
+	7: nvml.COMPUTE_INSTANCE_PROFILE_7_SLICE,
+	8: nvml.COMPUTE_INSTANCE_PROFILE_8_SLICE,
+}
+
+// Snapshot copies the MIG state of every GPU known to 'nvmlLib' into a new
+// Server. NVML must already be initialized. Nothing on the GPUs is changed.
+func Snapshot(nvmlLib nvml.Interface) (*Server, error) {
+	count, ret := nvmlLib.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %w", nvmlerrors.New(ret))
+	}
+
+	var devices []*Device
+	for i := 0; i < count; i++ {
+		device, ret := nvmlLib.DeviceGetHandleByIndex(i)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle for GPU %d: %w", i, nvmlerrors.New(ret))
+		}
+		d, err := SnapshotDevice(device)
+		if err != nil {
+			return nil, fmt.Errorf("error copying GPU %d: %w", i, err)
+		}
+		devices = append(devices, d)
+	}
+
+	return NewServer(devices...), nil
+}
+
+// SnapshotDevice copies the profile and placement tables of 'device', along
+// with its MIG mode and the GPU and compute instances that currently exist
+// on it.
+//
+// NVML only reports the compute instance profiles of GPU instances that
+// exist. For GPU instance profiles without one, compute instance profiles
+// spanning all of the GPU instance, or at most about half of it, are assumed.
+func SnapshotDevice(device nvml.Device) (*Device, error) {
+	d := NewDevice()
+
+	var ret nvml.Return
+	d.UUID, ret = device.GetUUID()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting UUID: %w", nvmlerrors.New(ret))
+	}
+	d.Name, ret = device.GetName()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting name: %w", nvmlerrors.New(ret))
+	}
+	d.PciInfo, ret = device.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting PCI info: %w", nvmlerrors.New(ret))
+	}
+	d.Memory, ret = device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting memory info: %w", nvmlerrors.New(ret))
+	}
+
+	d.MigMode, _, ret = device.GetMigMode()
+	if ret == nvml.ERROR_NOT_SUPPORTED {
+		d.MigCapable = false
+		return d, nil
+	}
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting MIG mode: %w", nvmlerrors.New(ret))
+	}
+
+	for giProfileID := 0; giProfileID < nvml.GPU_INSTANCE_PROFILE_COUNT; giProfileID++ {
+		info, ret := device.GetGpuInstanceProfileInfo(giProfileID)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance profile info for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		placements, ret := device.GetGpuInstancePossiblePlacements(&info)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instance possible placements for '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		d.GpuInstanceProfiles[giProfileID] = info
+		d.GpuInstancePlacements[giProfileID] = placements
+		d.ComputeInstanceProfiles[giProfileID] = assumedComputeInstanceProfiles(info)
+
+		// GPU instances can only exist while MIG mode is enabled.
+		if d.MigMode != nvml.DEVICE_MIG_ENABLE {
+			continue
+		}
+		gis, ret := device.GetGpuInstances(&info)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instances for profile '%v': %w", giProfileID, nvmlerrors.New(ret))
+		}
+		for _, gi := range gis {
+			err := d.copyGpuInstance(gi, &info)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return d, nil
+}
+
+// copyGpuInstance adds a copy of 'gi' (of profile 'info') and its compute
+// instances to the device, replacing the assumed compute instance profiles
+// of 'info' with those reported for 'gi'.
+func (d *Device) copyGpuInstance(gi nvml.GpuInstance, info *nvml.GpuInstanceProfileInfo) error {
+	giInfo, ret := gi.GetInfo()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting GPU instance info: %w", nvmlerrors.New(ret))
+	}
+
+	copied := &GpuInstance{
+		device:  d,
+		profile: *info,
+		info: nvml.GpuInstanceInfo{
+			Device:    d,
+			Id:        giInfo.Id,
+			ProfileId: giInfo.ProfileId,
+			Placement: giInfo.Placement,
+		},
+	}
+	copied.setMockFuncs()
+
+	// A GPU instance whose placement is not reported is assumed to be at the
+	// first placement still free for its profile.
+	if giInfo.Placement.Size == 0 {
+		for _, p := range d.GpuInstancePlacements[int(info.Id)] {
+			if d.canPlaceGpuInstance(info, p) == nvml.SUCCESS {
+				copied.info.Placement = p
+				break
+			}
+		}
+	}
+
+	profiles := make(map[int]nvml.ComputeInstanceProfileInfo)
+	for ciProfileID := 0; ciProfileID < nvml.COMPUTE_INSTANCE_PROFILE_COUNT; ciProfileID++ {
+		for ciEngProfileID := 0; ciEngProfileID < nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_COUNT; ciEngProfileID++ {
+			ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(ciProfileID, ciEngProfileID)
+			if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+				continue
+			}
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting compute instance profile info for '(%v, %v)': %w", ciProfileID, ciEngProfileID, nvmlerrors.New(ret))
+			}
+			cis, ret := gi.GetComputeInstances(&ciProfileInfo)
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting compute instances for profile '(%v, %v)': %w", ciProfileID, ciEngProfileID, nvmlerrors.New(ret))
+			}
+			if ciEngProfileID != nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED {
+				if len(cis) != 0 {
+					return fmt.Errorf("compute instances with dedicated engines in GPU instance %d are not supported", giInfo.Id)
+				}
+				continue
+			}
+			profiles[ciProfileID] = ciProfileInfo
+			for _, ci := range cis {
+				ciInfo, ret := ci.GetInfo()
+				if ret != nvml.SUCCESS {
+					return fmt.Errorf("error getting compute instance info: %w", nvmlerrors.New(ret))
+				}
+				copiedCI := &ComputeInstance{
+					gpuInstance: copied,
+					info: nvml.ComputeInstanceInfo{
+						Device:      d,
+						GpuInstance: copied,
+						Id:          ciInfo.Id,
+						ProfileId:   ciInfo.ProfileId,
+						Placement:   ciInfo.Placement,
+					},
+				}
+				copiedCI.setMockFuncs()
+				copied.computeInstances = append(copied.computeInstances, copiedCI)
+			}
+		}
+	}
+
+	d.ComputeInstanceProfiles[int(info.Id)] = profiles
+	d.gpuInstances = append(d.gpuInstances, copied)
+	return nil
+}
+
+// assumedComputeInstanceProfiles returns the compute instance profiles
+// assumed for GPU instances of profile 'info'.
+func assumedComputeInstanceProfiles(info nvml.GpuInstanceProfileInfo) map[int]nvml.ComputeInstanceProfileInfo {
+	profiles := make(map[int]nvml.ComputeInstanceProfileInfo)
+	giSize := info.SliceCount
+	if giSize == 0 {
+		return profiles
+	}
+	for size, id := range computeInstanceProfileIDs {
+		if size != giSize && 2*size > giSize+1 {
+			continue
+		}
+		profiles[id] = nvml.ComputeInstanceProfileInfo{
+			Id:                  uint32(id),
+			SliceCount:          size,
+			InstanceCount:       giSize / size,
+			MultiprocessorCount: info.MultiprocessorCount * size / giSize,
+		}
+	}
+	return profiles
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// DeviceModel identifies the model and capacity of a GPU: its name, its PCI
+// device ID and its total memory in bytes. Fields left unset match any GPU.
+type DeviceModel struct {
+	Name     string
+	DeviceID types.DeviceID
+	Memory   uint64
+}
+
+// Matches checks whether 'other' is a GPU of the model described by 'm'.
+func (m DeviceModel) Matches(other DeviceModel) bool {
+	if m.Name != "" && m.Name != other.Name {
+		return false
+	}
+	if m.DeviceID != 0 && m.DeviceID != other.DeviceID {
+		return false
+	}
+	if m.Memory != 0 && m.Memory != other.Memory {
+		return false
+	}
+	return true
+}
+
+// String returns the name of the model (or its PCI device ID if it has no
+// name) and its memory, if known.
+func (m DeviceModel) String() string {
+	s := m.Name
+	if s == "" {
+		s = m.DeviceID.String()
+	}
+	if m.Memory != 0 {
+		s += fmt.Sprintf(" with %dMiB of memory", m.Memory/(1024*1024))
+	}
+	return s
+}
+
+// Rebind returns a copy of the provided 'MigState' with each GPU in it bound
+// to a GPU on the current node by model and capacity rather than by UUID, so
+// that a 'MigState' fetched on one node can be restored on another,
+// identically configured node. 'models' holds the model of each GPU in
+// 'state', in the same order. GPUs are bound in order to the first MIG
+// capable GPU of the same model not already bound to another.
+func (m *migStateManager) Rebind(state *types.MigState, models []DeviceModel) (*types.MigState, error) {
+	if len(models) != len(state.Devices) {
+		return nil, fmt.Errorf("models of %d of %d GPUs are known", len(models), len(state.Devices))
+	}
+
+	ret := m.nvml.Init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer tryNvmlShutdown(m.nvml)
+
+	type candidate struct {
+		uuid  string
+		model DeviceModel
+		bound bool
+	}
+
+	numGPUs, ret := m.nvml.DeviceGetCount()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting device count: %w", nvmlerrors.New(ret))
+	}
+
+	var candidates []*candidate
+	for gpu := 0; gpu < numGPUs; gpu++ {
+		capable, err := m.mode.IsMigCapable(gpu)
+		if err != nil {
+			return nil, fmt.Errorf("error checking MIG capable: %w", err)
+		}
+		if !capable {
+			continue
+		}
+
+		device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
+		}
+
+		model, err := getDeviceModel(device)
+		if err != nil {
+			return nil, fmt.Errorf("error getting model of GPU %d: %w", gpu, err)
+		}
+
+		uuid, ret := device.GetUUID()
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting device uuid: %w", nvmlerrors.New(ret))
+		}
+
+		candidates = append(candidates, &candidate{uuid: uuid, model: model})
+	}
+
+	rebound := types.MigState{}
+	for i, deviceState := range state.Devices {
+		var match *candidate
+		for _, c := range candidates {
+			if !c.bound && models[i].Matches(c.model) {
+				match = c
+				break
+			}
+		}
+		if match == nil {
+			return nil, fmt.Errorf("no GPU on the node left to match GPU '%v' (%v)", deviceState.UUID, models[i])
+		}
+		match.bound = true
+
+		log.Debugf("Binding GPU '%v' to GPU '%v'", deviceState.UUID, match.uuid)
+		deviceState.UUID = match.uuid
+		rebound.Devices = append(rebound.Devices, deviceState)
+	}
+
+	return &rebound, nil
+}
+
+func getDeviceModel(device nvml.Device) (DeviceModel, error) {
+	name, ret := device.GetName()
+	if ret != nvml.SUCCESS {
+		return DeviceModel{}, fmt.Errorf("error getting device name: %w", nvmlerrors.New(ret))
+	}
+
+	pciInfo, ret := device.GetPciInfo()
+	if ret != nvml.SUCCESS {
+		return DeviceModel{}, fmt.Errorf("error getting PCI info: %w", nvmlerrors.New(ret))
+	}
+
+	memory, ret := device.GetMemoryInfo()
+	if ret != nvml.SUCCESS {
+		return DeviceModel{}, fmt.Errorf("error getting memory info: %w", nvmlerrors.New(ret))
+	}
+
+	return DeviceModel{
+		Name:     name,
+		DeviceID: types.DeviceID(pciInfo.PciDeviceId),
+		Memory:   memory.Total,
+	}, nil
+}
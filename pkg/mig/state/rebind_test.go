@@ -0,0 +1,133 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestRebind(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	newServer := func(models ...testutil.GPUModel) *testutil.Server {
+		builder := testutil.NewServerBuilder()
+		for _, model := range models {
+			builder = builder.WithGPU(testutil.GPU{
+				Model:      model,
+				MigEnabled: true,
+				GpuInstances: []testutil.GpuInstance{
+					{Profile: nvml.GPU_INSTANCE_PROFILE_3_SLICE},
+				},
+			})
+		}
+		return builder.MustBuild()
+	}
+
+	getModels := func(server *testutil.Server) []DeviceModel {
+		var models []DeviceModel
+		for _, d := range server.Devices {
+			model, err := getDeviceModel(d)
+			require.Nil(t, err)
+			models = append(models, model)
+		}
+		return models
+	}
+
+	source := newServer(testutil.A100_SXM4_80GB, testutil.A100_SXM4_40GB)
+	checkpointed, err := NewMockMigStateManager(source).Fetch()
+	require.Nil(t, err)
+	models := getModels(source)
+
+	testCases := []struct {
+		description     string
+		target          *testutil.Server
+		models          []DeviceModel
+		expectedGPUs    []int
+		expectedFailure bool
+	}{
+		{
+			"Same models in the same order",
+			newServer(testutil.A100_SXM4_80GB, testutil.A100_SXM4_40GB),
+			models,
+			[]int{0, 1},
+			false,
+		},
+		{
+			"Same models in a different order",
+			newServer(testutil.A100_SXM4_40GB, testutil.A100_SXM4_80GB),
+			models,
+			[]int{1, 0},
+			false,
+		},
+		{
+			"Only names known",
+			newServer(testutil.A100_SXM4_40GB, testutil.A100_SXM4_80GB),
+			[]DeviceModel{{Name: models[0].Name}, {Name: models[1].Name}},
+			[]int{1, 0},
+			false,
+		},
+		{
+			"Different capacity",
+			newServer(testutil.A100_SXM4_40GB, testutil.A100_SXM4_40GB),
+			models,
+			nil,
+			true,
+		},
+		{
+			"Fewer GPUs",
+			newServer(testutil.A100_SXM4_80GB),
+			models,
+			nil,
+			true,
+		},
+		{
+			"Models missing",
+			newServer(testutil.A100_SXM4_80GB, testutil.A100_SXM4_40GB),
+			nil,
+			nil,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockMigStateManager(tc.target)
+
+			rebound, err := manager.Rebind(checkpointed, tc.models)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Rebind")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Rebind")
+
+			require.Len(t, rebound.Devices, len(tc.expectedGPUs))
+			for i, gpu := range tc.expectedGPUs {
+				uuid, ret := tc.target.Devices[gpu].GetUUID()
+				require.Equal(t, nvml.SUCCESS, ret)
+				require.Equal(t, uuid, rebound.Devices[i].UUID)
+				require.Equal(t, checkpointed.Devices[i].GpuInstances, rebound.Devices[i].GpuInstances)
+			}
+		})
+	}
+}
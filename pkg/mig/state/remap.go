@@ -0,0 +1,107 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package state
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Remap returns a copy of the provided 'MigState' with the GPU instance
+// profile IDs it records translated to the equivalent profiles under the
+// running driver. Profiles are matched by slice count and memory size, so a
+// 'MigState' fetched under one driver can still be restored after the IDs
+// of its profiles change under another. GPU instances recorded without these
+// attributes are left untouched.
+func (m *migStateManager) Remap(state *types.MigState) (*types.MigState, error) {
+	ret := m.nvml.Init()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer tryNvmlShutdown(m.nvml)
+
+	remapped := types.MigState{}
+	for _, deviceState := range state.Devices {
+		if deviceState.MigMode == mode.Disabled || len(deviceState.GpuInstances) == 0 {
+			remapped.Devices = append(remapped.Devices, deviceState)
+			continue
+		}
+
+		device, ret := m.nvml.DeviceGetHandleByUUID(deviceState.UUID)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("device '%v' not found: %w", deviceState.UUID, nvmlerrors.New(ret))
+		}
+
+		gpuInstances := make([]types.GpuInstanceState, len(deviceState.GpuInstances))
+		for i, giState := range deviceState.GpuInstances {
+			profileID, err := remapGpuInstanceProfile(device, &giState)
+			if err != nil {
+				return nil, fmt.Errorf("error remapping GPU instance profile '%v' on device '%v': %w", giState.ProfileID, deviceState.UUID, err)
+			}
+			if profileID != giState.ProfileID {
+				log.Debugf("Remapping GPU instance profile '%v' to '%v' on device '%v'", giState.ProfileID, profileID, deviceState.UUID)
+			}
+			giState.ProfileID = profileID
+			gpuInstances[i] = giState
+		}
+		deviceState.GpuInstances = gpuInstances
+
+		remapped.Devices = append(remapped.Devices, deviceState)
+	}
+
+	return &remapped, nil
+}
+
+// remapGpuInstanceProfile returns the ID of the GPU instance profile on
+// 'device' whose slice count and memory size match those recorded in
+// 'giState'. The recorded profile ID is preferred if it still matches.
+func remapGpuInstanceProfile(device nvml.Device, giState *types.GpuInstanceState) (int, error) {
+	if giState.SliceCount == 0 {
+		return giState.ProfileID, nil
+	}
+
+	matches := func(info nvml.GpuInstanceProfileInfo) bool {
+		return info.SliceCount == giState.SliceCount && info.MemorySizeMB == giState.MemorySizeMB
+	}
+
+	info, ret := device.GetGpuInstanceProfileInfo(giState.ProfileID)
+	if ret == nvml.SUCCESS && matches(info) {
+		return giState.ProfileID, nil
+	}
+
+	for i := 0; i < nvml.GPU_INSTANCE_PROFILE_COUNT; i++ {
+		info, ret := device.GetGpuInstanceProfileInfo(i)
+		if ret == nvml.ERROR_NOT_SUPPORTED || ret == nvml.ERROR_INVALID_ARGUMENT {
+			continue
+		}
+		if ret != nvml.SUCCESS {
+			return -1, fmt.Errorf("error getting GPU instance profile info for '%v': %w", i, nvmlerrors.New(ret))
+		}
+		if matches(info) {
+			return i, nil
+		}
+	}
+
+	return -1, fmt.Errorf("no GPU instance profile with %d slices and %dMB of memory", giState.SliceCount, giState.MemorySizeMB)
+}
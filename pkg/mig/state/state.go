@@ -23,9 +23,10 @@ import (
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
-	"github.com/NVIDIA/mig-parted/internal/nvlib"
 	"github.com/NVIDIA/mig-parted/pkg/mig/config"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
@@ -34,6 +35,9 @@ type Manager interface {
 	Fetch() (*types.MigState, error)
 	RestoreMode(state *types.MigState) error
 	RestoreConfig(state *types.MigState) error
+	Validate(state *types.MigState) error
+	Remap(state *types.MigState) (*types.MigState, error)
+	Rebind(state *types.MigState, models []DeviceModel) (*types.MigState, error)
 }
 
 type migStateManager struct {
@@ -76,20 +80,20 @@ func NewMockMigStateManager(nvml nvml.Interface) Manager {
 func (m *migStateManager) Fetch() (*types.MigState, error) {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return nil, fmt.Errorf("error initializing NVML: %v", ret)
+		return nil, fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	numGPUs, ret := m.nvml.DeviceGetCount()
 	if ret != nvml.SUCCESS {
-		return nil, fmt.Errorf("error getting device count: %v", ret)
+		return nil, fmt.Errorf("error getting device count: %w", nvmlerrors.New(ret))
 	}
 
 	var migState types.MigState
 	for gpu := 0; gpu < numGPUs; gpu++ {
 		capable, err := m.mode.IsMigCapable(gpu)
 		if err != nil {
-			return nil, fmt.Errorf("error checking MIG capable: %v", err)
+			return nil, fmt.Errorf("error checking MIG capable: %w", err)
 		}
 
 		if !capable {
@@ -98,12 +102,12 @@ func (m *migStateManager) Fetch() (*types.MigState, error) {
 
 		device, ret := m.nvml.DeviceGetHandleByIndex(gpu)
 		if ret != nvml.SUCCESS {
-			return nil, fmt.Errorf("error getting device handle: %v", ret)
+			return nil, fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 		}
 
 		uuid, ret := device.GetUUID()
 		if ret != nvml.SUCCESS {
-			return nil, fmt.Errorf("error getting device uuid: %v", ret)
+			return nil, fmt.Errorf("error getting device uuid: %w", nvmlerrors.New(ret))
 		}
 
 		deviceState := types.DeviceState{
@@ -112,7 +116,7 @@ func (m *migStateManager) Fetch() (*types.MigState, error) {
 
 		deviceState.MigMode, err = m.mode.GetMigMode(gpu)
 		if err != nil {
-			return nil, fmt.Errorf("error getting MIG mode: %v", err)
+			return nil, fmt.Errorf("error getting MIG mode: %w", err)
 		}
 
 		if deviceState.MigMode == mode.Disabled {
@@ -123,12 +127,14 @@ func (m *migStateManager) Fetch() (*types.MigState, error) {
 		err = m.nvlib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, giProfileInfo nvml.GpuInstanceProfileInfo) error {
 			giInfo, ret := gi.GetInfo()
 			if ret != nvml.SUCCESS {
-				return fmt.Errorf("error getting GPU instance info for '%v': %v", giProfileID, ret)
+				return fmt.Errorf("error getting GPU instance info for '%v': %w", giProfileID, nvmlerrors.New(ret))
 			}
 
 			giState := types.GpuInstanceState{
-				ProfileID: giProfileID,
-				Placement: giInfo.Placement,
+				ProfileID:    giProfileID,
+				SliceCount:   giProfileInfo.SliceCount,
+				MemorySizeMB: giProfileInfo.MemorySizeMB,
+				Placement:    giInfo.Placement,
 			}
 
 			err := m.nvlib.Mig.GpuInstance(gi).WalkComputeInstances(func(ci nvml.ComputeInstance, ciProfileID int, ciEngProfileID int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error {
@@ -141,13 +147,13 @@ func (m *migStateManager) Fetch() (*types.MigState, error) {
 				return nil
 			})
 			if err != nil {
-				return fmt.Errorf("error walking compute instances for '%v': %v", giProfileID, err)
+				return fmt.Errorf("error walking compute instances for '%v': %w", giProfileID, err)
 			}
 			deviceState.GpuInstances = append(deviceState.GpuInstances, giState)
 			return nil
 		})
 		if err != nil {
-			return nil, fmt.Errorf("error walking gpu instances for '%v': %v", gpu, err)
+			return nil, fmt.Errorf("error walking gpu instances for '%v': %w", gpu, err)
 		}
 		migState.Devices = append(migState.Devices, deviceState)
 	}
@@ -159,24 +165,24 @@ func (m *migStateManager) Fetch() (*types.MigState, error) {
 func (m *migStateManager) RestoreMode(state *types.MigState) error {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error initializing NVML: %v", ret)
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
 	for _, deviceState := range state.Devices {
 		device, ret := m.nvml.DeviceGetHandleByUUID(deviceState.UUID)
 		if ret != nvml.SUCCESS {
-			return fmt.Errorf("error getting device handle: %v", ret)
+			return fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 		}
 
 		index, ret := device.GetIndex()
 		if ret != nvml.SUCCESS {
-			return fmt.Errorf("error getting device index: %v", ret)
+			return fmt.Errorf("error getting device index: %w", nvmlerrors.New(ret))
 		}
 
 		err := m.mode.SetMigMode(index, deviceState.MigMode)
 		if err != nil {
-			return fmt.Errorf("error setting MIG mode on device '%v': %v", deviceState.UUID, err)
+			return fmt.Errorf("error setting MIG mode on device '%v': %w", deviceState.UUID, err)
 		}
 	}
 
@@ -187,7 +193,7 @@ func (m *migStateManager) RestoreMode(state *types.MigState) error {
 func (m *migStateManager) RestoreConfig(state *types.MigState) error {
 	ret := m.nvml.Init()
 	if ret != nvml.SUCCESS {
-		return fmt.Errorf("error initializing NVML: %v", ret)
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
 	}
 	defer tryNvmlShutdown(m.nvml)
 
@@ -198,40 +204,40 @@ func (m *migStateManager) RestoreConfig(state *types.MigState) error {
 
 		device, ret := m.nvml.DeviceGetHandleByUUID(deviceState.UUID)
 		if ret != nvml.SUCCESS {
-			return fmt.Errorf("error getting device handle: %v", ret)
+			return fmt.Errorf("error getting device handle: %w", nvmlerrors.New(ret))
 		}
 
 		index, ret := device.GetIndex()
 		if ret != nvml.SUCCESS {
-			return fmt.Errorf("error getting device index: %v", ret)
+			return fmt.Errorf("error getting device index: %w", nvmlerrors.New(ret))
 		}
 
 		err := m.config.ClearMigConfig(index)
 		if err != nil {
-			return fmt.Errorf("error clearing existing MIG config: %v", err)
+			return fmt.Errorf("error clearing existing MIG config: %w", err)
 		}
 
 		for _, giState := range deviceState.GpuInstances {
 			giProfileInfo, ret := device.GetGpuInstanceProfileInfo(giState.ProfileID)
 			if ret != nvml.SUCCESS {
-				return fmt.Errorf("error getting GPU instance profile info for '%v': %v", giState.ProfileID, ret)
+				return fmt.Errorf("error getting GPU instance profile info for '%v': %w", giState.ProfileID, nvmlerrors.New(ret))
 			}
 
 			placement := giState.Placement
 			gi, ret := device.CreateGpuInstanceWithPlacement(&giProfileInfo, &placement)
 			if ret != nvml.SUCCESS {
-				return fmt.Errorf("error creating GPU instance for '%v': %v", giState.ProfileID, ret)
+				return fmt.Errorf("error creating GPU instance for '%v': %w", giState.ProfileID, nvmlerrors.New(ret))
 			}
 
 			for _, ciState := range giState.ComputeInstances {
 				ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(ciState.ProfileID, ciState.EngProfileID)
 				if ret != nvml.SUCCESS {
-					return fmt.Errorf("error getting Compute instance profile info for '(%v, %v)': %v", ciState.ProfileID, ciState.EngProfileID, ret)
+					return fmt.Errorf("error getting Compute instance profile info for '(%v, %v)': %w", ciState.ProfileID, ciState.EngProfileID, nvmlerrors.New(ret))
 				}
 
 				_, ret = gi.CreateComputeInstance(&ciProfileInfo)
 				if ret != nvml.SUCCESS {
-					return fmt.Errorf("error creating Compute instance for '(%v, %v)': %v", ciState.ProfileID, ciState.EngProfileID, ret)
+					return fmt.Errorf("error creating Compute instance for '(%v, %v)': %w", ciState.ProfileID, ciState.EngProfileID, nvmlerrors.New(ret))
 				}
 			}
 		}
@@ -239,3 +245,74 @@ func (m *migStateManager) RestoreConfig(state *types.MigState) error {
 
 	return nil
 }
+
+// Validate checks that the provided 'MigState' can be restored on the current node.
+// It ensures that every GPU in the 'MigState' is present and that the current
+// driver still supports the GPU instance profiles and placements it references.
+// Profiles and placements can only be queried once MIG mode is enabled, so
+// they are not checked on GPUs that have MIG mode disabled until it is restored.
+func (m *migStateManager) Validate(state *types.MigState) error {
+	ret := m.nvml.Init()
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error initializing NVML: %w", nvmlerrors.New(ret))
+	}
+	defer tryNvmlShutdown(m.nvml)
+
+	for _, deviceState := range state.Devices {
+		device, ret := m.nvml.DeviceGetHandleByUUID(deviceState.UUID)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("device '%v' not found: %w", deviceState.UUID, nvmlerrors.New(ret))
+		}
+
+		if deviceState.MigMode == mode.Disabled {
+			continue
+		}
+
+		index, ret := device.GetIndex()
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting device index: %w", nvmlerrors.New(ret))
+		}
+
+		capable, err := m.mode.IsMigCapable(index)
+		if err != nil {
+			return fmt.Errorf("error checking MIG capable: %w", err)
+		}
+		if !capable {
+			return fmt.Errorf("device '%v' is not MIG capable", deviceState.UUID)
+		}
+
+		current, err := m.mode.GetMigMode(index)
+		if err != nil {
+			return fmt.Errorf("error getting MIG mode: %w", err)
+		}
+		if current != mode.Enabled {
+			log.Debugf("MIG mode not yet enabled on device '%v', skipping profile validation", deviceState.UUID)
+			continue
+		}
+
+		for _, giState := range deviceState.GpuInstances {
+			giProfileInfo, ret := device.GetGpuInstanceProfileInfo(giState.ProfileID)
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("GPU instance profile '%v' not supported on device '%v': %w", giState.ProfileID, deviceState.UUID, nvmlerrors.New(ret))
+			}
+
+			placements, ret := device.GetGpuInstancePossiblePlacements(&giProfileInfo)
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting possible placements for GPU instance profile '%v': %w", giState.ProfileID, nvmlerrors.New(ret))
+			}
+
+			found := false
+			for _, p := range placements {
+				if p == giState.Placement {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("placement %+v not valid for GPU instance profile '%v' on device '%v'", giState.Placement, giState.ProfileID, deviceState.UUID)
+			}
+		}
+	}
+
+	return nil
+}
@@ -23,15 +23,15 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
-	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
 
 	"github.com/NVIDIA/mig-parted/pkg/mig/config"
 	"github.com/NVIDIA/mig-parted/pkg/mig/mode"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
 	"github.com/NVIDIA/mig-parted/pkg/types"
 )
 
 func newMockMigStateManagerOnLunaServer() *migStateManager {
-	nvml := dgxa100.New()
+	nvml := testutil.NewDGXA100()
 	return NewMockMigStateManager(nvml).(*migStateManager)
 }
 
@@ -84,7 +84,7 @@ func TestFetchRestore(t *testing.T) {
 				require.Nil(t, err)
 
 				if tc.mode == mode.Enabled {
-					err = manager.config.SetMigConfig(i, tc.config)
+					_, err = manager.config.SetMigConfig(i, tc.config)
 					require.Nil(t, err, "Unexpected failure from SetMigConfig")
 				}
 
@@ -105,3 +105,180 @@ func TestFetchRestore(t *testing.T) {
 		})
 	}
 }
+
+// newValidateTestServer returns a mock server whose GPU 0 has UUID 'uuid',
+// has MIG mode set to 'migEnabled' and, like real hardware, rejects GPU
+// instance profile queries while MIG mode is disabled.
+func newValidateTestServer(uuid string, migEnabled bool) *testutil.Server {
+	server := testutil.NewServerBuilder().WithGPU(testutil.GPU{Model: testutil.A100_SXM4_40GB, MigEnabled: migEnabled}).MustBuild()
+
+	device := server.Devices[0].(*testutil.Device)
+	device.UUID = uuid
+	getGpuInstanceProfileInfo := device.GetGpuInstanceProfileInfoFunc
+	device.GetGpuInstanceProfileInfoFunc = func(giProfileId int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+		if device.MigMode != nvml.DEVICE_MIG_ENABLE {
+			return nvml.GpuInstanceProfileInfo{}, nvml.ERROR_NOT_SUPPORTED
+		}
+		return getGpuInstanceProfileInfo(giProfileId)
+	}
+
+	return server
+}
+
+func TestValidate(t *testing.T) {
+	uuid := "GPU-00000000-0000-0000-0000-000000000000"
+
+	newState := func(giProfileID int, placement nvml.GpuInstancePlacement) *types.MigState {
+		return &types.MigState{
+			Devices: []types.DeviceState{
+				{
+					UUID:    uuid,
+					MigMode: mode.Enabled,
+					GpuInstances: []types.GpuInstanceState{
+						{
+							ProfileID: giProfileID,
+							Placement: placement,
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		description     string
+		migEnabled      bool
+		state           *types.MigState
+		expectedFailure bool
+	}{
+		{
+			"Valid placement",
+			true,
+			newState(nvml.GPU_INSTANCE_PROFILE_3_SLICE, nvml.GpuInstancePlacement{Start: 4, Size: 4}),
+			false,
+		},
+		{
+			"Invalid placement",
+			true,
+			newState(nvml.GPU_INSTANCE_PROFILE_3_SLICE, nvml.GpuInstancePlacement{Start: 2, Size: 4}),
+			true,
+		},
+		{
+			"Unsupported GPU instance profile",
+			true,
+			newState(nvml.GPU_INSTANCE_PROFILE_6_SLICE, nvml.GpuInstancePlacement{Start: 0, Size: 6}),
+			true,
+		},
+		{
+			"MIG mode not yet enabled skips profile checks",
+			false,
+			newState(nvml.GPU_INSTANCE_PROFILE_3_SLICE, nvml.GpuInstancePlacement{Start: 4, Size: 4}),
+			false,
+		},
+		{
+			"Unknown device",
+			true,
+			&types.MigState{
+				Devices: []types.DeviceState{
+					{
+						UUID:    "GPU-bogus",
+						MigMode: mode.Disabled,
+					},
+				},
+			},
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			manager := NewMockMigStateManager(newValidateTestServer(uuid, tc.migEnabled))
+			err := manager.Validate(tc.state)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Validate")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Validate")
+			}
+		})
+	}
+}
+
+func TestRemap(t *testing.T) {
+	manager := newMockMigStateManagerOnLunaServer()
+
+	device, ret := manager.nvml.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	uuid, ret := device.GetUUID()
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	newState := func(giProfileID int, sliceCount uint32, memorySizeMB uint64) *types.MigState {
+		return &types.MigState{
+			Devices: []types.DeviceState{
+				{
+					UUID:    uuid,
+					MigMode: mode.Enabled,
+					GpuInstances: []types.GpuInstanceState{
+						{
+							ProfileID:    giProfileID,
+							SliceCount:   sliceCount,
+							MemorySizeMB: memorySizeMB,
+							Placement:    nvml.GpuInstancePlacement{Start: 4, Size: 4},
+						},
+					},
+				},
+			},
+		}
+	}
+
+	testCases := []struct {
+		description       string
+		state             *types.MigState
+		expectedProfileID int
+		expectedFailure   bool
+	}{
+		{
+			"Matching profile ID",
+			newState(nvml.GPU_INSTANCE_PROFILE_3_SLICE, 3, 19968),
+			nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+			false,
+		},
+		{
+			"No recorded attributes",
+			newState(nvml.GPU_INSTANCE_PROFILE_6_SLICE, 0, 0),
+			nvml.GPU_INSTANCE_PROFILE_6_SLICE,
+			false,
+		},
+		{
+			"Unsupported profile ID",
+			newState(nvml.GPU_INSTANCE_PROFILE_6_SLICE, 3, 19968),
+			nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+			false,
+		},
+		{
+			"Mismatched profile ID",
+			newState(nvml.GPU_INSTANCE_PROFILE_2_SLICE, 3, 19968),
+			nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+			false,
+		},
+		{
+			"No matching profile",
+			newState(nvml.GPU_INSTANCE_PROFILE_3_SLICE, 3, 1024),
+			-1,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			original := tc.state.Devices[0].GpuInstances[0].ProfileID
+			remapped, err := manager.Remap(tc.state)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Remap")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Remap")
+			require.Equal(t, tc.expectedProfileID, remapped.Devices[0].GpuInstances[0].ProfileID)
+			require.Equal(t, original, tc.state.Devices[0].GpuInstances[0].ProfileID, "Remap modified its input")
+		})
+	}
+}
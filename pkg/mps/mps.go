@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package mps reads and writes the file describing the MIG devices to share
+// through MPS, along with the directories each of their MPS control daemons
+// should use.
+package mps
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Well-known locations of the MPS config file and of the directories the
+// pipe and log directories of each MPS control daemon are created under.
+const (
+	DefaultConfigFile    = "/run/nvidia-mig-manager/mps-config.json"
+	DefaultPipeDirectory = "/tmp/nvidia-mps"
+	DefaultLogDirectory  = "/var/log/nvidia-mps"
+)
+
+// Device describes a MIG device to share through MPS. MPS needs one control
+// daemon per MIG device, started with 'CUDA_VISIBLE_DEVICES' set to its UUID
+// and 'CUDA_MPS_PIPE_DIRECTORY' and 'CUDA_MPS_LOG_DIRECTORY' set to the
+// directories below.
+type Device struct {
+	GPU           int    `json:"gpu"`
+	UUID          string `json:"uuid"`
+	Profile       string `json:"profile"`
+	PipeDirectory string `json:"pipe-directory"`
+	LogDirectory  string `json:"log-directory"`
+}
+
+// Config is the content of the MPS config file.
+type Config struct {
+	Devices []Device `json:"devices"`
+}
+
+// NewDevice returns the 'Device' for the MIG device with 'uuid' on 'gpu',
+// giving it a pipe and log directory of its own under 'pipeRoot' and
+// 'logRoot'.
+func NewDevice(gpu int, uuid string, profile string, pipeRoot string, logRoot string) Device {
+	return Device{
+		GPU:           gpu,
+		UUID:          uuid,
+		Profile:       profile,
+		PipeDirectory: filepath.Join(pipeRoot, uuid),
+		LogDirectory:  filepath.Join(logRoot, uuid),
+	}
+}
+
+// UUIDs returns the comma separated UUIDs of all devices in 'c'.
+func (c *Config) UUIDs() string {
+	var uuids []string
+	for _, d := range c.Devices {
+		uuids = append(uuids, d.UUID)
+	}
+	return strings.Join(uuids, ",")
+}
+
+// Write atomically writes 'config' to 'path', creating its parent directory if needed.
+func Write(path string, config *Config) error {
+	output, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling MPS config: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating MPS config directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(output, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing MPS config: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing MPS config: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads the MPS config at 'path'. It returns nil if there is none.
+func Read(path string) (*Config, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading MPS config: %w", err)
+	}
+
+	var config Config
+	err = json.Unmarshal(content, &config)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling MPS config: %w", err)
+	}
+
+	return &config, nil
+}
+
+// Clear removes the MPS config at 'path' if it exists.
+func Clear(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing MPS config: %w", err)
+	}
+	return nil
+}
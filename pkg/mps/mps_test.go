@@ -0,0 +1,56 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvidia-mig-manager", "mps-config.json")
+
+	config, err := Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Nil(t, config, "Unexpected config before Write")
+
+	expected := &Config{
+		Devices: []Device{
+			NewDevice(0, "MIG-a", "1g.5gb", DefaultPipeDirectory, DefaultLogDirectory),
+			NewDevice(1, "MIG-b", "3g.20gb", DefaultPipeDirectory, DefaultLogDirectory),
+		},
+	}
+	require.Equal(t, "/tmp/nvidia-mps/MIG-a", expected.Devices[0].PipeDirectory)
+	require.Equal(t, "/var/log/nvidia-mps/MIG-a", expected.Devices[0].LogDirectory)
+	require.Equal(t, "MIG-a,MIG-b", expected.UUIDs())
+
+	err = Write(path, expected)
+	require.Nil(t, err, "Unexpected failure from Write")
+
+	config, err = Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Equal(t, expected, config)
+
+	err = Clear(path)
+	require.Nil(t, err, "Unexpected failure from Clear")
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Config not removed")
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package nodelock implements the client side of the handshake through which
+// the components that mutate the GPUs of a node through NVML during bring-up
+// (the GPU operator's validator, the device plugin and nvidia-mig-parted)
+// keep from interleaving their mutations.
+//
+// The handshake is an advisory flock(2) on a lock file shared by all of them
+// (DefaultPath unless configured otherwise):
+//
+//  1. Open the lock file read-write, creating it (mode 0644) if missing.
+//  2. Take an exclusive flock on it, waiting for whoever holds it.
+//  3. Truncate it and write a single line naming the holder
+//     ('<component> pid=<pid> since=<RFC 3339 time>'), for diagnostics only.
+//  4. Perform the NVML mutations (MIG mode changes, creating or destroying
+//     GPU and compute instances, GPU resets).
+//  5. Truncate it again, release the flock and close it.
+//
+// The lock file is never removed, as that would let a component lock a file
+// that another has already replaced. Components only reading from NVML need
+// not take the lock. Scripts can take part with flock(1), e.g. 'flock
+// /run/nvidia/nvml.lock <command>'. Since the lock is released by the kernel
+// when its holder exits, a crashed holder never blocks the others.
+package nodelock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// DefaultPath is the lock file shared by the components of the GPU operator.
+const DefaultPath = "/run/nvidia/nvml.lock"
+
+// pollInterval is how often the lock is retried while it is held.
+const pollInterval = 100 * time.Millisecond
+
+// ErrTimeout is returned by Acquire if the lock is not released in time.
+var ErrTimeout = errors.New("timed out waiting for the node lock")
+
+// Lock is a node lock held by this process.
+type Lock struct {
+	file *os.File
+}
+
+// Acquire takes the lock file at 'path' for 'owner' (the name of the calling
+// component), waiting up to 'timeout' for its current holder to release it
+// (forever if 'timeout' is not positive) unless 'ctx' is done first.
+func Acquire(ctx context.Context, path string, owner string, timeout time.Duration) (*Lock, error) {
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening lock file: %w", err)
+	}
+
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+
+	for {
+		err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			file.Close()
+			return nil, fmt.Errorf("error locking '%v': %w", path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			file.Close()
+			return nil, ctx.Err()
+		case <-deadline:
+			holder, _ := Holder(path)
+			file.Close()
+			if holder == "" {
+				holder = "unknown"
+			}
+			return nil, fmt.Errorf("%w '%v' after %v (held by %v)", ErrTimeout, path, timeout, holder)
+		case <-time.After(pollInterval):
+		}
+	}
+
+	l := &Lock{file: file}
+	line := fmt.Sprintf("%v pid=%d since=%v\n", owner, os.Getpid(), time.Now().UTC().Format(time.RFC3339))
+	err = l.write(line)
+	if err != nil {
+		l.Release()
+		return nil, fmt.Errorf("error recording holder of '%v': %w", path, err)
+	}
+	return l, nil
+}
+
+// Release releases the lock. It is safe to call more than once.
+func (l *Lock) Release() error {
+	if l.file == nil {
+		return nil
+	}
+	// The holder line is only a hint, so failing to clear it is not an error.
+	_ = l.write("")
+	err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	cerr := l.file.Close()
+	l.file = nil
+	if err != nil {
+		return fmt.Errorf("error unlocking: %w", err)
+	}
+	return cerr
+}
+
+func (l *Lock) write(line string) error {
+	err := l.file.Truncate(0)
+	if err != nil {
+		return err
+	}
+	_, err = l.file.WriteAt([]byte(line), 0)
+	return err
+}
+
+// Holder returns the line the current holder of the lock file at 'path'
+// recorded, or an empty string if it is not held (or its holder did not
+// record itself).
+func Holder(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
+}
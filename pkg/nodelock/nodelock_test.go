@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package nodelock
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcquire(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvml.lock")
+
+	lock, err := Acquire(context.Background(), path, "first", time.Second)
+	require.Nil(t, err, "Unexpected failure from Acquire")
+
+	holder, err := Holder(path)
+	require.Nil(t, err, "Unexpected failure from Holder")
+	require.Regexp(t, `^first pid=\d+ since=\S+$`, holder)
+
+	_, err = Acquire(context.Background(), path, "second", 200*time.Millisecond)
+	require.True(t, errors.Is(err, ErrTimeout), "Unexpected error from Acquire: %v", err)
+	require.Contains(t, err.Error(), "held by first")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Acquire(ctx, path, "second", 0)
+	require.True(t, errors.Is(err, context.Canceled), "Unexpected error from Acquire: %v", err)
+
+	released := make(chan error)
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		released <- lock.Release()
+	}()
+	second, err := Acquire(context.Background(), path, "second", 5*time.Second)
+	require.Nil(t, err, "Unexpected failure from Acquire")
+	require.Nil(t, <-released, "Unexpected failure from Release")
+
+	holder, err = Holder(path)
+	require.Nil(t, err, "Unexpected failure from Holder")
+	require.Regexp(t, `^second `, holder)
+
+	require.Nil(t, second.Release())
+	require.Nil(t, second.Release())
+
+	holder, err = Holder(path)
+	require.Nil(t, err, "Unexpected failure from Holder")
+	require.Empty(t, holder)
+}
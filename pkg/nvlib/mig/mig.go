@@ -14,6 +14,7 @@
  * limitations under the License.
  */
 
+// Package mig provides helpers for walking the MIG state of NVML devices.
 package mig
 
 import (
@@ -22,34 +23,42 @@ import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 )
 
+// Interface wraps NVML devices and GPU instances with MIG helpers.
 type Interface struct {
 	nvml nvml.Interface
 }
 
+// Device is an NVML device with MIG helpers.
 type Device struct {
 	nvml.Device
 }
 
+// GpuInstance is an NVML GPU instance with MIG helpers.
 type GpuInstance struct {
 	nvml.GpuInstance
 }
 
+// New returns an Interface backed by the NVML library on the host.
 func New() Interface {
 	return Interface{nvml.New()}
 }
 
+// NewMock returns an Interface backed by 'nvml', which is typically a mock.
 func NewMock(nvml nvml.Interface) Interface {
 	return Interface{nvml}
 }
 
+// Device wraps 'd' with MIG helpers.
 func (i Interface) Device(d nvml.Device) Device {
 	return Device{d}
 }
 
+// GpuInstance wraps 'gi' with MIG helpers.
 func (i Interface) GpuInstance(gi nvml.GpuInstance) GpuInstance {
 	return GpuInstance{gi}
 }
 
+// AssertMigEnabled returns an error unless MIG mode is currently enabled on the device.
 func (device Device) AssertMigEnabled() error {
 	mode, _, ret := device.GetMigMode()
 	if ret == nvml.ERROR_NOT_SUPPORTED {
@@ -64,6 +73,8 @@ func (device Device) AssertMigEnabled() error {
 	return nil
 }
 
+// WalkGpuInstances calls 'f' for each GPU instance on the device, along with
+// its profile ID and profile info.
 func (device Device) WalkGpuInstances(f func(nvml.GpuInstance, int, nvml.GpuInstanceProfileInfo) error) error {
 	for i := 0; i < nvml.GPU_INSTANCE_PROFILE_COUNT; i++ {
 		giProfileInfo, ret := device.GetGpuInstanceProfileInfo(i)
@@ -92,6 +103,8 @@ func (device Device) WalkGpuInstances(f func(nvml.GpuInstance, int, nvml.GpuInst
 	return nil
 }
 
+// WalkComputeInstances calls 'f' for each compute instance in the GPU
+// instance, along with its profile ID, engine profile ID and profile info.
 func (gi GpuInstance) WalkComputeInstances(f func(ci nvml.ComputeInstance, ciProfileId int, ciEngProfileId int, ciProfileInfo nvml.ComputeInstanceProfileInfo) error) error {
 	for j := 0; j < nvml.COMPUTE_INSTANCE_PROFILE_COUNT; j++ {
 		for k := 0; k < nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_COUNT; k++ {
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package mig_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/nvlib"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestWalkMockServer(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE},
+				},
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_1_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+				},
+			},
+		}).
+		WithGPU(testutil.GPU{
+			Model: testutil.A100_SXM4_40GB,
+		}).
+		MustBuild()
+	lib := nvlib.NewMock(server)
+
+	device, ret := server.DeviceGetHandleByIndex(0)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Nil(t, lib.Mig.Device(device).AssertMigEnabled(), "Unexpected failure from AssertMigEnabled")
+
+	giProfiles := []int{}
+	ciProfiles := []int{}
+	err := lib.Mig.Device(device).WalkGpuInstances(func(gi nvml.GpuInstance, giProfileID int, _ nvml.GpuInstanceProfileInfo) error {
+		giProfiles = append(giProfiles, giProfileID)
+		return lib.Mig.GpuInstance(gi).WalkComputeInstances(func(_ nvml.ComputeInstance, ciProfileID int, _ int, _ nvml.ComputeInstanceProfileInfo) error {
+			ciProfiles = append(ciProfiles, ciProfileID)
+			return nil
+		})
+	})
+	require.Nil(t, err, "Unexpected failure from WalkGpuInstances")
+	require.ElementsMatch(t, []int{nvml.GPU_INSTANCE_PROFILE_1_SLICE, nvml.GPU_INSTANCE_PROFILE_3_SLICE}, giProfiles)
+	require.ElementsMatch(t, []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE}, ciProfiles)
+
+	device, ret = server.DeviceGetHandleByIndex(1)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.NotNil(t, lib.Mig.Device(device).AssertMigEnabled(), "Unexpected success from AssertMigEnabled")
+}
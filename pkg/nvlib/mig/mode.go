@@ -16,13 +16,16 @@
 
 package mig
 
+// Mode is the MIG mode of a GPU.
 type Mode int
 
+// MIG modes.
 const (
 	Disabled Mode = 0
 	Enabled  Mode = 1
 )
 
+// String returns the name of the MIG mode.
 func (m Mode) String() string {
 	switch m {
 	case Disabled:
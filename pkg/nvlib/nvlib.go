@@ -14,19 +14,26 @@
  * limitations under the License.
  */
 
+// Package nvlib wraps the NVML bindings with the higher-level device
+// abstractions used by mig-parted. The same abstractions are backed by a mock
+// NVML implementation through NewMock, so code built on them (including
+// downstream projects embedding mig-parted logic) can be tested without a GPU,
+// e.g. against a server built with the 'pkg/testutil' package.
 package nvlib
 
 import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
-	"github.com/NVIDIA/mig-parted/internal/nvlib/mig"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib/mig"
 )
 
+// Interface provides access to the device abstractions of this package.
 type Interface struct {
 	nvml nvml.Interface
 	Mig  mig.Interface
 }
 
+// New returns an Interface backed by the NVML library on the host.
 func New() Interface {
 	return Interface{
 		nvml: nvml.New(),
@@ -34,6 +41,7 @@ func New() Interface {
 	}
 }
 
+// NewMock returns an Interface backed by 'nvml', which is typically a mock.
 func NewMock(nvml nvml.Interface) Interface {
 	return Interface{
 		nvml: nvml,
@@ -0,0 +1,277 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Version indicates the version of the 'Policy' struct used to hold policy rules.
+const Version = "v1"
+
+// Policy is a versioned set of rules that restrict which MIG configurations
+// may be applied to a node.
+type Policy struct {
+	Version string `json:"version" yaml:"version"`
+	Rules   []Rule `json:"rules"   yaml:"rules"`
+}
+
+// Rule restricts the MIG configurations allowed on the GPUs matched by its
+// device filter. An empty device filter matches all GPUs.
+type Rule struct {
+	Name              string   `json:"name"                         yaml:"name"`
+	DeviceFilter      []string `json:"device-filter,omitempty"      yaml:"device-filter,flow,omitempty"`
+	MaxInstances      *int     `json:"max-instances,omitempty"      yaml:"max-instances,omitempty"`
+	MinSlices         *int     `json:"min-slices,omitempty"         yaml:"min-slices,omitempty"`
+	ForbiddenProfiles []string `json:"forbidden-profiles,omitempty" yaml:"forbidden-profiles,flow,omitempty"`
+}
+
+// Violation describes a MIG configuration that breaks a policy rule.
+type Violation struct {
+	Rule   string
+	Reason error
+}
+
+// Error returns a string representation of the 'Violation'.
+func (v *Violation) Error() string {
+	return fmt.Sprintf("policy rule '%v' violated: %v", v.Rule, v.Reason)
+}
+
+// Unwrap returns the reason the 'Violation' occurred.
+func (v *Violation) Unwrap() error {
+	return v.Reason
+}
+
+// Parse parses raw YAML (or JSON) bytes into a 'Policy'.
+func Parse(b []byte) (*Policy, error) {
+	var p Policy
+	err := yaml.Unmarshal(b, &p)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// Check evaluates every rule that applies to a GPU with 'deviceID' against
+// the MIG devices in 'config' and returns the resulting violations.
+func (p *Policy) Check(deviceID types.DeviceID, config types.MigConfig) []error {
+	var violations []error
+	for i := range p.Rules {
+		if !p.Rules[i].Matches(deviceID) {
+			continue
+		}
+		violations = append(violations, p.Rules[i].Check(config)...)
+	}
+	return violations
+}
+
+// CheckSpec evaluates every rule that may apply to a GPU selected by 'spec'
+// against its MIG devices and returns the resulting violations. It requires
+// no GPUs to be present, making it suitable for linting config files.
+func (p *Policy) CheckSpec(spec *v1.MigConfigSpec) []error {
+	if !spec.MigEnabled {
+		return nil
+	}
+
+	var violations []error
+	for i := range p.Rules {
+		if !p.Rules[i].MatchesSpec(spec) {
+			continue
+		}
+		violations = append(violations, p.Rules[i].Check(spec.MigDevices)...)
+	}
+	return violations
+}
+
+// Matches checks if the device filter of a 'Rule' matches 'deviceID'.
+func (r *Rule) Matches(deviceID types.DeviceID) bool {
+	if len(r.DeviceFilter) == 0 {
+		return true
+	}
+	for _, df := range r.DeviceFilter {
+		id, _ := types.NewDeviceIDFromString(df)
+		if id == deviceID {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchesSpec checks if a 'Rule' may apply to any of the GPUs selected by 'spec'.
+func (r *Rule) MatchesSpec(spec *v1.MigConfigSpec) bool {
+	if len(r.DeviceFilter) == 0 {
+		return true
+	}
+	for _, df := range r.DeviceFilter {
+		id, _ := types.NewDeviceIDFromString(df)
+		if spec.MatchesDeviceFilter(id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Predicates returns the set of predicates configured on a 'Rule'.
+func (r *Rule) Predicates() []Predicate {
+	var predicates []Predicate
+	if r.MaxInstances != nil {
+		predicates = append(predicates, MaxInstances(*r.MaxInstances))
+	}
+	if r.MinSlices != nil {
+		predicates = append(predicates, MinSlices(*r.MinSlices))
+	}
+	if len(r.ForbiddenProfiles) > 0 {
+		predicates = append(predicates, ForbiddenProfiles(r.ForbiddenProfiles))
+	}
+	return predicates
+}
+
+// Check evaluates all predicates of a 'Rule' against 'config' and returns a
+// 'Violation' for each one that fails.
+func (r *Rule) Check(config types.MigConfig) []error {
+	var violations []error
+	for _, predicate := range r.Predicates() {
+		err := predicate(config)
+		if err != nil {
+			violations = append(violations, &Violation{Rule: r.Name, Reason: err})
+		}
+	}
+	return violations
+}
+
+// UnmarshalJSON unmarshals raw bytes into a versioned 'Policy'.
+func (p *Policy) UnmarshalJSON(b []byte) error {
+	policy := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &policy)
+	if err != nil {
+		return err
+	}
+
+	required := []string{"version", "rules"}
+	for _, r := range required {
+		if _, exists := policy[r]; !exists {
+			return fmt.Errorf("missing required field: %v", r)
+		}
+	}
+
+	result := Policy{}
+	for k, v := range policy {
+		switch k {
+		case "version":
+			err := json.Unmarshal(v, &result.Version)
+			if err != nil {
+				return err
+			}
+			if result.Version != Version {
+				return fmt.Errorf("unknown version: %v", result.Version)
+			}
+		case "rules":
+			err := json.Unmarshal(v, &result.Rules)
+			if err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	*p = result
+	return nil
+}
+
+// UnmarshalJSON unmarshals raw bytes into a 'Rule'.
+func (r *Rule) UnmarshalJSON(b []byte) error {
+	rule := make(map[string]json.RawMessage)
+	err := json.Unmarshal(b, &rule)
+	if err != nil {
+		return err
+	}
+
+	if _, exists := rule["name"]; !exists {
+		return fmt.Errorf("missing required field: name")
+	}
+
+	result := Rule{}
+	for k, v := range rule {
+		switch k {
+		case "name":
+			err := json.Unmarshal(v, &result.Name)
+			if err != nil {
+				return err
+			}
+		case "device-filter":
+			var filter string
+			err := json.Unmarshal(v, &filter)
+			if err == nil {
+				result.DeviceFilter = []string{filter}
+			} else {
+				err = json.Unmarshal(v, &result.DeviceFilter)
+			}
+			if err != nil {
+				return fmt.Errorf("error parsing '%v' of rule '%v': %v", k, result.Name, err)
+			}
+			for _, df := range result.DeviceFilter {
+				_, err := types.NewDeviceIDFromString(df)
+				if err != nil {
+					return fmt.Errorf("invalid '%v' of rule '%v': %v", k, result.Name, err)
+				}
+			}
+		case "max-instances":
+			err := json.Unmarshal(v, &result.MaxInstances)
+			if err != nil {
+				return err
+			}
+			if *result.MaxInstances < 0 {
+				return fmt.Errorf("invalid '%v' of rule '%v': %v", k, result.Name, *result.MaxInstances)
+			}
+		case "min-slices":
+			err := json.Unmarshal(v, &result.MinSlices)
+			if err != nil {
+				return err
+			}
+			if *result.MinSlices < 1 {
+				return fmt.Errorf("invalid '%v' of rule '%v': %v", k, result.Name, *result.MinSlices)
+			}
+		case "forbidden-profiles":
+			err := json.Unmarshal(v, &result.ForbiddenProfiles)
+			if err != nil {
+				return err
+			}
+			for _, profile := range result.ForbiddenProfiles {
+				err := types.AssertValidMigProfileFormat(profile)
+				if err != nil {
+					return fmt.Errorf("invalid '%v' of rule '%v': %v", k, result.Name, err)
+				}
+			}
+		default:
+			return fmt.Errorf("unexpected field: %v", k)
+		}
+	}
+
+	if len(result.Predicates()) == 0 {
+		return fmt.Errorf("rule '%v' has no restrictions", result.Name)
+	}
+
+	*r = result
+	return nil
+}
@@ -0,0 +1,297 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	v1 "github.com/NVIDIA/mig-parted/api/spec/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const h100DeviceID = types.DeviceID(0x233010DE)
+const a100DeviceID = types.DeviceID(0x20B010DE)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		description     string
+		policy          string
+		expectedFailure bool
+	}{
+		{
+			"Valid policy",
+			`
+version: v1
+rules:
+- name: max-instances
+  max-instances: 4
+- name: no-small-profiles-on-h100
+  device-filter: "0x233010DE"
+  min-slices: 2
+- name: no-1g
+  device-filter: ["0x20B010DE", "0x233010DE"]
+  forbidden-profiles: [1g.5gb, 1g.10gb]
+`,
+			false,
+		},
+		{
+			"Missing version",
+			`
+rules:
+- name: max-instances
+  max-instances: 4
+`,
+			true,
+		},
+		{
+			"Unknown version",
+			`
+version: v2
+rules:
+- name: max-instances
+  max-instances: 4
+`,
+			true,
+		},
+		{
+			"Missing rule name",
+			`
+version: v1
+rules:
+- max-instances: 4
+`,
+			true,
+		},
+		{
+			"Rule without restrictions",
+			`
+version: v1
+rules:
+- name: empty
+  device-filter: "0x233010DE"
+`,
+			true,
+		},
+		{
+			"Unexpected rule field",
+			`
+version: v1
+rules:
+- name: bogus
+  max-instances: 4
+  bogus: true
+`,
+			true,
+		},
+		{
+			"Invalid device filter",
+			`
+version: v1
+rules:
+- name: bogus
+  device-filter: "bogus"
+  max-instances: 4
+`,
+			true,
+		},
+		{
+			"Invalid forbidden profile",
+			`
+version: v1
+rules:
+- name: bogus
+  forbidden-profiles: [bogus]
+`,
+			true,
+		},
+		{
+			"Invalid min slices",
+			`
+version: v1
+rules:
+- name: bogus
+  min-slices: 0
+`,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := Parse([]byte(tc.policy))
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Parse")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Parse")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	policy, err := Parse([]byte(`
+version: v1
+rules:
+- name: max-instances
+  max-instances: 4
+- name: no-small-profiles-on-h100
+  device-filter: "0x233010DE"
+  min-slices: 2
+- name: no-1g.5gb
+  forbidden-profiles: [1g.5gb]
+`))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description        string
+		deviceID           types.DeviceID
+		config             types.MigConfig
+		expectedViolations []string
+	}{
+		{
+			"Empty config",
+			h100DeviceID,
+			types.MigConfig{},
+			nil,
+		},
+		{
+			"Allowed on H100",
+			h100DeviceID,
+			types.MigConfig{"2g.20gb": 2, "3g.40gb": 1},
+			nil,
+		},
+		{
+			"Too many instances",
+			a100DeviceID,
+			types.MigConfig{"1g.10gb": 7},
+			[]string{"max-instances"},
+		},
+		{
+			"Small profile on H100",
+			h100DeviceID,
+			types.MigConfig{"1g.10gb": 1, "3g.40gb": 1},
+			[]string{"no-small-profiles-on-h100"},
+		},
+		{
+			"Small profile on A100",
+			a100DeviceID,
+			types.MigConfig{"1g.10gb": 1, "3g.40gb": 1},
+			nil,
+		},
+		{
+			"Forbidden profile",
+			a100DeviceID,
+			types.MigConfig{"1g.5gb": 1},
+			[]string{"no-1g.5gb"},
+		},
+		{
+			"Forbidden profile with explicit compute instance",
+			a100DeviceID,
+			types.MigConfig{"1c.1g.5gb": 1},
+			[]string{"no-1g.5gb"},
+		},
+		{
+			"Zero count of forbidden profile",
+			a100DeviceID,
+			types.MigConfig{"1g.5gb": 0},
+			nil,
+		},
+		{
+			"Multiple violations",
+			h100DeviceID,
+			types.MigConfig{"1g.5gb": 7},
+			[]string{"max-instances", "no-small-profiles-on-h100", "no-1g.5gb"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			violations := policy.Check(tc.deviceID, tc.config)
+			var rules []string
+			for _, v := range violations {
+				var violation *Violation
+				require.ErrorAs(t, v, &violation)
+				rules = append(rules, violation.Rule)
+			}
+			require.Equal(t, tc.expectedViolations, rules)
+		})
+	}
+}
+
+func TestCheckSpec(t *testing.T) {
+	policy, err := Parse([]byte(`
+version: v1
+rules:
+- name: no-small-profiles-on-h100
+  device-filter: "0x233010DE"
+  min-slices: 2
+`))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description       string
+		spec              v1.MigConfigSpec
+		expectedViolation bool
+	}{
+		{
+			"No device filter",
+			v1.MigConfigSpec{
+				Devices:    "all",
+				MigEnabled: true,
+				MigDevices: types.MigConfig{"1g.10gb": 1},
+			},
+			true,
+		},
+		{
+			"Matching device filter",
+			v1.MigConfigSpec{
+				DeviceFilter: "0x233010DE",
+				Devices:      "all",
+				MigEnabled:   true,
+				MigDevices:   types.MigConfig{"1g.10gb": 1},
+			},
+			true,
+		},
+		{
+			"Other device filter",
+			v1.MigConfigSpec{
+				DeviceFilter: []string{"0x20B010DE"},
+				Devices:      "all",
+				MigEnabled:   true,
+				MigDevices:   types.MigConfig{"1g.10gb": 1},
+			},
+			false,
+		},
+		{
+			"MIG disabled",
+			v1.MigConfigSpec{
+				Devices:    "all",
+				MigEnabled: false,
+			},
+			false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			violations := policy.CheckSpec(&tc.spec)
+			require.Equal(t, tc.expectedViolation, len(violations) != 0)
+		})
+	}
+}
@@ -0,0 +1,120 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Predicate checks a MIG config against a single restriction and returns an
+// error describing why it is not allowed, or nil if it is.
+type Predicate func(config types.MigConfig) error
+
+// MaxInstances returns a 'Predicate' that allows at most 'max' MIG devices per GPU.
+func MaxInstances(max int) Predicate {
+	return func(config types.MigConfig) error {
+		total := 0
+		for _, count := range config {
+			total += count
+		}
+		if total > max {
+			return fmt.Errorf("%d MIG devices requested, at most %d allowed", total, max)
+		}
+		return nil
+	}
+}
+
+// MinSlices returns a 'Predicate' that forbids MIG devices with fewer than
+// 'min' GPU slices (e.g. a 'min' of 2 forbids all '1g' profiles).
+func MinSlices(min int) Predicate {
+	return func(config types.MigConfig) error {
+		for profile, count := range config {
+			if count == 0 {
+				continue
+			}
+			mp, err := parseMigProfile(profile)
+			if err != nil {
+				return fmt.Errorf("error parsing MIG profile '%v': %v", profile, err)
+			}
+			if mp.G < min {
+				return fmt.Errorf("MIG profile '%v' is smaller than %dg", profile, min)
+			}
+		}
+		return nil
+	}
+}
+
+// ForbiddenProfiles returns a 'Predicate' that forbids any of 'profiles'.
+func ForbiddenProfiles(profiles []string) Predicate {
+	return func(config types.MigConfig) error {
+		for _, forbidden := range profiles {
+			fp, err := parseMigProfile(forbidden)
+			if err != nil {
+				return fmt.Errorf("error parsing MIG profile '%v': %v", forbidden, err)
+			}
+			for profile, count := range config {
+				if count == 0 {
+					continue
+				}
+				if fp.Matches(profile) {
+					return fmt.Errorf("MIG profile '%v' is forbidden", profile)
+				}
+			}
+		}
+		return nil
+	}
+}
+
+// parseMigProfile converts a string representation of a MIG profile into a
+// 'MigProfile'. Unlike 'types.ParseMigProfile' it does not consult NVML, so
+// policies can be evaluated on nodes without any GPUs (e.g. by lint). Only
+// the C, G, GB and Attributes fields of the result are set.
+func parseMigProfile(profile string) (*types.MigProfile, error) {
+	err := types.AssertValidMigProfileFormat(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := &types.MigProfile{}
+
+	split := strings.SplitN(profile, "+", 2)
+	if len(split) == 2 {
+		mp.Attributes = strings.Split(split[1], ",")
+	}
+
+	fields := strings.Split(split[0], ".")
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		values[i], err = strconv.Atoi(strings.TrimRight(field, "cgb"))
+		if err != nil {
+			return nil, fmt.Errorf("malformed number in '%v'", field)
+		}
+	}
+
+	mp.G = values[len(values)-2]
+	mp.GB = values[len(values)-1]
+	mp.C = mp.G
+	if len(values) == 3 {
+		mp.C = values[0]
+	}
+
+	return mp, nil
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package reboot reads and writes the marker file that signals that a node
+// must be rebooted for a pending MIG mode change to take effect.
+package reboot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultMarkerFile is the well-known location of the reboot marker.
+const DefaultMarkerFile = "/run/nvidia-mig-manager/reboot-required"
+
+// Reasons a reboot may be required.
+const (
+	ReasonModeChangePending = "mig-mode-change-pending"
+	ReasonResetSkipped      = "gpu-reset-skipped"
+)
+
+// GPU describes a GPU whose MIG mode change is still pending.
+type GPU struct {
+	Index       int    `json:"index"`
+	DeviceID    string `json:"device-id"`
+	CurrentMode string `json:"current-mode"`
+	DesiredMode string `json:"desired-mode"`
+}
+
+// Marker is the content of the reboot marker file.
+type Marker struct {
+	Reason    string    `json:"reason"`
+	GPUs      []GPU     `json:"gpus"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Write atomically writes 'marker' to 'path', creating its parent directory if needed.
+func Write(path string, marker *Marker) error {
+	output, err := json.MarshalIndent(marker, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling reboot marker: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating reboot marker directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, append(output, '\n'), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing reboot marker: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing reboot marker: %w", err)
+	}
+
+	return nil
+}
+
+// Read reads the marker at 'path'. It returns nil if no reboot is required.
+func Read(path string) (*Marker, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading reboot marker: %w", err)
+	}
+
+	var marker Marker
+	err = json.Unmarshal(content, &marker)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshaling reboot marker: %w", err)
+	}
+
+	return &marker, nil
+}
+
+// Clear removes the marker at 'path' if it exists.
+func Clear(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error removing reboot marker: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package reboot
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarker(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nvidia-mig-manager", "reboot-required")
+
+	marker, err := Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Nil(t, marker, "Unexpected marker before Write")
+
+	expected := &Marker{
+		Reason: ReasonModeChangePending,
+		GPUs: []GPU{
+			{
+				Index:       1,
+				DeviceID:    "0x20B010DE",
+				CurrentMode: "Disabled",
+				DesiredMode: "Enabled",
+			},
+		},
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	err = Write(path, expected)
+	require.Nil(t, err, "Unexpected failure from Write")
+
+	marker, err = Read(path)
+	require.Nil(t, err, "Unexpected failure from Read")
+	require.Equal(t, expected, marker)
+
+	err = Clear(path)
+	require.Nil(t, err, "Unexpected failure from Clear")
+
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "Marker not removed")
+
+	err = Clear(path)
+	require.Nil(t, err, "Unexpected failure from Clear without a marker")
+}
+
+func TestReadInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reboot-required")
+	err := os.WriteFile(path, []byte("not json"), 0644)
+	require.Nil(t, err)
+
+	_, err = Read(path)
+	require.NotNil(t, err, "Unexpected success from Read")
+}
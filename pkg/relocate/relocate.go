@@ -0,0 +1,211 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package relocate plans where the workloads running on MIG devices that a
+// reconfiguration destroys can be moved, so that orchestration layers can
+// drain and reschedule them before the reconfiguration is applied.
+package relocate
+
+import (
+	"fmt"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Policies for deciding which surviving MIG devices a workload can be moved to.
+const (
+	// PolicySameProfile only moves a workload to a MIG device with exactly
+	// the same profile.
+	PolicySameProfile = "same-profile"
+	// PolicySameOrLarger also moves a workload to a MIG device with at
+	// least as many compute and memory slices and the same attributes.
+	PolicySameOrLarger = "same-or-larger"
+)
+
+// MigDevice describes a MIG device and the processes running on it.
+type MigDevice struct {
+	GPU               int      `json:"gpu"`
+	UUID              string   `json:"uuid"`
+	Profile           string   `json:"profile"`
+	GpuInstanceID     uint32   `json:"gpu-instance-id"`
+	ComputeInstanceID uint32   `json:"compute-instance-id"`
+	PIDs              []uint32 `json:"pids,omitempty"`
+}
+
+// Relocation moves the workload on a MIG device that a reconfiguration
+// destroys to a MIG device that survives it. 'To' is nil if no compatible
+// MIG device is left to move the workload to.
+type Relocation struct {
+	From MigDevice  `json:"from"`
+	To   *MigDevice `json:"to,omitempty"`
+}
+
+// Destroyed reports whether a MIG device is destroyed by a reconfiguration.
+type Destroyed func(MigDevice) bool
+
+// DestroyedBy returns the MIG devices destroyed by the steps of 'plan':
+// compute instances destroyed directly or along with their GPU instance, and
+// all MIG devices on a GPU whose MIG mode is disabled.
+func DestroyedBy(plan *planv1.Plan) Destroyed {
+	type key struct {
+		gpu   int
+		gi    uint32
+		ci    uint32
+		anyCI bool
+	}
+	destroyed := make(map[key]bool)
+	allOf := make(map[int]bool)
+	for _, g := range plan.GPUs {
+		for _, step := range g.Steps {
+			switch step.Type {
+			case planv1.SetMigMode:
+				if step.MigEnabled != nil && !*step.MigEnabled {
+					allOf[g.Index] = true
+				}
+			case planv1.DestroyGpuInstance:
+				if step.GpuInstance != nil {
+					destroyed[key{gpu: g.Index, gi: step.GpuInstance.ID, anyCI: true}] = true
+				}
+			case planv1.DestroyComputeInstance:
+				if step.ComputeInstance != nil {
+					destroyed[key{gpu: g.Index, gi: step.ComputeInstance.GpuInstanceID, ci: step.ComputeInstance.ComputeInstanceID}] = true
+				}
+			}
+		}
+	}
+
+	return func(d MigDevice) bool {
+		return allOf[d.GPU] ||
+			destroyed[key{gpu: d.GPU, gi: d.GpuInstanceID, anyCI: true}] ||
+			destroyed[key{gpu: d.GPU, gi: d.GpuInstanceID, ci: d.ComputeInstanceID}]
+	}
+}
+
+// Plan returns a relocation for every MIG device in 'devices' that is
+// destroyed and has processes running on it. Each workload is moved to a
+// different idle MIG device that survives and is compatible under 'policy',
+// preferring one on the same GPU and then the smallest one.
+func Plan(devices []MigDevice, destroyed Destroyed, policy string) ([]Relocation, error) {
+	compatible, err := compatibleFunc(policy)
+	if err != nil {
+		return nil, err
+	}
+
+	var displaced, idle []MigDevice
+	for _, d := range devices {
+		switch {
+		case destroyed(d) && len(d.PIDs) > 0:
+			displaced = append(displaced, d)
+		case !destroyed(d) && len(d.PIDs) == 0:
+			idle = append(idle, d)
+		}
+	}
+
+	relocations := []Relocation{}
+	taken := make([]bool, len(idle))
+	for _, from := range displaced {
+		best := -1
+		for i, to := range idle {
+			if taken[i] {
+				continue
+			}
+			ok, err := compatible(from.Profile, to.Profile)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+			if best == -1 || preferred(from, to, idle[best]) {
+				best = i
+			}
+		}
+
+		relocation := Relocation{From: from}
+		if best != -1 {
+			taken[best] = true
+			to := idle[best]
+			relocation.To = &to
+		}
+		relocations = append(relocations, relocation)
+	}
+
+	return relocations, nil
+}
+
+// compatibleFunc returns the function that checks whether a workload on a MIG
+// device with one profile can be moved to a MIG device with another under
+// 'policy'.
+func compatibleFunc(policy string) (func(from, to string) (bool, error), error) {
+	switch policy {
+	case PolicySameProfile:
+		return func(from, to string) (bool, error) {
+			f, t, err := parseProfiles(from, to)
+			if err != nil {
+				return false, err
+			}
+			return f.String() == t.String(), nil
+		}, nil
+	case PolicySameOrLarger:
+		return func(from, to string) (bool, error) {
+			f, t, err := parseProfiles(from, to)
+			if err != nil {
+				return false, err
+			}
+			if t.C < f.C || t.G < f.G || t.GB < f.GB {
+				return false, nil
+			}
+			for _, attr := range f.Attributes {
+				if !t.HasAttribute(attr) {
+					return false, nil
+				}
+			}
+			return len(t.Attributes) == len(f.Attributes), nil
+		}, nil
+	}
+	return nil, fmt.Errorf("unrecognized relocation policy: %v", policy)
+}
+
+func parseProfiles(from, to string) (*types.MigProfile, *types.MigProfile, error) {
+	f, err := types.ParseMigProfile(from)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing MIG profile '%v': %v", from, err)
+	}
+	t, err := types.ParseMigProfile(to)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error parsing MIG profile '%v': %v", to, err)
+	}
+	return f, t, nil
+}
+
+// preferred checks if moving the workload on 'from' to 'a' is preferred over
+// moving it to 'b': a MIG device on the same GPU is preferred, then the
+// smallest one, then the one listed first.
+func preferred(from, a, b MigDevice) bool {
+	if (a.GPU == from.GPU) != (b.GPU == from.GPU) {
+		return a.GPU == from.GPU
+	}
+	pa := types.MustParseMigProfile(a.Profile)
+	pb := types.MustParseMigProfile(b.Profile)
+	if pa.G != pb.G {
+		return pa.G < pb.G
+	}
+	if pa.GB != pb.GB {
+		return pa.GB < pb.GB
+	}
+	return pa.C < pb.C
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package relocate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	planv1 "github.com/NVIDIA/mig-parted/api/plan/v1"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestDestroyedBy(t *testing.T) {
+	disabled := false
+	plan := &planv1.Plan{
+		GPUs: []planv1.GPUPlan{
+			{
+				Index: 0,
+				Steps: []planv1.Step{
+					{Type: planv1.DestroyComputeInstance, ComputeInstance: &types.MigDevice{GpuInstanceID: 1, ComputeInstanceID: 1}},
+					{Type: planv1.DestroyGpuInstance, GpuInstance: &planv1.GpuInstanceRef{GpuInstance: types.GpuInstance{ID: 5}}},
+				},
+			},
+			{
+				Index: 2,
+				Steps: []planv1.Step{
+					{Type: planv1.SetMigMode, MigEnabled: &disabled},
+				},
+			},
+		},
+	}
+	destroyed := DestroyedBy(plan)
+
+	testCases := []struct {
+		description string
+		device      MigDevice
+		expected    bool
+	}{
+		{"compute instance destroyed", MigDevice{GPU: 0, GpuInstanceID: 1, ComputeInstanceID: 1}, true},
+		{"other compute instance in same GPU instance", MigDevice{GPU: 0, GpuInstanceID: 1, ComputeInstanceID: 0}, false},
+		{"GPU instance destroyed", MigDevice{GPU: 0, GpuInstanceID: 5, ComputeInstanceID: 0}, true},
+		{"GPU without steps", MigDevice{GPU: 1, GpuInstanceID: 5, ComputeInstanceID: 0}, false},
+		{"MIG mode disabled", MigDevice{GPU: 2, GpuInstanceID: 7, ComputeInstanceID: 3}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			require.Equal(t, tc.expected, destroyed(tc.device))
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	devices := []MigDevice{
+		{GPU: 0, UUID: "MIG-a", Profile: "1g.5gb", GpuInstanceID: 9, PIDs: []uint32{100}},
+		{GPU: 0, UUID: "MIG-b", Profile: "2g.10gb", GpuInstanceID: 5, PIDs: []uint32{200, 201}},
+		{GPU: 0, UUID: "MIG-c", Profile: "3g.20gb", GpuInstanceID: 1},
+		{GPU: 0, UUID: "MIG-d", Profile: "1g.5gb", GpuInstanceID: 13},
+		{GPU: 1, UUID: "MIG-e", Profile: "1g.5gb", GpuInstanceID: 9},
+		{GPU: 1, UUID: "MIG-f", Profile: "1g.5gb+me", GpuInstanceID: 10, PIDs: []uint32{300}},
+		{GPU: 1, UUID: "MIG-g", Profile: "2g.10gb", GpuInstanceID: 5, PIDs: []uint32{400}},
+	}
+	destroyed := func(d MigDevice) bool {
+		return d.UUID == "MIG-a" || d.UUID == "MIG-b" || d.UUID == "MIG-f"
+	}
+
+	testCases := []struct {
+		description string
+		policy      string
+		expected    map[string]string
+		expectedErr bool
+	}{
+		{
+			description: "same profile",
+			policy:      PolicySameProfile,
+			expected: map[string]string{
+				"MIG-a": "MIG-d",
+				"MIG-b": "",
+				"MIG-f": "",
+			},
+		},
+		{
+			description: "same or larger",
+			policy:      PolicySameOrLarger,
+			expected: map[string]string{
+				"MIG-a": "MIG-d",
+				"MIG-b": "MIG-c",
+				"MIG-f": "",
+			},
+		},
+		{
+			description: "unknown policy",
+			policy:      "bogus",
+			expectedErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			relocations, err := Plan(devices, destroyed, tc.policy)
+			if tc.expectedErr {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+
+			actual := make(map[string]string)
+			for _, r := range relocations {
+				actual[r.From.UUID] = ""
+				if r.To != nil {
+					actual[r.From.UUID] = r.To.UUID
+				}
+			}
+			require.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// The endpoints of the instance metadata services of each cloud.
+const (
+	DefaultEC2Endpoint   = "http://169.254.169.254"
+	DefaultGCEEndpoint   = "http://metadata.google.internal"
+	DefaultAzureEndpoint = "http://169.254.169.254"
+)
+
+// EC2 resolves the selected config from an instance tag of an EC2 instance,
+// through IMDSv2. Tags must be allowed in the instance metadata options.
+type EC2 struct {
+	Endpoint string
+	tag      string
+	client   *http.Client
+}
+
+var _ Resolver = (*EC2)(nil)
+
+// NewEC2 returns a Resolver returning the value of the instance tag 'tag'.
+func NewEC2(tag string) *EC2 {
+	return &EC2{Endpoint: DefaultEC2Endpoint, tag: tag, client: &http.Client{}}
+}
+
+func (r *EC2) Resolve(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, r.Endpoint+"/latest/api/token", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	token, err := do(r.client, req)
+	if err != nil {
+		return "", fmt.Errorf("error getting IMDSv2 token: %w", err)
+	}
+
+	value, err := get(ctx, r.client, r.Endpoint+"/latest/meta-data/tags/instance/"+url.PathEscape(r.tag), http.Header{
+		"X-Aws-Ec2-Metadata-Token": {token},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting instance tag '%v': %w", r.tag, err)
+	}
+	return value, nil
+}
+
+// GCE resolves the selected config from a custom metadata attribute of a GCE
+// instance. GCE does not expose the labels of an instance to the instance
+// itself, so the config is set as an attribute instead.
+type GCE struct {
+	Endpoint  string
+	attribute string
+	client    *http.Client
+}
+
+var _ Resolver = (*GCE)(nil)
+
+// NewGCE returns a Resolver returning the value of the custom metadata
+// 'attribute'.
+func NewGCE(attribute string) *GCE {
+	return &GCE{Endpoint: DefaultGCEEndpoint, attribute: attribute, client: &http.Client{}}
+}
+
+func (r *GCE) Resolve(ctx context.Context) (string, error) {
+	value, err := get(ctx, r.client, r.Endpoint+"/computeMetadata/v1/instance/attributes/"+url.PathEscape(r.attribute), http.Header{
+		"Metadata-Flavor": {"Google"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting metadata attribute '%v': %w", r.attribute, err)
+	}
+	return value, nil
+}
+
+// Azure resolves the selected config from a tag of an Azure VM.
+type Azure struct {
+	Endpoint string
+	tag      string
+	client   *http.Client
+}
+
+var _ Resolver = (*Azure)(nil)
+
+// NewAzure returns a Resolver returning the value of the tag 'tag'.
+func NewAzure(tag string) *Azure {
+	return &Azure{Endpoint: DefaultAzureEndpoint, tag: tag, client: &http.Client{}}
+}
+
+func (r *Azure) Resolve(ctx context.Context) (string, error) {
+	body, err := get(ctx, r.client, r.Endpoint+"/metadata/instance/compute/tagsList?api-version=2021-02-01&format=json", http.Header{
+		"Metadata": {"true"},
+	})
+	if err != nil {
+		return "", fmt.Errorf("error getting tags: %w", err)
+	}
+
+	var tags []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	err = json.Unmarshal([]byte(body), &tags)
+	if err != nil {
+		return "", fmt.Errorf("error parsing tags: %w", err)
+	}
+	for _, t := range tags {
+		if t.Name == r.tag {
+			return t.Value, nil
+		}
+	}
+	return "", fmt.Errorf("tag '%v' not set", r.tag)
+}
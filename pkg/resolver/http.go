@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxResponseSize bounds how much of a response from a metadata service is
+// read.
+const maxResponseSize = 64 * 1024
+
+// get issues a GET for 'url' with 'header' set, and returns the body of a
+// successful response.
+func get(ctx context.Context, client *http.Client, url string, header http.Header) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	return do(client, req)
+}
+
+func do(client *http.Client, req *http.Request) (string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return "", fmt.Errorf("error reading response: %w", err)
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("not found at %v", req.URL)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("unexpected response from %v: %v", req.URL, resp.Status)
+	}
+	return string(body), nil
+}
+
+// HTTP resolves the selected config from the body of a generic HTTP
+// endpoint, such as a cluster metadata service.
+type HTTP struct {
+	url      string
+	client   *http.Client
+	hostname func() (string, error)
+}
+
+var _ Resolver = (*HTTP)(nil)
+
+// NewHTTP returns a Resolver returning the body of a GET of 'url', with any
+// '{hostname}' in it replaced by the hostname of the node.
+func NewHTTP(url string) *HTTP {
+	return &HTTP{
+		url:      url,
+		client:   &http.Client{},
+		hostname: os.Hostname,
+	}
+}
+
+func (r *HTTP) Resolve(ctx context.Context) (string, error) {
+	url := r.url
+	if strings.Contains(url, "{hostname}") {
+		hostname, err := r.hostname()
+		if err != nil {
+			return "", fmt.Errorf("error getting hostname: %w", err)
+		}
+		url = strings.ReplaceAll(url, "{hostname}", hostname)
+	}
+	return get(ctx, r.client, url, nil)
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package resolver looks up the mig-config a node should use from outside
+// the node, so that cloud nodes can select their MIG layout at boot from
+// their instance tags (or a cluster metadata service) rather than from flags
+// baked into their image.
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds how long a single lookup may take, so that an
+// unreachable metadata service cannot stall an apply forever.
+const DefaultTimeout = 10 * time.Second
+
+// Resolver looks up the name of the mig-config to select for the node.
+type Resolver interface {
+	Resolve(ctx context.Context) (string, error)
+}
+
+// Factory builds the Resolver for the part of a source after its scheme.
+type Factory func(arg string) (Resolver, error)
+
+var (
+	factoriesLock sync.Mutex
+	factories     = map[string]Factory{
+		"ec2":   func(tag string) (Resolver, error) { return NewEC2(tag), nil },
+		"gce":   func(attribute string) (Resolver, error) { return NewGCE(attribute), nil },
+		"azure": func(tag string) (Resolver, error) { return NewAzure(tag), nil },
+		"http":  func(u string) (Resolver, error) { return NewHTTP("http:" + u), nil },
+		"https": func(u string) (Resolver, error) { return NewHTTP("https:" + u), nil },
+	}
+)
+
+// Register makes 'factory' build the Resolver for sources with 'scheme',
+// replacing any built-in one.
+func Register(scheme string, factory Factory) {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	factories[scheme] = factory
+}
+
+// Schemes returns the schemes sources can be given in, sorted.
+func Schemes() []string {
+	factoriesLock.Lock()
+	defer factoriesLock.Unlock()
+	var schemes []string
+	for s := range factories {
+		schemes = append(schemes, s)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// New returns the Resolver described by 'source':
+//   - "ec2:<tag>"           the instance tag 'tag' of an EC2 instance
+//   - "gce:<attribute>"     the custom metadata 'attribute' of a GCE instance
+//   - "azure:<tag>"         the tag 'tag' of an Azure VM
+//   - "http(s)://host/path" the body returned for a GET of the URL, with any
+//     '{hostname}' in it replaced by the hostname of the node
+//
+// along with those added through Register.
+func New(source string) (Resolver, error) {
+	scheme, arg, found := strings.Cut(source, ":")
+	if !found || scheme == "" || arg == "" {
+		return nil, fmt.Errorf("invalid selected-config source '%v': expected '<scheme>:<argument>'", source)
+	}
+
+	factoriesLock.Lock()
+	factory, exists := factories[scheme]
+	factoriesLock.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("unsupported selected-config source scheme '%v' (supported: %v)", scheme, strings.Join(Schemes(), ", "))
+	}
+	return factory(arg)
+}
+
+// Resolve looks up the mig-config named by 'source', failing if it comes
+// back empty.
+func Resolve(ctx context.Context, source string) (string, error) {
+	r, err := New(source)
+	if err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, DefaultTimeout)
+	defer cancel()
+	selected, err := r.Resolve(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error resolving selected-config from '%v': %w", source, err)
+	}
+	selected = strings.TrimSpace(selected)
+	if selected == "" {
+		return "", fmt.Errorf("no selected-config set at '%v'", source)
+	}
+	return selected, nil
+}
@@ -0,0 +1,153 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package resolver
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvers(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/latest/api/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			http.Error(w, "bad token request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "token")
+	})
+	mux.HandleFunc("/latest/meta-data/tags/instance/mig-config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-aws-ec2-metadata-token") != "token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, "all-1g.5gb")
+	})
+	mux.HandleFunc("/computeMetadata/v1/instance/attributes/mig-config", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing Metadata-Flavor", http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "all-2g.10gb")
+	})
+	mux.HandleFunc("/metadata/instance/compute/tagsList", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata") != "true" {
+			http.Error(w, "missing Metadata", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `[{"name":"team","value":"ml"},{"name":"mig-config","value":"all-3g.20gb"}]`)
+	})
+	mux.HandleFunc("/nodes/node001", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "all-balanced\n")
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	ec2 := func(tag string) Resolver {
+		r := NewEC2(tag)
+		r.Endpoint = server.URL
+		return r
+	}
+	gce := func(attribute string) Resolver {
+		r := NewGCE(attribute)
+		r.Endpoint = server.URL
+		return r
+	}
+	azure := func(tag string) Resolver {
+		r := NewAzure(tag)
+		r.Endpoint = server.URL
+		return r
+	}
+	node := NewHTTP(server.URL + "/nodes/{hostname}")
+	node.hostname = func() (string, error) { return "node001", nil }
+
+	testCases := []struct {
+		description     string
+		resolver        Resolver
+		expected        string
+		expectedFailure bool
+	}{
+		{"EC2 instance tag", ec2("mig-config"), "all-1g.5gb", false},
+		{"Missing EC2 instance tag", ec2("bogus"), "", true},
+		{"GCE metadata attribute", gce("mig-config"), "all-2g.10gb", false},
+		{"Missing GCE metadata attribute", gce("bogus"), "", true},
+		{"Azure tag", azure("mig-config"), "all-3g.20gb", false},
+		{"Missing Azure tag", azure("bogus"), "", true},
+		{"HTTP endpoint keyed by hostname", node, "all-balanced\n", false},
+		{"Missing HTTP endpoint", NewHTTP(server.URL + "/nodes/bogus"), "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			selected, err := tc.resolver.Resolve(context.Background())
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Resolve")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Resolve")
+			require.Equal(t, tc.expected, selected)
+		})
+	}
+}
+
+type staticResolver string
+
+func (r staticResolver) Resolve(context.Context) (string, error) {
+	return string(r), nil
+}
+
+func TestResolve(t *testing.T) {
+	Register("static", func(arg string) (Resolver, error) {
+		return staticResolver(arg), nil
+	})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "  all-1g.5gb\n")
+	}))
+	defer server.Close()
+
+	testCases := []struct {
+		description     string
+		source          string
+		expected        string
+		expectedFailure bool
+	}{
+		{"Registered scheme", "static:all-disabled", "all-disabled", false},
+		{"HTTP endpoint with whitespace", server.URL, "all-1g.5gb", false},
+		{"Empty value", "static: ", "", true},
+		{"Unknown scheme", "bogus:mig-config", "", true},
+		{"Missing argument", "ec2:", "", true},
+		{"Missing scheme", "mig-config", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			selected, err := Resolve(context.Background(), tc.source)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Resolve")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Resolve")
+			require.Equal(t, tc.expected, selected)
+		})
+	}
+}
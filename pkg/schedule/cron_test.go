@@ -0,0 +1,140 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron(t *testing.T) {
+	testCases := []struct {
+		description     string
+		expr            string
+		expectedFailure bool
+	}{
+		{"Every minute", "* * * * *", false},
+		{"Lists, ranges and steps", "0,30 8-18/2 1-15 */3 1-5", false},
+		{"Sunday as 7", "0 0 * * 7", false},
+		{"Descriptor", "@daily", false},
+		{"Too few fields", "0 8 * *", true},
+		{"Too many fields", "0 0 8 * * *", true},
+		{"Minute out of range", "60 * * * *", true},
+		{"Day of month out of range", "0 0 0 * *", true},
+		{"Inverted range", "0 18-8 * * *", true},
+		{"Invalid step", "*/0 * * * *", true},
+		{"Unsupported name", "0 0 * * MON", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := ParseCron(tc.expr)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from ParseCron")
+			} else {
+				require.Nil(t, err, "Unexpected failure from ParseCron")
+			}
+		})
+	}
+}
+
+func TestCronNextPrev(t *testing.T) {
+	testCases := []struct {
+		description  string
+		expr         string
+		now          string
+		expectedNext string
+		expectedPrev string
+	}{
+		{
+			"Every minute",
+			"* * * * *",
+			"2024-03-06 10:30",
+			"2024-03-06 10:31",
+			"2024-03-06 10:30",
+		},
+		{
+			"Weekday mornings",
+			"0 8 * * 1-5",
+			"2024-03-08 09:00", // A Friday
+			"2024-03-11 08:00",
+			"2024-03-08 08:00",
+		},
+		{
+			"Weekday mornings over the weekend",
+			"0 8 * * 1-5",
+			"2024-03-10 12:00", // A Sunday
+			"2024-03-11 08:00",
+			"2024-03-08 08:00",
+		},
+		{
+			"Every other hour",
+			"15 */2 * * *",
+			"2024-03-06 10:15",
+			"2024-03-06 12:15",
+			"2024-03-06 10:15",
+		},
+		{
+			"Day of month or day of week",
+			"0 0 1 * 0",
+			"2024-03-04 00:00", // A Monday
+			"2024-03-10 00:00",
+			"2024-03-03 00:00",
+		},
+		{
+			"Leap day",
+			"0 0 29 2 *",
+			"2024-03-01 00:00",
+			"2028-02-29 00:00",
+			"2024-02-29 00:00",
+		},
+		{
+			"Never",
+			"0 0 31 2 *",
+			"2024-03-01 00:00",
+			"",
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			c, err := ParseCron(tc.expr)
+			require.Nil(t, err)
+
+			var expectedNext, expectedPrev time.Time
+			if tc.expectedNext != "" {
+				expectedNext = date(tc.expectedNext)
+			}
+			if tc.expectedPrev != "" {
+				expectedPrev = date(tc.expectedPrev)
+			}
+			require.Equal(t, expectedNext, c.Next(date(tc.now)))
+			require.Equal(t, expectedPrev, c.Prev(date(tc.now)))
+		})
+	}
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package schedule switches the selected MIG config of a node at the times
+// listed in a schedule file, e.g. between a daytime inference layout and a
+// nighttime training layout.
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Version indicates the version of the 'Schedule' struct used to hold schedule entries.
+const Version = "v1"
+
+// Schedule is a versioned list of the MIG configs to switch to, and when.
+type Schedule struct {
+	Version string  `json:"version" yaml:"version"`
+	Entries []Entry `json:"schedule" yaml:"schedule"`
+}
+
+// Entry switches to 'SelectedConfig' each time its 'Cron' expression
+// matches, until the next entry does. With a 'MaxLifetime', it switches
+// back to the default MIG config once that long has passed instead, should
+// no other entry have matched by then.
+type Entry struct {
+	Name           string `json:"name"                   yaml:"name"`
+	Cron           string `json:"cron"                   yaml:"cron"`
+	SelectedConfig string `json:"selected-config"        yaml:"selected-config"`
+	MaxLifetime    string `json:"max-lifetime,omitempty" yaml:"max-lifetime,omitempty"`
+
+	cron        *Cron
+	maxLifetime time.Duration
+}
+
+// Parse parses raw YAML (or JSON) bytes into a 'Schedule', checking that
+// each of its entries is valid.
+func Parse(b []byte) (*Schedule, error) {
+	var s Schedule
+	err := yaml.Unmarshal(b, &s)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Version != Version {
+		return nil, fmt.Errorf("unsupported version '%v'", s.Version)
+	}
+	if len(s.Entries) == 0 {
+		return nil, fmt.Errorf("no entries in schedule")
+	}
+
+	names := make(map[string]bool)
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		if e.Name == "" {
+			return nil, fmt.Errorf("missing 'name' in entry %d", i)
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("duplicate entry '%v'", e.Name)
+		}
+		names[e.Name] = true
+
+		if e.SelectedConfig == "" {
+			return nil, fmt.Errorf("missing 'selected-config' in entry '%v'", e.Name)
+		}
+		e.cron, err = ParseCron(e.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("invalid 'cron' in entry '%v': %v", e.Name, err)
+		}
+		if e.MaxLifetime != "" {
+			e.maxLifetime, err = time.ParseDuration(e.MaxLifetime)
+			if err != nil || e.maxLifetime <= 0 {
+				return nil, fmt.Errorf("invalid 'max-lifetime' in entry '%v': %v", e.Name, e.MaxLifetime)
+			}
+		}
+	}
+
+	return &s, nil
+}
+
+// Active returns the entry in effect at 'now' and since when it has been,
+// i.e. the one that matched last, or nil if none has or its max lifetime is
+// over. Of entries matching at the same time, the last one listed wins.
+func (s *Schedule) Active(now time.Time) (*Entry, time.Time) {
+	var active *Entry
+	var since time.Time
+	for i := range s.Entries {
+		e := &s.Entries[i]
+		prev := e.cron.Prev(now)
+		if prev.IsZero() || prev.Before(since) {
+			continue
+		}
+		active, since = e, prev
+	}
+
+	if active != nil && active.maxLifetime > 0 && !now.Before(since.Add(active.maxLifetime)) {
+		return nil, since.Add(active.maxLifetime)
+	}
+	return active, since
+}
+
+// Next returns the first time after 'now' the active entry may change,
+// i.e. the next time any entry matches or the max lifetime of the active
+// one is over, or the zero time if it never does.
+func (s *Schedule) Next(now time.Time) time.Time {
+	var next time.Time
+	earliest := func(t time.Time) {
+		if !t.IsZero() && (next.IsZero() || t.Before(next)) {
+			next = t
+		}
+	}
+
+	for i := range s.Entries {
+		earliest(s.Entries[i].cron.Next(now))
+	}
+	if active, since := s.Active(now); active != nil && active.maxLifetime > 0 {
+		earliest(since.Add(active.maxLifetime))
+	}
+
+	return next
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testSchedule = `
+version: v1
+schedule:
+- name: daytime-inference
+  cron: "0 8 * * 1-5"
+  selected-config: all-1g.10gb
+- name: nighttime-training
+  cron: "0 20 * * 1-5"
+  selected-config: all-7g.80gb
+- name: weekend-maintenance
+  cron: "0 6 * * 6"
+  selected-config: all-disabled
+  max-lifetime: 4h
+`
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		description     string
+		schedule        string
+		expectedFailure bool
+	}{
+		{
+			"Valid schedule",
+			testSchedule,
+			false,
+		},
+		{
+			"Missing version",
+			`
+schedule:
+- name: daily
+  cron: "@daily"
+  selected-config: all-disabled
+`,
+			true,
+		},
+		{
+			"No entries",
+			`
+version: v1
+schedule: []
+`,
+			true,
+		},
+		{
+			"Missing name",
+			`
+version: v1
+schedule:
+- cron: "@daily"
+  selected-config: all-disabled
+`,
+			true,
+		},
+		{
+			"Duplicate name",
+			`
+version: v1
+schedule:
+- name: daily
+  cron: "@daily"
+  selected-config: all-disabled
+- name: daily
+  cron: "@hourly"
+  selected-config: all-enabled
+`,
+			true,
+		},
+		{
+			"Missing selected config",
+			`
+version: v1
+schedule:
+- name: daily
+  cron: "@daily"
+`,
+			true,
+		},
+		{
+			"Invalid cron expression",
+			`
+version: v1
+schedule:
+- name: daily
+  cron: "0 25 * * *"
+  selected-config: all-disabled
+`,
+			true,
+		},
+		{
+			"Invalid max lifetime",
+			`
+version: v1
+schedule:
+- name: daily
+  cron: "@daily"
+  selected-config: all-disabled
+  max-lifetime: -1h
+`,
+			true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := Parse([]byte(tc.schedule))
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Parse")
+			} else {
+				require.Nil(t, err, "Unexpected failure from Parse")
+			}
+		})
+	}
+}
+
+func TestActiveAndNext(t *testing.T) {
+	s, err := Parse([]byte(testSchedule))
+	require.Nil(t, err)
+
+	testCases := []struct {
+		description    string
+		now            string
+		expectedActive string
+		expectedSince  string
+		expectedNext   string
+	}{
+		{
+			"During the day",
+			"2024-03-06 12:00", // A Wednesday
+			"daytime-inference",
+			"2024-03-06 08:00",
+			"2024-03-06 20:00",
+		},
+		{
+			"At the switch to the night",
+			"2024-03-06 20:00",
+			"nighttime-training",
+			"2024-03-06 20:00",
+			"2024-03-07 08:00",
+		},
+		{
+			"Over the night",
+			"2024-03-07 03:00",
+			"nighttime-training",
+			"2024-03-06 20:00",
+			"2024-03-07 08:00",
+		},
+		{
+			"During maintenance",
+			"2024-03-09 07:00", // A Saturday
+			"weekend-maintenance",
+			"2024-03-09 06:00",
+			"2024-03-09 10:00",
+		},
+		{
+			"Once maintenance is over",
+			"2024-03-09 12:00",
+			"",
+			"2024-03-09 10:00",
+			"2024-03-11 08:00",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			active, since := s.Active(date(tc.now))
+			if tc.expectedActive == "" {
+				require.Nil(t, active)
+			} else {
+				require.NotNil(t, active)
+				require.Equal(t, tc.expectedActive, active.Name)
+			}
+			require.Equal(t, date(tc.expectedSince), since)
+			require.Equal(t, date(tc.expectedNext), s.Next(date(tc.now)))
+		})
+	}
+
+	require.Equal(t, 4*time.Hour, s.Entries[2].maxLifetime)
+}
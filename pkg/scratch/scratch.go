@@ -0,0 +1,427 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package scratch tracks scratch GPU instances: GPU instances created for a
+// limited time (their TTL) on behalf of an owner, e.g. for burst
+// experimentation on a shared machine. Unless its owner claims it in time, a
+// scratch GPU instance is destroyed by Reap once its lease expires.
+package scratch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Lease records a scratch GPU instance along with its owner and when it
+// expires. The profile and placement of the GPU instance are recorded along
+// with its ID, so that a GPU instance that has since been destroyed is not
+// mistaken for a new one reusing its ID. As a new GPU instance can also reuse
+// the profile and placement, the generation of the GPU instances on its GPU
+// is recorded as well (see Registry.Invalidate).
+type Lease struct {
+	GPU         int               `json:"gpu"`
+	GpuInstance types.GpuInstance `json:"gpu-instance"`
+	Owner       string            `json:"owner"`
+	Expires     time.Time         `json:"expires"`
+	Generation  uint64            `json:"generation,omitempty"`
+}
+
+// Expired returns whether 'l' has expired at 'now'.
+func (l *Lease) Expired(now time.Time) bool {
+	return !now.Before(l.Expires)
+}
+
+// Registry keeps the leases of the scratch GPU instances on the node in a
+// store.Store.
+type Registry struct {
+	store store.Store
+	now   func() time.Time
+}
+
+// NewRegistry returns a Registry keeping its leases in 's'.
+func NewRegistry(s store.Store) *Registry {
+	return &Registry{store: s, now: time.Now}
+}
+
+func key(gpu int, giID uint32) string {
+	return fmt.Sprintf("%d/%d", gpu, giID)
+}
+
+// generation returns the generation of the GPU instances on 'gpu'.
+func (r *Registry) generation(gpu int) (uint64, error) {
+	value, err := r.store.Get(store.BucketScratchGenerations, strconv.Itoa(gpu))
+	if errors.Is(err, store.ErrNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error getting generation of GPU %v: %w", gpu, err)
+	}
+	generation, err := strconv.ParseUint(string(value), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("error parsing generation of GPU %v: %w", gpu, err)
+	}
+	return generation, nil
+}
+
+// Invalidate drops the leases of all scratch GPU instances on 'gpu' by
+// starting a new generation of its GPU instances. It is called whenever the
+// GPU instances of 'gpu' are replaced as a whole (e.g. by applying a MIG
+// config), so that a GPU instance created with the ID, profile and placement
+// of a scratch GPU instance destroyed along the way is not reaped in its
+// place.
+func (r *Registry) Invalidate(gpu int) error {
+	generation, err := r.generation(gpu)
+	if err != nil {
+		return err
+	}
+	err = r.store.Put(store.BucketScratchGenerations, strconv.Itoa(gpu), []byte(strconv.FormatUint(generation+1, 10)))
+	if err != nil {
+		return fmt.Errorf("error storing generation of GPU %v: %w", gpu, err)
+	}
+	return nil
+}
+
+// Add records 'gi' on 'gpu' as a scratch GPU instance of 'owner' that expires
+// after 'ttl'.
+func (r *Registry) Add(gpu int, gi types.GpuInstance, owner string, ttl time.Duration) (*Lease, error) {
+	generation, err := r.generation(gpu)
+	if err != nil {
+		return nil, err
+	}
+	lease := &Lease{
+		GPU:         gpu,
+		GpuInstance: gi,
+		Owner:       owner,
+		Expires:     r.now().Add(ttl).UTC(),
+		Generation:  generation,
+	}
+	value, err := json.Marshal(lease)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling lease: %w", err)
+	}
+	err = r.store.Put(store.BucketScratch, key(gpu, gi.ID), value)
+	if err != nil {
+		return nil, fmt.Errorf("error storing lease: %w", err)
+	}
+	return lease, nil
+}
+
+// Get returns the lease of GPU instance 'giID' on 'gpu', or store.ErrNotFound
+// if it is not a scratch GPU instance.
+func (r *Registry) Get(gpu int, giID uint32) (*Lease, error) {
+	lease, err := r.get(gpu, giID)
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.current(lease)
+	if err != nil {
+		return nil, err
+	}
+	if !current {
+		return nil, store.ErrNotFound
+	}
+	return lease, nil
+}
+
+// current returns whether 'lease' was created in the current generation of
+// the GPU instances on its GPU.
+func (r *Registry) current(lease *Lease) (bool, error) {
+	generation, err := r.generation(lease.GPU)
+	if err != nil {
+		return false, err
+	}
+	return lease.Generation == generation, nil
+}
+
+// get is Get, including leases of an older generation.
+func (r *Registry) get(gpu int, giID uint32) (*Lease, error) {
+	value, err := r.store.Get(store.BucketScratch, key(gpu, giID))
+	if err != nil {
+		return nil, err
+	}
+	var lease Lease
+	err = json.Unmarshal(value, &lease)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing lease of GPU instance %v on GPU %v: %w", giID, gpu, err)
+	}
+	return &lease, nil
+}
+
+// List returns the leases of all scratch GPU instances, ordered by GPU and
+// GPU instance ID.
+func (r *Registry) List() ([]Lease, error) {
+	all, err := r.list()
+	if err != nil {
+		return nil, err
+	}
+	var leases []Lease
+	for _, lease := range all {
+		current, err := r.current(&lease)
+		if err != nil {
+			return nil, err
+		}
+		if current {
+			leases = append(leases, lease)
+		}
+	}
+	return leases, nil
+}
+
+// list is List, including leases of an older generation.
+func (r *Registry) list() ([]Lease, error) {
+	keys, err := r.store.Keys(store.BucketScratch)
+	if err != nil {
+		return nil, fmt.Errorf("error listing leases: %w", err)
+	}
+	var leases []Lease
+	for _, k := range keys {
+		var gpu int
+		var giID uint32
+		_, err := fmt.Sscanf(k, "%d/%d", &gpu, &giID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid lease key '%v': %w", k, err)
+		}
+		lease, err := r.get(gpu, giID)
+		if err != nil {
+			return nil, err
+		}
+		leases = append(leases, *lease)
+	}
+	return leases, nil
+}
+
+// Claim turns the scratch GPU instance 'giID' on 'gpu' into a regular GPU
+// instance, which is no longer destroyed when its lease expires. Only its
+// owner can claim it.
+func (r *Registry) Claim(gpu int, giID uint32, owner string) error {
+	lease, err := r.Get(gpu, giID)
+	if err != nil {
+		return err
+	}
+	if lease.Owner != owner {
+		return fmt.Errorf("GPU instance %v on GPU %v is owned by '%v'", giID, gpu, lease.Owner)
+	}
+	return r.Remove(gpu, giID)
+}
+
+// Remove drops the lease of GPU instance 'giID' on 'gpu', if any.
+func (r *Registry) Remove(gpu int, giID uint32) error {
+	err := r.store.Delete(store.BucketScratch, key(gpu, giID))
+	if err != nil {
+		return fmt.Errorf("error removing lease: %w", err)
+	}
+	return nil
+}
+
+// Held is an expired lease whose scratch GPU instance is not destroyed because
+// one of its MIG devices is reserved in the allocation ledger.
+type Held struct {
+	Lease
+	Reservation ledger.Reservation
+}
+
+// MigDeviceUUIDs returns the UUIDs of the MIG devices on 'gpu', keyed by the
+// IDs of their GPU instance and compute instance.
+type MigDeviceUUIDs func(gpu int) (map[[2]uint32]string, error)
+
+// Reap destroys the scratch GPU instances whose leases have expired through
+// 'manager', along with their compute instances, and returns their leases.
+// The leases of GPU instances that no longer exist, and those of an older
+// generation, are dropped without destroying anything. GPU instances holding a MIG device reserved in the
+// allocation ledger kept in the same store are left alone (looking up their
+// MIG devices with 'uuids') and returned as held instead, keeping their
+// leases until the reservation is released. Reaping carries on past a GPU
+// instance that cannot be destroyed, returning the errors for all of them.
+func (r *Registry) Reap(manager config.InstanceManager, uuids MigDeviceUUIDs) ([]Lease, []Held, error) {
+	leases, err := r.list()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reservations, err := ledger.NewLedger(r.store).List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading reservations: %w", err)
+	}
+	reserved := make(map[string]ledger.Reservation)
+	for _, reservation := range reservations {
+		reserved[reservation.UUID] = reservation
+	}
+
+	now := r.now()
+	var reaped []Lease
+	var held []Held
+	var errs []error
+	for _, lease := range leases {
+		current, err := r.current(&lease)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !current {
+			errs = append(errs, r.Remove(lease.GPU, lease.GpuInstance.ID))
+			continue
+		}
+		if !lease.Expired(now) {
+			continue
+		}
+		reservation, err := reap(manager, &lease, uuids, reserved)
+		if errors.Is(err, errGone) {
+			errs = append(errs, r.Remove(lease.GPU, lease.GpuInstance.ID))
+			continue
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error reaping GPU instance %v on GPU %v: %w", lease.GpuInstance.ID, lease.GPU, err))
+			continue
+		}
+		if reservation != nil {
+			held = append(held, Held{lease, *reservation})
+			continue
+		}
+		reaped = append(reaped, lease)
+		errs = append(errs, r.Remove(lease.GPU, lease.GpuInstance.ID))
+	}
+	return reaped, held, errors.Join(errs...)
+}
+
+// heldReservation returns the reservation in 'reserved' of a MIG device in the
+// GPU instance of 'lease', if there is any.
+func heldReservation(uuids MigDeviceUUIDs, reserved map[string]ledger.Reservation, lease *Lease) (*ledger.Reservation, error) {
+	if len(reserved) == 0 {
+		return nil, nil
+	}
+	devices, err := uuids(lease.GPU)
+	if err != nil {
+		return nil, fmt.Errorf("error getting MIG devices: %w", err)
+	}
+	for ids, uuid := range devices {
+		if ids[0] != lease.GpuInstance.ID {
+			continue
+		}
+		if reservation, exists := reserved[uuid]; exists {
+			return &reservation, nil
+		}
+	}
+	return nil, nil
+}
+
+// errGone is returned by reap when the GPU instance of a lease no longer exists.
+var errGone = errors.New("GPU instance no longer exists")
+
+// reap destroys the GPU instance of 'lease' along with its compute instances,
+// unless one of its MIG devices is reserved in 'reserved', whose reservation
+// it returns instead.
+func reap(manager config.InstanceManager, lease *Lease, uuids MigDeviceUUIDs, reserved map[string]ledger.Reservation) (*ledger.Reservation, error) {
+	gis, err := manager.ListGpuInstances(lease.GPU)
+	if err != nil {
+		return nil, fmt.Errorf("error listing GPU instances: %w", err)
+	}
+	exists := false
+	for _, gi := range gis {
+		if gi == lease.GpuInstance {
+			exists = true
+			break
+		}
+	}
+	if !exists {
+		return nil, errGone
+	}
+
+	reservation, err := heldReservation(uuids, reserved, lease)
+	if err != nil {
+		return nil, err
+	}
+	if reservation != nil {
+		return reservation, nil
+	}
+
+	cis, err := manager.ListComputeInstances(lease.GPU)
+	if err != nil {
+		return nil, fmt.Errorf("error listing compute instances: %w", err)
+	}
+	for _, ci := range cis {
+		if ci.GpuInstanceID != lease.GpuInstance.ID {
+			continue
+		}
+		err := manager.DestroyComputeInstance(lease.GPU, ci.GpuInstanceID, ci.ComputeInstanceID)
+		if err != nil {
+			return nil, fmt.Errorf("error destroying compute instance %v: %w", ci.ComputeInstanceID, err)
+		}
+	}
+
+	return nil, manager.DestroyGpuInstance(lease.GPU, lease.GpuInstance.ID)
+}
+
+// Invalidate is Registry.Invalidate for each of 'gpus' with the leases kept in
+// the store at 'storeFile'. Without a store, there are no leases to drop, so
+// none is created.
+func Invalidate(storeFile string, gpus []int) error {
+	if len(gpus) == 0 {
+		return nil
+	}
+	s, err := openExisting(storeFile)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+	defer s.Close()
+
+	registry := NewRegistry(s)
+	var errs []error
+	for _, gpu := range gpus {
+		errs = append(errs, registry.Invalidate(gpu))
+	}
+	return errors.Join(errs...)
+}
+
+// Remove is Registry.Remove with the leases kept in the store at
+// 'storeFile'. Without a store, there is no lease to drop, so none is
+// created.
+func Remove(storeFile string, gpu int, giID uint32) error {
+	s, err := openExisting(storeFile)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		return nil
+	}
+	defer s.Close()
+
+	return NewRegistry(s).Remove(gpu, giID)
+}
+
+// openExisting opens the store at 'storeFile', or returns nil if it does not
+// exist yet.
+func openExisting(storeFile string) (store.Store, error) {
+	_, err := os.Stat(storeFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error checking for store: %w", err)
+	}
+	return store.Open(storeFile)
+}
@@ -0,0 +1,255 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package scratch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/ledger"
+	"github.com/NVIDIA/mig-parted/pkg/mig/config"
+	"github.com/NVIDIA/mig-parted/pkg/store"
+	"github.com/NVIDIA/mig-parted/pkg/testutil"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestReap(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+				{
+					Profile: nvml.GPU_INSTANCE_PROFILE_1_SLICE,
+				},
+			},
+		}).
+		MustBuild()
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	gis, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Len(t, gis, 2)
+	if gis[0].Profile != "3g.20gb" {
+		gis[0], gis[1] = gis[1], gis[0]
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	registry := NewRegistry(store.NewMemoryStore())
+	registry.now = func() time.Time { return now }
+
+	_, err = registry.Add(0, gis[0], "alice", time.Hour)
+	require.Nil(t, err)
+	_, err = registry.Add(0, gis[1], "bob", 2*time.Hour)
+	require.Nil(t, err)
+	gone := types.GpuInstance{Profile: "7g.40gb", ID: 99}
+	_, err = registry.Add(0, gone, "carol", time.Minute)
+	require.Nil(t, err)
+
+	reaped, held, err := registry.Reap(manager, nil)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Empty(t, held)
+	require.Empty(t, reaped, "Unexpected GPU instances reaped before expiry")
+
+	now = now.Add(90 * time.Minute)
+	reaped, held, err = registry.Reap(manager, nil)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Empty(t, held)
+	require.Len(t, reaped, 1)
+	require.Equal(t, gis[0], reaped[0].GpuInstance)
+	require.Equal(t, "alice", reaped[0].Owner)
+
+	remaining, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Equal(t, []types.GpuInstance{gis[1]}, remaining)
+	cis, err := manager.ListComputeInstances(0)
+	require.Nil(t, err)
+	require.Empty(t, cis)
+
+	leases, err := registry.List()
+	require.Nil(t, err)
+	require.Len(t, leases, 1, "Expected only the unexpired lease to remain")
+	require.Equal(t, "bob", leases[0].Owner)
+	require.Equal(t, now.Add(30*time.Minute), leases[0].Expires)
+}
+
+func TestReapKeepsReservedGpuInstances(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE},
+				},
+			},
+		}).
+		MustBuild()
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	gis, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Len(t, gis, 1)
+	cis, err := manager.ListComputeInstances(0)
+	require.Nil(t, err)
+	require.Len(t, cis, 1)
+	uuids := func(gpu int) (map[[2]uint32]string, error) {
+		return map[[2]uint32]string{{cis[0].GpuInstanceID, cis[0].ComputeInstanceID}: "MIG-a"}, nil
+	}
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := store.NewMemoryStore()
+	registry := NewRegistry(s)
+	registry.now = func() time.Time { return now }
+
+	_, err = registry.Add(0, gis[0], "alice", time.Minute)
+	require.Nil(t, err)
+	_, err = ledger.NewLedger(s).Reserve("MIG-a", 0, "slurm", 0, nil)
+	require.Nil(t, err)
+
+	now = now.Add(time.Hour)
+	reaped, held, err := registry.Reap(manager, uuids)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Empty(t, reaped, "Unexpected GPU instance reaped while reserved")
+	require.Len(t, held, 1)
+	require.Equal(t, "alice", held[0].Owner)
+	require.Equal(t, "slurm", held[0].Reservation.Holder)
+
+	remaining, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Equal(t, gis, remaining)
+	_, err = registry.Get(0, gis[0].ID)
+	require.Nil(t, err, "Expected the lease of the held GPU instance to remain")
+
+	err = ledger.NewLedger(s).Release("MIG-a", "slurm")
+	require.Nil(t, err)
+
+	reaped, held, err = registry.Reap(manager, uuids)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Empty(t, held)
+	require.Len(t, reaped, 1)
+
+	remaining, err = manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Empty(t, remaining)
+}
+
+func TestReapRecreatedGpuInstance(t *testing.T) {
+	types.SetMockNVdevlib()
+
+	server := testutil.NewServerBuilder().
+		WithGPU(testutil.GPU{
+			Model:      testutil.A100_SXM4_40GB,
+			MigEnabled: true,
+			GpuInstances: []testutil.GpuInstance{
+				{Profile: nvml.GPU_INSTANCE_PROFILE_3_SLICE},
+			},
+		}).
+		MustBuild()
+	manager := config.NewMockNvmlInstanceManager(server)
+
+	gis, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Len(t, gis, 1)
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	registry := NewRegistry(store.NewMemoryStore())
+	registry.now = func() time.Time { return now }
+
+	_, err = registry.Add(0, gis[0], "alice", time.Minute)
+	require.Nil(t, err)
+
+	// Applying a MIG config destroys the scratch GPU instance and creates
+	// one declared by the config in its place, which NVML reports with the
+	// same ID, profile and placement. The GPU instance is left as it is to
+	// stand for it.
+	err = registry.Invalidate(0)
+	require.Nil(t, err, "Unexpected failure from Invalidate")
+
+	_, err = registry.Get(0, gis[0].ID)
+	require.ErrorIs(t, err, store.ErrNotFound)
+	leases, err := registry.List()
+	require.Nil(t, err)
+	require.Empty(t, leases)
+
+	now = now.Add(time.Hour)
+	reaped, held, err := registry.Reap(manager, nil)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Empty(t, held)
+	require.Empty(t, reaped, "Unexpected GPU instance reaped after being recreated")
+
+	remaining, err := manager.ListGpuInstances(0)
+	require.Nil(t, err)
+	require.Equal(t, gis, remaining)
+	_, err = registry.get(0, gis[0].ID)
+	require.ErrorIs(t, err, store.ErrNotFound, "Expected the stale lease to be dropped")
+
+	// A scratch GPU instance created in the new generation is reaped.
+	_, err = registry.Add(0, gis[0], "bob", time.Minute)
+	require.Nil(t, err)
+	now = now.Add(time.Hour)
+	reaped, _, err = registry.Reap(manager, nil)
+	require.Nil(t, err, "Unexpected failure from Reap")
+	require.Len(t, reaped, 1)
+	require.Equal(t, "bob", reaped[0].Owner)
+}
+
+func TestInvalidateWithoutStore(t *testing.T) {
+	storeFile := filepath.Join(t.TempDir(), "state.db")
+
+	err := Invalidate(storeFile, []int{0})
+	require.Nil(t, err, "Unexpected failure from Invalidate")
+	err = Remove(storeFile, 0, 1)
+	require.Nil(t, err, "Unexpected failure from Remove")
+
+	_, err = os.Stat(storeFile)
+	require.ErrorIs(t, err, os.ErrNotExist, "Unexpected store created")
+}
+
+func TestClaim(t *testing.T) {
+	registry := NewRegistry(store.NewMemoryStore())
+	gi := types.GpuInstance{Profile: "1g.5gb", ID: 3}
+
+	_, err := registry.Add(1, gi, "alice", time.Hour)
+	require.Nil(t, err)
+
+	err = registry.Claim(1, 3, "bob")
+	require.NotNil(t, err, "Unexpected success claiming another owner's GPU instance")
+
+	err = registry.Claim(1, 3, "alice")
+	require.Nil(t, err, "Unexpected failure from Claim")
+
+	_, err = registry.Get(1, 3)
+	require.ErrorIs(t, err, store.ErrNotFound)
+
+	err = registry.Claim(1, 3, "alice")
+	require.ErrorIs(t, err, store.ErrNotFound)
+}
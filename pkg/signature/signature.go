@@ -0,0 +1,266 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package signature verifies detached signatures on files against a set of
+// locally trusted public keys. Two signature formats are supported:
+//
+//   - cosign style: a PEM encoded ECDSA P-256 or Ed25519 public key and a
+//     base64 encoded signature, as written by 'cosign sign-blob'.
+//   - minisign style: a minisign public key and signature file. Only legacy
+//     (non-prehashed) signatures, as written by 'minisign -S -l', are
+//     supported.
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	// DefaultSuffix is appended to the path of a file to find its signature.
+	DefaultSuffix = ".sig"
+
+	minisignUntrustedComment = "untrusted comment:"
+	minisignTrustedComment   = "trusted comment: "
+	minisignAlgorithm        = "Ed"
+	minisignPrehashed        = "ED"
+	minisignKeyIDSize        = 8
+)
+
+// ErrVerificationFailed indicates that a signature was not made by any of the
+// trusted keys over the data being verified.
+var ErrVerificationFailed = errors.New("signature verification failed")
+
+// Verifier verifies detached signatures against a set of trusted public keys.
+type Verifier struct {
+	keys         []crypto.PublicKey
+	minisignKeys map[[minisignKeyIDSize]byte]ed25519.PublicKey
+}
+
+// NewVerifier creates a Verifier that does not trust any keys yet.
+func NewVerifier() *Verifier {
+	return &Verifier{
+		minisignKeys: make(map[[minisignKeyIDSize]byte]ed25519.PublicKey),
+	}
+}
+
+// LoadVerifier creates a Verifier trusting the public keys in the file at
+// 'path', or in every file of the directory at 'path'.
+func LoadVerifier(path string) (*Verifier, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		files = nil
+		for _, e := range entries {
+			if e.Type().IsRegular() {
+				files = append(files, filepath.Join(path, e.Name()))
+			}
+		}
+		sort.Strings(files)
+	}
+
+	v := NewVerifier()
+	for _, file := range files {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		err = v.AddKeys(b)
+		if err != nil {
+			return nil, fmt.Errorf("error loading keys from '%v': %w", file, err)
+		}
+	}
+	if v.NumKeys() == 0 {
+		return nil, fmt.Errorf("no trusted keys found in '%v'", path)
+	}
+
+	return v, nil
+}
+
+// NumKeys returns the number of keys trusted by the Verifier.
+func (v *Verifier) NumKeys() int {
+	return len(v.keys) + len(v.minisignKeys)
+}
+
+// AddKeys trusts the PEM encoded public keys, or the minisign public key, in 'b'.
+func (v *Verifier) AddKeys(b []byte) error {
+	if bytes.Contains(b, []byte("-----BEGIN")) {
+		return v.addPEMKeys(b)
+	}
+	return v.addMinisignKey(b)
+}
+
+func (v *Verifier) addPEMKeys(b []byte) error {
+	for {
+		var block *pem.Block
+		block, b = pem.Decode(b)
+		if block == nil {
+			return nil
+		}
+		if block.Type != "PUBLIC KEY" {
+			continue
+		}
+		key, err := x509.ParsePKIXPublicKey(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("error parsing public key: %w", err)
+		}
+		switch k := key.(type) {
+		case *ecdsa.PublicKey, ed25519.PublicKey:
+			v.keys = append(v.keys, k)
+		default:
+			return fmt.Errorf("unsupported public key type: %T", key)
+		}
+	}
+}
+
+func (v *Verifier) addMinisignKey(b []byte) error {
+	lines := nonCommentLines(b)
+	if len(lines) != 1 {
+		return fmt.Errorf("malformed minisign public key")
+	}
+	decoded, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return fmt.Errorf("error decoding minisign public key: %w", err)
+	}
+	if len(decoded) != len(minisignAlgorithm)+minisignKeyIDSize+ed25519.PublicKeySize || string(decoded[:2]) != minisignAlgorithm {
+		return fmt.Errorf("malformed minisign public key")
+	}
+
+	var id [minisignKeyIDSize]byte
+	copy(id[:], decoded[2:2+minisignKeyIDSize])
+	v.minisignKeys[id] = ed25519.PublicKey(decoded[2+minisignKeyIDSize:])
+	return nil
+}
+
+// Verify checks that 'signature' is a valid signature over 'data' from one
+// of the trusted keys.
+func (v *Verifier) Verify(data []byte, signature []byte) error {
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte(minisignUntrustedComment)) {
+		return v.verifyMinisign(data, signature)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("error decoding signature: %w", err)
+	}
+
+	digest := sha256.Sum256(data)
+	for _, key := range v.keys {
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			if ecdsa.VerifyASN1(k, digest[:], sig) {
+				return nil
+			}
+		case ed25519.PublicKey:
+			if ed25519.Verify(k, data, sig) {
+				return nil
+			}
+		}
+	}
+
+	return ErrVerificationFailed
+}
+
+func (v *Verifier) verifyMinisign(data []byte, signature []byte) error {
+	lines := nonCommentLines(signature)
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], minisignTrustedComment) {
+		return fmt.Errorf("malformed minisign signature")
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(lines[0])
+	if err != nil {
+		return fmt.Errorf("error decoding minisign signature: %w", err)
+	}
+	if len(decoded) != len(minisignAlgorithm)+minisignKeyIDSize+ed25519.SignatureSize {
+		return fmt.Errorf("malformed minisign signature")
+	}
+	switch string(decoded[:2]) {
+	case minisignAlgorithm:
+	case minisignPrehashed:
+		return fmt.Errorf("prehashed minisign signatures are not supported (sign with 'minisign -S -l')")
+	default:
+		return fmt.Errorf("unknown minisign signature algorithm: %q", decoded[:2])
+	}
+
+	var id [minisignKeyIDSize]byte
+	copy(id[:], decoded[2:2+minisignKeyIDSize])
+	key, exists := v.minisignKeys[id]
+	if !exists {
+		return fmt.Errorf("%w: signed with untrusted key %X", ErrVerificationFailed, id)
+	}
+
+	sig := decoded[2+minisignKeyIDSize:]
+	if !ed25519.Verify(key, data, sig) {
+		return ErrVerificationFailed
+	}
+
+	globalSig, err := base64.StdEncoding.DecodeString(lines[2])
+	if err != nil {
+		return fmt.Errorf("error decoding minisign global signature: %w", err)
+	}
+	trustedComment := strings.TrimPrefix(lines[1], minisignTrustedComment)
+	if !ed25519.Verify(key, append(append([]byte{}, sig...), trustedComment...), globalSig) {
+		return fmt.Errorf("%w: trusted comment has been tampered with", ErrVerificationFailed)
+	}
+
+	return nil
+}
+
+// VerifyFile checks that the signature in the file at 'signaturePath' is a
+// valid signature over 'data' from one of the trusted keys.
+func (v *Verifier) VerifyFile(data []byte, signaturePath string) error {
+	signature, err := os.ReadFile(signaturePath)
+	if err != nil {
+		return fmt.Errorf("error reading signature: %w", err)
+	}
+	return v.Verify(data, signature)
+}
+
+// nonCommentLines returns the non-empty lines of 'b' other than the minisign
+// untrusted comment.
+func nonCommentLines(b []byte) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, minisignUntrustedComment) {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
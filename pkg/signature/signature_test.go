@@ -0,0 +1,161 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package signature
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+var data = []byte("version: v1\nmig-configs:\n  all-disabled:\n  - devices: all\n    mig-enabled: false\n")
+
+func pemPublicKey(t *testing.T, key interface{}) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.Nil(t, err, "Unexpected failure from MarshalPKIXPublicKey")
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func cosignECDSA(t *testing.T) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.Nil(t, err, "Unexpected failure from GenerateKey")
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	require.Nil(t, err, "Unexpected failure from SignASN1")
+	return pemPublicKey(t, &key.PublicKey), []byte(base64.StdEncoding.EncodeToString(sig) + "\n")
+}
+
+func cosignEd25519(t *testing.T) ([]byte, []byte) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err, "Unexpected failure from GenerateKey")
+	sig := ed25519.Sign(private, data)
+	return pemPublicKey(t, public), []byte(base64.StdEncoding.EncodeToString(sig))
+}
+
+func minisign(t *testing.T, algorithm string, comment string) ([]byte, []byte) {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	require.Nil(t, err, "Unexpected failure from GenerateKey")
+	id := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	pub := append(append([]byte("Ed"), id...), public...)
+	pubFile := fmt.Sprintf("untrusted comment: minisign public key\n%s\n", base64.StdEncoding.EncodeToString(pub))
+
+	sig := ed25519.Sign(private, data)
+	globalSig := ed25519.Sign(private, append(append([]byte{}, sig...), "timestamp:1700000000"...))
+	sigFile := fmt.Sprintf("untrusted comment: signature from minisign secret key\n%s\ntrusted comment: %s\n%s\n",
+		base64.StdEncoding.EncodeToString(append(append([]byte(algorithm), id...), sig...)),
+		comment,
+		base64.StdEncoding.EncodeToString(globalSig))
+
+	return []byte(pubFile), []byte(sigFile)
+}
+
+func TestVerify(t *testing.T) {
+	ecdsaKey, ecdsaSig := cosignECDSA(t)
+	ed25519Key, ed25519Sig := cosignEd25519(t)
+	minisignKey, minisignSig := minisign(t, "Ed", "timestamp:1700000000")
+	_, minisignTamperedSig := minisign(t, "Ed", "timestamp:1800000000")
+	_, minisignPrehashedSig := minisign(t, "ED", "timestamp:1700000000")
+	otherKey, _ := cosignECDSA(t)
+
+	testCases := []struct {
+		description     string
+		keys            [][]byte
+		data            []byte
+		signature       []byte
+		expectedFailure bool
+	}{
+		{"cosign ECDSA", [][]byte{ecdsaKey}, data, ecdsaSig, false},
+		{"cosign Ed25519", [][]byte{ed25519Key}, data, ed25519Sig, false},
+		{"cosign with several trusted keys", [][]byte{otherKey, ecdsaKey}, data, ecdsaSig, false},
+		{"cosign untrusted key", [][]byte{otherKey}, data, ecdsaSig, true},
+		{"cosign tampered data", [][]byte{ecdsaKey}, []byte("tampered"), ecdsaSig, true},
+		{"cosign malformed signature", [][]byte{ecdsaKey}, data, []byte("!!!"), true},
+		{"minisign", [][]byte{minisignKey}, data, minisignSig, false},
+		{"minisign tampered data", [][]byte{minisignKey}, []byte("tampered"), minisignSig, true},
+		{"minisign untrusted key", [][]byte{ecdsaKey}, data, minisignSig, true},
+		{"minisign tampered trusted comment", [][]byte{minisignKey}, data, minisignTamperedSig, true},
+		{"minisign prehashed", [][]byte{minisignKey}, data, minisignPrehashedSig, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			v := NewVerifier()
+			for _, key := range tc.keys {
+				require.Nil(t, v.AddKeys(key), "Unexpected failure from AddKeys")
+			}
+			err := v.Verify(tc.data, tc.signature)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Verify")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Verify")
+		})
+	}
+}
+
+func TestVerifyFailedIs(t *testing.T) {
+	key, sig := cosignECDSA(t)
+	v := NewVerifier()
+	require.Nil(t, v.AddKeys(key), "Unexpected failure from AddKeys")
+	err := v.Verify([]byte("tampered"), sig)
+	require.True(t, errors.Is(err, ErrVerificationFailed))
+}
+
+func TestLoadVerifier(t *testing.T) {
+	ecdsaKey, ecdsaSig := cosignECDSA(t)
+	minisignKey, minisignSig := minisign(t, "Ed", "timestamp:1700000000")
+
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "cosign.pub"), ecdsaKey, 0600))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "minisign.pub"), minisignKey, 0600))
+
+	v, err := LoadVerifier(dir)
+	require.Nil(t, err, "Unexpected failure from LoadVerifier")
+	require.Equal(t, 2, v.NumKeys())
+	require.Nil(t, v.Verify(data, ecdsaSig), "Unexpected failure verifying cosign signature")
+	require.Nil(t, v.Verify(data, minisignSig), "Unexpected failure verifying minisign signature")
+
+	sigFile := filepath.Join(dir, "config.yaml.sig")
+	require.Nil(t, os.WriteFile(sigFile, ecdsaSig, 0600))
+	require.Nil(t, v.VerifyFile(data, sigFile), "Unexpected failure from VerifyFile")
+	require.NotNil(t, v.VerifyFile(data, sigFile+".missing"), "Unexpected success from VerifyFile with missing signature")
+
+	v, err = LoadVerifier(filepath.Join(dir, "cosign.pub"))
+	require.Nil(t, err, "Unexpected failure from LoadVerifier")
+	require.Equal(t, 1, v.NumKeys())
+
+	_, err = LoadVerifier(t.TempDir())
+	require.NotNil(t, err, "Unexpected success from LoadVerifier with no keys")
+
+	bogus := filepath.Join(t.TempDir(), "bogus.pub")
+	require.Nil(t, os.WriteFile(bogus, []byte("bogus"), 0600))
+	_, err = LoadVerifier(bogus)
+	require.NotNil(t, err, "Unexpected success from LoadVerifier with malformed key")
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simulate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// referenceMemoryMB is the memory of the GPU whose profile and placement
+// tables are embedded (those of an A100-SXM4-40GB).
+const referenceMemoryMB = 40960
+
+// gpuModelMemoryMB holds the memory of each GPU model in types.GpuModels
+// that shares the 7-slice MIG geometry of the A100, so that its tables are
+// those of the A100-SXM4-40GB with memory sizes scaled to its own. Models
+// with a different geometry (e.g. the 4-slice A30) are not listed.
+var gpuModelMemoryMB = map[string]uint64{
+	"A100-SXM4-40GB": 40960,
+	"A100-PCIE-40GB": 40960,
+	"A800-PCIE-40GB": 40960,
+	"A100-SXM4-80GB": 81920,
+	"A100-PCIE-80GB": 81920,
+	"A800-SXM4-80GB": 81920,
+	"A800-PCIE-80GB": 81920,
+	"H100-SXM5-80GB": 81920,
+	"H100-PCIE-80GB": 81920,
+	"H800-PCIE-80GB": 81920,
+}
+
+// GpuModelNames returns the names of the GPU models NewGpuModel can
+// simulate, in order.
+func GpuModelNames() []string {
+	var names []string
+	for name := range gpuModelMemoryMB {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewGpuModel returns a simulated GPU of 'model' (a name in types.GpuModels,
+// case insensitive) with no GPU instances, built from the embedded tables
+// alone, so that no GPU and no NVML are needed.
+func NewGpuModel(model string) (*GPU, error) {
+	var name string
+	var memoryMB uint64
+	for n, m := range gpuModelMemoryMB {
+		if strings.EqualFold(n, model) {
+			name, memoryMB = n, m
+		}
+	}
+	if name == "" {
+		return nil, fmt.Errorf("no MIG profile tables for GPU model '%v' (known models: %v)", model, strings.Join(GpuModelNames(), ", "))
+	}
+
+	device := dgxa100.NewDevice(0)
+	device.Name = "NVIDIA " + name
+	device.MemoryInfo = nvml.Memory{Total: memoryMB * 1024 * 1024}
+	deviceID := uint32(types.GpuModels[name][0])
+	device.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+		return nvml.PciInfo{PciDeviceId: deviceID}, nvml.SUCCESS
+	}
+	getGpuInstanceProfileInfo := device.GetGpuInstanceProfileInfoFunc
+	device.GetGpuInstanceProfileInfoFunc = func(giProfileId int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+		info, ret := getGpuInstanceProfileInfo(giProfileId)
+		if ret != nvml.SUCCESS {
+			return info, ret
+		}
+		info.MemorySizeMB = info.MemorySizeMB * memoryMB / referenceMemoryMB
+		return info, nvml.SUCCESS
+	}
+
+	return New(device)
+}
@@ -0,0 +1,204 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simulate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Report describes the GPU instances of a simulated GPU and how much room is
+// left around them. Slices are memory slices, the unit GPU instances are
+// placed in.
+//   - Fragmentation is the share of the free slices outside the largest run
+//     of contiguous free slices: 0 if the free slices are contiguous, and
+//     approaching 1 the more they are scattered.
+//   - Capacity is how many more GPU instances of each profile could still be
+//     created, were they the only ones created.
+type Report struct {
+	GpuInstances     []types.GpuInstance `json:"gpu-instances"`
+	Slices           int                 `json:"slices"`
+	FreeSlices       int                 `json:"free-slices"`
+	LargestFreeRange int                 `json:"largest-free-range"`
+	Fragmentation    float64             `json:"fragmentation"`
+	Capacity         map[string]int      `json:"capacity"`
+}
+
+// Report returns a Report of the current state of the GPU.
+func (g *GPU) Report() (*Report, error) {
+	gis, err := g.GpuInstances()
+	if err != nil {
+		return nil, err
+	}
+
+	used := usedMask(gis)
+	report := &Report{
+		GpuInstances: gis,
+		Slices:       int(g.slices),
+		Capacity:     make(map[string]int),
+	}
+
+	run := 0
+	for i := uint32(0); i < g.slices; i++ {
+		if used&(1<<i) != 0 {
+			run = 0
+			continue
+		}
+		run++
+		report.FreeSlices++
+		report.LargestFreeRange = max(report.LargestFreeRange, run)
+	}
+	if report.FreeSlices > 0 {
+		report.Fragmentation = 1 - float64(report.LargestFreeRange)/float64(report.FreeSlices)
+	}
+
+	for _, p := range g.profiles {
+		report.Capacity[p.profile.String()] = g.capacity(&p, gis, used)
+	}
+
+	return report, nil
+}
+
+// Fits checks whether GPU instances of all of 'profiles' could be created
+// together, alongside the GPU instances the GPU already has.
+func (g *GPU) Fits(profiles ...string) (bool, error) {
+	gis, err := g.GpuInstances()
+	if err != nil {
+		return false, err
+	}
+	placements, err := g.place(gis, profiles)
+	if err != nil {
+		return false, err
+	}
+	return placements != nil, nil
+}
+
+// capacity returns how many more GPU instances of 'p' fit around 'gis',
+// which occupy the slices in 'used'. Placements of the same profile all have
+// the same size, so taking the free placement that ends first each time
+// fits as many as possible.
+func (g *GPU) capacity(p *gpuInstanceProfile, gis []types.GpuInstance, used uint64) int {
+	remaining := int(p.info.InstanceCount)
+	for _, gi := range gis {
+		if gi.Profile == p.profile.String() {
+			remaining--
+		}
+	}
+
+	placements := append([]nvml.GpuInstancePlacement(nil), p.placements...)
+	sort.SliceStable(placements, func(i, j int) bool {
+		return placements[i].Start+placements[i].Size < placements[j].Start+placements[j].Size
+	})
+
+	count := 0
+	for _, placement := range placements {
+		if count == remaining {
+			break
+		}
+		mask := placementMask(placement)
+		if used&mask != 0 {
+			continue
+		}
+		used |= mask
+		count++
+	}
+	return count
+}
+
+// place finds a placement around 'gis' for a new GPU instance of each of
+// 'profiles' and returns them in the same order, or nil if they do not all
+// fit.
+func (g *GPU) place(gis []types.GpuInstance, profiles []string) ([]nvml.GpuInstancePlacement, error) {
+	remaining := make(map[uint32]int)
+	required := make([]*gpuInstanceProfile, len(profiles))
+	for i, profile := range profiles {
+		p, err := g.findProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		required[i] = p
+		remaining[p.info.Id] = int(p.info.InstanceCount)
+	}
+	for _, gi := range gis {
+		for _, p := range required {
+			if gi.Profile == p.profile.String() {
+				remaining[p.info.Id]--
+				break
+			}
+		}
+	}
+
+	// Placing the largest GPU instances first prunes the search the most.
+	order := make([]int, len(required))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return required[order[i]].info.SliceCount > required[order[j]].info.SliceCount
+	})
+
+	chosen := make([]nvml.GpuInstancePlacement, len(required))
+	var search func(i int, used uint64) bool
+	search = func(i int, used uint64) bool {
+		if i == len(order) {
+			return true
+		}
+		p := required[order[i]]
+		if remaining[p.info.Id] == 0 {
+			return false
+		}
+		remaining[p.info.Id]--
+		defer func() { remaining[p.info.Id]++ }()
+		for _, placement := range p.placements {
+			mask := placementMask(placement)
+			if used&mask != 0 {
+				continue
+			}
+			chosen[order[i]] = placement
+			if search(i+1, used|mask) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !search(0, usedMask(gis)) {
+		return nil, nil
+	}
+	return chosen, nil
+}
+
+func usedMask(gis []types.GpuInstance) uint64 {
+	var used uint64
+	for _, gi := range gis {
+		used |= placementMask(gi.Placement)
+	}
+	return used
+}
+
+func placementMask(p nvml.GpuInstancePlacement) uint64 {
+	return (uint64(1)<<p.Size - 1) << p.Start
+}
+
+// String summarizes 'r' on a single line.
+func (r *Report) String() string {
+	return fmt.Sprintf("%d GPU instances, %d/%d slices free, largest free range %d, fragmentation %.2f", len(r.GpuInstances), r.FreeSlices, r.Slices, r.LargestFreeRange, r.Fragmentation)
+}
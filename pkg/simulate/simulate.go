@@ -0,0 +1,323 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package simulate models the GPU instances of a single MIG capable GPU in
+// memory, for schedulers and capacity planners to find out where GPU
+// instances would be placed, how fragmented the GPU would become, and whether
+// further GPU instances would still fit, without touching any hardware.
+//
+// A simulated GPU is built from the profile and placement tables of a GPU of
+// the model being planned for, e.g. one snapshotted from a real GPU. Profiles
+// are resolved against those tables alone, so no GPU (and no NVML) is needed
+// once it has been built. Only GPU instances are modelled; the compute
+// instances inside them do not affect placement.
+package simulate
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+
+	"github.com/NVIDIA/mig-parted/pkg/mig/nvmlerrors"
+	"github.com/NVIDIA/mig-parted/pkg/mig/shadow"
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+// Action is what an Operation does.
+type Action string
+
+// Actions of an Operation.
+const (
+	ActionCreate  Action = "create"
+	ActionDestroy Action = "destroy"
+)
+
+// Operation is a single step run by GPU.Run.
+//   - Create operations create a GPU instance of 'Profile', at the placement
+//     beginning at memory slice 'Start' if it is set, or where NVML would
+//     place it otherwise.
+//   - Destroy operations destroy the GPU instance with ID 'ID'.
+type Operation struct {
+	Action  Action  `json:"action"`
+	Profile string  `json:"profile,omitempty"`
+	Start   *uint32 `json:"start,omitempty"`
+	ID      uint32  `json:"id,omitempty"`
+}
+
+// GPU is a simulated MIG capable GPU with MIG mode enabled.
+type GPU struct {
+	device   *shadow.Device
+	profiles []gpuInstanceProfile
+	slices   uint32
+}
+
+// gpuInstanceProfile is a GPU instance profile of the simulated GPU along
+// with the placements it can be created at.
+type gpuInstanceProfile struct {
+	profile    *types.MigProfile
+	info       nvml.GpuInstanceProfileInfo
+	placements []nvml.GpuInstancePlacement
+}
+
+// New returns a simulated GPU with the profile and placement tables of
+// 'device' and no GPU instances. NVML must already be initialized, but
+// nothing on 'device' is changed.
+func New(device nvml.Device) (*GPU, error) {
+	snapshot, err := shadow.SnapshotDevice(device)
+	if err != nil {
+		return nil, fmt.Errorf("error copying GPU: %w", err)
+	}
+	if !snapshot.MigCapable {
+		return nil, fmt.Errorf("GPU '%v' is not MIG capable", snapshot.Name)
+	}
+
+	d := shadow.NewDevice()
+	d.UUID = snapshot.UUID
+	d.Name = snapshot.Name
+	d.PciInfo = snapshot.PciInfo
+	d.Memory = snapshot.Memory
+	d.MigMode = nvml.DEVICE_MIG_ENABLE
+	d.GpuInstanceProfiles = snapshot.GpuInstanceProfiles
+	d.GpuInstancePlacements = snapshot.GpuInstancePlacements
+	d.ComputeInstanceProfiles = snapshot.ComputeInstanceProfiles
+
+	g := &GPU{device: d}
+	for giProfileID := 0; giProfileID < nvml.GPU_INSTANCE_PROFILE_COUNT; giProfileID++ {
+		info, exists := d.GpuInstanceProfiles[giProfileID]
+		if !exists || info.InstanceCount == 0 {
+			continue
+		}
+		mp, err := types.NewMigProfile(giProfileID, fullComputeInstanceProfileID(info.SliceCount), nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED, info.MemorySizeMB, d.Memory.Total)
+		if err != nil {
+			return nil, fmt.Errorf("error creating new MIG profile for '%v': %w", giProfileID, err)
+		}
+		placements := d.GpuInstancePlacements[giProfileID]
+		for _, p := range placements {
+			g.slices = max(g.slices, p.Start+p.Size)
+		}
+		g.profiles = append(g.profiles, gpuInstanceProfile{mp, info, placements})
+	}
+	if len(g.profiles) == 0 {
+		return nil, fmt.Errorf("GPU '%v' has no GPU instance profiles", d.Name)
+	}
+
+	return g, nil
+}
+
+// NewA100_SXM4_40GB returns a simulated A100-SXM4-40GB with no GPU instances.
+func NewA100_SXM4_40GB() (*GPU, error) {
+	return New(dgxa100.NewDevice(0))
+}
+
+// fullComputeInstanceProfileID returns the compute instance profile spanning
+// all of a GPU instance with 'slices' slices.
+func fullComputeInstanceProfileID(slices uint32) int {
+	switch slices {
+	case 1:
+		return nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE
+	case 2:
+		return nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE
+	case 3:
+		return nvml.COMPUTE_INSTANCE_PROFILE_3_SLICE
+	case 4:
+		return nvml.COMPUTE_INSTANCE_PROFILE_4_SLICE
+	case 6:
+		return nvml.COMPUTE_INSTANCE_PROFILE_6_SLICE
+	case 7:
+		return nvml.COMPUTE_INSTANCE_PROFILE_7_SLICE
+	}
+	return nvml.COMPUTE_INSTANCE_PROFILE_8_SLICE
+}
+
+// Profiles returns the GPU instance profiles of the GPU, in the canonical
+// order of MIG profiles.
+func (g *GPU) Profiles() []string {
+	var profiles []string
+	for _, mp := range g.MigProfiles() {
+		profiles = append(profiles, mp.String())
+	}
+	return profiles
+}
+
+// MigProfiles returns the MIG profiles of the GPU instance profiles of the
+// GPU, in the canonical order of MIG profiles.
+func (g *GPU) MigProfiles() []*types.MigProfile {
+	var mps []*types.MigProfile
+	for _, p := range g.profiles {
+		mps = append(mps, p.profile)
+	}
+	types.SortMigProfiles(mps)
+	return mps
+}
+
+// findProfile returns the GPU instance profile of the GPU matching 'profile'.
+func (g *GPU) findProfile(profile string) (*gpuInstanceProfile, error) {
+	for i := range g.profiles {
+		if g.profiles[i].profile.Matches(profile) {
+			return &g.profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported GPU instance profile '%v' (supported profiles: %v)", profile, g.Profiles())
+}
+
+// Create creates a GPU instance of 'profile', at the placement beginning at
+// memory slice 'start' if it is non-nil, or at the first free placement NVML
+// would choose otherwise.
+func (g *GPU) Create(profile string, start *uint32) (*types.GpuInstance, error) {
+	p, err := g.findProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	var gi nvml.GpuInstance
+	var ret nvml.Return
+	if start == nil {
+		gi, ret = g.device.CreateGpuInstance(&p.info)
+	} else {
+		placement, err := findPlacement(p.placements, *start)
+		if err != nil {
+			return nil, fmt.Errorf("invalid placement for '%v': %w", p.profile, err)
+		}
+		gi, ret = g.device.CreateGpuInstanceWithPlacement(&p.info, placement)
+	}
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error creating GPU instance for '%v': %w", p.profile, nvmlerrors.New(ret))
+	}
+
+	info, ret := gi.GetInfo()
+	if ret != nvml.SUCCESS {
+		return nil, fmt.Errorf("error getting GPU instance info for '%v': %w", p.profile, nvmlerrors.New(ret))
+	}
+	return &types.GpuInstance{Profile: p.profile.String(), ID: info.Id, Placement: info.Placement}, nil
+}
+
+// Destroy destroys the GPU instance with ID 'id'.
+func (g *GPU) Destroy(id uint32) error {
+	for _, p := range g.profiles {
+		gis, ret := g.device.GetGpuInstances(&p.info)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting GPU instances for '%v': %w", p.profile, nvmlerrors.New(ret))
+		}
+		for _, gi := range gis {
+			info, ret := gi.GetInfo()
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error getting GPU instance info for '%v': %w", p.profile, nvmlerrors.New(ret))
+			}
+			if info.Id != id {
+				continue
+			}
+			ret = gi.Destroy()
+			if ret != nvml.SUCCESS {
+				return fmt.Errorf("error destroying GPU instance %d: %w", id, nvmlerrors.New(ret))
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("GPU instance %d not found: %w", id, nvmlerrors.New(nvml.ERROR_NOT_FOUND))
+}
+
+// Run runs 'ops' in order, stopping at the first one that fails.
+func (g *GPU) Run(ops []Operation) error {
+	for i, op := range ops {
+		var err error
+		switch op.Action {
+		case ActionCreate:
+			_, err = g.Create(op.Profile, op.Start)
+		case ActionDestroy:
+			err = g.Destroy(op.ID)
+		default:
+			err = fmt.Errorf("unknown action '%v'", op.Action)
+		}
+		if err != nil {
+			return fmt.Errorf("operation %d (%v): %w", i, op.Action, err)
+		}
+	}
+	return nil
+}
+
+// Apply replaces the GPU instances of the GPU with those of 'config', or
+// leaves them unchanged if those do not fit. Profiles with compute instances
+// that share a GPU instance (e.g. '1c.4g.20gb') are not supported.
+func (g *GPU) Apply(config types.MigConfig) error {
+	var requests []string
+	for _, profile := range config.Profiles() {
+		for i := 0; i < config[profile]; i++ {
+			requests = append(requests, profile)
+		}
+	}
+
+	placements, err := g.place(nil, requests)
+	if err != nil {
+		return err
+	}
+	if placements == nil {
+		return fmt.Errorf("MIG config %v does not fit the GPU", config)
+	}
+
+	gis, err := g.GpuInstances()
+	if err != nil {
+		return err
+	}
+	for _, gi := range gis {
+		err := g.Destroy(gi.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, profile := range requests {
+		_, err := g.Create(profile, &placements[i].Start)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GpuInstances returns the GPU instances of the GPU, ordered by placement.
+func (g *GPU) GpuInstances() ([]types.GpuInstance, error) {
+	var gis []types.GpuInstance
+	for _, p := range g.profiles {
+		handles, ret := g.device.GetGpuInstances(&p.info)
+		if ret != nvml.SUCCESS {
+			return nil, fmt.Errorf("error getting GPU instances for '%v': %w", p.profile, nvmlerrors.New(ret))
+		}
+		for _, gi := range handles {
+			info, ret := gi.GetInfo()
+			if ret != nvml.SUCCESS {
+				return nil, fmt.Errorf("error getting GPU instance info for '%v': %w", p.profile, nvmlerrors.New(ret))
+			}
+			gis = append(gis, types.GpuInstance{Profile: p.profile.String(), ID: info.Id, Placement: info.Placement})
+		}
+	}
+	sort.Slice(gis, func(i, j int) bool {
+		return gis[i].Placement.Start < gis[j].Placement.Start
+	})
+	return gis, nil
+}
+
+func findPlacement(placements []nvml.GpuInstancePlacement, start uint32) (*nvml.GpuInstancePlacement, error) {
+	var starts []uint32
+	for i := range placements {
+		if placements[i].Start == start {
+			return &placements[i], nil
+		}
+		starts = append(starts, placements[i].Start)
+	}
+	return nil, fmt.Errorf("no placement starts at memory slice %d (valid starts: %v)", start, starts)
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package simulate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func newA100(t *testing.T) *GPU {
+	gpu, err := NewA100_SXM4_40GB()
+	require.Nil(t, err, "Unexpected failure from NewA100_SXM4_40GB")
+	return gpu
+}
+
+func TestProfiles(t *testing.T) {
+	gpu := newA100(t)
+	require.Equal(t, []string{"1g.5gb", "1g.5gb+me", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"}, gpu.Profiles())
+}
+
+func TestRun(t *testing.T) {
+	start := func(s uint32) *uint32 { return &s }
+
+	testCases := []struct {
+		description      string
+		ops              []Operation
+		expectedFailure  bool
+		expectedProfiles []string
+	}{
+		{
+			"Create",
+			[]Operation{
+				{Action: ActionCreate, Profile: "3g.20gb"},
+				{Action: ActionCreate, Profile: "1g.5gb"},
+			},
+			false,
+			[]string{"3g.20gb", "1g.5gb"},
+		},
+		{
+			"Create and destroy",
+			[]Operation{
+				{Action: ActionCreate, Profile: "3g.20gb"},
+				{Action: ActionCreate, Profile: "1g.5gb"},
+				{Action: ActionDestroy, ID: 1},
+			},
+			false,
+			[]string{"1g.5gb"},
+		},
+		{
+			"Create at placement",
+			[]Operation{
+				{Action: ActionCreate, Profile: "1g.5gb", Start: start(6)},
+			},
+			false,
+			[]string{"1g.5gb"},
+		},
+		{
+			"Overlapping placement",
+			[]Operation{
+				{Action: ActionCreate, Profile: "4g.20gb", Start: start(0)},
+				{Action: ActionCreate, Profile: "1g.5gb", Start: start(2)},
+			},
+			true,
+			nil,
+		},
+		{
+			"Unsupported profile",
+			[]Operation{
+				{Action: ActionCreate, Profile: "8g.80gb"},
+			},
+			true,
+			nil,
+		},
+		{
+			"Destroy missing GPU instance",
+			[]Operation{
+				{Action: ActionDestroy, ID: 3},
+			},
+			true,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			gpu := newA100(t)
+			err := gpu.Run(tc.ops)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from Run")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from Run")
+
+			gis, err := gpu.GpuInstances()
+			require.Nil(t, err)
+			var profiles []string
+			for _, gi := range gis {
+				profiles = append(profiles, gi.Profile)
+			}
+			require.Equal(t, tc.expectedProfiles, profiles)
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	gpu := newA100(t)
+
+	report, err := gpu.Report()
+	require.Nil(t, err)
+	require.Equal(t, 8, report.Slices)
+	require.Equal(t, 8, report.FreeSlices)
+	require.Equal(t, 0.0, report.Fragmentation)
+	require.Equal(t, 7, report.Capacity["1g.5gb"])
+	require.Equal(t, 1, report.Capacity["7g.40gb"])
+
+	// A 1g.5gb in the lower half leaves room for a 3g.20gb in the upper
+	// half, but no longer for a 4g.20gb or a 7g.40gb.
+	start := uint32(2)
+	_, err = gpu.Create("1g.5gb", &start)
+	require.Nil(t, err)
+
+	report, err = gpu.Report()
+	require.Nil(t, err)
+	require.Equal(t, 7, report.FreeSlices)
+	require.Equal(t, 5, report.LargestFreeRange)
+	require.InDelta(t, 2.0/7, report.Fragmentation, 1e-9)
+	require.Equal(t, 6, report.Capacity["1g.5gb"])
+	require.Equal(t, 1, report.Capacity["3g.20gb"])
+	require.Equal(t, 0, report.Capacity["4g.20gb"])
+	require.Equal(t, 0, report.Capacity["7g.40gb"])
+
+	fits, err := gpu.Fits("3g.20gb", "2g.10gb")
+	require.Nil(t, err)
+	require.True(t, fits)
+
+	fits, err = gpu.Fits("3g.20gb", "3g.20gb")
+	require.Nil(t, err)
+	require.False(t, fits)
+
+	_, err = gpu.Fits("8g.80gb")
+	require.NotNil(t, err, "Unexpected success from Fits with an unsupported profile")
+}
+
+func TestApply(t *testing.T) {
+	gpu := newA100(t)
+
+	_, err := gpu.Create("7g.40gb", nil)
+	require.Nil(t, err)
+
+	err = gpu.Apply(types.MigConfig{"3g.20gb": 1, "2g.10gb": 1, "1g.5gb": 2})
+	require.Nil(t, err, "Unexpected failure from Apply")
+
+	report, err := gpu.Report()
+	require.Nil(t, err)
+	require.Len(t, report.GpuInstances, 4)
+	require.Equal(t, 0, report.FreeSlices)
+
+	err = gpu.Apply(types.MigConfig{"4g.20gb": 2})
+	require.NotNil(t, err, "Unexpected success from Apply with a config that does not fit")
+
+	unchanged, err := gpu.Report()
+	require.Nil(t, err)
+	require.Equal(t, report.GpuInstances, unchanged.GpuInstances)
+}
+
+func TestNewGpuModel(t *testing.T) {
+	testCases := []struct {
+		model            string
+		expectedProfiles []string
+		expectedError    bool
+	}{
+		{
+			"A100-SXM4-40GB",
+			[]string{"1g.5gb", "1g.5gb+me", "1g.10gb", "2g.10gb", "3g.20gb", "4g.20gb", "7g.40gb"},
+			false,
+		},
+		{
+			"a100-sxm4-80gb",
+			[]string{"1g.10gb", "1g.10gb+me", "1g.20gb", "2g.20gb", "3g.40gb", "4g.40gb", "7g.80gb"},
+			false,
+		},
+		{"A30-24GB", nil, true},
+		{"unknown", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.model, func(t *testing.T) {
+			g, err := NewGpuModel(tc.model)
+			if tc.expectedError {
+				require.NotNil(t, err)
+				return
+			}
+			require.Nil(t, err)
+			require.Equal(t, tc.expectedProfiles, g.Profiles())
+		})
+	}
+}
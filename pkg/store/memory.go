@@ -0,0 +1,108 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]map[string][]byte
+	now     func() time.Time
+}
+
+var _ Store = (*memoryStore)(nil)
+
+// NewMemoryStore creates a Store that only keeps its contents in memory. It
+// is intended for tests, and for running without persistent state.
+func NewMemoryStore() Store {
+	return &memoryStore{
+		buckets: make(map[string]map[string][]byte),
+		now:     time.Now,
+	}
+}
+
+func (s *memoryStore) Get(bucket, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, exists := s.buckets[bucket][key]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return append([]byte{}, v...), nil
+}
+
+func (s *memoryStore) Put(bucket, key string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(bucket, key, value)
+	return nil
+}
+
+func (s *memoryStore) put(bucket, key string, value []byte) {
+	if s.buckets[bucket] == nil {
+		s.buckets[bucket] = make(map[string][]byte)
+	}
+	s.buckets[bucket][key] = append([]byte{}, value...)
+}
+
+func (s *memoryStore) Delete(bucket, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.buckets[bucket], key)
+	return nil
+}
+
+func (s *memoryStore) Keys(bucket string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var keys []string
+	for k := range s.buckets[bucket] {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+func (s *memoryStore) Lock(name, owner string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, err := takeLease(name, s.buckets[BucketLocks][name], owner, ttl, s.now())
+	if err != nil {
+		return err
+	}
+	s.put(BucketLocks, name, value)
+	return nil
+}
+
+func (s *memoryStore) Unlock(name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := releaseLease(name, s.buckets[BucketLocks][name], owner)
+	if err != nil {
+		return err
+	}
+	delete(s.buckets[BucketLocks], name)
+	return nil
+}
+
+func (s *memoryStore) Close() error {
+	return nil
+}
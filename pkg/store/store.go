@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultFile is the well-known location of the embedded store.
+const DefaultFile = "/var/lib/nvidia-mig-manager/state.db"
+
+// Buckets for the different kinds of operational state kept in a Store.
+const (
+	BucketAppliedConfigs     = "applied-configs"
+	BucketJournal            = "journal"
+	BucketCheckpoints        = "checkpoints"
+	BucketLocks              = "locks"
+	BucketScratch            = "scratch"
+	BucketScratchGenerations = "scratch-generations"
+	BucketLabels             = "labels"
+	BucketReservations       = "reservations"
+)
+
+var (
+	// ErrNotFound is returned when a key does not exist in a bucket.
+	ErrNotFound = errors.New("not found")
+	// ErrLocked is returned when a lock is held by a different owner.
+	ErrLocked = errors.New("locked")
+)
+
+// Store holds the operational state of mig-parted (e.g. the hashes of applied
+// configs, journal entries and checkpoints) as values in named buckets. It
+// also provides named locks, which are leases held by an owner until they are
+// released or expire. Implementations must be safe for concurrent use.
+//
+// The embedded implementation returned by Open keeps everything in a single
+// file on the node. Other implementations (e.g. backed by a ConfigMap or a
+// custom resource) can be swapped in by anything that only depends on Store.
+type Store interface {
+	// Get returns the value of 'key' in 'bucket', or ErrNotFound.
+	Get(bucket, key string) ([]byte, error)
+	// Put sets the value of 'key' in 'bucket', creating the bucket if needed.
+	Put(bucket, key string, value []byte) error
+	// Delete removes 'key' from 'bucket'. Deleting a missing key is not an error.
+	Delete(bucket, key string) error
+	// Keys returns the keys in 'bucket' in sorted order.
+	Keys(bucket string) ([]string, error)
+	// Lock takes the lock 'name' for 'owner' for the duration 'ttl'. Taking a
+	// lock already held by 'owner' extends it. It returns ErrLocked if the
+	// lock is held by another owner and has not expired.
+	Lock(name, owner string, ttl time.Duration) error
+	// Unlock releases the lock 'name' if it is held by 'owner'.
+	Unlock(name, owner string) error
+	// Close releases the resources held by the Store.
+	Close() error
+}
+
+// lease is the value stored in BucketLocks for a held lock.
+type lease struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+// takeLease decides whether 'owner' may take the lock whose current value is
+// 'current' (nil if it is not held) at time 'now', and returns the new value
+// to store if so.
+func takeLease(name string, current []byte, owner string, ttl time.Duration, now time.Time) ([]byte, error) {
+	if current != nil {
+		var l lease
+		err := json.Unmarshal(current, &l)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing lock '%v': %w", name, err)
+		}
+		if l.Owner != owner && now.Before(l.Expires) {
+			return nil, fmt.Errorf("%w: '%v' is held by '%v' until %v", ErrLocked, name, l.Owner, l.Expires.Format(time.RFC3339))
+		}
+	}
+	return json.Marshal(lease{Owner: owner, Expires: now.Add(ttl)})
+}
+
+// releaseLease decides whether 'owner' may release the lock whose current
+// value is 'current' (nil if it is not held).
+func releaseLease(name string, current []byte, owner string) error {
+	if current == nil {
+		return nil
+	}
+	var l lease
+	err := json.Unmarshal(current, &l)
+	if err != nil {
+		return fmt.Errorf("error parsing lock '%v': %w", name, err)
+	}
+	if l.Owner != owner {
+		return fmt.Errorf("%w: '%v' is held by '%v'", ErrLocked, name, l.Owner)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package store
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStores returns every Store implementation, with a clock that can be
+// advanced by the test.
+func newTestStores(t *testing.T) (map[string]Store, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	bolt, err := Open(filepath.Join(t.TempDir(), "state", "state.db"))
+	require.Nil(t, err, "Unexpected failure from Open")
+	bolt.(*boltStore).now = clock
+	t.Cleanup(func() { bolt.Close() })
+
+	memory := NewMemoryStore()
+	memory.(*memoryStore).now = clock
+
+	return map[string]Store{
+		"bolt":   bolt,
+		"memory": memory,
+	}, &now
+}
+
+func TestStoreValues(t *testing.T) {
+	stores, _ := newTestStores(t)
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			_, err := s.Get(BucketAppliedConfigs, "missing")
+			require.True(t, errors.Is(err, ErrNotFound), "Unexpected error from Get on missing bucket: %v", err)
+
+			keys, err := s.Keys(BucketAppliedConfigs)
+			require.Nil(t, err)
+			require.Empty(t, keys)
+
+			require.Nil(t, s.Put(BucketAppliedConfigs, "gpu-1", []byte("b")))
+			require.Nil(t, s.Put(BucketAppliedConfigs, "gpu-0", []byte("a")))
+			require.Nil(t, s.Put(BucketCheckpoints, "gpu-0", []byte("c")))
+
+			_, err = s.Get(BucketAppliedConfigs, "missing")
+			require.True(t, errors.Is(err, ErrNotFound), "Unexpected error from Get on missing key: %v", err)
+
+			value, err := s.Get(BucketAppliedConfigs, "gpu-0")
+			require.Nil(t, err)
+			require.Equal(t, []byte("a"), value)
+
+			// Values returned are copies that can be modified freely.
+			value[0] = 'z'
+			value, err = s.Get(BucketAppliedConfigs, "gpu-0")
+			require.Nil(t, err)
+			require.Equal(t, []byte("a"), value)
+
+			keys, err = s.Keys(BucketAppliedConfigs)
+			require.Nil(t, err)
+			require.Equal(t, []string{"gpu-0", "gpu-1"}, keys)
+
+			require.Nil(t, s.Put(BucketAppliedConfigs, "gpu-0", []byte("d")))
+			value, err = s.Get(BucketAppliedConfigs, "gpu-0")
+			require.Nil(t, err)
+			require.Equal(t, []byte("d"), value)
+
+			require.Nil(t, s.Delete(BucketAppliedConfigs, "gpu-0"))
+			require.Nil(t, s.Delete(BucketAppliedConfigs, "gpu-0"))
+			require.Nil(t, s.Delete(BucketJournal, "missing"))
+			keys, err = s.Keys(BucketAppliedConfigs)
+			require.Nil(t, err)
+			require.Equal(t, []string{"gpu-1"}, keys)
+
+			value, err = s.Get(BucketCheckpoints, "gpu-0")
+			require.Nil(t, err)
+			require.Equal(t, []byte("c"), value)
+		})
+	}
+}
+
+func TestStoreLocks(t *testing.T) {
+	stores, now := newTestStores(t)
+	for name, s := range stores {
+		t.Run(name, func(t *testing.T) {
+			require.Nil(t, s.Unlock("apply", "a"), "Unexpected failure releasing a lock that is not held")
+
+			require.Nil(t, s.Lock("apply", "a", time.Minute))
+			require.Nil(t, s.Lock("apply", "a", time.Minute), "Unexpected failure extending a held lock")
+
+			err := s.Lock("apply", "b", time.Minute)
+			require.True(t, errors.Is(err, ErrLocked), "Unexpected error taking a held lock: %v", err)
+			err = s.Unlock("apply", "b")
+			require.True(t, errors.Is(err, ErrLocked), "Unexpected error releasing a lock held by another owner: %v", err)
+
+			// Locks are independent of each other.
+			require.Nil(t, s.Lock("checkpoint", "b", time.Minute))
+			require.Nil(t, s.Unlock("checkpoint", "b"))
+
+			// An expired lock can be taken by another owner.
+			*now = now.Add(2 * time.Minute)
+			require.Nil(t, s.Lock("apply", "b", time.Minute))
+			err = s.Lock("apply", "a", time.Minute)
+			require.True(t, errors.Is(err, ErrLocked), "Unexpected error taking a held lock: %v", err)
+
+			require.Nil(t, s.Unlock("apply", "b"))
+			require.Nil(t, s.Lock("apply", "a", time.Minute))
+			require.Nil(t, s.Unlock("apply", "a"))
+		})
+	}
+}
+
+func TestOpenPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.db")
+
+	s, err := Open(path)
+	require.Nil(t, err)
+	require.Nil(t, s.Put(BucketAppliedConfigs, "hash", []byte("abc")))
+	require.Nil(t, s.Close())
+
+	s, err = Open(path)
+	require.Nil(t, err)
+	defer s.Close()
+	value, err := s.Get(BucketAppliedConfigs, "hash")
+	require.Nil(t, err)
+	require.Equal(t, []byte("abc"), value)
+}
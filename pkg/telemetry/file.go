@@ -0,0 +1,60 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+type fileSink struct {
+	sync.Mutex
+	file *os.File
+}
+
+var _ Telemetry = (*fileSink)(nil)
+
+// NewFileSink returns a Telemetry that appends each event to 'path' as a
+// single line of JSON.
+func NewFileSink(path string) (Telemetry, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening telemetry file: %v", err)
+	}
+	return &fileSink{file: file}, nil
+}
+
+func (t *fileSink) Emit(e Event) error {
+	output, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling telemetry event: %v", err)
+	}
+	output = append(output, '\n')
+
+	t.Lock()
+	defer t.Unlock()
+	if _, err := t.file.Write(output); err != nil {
+		return fmt.Errorf("error writing telemetry event: %w", err)
+	}
+	return nil
+}
+
+func (t *fileSink) Close() error {
+	return t.file.Close()
+}
@@ -0,0 +1,67 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds how long emitting a single event over HTTP may
+// take, so that an unreachable collector cannot stall a reconfiguration.
+const DefaultHTTPTimeout = 5 * time.Second
+
+type httpSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ Telemetry = (*httpSink)(nil)
+
+// NewHTTPSink returns a Telemetry that POSTs each event to 'url' as JSON.
+func NewHTTPSink(url string) Telemetry {
+	return &httpSink{
+		url:    url,
+		client: &http.Client{Timeout: DefaultHTTPTimeout},
+	}
+}
+
+func (t *httpSink) Emit(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshaling telemetry event: %v", err)
+	}
+
+	resp, err := t.client.Post(t.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting telemetry event: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected response posting telemetry event: %v", resp.Status)
+	}
+	return nil
+}
+
+func (t *httpSink) Close() error {
+	t.client.CloseIdleConnections()
+	return nil
+}
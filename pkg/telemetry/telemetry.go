@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package telemetry defines an opt-in sink for anonymized events about MIG
+// reconfiguration, so that fleet owners can aggregate reliability data
+// without scraping logs. Events never carry host names, config labels or
+// error messages; only event types, timings, GPU model counts and coarse
+// error classes.
+package telemetry
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	ApplyStarted   EventType = "apply-started"
+	ApplySucceeded EventType = "apply-succeeded"
+	ApplyFailed    EventType = "apply-failed"
+)
+
+// Event is a single anonymized telemetry event.
+type Event struct {
+	Type       EventType      `json:"type"`
+	Timestamp  time.Time      `json:"timestamp"`
+	DurationMS int64          `json:"duration-ms,omitempty"`
+	GPUModels  map[string]int `json:"gpu-models,omitempty"`
+	ErrorClass string         `json:"error-class,omitempty"`
+}
+
+// Telemetry is the interface implemented by every telemetry sink.
+type Telemetry interface {
+	Emit(Event) error
+	Close() error
+}
+
+type noop struct{}
+
+var _ Telemetry = (*noop)(nil)
+
+// NewNoop returns a Telemetry that discards all events. It is the default
+// when no sink is configured.
+func NewNoop() Telemetry {
+	return &noop{}
+}
+
+func (t *noop) Emit(Event) error { return nil }
+func (t *noop) Close() error     { return nil }
+
+// New returns the Telemetry sink described by 'sink':
+//   - ""                    discards all events
+//   - "file:///path/to/log" appends events to a file as JSON lines
+//   - "http(s)://host/path" POSTs each event as JSON
+func New(sink string) (Telemetry, error) {
+	if sink == "" {
+		return NewNoop(), nil
+	}
+
+	u, err := url.Parse(sink)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing telemetry sink '%v': %v", sink, err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		return NewFileSink(u.Path)
+	case "http", "https":
+		return NewHTTPSink(sink), nil
+	}
+
+	return nil, fmt.Errorf("unsupported telemetry sink scheme: %v", u.Scheme)
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package telemetry
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newEvent() Event {
+	return Event{
+		Type:       ApplyFailed,
+		Timestamp:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DurationMS: 1500,
+		GPUModels:  map[string]int{"0x20B010DE": 8},
+		ErrorClass: "in-use",
+	}
+}
+
+func TestNew(t *testing.T) {
+	testCases := []struct {
+		description     string
+		sink            string
+		expectedFailure bool
+	}{
+		{"No-op", "", false},
+		{"File", "file://" + filepath.Join(t.TempDir(), "events.jsonl"), false},
+		{"HTTP", "http://localhost:8080/events", false},
+		{"HTTPS", "https://localhost:8443/events", false},
+		{"Unsupported scheme", "ftp://localhost/events", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			sink, err := New(tc.sink)
+			if tc.expectedFailure {
+				require.NotNil(t, err, "Unexpected success from New")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from New")
+			require.Nil(t, sink.Close())
+		})
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	sink, err := NewFileSink(path)
+	require.Nil(t, err)
+	require.Nil(t, sink.Emit(newEvent()))
+	require.Nil(t, sink.Emit(newEvent()))
+	require.Nil(t, sink.Close())
+
+	contents, err := os.ReadFile(path)
+	require.Nil(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(contents)), "\n")
+	require.Len(t, lines, 2)
+
+	var e Event
+	require.Nil(t, json.Unmarshal([]byte(lines[0]), &e))
+	require.Equal(t, newEvent(), e)
+}
+
+func TestHTTPSink(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var e Event
+		if err := json.Unmarshal(body, &e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- e
+	}))
+	defer server.Close()
+
+	sink := NewHTTPSink(server.URL)
+	require.Nil(t, sink.Emit(newEvent()))
+	require.Equal(t, newEvent(), <-received)
+	require.Nil(t, sink.Close())
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	require.NotNil(t, NewHTTPSink(failing.URL).Emit(newEvent()))
+}
@@ -0,0 +1,311 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package testutil provides a programmable mock NVML server for use in tests.
+package testutil
+
+import (
+	"fmt"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock"
+	"github.com/NVIDIA/go-nvml/pkg/nvml/mock/dgxa100"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+const nvidiaVendorID = 0x10DE
+
+// GPUModel describes the static properties of a mock GPU.
+type GPUModel struct {
+	Name         string
+	Architecture nvml.DeviceArchitecture
+	DeviceID     uint16
+	MemoryMB     uint64
+}
+
+// GpuInstance describes a GPU instance (and the compute instances inside it)
+// that exists on a mock GPU when the server is built.
+type GpuInstance struct {
+	Profile          int
+	ComputeInstances []int
+}
+
+// GPU describes a single mock GPU.
+type GPU struct {
+	Model        GPUModel
+	MigEnabled   bool
+	GpuInstances []GpuInstance
+	// VGPUGuest makes the GPU appear as a vGPU assigned to a guest VM.
+	VGPUGuest bool
+}
+
+// Known GPU models. All of them expose the 7-slice MIG geometry of the
+// dgxa100 mock, with GPU instance memory sizes scaled to the model's memory.
+var (
+	A100_SXM4_40GB = GPUModel{
+		Name:         "Mock NVIDIA A100-SXM4-40GB",
+		Architecture: nvml.DEVICE_ARCH_AMPERE,
+		DeviceID:     0x20B0,
+		MemoryMB:     40960,
+	}
+	A100_SXM4_80GB = GPUModel{
+		Name:         "Mock NVIDIA A100-SXM4-80GB",
+		Architecture: nvml.DEVICE_ARCH_AMPERE,
+		DeviceID:     0x20B2,
+		MemoryMB:     81920,
+	}
+	H100_SXM5_80GB = GPUModel{
+		Name:         "Mock NVIDIA H100 80GB HBM3",
+		Architecture: nvml.DEVICE_ARCH_HOPPER,
+		DeviceID:     0x2330,
+		MemoryMB:     81920,
+	}
+)
+
+// Device is a mock GPU. Its mock functions can be overridden after the server
+// is built to inject failures.
+type Device = dgxa100.Device
+
+// Server is a mock NVML server with an arbitrary set of GPUs.
+type Server struct {
+	mock.Interface
+	mock.ExtendedInterface
+	Devices           []nvml.Device
+	DriverVersion     string
+	NvmlVersion       string
+	CudaDriverVersion int
+}
+
+var _ nvml.Interface = (*Server)(nil)
+
+// ServerBuilder constructs a mock NVML server one GPU at a time.
+type ServerBuilder struct {
+	gpus              []GPU
+	driverVersion     string
+	nvmlVersion       string
+	cudaDriverVersion int
+}
+
+// NewServerBuilder creates a builder for a server with no GPUs.
+func NewServerBuilder() *ServerBuilder {
+	return &ServerBuilder{
+		driverVersion:     "550.54.15",
+		nvmlVersion:       "12.550.54.15",
+		cudaDriverVersion: 12040,
+	}
+}
+
+// NewDGXA100 builds a server with 8 A100-SXM4-40GB GPUs and MIG disabled.
+func NewDGXA100() *Server {
+	return NewServerBuilder().WithGPUs(8, GPU{Model: A100_SXM4_40GB}).MustBuild()
+}
+
+// WithDriverVersion sets the driver version reported by the server.
+func (b *ServerBuilder) WithDriverVersion(version string) *ServerBuilder {
+	b.driverVersion = version
+	return b
+}
+
+// WithGPU appends a single GPU to the server.
+func (b *ServerBuilder) WithGPU(gpu GPU) *ServerBuilder {
+	b.gpus = append(b.gpus, gpu)
+	return b
+}
+
+// WithGPUs appends 'count' identical GPUs to the server.
+func (b *ServerBuilder) WithGPUs(count int, gpu GPU) *ServerBuilder {
+	for i := 0; i < count; i++ {
+		b.WithGPU(gpu)
+	}
+	return b
+}
+
+// Build constructs the server, creating any pre-existing MIG instances.
+func (b *ServerBuilder) Build() (*Server, error) {
+	server := &Server{
+		DriverVersion:     b.driverVersion,
+		NvmlVersion:       b.nvmlVersion,
+		CudaDriverVersion: b.cudaDriverVersion,
+	}
+	for i, gpu := range b.gpus {
+		device, err := newDevice(i, gpu)
+		if err != nil {
+			return nil, fmt.Errorf("error creating GPU %v: %w", i, err)
+		}
+		server.Devices = append(server.Devices, device)
+	}
+	server.setMockFuncs()
+	return server, nil
+}
+
+// MustBuild is like Build but panics on error.
+func (b *ServerBuilder) MustBuild() *Server {
+	server, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	return server
+}
+
+// DeviceIDs returns the PCI device ID of each GPU on the server, in index order.
+func (s *Server) DeviceIDs() []types.DeviceID {
+	var ids []types.DeviceID
+	for _, d := range s.Devices {
+		info, _ := d.GetPciInfo()
+		ids = append(ids, types.DeviceID(info.PciDeviceId))
+	}
+	return ids
+}
+
+func newDevice(index int, gpu GPU) (*Device, error) {
+	device := dgxa100.NewDevice(index)
+	device.Name = gpu.Model.Name
+	device.Architecture = gpu.Model.Architecture
+	device.MemoryInfo = nvml.Memory{Total: gpu.Model.MemoryMB * 1024 * 1024}
+
+	pciDeviceID := uint32(types.NewDeviceID(gpu.Model.DeviceID, nvidiaVendorID))
+	device.GetPciInfoFunc = func() (nvml.PciInfo, nvml.Return) {
+		info := nvml.PciInfo{
+			Bus:         uint32(index),
+			PciDeviceId: pciDeviceID,
+		}
+		return info, nvml.SUCCESS
+	}
+
+	getGpuInstanceProfileInfo := device.GetGpuInstanceProfileInfoFunc
+	device.GetGpuInstanceProfileInfoFunc = func(giProfileId int) (nvml.GpuInstanceProfileInfo, nvml.Return) {
+		info, ret := getGpuInstanceProfileInfo(giProfileId)
+		if ret != nvml.SUCCESS {
+			return info, ret
+		}
+		info.MemorySizeMB = info.MemorySizeMB * gpu.Model.MemoryMB / A100_SXM4_40GB.MemoryMB
+		return info, nvml.SUCCESS
+	}
+
+	virtualizationMode := nvml.GPU_VIRTUALIZATION_MODE_NONE
+	if gpu.VGPUGuest {
+		virtualizationMode = nvml.GPU_VIRTUALIZATION_MODE_VGPU
+	}
+	device.GetVirtualizationModeFunc = func() (nvml.GpuVirtualizationMode, nvml.Return) {
+		return virtualizationMode, nvml.SUCCESS
+	}
+
+	if gpu.MigEnabled {
+		device.MigMode = nvml.DEVICE_MIG_ENABLE
+	}
+
+	for _, instance := range gpu.GpuInstances {
+		if !gpu.MigEnabled {
+			return nil, fmt.Errorf("GPU instances require MIG mode to be enabled")
+		}
+		err := createGpuInstance(device, instance)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return device, nil
+}
+
+func createGpuInstance(device nvml.Device, instance GpuInstance) error {
+	giProfileInfo, ret := device.GetGpuInstanceProfileInfo(instance.Profile)
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error getting GPU instance profile info for '%v': %v", instance.Profile, ret)
+	}
+
+	gi, ret := device.CreateGpuInstance(&giProfileInfo)
+	if ret != nvml.SUCCESS {
+		return fmt.Errorf("error creating GPU instance for '%v': %v", instance.Profile, ret)
+	}
+
+	for _, ciProfile := range instance.ComputeInstances {
+		ciProfileInfo, ret := gi.GetComputeInstanceProfileInfo(ciProfile, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error getting compute instance profile info for '%v': %v", ciProfile, ret)
+		}
+
+		_, ret = gi.CreateComputeInstance(&ciProfileInfo)
+		if ret != nvml.SUCCESS {
+			return fmt.Errorf("error creating compute instance for '%v': %v", ciProfile, ret)
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) setMockFuncs() {
+	s.ExtensionsFunc = func() nvml.ExtendedInterface {
+		return s
+	}
+
+	s.LookupSymbolFunc = func(symbol string) error {
+		return nil
+	}
+
+	s.InitFunc = func() nvml.Return {
+		return nvml.SUCCESS
+	}
+
+	s.ShutdownFunc = func() nvml.Return {
+		return nvml.SUCCESS
+	}
+
+	s.SystemGetDriverVersionFunc = func() (string, nvml.Return) {
+		return s.DriverVersion, nvml.SUCCESS
+	}
+
+	s.SystemGetNVMLVersionFunc = func() (string, nvml.Return) {
+		return s.NvmlVersion, nvml.SUCCESS
+	}
+
+	s.SystemGetCudaDriverVersionFunc = func() (int, nvml.Return) {
+		return s.CudaDriverVersion, nvml.SUCCESS
+	}
+
+	s.DeviceGetCountFunc = func() (int, nvml.Return) {
+		return len(s.Devices), nvml.SUCCESS
+	}
+
+	s.DeviceGetHandleByIndexFunc = func(index int) (nvml.Device, nvml.Return) {
+		if index < 0 || index >= len(s.Devices) {
+			return nil, nvml.ERROR_INVALID_ARGUMENT
+		}
+		return s.Devices[index], nvml.SUCCESS
+	}
+
+	s.DeviceGetHandleByUUIDFunc = func(uuid string) (nvml.Device, nvml.Return) {
+		for _, d := range s.Devices {
+			if u, _ := d.GetUUID(); u == uuid {
+				return d, nvml.SUCCESS
+			}
+		}
+		return nil, nvml.ERROR_INVALID_ARGUMENT
+	}
+
+	s.DeviceGetHandleByPciBusIdFunc = func(busID string) (nvml.Device, nvml.Return) {
+		for _, d := range s.Devices {
+			if info, _ := d.GetPciInfo(); pciBusID(info) == busID {
+				return d, nvml.SUCCESS
+			}
+		}
+		return nil, nvml.ERROR_INVALID_ARGUMENT
+	}
+}
+
+func pciBusID(info nvml.PciInfo) string {
+	return fmt.Sprintf("%04x:%02x:%02x.0", info.Domain, info.Bus, info.Device)
+}
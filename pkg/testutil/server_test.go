@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package testutil
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+
+	"github.com/NVIDIA/mig-parted/pkg/types"
+)
+
+func TestServerBuilder(t *testing.T) {
+	server, err := NewServerBuilder().
+		WithDriverVersion("535.104.05").
+		WithGPUs(2, GPU{Model: A100_SXM4_40GB}).
+		WithGPU(GPU{
+			Model:      H100_SXM5_80GB,
+			MigEnabled: true,
+			GpuInstances: []GpuInstance{
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_3_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE},
+				},
+				{
+					Profile:          nvml.GPU_INSTANCE_PROFILE_1_SLICE,
+					ComputeInstances: []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE},
+				},
+			},
+		}).
+		Build()
+	require.Nil(t, err, "Unexpected failure from Build")
+
+	version, ret := server.SystemGetDriverVersion()
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, "535.104.05", version)
+
+	count, ret := server.DeviceGetCount()
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, 3, count)
+
+	require.Equal(t, []types.DeviceID{0x20B010DE, 0x20B010DE, 0x233010DE}, server.DeviceIDs())
+
+	device, ret := server.DeviceGetHandleByIndex(2)
+	require.Equal(t, nvml.SUCCESS, ret)
+
+	name, _ := device.GetName()
+	require.Equal(t, H100_SXM5_80GB.Name, name)
+
+	memory, _ := device.GetMemoryInfo()
+	require.Equal(t, uint64(80*1024*1024*1024), memory.Total)
+
+	info, ret := device.GetGpuInstanceProfileInfo(nvml.GPU_INSTANCE_PROFILE_7_SLICE)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, uint64(2*40192), info.MemorySizeMB)
+
+	mode, _, _ := device.GetMigMode()
+	require.Equal(t, nvml.DEVICE_MIG_ENABLE, mode)
+
+	uuid, _ := device.GetUUID()
+	byUUID, ret := server.DeviceGetHandleByUUID(uuid)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, device, byUUID)
+
+	byBusID, ret := server.DeviceGetHandleByPciBusId("0000:02:00.0")
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Equal(t, device, byBusID)
+
+	giProfileInfo, _ := device.GetGpuInstanceProfileInfo(nvml.GPU_INSTANCE_PROFILE_3_SLICE)
+	gis, ret := device.GetGpuInstances(&giProfileInfo)
+	require.Equal(t, nvml.SUCCESS, ret)
+	require.Len(t, gis, 1)
+	for _, ciProfile := range []int{nvml.COMPUTE_INSTANCE_PROFILE_1_SLICE, nvml.COMPUTE_INSTANCE_PROFILE_2_SLICE} {
+		ciProfileInfo, ret := gis[0].GetComputeInstanceProfileInfo(ciProfile, nvml.COMPUTE_INSTANCE_ENGINE_PROFILE_SHARED)
+		require.Equal(t, nvml.SUCCESS, ret)
+		cis, ret := gis[0].GetComputeInstances(&ciProfileInfo)
+		require.Equal(t, nvml.SUCCESS, ret)
+		require.Len(t, cis, 1)
+	}
+
+	other, _ := server.DeviceGetHandleByIndex(0)
+	mode, _, _ = other.GetMigMode()
+	require.Equal(t, nvml.DEVICE_MIG_DISABLE, mode)
+}
+
+func TestServerBuilderError(t *testing.T) {
+	_, err := NewServerBuilder().
+		WithGPU(GPU{
+			Model:        A100_SXM4_40GB,
+			GpuInstances: []GpuInstance{{Profile: nvml.GPU_INSTANCE_PROFILE_7_SLICE}},
+		}).
+		Build()
+	require.NotNil(t, err, "Unexpected success from Build with MIG disabled")
+
+	_, err = NewServerBuilder().
+		WithGPU(GPU{
+			Model:        A100_SXM4_40GB,
+			MigEnabled:   true,
+			GpuInstances: []GpuInstance{{Profile: nvml.GPU_INSTANCE_PROFILE_COUNT}},
+		}).
+		Build()
+	require.NotNil(t, err, "Unexpected success from Build with invalid profile")
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package textfile writes metrics in the Prometheus text format to files
+// picked up by the textfile collector of node_exporter, so that nodes
+// without a metrics endpoint (e.g. those using the systemd service) can be
+// monitored without opening any new port.
+package textfile
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Types of metrics.
+const (
+	Gauge   = "gauge"
+	Counter = "counter"
+)
+
+// Metric is a single sample of a metric, along with its metadata.
+type Metric struct {
+	Name   string
+	Help   string
+	Type   string
+	Labels map[string]string
+	Value  float64
+}
+
+// Format writes 'metrics' to 'w' in the Prometheus text format. Each metric
+// is written with its '# HELP' and '# TYPE' lines, and its labels sorted by
+// name.
+func Format(w io.Writer, metrics []Metric) error {
+	for _, m := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s%s %s\n",
+			m.Name, escapeHelp(m.Help), m.Name, m.Type, m.Name, formatLabels(m.Labels), strconv.FormatFloat(m.Value, 'f', -1, 64))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write atomically replaces the file at 'path' with 'metrics', creating its
+// parent directory if needed, so that the textfile collector never reads a
+// partially written file. The file name must end in '.prom' for the
+// collector to pick it up.
+func Write(path string, metrics []Metric) error {
+	var b bytes.Buffer
+	err := Format(&b, metrics)
+	if err != nil {
+		return fmt.Errorf("error formatting metrics: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return fmt.Errorf("error creating textfile directory: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	err = os.WriteFile(tmp, b.Bytes(), 0644)
+	if err != nil {
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+
+	err = os.Rename(tmp, path)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing metrics: %w", err)
+	}
+
+	return nil
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var names []string
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var pairs []string
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", name, escapeLabelValue(labels[name])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var (
+	helpEscaper       = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return labelValueEscaper.Replace(s)
+}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package textfile
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormat(t *testing.T) {
+	testCases := []struct {
+		description string
+		metrics     []Metric
+		expected    string
+	}{
+		{
+			"No metrics",
+			nil,
+			"",
+		},
+		{
+			"Gauge without labels",
+			[]Metric{{Name: "up", Help: "Whether it is up.", Type: Gauge, Value: 1}},
+			"# HELP up Whether it is up.\n# TYPE up gauge\nup 1\n",
+		},
+		{
+			"Sorted and escaped labels",
+			[]Metric{{Name: "duration_seconds", Help: "A\\B", Type: Gauge, Labels: map[string]string{"b": "x\"y\nz", "a": `c:\d`}, Value: 1.5}},
+			"# HELP duration_seconds A\\\\B\n# TYPE duration_seconds gauge\nduration_seconds{a=\"c:\\\\d\",b=\"x\\\"y\\nz\"} 1.5\n",
+		},
+		{
+			"Large value",
+			[]Metric{{Name: "timestamp_seconds", Help: "Time.", Type: Gauge, Value: 1704164645}},
+			"# HELP timestamp_seconds Time.\n# TYPE timestamp_seconds gauge\ntimestamp_seconds 1704164645\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			var b bytes.Buffer
+			require.Nil(t, Format(&b, tc.metrics))
+			require.Equal(t, tc.expected, b.String())
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "textfile", "test.prom")
+
+	require.Nil(t, Write(path, []Metric{{Name: "up", Help: "Up.", Type: Gauge, Value: 0}}))
+	require.Nil(t, Write(path, []Metric{{Name: "up", Help: "Up.", Type: Gauge, Value: 1}}))
+
+	content, err := os.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, "# HELP up Up.\n# TYPE up gauge\nup 1\n", string(content))
+	require.NoFileExists(t, path+".tmp")
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+)
+
+// CCMode is the confidential computing (CC) mode of a GPU.
+type CCMode string
+
+// Constants representing the supported CC modes.
+const (
+	// CCOff disables confidential computing.
+	CCOff CCMode = "off"
+	// CCOn enables confidential computing.
+	CCOn CCMode = "on"
+	// CCDevTools enables confidential computing with the GPU's debugging
+	// and profiling features left available, for development only.
+	CCDevTools CCMode = "devtools"
+)
+
+// AssertValid checks that 'm' is one of the supported CC modes.
+func (m CCMode) AssertValid() error {
+	switch m {
+	case CCOff, CCOn, CCDevTools:
+		return nil
+	}
+	return fmt.Errorf("unsupported CC mode '%v' (must be one of '%v', '%v' or '%v')", string(m), CCOn, CCOff, CCDevTools)
+}
+
+// SupportsMig checks if MIG mode can be enabled on a GPU in CC mode 'm'.
+// MIG is only available with CC mode off, as a GPU in CC (or CC devtools)
+// mode is handed to a single confidential VM as a whole.
+func (m CCMode) SupportsMig() bool {
+	return m == CCOff
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+)
+
+// ComputeInstanceLayer maps a GPU instance profile (e.g. "2g.20gb") to the
+// compute instances (e.g. {"1c.2g.20gb": 2}) that every GPU instance of that
+// profile should be split into. It allows the compute instances of a GPU to
+// be managed separately from the GPU instances that contain them.
+type ComputeInstanceLayer map[string]MigConfig
+
+// AssertValidFormat checks to ensure that all of the profiles making up a 'ComputeInstanceLayer' are of a valid format.
+func (l ComputeInstanceLayer) AssertValidFormat() error {
+	for gi, cis := range l {
+		err := AssertValidMigProfileFormat(gi)
+		if err != nil {
+			return fmt.Errorf("invalid format for '%v': %v", gi, err)
+		}
+		err = cis.AssertValidFormat()
+		if err != nil {
+			return fmt.Errorf("invalid compute instances for '%v': %v", gi, err)
+		}
+		if len(cis) == 0 {
+			return fmt.Errorf("no compute instances for '%v'", gi)
+		}
+	}
+	return nil
+}
+
+// AssertStrictFormat checks that all of the profiles making up a
+// 'ComputeInstanceLayer' are written in their canonical form (see
+// AssertStrictMigProfileFormat).
+func (l ComputeInstanceLayer) AssertStrictFormat() error {
+	for gi, cis := range l {
+		err := AssertStrictMigProfileFormat(gi)
+		if err != nil {
+			return err
+		}
+		err = cis.AssertStrictFormat()
+		if err != nil {
+			return fmt.Errorf("invalid compute instances for '%v': %w", gi, err)
+		}
+	}
+	return nil
+}
+
+// Apply returns the 'MigConfig' that results from splitting each GPU
+// instance in 'config' into the compute instances listed for its profile.
+// Profiles without an entry in the 'ComputeInstanceLayer' are left as is.
+func (l ComputeInstanceLayer) Apply(config MigConfig) MigConfig {
+	if len(l) == 0 {
+		return config
+	}
+	result := make(MigConfig)
+	for profile, count := range config {
+		if count == 0 {
+			continue
+		}
+		cis, exists := l[profile]
+		if !exists {
+			result[profile] += count
+			continue
+		}
+		for ci, n := range cis {
+			if n == 0 {
+				continue
+			}
+			result[ci] += count * n
+		}
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// GpuModels maps the names of MIG capable GPU models to the device IDs they
+// may have.
+var GpuModels = map[string][]DeviceID{
+	"A100-SXM4-40GB": {0x20B010DE},
+	"A100-PCIE-40GB": {0x20B110DE, 0x20F110DE},
+	"A100-SXM4-80GB": {0x20B210DE},
+	"A100-PCIE-80GB": {0x20B510DE},
+	"A30-24GB":       {0x20B710DE},
+	"A800-SXM4-80GB": {0x20F310DE},
+	"A800-PCIE-80GB": {0x20F510DE},
+	"A800-PCIE-40GB": {0x20F610DE},
+	"PG506-96GB":     {0x20B610DE},
+	"H100-SXM5-80GB": {0x233010DE},
+	"H100-PCIE-80GB": {0x233110DE},
+	"H100-NVL-94GB":  {0x232110DE},
+	"H800-PCIE-80GB": {0x232210DE},
+}
+
+// GetGpuModelDeviceIDs returns the device IDs of a GPU model, given either its
+// name in 'GpuModels' (case insensitive) or a single device ID.
+func GetGpuModelDeviceIDs(model string) ([]DeviceID, error) {
+	for name, ids := range GpuModels {
+		if strings.EqualFold(name, model) {
+			return ids, nil
+		}
+	}
+	if strings.HasPrefix(model, "0x") || strings.HasPrefix(model, "0X") {
+		id, err := NewDeviceIDFromString(model)
+		if err != nil {
+			return nil, err
+		}
+		return []DeviceID{id}, nil
+	}
+	return nil, fmt.Errorf("unknown GPU model '%v' (known models: %v)", model, strings.Join(GpuModelNames(), ", "))
+}
+
+// GpuModelNames returns the names of the GPU models in 'GpuModels', in order.
+func GpuModelNames() []string {
+	var names []string
+	for name := range GpuModels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -17,9 +17,13 @@
 package types
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"sort"
 	"strings"
+
+	yaml "gopkg.in/yaml.v2"
 )
 
 // MigConfig holds a map of strings representing a MigProfile to a count of that profile type.
@@ -47,7 +51,7 @@ func (m MigConfig) AssertValidFormat() error {
 			return fmt.Errorf("invalid format for '%v': %v", k, err)
 		}
 		if v < 0 {
-			return fmt.Errorf("invalid count for '%v': %v", v, err)
+			return fmt.Errorf("invalid count for '%v': %v", k, v)
 		}
 	}
 	for _, v := range m {
@@ -58,6 +62,18 @@ func (m MigConfig) AssertValidFormat() error {
 	return fmt.Errorf("all counts for all MigProfiles are 0")
 }
 
+// AssertStrictFormat checks that all of the 'MigProfile's making up a 'MigConfig'
+// are written in their canonical form (see AssertStrictMigProfileFormat).
+func (m MigConfig) AssertStrictFormat() error {
+	for k := range m {
+		err := AssertStrictMigProfileFormat(k)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // IsSubsetOf checks if the provided 'MigConfig' is a subset of the originating 'MigConfig'.
 func (m MigConfig) IsSubsetOf(config MigConfig) bool {
 	for k, v := range m {
@@ -96,8 +112,64 @@ func (m MigConfig) Equals(config MigConfig) bool {
 	return true
 }
 
+// Profiles returns the MIG profiles in 'm' in the canonical order of MIG
+// profiles (see 'MigProfile.Compare').
+func (m MigConfig) Profiles() []string {
+	var profiles []string
+	for k := range m {
+		profiles = append(profiles, k)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return CompareMigProfileNames(profiles[i], profiles[j]) < 0
+	})
+	return profiles
+}
+
+// String formats 'm' like a map, but with its MIG profiles in their canonical
+// order rather than sorted by name, so that '1g.10gb' follows '1g.5gb'.
+func (m MigConfig) String() string {
+	var entries []string
+	for _, k := range m.Profiles() {
+		entries = append(entries, fmt.Sprintf("%v:%v", k, m[k]))
+	}
+	return "map[" + strings.Join(entries, " ") + "]"
+}
+
+// MarshalJSON writes 'm' with its MIG profiles in their canonical order.
+func (m MigConfig) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range m.Profiles() {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		fmt.Fprintf(&buf, ":%d", m[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MarshalYAML writes 'm' with its MIG profiles in their canonical order.
+func (m MigConfig) MarshalYAML() (interface{}, error) {
+	slice := yaml.MapSlice{}
+	for _, k := range m.Profiles() {
+		slice = append(slice, yaml.MapItem{Key: k, Value: m[k]})
+	}
+	return slice, nil
+}
+
 // Flatten converts a 'MigConfig' into a slice of 'MigProfile's.
 // Duplicate 'MigProfile's will exist in this slice for each profile represented in the 'MigConfig'.
+// The slice is in the reverse of the canonical order of MIG profiles, i.e.
+// largest first, which is also the order in which permutations of it are tried.
 func (m MigConfig) Flatten() []*MigProfile {
 	var mps []*MigProfile
 	for k, v := range m {
@@ -115,20 +187,8 @@ func (m MigConfig) Flatten() []*MigProfile {
 			mps = append(mps, mp)
 		}
 	}
-	sort.Slice(mps, func(i, j int) bool {
-		if mps[j].G > mps[i].G {
-			return false
-		}
-		if mps[j].G < mps[i].G {
-			return true
-		}
-		if mps[j].C > mps[i].C {
-			return false
-		}
-		if mps[j].C < mps[i].C {
-			return true
-		}
-		return strings.Join(mps[j].Attributes, ",") < strings.Join(mps[i].Attributes, ",")
+	sort.SliceStable(mps, func(i, j int) bool {
+		return mps[j].Less(*mps[i])
 	})
 	return mps
 }
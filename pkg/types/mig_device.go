@@ -0,0 +1,68 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+// MigDevice describes a single MIG device that has been created on a GPU.
+type MigDevice struct {
+	Profile                  string                        `json:"profile"`
+	GpuInstanceID            uint32                        `json:"gpu-instance-id"`
+	GpuInstancePlacement     nvml.GpuInstancePlacement     `json:"gpu-instance-placement"`
+	ComputeInstanceID        uint32                        `json:"compute-instance-id"`
+	ComputeInstancePlacement nvml.ComputeInstancePlacement `json:"compute-instance-placement"`
+}
+
+// OrderingKey returns a key identifying where 'd' sits on its GPU, as the
+// start slices of its GPU instance and compute instance placements (e.g.
+// '4:0'). Unlike the instance IDs, which are handed out by the driver in the
+// order instances happen to be created, placements only depend on the MIG
+// config applied, so the key stays the same across reconfigurations that
+// recreate the device where it was.
+func (d MigDevice) OrderingKey() string {
+	return fmt.Sprintf("%d:%d", d.GpuInstancePlacement.Start, d.ComputeInstancePlacement.Start)
+}
+
+// SortMigDevices sorts 'devices' of a single GPU by where they are placed on
+// it (i.e. by OrderingKey), falling back to their instance IDs for devices
+// whose placement is unknown.
+func SortMigDevices(devices []MigDevice) {
+	sort.SliceStable(devices, func(i, j int) bool {
+		a, b := devices[i], devices[j]
+		switch {
+		case a.GpuInstancePlacement.Start != b.GpuInstancePlacement.Start:
+			return a.GpuInstancePlacement.Start < b.GpuInstancePlacement.Start
+		case a.ComputeInstancePlacement.Start != b.ComputeInstancePlacement.Start:
+			return a.ComputeInstancePlacement.Start < b.ComputeInstancePlacement.Start
+		case a.GpuInstanceID != b.GpuInstanceID:
+			return a.GpuInstanceID < b.GpuInstanceID
+		}
+		return a.ComputeInstanceID < b.ComputeInstanceID
+	})
+}
+
+// GpuInstance describes a single GPU instance that exists on a GPU.
+type GpuInstance struct {
+	Profile   string                    `json:"profile"`
+	ID        uint32                    `json:"id"`
+	Placement nvml.GpuInstancePlacement `json:"placement"`
+}
@@ -0,0 +1,74 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSortMigDevices(t *testing.T) {
+	device := func(giID, giStart, ciID, ciStart uint32) MigDevice {
+		return MigDevice{
+			GpuInstanceID:            giID,
+			GpuInstancePlacement:     nvml.GpuInstancePlacement{Start: giStart},
+			ComputeInstanceID:        ciID,
+			ComputeInstancePlacement: nvml.ComputeInstancePlacement{Start: ciStart},
+		}
+	}
+
+	testCases := []struct {
+		description string
+		devices     []MigDevice
+		expected    []MigDevice
+	}{
+		{
+			"By GPU instance placement rather than ID",
+			[]MigDevice{device(1, 4, 0, 0), device(7, 0, 0, 0), device(2, 6, 0, 0)},
+			[]MigDevice{device(7, 0, 0, 0), device(1, 4, 0, 0), device(2, 6, 0, 0)},
+		},
+		{
+			"By compute instance placement within a GPU instance",
+			[]MigDevice{device(1, 0, 0, 2), device(1, 0, 1, 0), device(2, 4, 0, 0)},
+			[]MigDevice{device(1, 0, 1, 0), device(1, 0, 0, 2), device(2, 4, 0, 0)},
+		},
+		{
+			"By instance IDs without placements",
+			[]MigDevice{device(3, 0, 1, 0), device(3, 0, 0, 0), device(2, 0, 0, 0)},
+			[]MigDevice{device(2, 0, 0, 0), device(3, 0, 0, 0), device(3, 0, 1, 0)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			SortMigDevices(tc.devices)
+			require.Equal(t, tc.expected, tc.devices)
+		})
+	}
+}
+
+func TestMigDeviceOrderingKey(t *testing.T) {
+	d := MigDevice{
+		GpuInstanceID:            9,
+		GpuInstancePlacement:     nvml.GpuInstancePlacement{Start: 4, Size: 2},
+		ComputeInstanceID:        1,
+		ComputeInstancePlacement: nvml.ComputeInstancePlacement{Start: 1, Size: 1},
+	}
+	require.Equal(t, "4:1", d.OrderingKey())
+}
@@ -17,6 +17,9 @@
 package types
 
 import (
+	"sort"
+	"strings"
+
 	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
 )
 
@@ -39,8 +42,17 @@ func NewMigProfile(giProfileID, ciProfileID, ciEngProfileID int, migMemorySizeMB
 	return &MigProfile{mp.GetInfo()}, nil
 }
 
-// AssertValidMigProfileFormat checks if the string is in the proper format to represent a MIG profile
+// AssertValidMigProfileFormat checks if the string is in the proper format to represent a MIG profile,
+// either by name, by its numeric profile ID (see 'MigProfileID') or as a fraction of a GPU (see
+// 'FractionalMigProfiles').
 func AssertValidMigProfileFormat(profile string) error {
+	if IsFractionalMigProfile(profile) {
+		return nil
+	}
+	if IsMigProfileID(profile) {
+		_, err := ParseMigProfileID(profile)
+		return err
+	}
 	return nvdevAssertValidMigProfileFormat(profile)
 }
 
@@ -68,3 +80,60 @@ func (m MigProfile) HasAttribute(attr string) bool {
 	}
 	return false
 }
+
+// Compare orders 'm' relative to 'other' in the canonical order of MIG
+// profiles: by GPU instance slices, then memory, then compute instance slices,
+// then attributes. It returns -1 if 'm' sorts first, 1 if 'other' does, and 0
+// if neither does.
+func (m MigProfile) Compare(other MigProfile) int {
+	switch {
+	case m.G != other.G:
+		return compareInts(m.G, other.G)
+	case m.GB != other.GB:
+		return compareInts(m.GB, other.GB)
+	case m.C != other.C:
+		return compareInts(m.C, other.C)
+	}
+	return strings.Compare(strings.Join(m.Attributes, ","), strings.Join(other.Attributes, ","))
+}
+
+// Less checks if 'm' sorts before 'other' in the canonical order of MIG profiles.
+func (m MigProfile) Less(other MigProfile) bool {
+	return m.Compare(other) < 0
+}
+
+// SortMigProfiles sorts 'mps' in the canonical order of MIG profiles.
+func SortMigProfiles(mps []*MigProfile) {
+	sort.SliceStable(mps, func(i, j int) bool {
+		return mps[i].Less(*mps[j])
+	})
+}
+
+// CompareMigProfileNames orders two MIG profile names like Compare orders the
+// profiles they name. Names that cannot be parsed sort after those that can,
+// and amongst themselves by name.
+func CompareMigProfileNames(a, b string) int {
+	pa, erra := ParseMigProfile(a)
+	pb, errb := ParseMigProfile(b)
+	switch {
+	case erra == nil && errb == nil:
+		if c := pa.Compare(*pb); c != 0 {
+			return c
+		}
+	case erra == nil:
+		return -1
+	case errb == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	}
+	if a > b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FractionalMigProfiles maps the abstract MIG profiles that ask for a
+// fraction of a GPU, rather than for a profile by name, to the number of
+// such fractions that make up the GPU.
+var FractionalMigProfiles = map[string]int{
+	"half":    2,
+	"quarter": 4,
+}
+
+// IsFractionalMigProfile checks if 'profile' asks for a fraction of a GPU
+// rather than for a MIG profile by name.
+func IsFractionalMigProfile(profile string) bool {
+	_, exists := FractionalMigProfiles[profile]
+	return exists
+}
+
+// ResolveFractionalMigProfile returns the MIG profile among 'mps' (those
+// supported by a GPU) nearest to the fraction of the GPU asked for by
+// 'profile'. Memory matters most: the profile whose memory is nearest to
+// the fraction of the memory of the GPU is chosen, then the one whose slice
+// count is nearest to the fraction of its slices, then the smaller one (e.g.
+// "half" is a 3g.20gb rather than a 4g.20gb on an A100-SXM4-40GB, so that two
+// of them fit, and "quarter" a 2g.10gb rather than a 1g.10gb). Only profiles without attributes and with a compute instance
+// spanning their whole GPU instance are considered. The size of the GPU is
+// taken from its largest profile.
+func ResolveFractionalMigProfile(profile string, mps []*MigProfile) (*MigProfile, error) {
+	n, exists := FractionalMigProfiles[profile]
+	if !exists {
+		return nil, fmt.Errorf("unknown fractional MIG profile '%v'", profile)
+	}
+
+	var candidates []*MigProfile
+	var slices, memory int
+	for _, mp := range mps {
+		if len(mp.Attributes) > 0 || mp.C != mp.G {
+			continue
+		}
+		candidates = append(candidates, mp)
+		slices = max(slices, mp.G)
+		memory = max(memory, mp.GB)
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no MIG profile to resolve '%v' to on this GPU", profile)
+	}
+
+	// Distances are scaled by 'n' to keep them integral.
+	distance := func(value, total int) int {
+		return abs(value*n - total)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if da, db := distance(a.GB, memory), distance(b.GB, memory); da != db {
+			return da < db
+		}
+		if da, db := distance(a.G, slices), distance(b.G, slices); da != db {
+			return da < db
+		}
+		return a.G < b.G
+	})
+
+	return candidates[0], nil
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// ResolveFractionalProfiles returns a copy of 'm' with every fractional MIG
+// profile replaced by the name of the profile among 'mps' it resolves to.
+func (m MigConfig) ResolveFractionalProfiles(mps []*MigProfile) (MigConfig, error) {
+	resolved := make(MigConfig)
+	for profile, count := range m {
+		if IsFractionalMigProfile(profile) {
+			mp, err := ResolveFractionalMigProfile(profile, mps)
+			if err != nil {
+				return nil, err
+			}
+			profile = mp.String()
+		}
+		resolved[profile] += count
+	}
+	return resolved, nil
+}
+
+// HasFractionalProfiles checks if any MIG profile in 'm' asks for a fraction
+// of a GPU.
+func (m MigConfig) HasFractionalProfiles() bool {
+	for profile := range m {
+		if IsFractionalMigProfile(profile) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,88 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveFractionalMigProfile(t *testing.T) {
+	a100 := []*MigProfile{
+		{nvdev.MigProfileInfo{C: 1, G: 1, GB: 5}},
+		{nvdev.MigProfileInfo{C: 1, G: 1, GB: 5, Attributes: []string{AttributeMediaExtensions}}},
+		{nvdev.MigProfileInfo{C: 1, G: 1, GB: 10}},
+		{nvdev.MigProfileInfo{C: 2, G: 2, GB: 10}},
+		{nvdev.MigProfileInfo{C: 3, G: 3, GB: 20}},
+		{nvdev.MigProfileInfo{C: 1, G: 3, GB: 20}},
+		{nvdev.MigProfileInfo{C: 4, G: 4, GB: 20}},
+		{nvdev.MigProfileInfo{C: 7, G: 7, GB: 40}},
+	}
+	a30 := []*MigProfile{
+		{nvdev.MigProfileInfo{C: 1, G: 1, GB: 6}},
+		{nvdev.MigProfileInfo{C: 2, G: 2, GB: 12}},
+		{nvdev.MigProfileInfo{C: 4, G: 4, GB: 24}},
+	}
+
+	testCases := []struct {
+		description string
+		profile     string
+		mps         []*MigProfile
+		expected    string
+		err         bool
+	}{
+		{"Half of an A100", "half", a100, "3g.20gb", false},
+		{"Quarter of an A100", "quarter", a100, "2g.10gb", false},
+		{"Half of an A30", "half", a30, "2g.12gb", false},
+		{"Quarter of an A30", "quarter", a30, "1g.6gb", false},
+		{"Unknown fraction", "third", a100, "", true},
+		{"No profiles", "half", nil, "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.description, func(t *testing.T) {
+			mp, err := ResolveFractionalMigProfile(tc.profile, tc.mps)
+			if tc.err {
+				require.NotNil(t, err, "Unexpected success from ResolveFractionalMigProfile")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from ResolveFractionalMigProfile")
+			require.Equal(t, tc.expected, mp.String())
+		})
+	}
+}
+
+func TestResolveFractionalProfiles(t *testing.T) {
+	mps := []*MigProfile{
+		{nvdev.MigProfileInfo{C: 1, G: 1, GB: 5}},
+		{nvdev.MigProfileInfo{C: 2, G: 2, GB: 10}},
+		{nvdev.MigProfileInfo{C: 3, G: 3, GB: 20}},
+		{nvdev.MigProfileInfo{C: 7, G: 7, GB: 40}},
+	}
+
+	config := MigConfig{"half": 1, "quarter": 1, "2g.10gb": 1, "1g.5gb": 1}
+	require.True(t, config.HasFractionalProfiles())
+	require.Nil(t, AssertValidMigProfileFormat("half"))
+	require.Nil(t, AssertStrictMigProfileFormat("quarter"))
+
+	resolved, err := config.ResolveFractionalProfiles(mps)
+	require.Nil(t, err)
+	require.Equal(t, MigConfig{"3g.20gb": 1, "2g.10gb": 2, "1g.5gb": 1}, resolved)
+	require.False(t, resolved.HasFractionalProfiles())
+}
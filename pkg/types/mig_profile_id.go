@@ -0,0 +1,169 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FullComputeInstance is the compute instance profile ID of a 'MigProfileID'
+// that identifies a compute instance spanning its whole GPU instance.
+const FullComputeInstance = -1
+
+// MigProfileID identifies a MIG profile by the numeric profile IDs reported
+// by 'nvidia-smi mig -lgip' (for the GPU instance) and 'nvidia-smi mig -lcip'
+// (for the compute instance). It is written as "<gi>" (e.g. "19") for a
+// compute instance spanning the whole GPU instance, or as "<gi>/<ci>" (e.g.
+// "9/1") otherwise. The same ID can refer to different profiles on different
+// GPU models, so it can only be translated with the 'MigProfileIDs' of a GPU.
+type MigProfileID struct {
+	GpuInstance     int
+	ComputeInstance int
+}
+
+// IsMigProfileID checks if 'profile' references a MIG profile by its numeric
+// profile IDs rather than by its name.
+func IsMigProfileID(profile string) bool {
+	return profile != "" && strings.Trim(profile, "0123456789/") == ""
+}
+
+// ParseMigProfileID parses a 'MigProfileID' from its string representation.
+func ParseMigProfileID(profile string) (*MigProfileID, error) {
+	gi, ci, found := strings.Cut(profile, "/")
+
+	id := MigProfileID{ComputeInstance: FullComputeInstance}
+	var err error
+	id.GpuInstance, err = parseProfileIDNumber(gi)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GPU instance profile ID in '%v': %v", profile, err)
+	}
+	if found {
+		id.ComputeInstance, err = parseProfileIDNumber(ci)
+		if err != nil {
+			return nil, fmt.Errorf("invalid compute instance profile ID in '%v': %v", profile, err)
+		}
+	}
+	return &id, nil
+}
+
+func parseProfileIDNumber(s string) (int, error) {
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, fmt.Errorf("not a number: '%v'", s)
+	}
+	return strconv.Atoi(s)
+}
+
+// String returns the string representation of a 'MigProfileID'.
+func (id MigProfileID) String() string {
+	if id.ComputeInstance == FullComputeInstance {
+		return fmt.Sprintf("%d", id.GpuInstance)
+	}
+	return fmt.Sprintf("%d/%d", id.GpuInstance, id.ComputeInstance)
+}
+
+// MigProfileIDs maps the numeric profile IDs of the MIG profiles supported by
+// a GPU to those profiles. A profile whose compute instance spans its whole
+// GPU instance is listed under both of its IDs, i.e. "<gi>" and "<gi>/<ci>".
+type MigProfileIDs map[MigProfileID]*MigProfile
+
+// Add records that 'gi' and 'ci' are the GPU instance and compute instance
+// profile IDs of 'mp'.
+func (ids MigProfileIDs) Add(gi int, ci int, mp *MigProfile) {
+	ids[MigProfileID{GpuInstance: gi, ComputeInstance: ci}] = mp
+	if mp.C == mp.G {
+		ids[MigProfileID{GpuInstance: gi, ComputeInstance: FullComputeInstance}] = mp
+	}
+}
+
+// Name translates 'profile' into the name of the MIG profile it references.
+// Profiles that are already referenced by name are returned unchanged.
+func (ids MigProfileIDs) Name(profile string) (string, error) {
+	if !IsMigProfileID(profile) {
+		return profile, nil
+	}
+	id, err := ParseMigProfileID(profile)
+	if err != nil {
+		return "", err
+	}
+	mp, exists := ids[*id]
+	if !exists {
+		return "", fmt.Errorf("no MIG profile with ID '%v' on this GPU", id)
+	}
+	return mp.String(), nil
+}
+
+// ID translates the name of a MIG profile into its numeric profile ID, using
+// the shorter "<gi>" form where possible.
+func (ids MigProfileIDs) ID(profile string) (*MigProfileID, error) {
+	var matches []MigProfileID
+	for id, mp := range ids {
+		if mp.String() == profile {
+			matches = append(matches, id)
+		}
+	}
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("no MIG profile named '%v' on this GPU", profile)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].ComputeInstance < matches[j].ComputeInstance
+	})
+	return &matches[0], nil
+}
+
+// Profiles returns the distinct MIG profiles in 'ids', in the canonical order
+// of MIG profiles.
+func (ids MigProfileIDs) Profiles() []*MigProfile {
+	seen := make(map[string]bool)
+	var mps []*MigProfile
+	for _, mp := range ids {
+		if seen[mp.String()] {
+			continue
+		}
+		seen[mp.String()] = true
+		mps = append(mps, mp)
+	}
+	SortMigProfiles(mps)
+	return mps
+}
+
+// ResolveProfileIDs returns a copy of 'm' with every MIG profile referenced by
+// its numeric profile ID replaced by its name, as translated by 'ids'.
+func (m MigConfig) ResolveProfileIDs(ids MigProfileIDs) (MigConfig, error) {
+	resolved := make(MigConfig)
+	for profile, count := range m {
+		name, err := ids.Name(profile)
+		if err != nil {
+			return nil, err
+		}
+		resolved[name] += count
+	}
+	return resolved, nil
+}
+
+// HasProfileIDs checks if any MIG profile in 'm' is referenced by its numeric
+// profile ID.
+func (m MigConfig) HasProfileIDs() bool {
+	for profile := range m {
+		if IsMigProfileID(profile) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMigProfileID(t *testing.T) {
+	testCases := []struct {
+		profile string
+		id      *MigProfileID
+		err     bool
+	}{
+		{"19", &MigProfileID{19, FullComputeInstance}, false},
+		{"0", &MigProfileID{0, FullComputeInstance}, false},
+		{"9/1", &MigProfileID{9, 1}, false},
+		{"9/", nil, true},
+		{"/1", nil, true},
+		{"9/1/0", nil, true},
+		{"1g.5gb", nil, true},
+		{"", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			id, err := ParseMigProfileID(tc.profile)
+			if tc.err {
+				require.NotNil(t, err, "Unexpected success from ParseMigProfileID")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from ParseMigProfileID")
+			require.Equal(t, tc.id, id)
+			require.Equal(t, tc.profile, id.String())
+		})
+	}
+}
+
+func TestMigProfileIDs(t *testing.T) {
+	ids := make(MigProfileIDs)
+	ids.Add(19, 0, &MigProfile{nvdev.MigProfileInfo{C: 1, G: 1, GB: 5, GIProfileID: 0, CIProfileID: 0}})
+	ids.Add(9, 2, &MigProfile{nvdev.MigProfileInfo{C: 3, G: 3, GB: 20, GIProfileID: 2, CIProfileID: 2}})
+	ids.Add(9, 0, &MigProfile{nvdev.MigProfileInfo{C: 1, G: 3, GB: 20, GIProfileID: 2, CIProfileID: 0}})
+
+	t.Run("Name", func(t *testing.T) {
+		testCases := []struct {
+			profile string
+			name    string
+			err     bool
+		}{
+			{"19", "1g.5gb", false},
+			{"19/0", "1g.5gb", false},
+			{"9", "3g.20gb", false},
+			{"9/2", "3g.20gb", false},
+			{"9/0", "1c.3g.20gb", false},
+			{"1g.5gb", "1g.5gb", false},
+			{"14", "", true},
+			{"9/1", "", true},
+		}
+		for _, tc := range testCases {
+			name, err := ids.Name(tc.profile)
+			if tc.err {
+				require.NotNil(t, err, "Unexpected success from Name(%v)", tc.profile)
+				continue
+			}
+			require.Nil(t, err, "Unexpected failure from Name(%v)", tc.profile)
+			require.Equal(t, tc.name, name)
+		}
+	})
+
+	t.Run("ID", func(t *testing.T) {
+		id, err := ids.ID("3g.20gb")
+		require.Nil(t, err)
+		require.Equal(t, "9", id.String())
+
+		id, err = ids.ID("1c.3g.20gb")
+		require.Nil(t, err)
+		require.Equal(t, "9/0", id.String())
+
+		_, err = ids.ID("7g.40gb")
+		require.NotNil(t, err)
+	})
+
+	t.Run("ResolveProfileIDs", func(t *testing.T) {
+		config := MigConfig{"19": 2, "1g.5gb": 1, "9": 1}
+		require.True(t, config.HasProfileIDs())
+
+		resolved, err := config.ResolveProfileIDs(ids)
+		require.Nil(t, err)
+		require.Equal(t, MigConfig{"1g.5gb": 3, "3g.20gb": 1}, resolved)
+		require.False(t, resolved.HasProfileIDs())
+
+		_, err = MigConfig{"14": 1}.ResolveProfileIDs(ids)
+		require.NotNil(t, err)
+	})
+}
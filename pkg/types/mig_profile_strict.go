@@ -0,0 +1,230 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+)
+
+// maxProfileNumberDigits bounds the numbers in a strictly parsed MIG profile,
+// which are never more than a few digits long on real devices.
+const maxProfileNumberDigits = 4
+
+// AssertStrictMigProfileFormat checks that 'profile' is a MIG profile written
+// in its canonical form, e.g. "1g.5gb", "1c.2g.10gb" or "1g.5gb+me". Unlike
+// AssertValidMigProfileFormat, it rejects inputs that are only accepted
+// because of lenient parsing, such as signs, leading zeros, spaces, upper case
+// units and attributes, or a compute instance count equal to the GPU instance
+// one, and points at the offending part of the input.
+func AssertStrictMigProfileFormat(profile string) error {
+	if IsFractionalMigProfile(profile) {
+		return nil
+	}
+	if IsMigProfileID(profile) {
+		id, err := ParseMigProfileID(profile)
+		if err != nil {
+			return err
+		}
+		if id.String() != profile {
+			return fmt.Errorf("profile ID '%v' is not in its canonical form '%v'", profile, id)
+		}
+		return nil
+	}
+	_, err := parseStrictMigProfile(profile)
+	return err
+}
+
+// ParseMigProfileStrict does the same as ParseMigProfile, but only accepts
+// profiles that pass AssertStrictMigProfileFormat.
+func ParseMigProfileStrict(profile string) (*MigProfile, error) {
+	err := AssertStrictMigProfileFormat(profile)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMigProfile(profile)
+}
+
+// parseStrictMigProfile parses the canonical form of a MIG profile:
+//
+//	[<c>c.]<g>g.<gb>gb[+<attribute>[,<attribute>...]]
+func parseStrictMigProfile(profile string) (*nvdev.MigProfileInfo, error) {
+	s := &profileScanner{profile: profile}
+	if profile == "" {
+		return nil, s.errorf("profile is empty")
+	}
+
+	info := &nvdev.MigProfileInfo{}
+	n, err := s.number()
+	if err != nil {
+		return nil, err
+	}
+	if s.peek() == 'c' || s.peek() == 'C' {
+		if err := s.literal("c."); err != nil {
+			return nil, err
+		}
+		info.C = n
+		if n, err = s.number(); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.literal("g."); err != nil {
+		return nil, err
+	}
+	info.G = n
+	if info.C == 0 {
+		info.C = info.G
+	} else if info.C > info.G {
+		return nil, s.errorAt(0, "compute instance count %dc exceeds GPU instance count %dg", info.C, info.G)
+	} else if info.C == info.G {
+		return nil, s.errorAt(0, "redundant compute instance count %dc, write '%dg.' instead", info.C, info.G)
+	}
+
+	gb, err := s.number()
+	if err != nil {
+		return nil, err
+	}
+	info.GB = gb
+	if err := s.literal("gb"); err != nil {
+		return nil, err
+	}
+
+	if s.done() {
+		return info, nil
+	}
+	if err := s.literal("+"); err != nil {
+		return nil, err
+	}
+	for {
+		attr, err := s.attribute()
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range info.Attributes {
+			if a == attr {
+				return nil, s.errorAt(s.pos-len(attr), "duplicate attribute '%v'", attr)
+			}
+		}
+		info.Attributes = append(info.Attributes, attr)
+		if s.done() {
+			return info, nil
+		}
+		if err := s.literal(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// profileScanner scans a MIG profile from left to right, producing errors
+// that point at the current position in it.
+type profileScanner struct {
+	profile string
+	pos     int
+}
+
+func (s *profileScanner) done() bool {
+	return s.pos == len(s.profile)
+}
+
+func (s *profileScanner) peek() byte {
+	if s.done() {
+		return 0
+	}
+	return s.profile[s.pos]
+}
+
+func (s *profileScanner) errorf(format string, a ...interface{}) error {
+	return s.errorAt(s.pos, format, a...)
+}
+
+func (s *profileScanner) errorAt(pos int, format string, a ...interface{}) error {
+	return fmt.Errorf("invalid MIG profile %q at offset %d: %v", s.profile, pos, fmt.Sprintf(format, a...))
+}
+
+// found describes what is at the current position, for use in errors.
+func (s *profileScanner) found() string {
+	if s.done() {
+		return "end of profile"
+	}
+	return fmt.Sprintf("%q", s.profile[s.pos:s.pos+1])
+}
+
+// number scans a positive decimal number without sign or leading zeros.
+func (s *profileScanner) number() (int, error) {
+	start := s.pos
+	for !s.done() && s.peek() >= '0' && s.peek() <= '9' {
+		s.pos++
+	}
+	digits := s.profile[start:s.pos]
+	switch {
+	case digits == "":
+		return 0, s.errorf("expected a number, found %v", s.found())
+	case digits[0] == '0' && len(digits) > 1:
+		return 0, s.errorAt(start, "leading zero in '%v'", digits)
+	case digits == "0":
+		return 0, s.errorAt(start, "'0' is not a valid count or size")
+	case len(digits) > maxProfileNumberDigits:
+		return 0, s.errorAt(start, "'%v' is too large", digits)
+	}
+	n, _ := strconv.Atoi(digits)
+	return n, nil
+}
+
+// literal scans 'lit', reporting upper case variants of it explicitly.
+func (s *profileScanner) literal(lit string) error {
+	rest := s.profile[s.pos:]
+	if strings.HasPrefix(rest, lit) {
+		s.pos += len(lit)
+		return nil
+	}
+	if len(rest) >= len(lit) && strings.EqualFold(rest[:len(lit)], lit) {
+		return s.errorf("'%v' must be written in lower case as '%v'", rest[:len(lit)], lit)
+	}
+	if strings.HasSuffix(lit, ".") && strings.HasPrefix(rest, strings.TrimSuffix(lit, ".")) {
+		s.pos += len(lit) - 1
+		return s.errorf("expected '.' after '%v', found %v", s.profile[:s.pos], s.found())
+	}
+	return s.errorf("expected '%v', found %v", lit, s.found())
+}
+
+// attribute scans a lower case alphanumeric attribute starting with a letter.
+func (s *profileScanner) attribute() (string, error) {
+	start := s.pos
+	for !s.done() && s.peek() != ',' {
+		c := s.peek()
+		switch {
+		case c >= 'a' && c <= 'z':
+		case c >= '0' && c <= '9' && s.pos > start:
+		case c >= 'A' && c <= 'Z':
+			end := strings.IndexByte(s.profile[start:], ',')
+			if end < 0 {
+				end = len(s.profile) - start
+			}
+			return "", s.errorf("attribute must be written in lower case as '%v'", strings.ToLower(s.profile[start:start+end]))
+		default:
+			return "", s.errorf("unexpected %v in attribute", s.found())
+		}
+		s.pos++
+	}
+	if s.pos == start {
+		return "", s.errorf("expected an attribute, found %v", s.found())
+	}
+	return s.profile[start:s.pos], nil
+}
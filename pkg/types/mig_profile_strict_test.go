@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertStrictMigProfileFormat(t *testing.T) {
+	testCases := []struct {
+		profile string
+		err     string
+	}{
+		{"1g.5gb", ""},
+		{"1c.2g.10gb", ""},
+		{"1g.5gb+me", ""},
+		{"1g.10gb+me,gfx", ""},
+		{"", `invalid MIG profile "" at offset 0: profile is empty`},
+		{"1g5gb", `invalid MIG profile "1g5gb" at offset 2: expected '.' after '1g', found "5"`},
+		{"1c2g.10gb", `invalid MIG profile "1c2g.10gb" at offset 2: expected '.' after '1c', found "2"`},
+		{"1G.5GB", `invalid MIG profile "1G.5GB" at offset 1: 'G.' must be written in lower case as 'g.'`},
+		{"1g.5GB", `invalid MIG profile "1g.5GB" at offset 4: 'GB' must be written in lower case as 'gb'`},
+		{"1g.5gb+ME", `invalid MIG profile "1g.5gb+ME" at offset 7: attribute must be written in lower case as 'me'`},
+		{" 1g.5gb", `invalid MIG profile " 1g.5gb" at offset 0: expected a number, found " "`},
+		{"1g.5gb ", `invalid MIG profile "1g.5gb " at offset 6: expected '+', found " "`},
+		{"+1g.5gb", `invalid MIG profile "+1g.5gb" at offset 0: expected a number, found "+"`},
+		{"01g.5gb", `invalid MIG profile "01g.5gb" at offset 0: leading zero in '01'`},
+		{"0g.0gb", `invalid MIG profile "0g.0gb" at offset 0: '0' is not a valid count or size`},
+		{"99999g.5gb", `invalid MIG profile "99999g.5gb" at offset 0: '99999' is too large`},
+		{"2c.2g.10gb", `invalid MIG profile "2c.2g.10gb" at offset 0: redundant compute instance count 2c, write '2g.' instead`},
+		{"3c.2g.10gb", `invalid MIG profile "3c.2g.10gb" at offset 0: compute instance count 3c exceeds GPU instance count 2g`},
+		{"1g.5gb+", `invalid MIG profile "1g.5gb+" at offset 7: expected an attribute, found end of profile`},
+		{"1g.5gb+me,me", `invalid MIG profile "1g.5gb+me,me" at offset 10: duplicate attribute 'me'`},
+		{"1g.5gb+1me", `invalid MIG profile "1g.5gb+1me" at offset 7: unexpected "1" in attribute`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			err := AssertStrictMigProfileFormat(tc.profile)
+			if tc.err == "" {
+				require.Nil(t, err, "Unexpected failure from AssertStrictMigProfileFormat")
+				return
+			}
+			require.EqualError(t, err, tc.err)
+		})
+	}
+}
+
+func FuzzAssertStrictMigProfileFormat(f *testing.F) {
+	for _, seed := range []string{"1g.5gb", "1c.2g.10gb", "1g.5gb+me", "1g5gb", "1G.5GB", "01g.5gb", "2c.2g.10gb", "1g.5gb+me,me", ""} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, profile string) {
+		info, err := parseStrictMigProfile(profile)
+		laxErr := AssertValidMigProfileFormat(profile)
+		if err != nil {
+			return
+		}
+		// Anything accepted in strict mode is accepted by the lenient parser
+		// and is already written exactly as it would be printed.
+		require.Nil(t, laxErr, "Strictly valid profile %q rejected by lenient parser", profile)
+		require.Equal(t, profile, info.String())
+	})
+}
+
+func FuzzParseMigProfile(f *testing.F) {
+	SetMockNVdevlib()
+	for _, seed := range []string{"1g.5gb", "1c.2g.10gb", "1g.5gb+me", "7g.40gb", "1g.5gb+me,me", "1c.1g", "+1g.5gb", ""} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, profile string) {
+		mp, err := ParseMigProfile(profile)
+		if err != nil {
+			return
+		}
+		require.Nil(t, AssertValidMigProfileFormat(profile), "Parsed profile %q has an invalid format", profile)
+		reparsed, err := ParseMigProfile(mp.String())
+		require.Nil(t, err, "Unable to parse %q printed for %q", mp.String(), profile)
+		require.Equal(t, mp, reparsed)
+	})
+}
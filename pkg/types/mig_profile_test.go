@@ -0,0 +1,87 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMigProfileCompare(t *testing.T) {
+	SetMockNVdevlib()
+
+	testCases := []struct {
+		a        string
+		b        string
+		expected int
+	}{
+		{"1g.5gb", "1g.5gb", 0},
+		{"1g.5gb", "2g.10gb", -1},
+		{"1g.10gb", "2g.10gb", -1},
+		{"1g.10gb", "1g.5gb", 1},
+		{"1g.5gb", "1g.5gb+me", -1},
+		{"1c.2g.10gb", "2g.10gb", -1},
+		{"7g.40gb", "3g.20gb", 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+" vs "+tc.b, func(t *testing.T) {
+			a := MustParseMigProfile(tc.a)
+			b := MustParseMigProfile(tc.b)
+			require.Equal(t, tc.expected, a.Compare(*b))
+			require.Equal(t, -tc.expected, b.Compare(*a))
+			require.Equal(t, tc.expected < 0, a.Less(*b))
+		})
+	}
+}
+
+func TestMigConfigCanonicalOrder(t *testing.T) {
+	SetMockNVdevlib()
+
+	config := MigConfig{
+		"1g.10gb":   1,
+		"2g.10gb":   2,
+		"1g.5gb":    3,
+		"1g.5gb+me": 1,
+		"invalid":   1,
+	}
+
+	require.Equal(t, []string{"1g.5gb", "1g.5gb+me", "1g.10gb", "2g.10gb", "invalid"}, config.Profiles())
+	require.Equal(t, "map[1g.5gb:3 1g.5gb+me:1 1g.10gb:1 2g.10gb:2 invalid:1]", config.String())
+
+	output, err := json.Marshal(config)
+	require.Nil(t, err)
+	require.Equal(t, `{"1g.5gb":3,"1g.5gb+me":1,"1g.10gb":1,"2g.10gb":2,"invalid":1}`, string(output))
+
+	output, err = yaml.Marshal(config)
+	require.Nil(t, err)
+	require.Equal(t, "1g.5gb: 3\n1g.5gb+me: 1\n1g.10gb: 1\n2g.10gb: 2\ninvalid: 1\n", string(output))
+
+	var parsed MigConfig
+	require.Nil(t, yaml.Unmarshal(output, &parsed))
+	require.Equal(t, config, parsed)
+
+	delete(config, "invalid")
+	var flattened []string
+	for _, mp := range config.Flatten() {
+		flattened = append(flattened, mp.String())
+	}
+	require.Equal(t, []string{"2g.10gb", "2g.10gb", "1g.10gb", "1g.5gb+me", "1g.5gb", "1g.5gb", "1g.5gb"}, flattened)
+}
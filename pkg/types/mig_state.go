@@ -19,7 +19,7 @@ package types
 import (
 	"github.com/NVIDIA/go-nvml/pkg/nvml"
 
-	"github.com/NVIDIA/mig-parted/internal/nvlib/mig"
+	"github.com/NVIDIA/mig-parted/pkg/nvlib/mig"
 )
 
 // MigState stores the MIG state for a set of GPUs.
@@ -35,8 +35,12 @@ type DeviceState struct {
 }
 
 // GpuInstanceState stores the MIG state for a specific GPUInstance.
+// The slice count and memory size of its profile are recorded alongside the
+// profile ID so that the profile can be identified under a different driver.
 type GpuInstanceState struct {
 	ProfileID        int
+	SliceCount       uint32 `json:",omitempty"`
+	MemorySizeMB     uint64 `json:",omitempty"`
 	Placement        nvml.GpuInstancePlacement
 	ComputeInstances []ComputeInstanceState
 }
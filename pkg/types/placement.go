@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"fmt"
+)
+
+// PlacementPreference determines which placement is chosen for each GPU
+// instance of a MIG config when more than one of them would work.
+type PlacementPreference string
+
+// Constants representing the supported placement preferences.
+const (
+	// PlacementPacked places GPU instances on the lowest free slices. This
+	// is the default.
+	PlacementPacked PlacementPreference = "packed"
+	// PlacementBalanced spreads GPU instances evenly across the two halves
+	// of a GPU.
+	PlacementBalanced PlacementPreference = "balanced"
+	// PlacementHighFirst places GPU instances on the highest free slices.
+	PlacementHighFirst PlacementPreference = "high-first"
+)
+
+// AssertValid checks that 'p' is empty or one of the supported placement preferences.
+func (p PlacementPreference) AssertValid() error {
+	switch p {
+	case "", PlacementPacked, PlacementBalanced, PlacementHighFirst:
+		return nil
+	}
+	return fmt.Errorf("unsupported placement preference '%v' (must be one of '%v', '%v' or '%v')", string(p), PlacementPacked, PlacementBalanced, PlacementHighFirst)
+}
+
+// PlacementExclusion reserves a range of slices of a GPU, so that no GPU
+// instance holding one of 'Profiles' is ever placed on any of them. This
+// keeps the slices free for a larger GPU instance created later on.
+//   - Slices is the first and last slice of the range, e.g. "0-1", or a
+//     single slice, e.g. "3".
+//   - Profiles are the MIG profiles kept out of the range, e.g. "1g.10gb".
+type PlacementExclusion struct {
+	Slices   string   `json:"slices"   yaml:"slices"`
+	Profiles []string `json:"profiles" yaml:"profiles,flow"`
+}
+
+// maxSlices bounds the slice indices of a 'PlacementExclusion', so that its
+// slices fit in a 64 bit mask.
+const maxSlices = 64
+
+// AssertValid checks that 'e' has a well-formed slice range and at least one
+// valid MIG profile.
+func (e PlacementExclusion) AssertValid() error {
+	_, _, err := e.SliceRange()
+	if err != nil {
+		return err
+	}
+	if len(e.Profiles) == 0 {
+		return fmt.Errorf("at least one profile is required")
+	}
+	for _, profile := range e.Profiles {
+		if IsMigProfileID(profile) {
+			return fmt.Errorf("invalid profile '%v': profile IDs are not supported", profile)
+		}
+		if IsFractionalMigProfile(profile) {
+			return fmt.Errorf("invalid profile '%v': fractional profiles are not supported", profile)
+		}
+		err := AssertValidMigProfileFormat(profile)
+		if err != nil {
+			return fmt.Errorf("invalid profile '%v': %v", profile, err)
+		}
+	}
+	return nil
+}
+
+// SliceRange returns the first and last slice reserved by 'e'.
+func (e PlacementExclusion) SliceRange() (uint32, uint32, error) {
+	var start, end uint32
+	n, err := fmt.Sscanf(e.Slices, "%d-%d", &start, &end)
+	if n == 1 && e.Slices == fmt.Sprintf("%d", start) {
+		end, err = start, nil
+	} else if err != nil || e.Slices != fmt.Sprintf("%d-%d", start, end) {
+		return 0, 0, fmt.Errorf("invalid slices '%v' (must be '<first>-<last>' or '<slice>')", e.Slices)
+	}
+	if start > end {
+		return 0, 0, fmt.Errorf("invalid slices '%v': first slice after last slice", e.Slices)
+	}
+	if end >= maxSlices {
+		return 0, 0, fmt.Errorf("invalid slices '%v': slices must be below %v", e.Slices, maxSlices)
+	}
+	return start, end, nil
+}
+
+// Mask returns the slices reserved by 'e' as a bitmask, or 0 if its slice
+// range is invalid.
+func (e PlacementExclusion) Mask() uint64 {
+	start, end, err := e.SliceRange()
+	if err != nil {
+		return 0
+	}
+	var mask uint64
+	for i := start; i <= end; i++ {
+		mask |= 1 << i
+	}
+	return mask
+}
+
+// Excludes checks whether 'profile' is one of the profiles kept out of the
+// slices reserved by 'e'. Profiles that can be parsed are compared in their
+// canonical form.
+func (e PlacementExclusion) Excludes(profile string) bool {
+	mp, _ := ParseMigProfile(profile)
+	for _, p := range e.Profiles {
+		if p == profile {
+			return true
+		}
+		if mp == nil {
+			continue
+		}
+		excluded, err := ParseMigProfile(p)
+		if err == nil && excluded.String() == mp.String() {
+			return true
+		}
+	}
+	return false
+}
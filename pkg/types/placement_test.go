@@ -0,0 +1,69 @@
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlacementExclusionSliceRange(t *testing.T) {
+	testCases := []struct {
+		slices string
+		mask   uint64
+		err    bool
+	}{
+		{"0-1", 0b11, false},
+		{"3", 0b1000, false},
+		{"4-6", 0b1110000, false},
+		{"1-0", 0, true},
+		{"0-", 0, true},
+		{"-1", 0, true},
+		{"0-1-2", 0, true},
+		{"0-64", 0, true},
+		{"a-b", 0, true},
+		{"", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.slices, func(t *testing.T) {
+			e := PlacementExclusion{Slices: tc.slices, Profiles: []string{"1g.5gb"}}
+			err := e.AssertValid()
+			if tc.err {
+				require.NotNil(t, err, "Unexpected success from AssertValid")
+				return
+			}
+			require.Nil(t, err, "Unexpected failure from AssertValid")
+			require.Equal(t, tc.mask, e.Mask())
+		})
+	}
+}
+
+func TestPlacementExclusionExcludes(t *testing.T) {
+	e := PlacementExclusion{Slices: "0-1", Profiles: []string{"1g.5gb", "2g.10gb"}}
+
+	require.True(t, e.Excludes("1g.5gb"))
+	require.True(t, e.Excludes("2g.10gb"))
+	require.False(t, e.Excludes("3g.20gb"))
+	require.False(t, e.Excludes("1c.3g.20gb"))
+	require.False(t, e.Excludes("invalid"))
+
+	require.NotNil(t, PlacementExclusion{Slices: "0-1"}.AssertValid(), "Unexpected success without profiles")
+	require.NotNil(t, PlacementExclusion{Slices: "0-1", Profiles: []string{"invalid"}}.AssertValid(), "Unexpected success with invalid profile")
+	require.NotNil(t, PlacementExclusion{Slices: "0-1", Profiles: []string{"19"}}.AssertValid(), "Unexpected success with profile ID")
+}
@@ -0,0 +1,44 @@
+//go:build e2e
+
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package e2e
+
+import (
+	"testing"
+)
+
+func TestApplyAndAssert(t *testing.T) {
+	requireE2E(t)
+	config := writeConfig(t)
+
+	mustMigParted(t, "apply", "-f", config, "-c", "full")
+	mustMigParted(t, "assert", "-f", config, "-c", "full")
+	if out, err := migParted("assert", "-f", config, "-c", "empty"); err == nil {
+		t.Fatalf("'assert -c empty' succeeded after applying 'full':\n%s", out)
+	}
+
+	// Applying a config that is already applied must be a no-op.
+	mustMigParted(t, "apply", "-f", config, "-c", "full")
+	mustMigParted(t, "assert", "-f", config, "-c", "full")
+
+	mustMigParted(t, "apply", "-f", config, "-c", "empty")
+	mustMigParted(t, "assert", "-f", config, "-c", "empty")
+	if out, err := migParted("assert", "-f", config, "-c", "full"); err == nil {
+		t.Fatalf("'assert -c full' succeeded after applying 'empty':\n%s", out)
+	}
+}
@@ -0,0 +1,41 @@
+//go:build e2e
+
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package e2e
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckpointAndRestore(t *testing.T) {
+	requireE2E(t)
+	config := writeConfig(t)
+
+	mustMigParted(t, "apply", "-f", config, "-c", "full")
+
+	checkpoint := filepath.Join(t.TempDir(), "checkpoint.json")
+	mustMigParted(t, "checkpoint", "-f", checkpoint)
+	mustMigParted(t, "checkpoint", "validate", "-f", checkpoint)
+
+	mustMigParted(t, "apply", "-f", config, "-c", "empty")
+	mustMigParted(t, "assert", "-f", config, "-c", "empty")
+
+	mustMigParted(t, "restore", "-f", checkpoint)
+	mustMigParted(t, "assert", "-f", config, "-c", "full")
+}
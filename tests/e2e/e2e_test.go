@@ -0,0 +1,240 @@
+//go:build e2e
+
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package e2e runs nvidia-mig-parted against a real MIG capable GPU.
+//
+// The tests reconfigure the GPU they run on, so they are only built with the
+// 'e2e' build tag and are skipped unless all of the following hold:
+//   - MIG_PARTED_E2E_ALLOW is set to "true".
+//   - MIG_PARTED_E2E_GPU_MODEL is set to the exact name of the GPU under test
+//     (as reported by 'nvidia-smi -L'), so they can't run on an unexpected
+//     machine by accident.
+//   - MIG mode is already enabled on that GPU, so no GPU reset is needed.
+//
+// The GPU under test is selected with MIG_PARTED_E2E_GPU (0 by default); no
+// other GPU is touched. Its MIG state is checkpointed before the tests run
+// and restored after them. The binary under test is built from this tree,
+// unless MIG_PARTED_E2E_BINARY points at a prebuilt one (e.g. a release).
+package e2e
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	nvdev "github.com/NVIDIA/go-nvlib/pkg/nvlib/device"
+	"github.com/NVIDIA/go-nvml/pkg/nvml"
+)
+
+const (
+	allowEnv    = "MIG_PARTED_E2E_ALLOW"
+	gpuModelEnv = "MIG_PARTED_E2E_GPU_MODEL"
+	gpuEnv      = "MIG_PARTED_E2E_GPU"
+	binaryEnv   = "MIG_PARTED_E2E_BINARY"
+)
+
+// e2e holds what the tests learned about the machine in TestMain.
+var e2e struct {
+	skipReason string
+	binary     string
+	gpu        int
+	// profile is the smallest MIG profile of the GPU under test, which the
+	// tests fill it with.
+	profile string
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(run(m))
+}
+
+func run(m *testing.M) int {
+	e2e.skipReason = checkSafetyRails()
+	if e2e.skipReason != "" {
+		return m.Run()
+	}
+
+	workdir, err := os.MkdirTemp("", "mig-parted-e2e-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error creating work directory: %v\n", err)
+		return 1
+	}
+	keep := false
+	defer func() {
+		if !keep {
+			os.RemoveAll(workdir)
+		}
+	}()
+
+	e2e.binary = os.Getenv(binaryEnv)
+	if e2e.binary == "" {
+		e2e.binary = filepath.Join(workdir, "nvidia-mig-parted")
+		build := exec.Command("go", "build", "-o", e2e.binary, "github.com/NVIDIA/mig-parted/cmd/nvidia-mig-parted")
+		build.Stdout = os.Stdout
+		build.Stderr = os.Stderr
+		if err := build.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "error building nvidia-mig-parted: %v\n", err)
+			return 1
+		}
+	}
+
+	original := filepath.Join(workdir, "original.json")
+	if out, err := migParted("checkpoint", "-f", original); err != nil {
+		fmt.Fprintf(os.Stderr, "error checkpointing the original MIG state: %v\n%s", err, out)
+		return 1
+	}
+
+	code := m.Run()
+
+	if out, err := migParted("restore", "-f", original); err != nil {
+		fmt.Fprintf(os.Stderr, "error restoring the original MIG state (saved at %v): %v\n%s", original, err, out)
+		// Keep the checkpoint around so the GPU can be restored by hand.
+		keep = true
+		return 1
+	}
+	return code
+}
+
+// checkSafetyRails returns why the tests must be skipped on this machine, or
+// "" if they may run. It also selects the GPU under test and its smallest MIG
+// profile.
+func checkSafetyRails() string {
+	if os.Getenv(allowEnv) != "true" {
+		return fmt.Sprintf("%v is not set to 'true'", allowEnv)
+	}
+	model := os.Getenv(gpuModelEnv)
+	if model == "" {
+		return fmt.Sprintf("%v is not set", gpuModelEnv)
+	}
+	if s := os.Getenv(gpuEnv); s != "" {
+		gpu, err := strconv.Atoi(s)
+		if err != nil || gpu < 0 {
+			return fmt.Sprintf("invalid %v: %v", gpuEnv, s)
+		}
+		e2e.gpu = gpu
+	}
+
+	nvmlLib := nvml.New()
+	if ret := nvmlLib.Init(); ret != nvml.SUCCESS {
+		return fmt.Sprintf("error initializing NVML: %v", ret)
+	}
+	defer func() { _ = nvmlLib.Shutdown() }()
+
+	handle, ret := nvmlLib.DeviceGetHandleByIndex(e2e.gpu)
+	if ret != nvml.SUCCESS {
+		return fmt.Sprintf("error getting GPU %d: %v", e2e.gpu, ret)
+	}
+	name, ret := handle.GetName()
+	if ret != nvml.SUCCESS {
+		return fmt.Sprintf("error getting name of GPU %d: %v", e2e.gpu, ret)
+	}
+	if name != model {
+		return fmt.Sprintf("GPU %d is a '%v', not the expected '%v'", e2e.gpu, name, model)
+	}
+
+	device, err := nvdev.New(nvdev.WithNvml(nvmlLib)).NewDevice(handle)
+	if err != nil {
+		return fmt.Sprintf("error creating device for GPU %d: %v", e2e.gpu, err)
+	}
+	enabled, err := device.IsMigEnabled()
+	if err != nil {
+		return fmt.Sprintf("error checking MIG mode of GPU %d: %v", e2e.gpu, err)
+	}
+	if !enabled {
+		return fmt.Sprintf("MIG mode is not enabled on GPU %d", e2e.gpu)
+	}
+
+	profiles, err := device.GetMigProfiles()
+	if err != nil {
+		return fmt.Sprintf("error getting MIG profiles of GPU %d: %v", e2e.gpu, err)
+	}
+	smallestGB := 0
+	for _, p := range profiles {
+		info := p.GetInfo()
+		if info.G != 1 || info.C != 1 || len(info.Attributes) != 0 {
+			continue
+		}
+		if e2e.profile == "" || info.GB < smallestGB {
+			e2e.profile = p.String()
+			smallestGB = info.GB
+		}
+	}
+	if e2e.profile == "" {
+		return fmt.Sprintf("no single slice MIG profile on GPU %d", e2e.gpu)
+	}
+
+	return ""
+}
+
+// requireE2E skips 't' unless the safety rails checked in TestMain all hold.
+func requireE2E(t *testing.T) {
+	t.Helper()
+	if e2e.skipReason != "" {
+		t.Skipf("Skipping end-to-end test: %v", e2e.skipReason)
+	}
+}
+
+// migParted runs the binary under test with 'args' and returns its combined
+// output.
+func migParted(args ...string) ([]byte, error) {
+	var out bytes.Buffer
+	cmd := exec.Command(e2e.binary, args...)
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	err := cmd.Run()
+	return out.Bytes(), err
+}
+
+// mustMigParted runs the binary under test with 'args', failing 't' if it
+// does not succeed.
+func mustMigParted(t *testing.T, args ...string) []byte {
+	t.Helper()
+	out, err := migParted(args...)
+	if err != nil {
+		t.Fatalf("'nvidia-mig-parted %v' failed: %v\n%s", args, err, out)
+	}
+	return out
+}
+
+// writeConfig writes a config file with a 'full' config filling the GPU
+// under test with its smallest MIG profile and an 'empty' config removing all
+// of its MIG devices, and returns its path.
+func writeConfig(t *testing.T) string {
+	t.Helper()
+	config := fmt.Sprintf(`version: v1
+mig-configs:
+  full:
+  - devices: [%[1]d]
+    mig-enabled: true
+    mig-devices: {}
+    fill: %[2]q
+  empty:
+  - devices: [%[1]d]
+    mig-enabled: true
+    mig-devices: {}
+`, e2e.gpu, e2e.profile)
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(config), 0600); err != nil {
+		t.Fatalf("error writing config file: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,46 @@
+//go:build e2e
+
+/*
+ * Copyright (c) 2024, NVIDIA CORPORATION.  All rights reserved.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package e2e
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExport(t *testing.T) {
+	requireE2E(t)
+	config := writeConfig(t)
+
+	mustMigParted(t, "apply", "-f", config, "-c", "full")
+
+	// The exported config must describe what was just applied, so asserting
+	// it on the same node has to succeed.
+	exported := filepath.Join(t.TempDir(), "exported.yaml")
+	out := mustMigParted(t, "export", "-o", "yaml")
+	if err := os.WriteFile(exported, out, 0600); err != nil {
+		t.Fatalf("error writing exported config: %v", err)
+	}
+	mustMigParted(t, "assert", "-f", exported, "-c", "current")
+
+	mustMigParted(t, "apply", "-f", config, "-c", "empty")
+	if out, err := migParted("assert", "-f", exported, "-c", "current"); err == nil {
+		t.Fatalf("exported config still asserted after applying 'empty':\n%s", out)
+	}
+}